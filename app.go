@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,9 +19,11 @@ import (
 type App struct {
 	ctx       context.Context
 	queue     *backend.Queue
-	config    *backend.Config
+	configs   *backend.ConfigStore // Shared with the queue
 	fileIndex *backend.FileIndex
 	history   *backend.History
+	channels  *backend.ChannelArchives
+	watchlist *backend.Watchlist
 }
 
 // NewApp creates a new App application struct
@@ -34,31 +37,30 @@ func (a *App) startup(ctx context.Context) {
 
 	// Load config first
 	config, err := backend.LoadConfig()
-	if err == nil {
-		a.config = config
-	} else {
+	if err != nil {
 		// Use default config
-		a.config = &backend.Config{
-			OutputDirectory:     backend.GetDefaultOutputDirectory(),
-			VideoQuality:        "best",
-			AudioSourcePriority: []string{"tidal", "qobuz", "amazon"},
-			NamingTemplate:      "{artist}/{title}/{title}",
-			GenerateNFO:         true,
-			ConcurrentDownloads: 2,
-			EmbedCoverArt:       true,
-			Theme:               "system",
-		}
+		config = backend.GetDefaultConfig()
+	}
+
+	// Normalize config; invalid values are reported but don't block the UI
+	validation := config.Validate()
+	for _, w := range validation.Warnings {
+		slog.Warn("config warning", "field", w.Field, "message", w.Message)
+	}
+	for _, e := range validation.Errors {
+		slog.Error("config error", "field", e.Field, "message", e.Message)
 	}
 
 	// Create queue with concurrency from config
-	maxConcurrent := a.config.ConcurrentDownloads
+	maxConcurrent := config.ConcurrentDownloads
 	if maxConcurrent < 1 {
 		maxConcurrent = 2
 	}
 	a.queue = backend.NewQueue(ctx, maxConcurrent)
 
-	// Set config for queue
-	a.queue.SetConfig(a.config)
+	// Settings are published as snapshots shared with the queue
+	a.configs = backend.NewConfigStore(config)
+	a.queue.SetConfigStore(a.configs)
 
 	// Set up progress callback to emit Wails events
 	a.queue.SetProgressCallback(func(event backend.QueueEvent) {
@@ -71,18 +73,27 @@ func (a *App) startup(ctx context.Context) {
 	// Start auto-save (every 30 seconds)
 	a.queue.AutoSave(30 * time.Second)
 
+	// Prune old finished items per the retention policy
+	a.queue.StartJanitor(backend.DefaultJanitorInterval)
+
+	// Resolvers, output permissions, chat bots and the other services
+	if err := backend.ApplyConfig(config, a.queue); err != nil {
+		slog.Warn("temp directory check failed", "err", err)
+	}
+
 	// Initialize file index for duplicate detection
 	a.fileIndex = backend.NewFileIndex(backend.GetDataPath())
 	a.fileIndex.Load()
 
-	// Scan output directory in background
+	// Scan output directory and per-artist directories in background
 	go func() {
-		outputDir := a.config.OutputDirectory
-		if outputDir == "" {
-			outputDir = backend.GetDefaultOutputDirectory()
+		for _, dir := range backend.LibraryDirectories(a.configs.Get()) {
+			a.fileIndex.ScanDirectory(dir)
 		}
-		a.fileIndex.ScanDirectory(outputDir)
-		a.fileIndex.Save()
+		a.fileIndex.ScheduleSave()
+		backend.SyncLibraryViews(a.configs.Get())
+		backend.BuildCollections(a.configs.Get())
+		backend.BuildJellyfinPlaylists(a.configs.Get())
 	}()
 
 	// Pass file index to queue for skip detection
@@ -94,6 +105,13 @@ func (a *App) startup(ctx context.Context) {
 	// Pass history to queue for recording completed downloads
 	a.queue.SetHistory(a.history)
 
+	// Followed channels
+	a.channels = backend.NewChannelArchives()
+
+	// Watched artists, checked for new releases in the background
+	a.watchlist = backend.NewWatchlist()
+	a.watchlist.Start(ctx, a.queue, a.history, a.fileIndex)
+
 	// Start processing queue
 	a.queue.StartProcessing()
 }
@@ -105,6 +123,9 @@ func (a *App) shutdown(ctx context.Context) {
 		a.queue.StopProcessing()
 		a.queue.SaveQueue()
 	}
+	if a.fileIndex != nil {
+		a.fileIndex.Flush()
+	}
 }
 
 // =============================================================================
@@ -159,6 +180,17 @@ func (a *App) FindAudioMatch(videoInfo *backend.VideoInfo) (*backend.MatchResult
 	return nil, nil
 }
 
+// Search runs a free-text search over YouTube and Tidal and returns the
+// merged, ranked candidates for the search-first add flow
+func (a *App) Search(query string, limit int) (*backend.SearchResponse, error) {
+	return backend.UnifiedSearch(query, limit, a.configs.Get().CookiesBrowser)
+}
+
+// ResolveTrack returns the preview card data for a URL before it is queued
+func (a *App) ResolveTrack(url string) (*backend.TrackPreview, error) {
+	return backend.ResolveTrack(url, a.configs.Get(), a.fileIndex)
+}
+
 // =============================================================================
 // Queue Management
 // =============================================================================
@@ -172,7 +204,7 @@ func (a *App) AddToQueue(request backend.DownloadRequest) (string, error) {
 		_, err := backend.ParseYouTubeURL(request.VideoURL)
 		if err != nil {
 			// Pure playlist URL (no video ID), fetch all videos
-			ids, err := a.AddPlaylistToQueue(request.VideoURL, request.Quality)
+			ids, err := a.addPlaylistToQueue(request.VideoURL, request.Quality, request.DryRun, backend.PlaylistRange{})
 			if err != nil {
 				return "", err
 			}
@@ -187,16 +219,61 @@ func (a *App) AddToQueue(request backend.DownloadRequest) (string, error) {
 
 // AddPlaylistToQueue fetches playlist videos and adds each to the queue
 func (a *App) AddPlaylistToQueue(playlistURL string, quality string) ([]string, error) {
+	return a.addPlaylistToQueue(playlistURL, quality, false, backend.PlaylistRange{})
+}
+
+// AddPlaylistRangeToQueue queues part of a playlist: a slice of positions,
+// entries uploaded since a date, optionally last-first
+func (a *App) AddPlaylistRangeToQueue(playlistURL string, quality string, rng backend.PlaylistRange) ([]string, error) {
+	if err := rng.Validate(); err != nil {
+		return nil, err
+	}
+	return a.addPlaylistToQueue(playlistURL, quality, false, rng)
+}
+
+// ContinuePlaylistToQueue queues the rest of a playlist that was fetched
+// partially, using the continuation token from the previous import
+func (a *App) ContinuePlaylistToQueue(continuation string, quality string) ([]string, error) {
+	playlistInfo, err := backend.ContinuePlaylist(continuation, 0)
+	if err != nil {
+		return nil, err
+	}
+	return a.queuePlaylistVideos(playlistInfo, quality, false), nil
+}
+
+// CheckPlaylist fetches a playlist and returns the pre-import summary:
+// which entries are new, already downloaded, in the library or unavailable
+func (a *App) CheckPlaylist(playlistURL string) (*backend.PlaylistCheck, error) {
 	playlistInfo, err := backend.GetPlaylistVideos(playlistURL)
 	if err != nil {
 		return nil, err
 	}
+	return backend.CheckPlaylist(playlistInfo, a.configs.Get(), a.history, a.fileIndex), nil
+}
 
+// addPlaylistToQueue queues the videos of a playlist rng selects, optionally as dry runs
+func (a *App) addPlaylistToQueue(playlistURL string, quality string, dryRun bool, rng backend.PlaylistRange) ([]string, error) {
+	playlistInfo, err := backend.GetPlaylistRange(playlistURL, rng, 0)
+	if err != nil {
+		return nil, err
+	}
+	if playlistInfo.Partial {
+		runtime.EventsEmit(a.ctx, "playlist:partial", playlistInfo.Continuation)
+	}
+	return a.queuePlaylistVideos(playlistInfo, quality, dryRun), nil
+}
+
+// queuePlaylistVideos adds the fetched videos of a playlist to the queue,
+// skipping unavailable entries and ones already downloaded
+func (a *App) queuePlaylistVideos(playlistInfo *backend.PlaylistInfo, quality string, dryRun bool) []string {
+	check := backend.CheckPlaylist(playlistInfo, a.configs.Get(), a.history, a.fileIndex)
 	ids := []string{}
-	for _, video := range playlistInfo.Videos {
+	for _, video := range check.Importable(false) {
 		request := backend.DownloadRequest{
-			VideoURL: video.URL,
-			Quality:  quality,
+			VideoURL:   video.URL,
+			Quality:    quality,
+			DryRun:     dryRun,
+			TrackTotal: playlistInfo.TrackTotal(),
 		}
 
 		// Add with metadata already fetched
@@ -217,7 +294,83 @@ func (a *App) AddPlaylistToQueue(playlistURL string, quality string) ([]string,
 		ids = append(ids, id)
 	}
 
-	return ids, nil
+	return ids
+}
+
+// GetChannelArchives returns the followed channels
+func (a *App) GetChannelArchives() []backend.ChannelArchive {
+	return a.channels.List()
+}
+
+// AddChannelArchive follows a channel and queues its videos
+func (a *App) AddChannelArchive(archive backend.ChannelArchive) (*backend.ChannelSyncResult, error) {
+	added, err := a.channels.Add(archive)
+	if err != nil {
+		return nil, err
+	}
+	return a.channels.Sync(added.ID, a.queue, a.history, a.fileIndex)
+}
+
+// SyncChannelArchive queues the videos uploaded since the last sync
+func (a *App) SyncChannelArchive(id string) (*backend.ChannelSyncResult, error) {
+	return a.channels.Sync(id, a.queue, a.history, a.fileIndex)
+}
+
+// RemoveChannelArchive stops following a channel
+func (a *App) RemoveChannelArchive(id string) error {
+	return a.channels.Remove(id)
+}
+
+// GetWatchlist returns the watched artists
+func (a *App) GetWatchlist() []backend.WatchedArtist {
+	return a.watchlist.List()
+}
+
+// AddWatchedArtist watches an artist; the first check records their existing releases
+func (a *App) AddWatchedArtist(artist backend.WatchedArtist) (*backend.WatchedArtist, error) {
+	added, err := a.watchlist.Add(artist)
+	if err != nil {
+		return nil, err
+	}
+	if added.LastCheckAt.IsZero() {
+		go a.watchlist.Check(added.ID, a.queue, a.history, a.fileIndex)
+	}
+	return added, nil
+}
+
+// CheckWatchedArtist looks for new releases of an artist now
+func (a *App) CheckWatchedArtist(id string) (*backend.WatchCheckResult, error) {
+	return a.watchlist.Check(id, a.queue, a.history, a.fileIndex)
+}
+
+// RemoveWatchedArtist stops watching an artist
+func (a *App) RemoveWatchedArtist(id string) error {
+	return a.watchlist.Remove(id)
+}
+
+// GetWatchReleases lists releases found by the watchlist, "" = any status
+func (a *App) GetWatchReleases(status string) []backend.WatchRelease {
+	return a.watchlist.Releases(status)
+}
+
+// DismissWatchRelease hides a release that has no music video
+func (a *App) DismissWatchRelease(id string) error {
+	return a.watchlist.Dismiss(id)
+}
+
+// PlanDownload dry-runs a download request and returns the plan without queueing it
+func (a *App) PlanDownload(request backend.DownloadRequest) (*backend.DownloadPlan, error) {
+	return backend.PlanRequest(request, a.configs.Get(), a.fileIndex)
+}
+
+// CommitDryRun queues a planned dry-run item for a real download
+func (a *App) CommitDryRun(id string) error {
+	return a.queue.CommitDryRun(id)
+}
+
+// RetrySync runs the rclone sync again for a completed item
+func (a *App) RetrySync(id string) error {
+	return a.queue.RetrySync(id)
 }
 
 // AddToQueueWithMetadata adds an item with pre-fetched metadata
@@ -235,11 +388,28 @@ func (a *App) GetQueueItem(id string) *backend.QueueItem {
 	return a.queue.GetItem(id)
 }
 
+// GetStageCatalog returns the queue stage messages for lang, keyed by the
+// stageCode sent with queue items
+func (a *App) GetStageCatalog(lang string) map[backend.StageCode]string {
+	return backend.StageCatalog(lang)
+}
+
 // GetQueueStats returns queue statistics
 func (a *App) GetQueueStats() backend.QueueStats {
 	return a.queue.GetStats()
 }
 
+// GetScheduleStatus reports whether the download schedule currently allows new downloads
+func (a *App) GetScheduleStatus() backend.ScheduleStatus {
+	return a.queue.GetScheduleStatus()
+}
+
+// OverrideSchedule runs the queue outside its schedule for the given minutes (0 clears)
+func (a *App) OverrideSchedule(minutes int) backend.ScheduleStatus {
+	a.queue.OverrideSchedule(time.Duration(minutes) * time.Minute)
+	return a.queue.GetScheduleStatus()
+}
+
 // RemoveFromQueue removes an item from the queue
 func (a *App) RemoveFromQueue(id string) error {
 	return a.queue.RemoveFromQueue(id)
@@ -270,6 +440,32 @@ func (a *App) MoveQueueItem(id string, newIndex int) error {
 	return a.queue.MoveItem(id, newIndex)
 }
 
+// SetQueueItemNotes replaces the notes and tags of a queue item
+func (a *App) SetQueueItemNotes(id, notes string, tags []string) error {
+	return a.queue.SetItemNotes(id, notes, tags)
+}
+
+// ApproveQueueItem starts an item awaiting approval; musicURL optionally picks the audio match
+func (a *App) ApproveQueueItem(id, musicURL string) error {
+	return a.queue.ApproveItem(id, musicURL)
+}
+
+// RejectQueueItem cancels an item awaiting approval
+func (a *App) RejectQueueItem(id string) error {
+	return a.queue.RejectItem(id)
+}
+
+// RejectQueueItemMatch deletes a completed download whose match was wrong
+// or out of sync and fails the item so it can be retried
+func (a *App) RejectQueueItemMatch(id string) error {
+	return a.queue.RejectMatch(id)
+}
+
+// ApproveAllQueueItems starts every item awaiting approval
+func (a *App) ApproveAllQueueItems() int {
+	return a.queue.ApproveAll()
+}
+
 // SaveQueue persists the queue to disk
 func (a *App) SaveQueue() error {
 	return a.queue.SaveQueue()
@@ -281,13 +477,130 @@ func (a *App) SaveQueue() error {
 
 // GetConfig returns current configuration
 func (a *App) GetConfig() *backend.Config {
-	return a.config
+	return a.configs.Get()
 }
 
-// SaveConfig saves configuration
+// SaveConfig validates and saves configuration
 func (a *App) SaveConfig(config backend.Config) error {
-	a.config = &config
-	return backend.SaveConfig(&config)
+	if err := config.Validate().Err(); err != nil {
+		return err
+	}
+	if err := backend.SaveConfig(&config); err != nil {
+		return err
+	}
+	if err := backend.ApplyConfig(&config, a.queue); err != nil {
+		slog.Warn("temp directory check failed", "err", err)
+	}
+	return nil
+}
+
+// ValidateConfig checks a config without saving it. The config is normalized
+// in place, so the returned values can be shown back in the settings form.
+func (a *App) ValidateConfig(config backend.Config) *ValidateConfigResult {
+	validation := config.Validate()
+	return &ValidateConfigResult{
+		Valid:    validation.Valid(),
+		Errors:   validation.Errors,
+		Warnings: validation.Warnings,
+		Config:   config,
+	}
+}
+
+type ValidateConfigResult struct {
+	Valid    bool                  `json:"valid"`
+	Errors   []backend.ConfigIssue `json:"errors"`
+	Warnings []backend.ConfigIssue `json:"warnings"`
+	Config   backend.Config        `json:"config"` // Normalized values
+}
+
+// TryMetadataRules applies metadata rules to a sample title, artist and
+// album. With no rules the configured ones are used.
+func (a *App) TryMetadataRules(rules []string, title, artist, album string) *MetadataRulesResult {
+	if len(rules) == 0 {
+		if config := a.configs.Get(); config != nil {
+			rules = config.MetadataRules
+		}
+	}
+	rewrite, errors := backend.TryMetadataRules(rules, title, artist, album)
+	return &MetadataRulesResult{MetadataRewrite: rewrite, Errors: errors}
+}
+
+type MetadataRulesResult struct {
+	backend.MetadataRewrite
+	Errors []string `json:"errors,omitempty"` // Rules that do not compile
+}
+
+// =============================================================================
+// Secrets
+// =============================================================================
+
+// ListSecrets returns the names of stored secrets (never their values)
+func (a *App) ListSecrets() ([]string, error) {
+	store := backend.DefaultSecretStore()
+	if !store.Exists() {
+		return []string{}, nil
+	}
+	return store.Names()
+}
+
+// SetSecret stores an encrypted secret; an empty value deletes it
+func (a *App) SetSecret(name, value string) error {
+	var err error
+	a.configs.Update(func(config *backend.Config) {
+		err = backend.SetSecret(name, value, config)
+	})
+	return err
+}
+
+// ImportCookiesFile stores a cookies.txt export in the encrypted secret store
+func (a *App) ImportCookiesFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cookies file: %w", err)
+	}
+	return backend.SetSecret(backend.SecretYouTubeCookies, string(data), nil)
+}
+
+// SendTestNotification sends a test message to the configured email/Apprise sinks
+func (a *App) SendTestNotification(config backend.Config) error {
+	return backend.SendTestNotification(&config)
+}
+
+// =============================================================================
+// First-run Setup
+// =============================================================================
+
+// GetSetupStatus reports missing binaries, config state and the suggested output folder
+func (a *App) GetSetupStatus() *backend.SetupStatus {
+	return backend.GetSetupStatus()
+}
+
+// TestOutputDirectory checks that a folder can be created and written to
+func (a *App) TestOutputDirectory(path string) error {
+	return backend.TestOutputDirectory(path)
+}
+
+// ProbeAudioServices reports which audio services are reachable
+func (a *App) ProbeAudioServices(proxyURL string) map[string]backend.ServiceStatus {
+	return backend.ProbeAudioServices(proxyURL)
+}
+
+// CompleteSetup validates and writes the initial config from the wizard
+func (a *App) CompleteSetup(config backend.Config) (*ValidateConfigResult, error) {
+	validation, err := backend.CompleteSetup(&config)
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.ApplyConfig(&config, a.queue); err != nil {
+		validation.Warnings = append(validation.Warnings, backend.ConfigIssue{Field: "tempDirectory", Message: err.Error()})
+	}
+
+	return &ValidateConfigResult{
+		Valid:    true,
+		Errors:   validation.Errors,
+		Warnings: validation.Warnings,
+		Config:   config,
+	}, nil
 }
 
 // GetDefaultOutputDirectory returns default output path
@@ -304,14 +617,14 @@ type FileInfo struct {
 	Name      string `json:"name"`
 	Path      string `json:"path"`
 	Size      int64  `json:"size"`
-	Type      string `json:"type"` // "video", "cover", "nfo"
-	Thumbnail string `json:"thumbnail,omitempty"`
+	Type      string `json:"type"`                // "video", "cover", "nfo"
+	Thumbnail string `json:"thumbnail,omitempty"` // data URL for video/audio files
 }
 
 // ListFiles lists files in a directory filtered by type
 func (a *App) ListFiles(directory string, fileType string) ([]FileInfo, error) {
 	if directory == "" {
-		directory = a.config.OutputDirectory
+		directory = a.configs.Get().OutputDirectory
 		if directory == "" {
 			directory = backend.GetDefaultOutputDirectory()
 		}
@@ -363,6 +676,22 @@ func (a *App) ListFiles(directory string, fileType string) ([]FileInfo, error) {
 		return nil
 	})
 
+	// Attach cached thumbnails for video and audio files
+	var mediaPaths []string
+	for _, f := range files {
+		if f.Type == "video" || f.Type == "audio" {
+			mediaPaths = append(mediaPaths, f.Path)
+		}
+	}
+	thumbs := backend.GenerateThumbnails(mediaPaths, 4)
+	for i := range files {
+		if thumbPath, ok := thumbs[files[i].Path]; ok {
+			if dataURL, err := a.GetImageAsDataURL(thumbPath); err == nil {
+				files[i].Thumbnail = dataURL
+			}
+		}
+	}
+
 	return files, nil
 }
 
@@ -492,13 +821,13 @@ func (a *App) ReorganizePlaylist(playlistFolder string) (*ReorganizePlaylistResu
 		newPath := filepath.Join(newFolder, newFilename)
 
 		// Create new directory
-		if err := os.MkdirAll(newFolder, 0755); err != nil {
+		if err := backend.MkdirOutput(newFolder); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Failed to create dir for %s: %v", filename, err))
 			return nil
 		}
 
 		// Move file
-		if err := os.Rename(path, newPath); err != nil {
+		if err := backend.MoveFile(path, newPath); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Failed to rename %s: %v", filename, err))
 			return nil
 		}
@@ -507,13 +836,13 @@ func (a *App) ReorganizePlaylist(playlistFolder string) (*ReorganizePlaylistResu
 		nfoPath := strings.TrimSuffix(path, ext) + ".nfo"
 		if _, err := os.Stat(nfoPath); err == nil {
 			newNfoPath := strings.TrimSuffix(newPath, ext) + ".nfo"
-			os.Rename(nfoPath, newNfoPath)
+			backend.MoveFile(nfoPath, newNfoPath)
 		}
 
 		posterPath := filepath.Join(parentDir, "poster.jpg")
 		if _, err := os.Stat(posterPath); err == nil {
 			newPosterPath := filepath.Join(newFolder, "poster.jpg")
-			os.Rename(posterPath, newPosterPath)
+			backend.MoveFile(posterPath, newPosterPath)
 		}
 
 		// Try to remove old empty directory
@@ -532,7 +861,7 @@ func (a *App) ReorganizePlaylist(playlistFolder string) (*ReorganizePlaylistResu
 
 // GetPlaylistFolders returns list of playlist folders in output directory
 func (a *App) GetPlaylistFolders() ([]string, error) {
-	outputDir := a.config.OutputDirectory
+	outputDir := a.configs.Get().OutputDirectory
 	if outputDir == "" {
 		outputDir = backend.GetDefaultOutputDirectory()
 	}
@@ -573,6 +902,7 @@ type FlattenPlaylistResult struct {
 }
 
 // FlattenPlaylistFolder moves all files from subfolders to the root of the playlist folder
+// (for folders downloaded before Config.PlaylistLayout was set to "flat")
 func (a *App) FlattenPlaylistFolder(playlistFolder string) (*FlattenPlaylistResult, error) {
 	result := &FlattenPlaylistResult{}
 
@@ -620,7 +950,7 @@ func (a *App) FlattenPlaylistFolder(playlistFolder string) (*FlattenPlaylistResu
 			continue
 		}
 
-		if err := os.Rename(f.srcPath, destPath); err != nil {
+		if err := backend.MoveFile(f.srcPath, destPath); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Failed to move %s: %v", f.filename, err))
 			continue
 		}
@@ -640,6 +970,61 @@ func (a *App) FlattenPlaylistFolder(playlistFolder string) (*FlattenPlaylistResu
 	return result, nil
 }
 
+// FindDuplicates returns an ISRC-based duplicate report for the indexed library
+func (a *App) FindDuplicates() *backend.DedupeReport {
+	return a.fileIndex.DedupeReport()
+}
+
+// GetAlbumCompleteness reports which tracks of each library album are present
+func (a *App) GetAlbumCompleteness() []backend.AlbumCompleteness {
+	return backend.CheckAlbums(a.fileIndex)
+}
+
+// DownloadMissingAlbumTracks queues the tracks of an album that are not in the library
+func (a *App) DownloadMissingAlbumTracks(albumID string) (*backend.MissingTracksResult, error) {
+	return a.queue.QueueMissingAlbumTracks(albumID)
+}
+
+// CheckLibrary verifies NFO, poster and lyrics sidecars in every library
+// directory. With repair set, missing or drifted sidecars are regenerated.
+func (a *App) CheckLibrary(repair bool) (*backend.LibraryCheckReport, error) {
+	config := a.configs.Get()
+	opts := backend.LibraryCheckOptionsFromConfig(config, repair)
+	opts.History = a.history
+	return backend.CheckLibraries(backend.LibraryDirectories(config), opts)
+}
+
+// SyncLibraryViews brings the configured link-farm views up to date with the library
+func (a *App) SyncLibraryViews() (*backend.LibraryViewReport, error) {
+	return backend.SyncLibraryViews(a.configs.Get())
+}
+
+// BuildCollections writes the Jellyfin collections for playlists and artists
+func (a *App) BuildCollections() (*backend.CollectionReport, error) {
+	return backend.BuildCollections(a.configs.Get())
+}
+
+// BuildJellyfinPlaylists writes a Jellyfin playlist for every playlist folder
+func (a *App) BuildJellyfinPlaylists() (*backend.PlaylistReport, error) {
+	return backend.BuildJellyfinPlaylists(a.configs.Get())
+}
+
+// ExportQualityReport analyzes every file in the library and writes the
+// report to path, as JSON for a .json extension and CSV otherwise
+func (a *App) ExportQualityReport(path string, spectral bool) (*backend.QualityReport, error) {
+	report, err := backend.BuildLibraryQualityReport(backend.LibraryDirectories(a.configs.Get()), backend.QualityReportOptions{
+		Spectral: spectral,
+		History:  a.history,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.SaveQualityReport(report, path); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
 // =============================================================================
 // History
 // =============================================================================
@@ -649,7 +1034,12 @@ func (a *App) GetHistory() []backend.HistoryEntry {
 	return a.history.GetAll()
 }
 
-// SearchHistory searches history by title or artist
+// QueryHistory returns one filtered, sorted page of history entries
+func (a *App) QueryHistory(query backend.HistoryQuery) (*backend.HistoryPage, error) {
+	return a.history.Query(query)
+}
+
+// SearchHistory searches history by title, artist, notes or tags
 func (a *App) SearchHistory(query string) []backend.HistoryEntry {
 	return a.history.Search(query)
 }
@@ -674,20 +1064,73 @@ func (a *App) DeleteHistoryEntry(id string) error {
 	return a.history.Delete(id)
 }
 
+// SetHistoryNotes replaces the notes and tags of a history entry
+func (a *App) SetHistoryNotes(id, notes string, tags []string) error {
+	return a.history.SetNotes(id, notes, tags)
+}
+
+// GetHistoryTags lists the tags used in history, most used first
+func (a *App) GetHistoryTags() []backend.TagCount {
+	return a.history.Tags()
+}
+
 // ClearHistory removes all history entries
 func (a *App) ClearHistory() error {
 	return a.history.Clear()
 }
 
+// ImportYtdlpArchive marks the videos of a yt-dlp archive.txt as downloaded,
+// so subscriptions and playlist imports skip them
+func (a *App) ImportYtdlpArchive(path string) (*backend.ArchiveImportResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	defer f.Close()
+	return a.history.ImportYtdlpArchive(f)
+}
+
+// GetFileMetadata returns the metadata of a library file for editing
+func (a *App) GetFileMetadata(path string) (*backend.Metadata, error) {
+	return backend.ReadMediaMetadata(path)
+}
+
+// UpdateFileMetadata applies edited metadata to a library file's NFO,
+// embedded tags and file index entry
+func (a *App) UpdateFileMetadata(path string, metadata backend.Metadata) error {
+	return backend.UpdateMediaMetadata(path, &metadata, a.configs.Get(), a.fileIndex)
+}
+
+// ExportYtdlpArchive writes the history as a yt-dlp archive.txt to path and
+// returns how many videos it lists
+func (a *App) ExportYtdlpArchive(path string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	count, err := a.history.ExportYtdlpArchive(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
 // RedownloadFromHistory adds a history item back to the queue for re-download
 func (a *App) RedownloadFromHistory(id string) (string, error) {
+	return a.RedownloadFromHistoryWithOverrides(id, nil)
+}
+
+// RedownloadFromHistoryWithOverrides re-queues a history item with different
+// quality, audio sources, output mode or naming template
+func (a *App) RedownloadFromHistoryWithOverrides(id string, overrides *backend.RedownloadOverrides) (string, error) {
 	entry := a.history.GetByID(id)
 	if entry == nil {
 		return "", fmt.Errorf("history entry not found: %s", id)
 	}
 
-	request := backend.DownloadRequest{
-		VideoURL: entry.VideoURL,
+	request, err := backend.RedownloadRequest(entry, overrides)
+	if err != nil {
+		return "", err
 	}
 
 	return a.queue.AddToQueue(request)
@@ -706,7 +1149,7 @@ func (a *App) AnalyzeAudio(filePath string) (*backend.AudioAnalysis, error) {
 // Returns the path to the generated PNG file
 func (a *App) GenerateSpectrogram(inputPath string) (string, error) {
 	// Generate spectrogram in temp directory
-	tempDir := filepath.Join(os.TempDir(), "youflac", "spectrograms")
+	tempDir := filepath.Join(backend.GetTempDirectory(), "spectrograms")
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -724,7 +1167,7 @@ func (a *App) GenerateSpectrogram(inputPath string) (string, error) {
 
 // GenerateWaveform creates a waveform image for an audio file
 func (a *App) GenerateWaveform(inputPath string) (string, error) {
-	tempDir := filepath.Join(os.TempDir(), "youflac", "waveforms")
+	tempDir := filepath.Join(backend.GetTempDirectory(), "waveforms")
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -0,0 +1,79 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Album art
+// =============================================================================
+
+// With a template that has an album folder ("{artist}/{album}/{title}"), the
+// tracks of an album share a directory. Instead of a poster per track, the
+// album cover from the audio service is downloaded once and written as
+// folder.jpg and cover.jpg at the album level, the names music libraries
+// (Jellyfin, Kodi, Plex, file managers) look for. Tracks in a disc folder
+// below it ("{album}/Disc {disc}/{title}") get a folder.jpg there too.
+
+// Album art file names
+const (
+	AlbumFolderArt = "folder.jpg"
+	AlbumCoverArt  = "cover.jpg"
+)
+
+// albumArtMu serializes album art writes so tracks of the same album
+// finishing together download the cover once
+var albumArtMu sync.Mutex
+
+// albumDirectory returns the directory the {album} folder of template
+// resolves to for outputPath, "" if the template has no album folder
+func albumDirectory(template, outputPath string) string {
+	segments := strings.Split(filepath.ToSlash(template), "/")
+	for i, segment := range segments[:len(segments)-1] {
+		if strings.Contains(segment, "{album}") {
+			dir := filepath.Dir(outputPath)
+			for range len(segments) - 2 - i {
+				dir = filepath.Dir(dir)
+			}
+			return dir
+		}
+	}
+	return ""
+}
+
+// WriteAlbumArt writes the album cover at coverURL as folder.jpg and
+// cover.jpg in albumDir, and as folder.jpg in trackDir when the track is in
+// a disc folder below it. Existing art is kept, so the cover is downloaded
+// once per album; without a cover URL or art nothing is written.
+func WriteAlbumArt(coverURL, albumDir, trackDir string) error {
+	albumArtMu.Lock()
+	defer albumArtMu.Unlock()
+
+	folderPath := filepath.Join(albumDir, AlbumFolderArt)
+	if !fileExists(folderPath) {
+		if coverURL == "" {
+			return nil
+		}
+		if err := DownloadPoster(coverURL, folderPath); err != nil {
+			return fmt.Errorf("album cover: %w", err)
+		}
+	}
+
+	targets := []string{filepath.Join(albumDir, AlbumCoverArt)}
+	if trackDir != "" && filepath.Clean(trackDir) != filepath.Clean(albumDir) {
+		targets = append(targets, filepath.Join(trackDir, AlbumFolderArt))
+	}
+	for _, target := range targets {
+		if fileExists(target) {
+			continue
+		}
+		if err := copyFile(folderPath, target); err != nil {
+			return fmt.Errorf("album cover: %w", err)
+		}
+		FinishOutputFile(target)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAlbumDirectory(t *testing.T) {
+	base := filepath.Join("/music", "Queen")
+	tests := []struct {
+		template, outputPath, want string
+	}{
+		{"{artist}/{album}/{title}", filepath.Join(base, "Innuendo", "Innuendo.mkv"), filepath.Join(base, "Innuendo")},
+		{"{artist}/{album}/Disc {disc}/{title}", filepath.Join(base, "Innuendo", "Disc 1", "Innuendo.mkv"), filepath.Join(base, "Innuendo")},
+		{"{artist}/{year} - {album}/{track} {title}", filepath.Join(base, "1991 - Innuendo", "01 Innuendo.mkv"), filepath.Join(base, "1991 - Innuendo")},
+		{"{artist}/{title}/{title}", filepath.Join(base, "Innuendo", "Innuendo.mkv"), ""},
+		{"{artist} - {album} - {title}", filepath.Join("/music", "Queen - Innuendo - Innuendo.mkv"), ""},
+	}
+	for _, tt := range tests {
+		if got := albumDirectory(tt.template, tt.outputPath); got != tt.want {
+			t.Errorf("albumDirectory(%q, %q) = %q, want %q", tt.template, tt.outputPath, got, tt.want)
+		}
+	}
+}
+
+func TestWriteAlbumArt(t *testing.T) {
+	fetches := fakeCoverCache(t, 0)
+	albumDir := filepath.Join(t.TempDir(), "Queen", "Innuendo")
+
+	// Two tracks of the album, the second on disc 2
+	if err := WriteAlbumArt("https://example.com/cover.jpg", albumDir, albumDir); err != nil {
+		t.Fatal(err)
+	}
+	discDir := filepath.Join(albumDir, "Disc 2")
+	if err := WriteAlbumArt("https://example.com/cover.jpg", albumDir, discDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(albumDir, AlbumFolderArt),
+		filepath.Join(albumDir, AlbumCoverArt),
+		filepath.Join(discDir, AlbumFolderArt),
+	} {
+		if !fileExists(path) {
+			t.Errorf("%s was not written", path)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("cover downloaded %d times, want once per album", n)
+	}
+}
+
+func TestWriteAlbumArt_NoCover(t *testing.T) {
+	fetches := fakeCoverCache(t, 0)
+	albumDir := t.TempDir()
+	if err := WriteAlbumArt("", albumDir, albumDir); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(filepath.Join(albumDir, AlbumFolderArt)) || fileExists(filepath.Join(albumDir, AlbumCoverArt)) || fetches.Load() != 0 {
+		t.Error("album art written without a cover")
+	}
+}
@@ -0,0 +1,354 @@
+package backend
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Album completeness
+// =============================================================================
+
+// When the audio of a single (non-playlist) item comes from a Tidal album,
+// the item records the album, its ID ("tidal:<id>"), the track and disc
+// number and the album's track count; they end up in the tags (ALBUM,
+// TIDAL_ALBUM_ID, TRACKNUMBER, TRACKTOTAL, DISCNUMBER) and the file index.
+// CheckAlbums groups the library by album ID and compares each album with
+// its track list on Tidal, and QueueMissingAlbumTracks queues the
+// tracks that are not in the library yet.
+
+// AlbumIDTag is the tag holding the Tidal album ID of a track
+const AlbumIDTag = "TIDAL_ALBUM_ID"
+
+// tidalAlbumPrefix starts the album IDs of Tidal albums
+const tidalAlbumPrefix = "tidal:"
+
+// TidalAlbum is an album with its track list
+type TidalAlbum struct {
+	ID             int    `json:"id"`
+	Title          string `json:"title"`
+	NumberOfTracks int    `json:"numberOfTracks"`
+	Artist         struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Tracks []TidalTrackResponse `json:"-"`
+}
+
+// tidalAlbumResponse is the /album/ response of the hifi API, with or
+// without the v2 "data" wrapper
+type tidalAlbumResponse struct {
+	TidalAlbum
+	Items []tidalAlbumItem `json:"items"`
+	Data  *struct {
+		TidalAlbum
+		Items []tidalAlbumItem `json:"items"`
+	} `json:"data"`
+}
+
+type tidalAlbumItem struct {
+	Item TidalTrackResponse `json:"item"`
+	Type string             `json:"type"`
+}
+
+// AlbumTrackStatus is one track of an album in a completeness report
+type AlbumTrackStatus struct {
+	Disc     int     `json:"disc,omitempty"`
+	Number   int     `json:"number"`
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist"`
+	ISRC     string  `json:"isrc,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	URL      string  `json:"url"`            // Tidal track page
+	Path     string  `json:"path,omitempty"` // Library file, "" when missing
+}
+
+// AlbumCompleteness is how much of an album the library holds
+type AlbumCompleteness struct {
+	AlbumID    string             `json:"albumId"`
+	Title      string             `json:"title"`
+	Artist     string             `json:"artist"`
+	TrackTotal int                `json:"trackTotal"`
+	Present    int                `json:"present"`
+	Complete   bool               `json:"complete"`
+	Tracks     []AlbumTrackStatus `json:"tracks,omitempty"`
+	Error      string             `json:"error,omitempty"` // Album lookup failed; only the library files are known
+}
+
+// MissingTracksResult is the result of QueueMissingAlbumTracks
+type MissingTracksResult struct {
+	Queued  []string `json:"queued"`            // Queue item IDs
+	Skipped []string `json:"skipped,omitempty"` // Tracks without a YouTube upload
+}
+
+// Album lookups are cached for the process lifetime: track lists rarely change
+var (
+	albumCacheMu sync.Mutex
+	albumCache   = make(map[int]*TidalAlbum)
+)
+
+// Tidal album lookup, replaced in tests
+var fetchTidalAlbum = func(albumID int) (*TidalAlbum, error) {
+	return NewTidalHifiService(httpClient).GetAlbum(albumID)
+}
+
+// tidalAlbumID returns the album ID of a Tidal track, "" if unknown
+func tidalAlbumID(track *TidalTrackResponse) string {
+	if track.Album.ID == 0 {
+		return ""
+	}
+	return tidalAlbumPrefix + strconv.Itoa(track.Album.ID)
+}
+
+// albumIDTagValue returns the AlbumIDTag value of an album ID, "" for
+// albums of other stores
+func albumIDTagValue(albumID string) string {
+	if id, ok := parseTidalAlbumID(albumID); ok {
+		return strconv.Itoa(id)
+	}
+	return ""
+}
+
+// parseTidalAlbumID returns the Tidal ID of a "tidal:<id>" album ID
+func parseTidalAlbumID(albumID string) (int, bool) {
+	rest, ok := strings.CutPrefix(albumID, tidalAlbumPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	return id, err == nil && id > 0
+}
+
+// GetAlbum fetches an album and its tracks by Tidal ID
+func (t *TidalHifiService) GetAlbum(albumID int) (*TidalAlbum, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/album/?id=%d", t.baseURL, albumID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("album request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("album request failed: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read album response: %w", err)
+	}
+
+	var parsed tidalAlbumResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse album response: %w", err)
+	}
+	album, items := parsed.TidalAlbum, parsed.Items
+	if parsed.Data != nil {
+		album, items = parsed.Data.TidalAlbum, parsed.Data.Items
+	}
+	for _, item := range items {
+		if item.Type == "" || item.Type == "track" {
+			album.Tracks = append(album.Tracks, item.Item)
+		}
+	}
+	if album.ID == 0 && len(album.Tracks) == 0 {
+		return nil, fmt.Errorf("album %d not found", albumID)
+	}
+	if album.NumberOfTracks == 0 {
+		album.NumberOfTracks = len(album.Tracks)
+	}
+	return &album, nil
+}
+
+// lookupAlbum returns an album by album ID, cached
+func lookupAlbum(albumID string) (*TidalAlbum, error) {
+	id, ok := parseTidalAlbumID(albumID)
+	if !ok {
+		return nil, fmt.Errorf("unsupported album ID %q", albumID)
+	}
+	albumCacheMu.Lock()
+	cached := albumCache[id]
+	albumCacheMu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	album, err := fetchTidalAlbum(id)
+	if err != nil {
+		return nil, err
+	}
+	albumCacheMu.Lock()
+	albumCache[id] = album
+	albumCacheMu.Unlock()
+	return album, nil
+}
+
+// CheckAlbums reports every album of the library with the tracks it has
+// and the ones it is missing, incomplete albums first
+func CheckAlbums(fileIndex *FileIndex) []AlbumCompleteness {
+	if fileIndex == nil {
+		return nil
+	}
+	byAlbum := make(map[string][]FileIndexEntry)
+	for _, entries := range fileIndex.snapshot() {
+		for _, entry := range entries {
+			if entry.AlbumID != "" {
+				byAlbum[entry.AlbumID] = append(byAlbum[entry.AlbumID], entry)
+			}
+		}
+	}
+
+	reports := make([]AlbumCompleteness, 0, len(byAlbum))
+	for albumID, files := range byAlbum {
+		reports = append(reports, albumCompleteness(albumID, files, fileIndex))
+	}
+	slices.SortFunc(reports, func(a, b AlbumCompleteness) int {
+		if a.Complete != b.Complete {
+			if a.Complete {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(cmp.Compare(strings.ToLower(a.Artist), strings.ToLower(b.Artist)), cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)))
+	})
+	return reports
+}
+
+// CheckAlbum reports one album of the library
+func CheckAlbum(fileIndex *FileIndex, albumID string) AlbumCompleteness {
+	var files []FileIndexEntry
+	if fileIndex != nil {
+		for _, entries := range fileIndex.snapshot() {
+			for _, entry := range entries {
+				if entry.AlbumID == albumID {
+					files = append(files, entry)
+				}
+			}
+		}
+	}
+	return albumCompleteness(albumID, files, fileIndex)
+}
+
+// albumCompleteness compares the library files of an album with its track
+// list. A track counts as present when a file has its ISRC or, failing that,
+// its title and artist.
+func albumCompleteness(albumID string, files []FileIndexEntry, fileIndex *FileIndex) AlbumCompleteness {
+	report := AlbumCompleteness{AlbumID: albumID, Present: len(files)}
+	if len(files) > 0 {
+		report.Title, report.Artist = files[0].Album, files[0].Artist
+	}
+
+	album, err := lookupAlbum(albumID)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Title = cmp.Or(album.Title, report.Title)
+	report.Artist = cmp.Or(album.Artist.Name, report.Artist)
+	report.TrackTotal = max(album.NumberOfTracks, len(album.Tracks))
+	report.Present = 0
+
+	for i := range album.Tracks {
+		track := &album.Tracks[i]
+		status := AlbumTrackStatus{
+			Disc:     track.Volume,
+			Number:   track.TrackNumber,
+			Title:    track.Title,
+			Artist:   tidalArtistName(track),
+			ISRC:     track.ISRC,
+			Duration: float64(track.Duration),
+			URL:      fmt.Sprintf("https://tidal.com/browse/track/%d", track.ID),
+		}
+		var found *FileIndexEntry
+		if status.ISRC != "" && fileIndex != nil {
+			found = fileIndex.FindByISRC(status.ISRC)
+		}
+		if found == nil && fileIndex != nil {
+			found = fileIndex.FindMatch(status.Title, status.Artist, status.Duration)
+		}
+		if found != nil {
+			status.Path = found.Path
+			report.Present++
+		}
+		report.Tracks = append(report.Tracks, status)
+	}
+	report.Complete = report.TrackTotal > 0 && report.Present >= report.TrackTotal
+	return report
+}
+
+// recordAlbum stores the album the audio of a single item came from: name
+// (unless the item has its own), album ID, track and disc number and the
+// album's track count
+func (q *Queue) recordAlbum(id string, track *AudioTrackInfo, config *Config) {
+	if track.Album == "" {
+		return
+	}
+	album := RewriteMetadata(MetadataRulesFromConfig(config), "", "", track.Album).Album
+	total := 0
+	if track.AlbumID != "" {
+		if found, err := lookupAlbum(track.AlbumID); err == nil {
+			total = found.NumberOfTracks
+		} else {
+			slog.Debug("album lookup failed", "album", track.AlbumID, "err", err)
+		}
+	}
+	q.updateItem(id, func(item *QueueItem) {
+		if item.Album != "" && !strings.EqualFold(item.Album, album) {
+			return // Imported with another album: keep it, the IDs would not match
+		}
+		item.Album = album
+		item.AlbumID = track.AlbumID
+		item.TrackNumber = track.TrackNumber
+		if item.Disc == 0 {
+			item.Disc = track.DiscNumber
+		}
+		if item.TrackTotal == 0 {
+			item.TrackTotal = total
+		}
+	})
+}
+
+// QueueMissingAlbumTracks queues every track of an album that is not in the
+// library. Each track is queued with its Tidal page as the audio source and
+// the YouTube upload SelectVideoVariant finds for it.
+func (q *Queue) QueueMissingAlbumTracks(albumID string) (*MissingTracksResult, error) {
+	q.mutex.RLock()
+	fileIndex := q.fileIndex
+	config := q.configs.Get()
+	q.mutex.RUnlock()
+
+	report := CheckAlbum(fileIndex, albumID)
+	if report.Error != "" {
+		return nil, fmt.Errorf("failed to look up album: %s", report.Error)
+	}
+
+	result := &MissingTracksResult{Queued: []string{}}
+	for _, track := range report.Tracks {
+		if track.Path != "" {
+			continue
+		}
+		video := SelectVideoVariant(&VideoInfo{Title: track.Title, Artist: track.Artist, Duration: track.Duration}, "", config)
+		if video == "" {
+			result.Skipped = append(result.Skipped, track.Artist+" - "+track.Title)
+			continue
+		}
+		id, err := q.AddToQueue(DownloadRequest{
+			VideoURL:   video,
+			SpotifyURL: track.URL,
+			Notes:      "Missing from album " + report.Title,
+		})
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s - %s (%v)", track.Artist, track.Title, err))
+			continue
+		}
+		result.Queued = append(result.Queued, id)
+	}
+	return result, nil
+}
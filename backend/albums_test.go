@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// stubTidalAlbum serves album 42 (three tracks) from fetchTidalAlbum and
+// clears the album cache around the test
+func stubTidalAlbum(t *testing.T) *int {
+	t.Helper()
+	orig := fetchTidalAlbum
+	fetches := 0
+	fetchTidalAlbum = func(albumID int) (*TidalAlbum, error) {
+		fetches++
+		if albumID != 42 {
+			return nil, fmt.Errorf("album %d not found", albumID)
+		}
+		album := &TidalAlbum{ID: 42, Title: "Record", NumberOfTracks: 3}
+		album.Artist.Name = "Artist"
+		for i, title := range []string{"One", "Two", "Three"} {
+			track := TidalTrackResponse{ID: 100 + i, Title: title, TrackNumber: i + 1, Volume: 1, ISRC: fmt.Sprintf("USXX1000000%d", i+1)}
+			track.Artist.Name = "Artist"
+			album.Tracks = append(album.Tracks, track)
+		}
+		return album, nil
+	}
+	resetCache := func() {
+		albumCacheMu.Lock()
+		albumCache = make(map[int]*TidalAlbum)
+		albumCacheMu.Unlock()
+	}
+	resetCache()
+	t.Cleanup(func() {
+		fetchTidalAlbum = orig
+		resetCache()
+	})
+	return &fetches
+}
+
+func TestTidalAlbumIDs(t *testing.T) {
+	track := &TidalTrackResponse{}
+	if got := tidalAlbumID(track); got != "" {
+		t.Errorf("tidalAlbumID without album = %q", got)
+	}
+	track.Album.ID = 42
+	if got := tidalAlbumID(track); got != "tidal:42" {
+		t.Errorf("tidalAlbumID = %q", got)
+	}
+	if got := albumIDTagValue("tidal:42"); got != "42" {
+		t.Errorf("albumIDTagValue = %q", got)
+	}
+	for _, id := range []string{"", "42", "tidal:", "tidal:x", "qobuz:42"} {
+		if got := albumIDTagValue(id); got != "" {
+			t.Errorf("albumIDTagValue(%q) = %q, want empty", id, got)
+		}
+	}
+}
+
+func TestTidalGetAlbum(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/album/" || r.URL.Query().Get("id") != "42" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"id":42,"title":"Record","numberOfTracks":2,"artist":{"name":"Artist"},"items":[
+			{"type":"track","item":{"id":1,"title":"One","trackNumber":1,"volumeNumber":1}},
+			{"type":"video","item":{"id":2,"title":"One (Video)"}},
+			{"type":"track","item":{"id":3,"title":"Two","trackNumber":2,"volumeNumber":1}}]}}`)
+	}))
+	defer ts.Close()
+
+	album, err := newTidalSvc(ts).GetAlbum(42)
+	if err != nil {
+		t.Fatalf("GetAlbum: %v", err)
+	}
+	if album.Title != "Record" || album.Artist.Name != "Artist" || album.NumberOfTracks != 2 {
+		t.Errorf("album = %+v", album)
+	}
+	if len(album.Tracks) != 2 || album.Tracks[1].Title != "Two" || album.Tracks[1].Volume != 1 {
+		t.Errorf("tracks = %+v, want the two tracks without the video", album.Tracks)
+	}
+
+	if _, err := newTidalSvc(ts).GetAlbum(7); err == nil {
+		t.Error("GetAlbum of an unknown album succeeded")
+	}
+}
+
+func TestCheckAlbums(t *testing.T) {
+	fetches := stubTidalAlbum(t)
+
+	dir := t.TempDir()
+	one, three := filepath.Join(dir, "one.mkv"), filepath.Join(dir, "three.mkv")
+	writeTestFile(t, one, 10)
+	writeTestFile(t, three, 10)
+	fi := NewFileIndex(dir)
+	fi.AddEntry(FileIndexEntry{Path: one, Title: "One (Remastered)", Artist: "Artist", Album: "Record", AlbumID: "tidal:42", ISRC: "USXX10000001"})
+	fi.AddEntry(FileIndexEntry{Path: three, Title: "Three", Artist: "Artist", Album: "Record", AlbumID: "tidal:42"})
+	fi.AddEntry(FileIndexEntry{Path: filepath.Join(dir, "gone.mkv"), Title: "Gone", Artist: "Other", Album: "Lost", AlbumID: "tidal:7"})
+	fi.AddEntry(FileIndexEntry{Path: filepath.Join(dir, "single.mkv"), Title: "Single", Artist: "Other"})
+
+	reports := CheckAlbums(fi)
+	if len(reports) != 2 {
+		t.Fatalf("got %d albums, want 2: %+v", len(reports), reports)
+	}
+	var record, lost AlbumCompleteness
+	for _, r := range reports {
+		switch r.AlbumID {
+		case "tidal:42":
+			record = r
+		case "tidal:7":
+			lost = r
+		}
+	}
+
+	if record.Title != "Record" || record.TrackTotal != 3 || record.Present != 2 || record.Complete {
+		t.Errorf("record = %+v, want 2 of 3 tracks", record)
+	}
+	if len(record.Tracks) != 3 || record.Tracks[0].Path != one || record.Tracks[1].Path != "" || record.Tracks[2].Path != three {
+		t.Errorf("record tracks = %+v", record.Tracks)
+	}
+	if lost.Error == "" || lost.Title != "Lost" || lost.Present != 1 {
+		t.Errorf("failed lookup = %+v, want the library files and an error", lost)
+	}
+
+	CheckAlbums(fi)
+	if *fetches != 3 {
+		t.Errorf("%d album fetches, want 3 (found albums cached)", *fetches)
+	}
+}
+
+func TestQueueMissingAlbumTracks(t *testing.T) {
+	stubTidalAlbum(t)
+	origSearch := searchVideoVariants
+	t.Cleanup(func() { searchVideoVariants = origSearch })
+	searchVideoVariants = func(query string, maxResults int, cookiesBrowser string) ([]VideoInfo, error) {
+		if query != "Artist Two" {
+			return nil, nil
+		}
+		return []VideoInfo{{ID: "two", Title: "Two", Artist: "Artist", URL: "https://www.youtube.com/watch?v=two"}}, nil
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "one.mkv"), 10)
+	fi := NewFileIndex(dir)
+	fi.AddEntry(FileIndexEntry{Path: filepath.Join(dir, "one.mkv"), Title: "One", Artist: "Artist", AlbumID: "tidal:42", ISRC: "USXX10000001"})
+	q := newTestQueue()
+	q.SetFileIndex(fi)
+
+	result, err := q.QueueMissingAlbumTracks("tidal:42")
+	if err != nil {
+		t.Fatalf("QueueMissingAlbumTracks: %v", err)
+	}
+	if len(result.Queued) != 1 || len(result.Skipped) != 1 || result.Skipped[0] != "Artist - Three" {
+		t.Fatalf("result = %+v, want Two queued and Three skipped", result)
+	}
+	item := q.GetItem(result.Queued[0])
+	if item == nil || item.VideoURL != "https://www.youtube.com/watch?v=two" || item.SpotifyURL != "https://tidal.com/browse/track/101" {
+		t.Errorf("queued item = %+v", item)
+	}
+
+	if _, err := q.QueueMissingAlbumTracks("tidal:7"); err == nil {
+		t.Error("QueueMissingAlbumTracks of an unknown album succeeded")
+	}
+}
+
+func TestRecordAlbum(t *testing.T) {
+	stubTidalAlbum(t)
+	q := newTestQueue()
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := GetDefaultConfig()
+	config.MetadataRules = []string{`album:\s*\(Deluxe\)=`}
+
+	q.recordAlbum(id, &AudioTrackInfo{Album: "Record (Deluxe)", AlbumID: "tidal:42", TrackNumber: 2, DiscNumber: 1}, config)
+	item := q.GetItem(id)
+	if item.Album != "Record" || item.AlbumID != "tidal:42" || item.TrackNumber != 2 || item.Disc != 1 || item.TrackTotal != 3 {
+		t.Errorf("item = album %q (%s) track %d/%d disc %d", item.Album, item.AlbumID, item.TrackNumber, item.TrackTotal, item.Disc)
+	}
+
+	// An item imported with another album keeps it
+	q.updateItem(id, func(item *QueueItem) { item.Album, item.AlbumID = "Compilation", "" })
+	q.recordAlbum(id, &AudioTrackInfo{Album: "Record", AlbumID: "tidal:42", TrackNumber: 2}, config)
+	if item := q.GetItem(id); item.Album != "Compilation" || item.AlbumID != "" {
+		t.Errorf("item = album %q (%s), want the imported album kept", item.Album, item.AlbumID)
+	}
+}
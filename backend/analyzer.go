@@ -84,7 +84,7 @@ func (a *AudioAnalysis) fetchProbeData() error {
 		a.FilePath,
 	}
 
-	cmd := exec.Command(ffprobePath, args...)
+	cmd := newCommand(ffprobePath, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -223,7 +223,7 @@ func (a *AudioAnalysis) detectFakeLossless() {
 		"-",
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommand(ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -373,7 +373,7 @@ func GenerateSpectrogram(inputPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommand(ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -401,7 +401,7 @@ func GenerateWaveform(inputPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommand(ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -423,7 +423,7 @@ func GetAudioFingerprint(filePath string) (string, error) {
 
 	args := []string{"-json", filePath}
 
-	cmd := exec.Command(fpcalcPath, args...)
+	cmd := newCommand(fpcalcPath, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Per-artist output directories
+// =============================================================================
+
+// Config.ArtistPathOverrides sends some artists to another base directory,
+// e.g. ["Pink Floyd=/mnt/archive/music", "the *=/mnt/b/music"]. The pattern
+// is matched case-insensitively against the album artist, then the track
+// artist; "*", "?" and "[...]" work as in path.Match. The first matching
+// entry wins. The playlist folder and naming template are applied below the
+// override directory exactly as below OutputDirectory.
+
+// parseArtistPathOverride splits "pattern=directory"
+func parseArtistPathOverride(entry string) (pattern, dir string, ok bool) {
+	pattern, dir, ok = strings.Cut(entry, "=")
+	pattern = strings.TrimSpace(pattern)
+	dir = strings.TrimSpace(dir)
+	return pattern, dir, ok && pattern != "" && dir != ""
+}
+
+// matchArtistPattern reports whether artist matches an override pattern
+func matchArtistPattern(pattern, artist string) bool {
+	pattern = strings.ToLower(pattern)
+	artist = strings.ToLower(strings.TrimSpace(artist))
+	if artist == "" {
+		return false
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		matched, err := path.Match(pattern, artist)
+		return err == nil && matched
+	}
+	return pattern == artist
+}
+
+// ArtistOutputDirectory returns the override base directory for the artist
+// of metadata, or "" when no override matches
+func ArtistOutputDirectory(metadata *Metadata, config *Config) string {
+	if config == nil || metadata == nil || len(config.ArtistPathOverrides) == 0 {
+		return ""
+	}
+	for _, entry := range config.ArtistPathOverrides {
+		pattern, dir, ok := parseArtistPathOverride(entry)
+		if !ok {
+			continue
+		}
+		if matchArtistPattern(pattern, metadata.AlbumArtist) || matchArtistPattern(pattern, metadata.Artist) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// LibraryDirectories returns OutputDirectory followed by every distinct
+// library (see libraries.go) and override directory, i.e. all roots the
+// library is spread over
+func LibraryDirectories(config *Config) []string {
+	root := config.OutputDirectory
+	if root == "" {
+		root = GetDefaultOutputDirectory()
+	}
+	dirs := []string{root}
+	for _, lib := range LibrariesFromConfig(config) {
+		if !containsString(dirs, lib.Directory) {
+			dirs = append(dirs, lib.Directory)
+		}
+	}
+	for _, entry := range config.ArtistPathOverrides {
+		_, dir, ok := parseArtistPathOverride(entry)
+		if ok && !containsString(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// outermostDirectories drops the directories of dirs that lie inside
+// another one, so walking the result visits every file once
+func outermostDirectories(dirs []string) []string {
+	var roots []string
+	for _, dir := range dirs {
+		nested := false
+		for _, other := range dirs {
+			if rel, err := filepath.Rel(other, dir); err == nil && other != dir && rel != "." && !strings.HasPrefix(rel, "..") {
+				nested = true
+				break
+			}
+		}
+		if !nested && !containsString(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	return roots
+}
+
+// libraryRootFor returns the library directory that contains file, so paths
+// relative to the library stay the same for artists stored elsewhere
+func libraryRootFor(config *Config, file string) string {
+	dirs := LibraryDirectories(config)
+	for _, dir := range dirs[1:] {
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return dir
+		}
+	}
+	return dirs[0]
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestArtistOutputDirectory(t *testing.T) {
+	config := &Config{
+		OutputDirectory:     "/music",
+		ArtistPathOverrides: []string{"Pink Floyd=/mnt/archive", "the *=/mnt/the", "broken"},
+	}
+	tests := []struct {
+		metadata Metadata
+		want     string
+	}{
+		{Metadata{Artist: "pink floyd"}, "/mnt/archive"},
+		{Metadata{Artist: "David Gilmour", AlbumArtist: "Pink Floyd"}, "/mnt/archive"},
+		{Metadata{Artist: "The Cure"}, "/mnt/the"},
+		{Metadata{Artist: "Pink Floyd Tribute"}, ""},
+		{Metadata{}, ""},
+	}
+	for _, tt := range tests {
+		if got := ArtistOutputDirectory(&tt.metadata, config); got != tt.want {
+			t.Errorf("ArtistOutputDirectory(%+v) = %q, want %q", tt.metadata, got, tt.want)
+		}
+	}
+
+	item := &QueueItem{PlaylistName: "Mix"}
+	if got := planOutputDir(item, &Metadata{Artist: "The Cure"}, config); got != filepath.Join("/mnt/the", "Mix") {
+		t.Errorf("planOutputDir = %q", got)
+	}
+	if got := planOutputDir(&QueueItem{}, &Metadata{Artist: "Björk"}, config); got != "/music" {
+		t.Errorf("planOutputDir = %q", got)
+	}
+}
+
+func TestLibraryDirectories(t *testing.T) {
+	config := &Config{
+		OutputDirectory:     "/music",
+		ArtistPathOverrides: []string{"a=/mnt/x", "b=/mnt/x", "c=/mnt/y"},
+	}
+	dirs := LibraryDirectories(config)
+	if len(dirs) != 3 || dirs[0] != "/music" || dirs[1] != "/mnt/x" || dirs[2] != "/mnt/y" {
+		t.Errorf("dirs = %v", dirs)
+	}
+	if got := libraryRootFor(config, "/mnt/y/C/song.flac"); got != "/mnt/y" {
+		t.Errorf("root = %q", got)
+	}
+	if got := libraryRootFor(config, "/music/A/song.flac"); got != "/music" {
+		t.Errorf("root = %q", got)
+	}
+}
+
+func TestConfigValidate_ArtistPathOverrides(t *testing.T) {
+	c := GetDefaultConfig()
+	c.ArtistPathOverrides = []string{" Pink Floyd = /mnt/archive ", "nodir", "bad[=/mnt/x", ""}
+	v := c.Validate()
+	if len(c.ArtistPathOverrides) != 1 || c.ArtistPathOverrides[0] != "Pink Floyd=/mnt/archive" {
+		t.Errorf("overrides = %q", c.ArtistPathOverrides)
+	}
+	if len(v.Warnings) != 2 {
+		t.Errorf("warnings = %+v", v.Warnings)
+	}
+}
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Multi-artist credit handling. A credit like "Artist A x Artist B feat. C" is
+// kept intact for the ARTIST tag, while ALBUMARTIST and the {albumartist}
+// placeholder use a stable primary artist so folders don't fragment.
+
+// Artist policies accepted in Config.AlbumArtistPolicy
+const (
+	ArtistPolicyFull  = "full"  // Keep the credit as-is: "A x B feat. C"
+	ArtistPolicyMain  = "main"  // Drop featured artists: "A x B"
+	ArtistPolicyFirst = "first" // First credited artist only: "A"
+)
+
+// VariousArtists is the album artist used for compilations
+const VariousArtists = "Various Artists"
+
+// featuredArtistPattern matches the start of a featured-artist suffix
+var featuredArtistPattern = regexp.MustCompile(`(?i)\s*[\(\[]?\s*\b(feat\.?|ft\.?|featuring|with)\s+`)
+
+// artistSeparatorPattern matches separators between co-credited artists
+var artistSeparatorPattern = regexp.MustCompile(`(?i)\s+(?:x|&|vs\.?|/)\s+|\s*[,;]\s*`)
+
+// IsVariousArtists reports whether the credit denotes a compilation
+func IsVariousArtists(artist string) bool {
+	switch strings.ToLower(strings.TrimSpace(artist)) {
+	case "various artists", "various", "va", "v.a.":
+		return true
+	}
+	return false
+}
+
+// SplitArtists returns every artist named in a credit, main artists first,
+// without duplicates. "A x B feat. C" -> ["A", "B", "C"].
+func SplitArtists(credit string) []string {
+	credit = strings.TrimSpace(credit)
+	if credit == "" {
+		return nil
+	}
+	if IsVariousArtists(credit) {
+		return []string{VariousArtists}
+	}
+
+	main, featured := splitFeatured(credit)
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range append(artistSeparatorPattern.Split(main, -1), artistSeparatorPattern.Split(featured, -1)...) {
+		name := strings.TrimSpace(strings.Trim(strings.TrimSpace(part), "()[]"))
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// PrimaryArtist reduces a credit according to an artist policy.
+// Unknown policies behave like ArtistPolicyFull.
+func PrimaryArtist(credit, policy string) string {
+	credit = strings.TrimSpace(credit)
+	if credit == "" || IsVariousArtists(credit) {
+		return credit
+	}
+
+	switch policy {
+	case ArtistPolicyMain:
+		main, _ := splitFeatured(credit)
+		return main
+	case ArtistPolicyFirst:
+		if names := SplitArtists(credit); len(names) > 0 {
+			return names[0]
+		}
+	}
+	return credit
+}
+
+// ResolveAlbumArtist picks the album artist for a track: an explicit album
+// artist wins, otherwise the track credit is reduced with the policy
+func ResolveAlbumArtist(albumArtist, credit, policy string) string {
+	if albumArtist = strings.TrimSpace(albumArtist); albumArtist != "" {
+		if IsVariousArtists(albumArtist) {
+			return VariousArtists
+		}
+		return albumArtist
+	}
+	return PrimaryArtist(credit, policy)
+}
+
+// splitFeatured separates "A x B feat. C" into "A x B" and "C"
+func splitFeatured(credit string) (main, featured string) {
+	loc := featuredArtistPattern.FindStringIndex(credit)
+	if loc == nil || loc[0] == 0 {
+		return strings.TrimSpace(credit), ""
+	}
+	main = strings.TrimSpace(credit[:loc[0]])
+	featured = strings.TrimSpace(strings.TrimRight(credit[loc[1]:], ")] "))
+	return main, featured
+}
+
+// ApplyArtistCredit fills AlbumArtist and Artists from the track credit using
+// the configured policies. With FirstArtistOnly the ARTIST tag also drops
+// featured artists.
+func ApplyArtistCredit(metadata *Metadata, albumArtist string, config *Config) {
+	credit := metadata.Artist
+	policy := ArtistPolicyMain
+	if config != nil {
+		if config.AlbumArtistPolicy != "" {
+			policy = config.AlbumArtistPolicy
+		}
+		if config.FirstArtistOnly {
+			metadata.Artist = PrimaryArtist(credit, ArtistPolicyMain)
+		}
+	}
+	metadata.Artists = SplitArtists(credit)
+	metadata.AlbumArtist = ResolveAlbumArtist(albumArtist, credit, policy)
+}
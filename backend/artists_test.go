@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArtists(t *testing.T) {
+	tests := []struct {
+		credit string
+		want   []string
+	}{
+		{"Rick Astley", []string{"Rick Astley"}},
+		{"Artist A x Artist B", []string{"Artist A", "Artist B"}},
+		{"Artist A feat. Artist C", []string{"Artist A", "Artist C"}},
+		{"A & B (feat. C, D)", []string{"A", "B", "C", "D"}},
+		{"Various Artists", []string{VariousArtists}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitArtists(tt.credit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArtists(%q) = %v, want %v", tt.credit, got, tt.want)
+		}
+	}
+}
+
+func TestPrimaryArtist(t *testing.T) {
+	credit := "Artist A x Artist B feat. Artist C"
+	tests := map[string]string{
+		ArtistPolicyFull:  credit,
+		ArtistPolicyMain:  "Artist A x Artist B",
+		ArtistPolicyFirst: "Artist A",
+	}
+	for policy, want := range tests {
+		if got := PrimaryArtist(credit, policy); got != want {
+			t.Errorf("PrimaryArtist(%q, %q) = %q, want %q", credit, policy, got, want)
+		}
+	}
+}
+
+func TestApplyArtistCredit(t *testing.T) {
+	config := &Config{AlbumArtistPolicy: ArtistPolicyFirst}
+
+	meta := &Metadata{Artist: "Artist A x Artist B"}
+	ApplyArtistCredit(meta, "", config)
+	if meta.AlbumArtist != "Artist A" {
+		t.Errorf("AlbumArtist = %q, want %q", meta.AlbumArtist, "Artist A")
+	}
+	if len(meta.Artists) != 2 {
+		t.Errorf("expected 2 artists, got %v", meta.Artists)
+	}
+	if meta.Artist != "Artist A x Artist B" {
+		t.Errorf("ARTIST tag should keep the full credit, got %q", meta.Artist)
+	}
+
+	compilation := &Metadata{Artist: "Artist A feat. Artist C"}
+	ApplyArtistCredit(compilation, "various", &Config{FirstArtistOnly: true})
+	if compilation.AlbumArtist != VariousArtists {
+		t.Errorf("AlbumArtist = %q, want %q", compilation.AlbumArtist, VariousArtists)
+	}
+	if compilation.Artist != "Artist A" {
+		t.Errorf("FirstArtistOnly should strip featured artists, got %q", compilation.Artist)
+	}
+}
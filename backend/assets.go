@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"sync"
+)
+
+// maxAssetFetches bounds the concurrent asset jobs of one queue item
+const maxAssetFetches = 3
+
+// AssetWarning records an optional asset (thumbnail, lyrics, NFO, poster)
+// that could not be fetched or written. The item still completes.
+type AssetWarning struct {
+	Asset   string `json:"asset"`
+	Message string `json:"message"`
+}
+
+// assetGroup runs the independent asset jobs of one item concurrently, at
+// most limit at a time. Failures never cancel the other jobs; they are
+// collected as warnings instead.
+type assetGroup struct {
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	mu      sync.Mutex
+	results []*AssetWarning // One slot per job, in start order
+}
+
+func newAssetGroup(limit int) *assetGroup {
+	if limit < 1 {
+		limit = 1
+	}
+	return &assetGroup{sem: make(chan struct{}, limit)}
+}
+
+// Go starts job in the background. The returned channel is closed when the
+// job has finished, for callers that need its result before Wait.
+func (g *assetGroup) Go(asset string, job func() error) <-chan struct{} {
+	done := make(chan struct{})
+
+	g.mu.Lock()
+	slot := len(g.results)
+	g.results = append(g.results, nil)
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer close(done)
+
+		g.sem <- struct{}{}
+		err := job()
+		<-g.sem
+
+		if err != nil {
+			g.mu.Lock()
+			g.results[slot] = &AssetWarning{Asset: asset, Message: err.Error()}
+			g.mu.Unlock()
+		}
+	}()
+	return done
+}
+
+// Wait waits for every job started so far and returns the warnings of the
+// failed ones in start order. Later calls only return new warnings.
+func (g *assetGroup) Wait() []AssetWarning {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var warnings []AssetWarning
+	for _, w := range g.results {
+		if w != nil {
+			warnings = append(warnings, *w)
+		}
+	}
+	g.results = g.results[:0]
+	return warnings
+}
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAssetGroup_BoundsConcurrency(t *testing.T) {
+	g := newAssetGroup(2)
+	var running, peak atomic.Int32
+	for range 6 {
+		g.Go("job", func() error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	if warnings := g.Wait(); len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", p)
+	}
+}
+
+func TestAssetGroup_FailuresAreWarnings(t *testing.T) {
+	g := newAssetGroup(maxAssetFetches)
+	var posterRan atomic.Bool
+	g.Go("thumbnail", func() error {
+		time.Sleep(10 * time.Millisecond) // Finishes last, still reported first
+		return errors.New("404")
+	})
+	g.Go("lyrics", func() error { return errors.New("not found") })
+	g.Go("poster", func() error {
+		posterRan.Store(true)
+		return nil
+	})
+
+	warnings := g.Wait()
+	want := []AssetWarning{{Asset: "thumbnail", Message: "404"}, {Asset: "lyrics", Message: "not found"}}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings = %v, want %v", warnings, want)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warnings[%d] = %v, want %v", i, warnings[i], want[i])
+		}
+	}
+	if !posterRan.Load() {
+		t.Error("a failed job stopped the others")
+	}
+	if again := g.Wait(); len(again) != 0 {
+		t.Errorf("second Wait returned %v, want no new warnings", again)
+	}
+}
+
+func TestAssetGroup_DoneChannel(t *testing.T) {
+	g := newAssetGroup(1)
+	var value string
+	done := g.Go("cover", func() error {
+		value = "cover.jpg"
+		return nil
+	})
+	<-done
+	if value != "cover.jpg" {
+		t.Errorf("value = %q after done, want cover.jpg", value)
+	}
+	g.Wait()
+}
+
+func TestWriteLyrics_LRCMode(t *testing.T) {
+	dir := t.TempDir()
+	media := filepath.Join(dir, "Artist - Title.mkv")
+
+	synced := &LyricsResult{SyncedLyrics: "[00:01.00]Hello", HasSync: true}
+	if err := writeLyrics(media, synced, ""); err != nil {
+		t.Fatalf("writeLyrics() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Artist - Title.lrc")); err != nil {
+		t.Errorf("LRC file not written: %v", err)
+	}
+
+	plain := &LyricsResult{PlainText: "Hello"}
+	if err := writeLyrics(media, plain, LyricsEmbedLRC); err != nil {
+		t.Fatalf("writeLyrics() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Artist - Title.txt")); err != nil {
+		t.Errorf("TXT file not written: %v", err)
+	}
+}
+
+func TestWriteLyrics_EmbedFailureIsReported(t *testing.T) {
+	media := filepath.Join(t.TempDir(), "missing.flac")
+	lyrics := &LyricsResult{PlainText: "Hello"}
+	if err := writeLyrics(media, lyrics, LyricsEmbedFile); err == nil {
+		t.Error("writeLyrics() on a missing file should fail")
+	}
+}
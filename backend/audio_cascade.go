@@ -0,0 +1,271 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// =============================================================================
+// Audio source cascade
+// =============================================================================
+
+// AudioCascade finds lossless audio for one track: song.link resolves the
+// source URL to streaming links, each source in priority order goes through
+// the service chain (TidalHifi for Tidal links, then Lucida, then OrpheusDL)
+// and a Tidal search by artist and title is the last resort. When all of it
+// fails the caller extracts the audio from the video instead.
+//
+// The queue processor runs it for every item; cmd/cascade-test runs it on
+// its own with a Trace to show why a track ends up with extracted audio.
+type AudioCascade struct {
+	Sources          []string // Source order, e.g. ["tidal", "qobuz", "amazon", "deezer"]
+	TidalSearch      bool     // Search Tidal by artist and title when no link worked
+	PreferredQuality string   // Config.PreferredQuality, to flag downgrades
+	TempDir          string   // Where downloaded audio is written
+
+	// OnStage is called before each step (optional)
+	OnStage func(progress int, code StageCode, params ...string)
+	// OnProgress is called as audio files download (optional)
+	OnProgress func(DownloadProgress)
+	// Trace records every decision with its timing (optional)
+	Trace *CascadeTrace
+
+	tidal   *TidalHifiService
+	lucida  *LucidaService
+	orpheus *OrpheusDLService
+}
+
+// AudioCascadeResult is the outcome of AudioCascade.Run
+type AudioCascadeResult struct {
+	Audio         *AudioDownloadResult // nil when no service delivered audio
+	Source        string               // tidal, qobuz, amazon, deezer or tidal-search
+	Service       string               // tidal-hifi, lucida or orpheusdl
+	ISRC          string               // From the downloaded track, else from song.link
+	TrackURL      string               // Store page of the downloaded track
+	TidalTrackURL string               // Tidal track the audio came from, for surround mixes
+	SourcesTried  []string
+	Candidates    []AudioCandidate // song.link candidates for diagnostics
+}
+
+// NewAudioCascade builds the download services with the proxy and timeout from config
+func NewAudioCascade(config *Config, tempDir string) *AudioCascade {
+	timeoutMinutes := config.DownloadTimeoutMinutes
+	if timeoutMinutes <= 0 {
+		timeoutMinutes = 10
+	}
+	downloadTimeout := time.Duration(timeoutMinutes * float64(time.Minute))
+	httpClient, err := NewHTTPClient(downloadTimeout, config.ProxyURL)
+	if err != nil {
+		slog.Warn("failed to create HTTP client with proxy, falling back to default", "err", err)
+		httpClient, _ = NewHTTPClient(downloadTimeout, "")
+	}
+	tidal := NewTidalHifiService(httpClient)
+	tidal.SetQuality(TidalQualityForPreference(config.PreferredQuality))
+	tidal.SetExplicitPreference(config.ExplicitPreference)
+	tidal.SetConnections(config.DownloadConnections)
+	lucida := NewLucidaService(httpClient)
+	lucida.SetConnections(config.DownloadConnections)
+
+	return &AudioCascade{
+		Sources:          config.AudioSourcePriority,
+		TidalSearch:      true,
+		PreferredQuality: config.PreferredQuality,
+		TempDir:          tempDir,
+		tidal:            tidal,
+		lucida:           lucida,
+		orpheus:          NewOrpheusDLService(),
+	}
+}
+
+// TidalService returns the TidalHifi service used by the cascade
+func (c *AudioCascade) TidalService() *TidalHifiService {
+	return c.tidal
+}
+
+func (c *AudioCascade) stage(progress int, code StageCode, params ...string) {
+	if c.OnStage != nil {
+		c.OnStage(progress, code, params...)
+	}
+}
+
+// Run tries every source for the track at sourceURL (a YouTube or streaming
+// URL, may be empty) and then the Tidal search for artist and title. It
+// stops early when ctx is cancelled; check ctx.Err() before using the result.
+func (c *AudioCascade) Run(ctx context.Context, sourceURL, artist, title string) *AudioCascadeResult {
+	res := &AudioCascadeResult{}
+	defer func() { c.Trace.finish(res) }()
+	c.tidal.SetDownloadContext(ctx, c.OnProgress)
+	c.lucida.SetDownloadContext(ctx, c.OnProgress)
+
+	if sourceURL != "" {
+		c.stage(45, StageResolvingSources)
+		res.SourcesTried = append(res.SourcesTried, "song.link")
+
+		slog.Debug("calling ResolveMusicURL", "url", sourceURL)
+		start := time.Now()
+		links, err := ResolveMusicURL(sourceURL)
+		slog.Debug("ResolveMusicURL result", "err", err, "hasLinks", links != nil)
+		if err != nil || links == nil {
+			c.Trace.record(start, CascadeEvent{Step: "resolve", Service: "song.link", URL: sourceURL, Decision: DecisionFailed}, err)
+		} else {
+			res.Candidates = buildCandidatesFromSongLink(links)
+			res.ISRC = links.ISRC
+			c.Trace.record(start, CascadeEvent{
+				Step:     "resolve",
+				Service:  "song.link",
+				URL:      sourceURL,
+				Decision: DecisionOK,
+				Detail:   fmt.Sprintf("%d candidate(s), ISRC %q", len(res.Candidates), links.ISRC),
+			}, nil)
+
+			for _, source := range c.Sources {
+				if ctx.Err() != nil {
+					return res
+				}
+				if c.trySource(source, sourceLinkURL(links, source), res) {
+					return res
+				}
+			}
+		}
+	}
+
+	// If songlink resolution failed or no FLAC sources found, try TidalHifi search
+	switch {
+	case !c.TidalSearch:
+		c.Trace.skip("tidal_search", "tidal-hifi", "tidal is not in the item's source list")
+	case artist == "" || title == "":
+		c.Trace.skip("tidal_search", "tidal-hifi", "artist or title unknown")
+	case ctx.Err() != nil:
+		return res
+	default:
+		slog.Debug("trying TidalHifi search", "artist", artist, "title", title)
+		c.stage(55, StageSearchingTidal)
+		res.SourcesTried = append(res.SourcesTried, "tidal_search")
+		if !c.tidal.IsAvailable() {
+			c.Trace.skip("tidal_search", "tidal-hifi", "service unavailable")
+			return res
+		}
+
+		start := time.Now()
+		result, err := c.tidal.DownloadBySearch(artist, title, c.TempDir)
+		event := CascadeEvent{Step: "tidal_search", Source: "tidal-search", Service: "tidal-hifi", Detail: artist + " - " + title}
+		if err != nil || result == nil {
+			slog.Warn("Tidal search failed", "err", err)
+			event.Decision = DecisionFailed
+			c.Trace.record(start, event, err)
+			return res
+		}
+		event.Decision = DecisionOK
+		c.Trace.record(start, event, nil)
+
+		slog.Info("FLAC found via Tidal search", "path", result.FilePath)
+		res.Audio = result
+		res.Source = "tidal-search"
+		res.Service = "tidal-hifi"
+		if result.Track != nil && result.Track.ISRC != "" {
+			res.ISRC = result.Track.ISRC
+		}
+		if result.Track != nil && result.Track.ID != "" {
+			res.TidalTrackURL = "https://tidal.com/browse/track/" + result.Track.ID
+			res.TrackURL = res.TidalTrackURL
+		}
+	}
+	return res
+}
+
+// trySource runs the service chain for one source and reports whether it
+// delivered audio
+func (c *AudioCascade) trySource(source, downloadURL string, res *AudioCascadeResult) bool {
+	if downloadURL == "" {
+		c.Trace.skip("source", "", "song.link has no "+source+" link", source)
+		return false
+	}
+	// The link must be from the service song.link listed it under
+	if err := ValidateTrackURLFor(source, downloadURL); err != nil {
+		slog.Warn("skipping audio source", "source", source, "err", err)
+		c.Trace.skip("source", "", err.Error(), source)
+		return false
+	}
+
+	slog.Debug("trying audio source", "source", source, "url", downloadURL)
+	c.stage(50, StageDownloadingFrom, "source", source)
+	res.SourcesTried = append(res.SourcesTried, source)
+
+	var result *AudioDownloadResult
+	var service string
+	attempt := func(name string, download func() (*AudioDownloadResult, error)) {
+		start := time.Now()
+		r, err := download()
+		event := CascadeEvent{Step: "download", Source: source, Service: name, URL: downloadURL, Decision: DecisionFailed}
+		if err != nil {
+			slog.Debug(name+" failed", "err", err)
+			c.Trace.record(start, event, err)
+			return
+		}
+		event.Decision = DecisionOK
+		if r.Track != nil {
+			event.Detail = r.Track.Quality
+		}
+		c.Trace.record(start, event, nil)
+		result, service = r, name
+	}
+
+	// 1. Try TidalHifiService FIRST for Tidal URLs (vogel.qqdl.site - works!)
+	if source == "tidal" {
+		if c.tidal.IsAvailable() {
+			c.stage(51, StageDownloadingTidal)
+			attempt("tidal-hifi", func() (*AudioDownloadResult, error) {
+				return c.tidal.Download(downloadURL, c.TempDir, "flac")
+			})
+		} else {
+			c.Trace.skip("download", "tidal-hifi", "service unavailable", source)
+		}
+	}
+
+	// 2. Try Lucida (web API) if TidalHifi failed or not Tidal
+	if result == nil {
+		attempt("lucida", func() (*AudioDownloadResult, error) {
+			return c.lucida.Download(downloadURL, c.TempDir, "flac")
+		})
+	}
+
+	// 3. Try OrpheusDL/Streamrip (Python subprocess) as last resort
+	if result == nil {
+		if c.orpheus.IsAvailable() {
+			c.stage(52, StageTryingOrpheus, "source", source)
+			attempt("orpheusdl", func() (*AudioDownloadResult, error) {
+				return c.orpheus.Download(downloadURL, c.TempDir, "flac")
+			})
+		} else {
+			c.Trace.skip("download", "orpheusdl", "not installed", source)
+		}
+	}
+
+	if result == nil {
+		return false
+	}
+
+	actualQuality := ""
+	if result.Track != nil {
+		actualQuality = result.Track.Quality
+		if result.Track.ISRC != "" {
+			res.ISRC = result.Track.ISRC
+		}
+	}
+	slog.Info("FLAC downloaded", "source", source, "path", result.FilePath, "quality", actualQuality)
+	if actualQuality != "" && isQualityDowngrade(c.PreferredQuality, actualQuality) {
+		slog.Warn("quality downgraded", "requested", c.PreferredQuality, "actual", actualQuality, "source", source)
+		c.Trace.note("quality", source, service, fmt.Sprintf("downgraded from %s to %s", c.PreferredQuality, actualQuality))
+	}
+
+	res.Audio = result
+	res.Source = source
+	res.Service = service
+	res.TrackURL = downloadURL
+	if source == "tidal" {
+		res.TidalTrackURL = downloadURL
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAudioCascadeTrace_NoSources(t *testing.T) {
+	cascade := NewAudioCascade(&defaultConfig, t.TempDir())
+	cascade.TidalSearch = false
+	cascade.Trace = NewCascadeTrace("")
+
+	var seen []CascadeEvent
+	cascade.Trace.OnEvent = func(e CascadeEvent) { seen = append(seen, e) }
+	var stages []StageCode
+	cascade.OnStage = func(_ int, code StageCode, _ ...string) { stages = append(stages, code) }
+
+	res := cascade.Run(context.Background(), "", "Artist", "Title")
+	if res.Audio != nil || len(res.SourcesTried) != 0 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(stages) != 0 {
+		t.Errorf("stages = %v", stages)
+	}
+	trace := cascade.Trace
+	if trace.Outcome != "fallback_extract" || len(trace.Events) != 1 || len(seen) != 1 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if e := trace.Events[0]; e.Step != "tidal_search" || e.Decision != DecisionSkipped {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestCascadeTrace_Nil(t *testing.T) {
+	var trace *CascadeTrace
+	trace.skip("source", "", "no link", "qobuz")
+	trace.note("quality", "tidal", "tidal-hifi", "downgraded")
+	trace.finish(&AudioCascadeResult{Audio: &AudioDownloadResult{FilePath: "x.flac"}})
+}
+
+func TestCascadeTrace_Finish(t *testing.T) {
+	trace := NewCascadeTrace("https://example.com")
+	trace.finish(&AudioCascadeResult{
+		Audio:   &AudioDownloadResult{FilePath: "/tmp/a.flac", Track: &AudioTrackInfo{Quality: "24-bit/96kHz"}},
+		Source:  "qobuz",
+		Service: "lucida",
+	})
+	if trace.Outcome != "downloaded" || trace.Source != "qobuz" || trace.Service != "lucida" || trace.Quality != "24-bit/96kHz" {
+		t.Errorf("trace = %+v", trace)
+	}
+}
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// Decisions recorded in a CascadeTrace
+const (
+	DecisionOK      = "ok"      // The step succeeded
+	DecisionFailed  = "failed"  // The step ran and failed
+	DecisionSkipped = "skipped" // The step was not attempted
+	DecisionNote    = "note"    // Informational, e.g. a quality downgrade
+)
+
+// CascadeEvent is one decision of the audio cascade
+type CascadeEvent struct {
+	Step       string `json:"step"` // resolve, source, download, tidal_search, quality
+	Source     string `json:"source,omitempty"`
+	Service    string `json:"service,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Decision   string `json:"decision"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	OffsetMs   int64  `json:"offsetMs"`             // Since the cascade started
+	DurationMs int64  `json:"durationMs,omitempty"` // Time the step took
+}
+
+// CascadeTrace collects the decisions of one AudioCascade run. A nil trace
+// records nothing, so the processor passes none.
+type CascadeTrace struct {
+	URL        string         `json:"url"`
+	StartedAt  time.Time      `json:"startedAt"`
+	DurationMs int64          `json:"durationMs"`
+	Events     []CascadeEvent `json:"events"`
+	Source     string         `json:"source,omitempty"`  // Source that delivered the audio
+	Service    string         `json:"service,omitempty"` // Service that delivered the audio
+	AudioPath  string         `json:"audioPath,omitempty"`
+	Quality    string         `json:"quality,omitempty"`
+	Outcome    string         `json:"outcome"` // "downloaded" or "fallback_extract"
+
+	// OnEvent is called for every recorded event (optional), e.g. for live output
+	OnEvent func(CascadeEvent) `json:"-"`
+
+	mu sync.Mutex
+}
+
+// NewCascadeTrace starts a trace for url
+func NewCascadeTrace(url string) *CascadeTrace {
+	return &CascadeTrace{URL: url, StartedAt: time.Now()}
+}
+
+// record adds an event for a step that started at start
+func (t *CascadeTrace) record(start time.Time, event CascadeEvent, err error) {
+	if t == nil {
+		return
+	}
+	event.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		event.Error = err.Error()
+	}
+	t.add(start, event)
+}
+
+// skip records a step that was not attempted
+func (t *CascadeTrace) skip(step, service, reason string, source ...string) {
+	if t == nil {
+		return
+	}
+	event := CascadeEvent{Step: step, Service: service, Decision: DecisionSkipped, Detail: reason}
+	if len(source) > 0 {
+		event.Source = source[0]
+	}
+	t.add(time.Now(), event)
+}
+
+// note records an informational event
+func (t *CascadeTrace) note(step, source, service, detail string) {
+	if t == nil {
+		return
+	}
+	t.add(time.Now(), CascadeEvent{Step: step, Source: source, Service: service, Decision: DecisionNote, Detail: detail})
+}
+
+func (t *CascadeTrace) add(at time.Time, event CascadeEvent) {
+	t.mu.Lock()
+	event.OffsetMs = at.Sub(t.StartedAt).Milliseconds()
+	t.Events = append(t.Events, event)
+	onEvent := t.OnEvent
+	t.mu.Unlock()
+	if onEvent != nil {
+		onEvent(event)
+	}
+}
+
+// finish stores the outcome of the run
+func (t *CascadeTrace) finish(res *AudioCascadeResult) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.DurationMs = time.Since(t.StartedAt).Milliseconds()
+	t.Outcome = "fallback_extract"
+	if res.Audio != nil {
+		t.Outcome = "downloaded"
+		t.Source = res.Source
+		t.Service = res.Service
+		t.AudioPath = res.Audio.FilePath
+		if res.Audio.Track != nil {
+			t.Quality = res.Audio.Track.Quality
+		}
+	}
+}
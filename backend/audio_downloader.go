@@ -2,7 +2,6 @@ package backend
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 )
@@ -22,6 +21,9 @@ type AudioTrackInfo struct {
 	CoverURL    string  `json:"coverUrl,omitempty"`
 	ReleaseDate string  `json:"releaseDate,omitempty"`
 	TrackNumber int     `json:"trackNumber,omitempty"`
+	Explicit    bool    `json:"explicit,omitempty"` // Marked explicit by the store
+	AlbumID     string  `json:"albumId,omitempty"`  // Store album, e.g. "tidal:12345" (see CheckAlbums)
+	DiscNumber  int     `json:"discNumber,omitempty"`
 }
 
 // AudioDownloadResult contains the result of a download
@@ -57,7 +59,7 @@ func DefaultDownloadConfig() *DownloadConfig {
 		PreferredFormat:  "flac",
 		PreferredQuality: "highest",
 		PlatformPriority: []string{"tidal", "qobuz", "amazon", "deezer"},
-		OutputDir:        os.TempDir(),
+		OutputDir:        GetTempDirectory(),
 		Timeout:          5 * time.Minute,
 	}
 }
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,8 +27,13 @@ var lucidaEndpoints = []string{
 
 // LucidaService implements AudioDownloadService using lucida.to
 type LucidaService struct {
-	client    *http.Client
-	endpoints []string // overrideable for testing
+	client      *http.Client
+	endpoints   []string // overrideable for testing
+	connections int      // Parallel range requests per file, see Downloader
+
+	// Cancellation and progress of file downloads, see SetDownloadContext
+	ctx      context.Context
+	progress func(DownloadProgress)
 }
 
 // LucidaResponse represents the API response from lucida.to
@@ -53,6 +59,9 @@ type LucidaResponse struct {
 	} `json:"formats"`
 }
 
+// lucidaServices are the services whose track links Lucida downloads
+var lucidaServices = []string{"tidal", "qobuz", "deezer", "amazon", "soundcloud", "spotify"}
+
 // NewLucidaService creates a new Lucida download service.
 // If client is nil, a default client is used (respects PROXY_URL env var).
 func NewLucidaService(client *http.Client) *LucidaService {
@@ -69,7 +78,12 @@ func (l *LucidaService) Name() string {
 	return "lucida"
 }
 
+// IsAvailable reports whether any Lucida endpoint answers (cached, see availabilityCache)
 func (l *LucidaService) IsAvailable() bool {
+	return cachedAvailability("lucida "+strings.Join(l.endpoints, " "), l.checkAvailable)
+}
+
+func (l *LucidaService) checkAvailable() bool {
 	for _, endpoint := range l.endpoints {
 		resp, err := l.client.Head(endpoint)
 		if err != nil {
@@ -114,6 +128,11 @@ func (l *LucidaService) GetTrackInfo(trackURL string) (*AudioTrackInfo, error) {
 }
 
 func (l *LucidaService) fetchTrackData(trackURL string) (*LucidaResponse, error) {
+	// Lucida fetches the link itself
+	if _, err := validateTrackURLOf(lucidaServices, trackURL); err != nil {
+		return nil, err
+	}
+
 	data := url.Values{}
 	data.Set("url", trackURL)
 
@@ -127,7 +146,6 @@ func (l *LucidaService) fetchTrackData(trackURL string) (*LucidaResponse, error)
 			continue
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 		req.Header.Set("Origin", endpoint)
 		req.Header.Set("Referer", endpoint+"/")
 
@@ -251,27 +269,22 @@ func (l *LucidaService) Download(trackURL string, outputDir string, format strin
 	}, nil
 }
 
+// downloadFile downloads and verifies one file. The size in Lucida's format
+// list is only an estimate, so the download server's Content-Length is what
+// the file is checked against.
 func (l *LucidaService) downloadFile(downloadURL, outputPath string) error {
-	resp, err := l.client.Get(downloadURL)
-	if err != nil {
-		return fmt.Errorf("failed to start download: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download server returned %d", resp.StatusCode)
-	}
-
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer outFile.Close()
+	d := &Downloader{Client: l.client, Connections: l.connections, Progress: l.progress}
+	return d.Download(downloadContext(l.ctx), downloadURL, outputPath, ExpectedFile{})
+}
 
-	if _, err = io.Copy(outFile, resp.Body); err != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("download interrupted: %w", err)
-	}
+// SetConnections sets how many parallel range requests fetch one file
+func (l *LucidaService) SetConnections(connections int) {
+	l.connections = connections
+}
 
-	return nil
+// SetDownloadContext makes file downloads stop when ctx is cancelled and
+// report their progress (progress may be nil)
+func (l *LucidaService) SetDownloadContext(ctx context.Context, progress func(DownloadProgress)) {
+	l.ctx = ctx
+	l.progress = progress
 }
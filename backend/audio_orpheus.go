@@ -1,10 +1,12 @@
 package backend
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -24,10 +26,31 @@ const (
 	tidalHifiAPIBase = "https://vogel.qqdl.site"
 )
 
+// Surround modes accepted in Config.SurroundMode
+const (
+	SurroundOff     = "off"     // Stereo only
+	SurroundPrefer  = "prefer"  // Use the multi-channel mix instead of stereo when offered
+	SurroundInclude = "include" // Keep the stereo FLAC and add the multi-channel mix as an extra track
+)
+
+// Tidal audio modes
+const (
+	TidalAudioModeStereo = "STEREO"
+	TidalAudioModeAtmos  = "DOLBY_ATMOS"
+	TidalAudioMode360RA  = "SONY_360RA"
+)
+
 // TidalHifiService implements AudioDownloadService using the hifi-api
 type TidalHifiService struct {
-	client  *http.Client
-	baseURL string
+	client      *http.Client
+	baseURL     string
+	quality     TidalQuality
+	explicit    string // ExplicitPreference for search results
+	connections int    // Parallel range requests per file, see Downloader
+
+	// Cancellation and progress of file downloads, see SetDownloadContext
+	ctx      context.Context
+	progress func(DownloadProgress)
 }
 
 // TidalManifest represents the decoded manifest from hifi-api
@@ -44,15 +67,23 @@ type TidalTrackResponse struct {
 	Title       string `json:"title"`
 	Duration    int    `json:"duration"`
 	TrackNumber int    `json:"trackNumber"`
+	Volume      int    `json:"volumeNumber"` // Disc
 	ISRC        string `json:"isrc"`
 	Explicit    bool   `json:"explicit"`
-	Artist      struct {
+	// Quality negotiation: best stereo tier and available mixes (STEREO, DOLBY_ATMOS, SONY_360RA)
+	AudioQuality  string   `json:"audioQuality,omitempty"`
+	AudioModes    []string `json:"audioModes,omitempty"`
+	MediaMetadata struct {
+		Tags []string `json:"tags"`
+	} `json:"mediaMetadata"`
+	Artist struct {
 		Name string `json:"name"`
 	} `json:"artist"`
 	Artists []struct {
 		Name string `json:"name"`
 	} `json:"artists"`
 	Album struct {
+		ID    int    `json:"id"`
 		Title string `json:"title"`
 		Cover string `json:"cover"`
 	} `json:"album"`
@@ -100,14 +131,66 @@ func NewTidalHifiService(client *http.Client) *TidalHifiService {
 	return &TidalHifiService{
 		client:  client,
 		baseURL: tidalHifiAPIBase,
+		quality: TidalQualityLossless,
 	}
 }
 
+// SetQuality sets the stereo quality tier requested from the API.
+// HI_RES_LOSSLESS falls back to LOSSLESS when the track or proxy doesn't offer it.
+func (t *TidalHifiService) SetQuality(quality TidalQuality) {
+	if quality != "" {
+		t.quality = quality
+	}
+}
+
+// SetExplicitPreference chooses between the explicit and clean versions of
+// a track when a search finds both (ExplicitAny, ExplicitPrefer, ExplicitAvoid)
+func (t *TidalHifiService) SetExplicitPreference(preference string) {
+	t.explicit = preference
+}
+
+// SetConnections sets how many parallel range requests fetch one file
+func (t *TidalHifiService) SetConnections(connections int) {
+	t.connections = connections
+}
+
+// SetDownloadContext makes file downloads stop when ctx is cancelled and
+// report their progress (progress may be nil)
+func (t *TidalHifiService) SetDownloadContext(ctx context.Context, progress func(DownloadProgress)) {
+	t.ctx = ctx
+	t.progress = progress
+}
+
+// SurroundModes returns the multi-channel mixes Tidal offers for a track
+func (track *TidalTrackResponse) SurroundModes() []string {
+	var modes []string
+	seen := make(map[string]bool)
+	add := func(mode string) {
+		mode = strings.ToUpper(mode)
+		if (mode == TidalAudioModeAtmos || mode == TidalAudioMode360RA) && !seen[mode] {
+			seen[mode] = true
+			modes = append(modes, mode)
+		}
+	}
+	for _, m := range track.AudioModes {
+		add(m)
+	}
+	for _, tag := range track.MediaMetadata.Tags {
+		add(tag)
+	}
+	return modes
+}
+
 func (t *TidalHifiService) Name() string {
 	return "tidal-hifi"
 }
 
+// IsAvailable reports whether the TidalHifi API answers (cached, see availabilityCache)
 func (t *TidalHifiService) IsAvailable() bool {
+	return cachedAvailability("tidal-hifi "+t.baseURL, t.checkAvailable)
+}
+
+func (t *TidalHifiService) checkAvailable() bool {
 	resp, err := t.client.Head(t.baseURL)
 	if err != nil {
 		return false
@@ -122,13 +205,21 @@ func (t *TidalHifiService) SupportsFormat(format string) bool {
 
 // SearchTrack searches for a track on Tidal
 func (t *TidalHifiService) SearchTrack(query string) (*TidalTrackResponse, error) {
+	items, err := t.SearchTracks(query)
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// SearchTracks searches for tracks by query and returns every result
+func (t *TidalHifiService) SearchTracks(query string) ([]TidalTrackResponse, error) {
 	searchURL := fmt.Sprintf("%s/search/?s=%s", t.baseURL, url.QueryEscape(query))
 
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
 	resp, err := t.client.Do(req)
 	if err != nil {
@@ -157,7 +248,7 @@ func (t *TidalHifiService) SearchTrack(query string) (*TidalTrackResponse, error
 		return nil, fmt.Errorf("no tracks found for query: %s", query)
 	}
 
-	return &items[0], nil
+	return items, nil
 }
 
 // GetTrackByID fetches track info by Tidal ID
@@ -168,7 +259,6 @@ func (t *TidalHifiService) GetTrackByID(trackID int) (*TidalTrackResponse, error
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
 	resp, err := t.client.Do(req)
 	if err != nil {
@@ -200,61 +290,100 @@ func (t *TidalHifiService) GetTrackByID(trackID int) (*TidalTrackResponse, error
 	return &trackInfo, nil
 }
 
+// TidalStream is a resolved stream for one quality tier / audio mode
+type TidalStream struct {
+	URL          string `json:"url"`
+	AudioMode    string `json:"audioMode"`
+	AudioQuality string `json:"audioQuality"`
+	MimeType     string `json:"mimeType"`
+	Codecs       string `json:"codecs"`
+}
+
 // GetStreamURL fetches the FLAC stream URL for a track
 func (t *TidalHifiService) GetStreamURL(trackID int) (string, error) {
-	streamURL := fmt.Sprintf("%s/track/?id=%d&quality=LOSSLESS", t.baseURL, trackID)
+	stream, err := t.GetStream(trackID, t.quality, false)
+	if err != nil {
+		return "", err
+	}
+	return stream.URL, nil
+}
+
+// negotiateStream requests the configured tier, stepping down to LOSSLESS if it isn't offered
+func (t *TidalHifiService) negotiateStream(trackID int) (*TidalStream, error) {
+	stream, err := t.GetStream(trackID, t.quality, false)
+	if err != nil && t.quality != TidalQualityLossless {
+		slog.Debug("tidal quality not available, falling back to LOSSLESS", "quality", t.quality, "err", err)
+		return t.GetStream(trackID, TidalQualityLossless, false)
+	}
+	return stream, err
+}
+
+// GetStream fetches the stream for a track at the given quality.
+// immersive requests the Dolby Atmos / 360 Reality Audio mix instead of stereo.
+func (t *TidalHifiService) GetStream(trackID int, quality TidalQuality, immersive bool) (*TidalStream, error) {
+	if quality == "" {
+		quality = TidalQualityLossless
+	}
+	streamURL := fmt.Sprintf("%s/track/?id=%d&quality=%s", t.baseURL, trackID, quality)
+	if immersive {
+		streamURL += "&immersiveaudio=true"
+	}
 
 	req, err := http.NewRequest("GET", streamURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("stream request failed: %w", err)
+		return nil, fmt.Errorf("stream request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read stream response: %w", err)
+		return nil, fmt.Errorf("failed to read stream response: %w", err)
 	}
 
 	// Try v2.0 wrapper format first
 	var streamDataResp TidalStreamDataResponse
 	if err := json.Unmarshal(body, &streamDataResp); err != nil {
-		return "", fmt.Errorf("failed to parse stream response: %w", err)
+		return nil, fmt.Errorf("failed to parse stream response: %w", err)
 	}
 
-	manifestBase64 := streamDataResp.Data.Manifest
-	if manifestBase64 == "" {
-		var streamResp TidalStreamResponse
+	streamResp := streamDataResp.Data
+	if streamResp.Manifest == "" {
 		if err := json.Unmarshal(body, &streamResp); err != nil {
-			return "", fmt.Errorf("failed to parse stream response (direct): %w", err)
+			return nil, fmt.Errorf("failed to parse stream response (direct): %w", err)
 		}
-		manifestBase64 = streamResp.Manifest
 	}
+	manifestBase64 := streamResp.Manifest
 
 	if manifestBase64 == "" {
-		return "", fmt.Errorf("no manifest in stream response")
+		return nil, fmt.Errorf("no manifest in stream response")
 	}
 
 	manifestBytes, err := base64.StdEncoding.DecodeString(manifestBase64)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode manifest: %w", err)
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
 	}
 
 	var manifest TidalManifest
 	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
-		return "", fmt.Errorf("failed to parse manifest: %w", err)
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
 	}
 
 	if len(manifest.URLs) == 0 {
-		return "", fmt.Errorf("no download URLs in manifest")
+		return nil, fmt.Errorf("no download URLs in manifest")
 	}
 
-	return manifest.URLs[0], nil
+	return &TidalStream{
+		URL:          manifest.URLs[0],
+		AudioMode:    streamResp.AudioMode,
+		AudioQuality: streamResp.AudioQuality,
+		MimeType:     manifest.MimeType,
+		Codecs:       manifest.Codecs,
+	}, nil
 }
 
 // ExtractTidalID extracts the track ID from a Tidal URL
@@ -304,6 +433,11 @@ func (t *TidalHifiService) GetTrackInfo(trackURL string) (*AudioTrackInfo, error
 		Quality:  "FLAC 16-bit/44.1kHz",
 		Platform: "tidal",
 		CoverURL: fmt.Sprintf("https://resources.tidal.com/images/%s/640x640.jpg", strings.ReplaceAll(track.Album.Cover, "-", "/")),
+		Explicit: track.Explicit,
+
+		AlbumID:     tidalAlbumID(track),
+		TrackNumber: track.TrackNumber,
+		DiscNumber:  track.Volume,
 	}, nil
 }
 
@@ -318,7 +452,7 @@ func (t *TidalHifiService) Download(trackURL string, outputDir string, format st
 		return nil, fmt.Errorf("failed to get track info: %w", err)
 	}
 
-	streamURL, err := t.GetStreamURL(trackID)
+	stream, err := t.negotiateStream(trackID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stream URL: %w", err)
 	}
@@ -334,10 +468,15 @@ func (t *TidalHifiService) Download(trackURL string, outputDir string, format st
 	safeTitle := SanitizeFileName(fmt.Sprintf("%s - %s", artistName, track.Title))
 	outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.flac", safeTitle))
 
-	if err := t.downloadFile(streamURL, outputPath); err != nil {
+	if err := t.downloadFile(stream.URL, outputPath); err != nil {
 		return nil, fmt.Errorf("download failed: %w", err)
 	}
 
+	quality := "FLAC LOSSLESS"
+	if stream.AudioQuality != "" {
+		quality = "FLAC " + stream.AudioQuality
+	}
+
 	stat, _ := os.Stat(outputPath)
 	var fileSize int64
 	if stat != nil {
@@ -354,8 +493,13 @@ func (t *TidalHifiService) Download(trackURL string, outputDir string, format st
 			Duration: float64(track.Duration),
 			ISRC:     track.ISRC,
 			Platform: "tidal",
-			Quality:  "FLAC LOSSLESS",
+			Quality:  quality,
 			CoverURL: fmt.Sprintf("https://resources.tidal.com/images/%s/640x640.jpg", strings.ReplaceAll(track.Album.Cover, "-", "/")),
+			Explicit: track.Explicit,
+
+			AlbumID:     tidalAlbumID(track),
+			TrackNumber: track.TrackNumber,
+			DiscNumber:  track.Volume,
 		},
 		Format: "flac",
 		Size:   fileSize,
@@ -363,44 +507,92 @@ func (t *TidalHifiService) Download(trackURL string, outputDir string, format st
 }
 
 func (t *TidalHifiService) downloadFile(downloadURL, outputPath string) error {
-	req, err := http.NewRequest("GET", downloadURL, nil)
+	d := &Downloader{Client: t.client, Connections: t.connections, Progress: t.progress}
+	return d.Download(downloadContext(t.ctx), downloadURL, outputPath, ExpectedFile{})
+}
+
+// DownloadSurround downloads the Dolby Atmos / 360 Reality Audio mix of a track.
+// Returns an error if Tidal only offers stereo for it.
+func (t *TidalHifiService) DownloadSurround(trackURL string, outputDir string) (*AudioDownloadResult, error) {
+	trackID, err := ExtractTidalID(trackURL)
 	if err != nil {
-		return fmt.Errorf("failed to create download request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
-	resp, err := t.client.Do(req)
+	track, err := t.GetTrackByID(trackID)
 	if err != nil {
-		return fmt.Errorf("failed to start download: %w", err)
+		return nil, fmt.Errorf("failed to get track info: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download server returned %d", resp.StatusCode)
+	if len(track.SurroundModes()) == 0 {
+		return nil, fmt.Errorf("no surround mix available for track %d", trackID)
 	}
 
-	outFile, err := os.Create(outputPath)
+	stream, err := t.GetStream(trackID, t.quality, true)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, fmt.Errorf("failed to get surround stream: %w", err)
+	}
+	if stream.AudioMode == "" || strings.EqualFold(stream.AudioMode, TidalAudioModeStereo) {
+		return nil, fmt.Errorf("API returned a stereo stream for track %d", trackID)
 	}
-	defer outFile.Close()
 
-	if _, err = io.Copy(outFile, resp.Body); err != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("download interrupted: %w", err)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	return nil
+	format, ext := surroundFormat(stream)
+	outputPath := filepath.Join(outputDir, fmt.Sprintf("%d-surround%s", trackID, ext))
+	if err := t.downloadFile(stream.URL, outputPath); err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	var fileSize int64
+	if stat, err := os.Stat(outputPath); err == nil {
+		fileSize = stat.Size()
+	}
+
+	return &AudioDownloadResult{
+		FilePath: outputPath,
+		Track: &AudioTrackInfo{
+			ID:       fmt.Sprintf("%d", track.ID),
+			Title:    track.Title,
+			Artist:   track.Artist.Name,
+			Album:    track.Album.Title,
+			Duration: float64(track.Duration),
+			ISRC:     track.ISRC,
+			Platform: "tidal",
+			Quality:  fmt.Sprintf("%s (%s)", stream.AudioMode, format),
+			Explicit: track.Explicit,
+		},
+		Format: format,
+		Size:   fileSize,
+	}, nil
+}
+
+// surroundFormat picks the codec name and file extension for a surround stream
+func surroundFormat(stream *TidalStream) (format, ext string) {
+	codecs := strings.ToLower(stream.Codecs)
+	switch {
+	case strings.Contains(codecs, "ec-3"), strings.Contains(codecs, "eac3"):
+		return "eac3", ".m4a"
+	case strings.Contains(codecs, "ac-4"), strings.Contains(codecs, "ac4"):
+		return "ac4", ".m4a"
+	case strings.Contains(codecs, "mha1"), strings.Contains(codecs, "mhm1"):
+		return "mpegh", ".m4a"
+	case strings.Contains(codecs, "flac"):
+		return "flac", ".flac"
+	}
+	return codecs, ".m4a"
 }
 
 // DownloadBySearch downloads FLAC by searching for artist + title
 func (t *TidalHifiService) DownloadBySearch(artist, title, outputDir string) (*AudioDownloadResult, error) {
 	query := fmt.Sprintf("%s %s", artist, title)
 
-	track, err := t.SearchTrack(query)
+	items, err := t.SearchTracks(query)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
+	track := pickExplicitVersion(items, t.explicit)
 
 	trackURL := fmt.Sprintf("https://tidal.com/browse/track/%d", track.ID)
 	return t.Download(trackURL, outputDir, "flac")
@@ -416,6 +608,13 @@ type OrpheusDLService struct {
 	pythonPath string
 }
 
+// Services whose track links streamrip and OrpheusDL (with its usual
+// modules) download
+var (
+	streamripServices = []string{"qobuz", "tidal", "deezer", "soundcloud"}
+	orpheusDLServices = []string{"tidal", "qobuz", "deezer", "soundcloud", "spotify"}
+)
+
 // NewOrpheusDLService creates a new OrpheusDL service
 func NewOrpheusDLService() *OrpheusDLService {
 	pythonPath := "python3"
@@ -431,11 +630,16 @@ func (o *OrpheusDLService) Name() string {
 	return "orpheusdl"
 }
 
+// IsAvailable reports whether streamrip or OrpheusDL is installed (cached, see availabilityCache)
 func (o *OrpheusDLService) IsAvailable() bool {
-	if err := exec.Command("rip", "--version").Run(); err == nil {
+	return cachedAvailability("orpheusdl "+o.pythonPath, o.checkAvailable)
+}
+
+func (o *OrpheusDLService) checkAvailable() bool {
+	if err := newCommand("rip", "--version").Run(); err == nil {
 		return true
 	}
-	return exec.Command(o.pythonPath, "-m", "streamrip", "--version").Run() == nil
+	return newCommand(o.pythonPath, "-m", "streamrip", "--version").Run() == nil
 }
 
 func (o *OrpheusDLService) SupportsFormat(format string) bool {
@@ -455,16 +659,16 @@ func (o *OrpheusDLService) Download(trackURL string, outputDir string, format st
 }
 
 func (o *OrpheusDLService) tryStreamrip(trackURL string, outputDir string) (*AudioDownloadResult, error) {
-	if err := ValidateTrackURL(trackURL); err != nil {
-		return nil, fmt.Errorf("rejected track URL: %w", err)
+	if _, err := validateTrackURLOf(streamripServices, trackURL); err != nil {
+		return nil, err
 	}
 
-	cmd := exec.Command("rip", "url", trackURL)
+	cmd := newCommand("rip", "url", trackURL)
 	cmd.Dir = outputDir
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		cmd2 := exec.Command(o.pythonPath, "-m", "streamrip", "url", trackURL)
+		cmd2 := newCommand(o.pythonPath, "-m", "streamrip", "url", trackURL)
 		cmd2.Dir = outputDir
 		output, err = cmd2.CombinedOutput()
 		if err != nil {
@@ -494,11 +698,11 @@ func (o *OrpheusDLService) tryStreamrip(trackURL string, outputDir string) (*Aud
 }
 
 func (o *OrpheusDLService) tryOrpheusDL(trackURL string, outputDir string) (*AudioDownloadResult, error) {
-	if err := ValidateTrackURL(trackURL); err != nil {
-		return nil, fmt.Errorf("rejected track URL: %w", err)
+	if _, err := validateTrackURLOf(orpheusDLServices, trackURL); err != nil {
+		return nil, err
 	}
 
-	cmd := exec.Command(o.pythonPath, "-m", "orpheusdl", trackURL, "-o", outputDir, "-q", "flac")
+	cmd := newCommand(o.pythonPath, "-m", "orpheusdl", trackURL, "-o", outputDir, "-q", "flac")
 	cmd.Dir = outputDir
 
 	output, err := cmd.CombinedOutput()
@@ -285,6 +285,75 @@ func TestTidalHifiService_GetStreamURL_EmptyURLs(t *testing.T) {
 	}
 }
 
+// ============================================================================
+// Quality negotiation / surround
+// ============================================================================
+
+func TestTidalQualityForPreference(t *testing.T) {
+	if got := TidalQualityForPreference("16bit"); got != TidalQualityLossless {
+		t.Errorf("16bit -> %q, want LOSSLESS", got)
+	}
+	if got := TidalQualityForPreference("highest"); got != TidalQualityHiResLossless {
+		t.Errorf("highest -> %q, want HI_RES_LOSSLESS", got)
+	}
+}
+
+func TestTidalHifiService_NegotiateStream_FallsBackToLossless(t *testing.T) {
+	manifest := tidalManifestBase64([]string{"https://cdn.example.com/stream.flac"})
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		quality := r.URL.Query().Get("quality")
+		requested = append(requested, quality)
+		w.Header().Set("Content-Type", "application/json")
+		if quality == string(TidalQualityHiResLossless) {
+			fmt.Fprint(w, `{"version":"2.0","data":{"manifest":""}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"version":"2.0","data":{"audioQuality":%q,"manifest":%q}}`, quality, manifest)
+	}))
+	defer ts.Close()
+
+	svc := newTidalSvc(ts)
+	svc.SetQuality(TidalQualityHiResLossless)
+	stream, err := svc.negotiateStream(12345)
+	if err != nil {
+		t.Fatalf("negotiateStream() error: %v", err)
+	}
+	if stream.AudioQuality != string(TidalQualityLossless) {
+		t.Errorf("AudioQuality = %q, want LOSSLESS", stream.AudioQuality)
+	}
+	if len(requested) != 2 || requested[0] != string(TidalQualityHiResLossless) {
+		t.Errorf("requested qualities = %v, want [HI_RES_LOSSLESS LOSSLESS]", requested)
+	}
+}
+
+func TestTidalTrackResponse_SurroundModes(t *testing.T) {
+	var track TidalTrackResponse
+	track.AudioModes = []string{"STEREO", "DOLBY_ATMOS"}
+	track.MediaMetadata.Tags = []string{"LOSSLESS", "DOLBY_ATMOS", "SONY_360RA"}
+
+	modes := track.SurroundModes()
+	if len(modes) != 2 || modes[0] != TidalAudioModeAtmos || modes[1] != TidalAudioMode360RA {
+		t.Errorf("SurroundModes() = %v, want [DOLBY_ATMOS SONY_360RA]", modes)
+	}
+}
+
+func TestTidalHifiService_DownloadSurround_StereoOnly(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"version":"2.0","data":{"id":12345,"title":"Test","audioModes":["STEREO"]}}`)
+	}))
+	defer ts.Close()
+
+	_, err := newTidalSvc(ts).DownloadSurround("https://tidal.com/browse/track/12345", t.TempDir())
+	if err == nil {
+		t.Fatal("expected error for stereo-only track, got nil")
+	}
+	if !strings.Contains(err.Error(), "no surround mix") {
+		t.Errorf("error %q should mention 'no surround mix'", err.Error())
+	}
+}
+
 // ============================================================================
 // GetTrackInfo — artist fallback
 // ============================================================================
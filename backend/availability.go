@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// availabilityCache caches the IsAvailable health checks of the download
+// services. Every queue item asks each service whether it is up; without
+// the cache a 200-track playlist import sends hundreds of HEAD requests
+// (and runs streamrip --version as often) before any audio is fetched.
+//
+// A result is fresh for ttl (downTTL when the service was down, so a short
+// outage doesn't disable it for long). A stale result is still returned
+// right away while one background check refreshes it; only results older
+// than maxStale, and the very first check, make the caller wait.
+type availabilityCache struct {
+	mu       sync.Mutex
+	entries  map[string]*availabilityEntry
+	ttl      time.Duration
+	downTTL  time.Duration
+	maxStale time.Duration
+}
+
+type availabilityEntry struct {
+	available  bool
+	checkedAt  time.Time
+	refreshing bool
+	ready      chan struct{} // Closed once the first check finished
+}
+
+var serviceAvailability = &availabilityCache{
+	entries:  make(map[string]*availabilityEntry),
+	ttl:      2 * time.Minute,
+	downTTL:  30 * time.Second,
+	maxStale: 15 * time.Minute,
+}
+
+// cachedAvailability returns the cached result of check for key, running
+// it when there is none yet and refreshing it in the background when stale
+func cachedAvailability(key string, check func() bool) bool {
+	return serviceAvailability.get(key, check)
+}
+
+func (c *availabilityCache) get(key string, check func() bool) bool {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok || time.Since(e.checkedAt) > c.maxStale && e.ready == nil {
+		// First check (or too old to trust): callers for the same key wait for it
+		e = &availabilityEntry{ready: make(chan struct{})}
+		c.entries[key] = e
+		c.mu.Unlock()
+
+		available := check()
+
+		c.mu.Lock()
+		e.available = available
+		e.checkedAt = time.Now()
+		close(e.ready)
+		e.ready = nil
+		c.mu.Unlock()
+		return available
+	}
+	if ready := e.ready; ready != nil {
+		c.mu.Unlock()
+		<-ready
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return e.available
+	}
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if !e.available {
+		ttl = c.downTTL
+	}
+	if time.Since(e.checkedAt) > ttl && !e.refreshing {
+		e.refreshing = true
+		go func() {
+			available := check()
+			c.mu.Lock()
+			e.available = available
+			e.checkedAt = time.Now()
+			e.refreshing = false
+			c.mu.Unlock()
+		}()
+	}
+	return e.available
+}
+
+// invalidate drops the cached result for key, or all results for ""
+func (c *availabilityCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key == "" {
+		clear(c.entries)
+		return
+	}
+	delete(c.entries, key)
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityCache(t *testing.T) {
+	c := &availabilityCache{
+		entries:  make(map[string]*availabilityEntry),
+		ttl:      time.Hour,
+		downTTL:  time.Hour,
+		maxStale: 2 * time.Hour,
+	}
+	var calls atomic.Int32
+	check := func() bool {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return true
+	}
+
+	// Concurrent first lookups share one check
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !c.get("svc", check) {
+				t.Error("expected available")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("checks = %d, want 1", n)
+	}
+
+	// Stale: the cached value is returned and refreshed in the background
+	c.mu.Lock()
+	c.entries["svc"].checkedAt = time.Now().Add(-90 * time.Minute)
+	c.mu.Unlock()
+	c.get("svc", check)
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("checks after stale lookup = %d, want 2", n)
+	}
+
+	// Too old: checked again before returning
+	c.mu.Lock()
+	c.entries["svc"].checkedAt = time.Now().Add(-3 * time.Hour)
+	c.mu.Unlock()
+	if !c.get("svc", func() bool { calls.Add(1); return true }) || calls.Load() != 3 {
+		t.Errorf("checks after expired lookup = %d, want 3", calls.Load())
+	}
+
+	c.invalidate("svc")
+	if _, ok := c.entries["svc"]; ok {
+		t.Error("invalidate kept the entry")
+	}
+}
+
+func TestAvailabilityCache_DownTTL(t *testing.T) {
+	c := &availabilityCache{
+		entries:  make(map[string]*availabilityEntry),
+		ttl:      time.Hour,
+		downTTL:  0,
+		maxStale: time.Hour,
+	}
+	var calls atomic.Int32
+	down := func() bool { calls.Add(1); return false }
+	c.get("svc", down)
+	time.Sleep(time.Millisecond)
+	c.get("svc", down)
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls.Load() < 2 {
+		t.Error("a down service should be checked again after downTTL")
+	}
+}
@@ -0,0 +1,378 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// =============================================================================
+// Channel archives
+// =============================================================================
+
+// A channel archive follows a YouTube channel (/@handle, /channel/ID, /c/name,
+// /user/name). Syncing lists the channel's videos and/or shorts tab, filters
+// them by duration and queues every video not archived before, with the
+// channel name as artist so files land in one artist folder. The archived IDs
+// are kept in channels.json, so later syncs only queue new uploads.
+
+// Channel tabs to archive
+const (
+	ChannelTabVideos = "videos"
+	ChannelTabShorts = "shorts"
+	ChannelTabAll    = "all" // videos and shorts
+)
+
+var channelURLRegex = regexp.MustCompile(`^https://(?:www\.|m\.|music\.)?youtube\.com/(@[\w.-]+|channel/UC[\w-]{22}|c/[^/?#]+|user/[^/?#]+)(?:/(?:videos|shorts|streams|featured|playlists)?)?/?(?:[?#].*)?$`)
+
+// IsChannelURL reports whether rawURL points to a YouTube channel
+func IsChannelURL(rawURL string) bool {
+	return channelURLRegex.MatchString(strings.TrimSpace(rawURL))
+}
+
+// ParseChannelURL returns the canonical channel URL without a tab,
+// e.g. https://www.youtube.com/@artist
+func ParseChannelURL(rawURL string) (string, error) {
+	m := channelURLRegex.FindStringSubmatch(strings.TrimSpace(rawURL))
+	if m == nil {
+		return "", fmt.Errorf("not a YouTube channel URL: %s", rawURL)
+	}
+	return "https://www.youtube.com/" + m[1], nil
+}
+
+// ChannelArtistName turns a channel name into an artist name
+// ("Artist - Topic", "ArtistVEVO" -> "Artist")
+func ChannelArtistName(channel string) string {
+	name := strings.TrimSpace(strings.TrimSuffix(channel, " - Topic"))
+	if trimmed := strings.TrimSuffix(name, "VEVO"); trimmed != name && trimmed != "" {
+		name = strings.TrimSpace(trimmed)
+	}
+	return name
+}
+
+// ChannelArchive is one followed channel
+type ChannelArchive struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`                   // Canonical channel URL
+	Name        string    `json:"name"`                  // Channel name, filled in by the first sync
+	Artist      string    `json:"artist"`                // Artist used for tags and folders, defaults to the channel name
+	Tab         string    `json:"tab"`                   // videos, shorts, all
+	MinDuration float64   `json:"minDuration,omitempty"` // Seconds, 0 = no minimum
+	MaxDuration float64   `json:"maxDuration,omitempty"` // Seconds, 0 = no maximum
+	Quality     string    `json:"quality,omitempty"`
+	ArchivedIDs []string  `json:"archivedIds,omitempty"` // Videos queued by earlier syncs
+	CreatedAt   time.Time `json:"createdAt"`
+	LastSyncAt  time.Time `json:"lastSyncAt,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// ChannelSyncResult summarizes one sync
+type ChannelSyncResult struct {
+	Queued      []string `json:"queued"`      // Queue item IDs
+	Archived    int      `json:"archived"`    // Already archived by an earlier sync
+	Existing    int      `json:"existing"`    // Downloaded before or found in the library
+	Unavailable int      `json:"unavailable"` // Deleted, private or members-only
+	Filtered    int      `json:"filtered"`    // Outside the duration limits
+	Partial     bool     `json:"partial"`     // Listing stopped early; the next sync picks up the rest
+}
+
+// ChannelArchives stores the followed channels
+type ChannelArchives struct {
+	archives []ChannelArchive
+	filePath string
+	mu       sync.Mutex
+	syncMu   sync.Mutex // One sync at a time
+}
+
+// NewChannelArchives loads channels.json from the config directory
+func NewChannelArchives() *ChannelArchives {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+
+	c := &ChannelArchives{filePath: filepath.Join(configDir, "youflac", "channels.json")}
+	c.load()
+	return c
+}
+
+func (c *ChannelArchives) load() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.archives = []ChannelArchive{}
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &c.archives); err != nil {
+		slog.Warn("failed to parse channel archives", "path", c.filePath, "err", err)
+		c.archives = []ChannelArchive{}
+	}
+}
+
+// save writes the archives; callers hold c.mu
+func (c *ChannelArchives) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.filePath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.archives, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.filePath, data, 0644)
+}
+
+// List returns all followed channels
+func (c *ChannelArchives) List() []ChannelArchive {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.archives)
+}
+
+// Get returns a copy of one archive, or nil
+func (c *ChannelArchives) Get(id string) *ChannelArchive {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, archive := range c.archives {
+		if archive.ID == id {
+			return &archive
+		}
+	}
+	return nil
+}
+
+// Add follows a channel. Adding a channel that is already followed updates
+// its filters instead.
+func (c *ChannelArchives) Add(archive ChannelArchive) (*ChannelArchive, error) {
+	canonical, err := ParseChannelURL(archive.URL)
+	if err != nil {
+		return nil, err
+	}
+	archive.URL = canonical
+	switch archive.Tab {
+	case "":
+		archive.Tab = ChannelTabVideos
+	case ChannelTabVideos, ChannelTabShorts, ChannelTabAll:
+	default:
+		return nil, fmt.Errorf("invalid channel tab %q, expected videos, shorts or all", archive.Tab)
+	}
+	if archive.MinDuration < 0 || archive.MaxDuration < 0 ||
+		(archive.MaxDuration > 0 && archive.MinDuration > archive.MaxDuration) {
+		return nil, fmt.Errorf("invalid duration limits %.0f-%.0f", archive.MinDuration, archive.MaxDuration)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.archives {
+		if c.archives[i].URL == archive.URL {
+			existing := &c.archives[i]
+			existing.Tab = archive.Tab
+			existing.MinDuration = archive.MinDuration
+			existing.MaxDuration = archive.MaxDuration
+			existing.Quality = archive.Quality
+			if archive.Artist != "" {
+				existing.Artist = archive.Artist
+			}
+			result := *existing
+			return &result, c.save()
+		}
+	}
+
+	archive.ID = uuid.New().String()
+	archive.ArchivedIDs = nil
+	archive.CreatedAt = time.Now()
+	c.archives = append(c.archives, archive)
+	return &archive, c.save()
+}
+
+// Remove stops following a channel; downloaded files are kept
+func (c *ChannelArchives) Remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, archive := range c.archives {
+		if archive.ID == id {
+			c.archives = append(c.archives[:i], c.archives[i+1:]...)
+			return c.save()
+		}
+	}
+	return fmt.Errorf("channel archive not found: %s", id)
+}
+
+// channelTabURLs returns the tab URLs listed for an archive
+func channelTabURLs(archive *ChannelArchive) []string {
+	switch archive.Tab {
+	case ChannelTabShorts:
+		return []string{archive.URL + "/shorts"}
+	case ChannelTabAll:
+		return []string{archive.URL + "/videos", archive.URL + "/shorts"}
+	default:
+		return []string{archive.URL + "/videos"}
+	}
+}
+
+// withinDuration applies the archive's duration limits. Entries without a
+// duration pass, since flat listings do not always include it.
+func (a *ChannelArchive) withinDuration(duration float64) bool {
+	if duration <= 0 {
+		return true
+	}
+	if a.MinDuration > 0 && duration < a.MinDuration {
+		return false
+	}
+	return a.MaxDuration <= 0 || duration <= a.MaxDuration
+}
+
+// Sync lists the channel and queues every new video. history and fileIndex
+// may be nil.
+func (c *ChannelArchives) Sync(id string, q *Queue, history *History, fileIndex *FileIndex) (*ChannelSyncResult, error) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	archive := c.Get(id)
+	if archive == nil {
+		return nil, fmt.Errorf("channel archive not found: %s", id)
+	}
+
+	listing := &PlaylistInfo{}
+	var fetchErr error
+	for _, tabURL := range channelTabURLs(archive) {
+		info, _, err := fetchPlaylistEntries(tabURL, PlaylistFetchOptions{})
+		if err != nil {
+			// A channel without shorts has no shorts tab
+			fetchErr = err
+			continue
+		}
+		if listing.Author == "" {
+			listing.Author = info.Author
+		}
+		listing.Videos = append(listing.Videos, info.Videos...)
+		listing.Partial = listing.Partial || info.Partial
+	}
+	if len(listing.Videos) == 0 && fetchErr != nil {
+		c.finishSync(id, "", nil, fetchErr)
+		return nil, fmt.Errorf("failed to list channel: %w", fetchErr)
+	}
+
+	if archive.Name == "" {
+		archive.Name = listing.Author
+	}
+	artist := archive.Artist
+	if artist == "" {
+		artist = ChannelArtistName(archive.Name)
+	}
+
+	result := &ChannelSyncResult{Queued: []string{}, Partial: listing.Partial}
+	archived := make(map[string]bool, len(archive.ArchivedIDs))
+	for _, videoID := range archive.ArchivedIDs {
+		archived[videoID] = true
+	}
+
+	candidates := &PlaylistInfo{}
+	for _, video := range listing.Videos {
+		switch {
+		case archived[video.ID]:
+			result.Archived++
+		case !archive.withinDuration(video.Duration):
+			result.Filtered++
+		default:
+			archived[video.ID] = true // A video can be in both tabs
+			if video.Artist == "" {
+				video.Artist = artist
+			}
+			candidates.Videos = append(candidates.Videos, video)
+		}
+	}
+
+	var newIDs []string
+	q.mutex.RLock()
+	config := q.configs.Get()
+	q.mutex.RUnlock()
+
+	check := CheckPlaylist(candidates, config, history, fileIndex)
+	for _, entry := range check.Entries {
+		switch entry.Status {
+		case PlaylistEntryUnavailable:
+			// Not archived: private videos may become public later
+			result.Unavailable++
+			continue
+		case PlaylistEntryFiltered:
+			result.Filtered++
+			newIDs = append(newIDs, entry.Video.ID)
+			continue
+		case PlaylistEntryDownloaded, PlaylistEntryInLibrary:
+			result.Existing++
+			newIDs = append(newIDs, entry.Video.ID)
+			continue
+		}
+
+		video := entry.Video
+		request := DownloadRequest{VideoURL: video.URL, Quality: archive.Quality, AlbumArtist: artist, RequireApproval: subscriptionApproval(config)}
+		videoInfo := &VideoInfo{
+			ID:        video.ID,
+			Title:     video.Title,
+			Artist:    video.Artist,
+			Duration:  video.Duration,
+			Thumbnail: video.Thumbnail,
+			URL:       video.URL,
+		}
+		itemID, err := q.AddToQueueWithMetadata(request, videoInfo)
+		if err != nil {
+			slog.Warn("failed to queue channel video", "channel", archive.URL, "video", video.ID, "err", err)
+			continue
+		}
+		result.Queued = append(result.Queued, itemID)
+		newIDs = append(newIDs, video.ID)
+	}
+
+	c.finishSync(id, listing.Author, newIDs, nil)
+	slog.Info("channel synced", "channel", archive.URL, "queued", len(result.Queued), "archived", result.Archived,
+		"existing", result.Existing, "unavailable", result.Unavailable, "filtered", result.Filtered)
+	return result, nil
+}
+
+// finishSync records the outcome of a sync on the stored archive
+func (c *ChannelArchives) finishSync(id, name string, newIDs []string, syncErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.archives {
+		archive := &c.archives[i]
+		if archive.ID != id {
+			continue
+		}
+		if archive.Name == "" {
+			archive.Name = name
+		}
+		archive.ArchivedIDs = append(archive.ArchivedIDs, newIDs...)
+		archive.LastSyncAt = time.Now()
+		archive.LastError = ""
+		if syncErr != nil {
+			archive.LastError = syncErr.Error()
+		}
+		if err := c.save(); err != nil {
+			slog.Warn("failed to save channel archives", "err", err)
+		}
+		return
+	}
+}
+
+// SyncAll syncs every followed channel, logging failures
+func (c *ChannelArchives) SyncAll(q *Queue, history *History, fileIndex *FileIndex) {
+	for _, archive := range c.List() {
+		if _, err := c.Sync(archive.ID, q, history, fileIndex); err != nil {
+			slog.Warn("channel sync failed", "channel", archive.URL, "err", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseChannelURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/@artist", "https://www.youtube.com/@artist"},
+		{"https://youtube.com/@some.artist/videos", "https://www.youtube.com/@some.artist"},
+		{"https://www.youtube.com/channel/UC1234567890abcdefghijAB/shorts?view=0", "https://www.youtube.com/channel/UC1234567890abcdefghijAB"},
+		{"https://m.youtube.com/c/ArtistName/", "https://www.youtube.com/c/ArtistName"},
+		{"https://www.youtube.com/user/artistvevo", "https://www.youtube.com/user/artistvevo"},
+	}
+	for _, tt := range tests {
+		got, err := ParseChannelURL(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseChannelURL(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://www.youtube.com/playlist?list=PL123",
+		"http://www.youtube.com/@artist",
+		"https://example.com/@artist",
+	} {
+		if IsChannelURL(bad) {
+			t.Errorf("IsChannelURL(%q) = true", bad)
+		}
+	}
+}
+
+func TestChannelArtistName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Artist - Topic": "Artist",
+		"ArtistVEVO":     "Artist",
+		"VEVO":           "VEVO",
+		"Plain Artist":   "Plain Artist",
+	} {
+		if got := ChannelArtistName(in); got != want {
+			t.Errorf("ChannelArtistName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChannelArchives_SyncIsIncremental(t *testing.T) {
+	listing := `echo '{"id":"vid00000001","title":"Song One","duration":200,"playlist_title":"Artist - Videos","playlist_uploader":"ArtistVEVO"}'
+echo '{"id":"vid00000002","title":"Teaser","duration":15}'
+echo '{"id":"vid00000003","title":"[Private video]"}'
+echo '{"id":"vid00000004","title":"Song Two","duration":240}'`
+	fakePlaylistCommand(t, listing)
+
+	dir := t.TempDir()
+	channels := &ChannelArchives{filePath: filepath.Join(dir, "channels.json")}
+	archive, err := channels.Add(ChannelArchive{URL: "https://www.youtube.com/@artist/videos", MinDuration: 60})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if archive.Tab != ChannelTabVideos || archive.URL != "https://www.youtube.com/@artist" {
+		t.Errorf("archive = %+v", archive)
+	}
+
+	q := newTestQueue()
+	result, err := channels.Sync(archive.ID, q, nil, nil)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Queued) != 2 || result.Filtered != 1 || result.Unavailable != 1 {
+		t.Fatalf("first sync = %+v", result)
+	}
+	item := q.GetItem(result.Queued[0])
+	if item.Artist != "Artist" || item.AlbumArtist != "Artist" || item.Title != "Song One" {
+		t.Errorf("queued item = %+v", item)
+	}
+
+	// Second run with one new upload only queues that one
+	fakePlaylistCommand(t, `echo '{"id":"vid00000005","title":"Song Three","duration":180}'; `+listing)
+	result, err = channels.Sync(archive.ID, q, nil, nil)
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if len(result.Queued) != 1 || result.Archived != 2 {
+		t.Errorf("second sync = %+v", result)
+	}
+
+	// State survives a reload
+	reloaded := &ChannelArchives{filePath: channels.filePath}
+	reloaded.load()
+	stored := reloaded.Get(archive.ID)
+	if stored == nil || len(stored.ArchivedIDs) != 3 || stored.Name != "ArtistVEVO" || stored.LastSyncAt.IsZero() {
+		t.Errorf("stored archive = %+v", stored)
+	}
+}
+
+func TestChannelArchives_AddValidates(t *testing.T) {
+	channels := &ChannelArchives{filePath: filepath.Join(t.TempDir(), "channels.json")}
+	if _, err := channels.Add(ChannelArchive{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}); err == nil {
+		t.Error("video URL should be rejected")
+	}
+	if _, err := channels.Add(ChannelArchive{URL: "https://www.youtube.com/@a", Tab: "live"}); err == nil {
+		t.Error("unknown tab should be rejected")
+	}
+	if _, err := channels.Add(ChannelArchive{URL: "https://www.youtube.com/@a", MinDuration: 600, MaxDuration: 60}); err == nil {
+		t.Error("min above max should be rejected")
+	}
+
+	first, _ := channels.Add(ChannelArchive{URL: "https://www.youtube.com/@a"})
+	second, _ := channels.Add(ChannelArchive{URL: "https://www.youtube.com/@a/shorts", Tab: ChannelTabShorts})
+	if first.ID != second.ID || len(channels.List()) != 1 || second.Tab != ChannelTabShorts {
+		t.Errorf("re-adding should update the archive: %+v, %+v", first, second)
+	}
+}
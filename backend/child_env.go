@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Child process environment
+// =============================================================================
+
+// Spawned tools don't inherit the server's environment, which holds the API
+// key, service tokens and other credentials passed to YouFlac. A child gets
+// the variables any process needs (PATH, HOME, locale, temp dir, proxy and
+// Python settings), those its tool reads (RCLONE_* for rclone) and whatever
+// Config.ChildEnvAllow lets through. Config.ChildEnv sets variables for
+// every child process on top. Both are global: they apply to all tools and
+// hooks alike, whatever item they run for.
+
+// baseChildEnv are the variables every child process receives. A trailing
+// "*" matches a prefix; names are compared in upper case.
+var baseChildEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TZ", "LANG", "LANGUAGE", "LC_*", "XDG_*",
+	"TMPDIR", "TEMP", "TMP",
+	"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY", "SSL_CERT_FILE", "SSL_CERT_DIR",
+	"LD_LIBRARY_PATH", "DYLD_LIBRARY_PATH",
+	"PYTHONPATH", "PYTHONHOME", "PYTHONUSERBASE", "VIRTUAL_ENV",
+	// Windows
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "USERNAME", "USERPROFILE",
+	"HOMEDRIVE", "HOMEPATH", "APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES", "PROGRAMFILES(X86)",
+	"NUMBER_OF_PROCESSORS", "PROCESSOR_ARCHITECTURE",
+}
+
+// toolChildEnv are the extra variables a tool receives, by executable name
+var toolChildEnv = map[string][]string{
+	"rclone":      {"RCLONE_*"},
+	"sftp":        {"SSH_AUTH_SOCK"},
+	"secret-tool": {"DBUS_SESSION_BUS_ADDRESS"},
+	"yt-dlp":      {"DBUS_SESSION_BUS_ADDRESS"}, // Browser cookies from the desktop keyring
+}
+
+// deniedChildEnv can't be set through Config.ChildEnv: dynamic loader
+// variables would load code into every tool YouFlac starts
+var deniedChildEnv = []string{"LD_*", "DYLD_*"}
+
+var (
+	childEnvMutex sync.RWMutex
+	childEnvAllow []string // Config.ChildEnvAllow
+	childEnvSet   []string // Config.ChildEnv
+)
+
+// ConfigureChildEnv applies Config.ChildEnvAllow and Config.ChildEnv to the
+// processes started from now on
+func ConfigureChildEnv(config *Config) {
+	childEnvMutex.Lock()
+	defer childEnvMutex.Unlock()
+	childEnvAllow = slices.Clone(config.ChildEnvAllow)
+	childEnvSet = nil
+	for _, kv := range config.ChildEnv {
+		if key, _, _ := strings.Cut(kv, "="); !envNameMatches(deniedChildEnv, key) {
+			childEnvSet = append(childEnvSet, kv)
+		}
+	}
+}
+
+// childEnv returns the environment for a child process running name
+func childEnv(name string) []string {
+	tool := strings.TrimSuffix(strings.ToLower(filepath.Base(name)), ".exe")
+
+	childEnvMutex.RLock()
+	allow := slices.Concat(baseChildEnv, toolChildEnv[tool], childEnvAllow)
+	set := childEnvSet
+	childEnvMutex.RUnlock()
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if key, _, ok := strings.Cut(kv, "="); ok && envNameMatches(allow, key) {
+			env = append(env, kv)
+		}
+	}
+	// exec keeps the last value of a duplicate key, so these win
+	return append(env, set...)
+}
+
+// envNameMatches reports whether key matches one of patterns (names or
+// "PREFIX*", upper case)
+func envNameMatches(patterns []string, key string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
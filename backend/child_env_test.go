@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChildEnv(t *testing.T) {
+	t.Cleanup(func() { ConfigureChildEnv(&Config{}) })
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("YOUFLAC_API_KEY", "secret")
+	t.Setenv("RCLONE_CONFIG_PASS", "rclone secret")
+	t.Setenv("LC_ALL", "C")
+	t.Setenv("MY_TOOL_HOME", "/opt/tool")
+
+	ConfigureChildEnv(&Config{})
+	env := childEnv("/usr/local/bin/ffmpeg")
+	for _, want := range []string{"PATH=/usr/bin", "LC_ALL=C"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("ffmpeg env lacks %s", want)
+		}
+	}
+	for _, withheld := range []string{"YOUFLAC_API_KEY=secret", "RCLONE_CONFIG_PASS=rclone secret", "MY_TOOL_HOME=/opt/tool"} {
+		if slices.Contains(env, withheld) {
+			t.Errorf("ffmpeg env has %s", withheld)
+		}
+	}
+
+	// A tool gets its own variables
+	if env := childEnv("rclone"); !slices.Contains(env, "RCLONE_CONFIG_PASS=rclone secret") {
+		t.Error("rclone env lacks RCLONE_CONFIG_PASS")
+	}
+
+	// Allowed and injected variables
+	ConfigureChildEnv(&Config{ChildEnvAllow: []string{"MY_TOOL_*"}, ChildEnv: []string{"LC_ALL=en_US.UTF-8", "EXTRA=1"}})
+	env = childEnv("python3")
+	for _, want := range []string{"MY_TOOL_HOME=/opt/tool", "EXTRA=1"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("python3 env lacks %s", want)
+		}
+	}
+	if i, j := slices.Index(env, "LC_ALL=C"), slices.Index(env, "LC_ALL=en_US.UTF-8"); j < i {
+		t.Errorf("injected LC_ALL at %d does not override the inherited one at %d", j, i)
+	}
+
+	// Loader variables are never injected, even without validation
+	ConfigureChildEnv(&Config{ChildEnv: []string{"LD_PRELOAD=/tmp/x.so", "DYLD_INSERT_LIBRARIES=/tmp/x.dylib"}})
+	for _, kv := range childEnv("ffmpeg") {
+		if strings.HasPrefix(kv, "LD_PRELOAD=") || strings.HasPrefix(kv, "DYLD_INSERT_LIBRARIES=") {
+			t.Errorf("ffmpeg env has %s", kv)
+		}
+	}
+
+	// Commands are started with it
+	if cmd := newCommand("ffmpeg", "-version"); slices.Contains(cmd.Env, "YOUFLAC_API_KEY=secret") {
+		t.Error("newCommand passed YOUFLAC_API_KEY on")
+	}
+}
+
+func TestConfigValidate_ChildEnv(t *testing.T) {
+	config := GetDefaultConfig()
+	config.ChildEnvAllow = []string{" my_tool_* ", "MY_TOOL_*", "bad name", ""}
+	config.ChildEnv = []string{" EXTRA =a=b", "novalue", "=x", "PREFIX*=1", "LD_PRELOAD=/tmp/x.so", "dyld_insert_libraries=/tmp/x.dylib"}
+	v := config.Validate()
+	if !slices.Equal(config.ChildEnvAllow, []string{"MY_TOOL_*"}) || !hasIssue(v.Warnings, "childEnvAllow") {
+		t.Errorf("childEnvAllow = %q, warnings %v", config.ChildEnvAllow, v.Warnings)
+	}
+	if !slices.Equal(config.ChildEnv, []string{"EXTRA=a=b"}) || !hasIssue(v.Warnings, "childEnv") {
+		t.Errorf("childEnv = %q, warnings %v", config.ChildEnv, v.Warnings)
+	}
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"youflac/backend/format"
+)
+
+// =============================================================================
+// Clips
+// =============================================================================
+
+// A request with ClipStart and/or ClipEnd downloads only that part of the
+// video, e.g. one song of a concert. yt-dlp fetches just the section
+// (--download-sections) and cuts it at exact frames; the audio, which the
+// services and YouTube Music deliver for the whole recording, is trimmed to
+// the same span before muxing. Audio extracted from the clipped video
+// already matches.
+
+// Clip is a section of a video, in seconds. End 0 means up to the end.
+type Clip struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end,omitempty"`
+}
+
+// ParseClip returns the clip between two timestamps, nil if both are empty
+func ParseClip(start, end string) (*Clip, error) {
+	if strings.TrimSpace(start) == "" && strings.TrimSpace(end) == "" {
+		return nil, nil
+	}
+
+	clip := &Clip{}
+	var err error
+	if strings.TrimSpace(start) != "" {
+		if clip.Start, err = format.ParseDuration(start); err != nil {
+			return nil, fmt.Errorf("clip start: %w", err)
+		}
+	}
+	if strings.TrimSpace(end) != "" {
+		if clip.End, err = format.ParseDuration(end); err != nil {
+			return nil, fmt.Errorf("clip end: %w", err)
+		}
+		if clip.End <= clip.Start {
+			return nil, fmt.Errorf("clip ends at %s, before it starts at %s", format.Duration(clip.End), format.Duration(clip.Start))
+		}
+	}
+	if clip.Start == 0 && clip.End == 0 {
+		return nil, nil // The whole video
+	}
+	return clip, nil
+}
+
+// Length returns the clip duration in a video of duration seconds (0 if
+// unknown)
+func (c *Clip) Length(duration float64) float64 {
+	end := c.End
+	if end == 0 || (duration > 0 && end > duration) {
+		end = duration
+	}
+	return max(0, end-c.Start)
+}
+
+// String formats the clip as "1:02-4:05" ("1:02-" when open ended)
+func (c *Clip) String() string {
+	if c.End == 0 {
+		return format.Duration(c.Start) + "-"
+	}
+	return format.Duration(c.Start) + "-" + format.Duration(c.End)
+}
+
+// ytdlpArgs makes yt-dlp download only the clip, cut at exact frames
+func (c *Clip) ytdlpArgs() []string {
+	end := "inf"
+	if c.End > 0 {
+		end = strconv.FormatFloat(c.End, 'f', -1, 64)
+	}
+	return []string{
+		"--download-sections", fmt.Sprintf("*%s-%s", strconv.FormatFloat(c.Start, 'f', -1, 64), end),
+		"--force-keyframes-at-cuts",
+	}
+}
+
+// TrimAudioToClip cuts the clip out of an audio file with sample-accurate
+// filters. Output is FLAC and, for lossless sources, verified against the
+// trimmed source samples.
+func TrimAudioToClip(ctx context.Context, inputPath, outputPath string, clip *Clip) error {
+	info, err := GetMediaInfo(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to get audio info: %w", err)
+	}
+
+	filter := fmt.Sprintf("atrim=start=%.6f", clip.Start)
+	if clip.End > 0 {
+		filter += fmt.Sprintf(":end=%.6f", clip.End)
+	}
+	filter += ",asetpts=PTS-STARTPTS"
+
+	args := []string{
+		"-y",
+		"-i", inputPath,
+		"-af", filter,
+	}
+	args = append(args, flacEncodeArgs()...)
+	args = append(args, outputPath)
+
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("audio clip failed: %v - %s", err, stderr.String())
+	}
+	if isLosslessCodec(info.AudioCodec) {
+		return verifyFLACEncode(ctx, inputPath, nil, filter, outputPath)
+	}
+	return nil
+}
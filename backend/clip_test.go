@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseClip(t *testing.T) {
+	if clip, err := ParseClip("", ""); clip != nil || err != nil {
+		t.Errorf("no timestamps = %v, %v, want the whole video", clip, err)
+	}
+	if clip, err := ParseClip("0:00", ""); clip != nil || err != nil {
+		t.Errorf("start 0 = %v, %v, want the whole video", clip, err)
+	}
+	if _, err := ParseClip("5:00", "4:00"); err == nil {
+		t.Error("accepted a clip ending before it starts")
+	}
+
+	clip, err := ParseClip("1:02:10", "1:06:45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := clip.Length(7200); got != 275 {
+		t.Errorf("Length = %v, want 275", got)
+	}
+	if got := clip.String(); got != "1:02:10-1:06:45" {
+		t.Errorf("String = %q", got)
+	}
+	if args := clip.ytdlpArgs(); !slices.Equal(args, []string{"--download-sections", "*3730-4005", "--force-keyframes-at-cuts"}) {
+		t.Errorf("ytdlpArgs = %q", args)
+	}
+
+	// Open ended clips run to the end of the video
+	clip, _ = ParseClip("30", "")
+	if got := clip.Length(200); got != 170 {
+		t.Errorf("Length = %v, want 170", got)
+	}
+	if args := clip.ytdlpArgs(); args[1] != "*30-inf" {
+		t.Errorf("ytdlpArgs = %q", args)
+	}
+}
+
+func TestAddToQueue_Clip(t *testing.T) {
+	q := newTestQueue()
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc", ClipStart: "1:00", ClipEnd: "2:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip := q.GetItem(id).Clip; clip == nil || clip.Start != 60 || clip.End != 150 {
+		t.Errorf("Clip = %+v", clip)
+	}
+	if _, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc", ClipEnd: "soon"}); err == nil {
+		t.Error("AddToQueue accepted an invalid clip")
+	}
+}
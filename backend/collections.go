@@ -0,0 +1,291 @@
+package backend
+
+import (
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Jellyfin collections
+// =============================================================================
+
+// Jellyfin keeps its collections ("box sets") in its data directory, one
+// folder per collection named "<name> [boxset]" with a collection.xml that
+// lists the paths of the items. BuildCollections writes such a folder for
+// every playlist folder of the library and, optionally, for every artist,
+// so imported playlists show up as curated collections after Jellyfin's
+// next library scan.
+//
+// The collections directory holds a manifest of the collections YouFlac
+// wrote. Only those are rewritten or removed; collections made in Jellyfin
+// are left alone.
+
+// Grouping modes for Config.CollectionGroups
+const (
+	CollectionGroupPlaylist = "playlist" // One collection per playlist folder, in playlist order
+	CollectionGroupArtist   = "artist"   // One collection per artist (album artist if tagged)
+)
+
+// collectionsManifest is the file in the collections directory listing the
+// collection folders YouFlac wrote
+const collectionsManifest = ".youflac-collections.json"
+
+// collectionsMu serializes BuildCollections
+var collectionsMu sync.Mutex
+
+// playlistTrackPattern matches the track number prefix of playlist files ("01 - ")
+var playlistTrackPattern = regexp.MustCompile(`^\d+ - `)
+
+// CollectionReport is the result of BuildCollections
+type CollectionReport struct {
+	Collections int      `json:"collections"` // Collections written
+	Items       int      `json:"items"`       // Items in those collections
+	Removed     int      `json:"removed"`     // Collections removed because their files are gone
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// jellyfinCollection is the collection.xml format of Jellyfin's box sets
+type jellyfinCollection struct {
+	XMLName    xml.Name                 `xml:"Item"`
+	LocalTitle string                   `xml:"LocalTitle"`
+	Overview   string                   `xml:"Overview,omitempty"`
+	LockData   bool                     `xml:"LockData"`
+	Items      []jellyfinCollectionItem `xml:"CollectionItems>CollectionItem"`
+}
+
+type jellyfinCollectionItem struct {
+	Path string `xml:"Path"`
+}
+
+// libraryCollection is a collection before it is written
+type libraryCollection struct {
+	Name     string
+	Overview string
+	Paths    []string
+}
+
+// collectionManifest records the collection folders YouFlac wrote
+type collectionManifest struct {
+	Collections []string `json:"collections"`
+}
+
+// BuildCollections writes the Jellyfin collections for the library and
+// removes the ones whose files are all gone
+func BuildCollections(config *Config) (*CollectionReport, error) {
+	report := &CollectionReport{}
+	if config == nil || config.JellyfinCollectionsDir == "" || len(config.CollectionGroups) == 0 {
+		return report, nil
+	}
+
+	files, err := libraryMediaFiles(config, LibraryViewsFromConfig(config))
+	if err != nil {
+		return nil, err
+	}
+	collections := groupCollections(config, files)
+
+	collectionsMu.Lock()
+	defer collectionsMu.Unlock()
+
+	dir := config.JellyfinCollectionsDir
+	if err := MkdirOutput(dir); err != nil {
+		return nil, fmt.Errorf("failed to create collections directory: %w", err)
+	}
+	previous := loadCollectionManifest(dir, collectionsManifest)
+
+	var written []string
+	for _, c := range collections {
+		folder := SanitizeFileName(c.Name) + " [boxset]"
+		if slices.Contains(written, folder) {
+			continue
+		}
+		path := filepath.Join(dir, folder)
+		if _, err := os.Stat(filepath.Join(path, "collection.xml")); err == nil && !slices.Contains(previous.Collections, folder) {
+			report.Errors = append(report.Errors, fmt.Sprintf("skipped %q: Jellyfin already has a collection with this name", c.Name))
+			continue
+		}
+		if err := writeCollection(path, c, config.JellyfinPathMap); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		written = append(written, folder)
+		report.Collections++
+		report.Items += len(c.Paths)
+	}
+
+	for _, folder := range previous.Collections {
+		if slices.Contains(written, folder) {
+			continue
+		}
+		path := filepath.Join(dir, folder)
+		if err := os.Remove(filepath.Join(path, "collection.xml")); err != nil && !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to remove collection %s: %v", folder, err))
+			written = append(written, folder) // Try again next time
+			continue
+		}
+		os.Remove(path) // Only if Jellyfin left nothing else in it
+		report.Removed++
+	}
+
+	slices.Sort(written)
+	if err := saveCollectionManifest(dir, collectionsManifest, &collectionManifest{Collections: written}); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to save collections manifest: %v", err))
+	}
+	return report, nil
+}
+
+// groupCollections groups library files (sorted by path) into collections.
+// Playlist collections come first and win name clashes with artists.
+func groupCollections(config *Config, files []string) []libraryCollection {
+	byPlaylist := make(map[string][]string)
+	byArtist := make(map[string][]string)
+	artistNames := make(map[string]string) // Lowercase -> first spelling seen
+	for _, file := range files {
+		if containsString(config.CollectionGroups, CollectionGroupPlaylist) {
+			if name := playlistFolderOf(config, file); name != "" {
+				byPlaylist[name] = append(byPlaylist[name], file)
+			}
+		}
+		if containsString(config.CollectionGroups, CollectionGroupArtist) {
+			artist := collectionArtist(file)
+			if artist == "" {
+				continue
+			}
+			key := strings.ToLower(artist)
+			if _, ok := artistNames[key]; !ok {
+				artistNames[key] = artist
+			}
+			byArtist[key] = append(byArtist[key], file)
+		}
+	}
+
+	var collections []libraryCollection
+	for _, name := range slices.Sorted(maps.Keys(byPlaylist)) {
+		collections = append(collections, libraryCollection{
+			Name:     name,
+			Overview: "Playlist downloaded with YouFlac",
+			Paths:    byPlaylist[name],
+		})
+	}
+	minItems := max(config.CollectionMinItems, 1)
+	for _, key := range slices.Sorted(maps.Keys(byArtist)) {
+		if len(byArtist[key]) < minItems {
+			continue
+		}
+		name := artistNames[key]
+		if _, clash := byPlaylist[name]; clash {
+			name += " (Artist)"
+		}
+		collections = append(collections, libraryCollection{
+			Name:     name,
+			Overview: "Music videos by " + artistNames[key],
+			Paths:    byArtist[key],
+		})
+	}
+	return collections
+}
+
+// collectionArtist returns the album artist or artist of a library file.
+// Untagged playlist files are named "01 - Artist - Title", so the track
+// number is dropped before the name is parsed.
+func collectionArtist(file string) string {
+	metadata := readViewMetadata(file)
+	artist := strings.TrimSpace(cmp.Or(metadata.AlbumArtist, metadata.Artist))
+	base := filepath.Base(file)
+	if prefix := playlistTrackPattern.FindString(base); prefix != "" && artist == strings.TrimSuffix(prefix, " - ") {
+		_, artist = ParseFilename(strings.TrimPrefix(base, prefix))
+	}
+	return artist
+}
+
+// playlistFolderOf returns the playlist folder a library file was downloaded
+// into, or "". Playlist items are "<playlist>/01 - Artist - Title.mkv" or,
+// with the nested layout, "<playlist>/01 - Artist - Title/01 - Artist - Title.mkv".
+func playlistFolderOf(config *Config, file string) string {
+	base := filepath.Base(file)
+	if !playlistTrackPattern.MatchString(base) {
+		return ""
+	}
+	rel, err := filepath.Rel(libraryRootFor(config, file), file)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	switch {
+	case len(parts) == 2:
+		return parts[0]
+	case len(parts) == 3 && parts[1] == strings.TrimSuffix(base, filepath.Ext(base)):
+		return parts[0]
+	}
+	return ""
+}
+
+// writeCollection writes the collection.xml of one collection folder
+func writeCollection(path string, c libraryCollection, pathMap string) error {
+	doc := jellyfinCollection{LocalTitle: c.Name, Overview: c.Overview}
+	for _, file := range c.Paths {
+		doc.Items = append(doc.Items, jellyfinCollectionItem{Path: mapJellyfinPath(file, pathMap)})
+	}
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate collection %s: %w", c.Name, err)
+	}
+	content := append([]byte(xml.Header), output...)
+	if err := WriteOutputFile(filepath.Join(path, "collection.xml"), content); err != nil {
+		return fmt.Errorf("failed to write collection %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// parseJellyfinPathMap splits "local=jellyfin"
+func parseJellyfinPathMap(pathMap string) (local, remote string, ok bool) {
+	local, remote, ok = strings.Cut(pathMap, "=")
+	local, remote = strings.TrimSpace(local), strings.TrimSpace(remote)
+	return local, remote, ok && local != "" && remote != ""
+}
+
+// mapJellyfinPath rewrites a library path to the path Jellyfin sees, e.g.
+// when Jellyfin runs in a container with the library mounted elsewhere
+func mapJellyfinPath(path, pathMap string) string {
+	local, remote, ok := parseJellyfinPathMap(pathMap)
+	if !ok || !isWithin(local, path) {
+		return path
+	}
+	rel, err := filepath.Rel(local, path)
+	if err != nil {
+		return path
+	}
+	return strings.TrimRight(remote, "/") + "/" + filepath.ToSlash(rel)
+}
+
+// loadCollectionManifest reads the manifest file name in dir (collections
+// and playlists keep separate ones)
+func loadCollectionManifest(dir, name string) *collectionManifest {
+	manifest := &collectionManifest{}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return manifest
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		slog.Warn("ignoring unreadable manifest", "path", filepath.Join(dir, name), "err", err)
+		return &collectionManifest{}
+	}
+	return manifest
+}
+
+func saveCollectionManifest(dir, name string, manifest *collectionManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteOutputFile(filepath.Join(dir, name), data)
+}
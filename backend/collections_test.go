@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTestCollection(t *testing.T, path string) jellyfinCollection {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc jellyfinCollection
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestBuildCollections(t *testing.T) {
+	root := t.TempDir()
+	library := filepath.Join(root, "library")
+	collections := filepath.Join(root, "jellyfin", "collections")
+	writeTestFile(t, filepath.Join(library, "Road Trip", "01 - Daft Punk - One More Time", "01 - Daft Punk - One More Time.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Road Trip", "02 - Justice - D.A.N.C.E", "02 - Justice - D.A.N.C.E.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Daft Punk - Around the World.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Daft Punk", "Aerodynamic", "Aerodynamic.mkv"), 8)
+
+	config := &Config{
+		OutputDirectory:        library,
+		JellyfinCollectionsDir: collections,
+		CollectionGroups:       []string{CollectionGroupPlaylist},
+		JellyfinPathMap:        library + "=/media/mv",
+	}
+	report, err := BuildCollections(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Collections != 1 || report.Items != 2 || len(report.Errors) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	doc := readTestCollection(t, filepath.Join(collections, "Road Trip [boxset]", "collection.xml"))
+	want := []string{
+		"/media/mv/Road Trip/01 - Daft Punk - One More Time/01 - Daft Punk - One More Time.mkv",
+		"/media/mv/Road Trip/02 - Justice - D.A.N.C.E/02 - Justice - D.A.N.C.E.mkv",
+	}
+	if doc.LocalTitle != "Road Trip" || len(doc.Items) != len(want) {
+		t.Fatalf("collection = %+v", doc)
+	}
+	for i, path := range want {
+		if doc.Items[i].Path != path {
+			t.Errorf("item %d = %q, want %q", i, doc.Items[i].Path, path)
+		}
+	}
+
+	// A collection made in Jellyfin with the same name as an artist is not touched
+	writeTestFile(t, filepath.Join(collections, "Justice [boxset]", "collection.xml"), 4)
+	writeTestFile(t, filepath.Join(library, "Justice - Genesis.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Justice - Phantom.mkv"), 8)
+	config.CollectionGroups = []string{CollectionGroupPlaylist, CollectionGroupArtist}
+	config.CollectionMinItems = 2
+	report, err = BuildCollections(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Collections != 2 || len(report.Errors) != 1 {
+		t.Fatalf("report = %+v, want the playlist and Daft Punk, Justice skipped", report)
+	}
+	if doc := readTestCollection(t, filepath.Join(collections, "Daft Punk [boxset]", "collection.xml")); len(doc.Items) != 3 {
+		t.Errorf("Daft Punk has %d items, want 3", len(doc.Items))
+	}
+
+	// Playlist deleted: its collection goes, the others stay
+	if err := os.RemoveAll(filepath.Join(library, "Road Trip")); err != nil {
+		t.Fatal(err)
+	}
+	report, err = BuildCollections(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Removed != 1 {
+		t.Errorf("removed = %d, want 1", report.Removed)
+	}
+	if _, err := os.Stat(filepath.Join(collections, "Road Trip [boxset]")); !os.IsNotExist(err) {
+		t.Errorf("playlist collection still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(collections, "Justice [boxset]", "collection.xml")); err != nil {
+		t.Errorf("Jellyfin's own collection was removed: %v", err)
+	}
+}
+
+func TestPlaylistFolderOf(t *testing.T) {
+	config := &Config{OutputDirectory: "/lib"}
+	tests := map[string]string{
+		"/lib/Mix/03 - A - B.mkv":            "Mix",
+		"/lib/Mix/03 - A - B/03 - A - B.mkv": "Mix",
+		"/lib/A/B/B.mkv":                     "",
+		"/lib/03 - A - B.mkv":                "",
+		"/lib/Artist/Album/03 - A - B.mkv":   "",
+		"/lib/Mix/03 - A - B/04 - Other.mkv": "",
+	}
+	for path, want := range tests {
+		if got := playlistFolderOf(config, filepath.FromSlash(path)); got != want {
+			t.Errorf("playlistFolderOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -6,57 +6,186 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"youflac/backend/format"
 )
 
 // Application configuration and settings
 
 type Config struct {
-	OutputDirectory      string   `json:"outputDirectory"`
-	VideoQuality         string   `json:"videoQuality"` // "best", "1080p", "720p"
-	AudioSourcePriority  []string `json:"audioSourcePriority"` // ["tidal", "qobuz", "amazon"]
-	NamingTemplate       string   `json:"namingTemplate"`
-	GenerateNFO          bool     `json:"generateNfo"`
-	ConcurrentDownloads  int      `json:"concurrentDownloads"`
-	EmbedCoverArt        bool     `json:"embedCoverArt"`
-	Theme                string   `json:"theme"`               // "dark", "light", "system"
-	CookiesBrowser       string   `json:"cookiesBrowser"`      // "firefox", "chrome", "chromium", "brave", "opera", "edge", ""
-	AccentColor          string   `json:"accentColor"`         // "pink", "blue", "green", "purple", "orange", "teal", "red", "yellow"
-	SoundEffectsEnabled  bool     `json:"soundEffectsEnabled"` // Play sounds on download complete, error, etc.
-	LyricsEnabled        bool     `json:"lyricsEnabled"`       // Fetch lyrics automatically
-	LyricsEmbedMode      string   `json:"lyricsEmbedMode"`     // "embed", "lrc", "both"
-	LogLevel                string  `json:"logLevel"`                // "debug", "info", "warn", "error"
-	ProxyURL                string  `json:"proxyUrl"`                // "socks5://127.0.0.1:1080" or ""
-	DownloadTimeoutMinutes  float64 `json:"downloadTimeoutMinutes"`  // per-file download timeout (0 = default 10m)
-	PreferredQuality        string  `json:"preferredQuality"`        // "highest", "24bit", "16bit"
-	GenerateM3U8            bool    `json:"generateM3u8"`            // Generate .m3u8 playlist when a batch completes
-	SkipExplicit            bool    `json:"skipExplicit"`            // Skip tracks marked explicit
-	SoundVolume             int     `json:"soundVolume"`             // Sound effects volume 0-100
-	SaveCoverFile           bool    `json:"saveCoverFile"`           // Save cover art as separate .jpg file
-	FirstArtistOnly         bool    `json:"firstArtistOnly"`         // Strip featured artists from artist tag
+	OutputDirectory        string           `json:"outputDirectory"`
+	TempDirectory          string           `json:"tempDirectory"`       // Work dir for downloads and mux temp files, "" = system temp dir
+	FileMode               string           `json:"fileMode"`            // Octal mode for library files, e.g. "0664"
+	DirMode                string           `json:"dirMode"`             // Octal mode for library folders, e.g. "0775"
+	FileOwner              string           `json:"fileOwner"`           // "uid:gid" to chown library files to, "" = keep the server's user
+	VideoQuality           string           `json:"videoQuality"`        // "best", "1080p", "720p"
+	AudioSourcePriority    []string         `json:"audioSourcePriority"` // ["tidal", "qobuz", "amazon"]
+	NamingTemplate         string           `json:"namingTemplate"`
+	GenerateNFO            bool             `json:"generateNfo"`
+	NFOFields              []string         `json:"nfoFields"`         // Optional NFO fields: "premiered", "sorttitle", "plot", "tag", "dateadded", "fileinfo"; null = all
+	ProvenanceSidecar      bool             `json:"provenanceSidecar"` // Write <name>.youflac.json with sources, formats and checksums
+	ConcurrentDownloads    int              `json:"concurrentDownloads"`
+	EmbedCoverArt          bool             `json:"embedCoverArt"`
+	Theme                  string           `json:"theme"`                  // "dark", "light", "system"
+	CookiesBrowser         string           `json:"cookiesBrowser"`         // "firefox", "chrome", "chromium", "brave", "opera", "edge", ""
+	AccentColor            string           `json:"accentColor"`            // "pink", "blue", "green", "purple", "orange", "teal", "red", "yellow"
+	SoundEffectsEnabled    bool             `json:"soundEffectsEnabled"`    // Play sounds on download complete, error, etc.
+	LyricsEnabled          bool             `json:"lyricsEnabled"`          // Fetch lyrics automatically
+	LyricsEmbedMode        string           `json:"lyricsEmbedMode"`        // "embed", "lrc", "both"
+	LogLevel               string           `json:"logLevel"`               // "debug", "info", "warn", "error"
+	ProxyURL               string           `json:"proxyUrl"`               // "socks5://127.0.0.1:1080" or ""
+	AllowPrivateURLs       bool             `json:"allowPrivateUrls"`       // Let stream and image downloads reach loopback, LAN and link-local hosts (self-hosted proxies)
+	DownloadTimeoutMinutes float64          `json:"downloadTimeoutMinutes"` // per-file download timeout (0 = default 10m)
+	DownloadConnections    int              `json:"downloadConnections"`    // Parallel range requests per lossless file, 1 = single connection
+	StallTimeoutMinutes    float64          `json:"stallTimeoutMinutes"`    // Items without progress for this long are stopped (hung yt-dlp/ffmpeg), 0 = no watchdog
+	StallRetries           int              `json:"stallRetries"`           // Restarts of a stalled item before it fails as stalled
+	FallbackPolicy         string           `json:"fallbackPolicy"`         // No lossless source: "ytmusic" (YouTube Music audio, else extract), "extract" (video audio), "upgrade" (as ytmusic, flagged for a later download) or "fail"
+	LengthMode             string           `json:"lengthMode"`             // Audio and video length differ: "off", "trim" (to the shorter), "freeze"/"black" (extend the video), "chapters" (keep, marked)
+	PreferredQuality       string           `json:"preferredQuality"`       // "highest", "24bit", "16bit"
+	GenerateM3U8           bool             `json:"generateM3u8"`           // Generate .m3u8 playlist when a batch completes
+	SkipExplicit           bool             `json:"skipExplicit"`           // Skip tracks marked explicit
+	ExplicitPreference     string           `json:"explicitPreference"`     // "any", "prefer" or "avoid" the explicit version when a search finds both
+	SoundVolume            int              `json:"soundVolume"`            // Sound effects volume 0-100
+	SaveCoverFile          bool             `json:"saveCoverFile"`          // Save cover art as separate .jpg file
+	FirstArtistOnly        bool             `json:"firstArtistOnly"`        // Strip featured artists from artist tag
+	AlbumArtistPolicy      string           `json:"albumArtistPolicy"`      // "full", "main", "first" - how ALBUMARTIST/{albumartist} is derived from the credit
+	AlternativeVideoMode   string           `json:"alternativeVideoMode"`   // "off", "suggest", "auto" - when the chosen video is unavailable
+	VideoVariant           string           `json:"videoVariant"`           // YouTube upload for streaming links: "video" (music video), "topic" (auto-generated audio) or "link" (song.link's)
+	MusicResolvers         []string         `json:"musicResolvers"`         // Resolver order: ["songlink", "musicbrainz"]
+	OdesliAPIKey           string           `json:"odesliApiKey"`           // Optional song.link API key (lifts rate limit)
+	YouTubeAPIKey          string           `json:"youtubeApiKey"`          // Optional YouTube Data API v3 key for playlist listing and metadata, "" = yt-dlp only
+	MuxBackend             string           `json:"muxBackend"`             // "ffmpeg", "mkvmerge" (falls back to ffmpeg)
+	AudioLanguage          string           `json:"audioLanguage"`          // ISO 639-2 language of the FLAC track, "" = undetermined
+	KeepOriginalAudio      bool             `json:"keepOriginalAudio"`      // Keep the YouTube audio as a second (non-default) track
+	SurroundMode           string           `json:"surroundMode"`           // "off", "prefer", "include" - Tidal Dolby Atmos/360RA mixes
+	GenreEnrichment        bool             `json:"genreEnrichment"`        // Look up genre from Last.fm/MusicBrainz tags
+	LastFMAPIKey           string           `json:"lastfmApiKey"`           // Optional Last.fm API key for genre lookup
+	NotifyEmailTo          string           `json:"notifyEmailTo"`          // Comma-separated recipients for email digests, "" = disabled
+	SMTPHost               string           `json:"smtpHost"`               // SMTP server for email digests
+	SMTPPort               int              `json:"smtpPort"`               // 587 (STARTTLS) or 465 (implicit TLS)
+	SMTPUsername           string           `json:"smtpUsername"`           // SMTP login, "" = no auth
+	SMTPPassword           string           `json:"smtpPassword"`           // SMTP password (kept in the secret store)
+	SMTPFrom               string           `json:"smtpFrom"`               // Sender address, defaults to the username
+	AppriseURL             string           `json:"appriseUrl"`             // Apprise API notify endpoint, "" = disabled
+	AppriseTargets         string           `json:"appriseTargets"`         // Apprise service URLs for the stateless endpoint
+	MQTTBrokerURL          string           `json:"mqttBrokerUrl"`          // "mqtt://homeassistant:1883" or "mqtts://..." - mirror queue events, "" = disabled
+	MQTTTopicPrefix        string           `json:"mqttTopicPrefix"`        // Topic prefix, "" = "youflac"
+	MQTTUsername           string           `json:"mqttUsername"`           // Broker login
+	MQTTPassword           string           `json:"mqttPassword"`           // Broker password (kept in the secret store)
+	NotifyFailureStreak    int              `json:"notifyFailureStreak"`    // Send a digest after this many failures in a row (0 = never)
+	NotifyQuietHours       string           `json:"notifyQuietHours"`       // "22:00-07:00" - hold notifications until the window ends
+	CompletedRetention     string           `json:"completedRetention"`     // "keep 200 items or 7 days" - prune finished queue items, "" = keep all
+	Schedule               string           `json:"schedule"`               // "01:00-07:00" only download then, "pause 17:00-23:00" never then, "" = always
+	DryRun                 bool             `json:"dryRun"`                 // Plan every queued item without downloading or muxing
+	StorageTargets         []string         `json:"storageTargets"`         // Remote copies: ["sftp://user@nas/music", "s3://bucket/prefix", "webdavs://host/dav"]
+	StorageMode            string           `json:"storageMode"`            // "copy" keeps local files, "move" removes them after verified uploads
+	StorageRetries         int              `json:"storageRetries"`         // Extra upload attempts per file and target
+	S3Endpoint             string           `json:"s3Endpoint"`             // S3-compatible endpoint, "" = AWS for S3Region
+	S3Region               string           `json:"s3Region"`               // e.g. "eu-west-1", "" = us-east-1
+	S3AccessKeyID          string           `json:"s3AccessKeyId"`          // S3 access key
+	S3SecretAccessKey      string           `json:"s3SecretAccessKey"`      // S3 secret key (kept in the secret store)
+	WebDAVUsername         string           `json:"webdavUsername"`         // WebDAV login, unless given in the target URL
+	WebDAVPassword         string           `json:"webdavPassword"`         // WebDAV password (kept in the secret store)
+	SFTPKeyFile            string           `json:"sftpKeyFile"`            // Private key for SFTP targets, "" = ssh agent/defaults
+	RcloneRemote           string           `json:"rcloneRemote"`           // rclone remote to mirror completed items to, e.g. "gdrive:Music", "" = disabled
+	RclonePathTemplate     string           `json:"rclonePathTemplate"`     // Remote folder template, e.g. "{albumartist}/{album}", "" = same layout as locally
+	MinVideoDuration       format.Seconds   `json:"minVideoDuration"`       // Seconds; shorter videos (Shorts, teasers) are skipped or flagged, 0 = no minimum
+	MaxVideoDuration       format.Seconds   `json:"maxVideoDuration"`       // Seconds; longer videos (10-hour loops) are skipped or flagged, 0 = no maximum
+	DurationPolicy         string           `json:"durationPolicy"`         // "skip" leaves out-of-range videos out, "flag" downloads them but marks the item
+	WatchlistIntervalHours float64          `json:"watchlistIntervalHours"` // How often watched artists are checked for new releases, 0 = manual checks only
+	ApprovalMode           string           `json:"approvalMode"`           // "off", "subscriptions" (channel and watchlist items) or "all" - items wait for approval before downloading
+	CoverCacheMB           format.Megabytes `json:"coverCacheMB"`           // Size limit of the shared cover art cache in the data dir, 0 = no caching
+	ArtworkAttempts        int              `json:"artworkAttempts"`        // Tries per cover art and thumbnail download, 0 = 3
+	FLACCompressionLevel   int              `json:"flacCompressionLevel"`   // 0 (fastest) to 8 (smallest) when FLAC has to be re-encoded; lossless at every level
+	ChildProcessPriority   string           `json:"childProcessPriority"`   // "normal", "low" or "idle" CPU and I/O priority for yt-dlp, ffmpeg and the other tools run
+	ChildEnvAllow          []string         `json:"childEnvAllow"`          // Extra variables (names or PREFIX_*) child processes inherit; the rest of the server's environment is withheld
+	ChildEnv               []string         `json:"childEnv"`               // "KEY=VALUE" variables set for every child process and hook (not LD_* or DYLD_*)
+	FLACVerify             bool             `json:"flacVerify"`             // Decode re-encoded lossless audio and compare it with the source samples
+	SilenceTrim            bool             `json:"silenceTrim"`            // Trim excess leading silence from the FLAC to keep A/V sync; off keeps quiet intros intact
+	SilenceThresholdDB     float64          `json:"silenceThresholdDb"`     // Level below which audio counts as silence for A/V sync, e.g. -50
+	SilenceMinDuration     float64          `json:"silenceMinDuration"`     // Seconds of silence needed before it counts for A/V sync
+	SyncPreview            bool             `json:"syncPreview"`            // Cut a 15 s clip around the first chorus of each MKV to check sync via the API
+	PlaylistLayout         string           `json:"playlistLayout"`         // "nested" (a folder per track) or "flat" (all tracks in the playlist folder)
+	UserAgent              string           `json:"userAgent"`              // Browser User-Agent for download and scraping services, "" = built-in
+	AppUserAgent           string           `json:"appUserAgent"`           // Identifies the app to APIs that ask for it (LRCLIB, MusicBrainz, song.link), "" = built-in
+	UserAgentOverrides     []string         `json:"userAgentOverrides"`     // Per service or host: ["lrclib=MyApp/1.0 (me@example.com)", "tidal=Mozilla/5.0 ..."]
+	ArtistPathOverrides    []string         `json:"artistPathOverrides"`    // Other base directories per artist: ["Pink Floyd=/mnt/archive/music", "the *=/mnt/b/music"]
+	MetadataRules          []string         `json:"metadataRules"`          // Regex rewrites of title/artist/album before matching and naming: ["title:\\s*\\(Remastered( \\d{4})?\\)="]
+	Libraries              []string         `json:"libraries"`              // Separate libraries per output mode: ["MusicVideos:video=/media/mv", "Music:audio=/media/music={albumartist}/{album}/{title}"]
+	LibraryViews           []string         `json:"libraryViews"`           // Link trees with another layout: ["/mnt/views/by-year={year}/{artist} - {title}"]
+	LibraryViewLinks       string           `json:"libraryViewLinks"`       // "symlink" or "hardlink" (view on the library's filesystem only)
+	DiscordBotToken        string           `json:"discordBotToken"`        // Bot token for "!grab <url>" commands, "" = disabled (kept in the secret store)
+	DiscordChannels        []string         `json:"discordChannels"`        // IDs of the channels the bot listens in
+	TelegramBotToken       string           `json:"telegramBotToken"`       // Bot token from @BotFather, "" = disabled (kept in the secret store)
+	TelegramChatIDs        []string         `json:"telegramChatIds"`        // Chats allowed to queue downloads (user or group IDs)
+	TelegramMaxUploadMB    format.Megabytes `json:"telegramMaxUploadMb"`    // Send finished files up to this size (max 50), larger ones as a link
+	TelegramLinkBaseURL    string           `json:"telegramLinkBaseUrl"`    // URL the library is served at, for links to large files, "" = send the path
+	JellyfinCollectionsDir string           `json:"jellyfinCollectionsDir"` // Jellyfin's data/collections directory, "" = no collections
+	CollectionGroups       []string         `json:"collectionGroups"`       // Collections to build: "playlist" (per playlist folder), "artist"
+	CollectionMinItems     int              `json:"collectionMinItems"`     // Artists with fewer files get no collection
+	JellyfinPlaylistsDir   string           `json:"jellyfinPlaylistsDir"`   // Jellyfin's data/playlists directory, "" = no playlists
+	JellyfinPathMap        string           `json:"jellyfinPathMap"`        // "local=jellyfin" library path prefix as Jellyfin sees it, "" = same paths
+	ShortcutKey            string           `json:"shortcutKey"`            // Key for GET /api/add (phone shortcuts), "" = endpoint disabled (kept in the secret store)
+	DownloadArchive        string           `json:"downloadArchive"`        // yt-dlp --download-archive file to append completed YouTube downloads to, "" = none
 }
 
 var defaultConfig = Config{
-	OutputDirectory:     "",
-	VideoQuality:        "best",
-	AudioSourcePriority: []string{"tidal", "qobuz", "amazon"},
-	NamingTemplate:      "{artist}/{title}/{title}",
-	GenerateNFO:         true,
-	ConcurrentDownloads: 2,
-	EmbedCoverArt:       true,
-	Theme:               "system",
-	AccentColor:         "pink",
-	SoundEffectsEnabled: true,
+	OutputDirectory:        "",
+	VideoQuality:           "best",
+	AudioSourcePriority:    []string{"tidal", "qobuz", "amazon"},
+	NamingTemplate:         "{artist}/{title}/{title}",
+	GenerateNFO:            true,
+	ProvenanceSidecar:      false,
+	ConcurrentDownloads:    2,
+	EmbedCoverArt:          true,
+	Theme:                  "system",
+	AccentColor:            "pink",
+	SoundEffectsEnabled:    true,
 	LyricsEnabled:          false,
 	LyricsEmbedMode:        "lrc",
 	LogLevel:               "info",
 	ProxyURL:               "",
 	DownloadTimeoutMinutes: 10,
+	DownloadConnections:    4,
+	StallTimeoutMinutes:    30,
+	StallRetries:           1,
+	FallbackPolicy:         FallbackYouTubeMusic,
+	LengthMode:             LengthModeOff,
 	PreferredQuality:       "highest",
 	GenerateM3U8:           false,
 	SkipExplicit:           false,
+	ExplicitPreference:     ExplicitAny,
 	SoundVolume:            70,
 	SaveCoverFile:          false,
 	FirstArtistOnly:        false,
+	AlbumArtistPolicy:      ArtistPolicyMain,
+	AlternativeVideoMode:   AlternativeVideoSuggest,
+	VideoVariant:           VideoVariantVideo,
+	MusicResolvers:         []string{ResolverSongLink, ResolverMusicBrainz},
+	GenreEnrichment:        false,
+	MuxBackend:             MuxBackendFFmpeg,
+	SurroundMode:           SurroundOff,
+	SMTPPort:               587,
+	NotifyFailureStreak:    DefaultNotifyFailureStreak,
+	FileMode:               "0644",
+	DirMode:                "0755",
+	StorageMode:            StorageModeCopy,
+	StorageRetries:         DefaultStorageRetries,
+	DurationPolicy:         DurationPolicySkip,
+	WatchlistIntervalHours: 6,
+	ApprovalMode:           ApprovalOff,
+	CoverCacheMB:           DefaultCoverCacheMB,
+	FLACCompressionLevel:   DefaultFLACCompressionLevel,
+	ChildProcessPriority:   ProcessPriorityNormal,
+	FLACVerify:             true,
+	SilenceTrim:            true,
+	SilenceThresholdDB:     DefaultSilenceThresholdDB,
+	SilenceMinDuration:     DefaultSilenceMinDuration,
+	PlaylistLayout:         PlaylistLayoutNested,
+	LibraryViewLinks:       ViewLinkSymlink,
+	TelegramMaxUploadMB:    TelegramUploadLimitMB,
+	CollectionGroups:       []string{CollectionGroupPlaylist},
+	CollectionMinItems:     2,
 }
 
 // GetConfigPath returns the path to the config file
@@ -84,20 +213,24 @@ func LoadConfig() (*Config, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Return default config if file doesn't exist
-			return &defaultConfig, nil
+			return defaultConfig.Clone(), nil
 		}
 		return nil, err
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	// Decode over the defaults so settings added since the file was written
+	// get their default instead of the zero value (e.g. silenceTrim)
+	config := defaultConfig.Clone()
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, err
 	}
+	FillConfigSecrets(config)
 
-	return &config, nil
+	return config, nil
 }
 
-// SaveConfig saves configuration to file
+// SaveConfig saves configuration to file. API keys go to the encrypted
+// secret store instead of the JSON file.
 func SaveConfig(config *Config) error {
 	configPath := GetConfigPath()
 
@@ -106,7 +239,12 @@ func SaveConfig(config *Config) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	stripped, err := stripConfigSecrets(config)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(stripped, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -126,8 +264,7 @@ func GetDefaultOutputDirectory() string {
 
 // GetDefaultConfig returns a copy of the default config
 func GetDefaultConfig() *Config {
-	config := defaultConfig
-	return &config
+	return defaultConfig.Clone()
 }
 
 // LoadConfigWithEnv loads config from file, then overrides with environment variables
@@ -142,6 +279,18 @@ func LoadConfigWithEnv() (*Config, error) {
 	if v := os.Getenv("OUTPUT_DIR"); v != "" {
 		config.OutputDirectory = v
 	}
+	if v := os.Getenv("TEMP_DIR"); v != "" {
+		config.TempDirectory = v
+	}
+	if v := os.Getenv("FILE_MODE"); v != "" {
+		config.FileMode = v
+	}
+	if v := os.Getenv("DIR_MODE"); v != "" {
+		config.DirMode = v
+	}
+	if v := os.Getenv("FILE_OWNER"); v != "" {
+		config.FileOwner = v
+	}
 	if v := os.Getenv("VIDEO_QUALITY"); v != "" {
 		config.VideoQuality = v
 	}
@@ -156,6 +305,15 @@ func LoadConfigWithEnv() (*Config, error) {
 	if v := os.Getenv("GENERATE_NFO"); v != "" {
 		config.GenerateNFO = strings.ToLower(v) == "true" || v == "1"
 	}
+	if v := os.Getenv("NFO_FIELDS"); v != "" {
+		config.NFOFields = strings.Split(strings.ToLower(v), ",")
+		if strings.EqualFold(v, "none") {
+			config.NFOFields = []string{}
+		}
+	}
+	if v := os.Getenv("PROVENANCE_SIDECAR"); v != "" {
+		config.ProvenanceSidecar = strings.ToLower(v) == "true" || v == "1"
+	}
 	if v := os.Getenv("EMBED_COVER_ART"); v != "" {
 		config.EmbedCoverArt = strings.ToLower(v) == "true" || v == "1"
 	}
@@ -174,6 +332,9 @@ func LoadConfigWithEnv() (*Config, error) {
 	if v := os.Getenv("LYRICS_EMBED_MODE"); v != "" {
 		config.LyricsEmbedMode = v
 	}
+	if v := os.Getenv("EXPLICIT_PREFERENCE"); v != "" {
+		config.ExplicitPreference = v
+	}
 	if v := os.Getenv("COOKIES_BROWSER"); v != "" {
 		config.CookiesBrowser = v
 	}
@@ -192,11 +353,301 @@ func LoadConfigWithEnv() (*Config, error) {
 	if v := os.Getenv("PROXY_URL"); v != "" {
 		config.ProxyURL = v
 	}
+	if v := os.Getenv("ALLOW_PRIVATE_URLS"); v != "" {
+		config.AllowPrivateURLs = strings.ToLower(v) == "true" || v == "1"
+	}
 	if v := os.Getenv("DOWNLOAD_TIMEOUT_MINUTES"); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
 			config.DownloadTimeoutMinutes = f
 		}
 	}
+	if v := os.Getenv("STALL_TIMEOUT_MINUTES"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			config.StallTimeoutMinutes = f
+		}
+	}
+	if v := os.Getenv("STALL_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.StallRetries = n
+		}
+	}
+	if v := os.Getenv("DOWNLOAD_CONNECTIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.DownloadConnections = n
+		}
+	}
+	if v := os.Getenv("FALLBACK_POLICY"); v != "" {
+		config.FallbackPolicy = strings.ToLower(v)
+	}
+	if v := os.Getenv("LENGTH_MODE"); v != "" {
+		config.LengthMode = strings.ToLower(v)
+	}
+	if v := os.Getenv("FIRST_ARTIST_ONLY"); v != "" {
+		config.FirstArtistOnly = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("ALBUM_ARTIST_POLICY"); v != "" {
+		config.AlbumArtistPolicy = strings.ToLower(v)
+	}
+	if v := os.Getenv("ALTERNATIVE_VIDEO_MODE"); v != "" {
+		config.AlternativeVideoMode = strings.ToLower(v)
+	}
+	if v := os.Getenv("VIDEO_VARIANT"); v != "" {
+		config.VideoVariant = strings.ToLower(v)
+	}
+	if v := os.Getenv("MUSIC_RESOLVERS"); v != "" {
+		resolvers := strings.Split(v, ",")
+		for i := range resolvers {
+			resolvers[i] = strings.TrimSpace(resolvers[i])
+		}
+		config.MusicResolvers = resolvers
+	}
+	if v := os.Getenv("ODESLI_API_KEY"); v != "" {
+		config.OdesliAPIKey = v
+	}
+	if v := os.Getenv("YOUTUBE_API_KEY"); v != "" {
+		config.YouTubeAPIKey = v
+	}
+	if v := os.Getenv("MUX_BACKEND"); v != "" {
+		config.MuxBackend = strings.ToLower(v)
+	}
+	if v := os.Getenv("AUDIO_LANGUAGE"); v != "" {
+		config.AudioLanguage = v
+	}
+	if v := os.Getenv("KEEP_ORIGINAL_AUDIO"); v != "" {
+		config.KeepOriginalAudio = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("SURROUND_MODE"); v != "" {
+		config.SurroundMode = strings.ToLower(v)
+	}
+	if v := os.Getenv("GENRE_ENRICHMENT"); v != "" {
+		config.GenreEnrichment = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("LASTFM_API_KEY"); v != "" {
+		config.LastFMAPIKey = v
+	}
+	if v := os.Getenv("NOTIFY_EMAIL_TO"); v != "" {
+		config.NotifyEmailTo = v
+	}
+	if v := os.Getenv("SMTP_HOST"); v != "" {
+		config.SMTPHost = v
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.SMTPPort = n
+		}
+	}
+	if v := os.Getenv("SMTP_USERNAME"); v != "" {
+		config.SMTPUsername = v
+	}
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		config.SMTPPassword = v
+	}
+	if v := os.Getenv("SMTP_FROM"); v != "" {
+		config.SMTPFrom = v
+	}
+	if v := os.Getenv("APPRISE_URL"); v != "" {
+		config.AppriseURL = v
+	}
+	if v := os.Getenv("APPRISE_TARGETS"); v != "" {
+		config.AppriseTargets = v
+	}
+	if v := os.Getenv("MQTT_BROKER_URL"); v != "" {
+		config.MQTTBrokerURL = v
+	}
+	if v := os.Getenv("MQTT_TOPIC_PREFIX"); v != "" {
+		config.MQTTTopicPrefix = v
+	}
+	if v := os.Getenv("MQTT_USERNAME"); v != "" {
+		config.MQTTUsername = v
+	}
+	if v := os.Getenv("MQTT_PASSWORD"); v != "" {
+		config.MQTTPassword = v
+	}
+	if v := os.Getenv("NOTIFY_FAILURE_STREAK"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.NotifyFailureStreak = n
+		}
+	}
+	if v := os.Getenv("NOTIFY_QUIET_HOURS"); v != "" {
+		config.NotifyQuietHours = v
+	}
+	if v := os.Getenv("COMPLETED_RETENTION"); v != "" {
+		config.CompletedRetention = v
+	}
+	if v := os.Getenv("DOWNLOAD_SCHEDULE"); v != "" {
+		config.Schedule = v
+	}
+	if v := os.Getenv("DRY_RUN"); v != "" {
+		config.DryRun = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("STORAGE_TARGETS"); v != "" {
+		config.StorageTargets = splitList(v)
+	}
+	if v := os.Getenv("STORAGE_MODE"); v != "" {
+		config.StorageMode = strings.ToLower(v)
+	}
+	if v := os.Getenv("STORAGE_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.StorageRetries = n
+		}
+	}
+	if v := os.Getenv("S3_ENDPOINT"); v != "" {
+		config.S3Endpoint = v
+	}
+	if v := os.Getenv("S3_REGION"); v != "" {
+		config.S3Region = v
+	}
+	if v := os.Getenv("S3_ACCESS_KEY_ID"); v != "" {
+		config.S3AccessKeyID = v
+	}
+	if v := os.Getenv("S3_SECRET_ACCESS_KEY"); v != "" {
+		config.S3SecretAccessKey = v
+	}
+	if v := os.Getenv("WEBDAV_USERNAME"); v != "" {
+		config.WebDAVUsername = v
+	}
+	if v := os.Getenv("WEBDAV_PASSWORD"); v != "" {
+		config.WebDAVPassword = v
+	}
+	if v := os.Getenv("SFTP_KEY_FILE"); v != "" {
+		config.SFTPKeyFile = v
+	}
+	if v := os.Getenv("RCLONE_REMOTE"); v != "" {
+		config.RcloneRemote = v
+	}
+	if v := os.Getenv("RCLONE_PATH_TEMPLATE"); v != "" {
+		config.RclonePathTemplate = v
+	}
+	if v := os.Getenv("MIN_VIDEO_DURATION"); v != "" {
+		if n, err := format.ParseSeconds(v); err == nil {
+			config.MinVideoDuration = n
+		}
+	}
+	if v := os.Getenv("MAX_VIDEO_DURATION"); v != "" {
+		if n, err := format.ParseSeconds(v); err == nil {
+			config.MaxVideoDuration = n
+		}
+	}
+	if v := os.Getenv("DURATION_POLICY"); v != "" {
+		config.DurationPolicy = v
+	}
+	if v := os.Getenv("WATCHLIST_INTERVAL_HOURS"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			config.WatchlistIntervalHours = f
+		}
+	}
+	if v := os.Getenv("APPROVAL_MODE"); v != "" {
+		config.ApprovalMode = v
+	}
+	if v := os.Getenv("COVER_CACHE_MB"); v != "" {
+		if n, err := format.ParseMegabytes(v); err == nil {
+			config.CoverCacheMB = n
+		}
+	}
+	if v := os.Getenv("ARTWORK_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.ArtworkAttempts = n
+		}
+	}
+	if v := os.Getenv("FLAC_COMPRESSION_LEVEL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.FLACCompressionLevel = n
+		}
+	}
+	if v := os.Getenv("CHILD_PROCESS_PRIORITY"); v != "" {
+		config.ChildProcessPriority = v
+	}
+	if v := os.Getenv("CHILD_ENV_ALLOW"); v != "" {
+		config.ChildEnvAllow = strings.Split(v, ",")
+	}
+	if v := os.Getenv("FLAC_VERIFY"); v != "" {
+		config.FLACVerify = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("SILENCE_TRIM"); v != "" {
+		config.SilenceTrim = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("SILENCE_THRESHOLD_DB"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			config.SilenceThresholdDB = f
+		}
+	}
+	if v := os.Getenv("SILENCE_MIN_DURATION"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			config.SilenceMinDuration = f
+		}
+	}
+	if v := os.Getenv("SYNC_PREVIEW"); v != "" {
+		config.SyncPreview = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("PLAYLIST_LAYOUT"); v != "" {
+		config.PlaylistLayout = v
+	}
+	if v := os.Getenv("USER_AGENT"); v != "" {
+		config.UserAgent = v
+	}
+	if v := os.Getenv("APP_USER_AGENT"); v != "" {
+		config.AppUserAgent = v
+	}
+	if v := os.Getenv("USER_AGENT_OVERRIDES"); v != "" {
+		// "|"-separated: user agents contain commas, semicolons and spaces
+		config.UserAgentOverrides = strings.Split(v, "|")
+	}
+	if v := os.Getenv("ARTIST_PATH_OVERRIDES"); v != "" {
+		// "|"-separated: artist names and paths contain commas and spaces
+		config.ArtistPathOverrides = strings.Split(v, "|")
+	}
+	if v := os.Getenv("METADATA_RULES"); v != "" {
+		// One rule per line: patterns contain "|", commas and spaces
+		config.MetadataRules = strings.Split(v, "\n")
+	}
+	if v := os.Getenv("LIBRARIES"); v != "" {
+		// "|"-separated like the other directory lists
+		config.Libraries = strings.Split(v, "|")
+	}
+	if v := os.Getenv("LIBRARY_VIEWS"); v != "" {
+		config.LibraryViews = strings.Split(v, "|")
+	}
+	if v := os.Getenv("LIBRARY_VIEW_LINKS"); v != "" {
+		config.LibraryViewLinks = strings.ToLower(v)
+	}
+	if v := os.Getenv("DISCORD_BOT_TOKEN"); v != "" {
+		config.DiscordBotToken = v
+	}
+	if v := os.Getenv("DISCORD_CHANNELS"); v != "" {
+		config.DiscordChannels = strings.Split(v, ",")
+	}
+	if v := os.Getenv("TELEGRAM_BOT_TOKEN"); v != "" {
+		config.TelegramBotToken = v
+	}
+	if v := os.Getenv("TELEGRAM_CHAT_IDS"); v != "" {
+		config.TelegramChatIDs = strings.Split(v, ",")
+	}
+	if v := os.Getenv("TELEGRAM_MAX_UPLOAD_MB"); v != "" {
+		if n, err := format.ParseMegabytes(v); err == nil {
+			config.TelegramMaxUploadMB = n
+		}
+	}
+	if v := os.Getenv("TELEGRAM_LINK_BASE_URL"); v != "" {
+		config.TelegramLinkBaseURL = v
+	}
+	if v := os.Getenv("JELLYFIN_COLLECTIONS_DIR"); v != "" {
+		config.JellyfinCollectionsDir = v
+	}
+	if v := os.Getenv("COLLECTION_GROUPS"); v != "" {
+		config.CollectionGroups = strings.Split(strings.ToLower(v), ",")
+	}
+	if v := os.Getenv("JELLYFIN_PLAYLISTS_DIR"); v != "" {
+		config.JellyfinPlaylistsDir = v
+	}
+	if v := os.Getenv("JELLYFIN_PATH_MAP"); v != "" {
+		config.JellyfinPathMap = v
+	}
+	if v := os.Getenv("SHORTCUT_KEY"); v != "" {
+		config.ShortcutKey = v
+	}
+	if v := os.Getenv("DOWNLOAD_ARCHIVE"); v != "" {
+		config.DownloadArchive = v
+	}
 
 	return config, nil
 }
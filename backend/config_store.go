@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// =============================================================================
+// Config store
+// =============================================================================
+
+// The App, the API server, the queue and its workers share one ConfigStore.
+// Each published Config is an immutable snapshot: readers call Get once and
+// keep using that value, so a worker sees one consistent configuration for a
+// whole item, and a save takes effect from the next item onwards. Changes
+// are made on a copy and published with Set or Update, never in place.
+
+// ConfigStore holds the current configuration snapshot
+type ConfigStore struct {
+	current  atomic.Pointer[Config]
+	updateMu sync.Mutex // Serializes Update so concurrent edits are not lost
+}
+
+// NewConfigStore creates a store publishing a copy of config (nil = unset)
+func NewConfigStore(config *Config) *ConfigStore {
+	s := &ConfigStore{}
+	s.Set(config)
+	return s
+}
+
+// Get returns the current snapshot, or nil if no config was set. The
+// returned Config is shared and must not be modified.
+func (s *ConfigStore) Get() *Config {
+	return s.current.Load()
+}
+
+// Set publishes a copy of config; later changes to config are not seen
+func (s *ConfigStore) Set(config *Config) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	s.current.Store(config.Clone())
+}
+
+// Update applies fn to a copy of the current config and publishes it.
+// Returns the new snapshot.
+func (s *ConfigStore) Update(fn func(config *Config)) *Config {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	next := s.current.Load().Clone()
+	if next == nil {
+		next = GetDefaultConfig()
+	}
+	fn(next)
+	s.current.Store(next)
+	return next
+}
+
+// Clone returns a deep copy of the config (nil for a nil config)
+func (c *Config) Clone() *Config {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.AudioSourcePriority = slices.Clone(c.AudioSourcePriority)
+	clone.MusicResolvers = slices.Clone(c.MusicResolvers)
+	clone.StorageTargets = slices.Clone(c.StorageTargets)
+	clone.UserAgentOverrides = slices.Clone(c.UserAgentOverrides)
+	clone.ArtistPathOverrides = slices.Clone(c.ArtistPathOverrides)
+	clone.Libraries = slices.Clone(c.Libraries)
+	clone.MetadataRules = slices.Clone(c.MetadataRules)
+	clone.LibraryViews = slices.Clone(c.LibraryViews)
+	clone.DiscordChannels = slices.Clone(c.DiscordChannels)
+	clone.TelegramChatIDs = slices.Clone(c.TelegramChatIDs)
+	clone.CollectionGroups = slices.Clone(c.CollectionGroups)
+	clone.NFOFields = slices.Clone(c.NFOFields)
+	clone.ChildEnvAllow = slices.Clone(c.ChildEnvAllow)
+	clone.ChildEnv = slices.Clone(c.ChildEnv)
+	return &clone
+}
+
+// ApplyConfig publishes config to the queue's store and configures every
+// subsystem that keeps its own copy of a setting. Secret fields left blank
+// are filled from the secret store first. Every entry point (startup, saved
+// settings, first-run setup) goes through here so none of them drift. The
+// returned error is the temp directory check; the other settings are still
+// applied.
+func ApplyConfig(config *Config, queue *Queue) error {
+	FillConfigSecrets(config)
+	queue.SetConfig(config)
+
+	ConfigureMusicResolvers(config)
+	ConfigureOutputPermissions(config)
+	ConfigureYouTubeAPI(config)
+	ConfigureMQTT(config)
+	ConfigureCoverCache(config)
+	ConfigureFLACEncoding(config)
+	ConfigureProcessPriority(config)
+	ConfigureChildEnv(config)
+	ConfigureURLPolicy(config)
+	ConfigureUserAgents(config)
+	err := ConfigureTempDirectory(config)
+
+	// Chat bots: "!grab <url>" on Discord, links sent on Telegram
+	ConfigureDiscord(config, queue)
+	ConfigureTelegram(config, queue)
+	return err
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConfigStore(t *testing.T) {
+	config := GetDefaultConfig()
+	config.AudioSourcePriority = []string{"tidal", "qobuz"}
+	store := NewConfigStore(config)
+
+	// The store keeps its own copy
+	config.VideoQuality = "720p"
+	config.AudioSourcePriority[0] = "amazon"
+	snapshot := store.Get()
+	if snapshot.VideoQuality != "best" || snapshot.AudioSourcePriority[0] != "tidal" {
+		t.Fatalf("snapshot changed with the caller's config: %+v", snapshot)
+	}
+
+	// Updates publish a new snapshot and leave old ones alone
+	updated := store.Update(func(c *Config) {
+		c.VideoQuality = "1080p"
+		c.AudioSourcePriority = append(c.AudioSourcePriority[:0], "qobuz")
+	})
+	if store.Get() != updated || updated.VideoQuality != "1080p" {
+		t.Errorf("Get = %+v, want the updated snapshot", store.Get())
+	}
+	if snapshot.VideoQuality != "best" || snapshot.AudioSourcePriority[0] != "tidal" {
+		t.Errorf("old snapshot was modified: %+v", snapshot)
+	}
+
+	// Concurrent updates are not lost
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Update(func(c *Config) { c.ConcurrentDownloads++ })
+		}()
+	}
+	wg.Wait()
+	if got := store.Get().ConcurrentDownloads; got != config.ConcurrentDownloads+50 {
+		t.Errorf("ConcurrentDownloads = %d, want %d", got, config.ConcurrentDownloads+50)
+	}
+
+	if NewConfigStore(nil).Get() != nil {
+		t.Error("unset store should return nil")
+	}
+}
+
+func TestQueueConfigSnapshot(t *testing.T) {
+	store := NewConfigStore(&Config{VideoQuality: "best"})
+	q := newTestQueue()
+	q.SetConfigStore(store)
+
+	before := q.configs.Get()
+	q.SetConfig(&Config{VideoQuality: "720p"})
+	if store.Get().VideoQuality != "720p" {
+		t.Error("SetConfig should publish to the shared store")
+	}
+	if before.VideoQuality != "best" {
+		t.Error("a snapshot taken before the save must not change")
+	}
+}
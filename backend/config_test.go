@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLegacyConfig writes a config.json from before the settings added by
+// later versions and points GetConfigPath at it
+func writeLegacyConfig(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("CONFIG_DIR", filepath.Join(home, "data"))
+	legacy := `{"outputDirectory": "/music", "videoQuality": "1080p", "namingTemplate": "{artist}/{title}", "generateNfo": true, "concurrentDownloads": 3}`
+	if err := os.MkdirAll(filepath.Dir(GetConfigPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(GetConfigPath(), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig_LegacyFileKeepsSilenceTrim(t *testing.T) {
+	writeLegacyConfig(t)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.VideoQuality != "1080p" || config.ConcurrentDownloads != 3 {
+		t.Errorf("stored settings not loaded: %+v", config)
+	}
+	opts := muxOptionsFromConfig(config)
+	if opts.NoSilenceTrim || opts.SilenceThresholdDB != DefaultSilenceThresholdDB || opts.SilenceMinDuration != DefaultSilenceMinDuration {
+		t.Errorf("mux options = %+v, want the default silence trim", opts)
+	}
+	if defaultConfig.OutputDirectory != "" {
+		t.Error("LoadConfig modified the defaults")
+	}
+}
+
+func TestLoadConfig_LegacyFileKeepsFLACEncoding(t *testing.T) {
+	writeLegacyConfig(t)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ConfigureFLACEncoding(config)
+	defer ConfigureFLACEncoding(GetDefaultConfig())
+
+	args := flacEncodeArgs()
+	if level := args[len(args)-1]; level != "8" || !flacVerifyEnabled() {
+		t.Errorf("FLAC encoding = level %s, verify %v; want level 8 with verification", level, flacVerifyEnabled())
+	}
+}
+
+func TestLoadConfig_LegacyFileEnablesWatchdogAndWatchlist(t *testing.T) {
+	writeLegacyConfig(t)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StallTimeoutMinutes != defaultConfig.StallTimeoutMinutes || config.StallRetries != defaultConfig.StallRetries {
+		t.Errorf("stall watchdog = %g min, %d retries; want the defaults", config.StallTimeoutMinutes, config.StallRetries)
+	}
+	if config.WatchlistIntervalHours != defaultConfig.WatchlistIntervalHours {
+		t.Errorf("watchlistIntervalHours = %g, want the default", config.WatchlistIntervalHours)
+	}
+}
+
+func TestLoadConfig_LegacyFileLeavesProvenanceSidecarOff(t *testing.T) {
+	writeLegacyConfig(t)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ProvenanceSidecar {
+		t.Error("provenanceSidecar is on without opting in")
+	}
+}
+
+func TestLoadConfig_LegacyFileLeavesGenreEnrichmentOff(t *testing.T) {
+	writeLegacyConfig(t)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GenreEnrichment {
+		t.Error("genreEnrichment is on without opting in")
+	}
+}
@@ -0,0 +1,675 @@
+package backend
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"youflac/backend/format"
+)
+
+// Config validation. Validate normalizes values that have an obvious fix
+// (case, whitespace, out-of-range numbers, unknown enum values) and reports
+// them as warnings; values that can't be fixed safely are reported as errors.
+
+// ConfigIssue is a single validation finding for one config field
+type ConfigIssue struct {
+	Field   string `json:"field"`   // JSON name of the field, e.g. "namingTemplate"
+	Message string `json:"message"` // What is wrong and how to fix it
+}
+
+// ConfigValidation is the result of Config.Validate
+type ConfigValidation struct {
+	Errors   []ConfigIssue `json:"errors"`
+	Warnings []ConfigIssue `json:"warnings"`
+}
+
+// Valid reports whether the config has no errors (warnings are allowed)
+func (v *ConfigValidation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// Err returns the errors as a single error, or nil if the config is valid
+func (v *ConfigValidation) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	msgs := make([]string, len(v.Errors))
+	for i, e := range v.Errors {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return fmt.Errorf("invalid config: %s", strings.Join(msgs, "; "))
+}
+
+func (v *ConfigValidation) errorf(field, format string, args ...interface{}) {
+	v.Errors = append(v.Errors, ConfigIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *ConfigValidation) warnf(field, format string, args ...interface{}) {
+	v.Warnings = append(v.Warnings, ConfigIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Accepted values for the enum-like config fields
+var (
+	validVideoQualities      = []string{"best", "2160p", "1440p", "1080p", "720p", "480p", "360p"}
+	validThemes              = []string{"dark", "light", "system"}
+	validAccentColors        = []string{"pink", "blue", "green", "purple", "orange", "teal", "red", "yellow"}
+	validCookiesBrowsers     = []string{"firefox", "chrome", "chromium", "brave", "opera", "edge", "safari", "vivaldi", "librewolf"}
+	validLyricsEmbedModes    = []string{string(LyricsEmbedFile), string(LyricsEmbedLRC), string(LyricsEmbedBoth)}
+	validLogLevels           = []string{"debug", "info", "warn", "error"}
+	validPreferredQualities  = []string{"highest", "24bit", "16bit"}
+	validArtistPolicies      = []string{ArtistPolicyFull, ArtistPolicyMain, ArtistPolicyFirst}
+	validAlternativeModes    = []string{AlternativeVideoOff, AlternativeVideoSuggest, AlternativeVideoAuto}
+	validMusicResolvers      = []string{ResolverSongLink, ResolverMusicBrainz}
+	validMuxBackends         = []string{MuxBackendFFmpeg, MuxBackendMKVMerge}
+	validSurroundModes       = []string{SurroundOff, SurroundPrefer, SurroundInclude}
+	validExplicitPreferences = []string{ExplicitAny, ExplicitPrefer, ExplicitAvoid}
+	validVideoVariants       = []string{VideoVariantVideo, VideoVariantTopic, VideoVariantLink}
+	validProxySchemes        = []string{"http", "https", "socks5", "socks5h"}
+	validFallbackPolicies    = []string{FallbackYouTubeMusic, FallbackExtract, FallbackUpgrade, FallbackFail}
+)
+
+// languageCodePattern matches ISO 639-2 codes such as "eng" or "jpn"
+var languageCodePattern = regexp.MustCompile(`^[a-z]{3}$`)
+
+// envNamePattern matches an environment variable name or a prefix ending in
+// "*" ("*" alone is every variable), in upper case
+var envNamePattern = regexp.MustCompile(`^([A-Z0-9_().]+\*?|\*)$`)
+
+// Validate normalizes the config in place and reports errors and warnings.
+// Fields with unknown values are reset to their default.
+func (c *Config) Validate() *ConfigValidation {
+	v := &ConfigValidation{}
+
+	// Paths and templates
+	c.OutputDirectory = strings.TrimSpace(c.OutputDirectory)
+	if err := ValidateOutputDirectory(c.OutputDirectory); err != nil {
+		v.errorf("outputDirectory", "%v", err)
+	} else if c.OutputDirectory != "" && !filepath.IsAbs(c.OutputDirectory) {
+		v.warnf("outputDirectory", "relative path %q is resolved against the working directory; use an absolute path", c.OutputDirectory)
+	}
+
+	c.NamingTemplate = resolveNamingTemplate(strings.TrimSpace(c.NamingTemplate))
+	if c.NamingTemplate == "" {
+		c.NamingTemplate = defaultConfig.NamingTemplate
+		v.warnf("namingTemplate", "empty template, using %q", c.NamingTemplate)
+	} else if err := ValidateTemplate(c.NamingTemplate); err != nil {
+		v.errorf("namingTemplate", "%v", err)
+	}
+
+	// NFO fields: null keeps all of them, an empty list none
+	if c.NFOFields != nil {
+		fields := []string{}
+		for _, field := range c.NFOFields {
+			field = strings.ToLower(strings.TrimSpace(field))
+			if field == "" || containsString(fields, field) {
+				continue
+			}
+			if !containsString(NFOFields, field) {
+				v.warnf("nfoFields", "unknown NFO field %q was removed (known: %s)", field, strings.Join(NFOFields, ", "))
+				continue
+			}
+			fields = append(fields, field)
+		}
+		c.NFOFields = fields
+	}
+
+	// Audio sources: drop unknown entries, keep the order of the rest
+	var sources []string
+	seen := make(map[string]bool)
+	for _, s := range c.AudioSourcePriority {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch {
+		case s == "" || seen[s]:
+			continue
+		case !validAudioSources[s]:
+			v.warnf("audioSourcePriority", "unknown audio source %q was removed (known: tidal, qobuz, amazon, deezer)", s)
+			continue
+		}
+		seen[s] = true
+		sources = append(sources, s)
+	}
+	if len(sources) == 0 {
+		sources = append([]string(nil), defaultConfig.AudioSourcePriority...)
+		if len(c.AudioSourcePriority) > 0 {
+			v.warnf("audioSourcePriority", "no known audio sources left, using %s", strings.Join(sources, ", "))
+		}
+	}
+	c.AudioSourcePriority = sources
+
+	var resolvers []string
+	for _, r := range c.MusicResolvers {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if !containsString(validMusicResolvers, r) {
+			v.warnf("musicResolvers", "unknown resolver %q was removed (known: %s)", r, strings.Join(validMusicResolvers, ", "))
+			continue
+		}
+		resolvers = append(resolvers, r)
+	}
+	if len(resolvers) == 0 && len(c.MusicResolvers) > 0 {
+		resolvers = append([]string(nil), defaultConfig.MusicResolvers...)
+	}
+	c.MusicResolvers = resolvers
+
+	// Numbers
+	if c.ConcurrentDownloads == 0 {
+		c.ConcurrentDownloads = defaultConfig.ConcurrentDownloads
+	} else if c.ConcurrentDownloads < 1 || c.ConcurrentDownloads > 10 {
+		clamped := clampInt(c.ConcurrentDownloads, 1, 10)
+		v.warnf("concurrentDownloads", "%d is out of range 1-10, using %d", c.ConcurrentDownloads, clamped)
+		c.ConcurrentDownloads = clamped
+	}
+	if c.DownloadTimeoutMinutes < 0 {
+		v.warnf("downloadTimeoutMinutes", "negative timeout %g, using the default", c.DownloadTimeoutMinutes)
+		c.DownloadTimeoutMinutes = 0
+	}
+	if c.StallTimeoutMinutes < 0 {
+		v.warnf("stallTimeoutMinutes", "negative timeout %g, disabling the stall watchdog", c.StallTimeoutMinutes)
+		c.StallTimeoutMinutes = 0
+	}
+	if c.StallRetries < 0 || c.StallRetries > 10 {
+		clamped := clampInt(c.StallRetries, 0, 10)
+		v.warnf("stallRetries", "%d is out of range 0-10, using %d", c.StallRetries, clamped)
+		c.StallRetries = clamped
+	}
+	if c.DownloadConnections == 0 {
+		c.DownloadConnections = defaultConfig.DownloadConnections
+	} else if c.DownloadConnections < 1 || c.DownloadConnections > maxDownloadConnections {
+		clamped := clampInt(c.DownloadConnections, 1, maxDownloadConnections)
+		v.warnf("downloadConnections", "%d is out of range 1-%d, using %d", c.DownloadConnections, maxDownloadConnections, clamped)
+		c.DownloadConnections = clamped
+	}
+	if c.SoundVolume < 0 || c.SoundVolume > 100 {
+		clamped := clampInt(c.SoundVolume, 0, 100)
+		v.warnf("soundVolume", "%d is out of range 0-100, using %d", c.SoundVolume, clamped)
+		c.SoundVolume = clamped
+	}
+
+	// Enums
+	c.VideoQuality = normalizeEnum(v, "videoQuality", c.VideoQuality, validVideoQualities, defaultConfig.VideoQuality)
+	c.Theme = normalizeEnum(v, "theme", c.Theme, validThemes, defaultConfig.Theme)
+	c.AccentColor = normalizeEnum(v, "accentColor", c.AccentColor, validAccentColors, defaultConfig.AccentColor)
+	c.LyricsEmbedMode = normalizeEnum(v, "lyricsEmbedMode", c.LyricsEmbedMode, validLyricsEmbedModes, defaultConfig.LyricsEmbedMode)
+	c.LogLevel = normalizeEnum(v, "logLevel", c.LogLevel, validLogLevels, defaultConfig.LogLevel)
+	c.PreferredQuality = normalizeEnum(v, "preferredQuality", c.PreferredQuality, validPreferredQualities, defaultConfig.PreferredQuality)
+	c.AlbumArtistPolicy = normalizeEnum(v, "albumArtistPolicy", c.AlbumArtistPolicy, validArtistPolicies, defaultConfig.AlbumArtistPolicy)
+	c.AlternativeVideoMode = normalizeEnum(v, "alternativeVideoMode", c.AlternativeVideoMode, validAlternativeModes, defaultConfig.AlternativeVideoMode)
+	c.VideoVariant = normalizeEnum(v, "videoVariant", c.VideoVariant, validVideoVariants, defaultConfig.VideoVariant)
+	c.MuxBackend = normalizeEnum(v, "muxBackend", c.MuxBackend, validMuxBackends, defaultConfig.MuxBackend)
+	c.SurroundMode = normalizeEnum(v, "surroundMode", c.SurroundMode, validSurroundModes, defaultConfig.SurroundMode)
+	c.ExplicitPreference = normalizeEnum(v, "explicitPreference", c.ExplicitPreference, validExplicitPreferences, defaultConfig.ExplicitPreference)
+	c.FallbackPolicy = normalizeEnum(v, "fallbackPolicy", c.FallbackPolicy, validFallbackPolicies, defaultConfig.FallbackPolicy)
+	c.LengthMode = normalizeEnum(v, "lengthMode", c.LengthMode, validLengthModes, defaultConfig.LengthMode)
+
+	// Cookies browser may carry a profile ("firefox:default-release")
+	c.CookiesBrowser = strings.TrimSpace(c.CookiesBrowser)
+	if c.CookiesBrowser != "" {
+		name, profile, _ := strings.Cut(c.CookiesBrowser, ":")
+		name = strings.ToLower(name)
+		if !containsString(validCookiesBrowsers, name) {
+			v.errorf("cookiesBrowser", "unknown browser %q (supported: %s)", name, strings.Join(validCookiesBrowsers, ", "))
+		} else if profile != "" {
+			c.CookiesBrowser = name + ":" + profile
+		} else {
+			c.CookiesBrowser = name
+		}
+	}
+
+	c.ProxyURL = strings.TrimSpace(c.ProxyURL)
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil || u.Host == "" {
+			v.errorf("proxyUrl", "invalid proxy URL %q, expected e.g. socks5://127.0.0.1:1080", c.ProxyURL)
+		} else if !containsString(validProxySchemes, strings.ToLower(u.Scheme)) {
+			v.errorf("proxyUrl", "unsupported proxy scheme %q (supported: %s)", u.Scheme, strings.Join(validProxySchemes, ", "))
+		}
+	}
+
+	c.AudioLanguage = strings.ToLower(strings.TrimSpace(c.AudioLanguage))
+	if c.AudioLanguage != "" && !languageCodePattern.MatchString(c.AudioLanguage) {
+		v.warnf("audioLanguage", "%q is not an ISO 639-2 code (e.g. \"eng\"), the track will be left undetermined", c.AudioLanguage)
+		c.AudioLanguage = ""
+	}
+
+	// Notifications
+	c.NotifyEmailTo = strings.TrimSpace(c.NotifyEmailTo)
+	for _, addr := range splitList(c.NotifyEmailTo) {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			v.errorf("notifyEmailTo", "invalid email address %q", addr)
+		}
+	}
+	if c.NotifyEmailTo != "" && strings.TrimSpace(c.SMTPHost) == "" {
+		v.errorf("smtpHost", "an SMTP server is required to send email notifications")
+	}
+	if c.SMTPPort < 0 || c.SMTPPort > 65535 {
+		v.errorf("smtpPort", "invalid port %d", c.SMTPPort)
+	}
+	c.AppriseURL = strings.TrimSpace(c.AppriseURL)
+	if c.AppriseURL != "" {
+		if u, err := url.Parse(c.AppriseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			v.errorf("appriseUrl", "invalid Apprise URL %q, expected e.g. http://apprise:8000/notify/youflac", c.AppriseURL)
+		}
+	}
+	c.MQTTBrokerURL = strings.TrimSpace(c.MQTTBrokerURL)
+	if c.MQTTBrokerURL != "" {
+		if _, err := ParseMQTTBroker(c.MQTTBrokerURL); err != nil {
+			v.errorf("mqttBrokerUrl", "%v", err)
+		}
+	}
+	if strings.ContainsAny(c.MQTTTopicPrefix, "+#") {
+		v.errorf("mqttTopicPrefix", "topic prefix %q must not contain MQTT wildcards", c.MQTTTopicPrefix)
+	}
+	c.DiscordBotToken = strings.TrimSpace(c.DiscordBotToken)
+	var discordChannels []string
+	for _, id := range c.DiscordChannels {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			v.warnf("discordChannels", "%q was removed, expected a channel ID (enable Developer Mode and use Copy Channel ID)", id)
+			continue
+		}
+		discordChannels = append(discordChannels, id)
+	}
+	c.DiscordChannels = discordChannels
+	if c.DiscordBotToken != "" && len(c.DiscordChannels) == 0 {
+		v.warnf("discordChannels", "the Discord bot only listens in configured channels; add at least one channel ID")
+	}
+	c.TelegramBotToken = strings.TrimSpace(c.TelegramBotToken)
+	var telegramChats []string
+	for _, id := range c.TelegramChatIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			v.warnf("telegramChatIds", "%q was removed, expected a numeric chat ID", id)
+			continue
+		}
+		telegramChats = append(telegramChats, id)
+	}
+	c.TelegramChatIDs = telegramChats
+	if c.TelegramBotToken != "" && len(c.TelegramChatIDs) == 0 {
+		v.warnf("telegramChatIds", "the Telegram bot only answers whitelisted chats; add at least one chat ID")
+	}
+	if c.TelegramMaxUploadMB < 0 || c.TelegramMaxUploadMB > TelegramUploadLimitMB {
+		clamped := format.Megabytes(clampInt(int(c.TelegramMaxUploadMB), 0, TelegramUploadLimitMB))
+		v.warnf("telegramMaxUploadMb", "%d is out of range 0-%d (the Bot API upload limit), using %d", c.TelegramMaxUploadMB, TelegramUploadLimitMB, clamped)
+		c.TelegramMaxUploadMB = clamped
+	}
+	c.TelegramLinkBaseURL = strings.TrimSpace(c.TelegramLinkBaseURL)
+	if c.TelegramLinkBaseURL != "" {
+		if u, err := url.Parse(c.TelegramLinkBaseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			v.errorf("telegramLinkBaseUrl", "invalid URL %q, expected e.g. https://media.example.com/music", c.TelegramLinkBaseURL)
+		}
+	}
+	c.ShortcutKey = strings.TrimSpace(c.ShortcutKey)
+	if c.ShortcutKey != "" && len(c.ShortcutKey) < 16 {
+		v.warnf("shortcutKey", "the key is sent in the URL of /api/add; use at least 16 random characters")
+	}
+	if c.NotifyFailureStreak < 0 {
+		v.warnf("notifyFailureStreak", "negative streak %d, failure digests disabled", c.NotifyFailureStreak)
+		c.NotifyFailureStreak = 0
+	}
+	if _, err := ParseDailyWindow(c.NotifyQuietHours); err != nil {
+		v.errorf("notifyQuietHours", "%v", err)
+	}
+
+	c.CompletedRetention = strings.TrimSpace(c.CompletedRetention)
+	if _, err := ParseRetentionPolicy(c.CompletedRetention); err != nil {
+		v.errorf("completedRetention", "%v", err)
+	}
+
+	c.Schedule = strings.TrimSpace(c.Schedule)
+	if _, err := ParseDownloadSchedule(c.Schedule); err != nil {
+		v.errorf("schedule", "%v", err)
+	}
+
+	c.TempDirectory = strings.TrimSpace(c.TempDirectory)
+	if c.TempDirectory != "" && !filepath.IsAbs(c.TempDirectory) {
+		v.warnf("tempDirectory", "relative path %q is resolved against the working directory; use an absolute path", c.TempDirectory)
+	}
+
+	// Library permissions
+	c.FileMode = strings.TrimSpace(c.FileMode)
+	if c.FileMode == "" {
+		c.FileMode = "0644"
+	} else if mode, err := ParseFileMode(c.FileMode); err != nil {
+		v.errorf("fileMode", "%v", err)
+	} else if mode&0400 == 0 {
+		v.warnf("fileMode", "mode %s makes library files unreadable by their owner", c.FileMode)
+	}
+	c.DirMode = strings.TrimSpace(c.DirMode)
+	if c.DirMode == "" {
+		c.DirMode = "0755"
+	} else if mode, err := ParseFileMode(c.DirMode); err != nil {
+		v.errorf("dirMode", "%v", err)
+	} else if mode&0100 == 0 {
+		v.warnf("dirMode", "mode %s makes library folders impossible to enter for their owner", c.DirMode)
+	}
+	c.FileOwner = strings.TrimSpace(c.FileOwner)
+	if _, _, err := ParseFileOwner(c.FileOwner); err != nil {
+		v.errorf("fileOwner", "%v", err)
+	} else if c.FileOwner != "" && runtime.GOOS == "windows" {
+		v.warnf("fileOwner", "changing file ownership is not supported on Windows, ignored")
+	}
+
+	// Remote storage
+	c.StorageMode = normalizeEnum(v, "storageMode", c.StorageMode, []string{StorageModeCopy, StorageModeMove}, StorageModeCopy)
+	if c.StorageRetries < 0 || c.StorageRetries > 10 {
+		clamped := clampInt(c.StorageRetries, 0, 10)
+		v.warnf("storageRetries", "%d is out of range 0-10, using %d", c.StorageRetries, clamped)
+		c.StorageRetries = clamped
+	}
+	for _, target := range c.StorageTargets {
+		if _, err := NewStorageBackend(target, c); err != nil {
+			v.errorf("storageTargets", "%v", err)
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(target), "s3://") && (c.S3AccessKeyID == "" || c.S3SecretAccessKey == "") {
+			v.errorf("s3AccessKeyId", "S3 credentials are required for %s", target)
+		}
+	}
+	c.S3Endpoint = strings.TrimSpace(c.S3Endpoint)
+	if c.S3Endpoint != "" {
+		if u, err := url.Parse(c.S3Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			v.errorf("s3Endpoint", "invalid S3 endpoint %q, expected e.g. http://minio:9000", c.S3Endpoint)
+		}
+	}
+
+	c.RcloneRemote = strings.TrimSpace(c.RcloneRemote)
+	if c.RcloneRemote != "" && !strings.Contains(c.RcloneRemote, ":") {
+		v.errorf("rcloneRemote", "invalid rclone remote %q, expected e.g. gdrive:Music", c.RcloneRemote)
+	}
+	if c.RclonePathTemplate != "" {
+		if err := ValidateTemplate(c.RclonePathTemplate); err != nil {
+			v.errorf("rclonePathTemplate", "%v", err)
+		}
+	}
+
+	// Duration limits
+	if c.MinVideoDuration < 0 {
+		v.warnf("minVideoDuration", "%d is negative, disabling the minimum", c.MinVideoDuration)
+		c.MinVideoDuration = 0
+	}
+	if c.MaxVideoDuration < 0 {
+		v.warnf("maxVideoDuration", "%d is negative, disabling the maximum", c.MaxVideoDuration)
+		c.MaxVideoDuration = 0
+	}
+	if c.MaxVideoDuration > 0 && c.MinVideoDuration > c.MaxVideoDuration {
+		v.errorf("maxVideoDuration", "maximum duration %ds is below the minimum %ds", c.MaxVideoDuration, c.MinVideoDuration)
+	}
+	c.DurationPolicy = normalizeEnum(v, "durationPolicy", c.DurationPolicy, []string{DurationPolicySkip, DurationPolicyFlag}, DurationPolicySkip)
+
+	if c.WatchlistIntervalHours < 0 {
+		v.warnf("watchlistIntervalHours", "negative interval %g, disabling scheduled watchlist checks", c.WatchlistIntervalHours)
+		c.WatchlistIntervalHours = 0
+	}
+	c.ApprovalMode = normalizeEnum(v, "approvalMode", c.ApprovalMode, []string{ApprovalOff, ApprovalSubscriptions, ApprovalAll}, ApprovalOff)
+	if c.CoverCacheMB < 0 {
+		v.warnf("coverCacheMB", "%d is negative, disabling the cover art cache", c.CoverCacheMB)
+		c.CoverCacheMB = 0
+	}
+	if c.ArtworkAttempts < 0 || c.ArtworkAttempts > 10 {
+		clamped := clampInt(c.ArtworkAttempts, 0, 10)
+		v.warnf("artworkAttempts", "%d is out of range 0-10, using %d", c.ArtworkAttempts, clamped)
+		c.ArtworkAttempts = clamped
+	}
+	if c.FLACCompressionLevel < 0 || c.FLACCompressionLevel > 8 {
+		clamped := clampInt(c.FLACCompressionLevel, 0, 8)
+		v.warnf("flacCompressionLevel", "%d is out of range 0-8, using %d", c.FLACCompressionLevel, clamped)
+		c.FLACCompressionLevel = clamped
+	}
+	c.ChildProcessPriority = normalizeEnum(v, "childProcessPriority", c.ChildProcessPriority, validProcessPriorities, ProcessPriorityNormal)
+	var allow []string
+	for _, name := range c.ChildEnvAllow {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case !envNamePattern.MatchString(name):
+			v.warnf("childEnvAllow", "invalid variable name %q was removed", name)
+		case !containsString(allow, name):
+			allow = append(allow, name)
+		}
+	}
+	c.ChildEnvAllow = allow
+	var env []string
+	for _, kv := range c.ChildEnv {
+		key, value, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envNamePattern.MatchString(strings.ToUpper(key)) || strings.HasSuffix(key, "*") {
+			v.warnf("childEnv", "%q is not a KEY=VALUE assignment and was removed", kv)
+			continue
+		}
+		if envNameMatches(deniedChildEnv, key) {
+			v.warnf("childEnv", "%s is a dynamic loader variable and was removed", key)
+			continue
+		}
+		env = append(env, key+"="+value)
+	}
+	c.ChildEnv = env
+	c.PlaylistLayout = normalizeEnum(v, "playlistLayout", c.PlaylistLayout, []string{PlaylistLayoutNested, PlaylistLayoutFlat}, PlaylistLayoutNested)
+	if c.SilenceThresholdDB == 0 {
+		c.SilenceThresholdDB = DefaultSilenceThresholdDB
+	} else if c.SilenceThresholdDB > 0 || c.SilenceThresholdDB < -120 {
+		v.warnf("silenceThresholdDb", "%g dB is out of range -120 to 0, using %g", c.SilenceThresholdDB, DefaultSilenceThresholdDB)
+		c.SilenceThresholdDB = DefaultSilenceThresholdDB
+	}
+	if c.SilenceMinDuration == 0 {
+		c.SilenceMinDuration = DefaultSilenceMinDuration
+	} else if c.SilenceMinDuration < 0 || c.SilenceMinDuration > 10 {
+		v.warnf("silenceMinDuration", "%gs is out of range 0-10s, using %g", c.SilenceMinDuration, DefaultSilenceMinDuration)
+		c.SilenceMinDuration = DefaultSilenceMinDuration
+	}
+
+	// User agents: overrides are "service=agent" or "host=agent"
+	c.UserAgent = strings.TrimSpace(c.UserAgent)
+	c.AppUserAgent = strings.TrimSpace(c.AppUserAgent)
+	var overrides []string
+	for _, entry := range c.UserAgentOverrides {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, agent, ok := parseUserAgentOverride(entry)
+		switch {
+		case !ok:
+			v.warnf("userAgentOverrides", "%q was removed, expected service=agent", entry)
+			continue
+		case !strings.Contains(key, ".") && !slices.Contains(userAgentServiceNames(), key):
+			v.warnf("userAgentOverrides", "unknown service %q was removed (known: %s, or a host name)", key, strings.Join(userAgentServiceNames(), ", "))
+			continue
+		}
+		overrides = append(overrides, key+"="+agent)
+	}
+	c.UserAgentOverrides = overrides
+
+	// Per-artist directories: "pattern=directory", same rules as OutputDirectory
+	var artistPaths []string
+	for _, entry := range c.ArtistPathOverrides {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		pattern, dir, ok := parseArtistPathOverride(entry)
+		if !ok {
+			v.warnf("artistPathOverrides", "%q was removed, expected artist=directory", entry)
+			continue
+		}
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			v.warnf("artistPathOverrides", "%q was removed: invalid pattern %q", entry, pattern)
+			continue
+		}
+		if err := ValidateOutputDirectory(dir); err != nil {
+			v.warnf("artistPathOverrides", "%q was removed: %v", entry, err)
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			v.warnf("artistPathOverrides", "relative path %q is resolved against the working directory; use an absolute path", dir)
+		}
+		artistPaths = append(artistPaths, pattern+"="+dir)
+	}
+	c.ArtistPathOverrides = artistPaths
+
+	// Libraries: "name:mode=directory[=template]", one per output mode, not
+	// nested in each other so every file belongs to one library
+	var libraries []string
+	var kept []Library
+	for _, entry := range c.Libraries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		lib, ok := parseLibrary(entry)
+		if !ok {
+			v.warnf("libraries", "%q was removed, expected name:mode=directory[=template]", entry)
+			continue
+		}
+		if lib.OutputMode != OutputModeVideo && lib.OutputMode != OutputModeAudio {
+			v.warnf("libraries", "%q was removed: output mode %q is not %s or %s", entry, lib.OutputMode, OutputModeVideo, OutputModeAudio)
+			continue
+		}
+		if lib.Template != "" {
+			lib.Template = resolveNamingTemplate(lib.Template)
+			if err := ValidateTemplate(lib.Template); err != nil {
+				v.warnf("libraries", "%q was removed: %v", entry, err)
+				continue
+			}
+		}
+		if err := ValidateOutputDirectory(lib.Directory); err != nil {
+			v.warnf("libraries", "%q was removed: %v", entry, err)
+			continue
+		}
+		if i := slices.IndexFunc(kept, func(other Library) bool {
+			return other.Name == lib.Name || other.OutputMode == lib.OutputMode ||
+				isWithin(other.Directory, lib.Directory) || isWithin(lib.Directory, other.Directory)
+		}); i >= 0 {
+			v.warnf("libraries", "%q was removed: it clashes with library %s (names, output modes and directories must differ)", entry, kept[i].Name)
+			continue
+		}
+		if !filepath.IsAbs(lib.Directory) {
+			v.warnf("libraries", "relative path %q is resolved against the working directory; use an absolute path", lib.Directory)
+		}
+		kept = append(kept, lib)
+		libraries = append(libraries, lib.String())
+	}
+	c.Libraries = libraries
+
+	// Metadata rules: "[field:]pattern=replacement" with a valid regexp
+	var metadataRules []string
+	for _, entry := range c.MetadataRules {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		if _, err := ParseMetadataRule(entry); err != nil {
+			v.warnf("metadataRules", "%q was removed: %v", entry, err)
+			continue
+		}
+		metadataRules = append(metadataRules, entry)
+	}
+	c.MetadataRules = metadataRules
+
+	// Library views: "directory=template", kept outside the library so they are not indexed twice
+	c.LibraryViewLinks = normalizeEnum(v, "libraryViewLinks", c.LibraryViewLinks, []string{ViewLinkSymlink, ViewLinkHardlink}, ViewLinkSymlink)
+	var views []string
+	for _, entry := range c.LibraryViews {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		view, ok := parseLibraryView(entry)
+		if !ok {
+			v.warnf("libraryViews", "%q was removed, expected directory=template", entry)
+			continue
+		}
+		if err := ValidateTemplate(view.Template); err != nil {
+			v.warnf("libraryViews", "%q was removed: %v", entry, err)
+			continue
+		}
+		if err := ValidateOutputDirectory(view.Directory); err != nil {
+			v.warnf("libraryViews", "%q was removed: %v", entry, err)
+			continue
+		}
+		if dir := overlappingLibraryDirectory(c, view.Directory); dir != "" {
+			v.warnf("libraryViews", "%q was removed: the view directory overlaps the library directory %s", entry, dir)
+			continue
+		}
+		views = append(views, view.Directory+"="+view.Template)
+	}
+	c.LibraryViews = views
+
+	// Jellyfin collections
+	c.JellyfinCollectionsDir = strings.TrimSpace(c.JellyfinCollectionsDir)
+	c.JellyfinPlaylistsDir = strings.TrimSpace(c.JellyfinPlaylistsDir)
+	var groups []string
+	for _, group := range c.CollectionGroups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group == "" || containsString(groups, group) {
+			continue
+		}
+		if group != CollectionGroupPlaylist && group != CollectionGroupArtist {
+			v.warnf("collectionGroups", "%q was removed (supported: %s, %s)", group, CollectionGroupPlaylist, CollectionGroupArtist)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	c.CollectionGroups = groups
+	if c.CollectionMinItems < 1 {
+		v.warnf("collectionMinItems", "%d is below 1, using 1", c.CollectionMinItems)
+		c.CollectionMinItems = 1
+	}
+	c.JellyfinPathMap = strings.TrimSpace(c.JellyfinPathMap)
+	if c.JellyfinPathMap != "" {
+		if _, _, ok := parseJellyfinPathMap(c.JellyfinPathMap); !ok {
+			v.warnf("jellyfinPathMap", "%q was removed, expected local=jellyfin, e.g. /downloads=/media/music-videos", c.JellyfinPathMap)
+			c.JellyfinPathMap = ""
+		}
+	}
+
+	// Combinations that are valid but probably not what the user wants
+	if c.SurroundMode != SurroundOff && !containsString(c.AudioSourcePriority, "tidal") {
+		v.warnf("surroundMode", "surround mixes only come from Tidal, which is not in the audio source priority")
+	}
+
+	return v
+}
+
+// normalizeEnum lowercases value and checks it against allowed; empty values
+// silently take the default, unknown ones take it with a warning
+func normalizeEnum(v *ConfigValidation, field, value string, allowed []string, def string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return def
+	}
+	if !containsString(allowed, value) {
+		v.warnf(field, "unknown value %q, using %q (allowed: %s)", value, def, strings.Join(allowed, ", "))
+		return def
+	}
+	return value
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
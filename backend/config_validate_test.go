@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func hasIssue(issues []ConfigIssue, field string) bool {
+	for _, i := range issues {
+		if i.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConfigValidate_Defaults(t *testing.T) {
+	config := GetDefaultConfig()
+	v := config.Validate()
+	if !v.Valid() || len(v.Warnings) != 0 {
+		t.Errorf("default config should validate cleanly, got errors=%v warnings=%v", v.Errors, v.Warnings)
+	}
+}
+
+func TestConfigValidate_Normalizes(t *testing.T) {
+	config := GetDefaultConfig()
+	config.ConcurrentDownloads = -3
+	config.SoundVolume = 250
+	config.DownloadConnections = 64
+	config.Theme = " DARK "
+	config.LogLevel = "verbose"
+	config.NamingTemplate = "Plex"
+	config.AudioSourcePriority = []string{"Qobuz", "napster", "tidal", "qobuz"}
+	config.CookiesBrowser = "Firefox:default-release"
+	config.AudioLanguage = "english"
+
+	v := config.Validate()
+	if !v.Valid() {
+		t.Fatalf("expected only warnings, got errors: %v", v.Errors)
+	}
+
+	if config.ConcurrentDownloads != 1 || !hasIssue(v.Warnings, "concurrentDownloads") {
+		t.Errorf("ConcurrentDownloads = %d, want clamped to 1 with a warning", config.ConcurrentDownloads)
+	}
+	if config.SoundVolume != 100 {
+		t.Errorf("SoundVolume = %d, want 100", config.SoundVolume)
+	}
+	if config.DownloadConnections != maxDownloadConnections || !hasIssue(v.Warnings, "downloadConnections") {
+		t.Errorf("DownloadConnections = %d, want clamped to %d with a warning", config.DownloadConnections, maxDownloadConnections)
+	}
+	if config.Theme != "dark" {
+		t.Errorf("Theme = %q, want dark", config.Theme)
+	}
+	if config.LogLevel != "info" || !hasIssue(v.Warnings, "logLevel") {
+		t.Errorf("LogLevel = %q, want reset to info with a warning", config.LogLevel)
+	}
+	if config.NamingTemplate != "{artist}/{title}" {
+		t.Errorf("NamingTemplate = %q, want preset name resolved", config.NamingTemplate)
+	}
+	if got := strings.Join(config.AudioSourcePriority, ","); got != "qobuz,tidal" {
+		t.Errorf("AudioSourcePriority = %s, want qobuz,tidal", got)
+	}
+	if config.CookiesBrowser != "firefox:default-release" {
+		t.Errorf("CookiesBrowser = %q, want profile kept", config.CookiesBrowser)
+	}
+	if config.AudioLanguage != "" || !hasIssue(v.Warnings, "audioLanguage") {
+		t.Errorf("AudioLanguage = %q, want cleared with a warning", config.AudioLanguage)
+	}
+}
+
+func TestConfigValidate_Errors(t *testing.T) {
+	config := GetDefaultConfig()
+	config.OutputDirectory = "/etc/music"
+	config.NamingTemplate = "no placeholders"
+	config.ProxyURL = "ftp://proxy:21"
+	config.CookiesBrowser = "netscape"
+
+	v := config.Validate()
+	if v.Valid() {
+		t.Fatal("expected validation errors")
+	}
+	for _, field := range []string{"outputDirectory", "namingTemplate", "proxyUrl", "cookiesBrowser"} {
+		if !hasIssue(v.Errors, field) {
+			t.Errorf("expected an error for %s, got %v", field, v.Errors)
+		}
+	}
+	if err := v.Err(); err == nil || !strings.Contains(err.Error(), "namingTemplate") {
+		t.Errorf("Err() = %v, want it to name the failing fields", err)
+	}
+}
+
+func TestConfigValidate_SurroundWithoutTidal(t *testing.T) {
+	config := GetDefaultConfig()
+	config.SurroundMode = "Include"
+	config.AudioSourcePriority = []string{"qobuz"}
+
+	v := config.Validate()
+	if config.SurroundMode != SurroundInclude {
+		t.Errorf("SurroundMode = %q, want include", config.SurroundMode)
+	}
+	if !hasIssue(v.Warnings, "surroundMode") {
+		t.Error("expected a warning when surround is enabled without Tidal")
+	}
+}
+
+func TestConfigValidate_SilenceThresholds(t *testing.T) {
+	config := GetDefaultConfig()
+	config.SilenceThresholdDB = 0
+	config.SilenceMinDuration = 0
+	if v := config.Validate(); len(v.Warnings) != 0 || config.SilenceThresholdDB != DefaultSilenceThresholdDB || config.SilenceMinDuration != DefaultSilenceMinDuration {
+		t.Errorf("unset thresholds should take the defaults quietly, got %v, %g, %g", v.Warnings, config.SilenceThresholdDB, config.SilenceMinDuration)
+	}
+
+	config.SilenceThresholdDB = 6
+	config.SilenceMinDuration = -1
+	v := config.Validate()
+	if !hasIssue(v.Warnings, "silenceThresholdDb") || !hasIssue(v.Warnings, "silenceMinDuration") {
+		t.Errorf("expected warnings, got %v", v.Warnings)
+	}
+	if config.SilenceThresholdDB != DefaultSilenceThresholdDB || config.SilenceMinDuration != DefaultSilenceMinDuration {
+		t.Errorf("thresholds = %g, %g, want defaults", config.SilenceThresholdDB, config.SilenceMinDuration)
+	}
+}
+
+func TestConfigValidate_ShortcutKey(t *testing.T) {
+	config := GetDefaultConfig()
+	config.ShortcutKey = " abc "
+	if v := config.Validate(); !hasIssue(v.Warnings, "shortcutKey") || config.ShortcutKey != "abc" {
+		t.Errorf("short key: warnings=%v key=%q", v.Warnings, config.ShortcutKey)
+	}
+
+	config.ShortcutKey = "k3vX9q2LmT7wRb4Zp8Yd"
+	if v := config.Validate(); hasIssue(v.Warnings, "shortcutKey") {
+		t.Errorf("unexpected warning for a long key: %v", v.Warnings)
+	}
+}
+
+func TestConfigValidate_NFOFields(t *testing.T) {
+	config := GetDefaultConfig()
+	config.NFOFields = []string{" Premiered", "sorttitle", "premiered", "rating"}
+	v := config.Validate()
+	if !hasIssue(v.Warnings, "nfoFields") || strings.Join(config.NFOFields, ",") != "premiered,sorttitle" {
+		t.Errorf("nfoFields = %v, warnings=%v", config.NFOFields, v.Warnings)
+	}
+
+	// An empty list turns all optional fields off instead of meaning "all"
+	config.NFOFields = []string{"rating"}
+	config.Validate()
+	if config.NFOFields == nil || len(config.NFOFields) != 0 {
+		t.Errorf("nfoFields = %#v, want an empty list", config.NFOFields)
+	}
+}
+
+func TestConfig_DurationAndSizeInputs(t *testing.T) {
+	var config Config
+	data := `{"minVideoDuration": "1:00", "maxVideoDuration": "1h30m", "coverCacheMB": "1,5 GB", "telegramMaxUploadMb": 20}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.MinVideoDuration != 60 || config.MaxVideoDuration != 5400 || config.CoverCacheMB != 1536 || config.TelegramMaxUploadMB != 20 {
+		t.Errorf("decoded min=%d max=%d cover=%d telegram=%d", config.MinVideoDuration, config.MaxVideoDuration, config.CoverCacheMB, config.TelegramMaxUploadMB)
+	}
+	if err := json.Unmarshal([]byte(`{"minVideoDuration": "a minute"}`), &config); err == nil {
+		t.Error("decoded an invalid duration")
+	}
+}
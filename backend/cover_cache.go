@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"youflac/backend/format"
+)
+
+// =============================================================================
+// Cover art cache
+// =============================================================================
+
+// Cover art and posters are cached in the data directory, keyed by a hash of
+// the image URL, so tracks sharing an album cover and re-downloads of the
+// same video fetch each image once. The cache is bounded by
+// Config.CoverCacheMB; the least recently used images are evicted first.
+//
+// Images are downloaded with the shared Downloader, retried up to
+// Config.ArtworkAttempts times, and must be images: a CDN answering with an
+// HTML error page fails with its content type instead of an ffmpeg error.
+// JPEGs are stored as they are; ffmpeg only converts other formats.
+
+// DefaultCoverCacheMB is the default cover art cache size
+const DefaultCoverCacheMB = 200
+
+var (
+	coverCacheMaxBytes int64  = DefaultCoverCacheMB << 20
+	artworkAttempts    int    // Config.ArtworkAttempts, 0 = the Downloader's default
+	coverCacheRoot     string // "" = GetDataPath()/covers
+	coverCacheMutex    sync.Mutex
+	coverInflight      = make(map[string]chan struct{})
+
+	// coverConverter fetches and converts one image to JPEG; replaced in tests
+	coverConverter = convertCoverArt
+)
+
+// ConfigureCoverCache applies Config.CoverCacheMB (0 disables the cache) and
+// Config.ArtworkAttempts
+func ConfigureCoverCache(config *Config) {
+	coverCacheMutex.Lock()
+	coverCacheMaxBytes = int64(max(config.CoverCacheMB, 0)) << 20
+	artworkAttempts = max(config.ArtworkAttempts, 0)
+	coverCacheMutex.Unlock()
+	evictCoverCache()
+}
+
+// GetCoverCacheDir returns the directory holding cached cover art
+func GetCoverCacheDir() string {
+	if coverCacheRoot != "" {
+		return coverCacheRoot
+	}
+	return filepath.Join(GetDataPath(), "covers")
+}
+
+// coverCacheKey is the file name an image URL is cached under
+func coverCacheKey(imageURL string) string {
+	sum := sha1.Sum([]byte(imageURL))
+	return hex.EncodeToString(sum[:]) + ".jpg"
+}
+
+// cachedCoverArt returns the cached JPEG for imageURL, fetching it on a miss.
+// Concurrent requests for the same URL share one download.
+func cachedCoverArt(imageURL string) (string, error) {
+	key := coverCacheKey(imageURL)
+	path := filepath.Join(GetCoverCacheDir(), key)
+
+	for {
+		coverCacheMutex.Lock()
+		if fileExists(path) {
+			coverCacheMutex.Unlock()
+			// Mark as recently used for eviction
+			now := time.Now()
+			os.Chtimes(path, now, now)
+			return path, nil
+		}
+		wait, busy := coverInflight[key]
+		if !busy {
+			coverInflight[key] = make(chan struct{})
+			coverCacheMutex.Unlock()
+			break
+		}
+		coverCacheMutex.Unlock()
+		<-wait
+	}
+
+	defer func() {
+		coverCacheMutex.Lock()
+		close(coverInflight[key])
+		delete(coverInflight, key)
+		coverCacheMutex.Unlock()
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cover cache: %w", err)
+	}
+	tmp := path + ".tmp.jpg"
+	if err := coverConverter(imageURL, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to store cover art: %w", err)
+	}
+	evictCoverCache()
+	return path, nil
+}
+
+// evictCoverCache removes the least recently used images until the cache
+// fits Config.CoverCacheMB
+func evictCoverCache() {
+	coverCacheMutex.Lock()
+	defer coverCacheMutex.Unlock()
+
+	entries, err := os.ReadDir(GetCoverCacheDir())
+	if err != nil {
+		return
+	}
+	type cachedFile struct {
+		path   string
+		size   int64
+		usedAt time.Time
+	}
+	var files []cachedFile
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp.jpg") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cachedFile{filepath.Join(GetCoverCacheDir(), entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	if total <= coverCacheMaxBytes {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].usedAt.Before(files[j].usedAt) })
+	removed := 0
+	for _, file := range files {
+		if total <= coverCacheMaxBytes {
+			break
+		}
+		if err := os.Remove(file.path); err != nil {
+			continue
+		}
+		total -= file.size
+		removed++
+	}
+	slog.Debug("evicted cover art", "files", removed, "remaining", format.FileSize(total))
+}
+
+// coverHTTPClient fetches cover art and thumbnails
+var coverHTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: sharedTransport,
+}
+
+// fetchImage downloads a remote image to a temporary file; anything else (a
+// local path) is used as is. Returns the downloaded image's content type,
+// "" for local files; cleanup removes the temporary file.
+func fetchImage(imageURL string) (path, contentType string, cleanup func(), err error) {
+	if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+		// ffmpeg would open any of its protocols (file:, ftp:, concat: ...)
+		if !isLocalPath(imageURL) {
+			return "", "", nil, fmt.Errorf("unsupported image URL %q", imageURL)
+		}
+		return imageURL, "", func() {}, nil
+	}
+
+	f, err := os.CreateTemp(GetTempDirectory(), "image-*.download")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	path = f.Name()
+	f.Close()
+
+	coverCacheMutex.Lock()
+	attempts := artworkAttempts
+	coverCacheMutex.Unlock()
+	downloader := &Downloader{Client: coverHTTPClient, Attempts: attempts}
+	if err := downloader.Download(context.Background(), imageURL, path, ExpectedFile{}); err != nil {
+		os.Remove(path)
+		return "", "", nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	contentType, err = sniffImage(path)
+	if err != nil {
+		os.Remove(path)
+		return "", "", nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	return path, contentType, func() { os.Remove(path) }, nil
+}
+
+// sniffImage returns the content type of the image at path. Formats Go
+// doesn't recognise (AVIF, HEIC) come back as application/octet-stream and
+// are left to ffmpeg; text, HTML and the like are an error.
+func sniffImage(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if n == 0 {
+		return "", fmt.Errorf("image is empty")
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/octet-stream" {
+		return "", fmt.Errorf("not an image: got %s", contentType)
+	}
+	return contentType, nil
+}
+
+// convertCoverArt downloads an image and stores it as a JPEG, converting it
+// with ffmpeg unless it is one already
+func convertCoverArt(imageURL, outputPath string) error {
+	source, contentType, cleanup, err := fetchImage(imageURL)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if contentType == "image/jpeg" {
+		return copyFile(source, outputPath)
+	}
+	ffmpegPath := GetFFmpegPath()
+	if ffmpegPath == "" {
+		return fmt.Errorf("ffmpeg not found, cannot convert %s cover art to JPEG", contentType)
+	}
+
+	args := []string{
+		"-y",
+		"-i", source,
+		"-vframes", "1",
+		"-q:v", "2",
+		outputPath,
+	}
+
+	cmd := newCommand(ffmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to convert poster: %w, output: %s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCoverCache points the cache at a temp dir and counts image fetches
+func fakeCoverCache(t *testing.T, maxBytes int64) *atomic.Int32 {
+	oldRoot, oldMax, oldConverter := coverCacheRoot, coverCacheMaxBytes, coverConverter
+	coverCacheRoot = t.TempDir()
+	coverCacheMaxBytes = maxBytes
+	fetches := &atomic.Int32{}
+	coverConverter = func(imageURL, outputPath string) error {
+		fetches.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return os.WriteFile(outputPath, make([]byte, 100), 0644)
+	}
+	t.Cleanup(func() { coverCacheRoot, coverCacheMaxBytes, coverConverter = oldRoot, oldMax, oldConverter })
+	return fetches
+}
+
+func TestDownloadPoster_Cached(t *testing.T) {
+	fetches := fakeCoverCache(t, 1<<20)
+	out := t.TempDir()
+
+	// Tracks of one album fetch the shared cover once, even concurrently
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := DownloadPoster("https://example.com/album.jpg", filepath.Join(out, "track", fmt.Sprintf("%d.jpg", i))); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches.Load() != 1 {
+		t.Errorf("fetched %d times, want 1", fetches.Load())
+	}
+	if info, err := os.Stat(filepath.Join(out, "track", "2.jpg")); err != nil || info.Size() != 100 {
+		t.Errorf("poster copy = %v, %v", info, err)
+	}
+
+	// Disabled cache downloads directly
+	coverCacheMaxBytes = 0
+	DownloadPoster("https://example.com/album.jpg", filepath.Join(out, "direct.jpg"))
+	if fetches.Load() != 2 {
+		t.Errorf("disabled cache: fetched %d times, want 2", fetches.Load())
+	}
+}
+
+func TestCoverCache_Eviction(t *testing.T) {
+	fakeCoverCache(t, 250) // Room for two 100-byte images
+
+	first, _ := cachedCoverArt("https://example.com/1.jpg")
+	second, _ := cachedCoverArt("https://example.com/2.jpg")
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(first, old, old)
+	os.Chtimes(second, old.Add(time.Minute), old.Add(time.Minute))
+
+	// A hit marks the first image as recently used, so the second is evicted
+	cachedCoverArt("https://example.com/1.jpg")
+	cachedCoverArt("https://example.com/3.jpg")
+	if !fileExists(first) || fileExists(second) {
+		t.Errorf("first kept = %v, second kept = %v", fileExists(first), fileExists(second))
+	}
+
+	ConfigureCoverCache(&Config{CoverCacheMB: 0})
+	if entries, _ := os.ReadDir(GetCoverCacheDir()); len(entries) != 0 {
+		t.Errorf("disabling the cache left %d files", len(entries))
+	}
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ISRC-based duplicate detection across the indexed library.
+// Files sharing an ISRC are the same recording even when titles differ
+// ("Song" vs "Song (2011 Remaster)") or formats differ (MKV vs FLAC).
+
+// DuplicateGroup is a set of files sharing one ISRC
+type DuplicateGroup struct {
+	ISRC               string           `json:"isrc"`
+	Files              []FileIndexEntry `json:"files"`
+	Keep               string           `json:"keep"`               // Path suggested to keep
+	SuggestedDeletions []string         `json:"suggestedDeletions"` // Paths that can be removed
+	ReclaimableBytes   int64            `json:"reclaimableBytes"`
+}
+
+// DedupeReport summarizes duplicate recordings in the library
+type DedupeReport struct {
+	Groups           []DuplicateGroup `json:"groups"`
+	TotalFiles       int              `json:"totalFiles"`
+	DuplicateFiles   int              `json:"duplicateFiles"` // Files suggested for deletion
+	ReclaimableBytes int64            `json:"reclaimableBytes"`
+	GeneratedAt      time.Time        `json:"generatedAt"`
+}
+
+// DedupeReport groups indexed files by ISRC and suggests which copies to delete.
+// Entries whose files no longer exist are ignored. Nothing is deleted.
+func (fi *FileIndex) DedupeReport() *DedupeReport {
+	byISRC := make(map[string][]FileIndexEntry)
+	seen := make(map[string]bool)
+	total := 0
+	for _, entries := range fi.snapshot() {
+		for _, entry := range entries {
+			if seen[entry.Path] {
+				continue
+			}
+			seen[entry.Path] = true
+			total++
+			if entry.ISRC != "" {
+				byISRC[entry.ISRC] = append(byISRC[entry.ISRC], entry)
+			}
+		}
+	}
+
+	report := &DedupeReport{
+		Groups:      []DuplicateGroup{},
+		TotalFiles:  total,
+		GeneratedAt: time.Now(),
+	}
+
+	for isrc, entries := range byISRC {
+		var existing []FileIndexEntry
+		for _, e := range entries {
+			if _, err := os.Stat(e.Path); err == nil {
+				existing = append(existing, e)
+			}
+		}
+		if len(existing) < 2 {
+			continue
+		}
+
+		sort.SliceStable(existing, func(i, j int) bool {
+			return preferForKeep(existing[i], existing[j])
+		})
+
+		group := DuplicateGroup{
+			ISRC:  isrc,
+			Files: existing,
+			Keep:  existing[0].Path,
+		}
+		for _, e := range existing[1:] {
+			group.SuggestedDeletions = append(group.SuggestedDeletions, e.Path)
+			group.ReclaimableBytes += e.Size
+		}
+
+		report.Groups = append(report.Groups, group)
+		report.DuplicateFiles += len(group.SuggestedDeletions)
+		report.ReclaimableBytes += group.ReclaimableBytes
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].ReclaimableBytes > report.Groups[j].ReclaimableBytes
+	})
+
+	return report
+}
+
+// preferForKeep reports whether a should be kept over b: music videos beat
+// audio-only files, then larger files (higher quality), then the newest.
+func preferForKeep(a, b FileIndexEntry) bool {
+	aVideo := isVideoExtension(a.Path)
+	bVideo := isVideoExtension(b.Path)
+	if aVideo != bVideo {
+		return aVideo
+	}
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+	return a.IndexedAt.After(b.IndexedAt)
+}
+
+func isVideoExtension(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mkv", ".mp4", ".webm":
+		return true
+	}
+	return false
+}
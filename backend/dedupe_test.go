@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedupeReport_ISRC(t *testing.T) {
+	dir := t.TempDir()
+	fi := NewFileIndex(dir)
+
+	mkv := filepath.Join(dir, "Artist", "Song", "Song.mkv")
+	remaster := filepath.Join(dir, "Artist", "Song (2011 Remaster).flac")
+	other := filepath.Join(dir, "Artist", "Other.mkv")
+	writeTestFile(t, mkv, 100)
+	writeTestFile(t, remaster, 300)
+	writeTestFile(t, other, 50)
+
+	now := time.Now()
+	fi.AddEntry(FileIndexEntry{Path: mkv, Title: "Song", Artist: "Artist", ISRC: "us-abc-11-00001", Size: 100, IndexedAt: now})
+	fi.AddEntry(FileIndexEntry{Path: remaster, Title: "Song (2011 Remaster)", Artist: "Artist", ISRC: "USABC1100001", Size: 300, IndexedAt: now})
+	fi.AddEntry(FileIndexEntry{Path: other, Title: "Other", Artist: "Artist", ISRC: "USABC1100002", Size: 50, IndexedAt: now})
+	// Stale entry for a deleted file must be ignored
+	fi.AddEntry(FileIndexEntry{Path: filepath.Join(dir, "gone.mkv"), Title: "Gone", ISRC: "USABC1100002", IndexedAt: now})
+
+	report := fi.DedupeReport()
+	if report.TotalFiles != 4 {
+		t.Errorf("expected 4 indexed files, got %d", report.TotalFiles)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d", len(report.Groups))
+	}
+
+	group := report.Groups[0]
+	if group.ISRC != "USABC1100001" {
+		t.Errorf("expected normalized ISRC, got %q", group.ISRC)
+	}
+	if group.Keep != mkv {
+		t.Errorf("expected music video to be kept, got %q", group.Keep)
+	}
+	if len(group.SuggestedDeletions) != 1 || group.SuggestedDeletions[0] != remaster {
+		t.Errorf("expected remaster FLAC suggested for deletion, got %v", group.SuggestedDeletions)
+	}
+	if report.ReclaimableBytes != 300 || report.DuplicateFiles != 1 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+}
+
+func TestFindByISRC(t *testing.T) {
+	dir := t.TempDir()
+	fi := NewFileIndex(dir)
+
+	path := filepath.Join(dir, "a.mkv")
+	writeTestFile(t, path, 10)
+	fi.AddEntry(FileIndexEntry{Path: path, Title: "A", ISRC: "GBARL9300135"})
+
+	if got := fi.FindByISRC("gb-arl-93-00135"); got == nil || got.Path != path {
+		t.Errorf("expected match by normalized ISRC, got %+v", got)
+	}
+	if got := fi.FindByISRC("GBARL9300136"); got != nil {
+		t.Errorf("expected no match, got %+v", got)
+	}
+	if got := fi.FindByISRC(""); got != nil {
+		t.Error("expected nil for empty ISRC")
+	}
+}
@@ -0,0 +1,582 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"youflac/backend/format"
+)
+
+// =============================================================================
+// Discord bot
+// =============================================================================
+
+// The Discord bot lets a server queue downloads from chat: "!grab <url>" in
+// one of Config.DiscordChannels adds the video to the queue, and the bot
+// answers with an embed it keeps editing as the item moves through the
+// pipeline, ending with the thumbnail, quality and any warnings.
+//
+// It speaks the small part of the Gateway API a command bot needs
+// (identify, heartbeat, MESSAGE_CREATE) and posts through the REST API.
+// Queue events come from the queue's event bus. The bot application needs
+// the Message Content intent enabled in the developer portal.
+
+const (
+	discordAPIBase     = "https://discord.com/api/v10"
+	discordUserAgent   = "DiscordBot (https://github.com/kushiemoon-dev/YouFLAC, 1)"
+	discordGrabCommand = "!grab"
+	discordRetryDelay  = 30 * time.Second
+	discordQueueSize   = 256
+
+	// GUILD_MESSAGES | DIRECT_MESSAGES | MESSAGE_CONTENT
+	discordIntents = 1<<9 | 1<<12 | 1<<15
+)
+
+// Gateway opcodes
+const (
+	discordOpDispatch       = 0
+	discordOpHeartbeat      = 1
+	discordOpIdentify       = 2
+	discordOpReconnect      = 7
+	discordOpInvalidSession = 9
+	discordOpHello          = 10
+)
+
+// Embed colors
+const (
+	discordColorQueued   = 0x5865F2
+	discordColorComplete = 0x57F287
+	discordColorWarning  = 0xFEE75C
+	discordColorError    = 0xED4245
+)
+
+// DiscordBot is one bot session driving the queue
+type DiscordBot struct {
+	token    string
+	channels map[string]bool
+	queue    *Queue
+	apiBase  string
+	client   *http.Client
+
+	mu      sync.Mutex
+	tracked map[string]*discordTrackedItem // Queue item ID -> status message
+
+	events      chan QueueEvent
+	unsubscribe func()
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// discordTrackedItem is the status message of an item queued from chat
+type discordTrackedItem struct {
+	channelID  string
+	messageID  string
+	lastStatus QueueStatus
+}
+
+type discordPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+type discordMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"author"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Thumbnail   *discordEmbedImage  `json:"thumbnail,omitempty"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// NewDiscordBot creates a bot for the channels in config. Call Start to connect.
+func NewDiscordBot(config *Config, queue *Queue) (*DiscordBot, error) {
+	token := strings.TrimSpace(config.DiscordBotToken)
+	if token == "" {
+		return nil, fmt.Errorf("discord bot token is not set")
+	}
+	if len(config.DiscordChannels) == 0 {
+		return nil, fmt.Errorf("no discord channels configured")
+	}
+	channels := make(map[string]bool, len(config.DiscordChannels))
+	for _, id := range config.DiscordChannels {
+		channels[strings.TrimSpace(id)] = true
+	}
+	return &DiscordBot{
+		token:    token,
+		channels: channels,
+		queue:    queue,
+		apiBase:  discordAPIBase,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		tracked:  make(map[string]*discordTrackedItem),
+		events:   make(chan QueueEvent, discordQueueSize),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start subscribes to queue events and connects to the gateway in the background
+func (b *DiscordBot) Start() {
+	b.unsubscribe = b.queue.Subscribe(func(event QueueEvent) {
+		select {
+		case b.events <- event:
+		default: // Discord is slow or unreachable; drop rather than block the queue
+		}
+	})
+	b.wg.Add(2)
+	go b.runGateway()
+	go b.runEvents()
+}
+
+// Close disconnects the bot and waits for its goroutines
+func (b *DiscordBot) Close() {
+	if b.unsubscribe != nil {
+		b.unsubscribe()
+	}
+	close(b.done)
+	b.wg.Wait()
+}
+
+// runGateway keeps a gateway session open, reconnecting after failures
+func (b *DiscordBot) runGateway() {
+	defer b.wg.Done()
+	for {
+		err := b.session()
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+		delay := time.Second
+		if err != nil {
+			slog.Warn("discord gateway disconnected", "err", err)
+			delay = discordRetryDelay
+		}
+		select {
+		case <-b.done:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// session runs one gateway connection until it fails, Discord asks for a
+// reconnect (nil error) or the bot is closed
+func (b *DiscordBot) session() error {
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := b.api(http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+		return fmt.Errorf("failed to get gateway URL: %w", err)
+	}
+	config, err := websocket.NewConfig(gateway.URL+"/?v=10&encoding=json", "https://discord.com")
+	if err != nil {
+		return fmt.Errorf("invalid gateway URL %q: %w", gateway.URL, err)
+	}
+	config.Header.Set("User-Agent", discordUserAgent)
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to gateway: %w", err)
+	}
+
+	// Closing the connection unblocks Receive when the bot stops
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-b.done:
+		case <-stop:
+		}
+		ws.Close()
+	}()
+
+	var hello discordPayload
+	if err := websocket.JSON.Receive(ws, &hello); err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+	var helloData struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	if hello.Op != discordOpHello || json.Unmarshal(hello.D, &helloData) != nil || helloData.HeartbeatInterval <= 0 {
+		return fmt.Errorf("unexpected first gateway message (op %d)", hello.Op)
+	}
+
+	identify := map[string]any{
+		"op": discordOpIdentify,
+		"d": map[string]any{
+			"token":   b.token,
+			"intents": discordIntents,
+			"properties": map[string]string{
+				"os":      "linux",
+				"browser": "youflac",
+				"device":  "youflac",
+			},
+		},
+	}
+	if err := websocket.JSON.Send(ws, identify); err != nil {
+		return fmt.Errorf("failed to identify: %w", err)
+	}
+
+	var seqMu sync.Mutex
+	var seq *int64
+	go func() {
+		ticker := time.NewTicker(time.Duration(helloData.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				seqMu.Lock()
+				beat := map[string]any{"op": discordOpHeartbeat, "d": seq}
+				seqMu.Unlock()
+				if err := websocket.JSON.Send(ws, beat); err != nil {
+					ws.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var p discordPayload
+		if err := websocket.JSON.Receive(ws, &p); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("gateway closed the connection")
+			}
+			return err
+		}
+		if p.S != nil {
+			seqMu.Lock()
+			seq = p.S
+			seqMu.Unlock()
+		}
+		switch p.Op {
+		case discordOpDispatch:
+			switch p.T {
+			case "READY":
+				slog.Info("discord bot connected", "channels", len(b.channels))
+			case "MESSAGE_CREATE":
+				var m discordMessage
+				if err := json.Unmarshal(p.D, &m); err == nil {
+					b.handleMessage(m)
+				}
+			}
+		case discordOpHeartbeat:
+			seqMu.Lock()
+			beat := map[string]any{"op": discordOpHeartbeat, "d": seq}
+			seqMu.Unlock()
+			websocket.JSON.Send(ws, beat)
+		case discordOpReconnect:
+			return nil
+		case discordOpInvalidSession:
+			return fmt.Errorf("gateway rejected the session")
+		}
+	}
+}
+
+// parseGrabCommand returns the URLs of a "!grab <url> [url...]" message.
+// ok is false for messages that are not a grab command.
+func parseGrabCommand(content string) (urls []string, ok bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], discordGrabCommand) {
+		return nil, false
+	}
+	for _, f := range fields[1:] {
+		urls = append(urls, strings.Trim(f, "<>")) // <url> suppresses Discord's link preview
+	}
+	return urls, true
+}
+
+// handleMessage queues the URLs of a grab command from a watched channel
+func (b *DiscordBot) handleMessage(m discordMessage) {
+	if m.Author.Bot || !b.channels[m.ChannelID] {
+		return
+	}
+	urls, ok := parseGrabCommand(m.Content)
+	if !ok {
+		return
+	}
+	if len(urls) == 0 {
+		b.reply(m, discordEmbed{Description: "Usage: `" + discordGrabCommand + " <YouTube URL>`", Color: discordColorError})
+		return
+	}
+
+	for _, url := range urls {
+		if err := ValidateYouTubeURL(url); err != nil {
+			b.reply(m, discordEmbed{Title: "Not queued", Description: fmt.Sprintf("%s: %v", url, err), Color: discordColorError})
+			continue
+		}
+		id, err := b.queue.AddToQueue(DownloadRequest{VideoURL: url, Notes: "Requested on Discord"})
+		if err != nil {
+			b.reply(m, discordEmbed{Title: "Not queued", Description: fmt.Sprintf("%s: %v", url, err), Color: discordColorError})
+			continue
+		}
+		item := b.queue.GetItem(id)
+		if item == nil {
+			continue
+		}
+		messageID, err := b.reply(m, discordItemEmbed(item))
+		if err != nil {
+			slog.Warn("failed to post discord message", "err", err)
+			continue
+		}
+		b.mu.Lock()
+		b.tracked[id] = &discordTrackedItem{channelID: m.ChannelID, messageID: messageID, lastStatus: item.Status}
+		b.mu.Unlock()
+	}
+}
+
+// runEvents edits the status message of tracked items as they progress.
+// Only status changes are sent, which keeps the bot well inside rate limits.
+func (b *DiscordBot) runEvents() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.done:
+			return
+		case event := <-b.events:
+			b.handleEvent(event)
+		}
+	}
+}
+
+func (b *DiscordBot) handleEvent(event QueueEvent) {
+	b.mu.Lock()
+	tracked := b.tracked[event.ItemID]
+	if tracked == nil {
+		b.mu.Unlock()
+		return
+	}
+	final := false
+	switch event.Type {
+	case "removed":
+		delete(b.tracked, event.ItemID)
+		b.mu.Unlock()
+		return
+	case "completed", "error":
+		final = true
+		delete(b.tracked, event.ItemID)
+	case "updated":
+		if event.Status == tracked.lastStatus {
+			b.mu.Unlock()
+			return
+		}
+		tracked.lastStatus = event.Status
+	default:
+		b.mu.Unlock()
+		return
+	}
+	channelID, messageID := tracked.channelID, tracked.messageID
+	b.mu.Unlock()
+
+	item := event.Item
+	if final || item == nil {
+		item = b.queue.GetItem(event.ItemID)
+	}
+	if item == nil {
+		return
+	}
+	body := map[string]any{"embeds": []discordEmbed{discordItemEmbed(item)}}
+	if err := b.api(http.MethodPatch, "/channels/"+channelID+"/messages/"+messageID, body, nil); err != nil {
+		slog.Warn("failed to update discord message", "item", event.ItemID, "err", err)
+	}
+}
+
+// discordItemEmbed describes the current state of a queue item
+func discordItemEmbed(item *QueueItem) discordEmbed {
+	title := item.Title
+	if item.Artist != "" && title != "" {
+		title = item.Artist + " - " + title
+	}
+	if title == "" {
+		title = item.VideoURL
+	}
+	embed := discordEmbed{Title: title, URL: item.VideoURL, Color: discordColorQueued}
+	if item.Thumbnail != "" {
+		embed.Thumbnail = &discordEmbedImage{URL: item.Thumbnail}
+	}
+
+	stage := item.Stage
+	if item.StageCode != "" {
+		stage = StageMessage(item.StageCode, item.StageParams, DefaultStageLanguage)
+	}
+
+	switch item.Status {
+	case StatusComplete:
+		embed.Color = discordColorComplete
+		embed.Description = "Downloaded"
+		if item.AudioSource != "" {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Audio", Value: item.AudioSource, Inline: true})
+		}
+		if item.ActualQuality != "" {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Quality", Value: item.ActualQuality, Inline: true})
+		}
+		if item.FileSize > 0 {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Size", Value: format.FileSize(item.FileSize), Inline: true})
+		}
+		if len(item.Warnings) > 0 {
+			embed.Color = discordColorWarning
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Warnings", Value: truncateDiscord("• "+strings.Join(item.Warnings, "\n• "), 1024)})
+		}
+	case StatusError:
+		embed.Color = discordColorError
+		embed.Description = truncateDiscord("Failed: "+item.Error, 4096)
+	case StatusCancelled:
+		embed.Color = discordColorError
+		embed.Description = "Cancelled"
+	default:
+		embed.Description = stage
+		if item.Progress > 0 {
+			embed.Description += " (" + strconv.Itoa(item.Progress) + "%)"
+		}
+	}
+	return embed
+}
+
+// truncateDiscord cuts s to Discord's field limit of n characters
+func truncateDiscord(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// reply posts an embed in answer to m and returns the new message's ID
+func (b *DiscordBot) reply(m discordMessage, embed discordEmbed) (string, error) {
+	body := map[string]any{
+		"embeds":            []discordEmbed{embed},
+		"message_reference": map[string]string{"message_id": m.ID},
+		"allowed_mentions":  map[string]any{"parse": []string{}},
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := b.api(http.MethodPost, "/channels/"+m.ChannelID+"/messages", body, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// api calls the REST API, waiting once when rate limited
+func (b *DiscordBot) api(method, path string, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, b.apiBase+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+b.token)
+		req.Header.Set("User-Agent", discordUserAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("discord request failed: %w", err)
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			var limit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.Unmarshal(respBody, &limit)
+			wait := time.Duration(limit.RetryAfter * float64(time.Second))
+			if wait <= 0 || wait > time.Minute {
+				wait = 5 * time.Second
+			}
+			select {
+			case <-b.done:
+				return fmt.Errorf("discord bot stopped")
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("discord returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		}
+		if out != nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("failed to parse discord response: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+var (
+	discordBot      *DiscordBot
+	discordBotKey   string // Settings the bot was built from
+	discordBotMutex sync.Mutex
+)
+
+// ConfigureDiscord starts, restarts or stops the Discord bot to match config
+func ConfigureDiscord(config *Config, queue *Queue) {
+	if queue == nil {
+		return
+	}
+	key := config.DiscordBotToken + "\x00" + strings.Join(config.DiscordChannels, ",")
+
+	discordBotMutex.Lock()
+	defer discordBotMutex.Unlock()
+	enabled := config.DiscordBotToken != "" && len(config.DiscordChannels) > 0
+	if key == discordBotKey && (discordBot != nil) == enabled && (discordBot == nil || discordBot.queue == queue) {
+		return
+	}
+	if discordBot != nil {
+		discordBot.Close()
+		discordBot = nil
+	}
+	discordBotKey = key
+	if !enabled {
+		return
+	}
+	bot, err := NewDiscordBot(config, queue)
+	if err != nil {
+		slog.Warn("Discord bot disabled", "err", err)
+		return
+	}
+	bot.Start()
+	discordBot = bot
+}
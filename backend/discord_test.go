@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseGrabCommand(t *testing.T) {
+	tests := []struct {
+		content string
+		urls    []string
+		ok      bool
+	}{
+		{"!grab https://youtu.be/abc", []string{"https://youtu.be/abc"}, true},
+		{"!GRAB <https://youtu.be/abc>  https://youtu.be/def", []string{"https://youtu.be/abc", "https://youtu.be/def"}, true},
+		{"!grab", nil, true},
+		{"hello !grab https://youtu.be/abc", nil, false},
+		{"!grabber https://youtu.be/abc", nil, false},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		urls, ok := parseGrabCommand(tt.content)
+		if ok != tt.ok || strings.Join(urls, " ") != strings.Join(tt.urls, " ") {
+			t.Errorf("parseGrabCommand(%q) = %v, %v, want %v, %v", tt.content, urls, ok, tt.urls, tt.ok)
+		}
+	}
+}
+
+func TestDiscordItemEmbed(t *testing.T) {
+	item := &QueueItem{
+		Title:         "Aerodynamic",
+		Artist:        "Daft Punk",
+		VideoURL:      "https://www.youtube.com/watch?v=abc",
+		Thumbnail:     "https://i.ytimg.com/vi/abc/maxresdefault.jpg",
+		Status:        StatusComplete,
+		AudioSource:   "tidal",
+		ActualQuality: "24-bit/96kHz",
+	}
+	embed := discordItemEmbed(item)
+	if embed.Title != "Daft Punk - Aerodynamic" || embed.Color != discordColorComplete {
+		t.Errorf("embed = %+v", embed)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != item.Thumbnail {
+		t.Errorf("thumbnail = %+v, want %s", embed.Thumbnail, item.Thumbnail)
+	}
+
+	item.Warnings = []string{"lyrics: no lyrics found"}
+	embed = discordItemEmbed(item)
+	if embed.Color != discordColorWarning {
+		t.Errorf("color = %x, want warning color", embed.Color)
+	}
+	if last := embed.Fields[len(embed.Fields)-1]; last.Name != "Warnings" || !strings.Contains(last.Value, "no lyrics") {
+		t.Errorf("warnings field = %+v", last)
+	}
+
+	failed := discordItemEmbed(&QueueItem{VideoURL: "https://youtu.be/x", Status: StatusError, Error: "video unavailable"})
+	if failed.Color != discordColorError || !strings.Contains(failed.Description, "video unavailable") {
+		t.Errorf("error embed = %+v", failed)
+	}
+}
+
+// fakeDiscordAPI records REST calls and answers message creation with an ID
+type fakeDiscordAPI struct {
+	mu    sync.Mutex
+	calls []string
+	posts []map[string]any
+}
+
+func (f *fakeDiscordAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	f.posts = append(f.posts, body)
+	f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bot test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte(`{"id":"msg-1"}`))
+}
+
+func newTestDiscordBot(t *testing.T) (*DiscordBot, *fakeDiscordAPI) {
+	t.Helper()
+	api := &fakeDiscordAPI{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	bot, err := NewDiscordBot(&Config{DiscordBotToken: "test-token", DiscordChannels: []string{"100"}}, newTestQueue())
+	if err != nil {
+		t.Fatalf("NewDiscordBot() error = %v", err)
+	}
+	bot.apiBase = server.URL
+	return bot, api
+}
+
+func TestDiscordBot_GrabQueuesAndTracks(t *testing.T) {
+	bot, api := newTestDiscordBot(t)
+
+	msg := discordMessage{ID: "m1", ChannelID: "100", Content: "!grab https://www.youtube.com/watch?v=dQw4w9WgXcQ"}
+	bot.handleMessage(msg)
+
+	items := bot.queue.GetQueue()
+	if len(items) != 1 || items[0].VideoURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Fatalf("queue = %+v, want the grabbed video", items)
+	}
+	if len(api.calls) != 1 || api.calls[0] != "POST /channels/100/messages" {
+		t.Fatalf("calls = %v, want one reply", api.calls)
+	}
+
+	id := items[0].ID
+	bot.handleEvent(QueueEvent{Type: "updated", ItemID: id, Status: StatusPending})
+	if len(api.calls) != 1 {
+		t.Errorf("unchanged status sent an edit: %v", api.calls)
+	}
+	bot.handleEvent(QueueEvent{Type: "updated", ItemID: id, Status: StatusDownloadingVideo, Item: &QueueItem{ID: id, Status: StatusDownloadingVideo}})
+	bot.handleEvent(QueueEvent{Type: "error", ItemID: id})
+	if len(api.calls) != 3 || api.calls[2] != "PATCH /channels/100/messages/msg-1" {
+		t.Errorf("calls = %v, want two edits", api.calls)
+	}
+	bot.handleEvent(QueueEvent{Type: "updated", ItemID: id, Status: StatusPending})
+	if len(api.calls) != 3 {
+		t.Errorf("finished item still tracked: %v", api.calls)
+	}
+}
+
+func TestDiscordBot_IgnoresOtherMessages(t *testing.T) {
+	bot, api := newTestDiscordBot(t)
+
+	bot.handleMessage(discordMessage{ID: "m1", ChannelID: "999", Content: "!grab https://www.youtube.com/watch?v=dQw4w9WgXcQ"})
+	bot.handleMessage(discordMessage{ID: "m2", ChannelID: "100", Content: "nice song"})
+	bot.handleMessage(discordMessage{ID: "m3", ChannelID: "100", Content: "!grab https://example.com/video"})
+	bot.handleMessage(func() discordMessage {
+		m := discordMessage{ID: "m4", ChannelID: "100", Content: "!grab https://www.youtube.com/watch?v=dQw4w9WgXcQ"}
+		m.Author.Bot = true
+		return m
+	}())
+
+	if n := len(bot.queue.GetQueue()); n != 0 {
+		t.Errorf("queued %d items, want 0", n)
+	}
+	if len(api.calls) != 1 {
+		t.Errorf("calls = %v, want only the rejection of the invalid URL", api.calls)
+	}
+}
+
+func TestConfigValidate_DiscordChannels(t *testing.T) {
+	config := GetDefaultConfig()
+	config.DiscordBotToken = " token "
+	config.DiscordChannels = []string{" 123456789012345678 ", "general", ""}
+	result := config.Validate()
+	if config.DiscordBotToken != "token" {
+		t.Errorf("token = %q, want trimmed", config.DiscordBotToken)
+	}
+	if len(config.DiscordChannels) != 1 || config.DiscordChannels[0] != "123456789012345678" {
+		t.Errorf("channels = %v", config.DiscordChannels)
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("expected a warning for the channel name")
+	}
+}
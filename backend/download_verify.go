@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// Download verification
+// =============================================================================
+
+// A download that breaks off can still look finished: the file is there,
+// just shorter, and ffmpeg happily muxes the first half of a song. Every
+// HTTP download is therefore checked against what the provider promised:
+// the size (Content-Length, or an exact figure from the provider's API) and,
+// where one is exposed, a checksum (Content-MD5, x-goog-hash or a provider
+// hash). A file that fails the check is downloaded again. Videos come from
+// yt-dlp, which only knows approximate sizes, so they are checked by
+// duration instead.
+
+var (
+	// ErrTruncatedDownload means the file is shorter than the provider said
+	ErrTruncatedDownload = errors.New("download truncated")
+	// ErrChecksumMismatch means the file does not hash to the provider's checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+const (
+	// Attempts at a download that fails verification
+	downloadVerifyAttempts = 3
+	// A video this much shorter than its reported duration is truncated
+	videoDurationTolerance = 2.0 // seconds
+)
+
+// ExpectedFile is what a downloaded file should look like. Zero fields are
+// not checked.
+type ExpectedFile struct {
+	Size   int64  // Bytes; only a shorter file fails, providers round some sizes up
+	MD5    string // Hex
+	SHA256 string // Hex
+}
+
+// merge fills the fields e leaves empty from other
+func (e ExpectedFile) merge(other ExpectedFile) ExpectedFile {
+	if e.Size <= 0 {
+		e.Size = other.Size
+	}
+	if e.MD5 == "" {
+		e.MD5 = other.MD5
+	}
+	if e.SHA256 == "" {
+		e.SHA256 = other.SHA256
+	}
+	return e
+}
+
+// expectedFromResponse reads the size and checksums a full (200) response
+// announces
+func expectedFromResponse(resp *http.Response) ExpectedFile {
+	want := ExpectedFile{Size: resp.ContentLength}
+	if sum, err := base64.StdEncoding.DecodeString(resp.Header.Get("Content-MD5")); err == nil && len(sum) == md5.Size {
+		want.MD5 = hex.EncodeToString(sum)
+	}
+	// Google Cloud Storage: "x-goog-hash: crc32c=...,md5=..."
+	for _, value := range resp.Header.Values("X-Goog-Hash") {
+		for _, part := range strings.Split(value, ",") {
+			if encoded, ok := strings.CutPrefix(strings.TrimSpace(part), "md5="); ok {
+				if sum, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(sum) == md5.Size {
+					want.MD5 = hex.EncodeToString(sum)
+				}
+			}
+		}
+	}
+	return want
+}
+
+// VerifyDownloadedFile checks path against want
+func VerifyDownloadedFile(path string, want ExpectedFile) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if want.Size > 0 && info.Size() < want.Size {
+		return fmt.Errorf("%w: %d of %d bytes", ErrTruncatedDownload, info.Size(), want.Size)
+	}
+
+	var hashes []hash.Hash
+	var names, sums []string
+	if want.MD5 != "" {
+		hashes, names, sums = append(hashes, md5.New()), append(names, "MD5"), append(sums, want.MD5)
+	}
+	if want.SHA256 != "" {
+		hashes, names, sums = append(hashes, sha256.New()), append(names, "SHA-256"), append(sums, want.SHA256)
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writers := make([]io.Writer, len(hashes))
+	for i, h := range hashes {
+		writers[i] = h
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return fmt.Errorf("failed to hash download: %w", err)
+	}
+	for i, h := range hashes {
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, sums[i]) {
+			return fmt.Errorf("%w: %s is %s, want %s", ErrChecksumMismatch, names[i], got, strings.ToLower(sums[i]))
+		}
+	}
+	return nil
+}
+
+// isVerificationError reports whether a download failed because the file
+// was incomplete or corrupt, which another attempt may fix
+func isVerificationError(err error) bool {
+	return errors.Is(err, ErrTruncatedDownload) || errors.Is(err, ErrChecksumMismatch)
+}
+
+// Media duration probe, replaced in tests
+var probeMediaDuration = func(path string) (float64, error) {
+	info, err := GetMediaInfo(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Duration, nil
+}
+
+// verifyMediaDuration checks that a media file plays for about as long as
+// expected. A failed probe is not an error: the mux reports unreadable files.
+func verifyMediaDuration(path string, expected float64) error {
+	if expected <= 0 {
+		return nil
+	}
+	duration, err := probeMediaDuration(path)
+	if err != nil {
+		slog.Debug("duration probe failed, skipping truncation check", "path", path, "err", err)
+		return nil
+	}
+	if duration > 0 && duration < expected-videoDurationTolerance {
+		return fmt.Errorf("%w: %.1fs of %.1fs", ErrTruncatedDownload, duration, expected)
+	}
+	return nil
+}
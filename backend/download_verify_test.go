@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyDownloadedFile(t *testing.T) {
+	content := []byte("fLaC and then some audio")
+	path := filepath.Join(t.TempDir(), "track.flac")
+	os.WriteFile(path, content, 0644)
+	md5Sum := md5.Sum(content)
+	shaSum := sha256.Sum256(content)
+
+	ok := []ExpectedFile{
+		{},
+		{Size: int64(len(content))},
+		{Size: int64(len(content)) - 5}, // Provider rounded down
+		{MD5: hex.EncodeToString(md5Sum[:]), SHA256: hex.EncodeToString(shaSum[:])},
+	}
+	for _, want := range ok {
+		if err := VerifyDownloadedFile(path, want); err != nil {
+			t.Errorf("VerifyDownloadedFile(%+v) = %v", want, err)
+		}
+	}
+
+	if err := VerifyDownloadedFile(path, ExpectedFile{Size: 1000}); !errors.Is(err, ErrTruncatedDownload) {
+		t.Errorf("short file = %v, want ErrTruncatedDownload", err)
+	}
+	if err := VerifyDownloadedFile(path, ExpectedFile{MD5: "00112233445566778899aabbccddeeff"}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("wrong MD5 = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestExpectedFromResponse(t *testing.T) {
+	sum := md5.Sum([]byte("audio"))
+	resp := &http.Response{ContentLength: 5, Header: http.Header{}}
+	resp.Header.Set("X-Goog-Hash", "crc32c=AAAAAA==,md5="+base64.StdEncoding.EncodeToString(sum[:]))
+
+	want := expectedFromResponse(resp)
+	if want.Size != 5 || want.MD5 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expectedFromResponse = %+v", want)
+	}
+
+	resp.Header = http.Header{"Content-Md5": {"not base64"}}
+	if want := expectedFromResponse(resp); want.MD5 != "" {
+		t.Errorf("invalid Content-MD5 read as %q", want.MD5)
+	}
+}
+
+func TestDownloader_RetriesTruncatedDownload(t *testing.T) {
+	content := testContent(10000)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		if requests.Add(1) == 1 {
+			w.Write(content[:4000]) // Connection closes short of Content-Length
+			return
+		}
+		w.Write(content)
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes, content differs", len(got))
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestDownloader_ChecksumMismatch(t *testing.T) {
+	content := testContent(10000)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(make([]byte, md5.Size)))
+		w.Write(content)
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Download = %v, want ErrChecksumMismatch", err)
+	}
+	if n := requests.Load(); n != downloadVerifyAttempts {
+		t.Errorf("%d requests, want %d", n, downloadVerifyAttempts)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("corrupt download left behind")
+	}
+
+	// The provider's size counts even when the server sends none
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // Chunked, no Content-Length
+		w.Write(content)
+	}))
+	defer ts.Close()
+	err = download(ts.Client(), ts.URL, out, 1, ExpectedFile{Size: int64(len(content)) * 2})
+	if !errors.Is(err, ErrTruncatedDownload) {
+		t.Errorf("Download = %v, want ErrTruncatedDownload", err)
+	}
+}
+
+func TestVerifyMediaDuration(t *testing.T) {
+	orig := probeMediaDuration
+	t.Cleanup(func() { probeMediaDuration = orig })
+	probed, probeErr := 0.0, error(nil)
+	probeMediaDuration = func(path string) (float64, error) { return probed, probeErr }
+
+	probed = 120
+	if err := verifyMediaDuration("video.mp4", 240); !errors.Is(err, ErrTruncatedDownload) {
+		t.Errorf("half-length video = %v, want ErrTruncatedDownload", err)
+	}
+	probed = 239
+	if err := verifyMediaDuration("video.mp4", 240); err != nil {
+		t.Errorf("video within tolerance = %v", err)
+	}
+	if err := verifyMediaDuration("video.mp4", 0); err != nil {
+		t.Errorf("unknown expected duration = %v", err)
+	}
+	probeErr = errors.New("ffprobe missing")
+	if err := verifyMediaDuration("video.mp4", 240); err != nil {
+		t.Errorf("failed probe = %v, want no error", err)
+	}
+}
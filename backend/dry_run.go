@@ -0,0 +1,322 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// =============================================================================
+// Dry Run
+// =============================================================================
+
+// Dry runs go through URL parsing, metadata fetch, source resolution, match
+// scoring and path generation, but never download, mux or write files. They
+// are meant for checking large playlist imports before spending bandwidth.
+
+// Dry-run step outcomes
+const (
+	PlanStepOK   = "ok"
+	PlanStepSkip = "skip"
+	PlanStepWarn = "warn"
+	PlanStepFail = "fail"
+)
+
+// PlanStep is one pipeline stage as it would run
+type PlanStep struct {
+	Stage  string `json:"stage"`  // "parse_url", "metadata", "existing_file", "resolve", "match", "source", "output"
+	Result string `json:"result"` // ok, skip, warn, fail
+	Detail string `json:"detail"`
+}
+
+// DownloadPlan is the result of a dry run
+type DownloadPlan struct {
+	VideoURL     string           `json:"videoUrl,omitempty"`
+	VideoID      string           `json:"videoId,omitempty"`
+	Title        string           `json:"title"`
+	Artist       string           `json:"artist"`
+	Duration     float64          `json:"duration,omitempty"`
+	ExistingFile string           `json:"existingFile,omitempty"` // Library file that would be reused instead of downloading
+	SourceOrder  []string         `json:"sourceOrder"`            // Audio sources in the order they would be tried
+	Candidates   []AudioCandidate `json:"candidates,omitempty"`
+	Match        *MatchResult     `json:"match,omitempty"`
+	AudioSource  string           `json:"audioSource,omitempty"` // First source that would be tried ("tidal-search", "youtube-music" or "extracted" for fallbacks)
+	AudioURL     string           `json:"audioUrl,omitempty"`
+	OutputPath   string           `json:"outputPath,omitempty"`
+	Steps        []PlanStep       `json:"steps"`
+	WillDownload bool             `json:"willDownload"` // False when the real run would fail or reuse an existing file
+	PlannedAt    time.Time        `json:"plannedAt"`
+}
+
+func (p *DownloadPlan) step(stage, result, format string, args ...interface{}) {
+	p.Steps = append(p.Steps, PlanStep{Stage: stage, Result: result, Detail: fmt.Sprintf(format, args...)})
+}
+
+// PlanDownload simulates processing item with config. fileIndex may be nil.
+func PlanDownload(item *QueueItem, config *Config, fileIndex *FileIndex) *DownloadPlan {
+	plan := &DownloadPlan{
+		VideoURL:  item.VideoURL,
+		Title:     item.Title,
+		Artist:    item.Artist,
+		Duration:  item.Duration,
+		PlannedAt: time.Now(),
+	}
+
+	// Stage 1: URL and metadata
+	if item.VideoURL != "" {
+		videoID, err := ParseYouTubeURL(item.VideoURL)
+		if err != nil {
+			plan.step("parse_url", PlanStepFail, "invalid YouTube URL: %v", err)
+			return plan
+		}
+		plan.VideoID = videoID
+		plan.step("parse_url", PlanStepOK, "video %s", videoID)
+	}
+
+	if plan.Title == "" {
+		if plan.VideoID == "" {
+			plan.step("metadata", PlanStepFail, "no video URL or title to work from")
+			return plan
+		}
+		info, err := GetVideoMetadata(plan.VideoID)
+		if err != nil {
+			plan.step("metadata", PlanStepFail, "failed to fetch video info: %v", err)
+			return plan
+		}
+		plan.Title = info.Title
+		plan.Artist = info.Artist
+		plan.Duration = info.Duration
+		plan.step("metadata", PlanStepOK, "%s - %s", info.Artist, info.Title)
+	} else {
+		plan.step("metadata", PlanStepSkip, "using queued metadata: %s - %s", plan.Artist, plan.Title)
+	}
+
+	album := item.Album
+	if rules := MetadataRulesFromConfig(config); len(rules) > 0 {
+		rewrite := RewriteMetadata(rules, plan.Title, plan.Artist, album)
+		if len(rewrite.Applied) > 0 {
+			plan.Title = rewrite.Title
+			plan.Artist = rewrite.Artist
+			album = rewrite.Album
+			plan.step("metadata_rules", PlanStepOK, "%s - %s (%d rules)", plan.Artist, plan.Title, len(rewrite.Applied))
+		}
+	}
+
+	metadata := &Metadata{
+		Title:      plan.Title,
+		Artist:     plan.Artist,
+		Album:      album,
+		Duration:   plan.Duration,
+		Track:      item.PlaylistPosition,
+		TrackTotal: item.TrackTotal,
+		Disc:       item.Disc,
+		Tags:       item.Tags,
+	}
+	ApplyArtistCredit(metadata, item.AlbumArtist, config)
+	outputDir := planOutputDir(item, metadata, config)
+
+	// Stage 1.5: skip detection
+	if fileIndex != nil {
+		if existing := fileIndex.FindVideo(LibraryIndexScope(config, item), plan.VideoID, plan.Title, plan.Artist, plan.Duration); existing != nil {
+			plan.ExistingFile = existing.Path
+			ext := filepath.Ext(existing.Path)
+			if ext == "" {
+				ext = ".mkv"
+			}
+			plan.OutputPath = planOutputPath(item, metadata, config, outputDir, ext)
+			plan.step("existing_file", PlanStepSkip, "already in library at %s, would reuse it", existing.Path)
+			return plan
+		}
+		plan.step("existing_file", PlanStepOK, "not in library")
+	}
+
+	// Stage 3: source resolution and match scoring
+	plan.SourceOrder = config.AudioSourcePriority
+	if len(item.AudioSourcePriority) > 0 {
+		plan.SourceOrder = item.AudioSourcePriority
+	}
+
+	sourceURL := item.VideoURL
+	if item.SpotifyURL != "" {
+		sourceURL = item.SpotifyURL
+	}
+	if sourceURL != "" {
+		links, err := ResolveMusicURL(sourceURL)
+		if err != nil {
+			plan.step("resolve", PlanStepWarn, "song.link resolution failed: %v", err)
+		} else {
+			plan.Candidates = buildCandidatesFromSongLink(links)
+			metadata.ISRC = links.ISRC
+			plan.step("resolve", PlanStepOK, "%d streaming link(s) found", len(plan.Candidates))
+
+			video := &VideoInfo{ID: plan.VideoID, Title: plan.Title, Artist: plan.Artist, Duration: plan.Duration, ISRC: links.ISRC}
+			if len(plan.Candidates) > 0 {
+				if match, err := MatchVideoToAudio(video, plan.Candidates, nil); err == nil {
+					plan.Match = match
+					if match.IsValid {
+						plan.step("match", PlanStepOK, "%s match via %s (%.0f%%)", GetMatchConfidenceLabel(match.Confidence), GetMatchMethodLabel(match.MatchMethod), match.Confidence*100)
+					} else {
+						plan.step("match", PlanStepWarn, "no candidate above the confidence threshold")
+					}
+				}
+			}
+
+			for _, source := range plan.SourceOrder {
+				if url := sourceLinkURL(links, source); url != "" {
+					plan.AudioSource = source
+					plan.AudioURL = url
+					break
+				}
+			}
+		}
+	}
+
+	switch {
+	case plan.AudioSource != "":
+		plan.step("source", PlanStepOK, "would download FLAC from %s", plan.AudioSource)
+	case plan.Artist != "" && plan.Title != "" && (len(item.AudioSourcePriority) == 0 || containsString(item.AudioSourcePriority, "tidal")):
+		plan.AudioSource = "tidal-search"
+		plan.step("source", PlanStepWarn, "no direct link, would search Tidal for %q", plan.Artist+" - "+plan.Title)
+	case config.FallbackPolicy == FallbackFail:
+		plan.step("source", PlanStepFail, "no lossless source and the fallback policy is %q", FallbackFail)
+		return plan
+	case plan.VideoID != "" && fallbackTriesYouTubeMusic(config.FallbackPolicy):
+		plan.AudioSource = YouTubeAudioSource
+		plan.step("source", PlanStepWarn, "no lossless source, would download the lossy YouTube Music audio")
+	case plan.VideoID != "" && item.OutputMode != OutputModeAudio:
+		plan.AudioSource = "extracted"
+		plan.step("source", PlanStepWarn, "no lossless source, would extract the YouTube audio")
+	default:
+		plan.step("source", PlanStepFail, "no audio source available")
+		return plan
+	}
+
+	// Stage 4: output path (audio-only items become .flac)
+	ext := ".mkv"
+	if item.AudioOnly || item.OutputMode == OutputModeAudio || plan.VideoID == "" {
+		ext = ".flac"
+	}
+	plan.OutputPath = planOutputPath(item, metadata, config, outputDir, ext)
+	plan.step("output", PlanStepOK, "%s", plan.OutputPath)
+
+	plan.WillDownload = true
+	return plan
+}
+
+// sourceLinkURL returns the song.link URL for an audio source name
+func sourceLinkURL(links *SongLinkTrackInfo, source string) string {
+	switch source {
+	case "tidal":
+		return links.URLs.TidalURL
+	case "qobuz":
+		return links.URLs.QobuzURL
+	case "amazon":
+		return links.URLs.AmazonURL
+	case "deezer":
+		return links.URLs.DeezerURL
+	}
+	return ""
+}
+
+func planOutputDir(item *QueueItem, metadata *Metadata, config *Config) string {
+	outputDir := ArtistOutputDirectory(metadata, config)
+	if outputDir == "" {
+		if lib := LibraryFor(config, item); lib != nil {
+			outputDir = lib.Directory
+		}
+	}
+	if outputDir == "" {
+		outputDir = config.OutputDirectory
+	}
+	if outputDir == "" {
+		outputDir = GetDefaultOutputDirectory()
+	}
+	if item.PlaylistName != "" {
+		outputDir = filepath.Join(outputDir, SanitizeFileName(item.PlaylistName))
+	}
+	return outputDir
+}
+
+func planOutputPath(item *QueueItem, metadata *Metadata, config *Config, outputDir, ext string) string {
+	if item.PlaylistPosition > 0 {
+		return GeneratePlaylistFilePath(metadata, outputDir, ext, config.PlaylistLayout)
+	}
+	return GenerateFilePath(metadata, itemNamingTemplate(item, config), outputDir, ext)
+}
+
+// PlanRequest runs a dry run for a download request without queueing it
+func PlanRequest(request DownloadRequest, config *Config, fileIndex *FileIndex) (*DownloadPlan, error) {
+	if err := ValidateAudioSources(request.AudioSourcePriority); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &defaultConfig
+	}
+	item := &QueueItem{
+		VideoURL:            request.VideoURL,
+		SpotifyURL:          request.SpotifyURL,
+		AudioSourcePriority: request.AudioSourcePriority,
+		AlbumArtist:         request.AlbumArtist,
+		NamingTemplate:      request.NamingTemplate,
+		OutputMode:          request.OutputMode,
+	}
+	return PlanDownload(item, config, fileIndex), nil
+}
+
+// processDryRun completes a queued dry-run item with its plan instead of downloading
+func (q *Queue) processDryRun(id string, item *QueueItem, config *Config) {
+	q.mutex.RLock()
+	fileIndex := q.fileIndex
+	q.mutex.RUnlock()
+
+	plan := PlanDownload(item, config, fileIndex)
+
+	q.updateItem(id, func(item *QueueItem) {
+		item.Plan = plan
+		if item.Title == "" {
+			item.Title = plan.Title
+			item.Artist = plan.Artist
+			item.Duration = plan.Duration
+		}
+		item.AudioSource = plan.AudioSource
+		item.OutputPath = plan.OutputPath
+		item.Status = StatusComplete
+		item.Progress = 100
+		item.setStage(StageDryRunComplete)
+		if !plan.WillDownload && plan.ExistingFile == "" {
+			item.setStage(StageDryRunWouldFail)
+		}
+		item.CompletedAt = time.Now()
+	})
+	q.emit(QueueEvent{
+		Type:     "completed",
+		ItemID:   id,
+		Progress: 100,
+		Status:   StatusComplete,
+	})
+}
+
+// CommitDryRun turns a planned dry-run item into a real download
+func (q *Queue) CommitDryRun(id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if !item.DryRun || item.Status != StatusComplete {
+		return fmt.Errorf("item %s is not a finished dry run", id)
+	}
+	item.DryRun = false
+	item.Plan = nil
+	item.OutputPath = ""
+	item.AudioSource = ""
+	q.setStatus(item, StatusPending)
+	item.Progress = 0
+	item.setStage(StageWaiting)
+	item.CompletedAt = time.Time{}
+	cp := *item
+	go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanDownload_MetadataOnly(t *testing.T) {
+	config := GetDefaultConfig()
+	config.OutputDirectory = "/music"
+	config.NamingTemplate = "{artist}/{title}"
+
+	plan := PlanDownload(&QueueItem{Title: "Song", Artist: "Artist"}, config, nil)
+	if !plan.WillDownload || plan.AudioSource != "tidal-search" {
+		t.Fatalf("expected a Tidal search plan, got %+v", plan)
+	}
+	if want := filepath.Join("/music", "Artist", "Song.flac"); plan.OutputPath != want {
+		t.Errorf("OutputPath = %q, want %q", plan.OutputPath, want)
+	}
+
+	// Excluding Tidal leaves nothing to try without a video
+	plan = PlanDownload(&QueueItem{Title: "Song", Artist: "Artist", AudioSourcePriority: []string{"qobuz"}}, config, nil)
+	if plan.WillDownload || plan.Steps[len(plan.Steps)-1].Result != PlanStepFail {
+		t.Errorf("expected a failing plan, got %+v", plan.Steps)
+	}
+
+	// The "fail" fallback policy never settles for lossy audio
+	config.FallbackPolicy = FallbackFail
+	plan = PlanDownload(&QueueItem{Title: "Song", Artist: "Artist", AudioSourcePriority: []string{"qobuz"}}, config, nil)
+	if last := plan.Steps[len(plan.Steps)-1]; plan.WillDownload || last.Result != PlanStepFail || !strings.Contains(last.Detail, "fallback policy") {
+		t.Errorf("expected the fallback policy to fail the plan, got %+v", plan.Steps)
+	}
+}
+
+func TestPlanDownload_InvalidURL(t *testing.T) {
+	plan := PlanDownload(&QueueItem{VideoURL: "https://example.com/nope"}, GetDefaultConfig(), nil)
+	if plan.WillDownload || len(plan.Steps) != 1 || plan.Steps[0].Stage != "parse_url" {
+		t.Errorf("expected the plan to stop at URL parsing, got %+v", plan.Steps)
+	}
+}
+
+func TestPlanDownload_ExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "Artist", "Song.mkv")
+	writeTestFile(t, existing, 10)
+
+	fi := NewFileIndex(dir)
+	fi.AddEntry(FileIndexEntry{Path: existing, Title: "Song", Artist: "Artist", IndexedAt: time.Now()})
+
+	config := GetDefaultConfig()
+	config.OutputDirectory = dir
+	plan := PlanDownload(&QueueItem{Title: "Song", Artist: "Artist", PlaylistName: "Mix", PlaylistPosition: 3}, config, fi)
+	if plan.WillDownload || plan.ExistingFile != existing {
+		t.Fatalf("expected the existing file to be reused, got %+v", plan)
+	}
+	if !strings.HasPrefix(plan.OutputPath, filepath.Join(dir, "Mix")+string(filepath.Separator)) {
+		t.Errorf("OutputPath = %q, want it inside the playlist folder", plan.OutputPath)
+	}
+}
+
+func TestQueue_DryRunItem(t *testing.T) {
+	q := newTestQueue()
+	config := GetDefaultConfig()
+	config.OutputDirectory = t.TempDir()
+	q.SetConfig(config)
+
+	id, err := q.AddToQueueWithMetadata(DownloadRequest{DryRun: true}, &VideoInfo{Title: "Song", Artist: "Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.processItem(id)
+
+	item := q.GetItem(id)
+	if item.Status != StatusComplete || item.Plan == nil || item.OutputPath != item.Plan.OutputPath {
+		t.Fatalf("dry run not recorded: %+v", item)
+	}
+	if entries, _ := os.ReadDir(config.OutputDirectory); len(entries) != 0 {
+		t.Errorf("dry run wrote files: %v", entries)
+	}
+
+	if err := q.CommitDryRun(id); err != nil {
+		t.Fatalf("CommitDryRun failed: %v", err)
+	}
+	item = q.GetItem(id)
+	if item.Status != StatusPending || item.DryRun || item.Plan != nil {
+		t.Errorf("committed item not reset: %+v", item)
+	}
+	if err := q.CommitDryRun(id); err == nil {
+		t.Error("committing twice should fail")
+	}
+}
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"fmt"
+
+	"youflac/backend/format"
+)
+
+// Duration limits. Config.MinVideoDuration and MaxVideoDuration keep Shorts,
+// teasers and 10-hour loops out of the library. Playlist and channel imports
+// apply them before queueing; single adds are checked once the processor
+// knows the duration. With DurationPolicyFlag the video is downloaded anyway
+// and the queue item carries the reason in DurationFlag.
+
+// Duration policies
+const (
+	DurationPolicySkip = "skip"
+	DurationPolicyFlag = "flag"
+)
+
+// DurationOutsideLimits returns why duration is outside the configured
+// limits, or "" when it is within them or unknown (0)
+func DurationOutsideLimits(duration float64, config *Config) string {
+	if config == nil || duration <= 0 {
+		return ""
+	}
+	if config.MinVideoDuration > 0 && duration < float64(config.MinVideoDuration) {
+		return fmt.Sprintf("%s is shorter than the minimum of %s", format.Duration(duration), format.Duration(float64(config.MinVideoDuration)))
+	}
+	if config.MaxVideoDuration > 0 && duration > float64(config.MaxVideoDuration) {
+		return fmt.Sprintf("%s is longer than the maximum of %s", format.Duration(duration), format.Duration(float64(config.MaxVideoDuration)))
+	}
+	return ""
+}
+
+// skipsOutOfRange reports whether out-of-range videos are left out rather than flagged
+func skipsOutOfRange(config *Config) bool {
+	return config != nil && config.DurationPolicy != DurationPolicyFlag
+}
@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"sync"
+)
+
+// eventBus fans queue events out to any number of subscribers, next to the
+// single UI callback of SetProgressCallback. Integrations such as chat bots
+// subscribe here. Subscribers run on the goroutine that emitted the event
+// and must not block: hand the event to a channel and return.
+type eventBus struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]QueueProgressCallback
+}
+
+func (b *eventBus) subscribe(cb QueueProgressCallback) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]QueueProgressCallback)
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = cb
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *eventBus) publish(event QueueEvent) {
+	b.mu.RLock()
+	subs := make([]QueueProgressCallback, 0, len(b.subs))
+	for _, cb := range b.subs {
+		subs = append(subs, cb)
+	}
+	b.mu.RUnlock()
+
+	for _, cb := range subs {
+		cb(event)
+	}
+}
+
+// Subscribe calls cb for every queue event until the returned function is
+// called. cb must not block.
+func (q *Queue) Subscribe(cb QueueProgressCallback) (unsubscribe func()) {
+	return q.events.subscribe(cb)
+}
@@ -0,0 +1,37 @@
+package backend
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestQueueSubscribe(t *testing.T) {
+	q := newTestQueue()
+
+	var mu sync.Mutex
+	var a, b []string
+	unsubscribeA := q.Subscribe(func(e QueueEvent) {
+		mu.Lock()
+		a = append(a, e.Type)
+		mu.Unlock()
+	})
+	q.Subscribe(func(e QueueEvent) {
+		mu.Lock()
+		b = append(b, e.Type)
+		mu.Unlock()
+	})
+
+	q.emit(QueueEvent{Type: "updated", ItemID: "x"})
+	unsubscribeA()
+	unsubscribeA() // Safe to call twice
+	q.emit(QueueEvent{Type: "removed", ItemID: "x"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(a) != 1 || a[0] != "updated" {
+		t.Errorf("unsubscribed subscriber got %v, want [updated]", a)
+	}
+	if len(b) != 2 {
+		t.Errorf("subscriber got %v, want both events", b)
+	}
+}
@@ -0,0 +1,60 @@
+package backend
+
+// =============================================================================
+// Explicit content
+// =============================================================================
+
+// Tidal marks tracks with explicit lyrics. The flag travels with the audio
+// (AudioTrackInfo.Explicit) into the tags (ITUNESADVISORY=1), the NFO
+// (<mpaa>Explicit</mpaa>) and the {explicit} naming placeholder. When a
+// Tidal search finds both the explicit and the clean version of a track,
+// Config.ExplicitPreference picks one.
+
+// Values of Config.ExplicitPreference
+const (
+	ExplicitAny    = "any"    // Take the best match, explicit or not
+	ExplicitPrefer = "prefer" // Take the explicit version when there is one
+	ExplicitAvoid  = "avoid"  // Take the clean version when there is one
+)
+
+// ExplicitPlaceholderLabel is what {explicit} expands to for explicit tracks
+const ExplicitPlaceholderLabel = "[E]"
+
+// explicitLabel returns the {explicit} value of metadata
+func explicitLabel(metadata *Metadata) string {
+	if metadata.Explicit {
+		return ExplicitPlaceholderLabel
+	}
+	return ""
+}
+
+// pickExplicitVersion returns the search result to download. Results are
+// ranked by Tidal, so the first one is the match; another result is only
+// taken if it is the same song (same title and artist) with the explicit
+// flag the preference asks for.
+func pickExplicitVersion(items []TidalTrackResponse, preference string) *TidalTrackResponse {
+	best := &items[0]
+	if preference != ExplicitPrefer && preference != ExplicitAvoid {
+		return best
+	}
+	want := preference == ExplicitPrefer
+	if best.Explicit == want {
+		return best
+	}
+	title, artist := normalizeTitle(best.Title), normalizeArtist(tidalArtistName(best))
+	for i := range items[1:] {
+		item := &items[i+1]
+		if item.Explicit == want && normalizeTitle(item.Title) == title && normalizeArtist(tidalArtistName(item)) == artist {
+			return item
+		}
+	}
+	return best
+}
+
+// tidalArtistName returns the main artist of a Tidal track
+func tidalArtistName(track *TidalTrackResponse) string {
+	if track.Artist.Name == "" && len(track.Artists) > 0 {
+		return track.Artists[0].Name
+	}
+	return track.Artist.Name
+}
@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func explicitTestTrack(id int, title, artist string, explicit bool) TidalTrackResponse {
+	track := TidalTrackResponse{ID: id, Title: title, Explicit: explicit}
+	track.Artist.Name = artist
+	return track
+}
+
+func TestPickExplicitVersion(t *testing.T) {
+	items := []TidalTrackResponse{
+		explicitTestTrack(1, "Song", "Artist", true),
+		explicitTestTrack(2, "Other Song", "Artist", false),
+		explicitTestTrack(3, "Song", "Artist", false),
+	}
+
+	tests := []struct {
+		preference string
+		want       int
+	}{
+		{ExplicitAny, 1},
+		{"", 1},
+		{ExplicitPrefer, 1},
+		{ExplicitAvoid, 3},
+	}
+	for _, tt := range tests {
+		if got := pickExplicitVersion(items, tt.preference); got.ID != tt.want {
+			t.Errorf("pickExplicitVersion(%q) = %d, want %d", tt.preference, got.ID, tt.want)
+		}
+	}
+
+	// No clean version of the same song: keep the best match
+	if got := pickExplicitVersion(items[:2], ExplicitAvoid); got.ID != 1 {
+		t.Errorf("pickExplicitVersion without clean version = %d, want 1", got.ID)
+	}
+}
+
+func TestApplyTemplate_Explicit(t *testing.T) {
+	metadata := &Metadata{Artist: "Artist", Title: "Song", Explicit: true}
+	if got := ApplyTemplate("{artist} - {title} {explicit}", metadata); got != "Artist - Song [E]" {
+		t.Errorf("explicit = %q", got)
+	}
+	metadata.Explicit = false
+	if got := ApplyTemplate("{artist} - {title} {explicit}", metadata); got != "Artist - Song" {
+		t.Errorf("clean = %q", got)
+	}
+}
+
+func TestGenerateNFO_Explicit(t *testing.T) {
+	nfo, err := GenerateNFO(&Metadata{Title: "Song", Artist: "Artist", Explicit: true}, nil)
+	if err != nil {
+		t.Fatalf("GenerateNFO: %v", err)
+	}
+	if !strings.Contains(string(nfo), "<mpaa>Explicit</mpaa>") {
+		t.Errorf("NFO has no explicit rating:\n%s", nfo)
+	}
+}
+
+func TestConfigValidate_ExplicitPreference(t *testing.T) {
+	config := GetDefaultConfig()
+	config.ExplicitPreference = "Prefer"
+	config.Validate()
+	if config.ExplicitPreference != ExplicitPrefer {
+		t.Errorf("ExplicitPreference = %q, want prefer", config.ExplicitPreference)
+	}
+
+	config.ExplicitPreference = "sometimes"
+	validation := config.Validate()
+	if !hasIssue(validation.Warnings, "explicitPreference") || config.ExplicitPreference != ExplicitAny {
+		t.Errorf("unknown preference: %q, warnings %+v", config.ExplicitPreference, validation.Warnings)
+	}
+}
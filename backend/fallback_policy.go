@@ -0,0 +1,16 @@
+package backend
+
+// Fallback policies (Config.FallbackPolicy): what an item does when no
+// service has the track in lossless quality
+const (
+	FallbackYouTubeMusic = "ytmusic" // YouTube Music audio, else the video's audio track
+	FallbackExtract      = "extract" // The video's audio track
+	FallbackUpgrade      = "upgrade" // As ytmusic, and flag the item to be downloaded again later
+	FallbackFail         = "fail"    // Fail the item
+)
+
+// fallbackTriesYouTubeMusic reports whether the policy downloads the YouTube
+// Music audio before extracting the video's
+func fallbackTriesYouTubeMusic(policy string) bool {
+	return policy == FallbackYouTubeMusic || policy == FallbackUpgrade || policy == ""
+}
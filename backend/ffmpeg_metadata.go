@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -40,7 +41,7 @@ func embedMetadataMkvpropedit(mkvPath string, metadata map[string]string, mkvpro
 		}
 	}
 
-	cmd := exec.Command(mkvpropeditPath, args...)
+	cmd := newCommand(mkvpropeditPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -53,23 +54,25 @@ func embedMetadataMkvpropedit(mkvPath string, metadata map[string]string, mkvpro
 
 func embedMetadataFFmpeg(mkvPath string, metadata map[string]string) error {
 	// FFmpeg requires re-muxing to change metadata
-	tempPath := mkvPath + ".tmp"
+	// The extension tells ffmpeg the output format
+	tempPath := strings.TrimSuffix(mkvPath, filepath.Ext(mkvPath)) + ".tmp" + filepath.Ext(mkvPath)
 
+	// Every stream is kept: extra audio tracks, attachments, subtitles
 	args := []string{
 		"-y",
 		"-i", mkvPath,
+		"-map", "0",
 		"-c", "copy",
 	}
 
+	// An empty value removes the tag
 	for key, value := range metadata {
-		if value != "" {
-			args = append(args, "-metadata", fmt.Sprintf("%s=%s", key, value))
-		}
+		args = append(args, "-metadata", fmt.Sprintf("%s=%s", key, value))
 	}
 
 	args = append(args, tempPath)
 
-	cmd := exec.Command(GetFFmpegPath(), args...)
+	cmd := newCommand(GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -79,15 +82,16 @@ func embedMetadataFFmpeg(mkvPath string, metadata map[string]string) error {
 	}
 
 	// Replace original with temp
-	if err := os.Rename(tempPath, mkvPath); err != nil {
+	if err := MoveFile(tempPath, mkvPath); err != nil {
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
+	FinishOutputFile(mkvPath)
 
 	return nil
 }
 
-// EmbedCoverArt adds cover art to existing MKV
+// EmbedCoverArt adds cover art to existing MKV (FLAC files get a native PICTURE block)
 func EmbedCoverArt(mkvPath, coverPath string) error {
 	if _, err := os.Stat(mkvPath); os.IsNotExist(err) {
 		return fmt.Errorf("mkv file not found: %s", mkvPath)
@@ -96,6 +100,10 @@ func EmbedCoverArt(mkvPath, coverPath string) error {
 		return fmt.Errorf("cover file not found: %s", coverPath)
 	}
 
+	if strings.EqualFold(filepath.Ext(mkvPath), ".flac") {
+		return FLACTagWriter{}.EmbedCover(mkvPath, coverPath)
+	}
+
 	// Try mkvpropedit first
 	mkvpropeditPath, err := exec.LookPath("mkvpropedit")
 	if err == nil {
@@ -112,7 +120,7 @@ func embedCoverMkvpropedit(mkvPath, coverPath, mkvpropeditPath string) error {
 		"--add-attachment", coverPath,
 	}
 
-	cmd := exec.Command(mkvpropeditPath, args...)
+	cmd := newCommand(mkvpropeditPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -138,7 +146,7 @@ func embedCoverFFmpeg(mkvPath, coverPath string) error {
 		tempPath,
 	}
 
-	cmd := exec.Command(GetFFmpegPath(), args...)
+	cmd := newCommand(GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -147,10 +155,11 @@ func embedCoverFFmpeg(mkvPath, coverPath string) error {
 		return fmt.Errorf("ffmpeg cover failed: %v - %s", err, stderr.String())
 	}
 
-	if err := os.Rename(tempPath, mkvPath); err != nil {
+	if err := MoveFile(tempPath, mkvPath); err != nil {
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
+	FinishOutputFile(mkvPath)
 
 	return nil
 }
@@ -162,7 +171,7 @@ func AddChapters(mkvPath string, chapters []Chapter) error {
 	}
 
 	// Create chapters file in XML format for mkvpropedit
-	chaptersFile, err := os.CreateTemp("", "chapters-*.xml")
+	chaptersFile, err := os.CreateTemp(GetTempDirectory(), "chapters-*.xml")
 	if err != nil {
 		return fmt.Errorf("failed to create chapters file: %w", err)
 	}
@@ -208,7 +217,7 @@ func AddChapters(mkvPath string, chapters []Chapter) error {
 		"--chapters", chaptersFile.Name(),
 	}
 
-	cmd := exec.Command(mkvpropeditPath, args...)
+	cmd := newCommand(mkvpropeditPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -26,8 +26,46 @@ type MuxOptions struct {
 	CoverArtPath string            `json:"coverArtPath,omitempty"`
 	Chapters     []Chapter         `json:"chapters,omitempty"`
 	Overwrite    bool              `json:"overwrite"` // Overwrite output if exists
+
+	// Backend selects the muxer ("ffmpeg" or "mkvmerge"); mkvmerge falls back to ffmpeg on failure
+	Backend        string `json:"backend,omitempty"`
+	AudioLanguage  string `json:"audioLanguage,omitempty"`  // ISO 639-2 code for the FLAC track ("und" if empty)
+	AudioTrackName string `json:"audioTrackName,omitempty"` // e.g. "FLAC 24/96"
+	VideoTrackName string `json:"videoTrackName,omitempty"` // defaults to "Official Video"
+
+	// KeepOriginalAudio adds the video's own audio as a second, non-default track
+	KeepOriginalAudio bool `json:"keepOriginalAudio,omitempty"`
+
+	// SurroundAudioPath adds a multi-channel mix (e.g. Dolby Atmos) as a non-default track
+	SurroundAudioPath string `json:"surroundAudioPath,omitempty"`
+
+	// Leading-silence A/V sync. NoSilenceTrim keeps excess FLAC silence (the
+	// FLAC is still delayed when it starts early); zero thresholds use the defaults.
+	NoSilenceTrim      bool    `json:"noSilenceTrim,omitempty"`
+	SilenceThresholdDB float64 `json:"silenceThresholdDb,omitempty"` // Below this level counts as silence (default -50)
+	SilenceMinDuration float64 `json:"silenceMinDuration,omitempty"` // Seconds of silence needed to count (default 0.05)
+}
+
+// Default leading-silence detection thresholds
+const (
+	DefaultSilenceThresholdDB = -50.0
+	DefaultSilenceMinDuration = 0.05
+)
+
+// SyncAdjustment records the A/V sync correction applied to the FLAC track
+type SyncAdjustment struct {
+	Trimmed   float64 `json:"trimmed,omitempty"`   // Seconds of excess leading silence cut from the FLAC
+	Delay     float64 `json:"delay,omitempty"`     // Seconds the FLAC was delayed to match the video
+	Skipped   float64 `json:"skipped,omitempty"`   // Excess silence left in place because trimming is disabled
+	TrimError string  `json:"trimError,omitempty"` // Trimming was needed but failed; the FLAC was muxed as is
 }
 
+// Track names of the secondary audio tracks
+const (
+	OriginalAudioTrackName = "Original Video Audio"
+	SurroundTrackName      = "Surround Mix"
+)
+
 // Chapter represents a chapter marker
 type Chapter struct {
 	Title     string  `json:"title"`
@@ -37,20 +75,22 @@ type Chapter struct {
 
 // MediaInfo contains media file information from ffprobe
 type MediaInfo struct {
-	Duration    float64     `json:"duration"`
-	VideoCodec  string      `json:"videoCodec"`
-	AudioCodec  string      `json:"audioCodec"`
-	Width       int         `json:"width"`
-	Height      int         `json:"height"`
-	Bitrate     int64       `json:"bitrate"`
-	FrameRate   float64     `json:"frameRate"`
-	SampleRate  int         `json:"sampleRate"`
-	Channels    int         `json:"channels"`
-	Format      string      `json:"format"`
-	HasVideo    bool        `json:"hasVideo"`
-	HasAudio    bool        `json:"hasAudio"`
-	VideoStream *StreamInfo `json:"videoStream,omitempty"`
-	AudioStream *StreamInfo `json:"audioStream,omitempty"`
+	Duration    float64      `json:"duration"`
+	VideoCodec  string       `json:"videoCodec"`
+	AudioCodec  string       `json:"audioCodec"`
+	Width       int          `json:"width"`
+	Height      int          `json:"height"`
+	Bitrate     int64        `json:"bitrate"`
+	FrameRate   float64      `json:"frameRate"`
+	SampleRate  int          `json:"sampleRate"`
+	BitDepth    int          `json:"bitDepth,omitempty"`
+	Channels    int          `json:"channels"`
+	Format      string       `json:"format"`
+	HasVideo    bool         `json:"hasVideo"`
+	HasAudio    bool         `json:"hasAudio"`
+	Gapless     *GaplessInfo `json:"gapless,omitempty"` // Encoder delay/padding (iTunSMPB)
+	VideoStream *StreamInfo  `json:"videoStream,omitempty"`
+	AudioStream *StreamInfo  `json:"audioStream,omitempty"`
 }
 
 // StreamInfo contains detailed stream information
@@ -79,6 +119,8 @@ type MuxResult struct {
 	HasCoverArt bool          `json:"hasCoverArt"`
 	HasMetadata bool          `json:"hasMetadata"`
 	HasChapters bool          `json:"hasChapters"`
+
+	Sync SyncAdjustment `json:"sync"` // A/V sync correction (MKV only)
 }
 
 // ProgressCallback is called during muxing with progress updates
@@ -106,6 +148,19 @@ func DefaultMuxOptions() MuxOptions {
 	}
 }
 
+// muxOptionsFromConfig returns the default options with the mux settings
+// of config applied
+func muxOptionsFromConfig(config *Config) MuxOptions {
+	opts := DefaultMuxOptions()
+	opts.Backend = config.MuxBackend
+	opts.AudioLanguage = config.AudioLanguage
+	opts.KeepOriginalAudio = config.KeepOriginalAudio
+	opts.NoSilenceTrim = !config.SilenceTrim
+	opts.SilenceThresholdDB = config.SilenceThresholdDB
+	opts.SilenceMinDuration = config.SilenceMinDuration
+	return opts
+}
+
 // MuxVideoAudio combines video and audio into MKV without re-encoding
 func MuxVideoAudio(videoPath, audioPath, outputPath string, opts MuxOptions) error {
 	return MuxVideoAudioWithProgress(videoPath, audioPath, outputPath, opts, nil)
@@ -113,19 +168,20 @@ func MuxVideoAudio(videoPath, audioPath, outputPath string, opts MuxOptions) err
 
 // detectLeadingSilenceFromStream measures the leading silence in a file's audio stream.
 // streamMap selects the audio stream (e.g. "0:a:0", or "" for default audio).
+// Audio below thresholdDB for at least minDuration seconds counts as silence.
 // Returns 0 if no leading silence is found or on any error.
-func detectLeadingSilenceFromStream(filePath, streamMap string) float64 {
+func detectLeadingSilenceFromStream(ctx context.Context, filePath, streamMap string, thresholdDB, minDuration float64) float64 {
 	ffmpegPath := GetFFmpegPath()
 	args := []string{"-i", filePath}
 	if streamMap != "" {
 		args = append(args, "-map", streamMap)
 	}
 	args = append(args,
-		"-af", "silencedetect=noise=-50dB:d=0.05",
+		"-af", fmt.Sprintf("silencedetect=noise=%gdB:d=%g", thresholdDB, minDuration),
 		"-f", "null", "-",
 	)
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommandContext(ctx, ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	cmd.Run() // exit code is irrelevant; output is in stderr
@@ -149,40 +205,62 @@ func detectLeadingSilenceFromStream(filePath, streamMap string) float64 {
 }
 
 // TrimAudioStart removes the first `duration` seconds from an audio file using
-// sample-accurate audio filters. Output is re-encoded to FLAC (lossless).
-func TrimAudioStart(inputPath, outputPath string, duration float64) error {
+// sample-accurate audio filters. Output is re-encoded to FLAC (lossless) and,
+// for lossless sources, verified against the trimmed source samples.
+func TrimAudioStart(ctx context.Context, inputPath, outputPath string, duration float64) error {
+	info, err := GetMediaInfo(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to get audio info: %w", err)
+	}
+	isFLAC := strings.EqualFold(info.AudioCodec, "flac") && strings.Contains(info.Format, "flac")
+
+	// Nothing to trim: keep the original encode
+	if duration <= 0 && isFLAC {
+		return copyFile(inputPath, outputPath)
+	}
+
+	filter := fmt.Sprintf("atrim=start=%.6f,asetpts=PTS-STARTPTS", duration)
 	ffmpegPath := GetFFmpegPath()
 	args := []string{
 		"-y",
 		"-i", inputPath,
-		"-af", fmt.Sprintf("atrim=start=%.6f,asetpts=PTS-STARTPTS", duration),
-		"-c:a", "flac",
-		"-compression_level", "5",
-		outputPath,
+		"-af", filter,
 	}
+	args = append(args, flacEncodeArgs()...)
+	args = append(args, outputPath)
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommandContext(ctx, ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("audio trim failed: %v - %s", err, stderr.String())
 	}
+	if isLosslessCodec(info.AudioCodec) {
+		return verifyFLACEncode(ctx, inputPath, nil, filter, outputPath)
+	}
 	return nil
 }
 
 // MuxVideoAudioWithProgress combines video and audio with progress callback
 func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts MuxOptions, progress ProgressCallback) error {
+	_, err := muxVideoAudio(context.Background(), videoPath, audioPath, outputPath, opts, progress)
+	return err
+}
+
+// muxVideoAudio combines video and audio and reports the A/V sync correction
+func muxVideoAudio(ctx context.Context, videoPath, audioPath, outputPath string, opts MuxOptions, progress ProgressCallback) (SyncAdjustment, error) {
+	var sync SyncAdjustment
 	if _, err := os.Stat(videoPath); os.IsNotExist(err) {
-		return fmt.Errorf("video file not found: %s", videoPath)
+		return sync, fmt.Errorf("video file not found: %s", videoPath)
 	}
 	if _, err := os.Stat(audioPath); os.IsNotExist(err) {
-		return fmt.Errorf("audio file not found: %s", audioPath)
+		return sync, fmt.Errorf("audio file not found: %s", audioPath)
 	}
 
 	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if err := MkdirOutput(outputDir); err != nil {
+		return sync, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	if progress != nil {
@@ -200,8 +278,17 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 	//   < 0 → FLAC has excess silence → trim it
 	const minAdjustSec = 0.05 // ignore differences < 50 ms
 
-	videoAudioSilence := detectLeadingSilenceFromStream(videoPath, "0:a:0")
-	flacSilence := detectLeadingSilenceFromStream(audioPath, "")
+	thresholdDB := opts.SilenceThresholdDB
+	if thresholdDB == 0 {
+		thresholdDB = DefaultSilenceThresholdDB
+	}
+	minDuration := opts.SilenceMinDuration
+	if minDuration <= 0 {
+		minDuration = DefaultSilenceMinDuration
+	}
+
+	videoAudioSilence := detectLeadingSilenceFromStream(ctx, videoPath, "0:a:0", thresholdDB, minDuration)
+	flacSilence := detectLeadingSilenceFromStream(ctx, audioPath, "", thresholdDB, minDuration)
 	adjust := videoAudioSilence - flacSilence
 
 	slog.Debug("A/V sync analysis",
@@ -213,22 +300,43 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 	effectiveAudioPath := audioPath
 	var itsOffset float64
 
-	if adjust < -minAdjustSec {
+	if adjust < -minAdjustSec && opts.NoSilenceTrim {
+		// Quiet intros may be intentional; leave them alone when asked to
+		sync.Skipped = -adjust
+		slog.Info("A/V sync: silence trim disabled, keeping FLAC excess silence", "excess_sec", -adjust)
+	} else if adjust < -minAdjustSec {
 		// FLAC has more silence than the video audio → trim the excess
 		trimPath := audioPath + ".sync_trimmed.flac"
-		if err := TrimAudioStart(audioPath, trimPath, -adjust); err == nil {
+		if err := TrimAudioStart(ctx, audioPath, trimPath, -adjust); err == nil {
 			slog.Info("A/V sync: trimmed FLAC excess silence", "trim_sec", -adjust)
 			defer os.Remove(trimPath)
 			effectiveAudioPath = trimPath
+			sync.Trimmed = -adjust
 		} else {
 			slog.Warn("A/V sync: trim failed, proceeding without trim", "err", err)
+			sync.TrimError = err.Error()
 		}
 	} else if adjust > minAdjustSec {
 		// FLAC needs to start later → delay it with itsoffset
 		itsOffset = adjust
+		sync.Delay = adjust
 		slog.Info("A/V sync: delaying FLAC with itsoffset", "itsoffset_sec", itsOffset)
 	}
 
+	if opts.Backend == MuxBackendMKVMerge {
+		if progress != nil {
+			progress(10, "Starting mkvmerge")
+		}
+		err := muxWithMKVMerge(videoPath, effectiveAudioPath, outputPath, opts, itsOffset)
+		if err == nil {
+			if progress != nil {
+				progress(100, "Muxing complete")
+			}
+			return sync, nil
+		}
+		slog.Warn("mkvmerge mux failed, falling back to ffmpeg", "err", err)
+	}
+
 	ffmpegPath := GetFFmpegPath()
 	args := []string{}
 
@@ -243,17 +351,38 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 		args = append(args, "-itsoffset", fmt.Sprintf("%.6f", itsOffset))
 	}
 	args = append(args, "-i", effectiveAudioPath)
+	nextInput := 2
+
+	hasSurround := opts.SurroundAudioPath != "" && fileExists(opts.SurroundAudioPath)
+	surroundInput := 0
+	if hasSurround {
+		args = append(args, "-i", opts.SurroundAudioPath)
+		surroundInput = nextInput
+		nextInput++
+	}
 
 	hasCover := opts.CoverArtPath != "" && fileExists(opts.CoverArtPath)
+	coverInput := 0
 	if hasCover {
 		args = append(args, "-i", opts.CoverArtPath)
+		coverInput = nextInput
 	}
 
+	// Audio track order: FLAC (default), surround mix, original video audio
 	args = append(args, "-map", "0:v:0")
 	args = append(args, "-map", "1:a:0")
+	audioTitles := []string{opts.AudioTrackName}
+	if hasSurround {
+		args = append(args, "-map", fmt.Sprintf("%d:a:0", surroundInput))
+		audioTitles = append(audioTitles, SurroundTrackName)
+	}
+	if opts.KeepOriginalAudio {
+		args = append(args, "-map", "0:a:0")
+		audioTitles = append(audioTitles, OriginalAudioTrackName)
+	}
 
 	if hasCover {
-		args = append(args, "-map", "2:0")
+		args = append(args, "-map", fmt.Sprintf("%d:0", coverInput))
 	}
 
 	videoCodec := opts.VideoCodec
@@ -273,6 +402,23 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 		args = append(args, "-disposition:v:1", "attached_pic")
 	}
 
+	if len(audioTitles) > 1 {
+		// FLAC stays the default track; the others are there for comparison
+		for i, title := range audioTitles {
+			disposition := "0"
+			if i == 0 {
+				disposition = "default"
+			}
+			args = append(args, fmt.Sprintf("-disposition:a:%d", i), disposition)
+			if title != "" {
+				args = append(args, fmt.Sprintf("-metadata:s:a:%d", i), "title="+title)
+			}
+			if opts.AudioLanguage != "" {
+				args = append(args, fmt.Sprintf("-metadata:s:a:%d", i), "language="+opts.AudioLanguage)
+			}
+		}
+	}
+
 	for key, value := range opts.Metadata {
 		if value != "" {
 			args = append(args, "-metadata", fmt.Sprintf("%s=%s", key, value))
@@ -286,12 +432,12 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 		progress(10, "Starting FFmpeg")
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommandContext(ctx, ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return &MuxError{
+		return sync, &MuxError{
 			Command: ffmpegPath,
 			Args:    args,
 			Stderr:  stderr.String(),
@@ -303,11 +449,17 @@ func MuxVideoAudioWithProgress(videoPath, audioPath, outputPath string, opts Mux
 		progress(100, "Muxing complete")
 	}
 
-	return nil
+	return sync, nil
 }
 
 // MuxVideoWithFLAC is a high-level function that handles the complete muxing workflow
 func MuxVideoWithFLAC(videoPath, audioPath, outputPath string, metadata *Metadata, coverPath string, progress ProgressCallback) (*MuxResult, error) {
+	return MuxVideoWithFLACOptions(context.Background(), videoPath, audioPath, outputPath, metadata, coverPath, DefaultMuxOptions(), progress)
+}
+
+// MuxVideoWithFLACOptions is MuxVideoWithFLAC with a caller-chosen backend and track settings.
+// Metadata, cover art and the audio track name are filled in from the inputs.
+func MuxVideoWithFLACOptions(ctx context.Context, videoPath, audioPath, outputPath string, metadata *Metadata, coverPath string, opts MuxOptions, progress ProgressCallback) (*MuxResult, error) {
 	startTime := time.Now()
 
 	if progress != nil {
@@ -330,6 +482,10 @@ func MuxVideoWithFLAC(videoPath, audioPath, outputPath string, metadata *Metadat
 	if !audioInfo.HasAudio {
 		return nil, fmt.Errorf("input audio file has no audio stream")
 	}
+	if opts.KeepOriginalAudio && !videoInfo.HasAudio {
+		slog.Debug("video has no audio stream, muxing FLAC only", "path", videoPath)
+		opts.KeepOriginalAudio = false
+	}
 
 	if progress != nil {
 		progress(10, "Validating inputs")
@@ -343,23 +499,48 @@ func MuxVideoWithFLAC(videoPath, audioPath, outputPath string, metadata *Metadat
 		if metadata.Artist != "" {
 			metadataMap["artist"] = metadata.Artist
 		}
+		if metadata.AlbumArtist != "" {
+			metadataMap["album_artist"] = metadata.AlbumArtist
+		}
 		if metadata.Album != "" {
 			metadataMap["album"] = metadata.Album
 		}
 		if metadata.Year > 0 {
 			metadataMap["date"] = strconv.Itoa(metadata.Year)
 		}
+		if metadata.Genre != "" {
+			metadataMap["genre"] = metadata.Genre
+		}
 		if metadata.ISRC != "" {
 			metadataMap["ISRC"] = metadata.ISRC
 		}
+		if metadata.Explicit {
+			metadataMap["ITUNESADVISORY"] = "1"
+		}
+		if id := albumIDTagValue(metadata.AlbumID); id != "" {
+			metadataMap[AlbumIDTag] = id
+		}
+		if metadata.YouTubeID != "" {
+			metadataMap[YouTubeIDTag] = metadata.YouTubeID
+		}
+		if metadata.Track > 0 {
+			metadataMap["track"] = strconv.Itoa(metadata.Track)
+			if metadata.TrackTotal > 0 {
+				metadataMap["track"] += "/" + strconv.Itoa(metadata.TrackTotal)
+			}
+		}
+		if metadata.Disc > 0 {
+			metadataMap["disc"] = strconv.Itoa(metadata.Disc)
+		}
 	}
 
-	opts := MuxOptions{
-		VideoCodec:   "copy",
-		AudioCodec:   "copy",
-		Metadata:     metadataMap,
-		CoverArtPath: coverPath,
-		Overwrite:    true,
+	opts.VideoCodec = "copy"
+	opts.AudioCodec = "copy"
+	opts.Metadata = metadataMap
+	opts.CoverArtPath = coverPath
+	opts.Overwrite = true
+	if opts.AudioTrackName == "" {
+		opts.AudioTrackName = AudioTrackName(audioInfo)
 	}
 
 	muxProgress := func(p float64, stage string) {
@@ -368,7 +549,8 @@ func MuxVideoWithFLAC(videoPath, audioPath, outputPath string, metadata *Metadat
 		}
 	}
 
-	if err := MuxVideoAudioWithProgress(videoPath, audioPath, outputPath, opts, muxProgress); err != nil {
+	sync, err := muxVideoAudio(ctx, videoPath, audioPath, outputPath, opts, muxProgress)
+	if err != nil {
 		return nil, err
 	}
 
@@ -400,12 +582,13 @@ func MuxVideoWithFLAC(videoPath, audioPath, outputPath string, metadata *Metadat
 		HasCoverArt: coverPath != "" && fileExists(coverPath),
 		HasMetadata: len(metadataMap) > 0,
 		HasChapters: false,
+		Sync:        sync,
 	}, nil
 }
 
 // CreateFLACWithMetadata creates a FLAC file with embedded metadata and optional cover art.
 // Used for audio-only fallback when video is unavailable.
-func CreateFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, coverPath string) (*MuxResult, error) {
+func CreateFLACWithMetadata(ctx context.Context, audioPath, outputPath string, metadata *Metadata, coverPath string) (*MuxResult, error) {
 	startTime := time.Now()
 
 	audioInfo, err := GetMediaInfo(audioPath)
@@ -417,11 +600,27 @@ func CreateFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, co
 		return nil, fmt.Errorf("input file has no audio stream")
 	}
 
+	isFLAC := strings.EqualFold(audioInfo.AudioCodec, "flac")
+	hasCover := coverPath != "" && fileExists(coverPath)
+
+	// FLAC sources are copied as-is so encoder padding and gapless album
+	// transitions survive; ffmpeg would rewrite STREAMINFO and padding
+	if isFLAC && strings.Contains(audioInfo.Format, "flac") {
+		err := CopyFLACWithMetadata(audioPath, outputPath, metadata, coverPath)
+		if err == nil {
+			return flacMuxResult(outputPath, metadata, hasCover, startTime)
+		}
+		slog.Warn("native FLAC copy failed, falling back to ffmpeg", "path", audioPath, "err", err)
+	}
+
 	ffmpegPath := GetFFmpegPath()
 	args := []string{"-y"}
+	gaplessArgs, gaplessFilter := gaplessInputArgs(audioInfo)
+	if !isFLAC {
+		args = append(args, gaplessArgs...)
+	}
 	args = append(args, "-i", audioPath)
 
-	hasCover := coverPath != "" && fileExists(coverPath)
 	if hasCover {
 		args = append(args, "-i", coverPath)
 	}
@@ -431,10 +630,13 @@ func CreateFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, co
 		args = append(args, "-map", "1:0")
 	}
 
-	if strings.EqualFold(audioInfo.AudioCodec, "flac") {
+	if isFLAC {
 		args = append(args, "-c:a", "copy")
 	} else {
-		args = append(args, "-c:a", "flac", "-compression_level", "8")
+		if gaplessFilter != "" {
+			args = append(args, "-af", gaplessFilter)
+		}
+		args = append(args, flacEncodeArgs()...)
 	}
 
 	if hasCover {
@@ -448,22 +650,46 @@ func CreateFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, co
 		if metadata.Artist != "" {
 			args = append(args, "-metadata", fmt.Sprintf("ARTIST=%s", metadata.Artist))
 		}
+		if metadata.AlbumArtist != "" {
+			args = append(args, "-metadata", fmt.Sprintf("ALBUMARTIST=%s", metadata.AlbumArtist))
+		}
 		if metadata.Album != "" {
 			args = append(args, "-metadata", fmt.Sprintf("ALBUM=%s", metadata.Album))
 		}
 		if metadata.Year > 0 {
 			args = append(args, "-metadata", fmt.Sprintf("DATE=%d", metadata.Year))
 		}
+		if metadata.Genre != "" {
+			args = append(args, "-metadata", fmt.Sprintf("GENRE=%s", metadata.Genre))
+		}
 		if metadata.ISRC != "" {
 			args = append(args, "-metadata", fmt.Sprintf("ISRC=%s", metadata.ISRC))
 		}
+		if metadata.Explicit {
+			args = append(args, "-metadata", "ITUNESADVISORY=1")
+		}
+		if id := albumIDTagValue(metadata.AlbumID); id != "" {
+			args = append(args, "-metadata", AlbumIDTag+"="+id)
+		}
+		if metadata.YouTubeID != "" {
+			args = append(args, "-metadata", YouTubeIDTag+"="+metadata.YouTubeID)
+		}
+		if metadata.Track > 0 {
+			args = append(args, "-metadata", fmt.Sprintf("TRACKNUMBER=%d", metadata.Track))
+		}
+		if metadata.TrackTotal > 0 {
+			args = append(args, "-metadata", fmt.Sprintf("TRACKTOTAL=%d", metadata.TrackTotal))
+		}
+		if metadata.Disc > 0 {
+			args = append(args, "-metadata", fmt.Sprintf("DISCNUMBER=%d", metadata.Disc))
+		}
 	}
 
 	args = append(args, outputPath)
 
 	slog.Debug("creating FLAC", "args", strings.Join(args, " "))
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommandContext(ctx, ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -471,6 +697,25 @@ func CreateFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, co
 		return nil, fmt.Errorf("ffmpeg failed: %v - %s", err, stderr.String())
 	}
 
+	// Re-compressed lossless sources must decode to the same samples
+	if !isFLAC && isLosslessCodec(audioInfo.AudioCodec) {
+		if err := verifyFLACEncode(ctx, audioPath, gaplessArgs, gaplessFilter, outputPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// ffmpeg can only write one value per key; add multi-value tags natively
+	if metadata != nil && len(metadata.Artists) > 1 {
+		if err := WriteTags(outputPath, map[string][]string{"ARTISTS": metadata.Artists}); err != nil {
+			slog.Warn("failed to write ARTISTS tag", "path", outputPath, "err", err)
+		}
+	}
+
+	return flacMuxResult(outputPath, metadata, hasCover, startTime)
+}
+
+// flacMuxResult builds the MuxResult for a finished FLAC file
+func flacMuxResult(outputPath string, metadata *Metadata, hasCover bool, startTime time.Time) (*MuxResult, error) {
 	outputInfo, err := os.Stat(outputPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify output: %w", err)
@@ -508,7 +753,7 @@ func GetMediaInfo(filePath string) (*MediaInfo, error) {
 		filePath,
 	}
 
-	cmd := exec.Command(ffprobePath, args...)
+	cmd := newCommand(ffprobePath, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -519,26 +764,29 @@ func GetMediaInfo(filePath string) (*MediaInfo, error) {
 
 	var probeData struct {
 		Streams []struct {
-			Index         int    `json:"index"`
-			CodecName     string `json:"codec_name"`
-			CodecLongName string `json:"codec_long_name"`
-			CodecType     string `json:"codec_type"`
-			Profile       string `json:"profile"`
-			Width         int    `json:"width"`
-			Height        int    `json:"height"`
-			SampleRate    string `json:"sample_rate"`
-			Channels      int    `json:"channels"`
-			BitRate       string `json:"bit_rate"`
-			Duration      string `json:"duration"`
-			RFrameRate    string `json:"r_frame_rate"`
-			AvgFrameRate  string `json:"avg_frame_rate"`
+			Index         int               `json:"index"`
+			CodecName     string            `json:"codec_name"`
+			CodecLongName string            `json:"codec_long_name"`
+			CodecType     string            `json:"codec_type"`
+			Profile       string            `json:"profile"`
+			Width         int               `json:"width"`
+			Height        int               `json:"height"`
+			SampleRate    string            `json:"sample_rate"`
+			BitsPerSample string            `json:"bits_per_raw_sample"`
+			Channels      int               `json:"channels"`
+			BitRate       string            `json:"bit_rate"`
+			Duration      string            `json:"duration"`
+			RFrameRate    string            `json:"r_frame_rate"`
+			AvgFrameRate  string            `json:"avg_frame_rate"`
+			Tags          map[string]string `json:"tags"`
 		} `json:"streams"`
 		Format struct {
-			Filename   string `json:"filename"`
-			FormatName string `json:"format_name"`
-			Duration   string `json:"duration"`
-			BitRate    string `json:"bit_rate"`
-			Size       string `json:"size"`
+			Filename   string            `json:"filename"`
+			FormatName string            `json:"format_name"`
+			Duration   string            `json:"duration"`
+			BitRate    string            `json:"bit_rate"`
+			Size       string            `json:"size"`
+			Tags       map[string]string `json:"tags"`
 		} `json:"format"`
 	}
 
@@ -556,6 +804,7 @@ func GetMediaInfo(filePath string) (*MediaInfo, error) {
 	if br, err := strconv.ParseInt(probeData.Format.BitRate, 10, 64); err == nil {
 		info.Bitrate = br
 	}
+	info.Gapless = findITunSMPB(probeData.Format.Tags)
 
 	for _, stream := range probeData.Streams {
 		switch stream.CodecType {
@@ -589,6 +838,12 @@ func GetMediaInfo(filePath string) (*MediaInfo, error) {
 				info.SampleRate = sr
 			}
 			info.Channels = stream.Channels
+			if bits, err := strconv.Atoi(stream.BitsPerSample); err == nil {
+				info.BitDepth = bits
+			}
+			if info.Gapless == nil {
+				info.Gapless = findITunSMPB(stream.Tags)
+			}
 
 			info.AudioStream = &StreamInfo{
 				Index:      stream.Index,
@@ -611,7 +866,7 @@ func GetMediaInfo(filePath string) (*MediaInfo, error) {
 }
 
 // ExtractAudioStream extracts audio from a video file
-func ExtractAudioStream(videoPath, outputPath string) error {
+func ExtractAudioStream(ctx context.Context, videoPath, outputPath string) error {
 	args := []string{
 		"-y",
 		"-i", videoPath,
@@ -620,7 +875,7 @@ func ExtractAudioStream(videoPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(GetFFmpegPath(), args...)
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -641,7 +896,7 @@ func ExtractVideoStream(videoPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(GetFFmpegPath(), args...)
+	cmd := newCommand(GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -662,7 +917,7 @@ func ConvertToMKV(inputPath, outputPath string) error {
 		outputPath,
 	}
 
-	cmd := exec.Command(GetFFmpegPath(), args...)
+	cmd := newCommand(GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -675,18 +930,29 @@ func ConvertToMKV(inputPath, outputPath string) error {
 
 // DownloadThumbnail downloads thumbnail from URL to local file
 func DownloadThumbnail(url, outputPath string) error {
+	source, contentType, cleanup, err := fetchImage(url)
+	if err != nil {
+		return fmt.Errorf("thumbnail download failed: %w", err)
+	}
+	defer cleanup()
+
+	// A JPEG saved as .jpg needs no conversion
+	if ext := strings.ToLower(filepath.Ext(outputPath)); contentType == "image/jpeg" && (ext == ".jpg" || ext == ".jpeg") {
+		return copyFile(source, outputPath)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	args := []string{
 		"-y",
-		"-i", url,
+		"-i", source,
 		"-vframes", "1",
 		"-f", "image2",
 		outputPath,
 	}
 
-	cmd := exec.CommandContext(ctx, GetFFmpegPath(), args...)
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -739,7 +1005,7 @@ func GetFFprobePath() string {
 
 // CheckFFmpegInstalled verifies FFmpeg is available
 func CheckFFmpegInstalled() error {
-	cmd := exec.Command(GetFFmpegPath(), "-version")
+	cmd := newCommand(GetFFmpegPath(), "-version")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("FFmpeg not found or not executable: %w", err)
 	}
@@ -748,7 +1014,7 @@ func CheckFFmpegInstalled() error {
 
 // CheckFFprobeInstalled verifies FFprobe is available
 func CheckFFprobeInstalled() error {
-	cmd := exec.Command(GetFFprobePath(), "-version")
+	cmd := newCommand(GetFFprobePath(), "-version")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("FFprobe not found or not executable: %w", err)
 	}
@@ -757,7 +1023,7 @@ func CheckFFprobeInstalled() error {
 
 // GetFFmpegVersion returns FFmpeg version string
 func GetFFmpegVersion() (string, error) {
-	cmd := exec.Command(GetFFmpegPath(), "-version")
+	cmd := newCommand(GetFFmpegPath(), "-version")
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
@@ -804,32 +1070,6 @@ func getAppDataDir() string {
 	return filepath.Join(homeDir, ".youflac")
 }
 
-// FormatDuration formats seconds into HH:MM:SS
-func FormatDuration(seconds float64) string {
-	h := int(seconds) / 3600
-	m := (int(seconds) % 3600) / 60
-	s := int(seconds) % 60
-
-	if h > 0 {
-		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
-	}
-	return fmt.Sprintf("%d:%02d", m, s)
-}
-
-// FormatFileSize formats bytes into human readable format
-func FormatFileSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
 // ValidateOutputPath ensures output path is valid and writable
 func ValidateOutputPath(outputPath string) error {
 	dir := filepath.Dir(outputPath)
@@ -1,11 +1,14 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
+
+	"youflac/backend/format"
 )
 
 func TestGetFFmpegPath(t *testing.T) {
@@ -104,53 +107,6 @@ func TestParseFrameRate(t *testing.T) {
 	}
 }
 
-func TestFormatDuration(t *testing.T) {
-	tests := []struct {
-		seconds  float64
-		expected string
-	}{
-		{0, "0:00"},
-		{30, "0:30"},
-		{60, "1:00"},
-		{90, "1:30"},
-		{3600, "1:00:00"},
-		{3661, "1:01:01"},
-		{213, "3:33"},
-	}
-
-	for _, tt := range tests {
-		t.Run(fmt.Sprintf("%.0fs", tt.seconds), func(t *testing.T) {
-			result := FormatDuration(tt.seconds)
-			if result != tt.expected {
-				t.Errorf("FormatDuration(%v) = %q, want %q", tt.seconds, result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestFormatFileSize(t *testing.T) {
-	tests := []struct {
-		bytes    int64
-		expected string
-	}{
-		{0, "0 B"},
-		{100, "100 B"},
-		{1024, "1.0 KB"},
-		{1536, "1.5 KB"},
-		{1048576, "1.0 MB"},
-		{1073741824, "1.0 GB"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.expected, func(t *testing.T) {
-			result := FormatFileSize(tt.bytes)
-			if result != tt.expected {
-				t.Errorf("FormatFileSize(%d) = %q, want %q", tt.bytes, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestValidateOutputPath(t *testing.T) {
 	// Create temp directory for testing
 	tmpDir := t.TempDir()
@@ -421,8 +377,8 @@ func TestMuxVideoWithFLAC(t *testing.T) {
 	fmt.Println()
 	fmt.Println("=== MuxVideoWithFLAC Result ===")
 	fmt.Printf("Output: %s\n", result.OutputPath)
-	fmt.Printf("Duration: %s\n", FormatDuration(result.Duration))
-	fmt.Printf("File Size: %s\n", FormatFileSize(result.FileSize))
+	fmt.Printf("Duration: %s\n", format.Duration(result.Duration))
+	fmt.Printf("File Size: %s\n", format.FileSize(result.FileSize))
 	fmt.Printf("Video Codec: %s\n", result.VideoCodec)
 	fmt.Printf("Audio Codec: %s\n", result.AudioCodec)
 	fmt.Printf("Elapsed: %s\n", result.ElapsedTime)
@@ -472,7 +428,7 @@ func TestExtractAudioStream(t *testing.T) {
 
 	// Extract audio
 	audioPath := filepath.Join(tmpDir, "audio.aac")
-	err := ExtractAudioStream(videoPath, audioPath)
+	err := ExtractAudioStream(context.Background(), videoPath, audioPath)
 	if err != nil {
 		t.Fatalf("ExtractAudioStream failed: %v", err)
 	}
@@ -581,6 +537,49 @@ func TestConvertToMKV(t *testing.T) {
 	t.Logf("Converted to MKV: format=%s", info.Format)
 }
 
+func TestMuxSilenceTrimOptOut(t *testing.T) {
+	if err := CheckFFmpegInstalled(); err != nil {
+		t.Skip("FFmpeg not installed")
+	}
+
+	tmpDir := t.TempDir()
+	videoPath := filepath.Join(tmpDir, "video.mkv")
+	if out, err := exec.Command(GetFFmpegPath(), "-y",
+		"-f", "lavfi", "-i", "testsrc=duration=3:size=320x240:rate=25",
+		"-f", "lavfi", "-i", "sine=frequency=440:duration=3",
+		"-c:v", "libx264", "-c:a", "flac", "-shortest", videoPath).CombinedOutput(); err != nil {
+		t.Fatalf("Could not create test video: %v - %s", err, out)
+	}
+
+	// The FLAC starts with a second of silence the video audio does not have
+	audioPath := filepath.Join(tmpDir, "audio.flac")
+	if out, err := exec.Command(GetFFmpegPath(), "-y",
+		"-f", "lavfi", "-i", "anullsrc=sample_rate=44100:channel_layout=mono",
+		"-f", "lavfi", "-i", "sine=frequency=440:duration=2",
+		"-filter_complex", "[0:a]atrim=duration=1[s];[s][1:a]concat=n=2:v=0:a=1",
+		"-c:a", "flac", audioPath).CombinedOutput(); err != nil {
+		t.Fatalf("Could not create test audio: %v - %s", err, out)
+	}
+
+	result, err := MuxVideoWithFLACOptions(context.Background(), videoPath, audioPath, filepath.Join(tmpDir, "trimmed.mkv"), nil, "", DefaultMuxOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sync.Trimmed < 0.9 || result.Sync.Skipped != 0 {
+		t.Errorf("default sync = %+v, want ~1s trimmed", result.Sync)
+	}
+
+	opts := DefaultMuxOptions()
+	opts.NoSilenceTrim = true
+	result, err = MuxVideoWithFLACOptions(context.Background(), videoPath, audioPath, filepath.Join(tmpDir, "kept.mkv"), nil, "", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sync.Trimmed != 0 || result.Sync.Skipped < 0.9 {
+		t.Errorf("opt-out sync = %+v, want the silence kept", result.Sync)
+	}
+}
+
 // Helper function to run shell commands
 func runCommand(cmd string) error {
 	parts := splitCommand(cmd)
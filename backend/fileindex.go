@@ -2,13 +2,20 @@ package backend
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,31 +25,158 @@ type FileIndexEntry struct {
 	Title     string    `json:"title"`
 	Artist    string    `json:"artist"`
 	Album     string    `json:"album,omitempty"`
+	AlbumID   string    `json:"albumId,omitempty"` // Store album, e.g. "tidal:12345"
 	Duration  float64   `json:"duration,omitempty"`
+	ISRC      string    `json:"isrc,omitempty"`
+	YouTubeID string    `json:"youtubeId,omitempty"` // Video the file was made from
 	Size      int64     `json:"size"`
 	IndexedAt time.Time `json:"indexedAt"`
 }
 
+// indexedExtensions are the media types picked up by ScanDirectory
+var indexedExtensions = map[string]bool{
+	".mkv":  true,
+	".mp4":  true,
+	".flac": true,
+}
+
+// fileIndexFile is the format of fileindex.json
+type fileIndexFile struct {
+	SchemaVersion int              `json:"schemaVersion"` // See migrations.go
+	Entries       []FileIndexEntry `json:"entries"`
+}
+
 // NormalizedKey is used for matching (lowercase, sanitized)
 type NormalizedKey struct {
 	Title  string
 	Artist string
 }
 
-// FileIndex maintains an index of existing files for duplicate detection
+// fileIndexEntries maps normalized title and artist to the entries under them
+type fileIndexEntries map[NormalizedKey][]FileIndexEntry
+
+// fileIndexState is one immutable snapshot of the index: its entries plus
+// lookups by path, YouTube ID and ISRC. Writers publish a new state instead
+// of changing a published one, so readers never lock.
+type fileIndexState struct {
+	entries     fileIndexEntries
+	byPath      map[string]FileIndexEntry
+	byYouTubeID map[string][]string // Video ID -> paths
+	byISRC      map[string][]string // ISRC -> paths
+}
+
+func newFileIndexState() *fileIndexState {
+	return &fileIndexState{
+		entries:     make(fileIndexEntries),
+		byPath:      make(map[string]FileIndexEntry),
+		byYouTubeID: make(map[string][]string),
+		byISRC:      make(map[string][]string),
+	}
+}
+
+// clone copies the maps of s; the slices are shared and replaced, never
+// modified, by add and remove
+func (s *fileIndexState) clone() *fileIndexState {
+	return &fileIndexState{
+		entries:     maps.Clone(s.entries),
+		byPath:      maps.Clone(s.byPath),
+		byYouTubeID: maps.Clone(s.byYouTubeID),
+		byISRC:      maps.Clone(s.byISRC),
+	}
+}
+
+// add indexes entry, replacing any entry for the same path
+func (s *fileIndexState) add(entry FileIndexEntry) {
+	s.remove(entry.Path)
+	key := NormalizeForMatching(entry.Title, entry.Artist)
+	s.entries[key] = append(slices.Clip(s.entries[key]), entry)
+	s.byPath[entry.Path] = entry
+	if entry.YouTubeID != "" {
+		s.byYouTubeID[entry.YouTubeID] = append(slices.Clip(s.byYouTubeID[entry.YouTubeID]), entry.Path)
+	}
+	if entry.ISRC != "" {
+		s.byISRC[entry.ISRC] = append(slices.Clip(s.byISRC[entry.ISRC]), entry.Path)
+	}
+}
+
+// remove drops the entry for path and reports whether there was one
+func (s *fileIndexState) remove(path string) bool {
+	entry, ok := s.byPath[path]
+	if !ok {
+		return false
+	}
+	delete(s.byPath, path)
+	key := NormalizeForMatching(entry.Title, entry.Artist)
+	if kept := slices.DeleteFunc(slices.Clone(s.entries[key]), func(e FileIndexEntry) bool { return e.Path == path }); len(kept) > 0 {
+		s.entries[key] = kept
+	} else {
+		delete(s.entries, key)
+	}
+	removePathFrom(s.byYouTubeID, entry.YouTubeID, path)
+	removePathFrom(s.byISRC, entry.ISRC, path)
+	return true
+}
+
+// removePathFrom drops path from the paths listed under key in lookup
+func removePathFrom(lookup map[string][]string, key, path string) {
+	if key == "" {
+		return
+	}
+	if kept := slices.DeleteFunc(slices.Clone(lookup[key]), func(p string) bool { return p == path }); len(kept) > 0 {
+		lookup[key] = kept
+	} else {
+		delete(lookup, key)
+	}
+}
+
+// indexDurationTolerance is how far in seconds an indexed file's length may
+// be from the expected one and still count as the same recording; muxed
+// files, trims and re-uploads differ by a few seconds, live and extended
+// versions by far more
+const indexDurationTolerance = 10.0
+
+// fileIndexSaveDelay is how long ScheduleSave waits for more changes before writing
+const fileIndexSaveDelay = 2 * time.Second
+
+// FileIndex maintains an index of existing files for duplicate detection.
+// Lookups read the current snapshot without locking; AddEntry and
+// ScanDirectory build a new snapshot under writeMu (copy-on-write), so a
+// startup scan never blocks or loses entries added by download workers.
 type FileIndex struct {
-	entries   map[NormalizedKey][]FileIndexEntry
-	mutex     sync.RWMutex
+	state     atomic.Pointer[fileIndexState]
+	writeMu   sync.Mutex    // Serializes writers
+	version   atomic.Uint64 // Bumped on every change
+	saveMu    sync.Mutex    // Guards saved, saveErr, saveTimer and the index file
+	saved     uint64        // Version last written to disk
+	saveErr   error         // Set when the index file is from a newer version
+	saveTimer *time.Timer
 	indexPath string
-	dirty     bool
 }
 
 // NewFileIndex creates a new file index
 func NewFileIndex(dataPath string) *FileIndex {
-	return &FileIndex{
-		entries:   make(map[NormalizedKey][]FileIndexEntry),
+	fi := &FileIndex{
 		indexPath: filepath.Join(dataPath, "fileindex.json"),
 	}
+	fi.state.Store(newFileIndexState())
+	return fi
+}
+
+// snapshot returns the current entries. The map and its slices must not be modified.
+func (fi *FileIndex) snapshot() fileIndexEntries {
+	return fi.state.Load().entries
+}
+
+// update applies fn to a copy of the current state and publishes the result.
+// fn changes the copy with its add and remove methods.
+func (fi *FileIndex) update(fn func(next *fileIndexState)) {
+	fi.writeMu.Lock()
+	defer fi.writeMu.Unlock()
+
+	next := fi.state.Load().clone()
+	fn(next)
+	fi.state.Store(next)
+	fi.version.Add(1)
 }
 
 // NormalizeForMatching creates a normalized key for matching
@@ -95,33 +229,56 @@ func normalizeString(s string) string {
 	return strings.TrimSpace(s)
 }
 
-// ScanDirectory scans a directory and indexes all MKV files
+// ScanDirectory scans a directory and indexes all MKV/MP4/FLAC files.
+// Files are probed without holding any lock; the results then replace the
+// entries previously indexed under dir. Entries added while the scan was
+// running are kept.
 func (fi *FileIndex) ScanDirectory(dir string) error {
-	fi.mutex.Lock()
-	defer fi.mutex.Unlock()
+	scanStart := time.Now()
+	var scanned []FileIndexEntry
 
 	// Walk the directory recursively
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".mkv" && ext != ".mp4" {
+		if !indexedExtensions[strings.ToLower(filepath.Ext(path))] {
 			return nil
 		}
 
-		entry := fi.extractMetadataFromFile(path)
-		if entry != nil {
-			key := NormalizeForMatching(entry.Title, entry.Artist)
-			fi.entries[key] = append(fi.entries[key], *entry)
-			fi.dirty = true
+		if entry := fi.extractMetadataFromFile(path); entry != nil {
+			scanned = append(scanned, *entry)
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(scanned))
+	for _, entry := range scanned {
+		seen[entry.Path] = true
+	}
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+
+	fi.update(func(next *fileIndexState) {
+		// Drop files under dir that are gone; the scan replaces the others
+		for path, e := range next.byPath {
+			if !seen[path] && strings.HasPrefix(path, prefix) && e.IndexedAt.Before(scanStart) {
+				next.remove(path)
+			}
+		}
+		for _, entry := range scanned {
+			next.add(entry)
+		}
+	})
+	return nil
 }
 
-// extractMetadataFromFile extracts title/artist from MKV file
+// extractMetadataFromFile reads title, artist, album, ISRC and duration from
+// the file's embedded tags, so files renamed by other tools are still
+// recognised; the file name is only a fallback
 func (fi *FileIndex) extractMetadataFromFile(path string) *FileIndexEntry {
 	entry := &FileIndexEntry{
 		Path:      path,
@@ -134,11 +291,23 @@ func (fi *FileIndex) extractMetadataFromFile(path string) *FileIndexEntry {
 	}
 
 	// Try to extract embedded metadata using ffprobe
-	metadata := extractMKVTags(path)
+	metadata, duration := probeMediaTags(path)
+	entry.Duration = duration
 	if metadata != nil {
 		entry.Title = metadata["title"]
-		entry.Artist = metadata["artist"]
+		entry.Artist = cmp.Or(metadata["artist"], metadata["album_artist"], metadata["albumartist"])
 		entry.Album = metadata["album"]
+		if id := metadata[strings.ToLower(AlbumIDTag)]; id != "" {
+			entry.AlbumID = tidalAlbumPrefix + id
+		}
+		entry.ISRC = metadata["isrc"]
+		if entry.ISRC == "" {
+			entry.ISRC = metadata["tsrc"] // ID3 frame name used by some taggers
+		}
+		if entry.ISRC != "" {
+			entry.ISRC = normalizeISRC(entry.ISRC)
+		}
+		entry.YouTubeID = youtubeIDFromTags(metadata)
 	}
 
 	// Fallback: parse from filename using naming patterns
@@ -162,6 +331,14 @@ func (fi *FileIndex) extractMetadataFromFile(path string) *FileIndexEntry {
 
 // extractMKVTags uses ffprobe to extract embedded tags
 func extractMKVTags(path string) map[string]string {
+	tags, _ := probeMediaTags(path)
+	return tags
+}
+
+// probeMediaTags uses ffprobe to read a file's container tags, with lower
+// case keys, and its duration in seconds (0 if unknown). tags is nil when
+// the file can't be probed.
+func probeMediaTags(path string) (tags map[string]string, duration float64) {
 	ffprobePath := GetFFprobePath()
 	args := []string{
 		"-v", "quiet",
@@ -170,22 +347,23 @@ func extractMKVTags(path string) map[string]string {
 		path,
 	}
 
-	cmd := exec.Command(ffprobePath, args...)
+	cmd := newCommand(ffprobePath, args...)
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
 	if err := cmd.Run(); err != nil {
-		return nil
+		return nil, 0
 	}
 
 	var probeData struct {
 		Format struct {
-			Tags map[string]string `json:"tags"`
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
 		} `json:"format"`
 	}
 
 	if err := json.Unmarshal(stdout.Bytes(), &probeData); err != nil {
-		return nil
+		return nil, 0
 	}
 
 	// Normalize tag keys to lowercase
@@ -193,7 +371,25 @@ func extractMKVTags(path string) map[string]string {
 	for k, v := range probeData.Format.Tags {
 		result[strings.ToLower(k)] = v
 	}
-	return result
+	if d, err := strconv.ParseFloat(probeData.Format.Duration, 64); err == nil && d > 0 {
+		duration = d
+	}
+	return result, duration
+}
+
+// youtubeIDFromTags returns the YouTube video ID in a file's tags (lower
+// case keys): YouFlac's YOUTUBE_ID, else the video URL yt-dlp and other
+// downloaders store as purl or comment
+func youtubeIDFromTags(tags map[string]string) string {
+	if id := strings.TrimSpace(tags[strings.ToLower(YouTubeIDTag)]); id != "" {
+		return id
+	}
+	for _, key := range []string{"purl", "comment"} {
+		if id, err := ParseYouTubeURL(strings.TrimSpace(tags[key])); err == nil {
+			return id
+		}
+	}
+	return ""
 }
 
 // ParseFilename extracts title and artist from filename
@@ -221,19 +417,29 @@ func ParseFilename(path string) (title, artist string) {
 	return name, ""
 }
 
-// FindMatch looks for an existing file matching title + artist
-func (fi *FileIndex) FindMatch(title, artist string) *FileIndexEntry {
-	fi.mutex.RLock()
-	defer fi.mutex.RUnlock()
+// FindMatch looks for an existing file matching title + artist. duration is
+// the expected length in seconds, 0 if unknown; files of a known length
+// further than indexDurationTolerance from it are another version and don't
+// match.
+func (fi *FileIndex) FindMatch(title, artist string, duration float64) *FileIndexEntry {
+	return fi.findMatch(IndexScope{}, title, artist, duration)
+}
 
+func (fi *FileIndex) findMatch(scope IndexScope, title, artist string, duration float64) *FileIndexEntry {
 	key := NormalizeForMatching(title, artist)
-	entries, exists := fi.entries[key]
+	entries, exists := fi.snapshot()[key]
 	if !exists || len(entries) == 0 {
 		return nil
 	}
 
 	// Verify file still exists
 	for _, entry := range entries {
+		if duration > 0 && entry.Duration > 0 && math.Abs(entry.Duration-duration) > indexDurationTolerance {
+			continue
+		}
+		if !scope.contains(entry.Path) {
+			continue
+		}
 		if _, err := os.Stat(entry.Path); err == nil {
 			return &entry
 		}
@@ -242,32 +448,95 @@ func (fi *FileIndex) FindMatch(title, artist string) *FileIndexEntry {
 	return nil
 }
 
-// AddEntry adds a new entry to the index
+// FindByYouTubeID looks for an existing file made from the YouTube video
+// videoID
+func (fi *FileIndex) FindByYouTubeID(videoID string) *FileIndexEntry {
+	return fi.findByYouTubeID(IndexScope{}, videoID)
+}
+
+func (fi *FileIndex) findByYouTubeID(scope IndexScope, videoID string) *FileIndexEntry {
+	if videoID == "" {
+		return nil
+	}
+	state := fi.state.Load()
+	return state.findPath(state.byYouTubeID[videoID], scope)
+}
+
+// findPath returns the entry of the first of paths in scope whose file
+// still exists
+func (s *fileIndexState) findPath(paths []string, scope IndexScope) *FileIndexEntry {
+	for _, path := range paths {
+		if !scope.contains(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			entry := s.byPath[path]
+			return &entry
+		}
+	}
+	return nil
+}
+
+// FindVideo looks for an existing file of a YouTube video in scope: by its
+// ID, which catches files whose video was re-titled, then by title, artist
+// and duration as FindMatch does. videoID may be "".
+func (fi *FileIndex) FindVideo(scope IndexScope, videoID, title, artist string, duration float64) *FileIndexEntry {
+	if entry := fi.findByYouTubeID(scope, videoID); entry != nil {
+		return entry
+	}
+	return fi.findMatch(scope, title, artist, duration)
+}
+
+// FindByISRC looks for an existing file with the given ISRC
+func (fi *FileIndex) FindByISRC(isrc string) *FileIndexEntry {
+	if isrc == "" {
+		return nil
+	}
+	isrc = normalizeISRC(isrc)
+
+	state := fi.state.Load()
+	return state.findPath(state.byISRC[isrc], IndexScope{})
+}
+
+// AddEntry adds a new entry to the index, replacing any entry for the same path
 func (fi *FileIndex) AddEntry(entry FileIndexEntry) {
-	fi.mutex.Lock()
-	defer fi.mutex.Unlock()
+	if entry.ISRC != "" {
+		entry.ISRC = normalizeISRC(entry.ISRC)
+	}
+	fi.update(func(next *fileIndexState) {
+		next.add(entry)
+	})
+}
 
-	key := NormalizeForMatching(entry.Title, entry.Artist)
-	fi.entries[key] = append(fi.entries[key], entry)
-	fi.dirty = true
+// RemovePath drops the entry for path and reports whether there was one
+func (fi *FileIndex) RemovePath(path string) bool {
+	removed := false
+	fi.update(func(next *fileIndexState) {
+		removed = next.remove(path)
+	})
+	return removed
 }
 
-// Save persists the index to disk
+// Save persists the index to disk if it changed since the last save
 func (fi *FileIndex) Save() error {
-	fi.mutex.Lock()
-	defer fi.mutex.Unlock()
+	fi.saveMu.Lock()
+	defer fi.saveMu.Unlock()
+	if fi.saveErr != nil {
+		return fmt.Errorf("file index not saved: %w", fi.saveErr)
+	}
 
-	if !fi.dirty {
+	version := fi.version.Load()
+	if version == fi.saved {
 		return nil
 	}
 
 	// Convert map to slice for JSON
 	var allEntries []FileIndexEntry
-	for _, entries := range fi.entries {
+	for _, entries := range fi.snapshot() {
 		allEntries = append(allEntries, entries...)
 	}
 
-	data, err := json.MarshalIndent(allEntries, "", "  ")
+	data, err := json.MarshalIndent(fileIndexFile{SchemaVersion: fileIndexSchema.Current, Entries: allEntries}, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -276,12 +545,49 @@ func (fi *FileIndex) Save() error {
 		return err
 	}
 
-	fi.dirty = false
-	return os.WriteFile(fi.indexPath, data, 0644)
+	if err := os.WriteFile(fi.indexPath, data, 0644); err != nil {
+		return err
+	}
+	fi.saved = version
+	return nil
 }
 
-// Load loads the index from disk
+// ScheduleSave saves the index once no further changes arrive for
+// fileIndexSaveDelay, so a burst of completed downloads causes a single write
+func (fi *FileIndex) ScheduleSave() {
+	fi.saveMu.Lock()
+	defer fi.saveMu.Unlock()
+
+	if fi.saveTimer != nil {
+		fi.saveTimer.Reset(fileIndexSaveDelay)
+		return
+	}
+	fi.saveTimer = time.AfterFunc(fileIndexSaveDelay, func() {
+		if err := fi.Save(); err != nil {
+			slog.Warn("failed to save file index", "err", err)
+		}
+	})
+}
+
+// Flush cancels a pending scheduled save and writes any changes now
+func (fi *FileIndex) Flush() error {
+	fi.saveMu.Lock()
+	if fi.saveTimer != nil {
+		fi.saveTimer.Stop()
+	}
+	fi.saveMu.Unlock()
+	return fi.Save()
+}
+
+// Load loads the index from disk. An index from a newer version is not
+// loaded and later saves fail instead of overwriting it.
 func (fi *FileIndex) Load() error {
+	if err := migrateForLoad(fi.indexPath, fileIndexSchema); err != nil {
+		fi.saveMu.Lock()
+		fi.saveErr = err
+		fi.saveMu.Unlock()
+		return err
+	}
 	data, err := os.ReadFile(fi.indexPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -290,31 +596,23 @@ func (fi *FileIndex) Load() error {
 		return err
 	}
 
-	var entries []FileIndexEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
+	var file fileIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
 		return err
 	}
 
-	fi.mutex.Lock()
-	defer fi.mutex.Unlock()
-
-	fi.entries = make(map[NormalizedKey][]FileIndexEntry)
-	for _, entry := range entries {
-		key := NormalizeForMatching(entry.Title, entry.Artist)
-		fi.entries[key] = append(fi.entries[key], entry)
+	loaded := newFileIndexState()
+	for _, entry := range file.Entries {
+		loaded.add(entry)
 	}
 
+	fi.writeMu.Lock()
+	fi.state.Store(loaded)
+	fi.writeMu.Unlock()
 	return nil
 }
 
 // Count returns the number of indexed files
 func (fi *FileIndex) Count() int {
-	fi.mutex.RLock()
-	defer fi.mutex.RUnlock()
-
-	count := 0
-	for _, entries := range fi.entries {
-		count += len(entries)
-	}
-	return count
+	return len(fi.state.Load().byPath)
 }
@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileIndex_RescanReplacesEntries(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "Artist - Kept.flac")
+	gone := filepath.Join(dir, "Artist - Gone.flac")
+	writeTestFile(t, kept, 10)
+	writeTestFile(t, gone, 10)
+
+	fi := NewFileIndex(t.TempDir())
+	if err := fi.ScanDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Count() != 2 {
+		t.Fatalf("Count = %d after first scan, want 2", fi.Count())
+	}
+
+	os.Remove(gone)
+	outside := FileIndexEntry{Path: "/elsewhere/Other.mkv", Title: "Other", Artist: "Artist", IndexedAt: time.Now().Add(-time.Hour)}
+	fi.AddEntry(outside)
+	if err := fi.ScanDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Count() != 2 || fi.FindMatch("Gone", "Artist", 0) != nil || len(fi.snapshot()[NormalizeForMatching("Other", "Artist")]) != 1 {
+		t.Errorf("rescan should drop deleted files, keep others and not duplicate: count %d", fi.Count())
+	}
+}
+
+func TestFileIndex_ScanReadsTags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe is a shell script")
+	}
+
+	// Fake ffprobe: tags and duration of a file renamed by another tool
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncat <<'EOF'\n" +
+		`{"format": {"duration": "243.520000", "tags": {"TITLE": "Real Title", "ALBUM_ARTIST": "Real Artist", "ISRC": "GB-ARL-93-00135"}}}` +
+		"\nEOF\n"
+	if err := os.WriteFile(filepath.Join(bin, "ffprobe"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "track01.flac")
+	writeTestFile(t, path, 10)
+	fi := NewFileIndex(t.TempDir())
+	if err := fi.ScanDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got := fi.FindMatch("Real Title", "Real Artist", 0)
+	if got == nil || got.Path != path || got.Duration != 243.52 || got.ISRC != "GBARL9300135" {
+		t.Fatalf("FindMatch = %+v, want the renamed file with its tags", got)
+	}
+	if fi.FindMatch("track01", "", 0) != nil {
+		t.Error("file indexed under its file name despite its tags")
+	}
+
+	// A version of a different length isn't the same recording
+	if fi.FindMatch("Real Title", "Real Artist", 246) == nil {
+		t.Error("a few seconds' difference should still match")
+	}
+	if fi.FindMatch("Real Title", "Real Artist", 420) != nil {
+		t.Error("a 7-minute version matched a 4-minute file")
+	}
+
+	// Entries of unknown length match any duration
+	fi.AddEntry(FileIndexEntry{Path: path, Title: "Real Title", Artist: "Real Artist"})
+	if fi.FindMatch("Real Title", "Real Artist", 420) == nil {
+		t.Error("entry without a duration should match")
+	}
+}
+
+func TestFileIndex_FindVideo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Artist - Song.mkv")
+	writeTestFile(t, path, 10)
+
+	fi := NewFileIndex(t.TempDir())
+	fi.AddEntry(FileIndexEntry{Path: path, Title: "Song", Artist: "Artist", Duration: 200, YouTubeID: "dQw4w9WgXcQ"})
+
+	// The ID matches a video that was re-titled since, of any length
+	if got := fi.FindVideo(IndexScope{}, "dQw4w9WgXcQ", "Song (New Title)", "Artist", 260); got == nil || got.Path != path {
+		t.Errorf("FindVideo by ID = %+v", got)
+	}
+	// Another video falls back to title and artist
+	if got := fi.FindVideo(IndexScope{}, "aaaaaaaaaaa", "Song", "Artist", 201); got == nil || got.Path != path {
+		t.Errorf("FindVideo by title = %+v", got)
+	}
+	if fi.FindVideo(IndexScope{}, "aaaaaaaaaaa", "Other", "Artist", 0) != nil || fi.FindByYouTubeID("") != nil {
+		t.Error("unrelated video matched")
+	}
+}
+
+func TestFileIndex_IDLookupsFollowChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Artist - Song.mkv")
+	writeTestFile(t, path, 10)
+
+	dataDir := t.TempDir()
+	fi := NewFileIndex(dataDir)
+	fi.AddEntry(FileIndexEntry{Path: path, Title: "Song", Artist: "Artist", ISRC: "GB-ARL-93-00135", YouTubeID: "dQw4w9WgXcQ"})
+	if got := fi.FindByISRC("GBARL9300135"); got == nil || got.Path != path {
+		t.Errorf("FindByISRC = %+v", got)
+	}
+
+	// Re-adding the path under a new video drops the old ID
+	fi.AddEntry(FileIndexEntry{Path: path, Title: "Song", Artist: "Artist", YouTubeID: "aaaaaaaaaaa"})
+	if fi.FindByYouTubeID("dQw4w9WgXcQ") != nil || fi.FindByISRC("GBARL9300135") != nil {
+		t.Error("lookups kept the replaced entry")
+	}
+	if got := fi.FindByYouTubeID("aaaaaaaaaaa"); got == nil || got.Path != path {
+		t.Errorf("FindByYouTubeID = %+v", got)
+	}
+
+	fi.ScheduleSave()
+	if err := fi.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewFileIndex(dataDir)
+	if err := loaded.Load(); err != nil || loaded.FindByYouTubeID("aaaaaaaaaaa") == nil {
+		t.Errorf("reloaded index doesn't find the video, err %v", err)
+	}
+
+	if !fi.RemovePath(path) || fi.FindByYouTubeID("aaaaaaaaaaa") != nil || fi.Count() != 0 {
+		t.Error("RemovePath left the entry")
+	}
+}
+
+func TestYouTubeIDFromTags(t *testing.T) {
+	tests := []struct {
+		tags map[string]string
+		want string
+	}{
+		{map[string]string{"youtube_id": "dQw4w9WgXcQ", "purl": "https://www.youtube.com/watch?v=aaaaaaaaaaa"}, "dQw4w9WgXcQ"},
+		{map[string]string{"purl": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, "dQw4w9WgXcQ"},
+		{map[string]string{"comment": "https://youtu.be/dQw4w9WgXcQ"}, "dQw4w9WgXcQ"},
+		{map[string]string{"comment": "Ripped from vinyl"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := youtubeIDFromTags(tt.tags); got != tt.want {
+			t.Errorf("youtubeIDFromTags(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestFileIndex_AddEntryReplacesPath(t *testing.T) {
+	fi := NewFileIndex(t.TempDir())
+	fi.AddEntry(FileIndexEntry{Path: "/music/a.mkv", Title: "Song", Artist: "Artist", Size: 1})
+	fi.AddEntry(FileIndexEntry{Path: "/music/a.mkv", Title: "Song", Artist: "Artist", Size: 2})
+	if fi.Count() != 1 {
+		t.Errorf("Count = %d, want 1", fi.Count())
+	}
+}
+
+func TestFileIndex_ConcurrentScanAndAdd(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"A - One.flac", "A - Two.flac", "A - Three.flac"} {
+		writeTestFile(t, filepath.Join(dir, name), 10)
+	}
+	fi := NewFileIndex(t.TempDir())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		fi.ScanDirectory(dir)
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			fi.AddEntry(FileIndexEntry{Path: filepath.Join("/other", string(rune('a'+i%26)), "x.mkv"), Title: "T", Artist: "B", IndexedAt: time.Now()})
+			fi.FindMatch("One", "A", 0)
+		}
+	}()
+	wg.Wait()
+
+	// 3 scanned files plus 26 distinct added paths
+	if fi.Count() != 29 || fi.FindMatch("One", "A", 0) == nil {
+		t.Errorf("entries lost during concurrent scan: count %d", fi.Count())
+	}
+}
+
+func TestFileIndex_SaveOnlyWhenChanged(t *testing.T) {
+	dataDir := t.TempDir()
+	fi := NewFileIndex(dataDir)
+	indexPath := filepath.Join(dataDir, "fileindex.json")
+
+	if err := fi.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		t.Fatal("unchanged index was written")
+	}
+
+	fi.AddEntry(FileIndexEntry{Path: "/music/a.mkv", Title: "Song", Artist: "Artist"})
+	fi.ScheduleSave()
+	if err := fi.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewFileIndex(dataDir)
+	if err := loaded.Load(); err != nil || loaded.Count() != 1 {
+		t.Errorf("reloaded index has %d entries, err %v", loaded.Count(), err)
+	}
+}
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// FLAC encoding
+// =============================================================================
+
+// FLAC is only re-encoded when a stream copy cannot do: lossy or non-FLAC
+// sources, and sample-accurate trims. The compression level comes from
+// Config.FLACCompressionLevel; with Config.FLACVerify the result is decoded
+// again and compared sample for sample against the source.
+
+// DefaultFLACCompressionLevel is the default FLAC compression level (0-8)
+const DefaultFLACCompressionLevel = 8
+
+var (
+	flacEncodeMutex sync.RWMutex
+	flacLevel       = DefaultFLACCompressionLevel
+	flacVerify      = true
+)
+
+// ConfigureFLACEncoding applies Config.FLACCompressionLevel and Config.FLACVerify
+func ConfigureFLACEncoding(config *Config) {
+	flacEncodeMutex.Lock()
+	defer flacEncodeMutex.Unlock()
+	flacLevel = clampInt(config.FLACCompressionLevel, 0, 8)
+	flacVerify = config.FLACVerify
+}
+
+// flacEncodeArgs returns the ffmpeg output arguments for a FLAC encode
+func flacEncodeArgs() []string {
+	flacEncodeMutex.RLock()
+	defer flacEncodeMutex.RUnlock()
+	return []string{"-c:a", "flac", "-compression_level", strconv.Itoa(flacLevel)}
+}
+
+// flacVerifyEnabled reports whether re-encoded FLAC files are verified
+func flacVerifyEnabled() bool {
+	flacEncodeMutex.RLock()
+	defer flacEncodeMutex.RUnlock()
+	return flacVerify
+}
+
+// isLosslessCodec reports whether decoding codec yields the exact samples
+// that were encoded, so a re-encode to FLAC can be checked bit for bit
+func isLosslessCodec(codec string) bool {
+	codec = strings.ToLower(codec)
+	return codec == "flac" || codec == "alac" || codec == "wavpack" || codec == "tta" || strings.HasPrefix(codec, "pcm_")
+}
+
+// decodedAudioMD5 returns the MD5 of the first audio stream of path decoded
+// to 32-bit PCM, read with inputArgs and run through filter ("" = none)
+func decodedAudioMD5(ctx context.Context, path string, inputArgs []string, filter string) (string, error) {
+	args := append([]string{"-v", "error"}, inputArgs...)
+	args = append(args, "-i", path, "-map", "0:a:0")
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args, "-c:a", "pcm_s32le", "-f", "md5", "-")
+
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %v - %s", path, err, stderr.String())
+	}
+	sum, ok := strings.CutPrefix(strings.TrimSpace(stdout.String()), "MD5=")
+	if !ok || sum == "" {
+		return "", fmt.Errorf("unexpected md5 output for %s: %q", path, stdout.String())
+	}
+	return sum, nil
+}
+
+// verifyFLACEncode checks that outputPath decodes to exactly the samples the
+// encode read from sourcePath (same input args and filter). It is a no-op
+// when verification is disabled.
+func verifyFLACEncode(ctx context.Context, sourcePath string, inputArgs []string, filter, outputPath string) error {
+	if !flacVerifyEnabled() {
+		return nil
+	}
+	want, err := decodedAudioMD5(ctx, sourcePath, inputArgs, filter)
+	if err != nil {
+		return fmt.Errorf("failed to verify FLAC: %w", err)
+	}
+	got, err := decodedAudioMD5(ctx, outputPath, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to verify FLAC: %w", err)
+	}
+	if got != want {
+		os.Remove(outputPath)
+		return fmt.Errorf("FLAC verification failed: decoded audio differs from %s", sourcePath)
+	}
+	return nil
+}
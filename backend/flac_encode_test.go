@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfigureFLACEncoding(t *testing.T) {
+	defer ConfigureFLACEncoding(&defaultConfig)
+
+	ConfigureFLACEncoding(&Config{FLACCompressionLevel: 3})
+	if args := flacEncodeArgs(); !slices.Equal(args, []string{"-c:a", "flac", "-compression_level", "3"}) {
+		t.Errorf("args = %v", args)
+	}
+	if flacVerifyEnabled() {
+		t.Error("verification should be off")
+	}
+
+	ConfigureFLACEncoding(&Config{FLACCompressionLevel: 12, FLACVerify: true})
+	if args := flacEncodeArgs(); args[3] != "8" || !flacVerifyEnabled() {
+		t.Errorf("args = %v, verify = %v", args, flacVerifyEnabled())
+	}
+
+	for codec, want := range map[string]bool{"flac": true, "ALAC": true, "pcm_s24le": true, "aac": false, "opus": false} {
+		if got := isLosslessCodec(codec); got != want {
+			t.Errorf("isLosslessCodec(%q) = %v", codec, got)
+		}
+	}
+}
+
+func TestTrimAudioStartVerified(t *testing.T) {
+	if err := CheckFFmpegInstalled(); err != nil {
+		t.Skip("FFmpeg not installed")
+	}
+	ConfigureFLACEncoding(&Config{FLACCompressionLevel: 0, FLACVerify: true})
+	defer ConfigureFLACEncoding(&defaultConfig)
+
+	tmpDir := t.TempDir()
+	audioPath := filepath.Join(tmpDir, "audio.flac")
+	audioCmd := fmt.Sprintf("%s -f lavfi -i sine=frequency=440:duration=2 -c:a flac -y %s", GetFFmpegPath(), audioPath)
+	if err := runCommand(audioCmd); err != nil {
+		t.Fatalf("Could not create test audio: %v", err)
+	}
+
+	trimmed := filepath.Join(tmpDir, "trimmed.flac")
+	if err := TrimAudioStart(context.Background(), audioPath, trimmed, 0.5); err != nil {
+		t.Fatalf("TrimAudioStart: %v", err)
+	}
+	info, err := GetMediaInfo(trimmed)
+	if err != nil || info.Duration > 1.6 {
+		t.Errorf("trimmed info = %+v, %v", info, err)
+	}
+
+	// A zero trim is a plain copy
+	copied := filepath.Join(tmpDir, "copied.flac")
+	if err := TrimAudioStart(context.Background(), audioPath, copied, 0); err != nil {
+		t.Fatal(err)
+	}
+	src, _ := os.ReadFile(audioPath)
+	dst, _ := os.ReadFile(copied)
+	if string(src) != string(dst) {
+		t.Error("zero trim should copy the file unchanged")
+	}
+
+	// Verification catches a different output
+	if err := verifyFLACEncode(context.Background(), audioPath, nil, "", trimmed); err == nil {
+		t.Error("verification should fail for different audio")
+	}
+	if fileExists(trimmed) {
+		t.Error("failed output should be removed")
+	}
+}
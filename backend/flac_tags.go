@@ -0,0 +1,460 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Native FLAC metadata editing. Vorbis comments and PICTURE blocks are rewritten
+// in place when they fit in the existing metadata area (using the PADDING
+// block as slack); otherwise only the metadata is rewritten and the audio
+// frames are copied byte-for-byte. Audio data is never re-encoded.
+
+// FLAC metadata block types
+const (
+	flacBlockStreamInfo    = 0
+	flacBlockPadding       = 1
+	flacBlockVorbisComment = 4
+	flacBlockPicture       = 6
+)
+
+// flacDefaultPadding is the padding reserved when the metadata area has to grow
+const flacDefaultPadding = 8192
+
+// flacMaxBlockSize is the largest metadata block the 24-bit length field can describe
+const flacMaxBlockSize = 1<<24 - 1
+
+// FLACPictureFrontCover is the PICTURE type for the front cover
+const FLACPictureFrontCover = 3
+
+// VorbisComment is a single KEY=value entry. Keys may repeat for multi-value tags.
+type VorbisComment struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// FLACPicture is a PICTURE metadata block
+type FLACPicture struct {
+	Type        uint32 `json:"type"`
+	MIMEType    string `json:"mimeType"`
+	Description string `json:"description,omitempty"`
+	Width       uint32 `json:"width"`
+	Height      uint32 `json:"height"`
+	Depth       uint32 `json:"depth"`
+	Colors      uint32 `json:"colors"`
+	Data        []byte `json:"-"`
+}
+
+// FLACMetadata holds the editable metadata of a FLAC file
+type FLACMetadata struct {
+	Vendor   string          `json:"vendor"`
+	Comments []VorbisComment `json:"comments"`
+	Pictures []FLACPicture   `json:"pictures,omitempty"`
+}
+
+type flacBlock struct {
+	Type byte
+	Data []byte
+}
+
+// flacLayout describes where the metadata blocks live in a file
+type flacLayout struct {
+	prefixLen   int64 // bytes before the "fLaC" marker (e.g. an ID3v2 tag)
+	audioOffset int64 // first byte after the last metadata block
+	blocks      []flacBlock
+}
+
+// Get returns all values for a key (case-insensitive)
+func (m *FLACMetadata) Get(key string) []string {
+	var values []string
+	for _, c := range m.Comments {
+		if strings.EqualFold(c.Key, key) {
+			values = append(values, c.Value)
+		}
+	}
+	return values
+}
+
+// Set replaces all values for a key. Passing no values removes the key.
+func (m *FLACMetadata) Set(key string, values ...string) {
+	key = strings.ToUpper(key)
+	kept := m.Comments[:0]
+	for _, c := range m.Comments {
+		if !strings.EqualFold(c.Key, key) {
+			kept = append(kept, c)
+		}
+	}
+	m.Comments = kept
+	for _, v := range values {
+		m.Comments = append(m.Comments, VorbisComment{Key: key, Value: v})
+	}
+}
+
+// SetPicture replaces any existing picture of the same type
+func (m *FLACMetadata) SetPicture(pic FLACPicture) {
+	kept := m.Pictures[:0]
+	for _, p := range m.Pictures {
+		if p.Type != pic.Type {
+			kept = append(kept, p)
+		}
+	}
+	m.Pictures = append(kept, pic)
+}
+
+// ReadFLACMetadata reads the Vorbis comments and pictures of a FLAC file
+func ReadFLACMetadata(path string) (*FLACMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC: %w", err)
+	}
+	defer f.Close()
+
+	layout, err := readFLACLayout(f)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &FLACMetadata{}
+	for _, b := range layout.blocks {
+		switch b.Type {
+		case flacBlockVorbisComment:
+			if err := parseVorbisComment(b.Data, meta); err != nil {
+				return nil, err
+			}
+		case flacBlockPicture:
+			pic, err := parseFLACPicture(b.Data)
+			if err != nil {
+				return nil, err
+			}
+			meta.Pictures = append(meta.Pictures, *pic)
+		}
+	}
+	return meta, nil
+}
+
+// WriteFLACMetadata replaces the Vorbis comment and PICTURE blocks of a FLAC file
+func WriteFLACMetadata(path string, meta *FLACMetadata) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open FLAC: %w", err)
+	}
+
+	layout, err := readFLACLayout(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Keep STREAMINFO first and any other blocks (seektable, cuesheet, application)
+	var blocks []flacBlock
+	for _, b := range layout.blocks {
+		switch b.Type {
+		case flacBlockVorbisComment, flacBlockPicture, flacBlockPadding:
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	blocks = append(blocks, flacBlock{Type: flacBlockVorbisComment, Data: encodeVorbisComment(meta)})
+	for _, pic := range meta.Pictures {
+		data := encodeFLACPicture(&pic)
+		if len(data) > flacMaxBlockSize {
+			f.Close()
+			return fmt.Errorf("picture too large for FLAC metadata block: %d bytes", len(data))
+		}
+		blocks = append(blocks, flacBlock{Type: flacBlockPicture, Data: data})
+	}
+
+	needed := int64(0)
+	for _, b := range blocks {
+		needed += 4 + int64(len(b.Data))
+	}
+	available := layout.audioOffset - layout.prefixLen - 4
+
+	// Fits in the existing metadata area: rewrite in place, adjusting padding
+	if needed == available || needed+4 <= available {
+		if needed < available {
+			blocks = append(blocks, flacBlock{Type: flacBlockPadding, Data: make([]byte, available-needed-4)})
+		}
+		_, err := f.WriteAt(encodeFLACBlocks(blocks), layout.prefixLen+4)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write FLAC metadata: %w", err)
+		}
+		return nil
+	}
+
+	// Metadata grew: write a new file with fresh padding and copy the audio frames
+	blocks = append(blocks, flacBlock{Type: flacBlockPadding, Data: make([]byte, flacDefaultPadding)})
+	return rewriteFLAC(f, path, layout, blocks)
+}
+
+// rewriteFLAC writes prefix + new metadata + original audio to a temp file and
+// swaps it in. The source file is closed before the rename (required on Windows).
+func rewriteFLAC(f *os.File, path string, layout *flacLayout, blocks []flacBlock) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".flactags-*.tmp")
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	fail := func(err error) error {
+		f.Close()
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, 0, layout.prefixLen)); err != nil {
+		return fail(fmt.Errorf("failed to copy FLAC prefix: %w", err))
+	}
+	if _, err := tmp.Write([]byte("fLaC")); err != nil {
+		return fail(fmt.Errorf("failed to write FLAC marker: %w", err))
+	}
+	if _, err := tmp.Write(encodeFLACBlocks(blocks)); err != nil {
+		return fail(fmt.Errorf("failed to write FLAC metadata: %w", err))
+	}
+	if _, err := f.Seek(layout.audioOffset, io.SeekStart); err != nil {
+		return fail(fmt.Errorf("failed to seek to audio frames: %w", err))
+	}
+	if _, err := io.Copy(tmp, f); err != nil {
+		return fail(fmt.Errorf("failed to copy audio frames: %w", err))
+	}
+	if err := tmp.Close(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to finalize FLAC: %w", err)
+	}
+
+	if stat, err := f.Stat(); err == nil {
+		os.Chmod(tmpPath, stat.Mode().Perm())
+	}
+	f.Close()
+	if err := MoveFile(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	FinishOutputFile(path)
+	return nil
+}
+
+// readFLACLayout parses the metadata block headers, skipping a leading ID3v2 tag
+func readFLACLayout(r io.ReaderAt) (*flacLayout, error) {
+	layout := &flacLayout{}
+
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header[:4], 0); err != nil {
+		return nil, fmt.Errorf("failed to read FLAC header: %w", err)
+	}
+	if string(header[:3]) == "ID3" {
+		if _, err := r.ReadAt(header, 0); err != nil {
+			return nil, fmt.Errorf("failed to read ID3 header: %w", err)
+		}
+		size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		layout.prefixLen = 10 + size
+		if header[5]&0x10 != 0 {
+			layout.prefixLen += 10 // footer present
+		}
+		if _, err := r.ReadAt(header[:4], layout.prefixLen); err != nil {
+			return nil, fmt.Errorf("failed to read FLAC header: %w", err)
+		}
+	}
+	if string(header[:4]) != "fLaC" {
+		return nil, fmt.Errorf("not a FLAC file")
+	}
+
+	offset := layout.prefixLen + 4
+	for {
+		var bh [4]byte
+		if _, err := r.ReadAt(bh[:], offset); err != nil {
+			return nil, fmt.Errorf("failed to read metadata block header: %w", err)
+		}
+		last := bh[0]&0x80 != 0
+		blockType := bh[0] & 0x7f
+		length := int64(bh[1])<<16 | int64(bh[2])<<8 | int64(bh[3])
+
+		data := make([]byte, length)
+		if _, err := r.ReadAt(data, offset+4); err != nil {
+			return nil, fmt.Errorf("failed to read metadata block: %w", err)
+		}
+		layout.blocks = append(layout.blocks, flacBlock{Type: blockType, Data: data})
+		offset += 4 + length
+
+		if last {
+			break
+		}
+	}
+
+	if len(layout.blocks) == 0 || layout.blocks[0].Type != flacBlockStreamInfo {
+		return nil, fmt.Errorf("FLAC file is missing STREAMINFO")
+	}
+	layout.audioOffset = offset
+	return layout, nil
+}
+
+func encodeFLACBlocks(blocks []flacBlock) []byte {
+	var buf bytes.Buffer
+	for i, b := range blocks {
+		header := b.Type
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		n := len(b.Data)
+		buf.Write([]byte{header, byte(n >> 16), byte(n >> 8), byte(n)})
+		buf.Write(b.Data)
+	}
+	return buf.Bytes()
+}
+
+// parseVorbisComment decodes a VORBIS_COMMENT block (little-endian lengths)
+func parseVorbisComment(data []byte, meta *FLACMetadata) error {
+	r := bytes.NewReader(data)
+	readString := func() (string, error) {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", err
+		}
+		if int64(n) > int64(r.Len()) {
+			return "", fmt.Errorf("length %d exceeds block", n)
+		}
+		s := make([]byte, n)
+		if _, err := io.ReadFull(r, s); err != nil {
+			return "", err
+		}
+		return string(s), nil
+	}
+
+	vendor, err := readString()
+	if err != nil {
+		return fmt.Errorf("invalid vorbis comment vendor: %w", err)
+	}
+	meta.Vendor = vendor
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("invalid vorbis comment count: %w", err)
+	}
+	for i := uint32(0); i < count; i++ {
+		entry, err := readString()
+		if err != nil {
+			return fmt.Errorf("invalid vorbis comment: %w", err)
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		meta.Comments = append(meta.Comments, VorbisComment{Key: strings.ToUpper(key), Value: value})
+	}
+	return nil
+}
+
+func encodeVorbisComment(meta *FLACMetadata) []byte {
+	var buf bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+
+	vendor := meta.Vendor
+	if vendor == "" {
+		vendor = "YouFlac"
+	}
+	writeString(vendor)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(meta.Comments)))
+	for _, c := range meta.Comments {
+		writeString(strings.ToUpper(c.Key) + "=" + c.Value)
+	}
+	return buf.Bytes()
+}
+
+// parseFLACPicture decodes a PICTURE block (big-endian lengths)
+func parseFLACPicture(data []byte) (*FLACPicture, error) {
+	r := bytes.NewReader(data)
+	pic := &FLACPicture{}
+	readBytes := func() ([]byte, error) {
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if int64(n) > int64(r.Len()) {
+			return nil, fmt.Errorf("length %d exceeds block", n)
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &pic.Type); err != nil {
+		return nil, fmt.Errorf("invalid picture block: %w", err)
+	}
+	mime, err := readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture MIME type: %w", err)
+	}
+	desc, err := readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture description: %w", err)
+	}
+	for _, v := range []*uint32{&pic.Width, &pic.Height, &pic.Depth, &pic.Colors} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return nil, fmt.Errorf("invalid picture block: %w", err)
+		}
+	}
+	imgData, err := readBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture data: %w", err)
+	}
+
+	pic.MIMEType = string(mime)
+	pic.Description = string(desc)
+	pic.Data = imgData
+	return pic, nil
+}
+
+func encodeFLACPicture(pic *FLACPicture) []byte {
+	var buf bytes.Buffer
+	writeBytes := func(b []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(b)))
+		buf.Write(b)
+	}
+
+	binary.Write(&buf, binary.BigEndian, pic.Type)
+	writeBytes([]byte(pic.MIMEType))
+	writeBytes([]byte(pic.Description))
+	for _, v := range []uint32{pic.Width, pic.Height, pic.Depth, pic.Colors} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	writeBytes(pic.Data)
+	return buf.Bytes()
+}
+
+// NewFLACPictureFromFile builds a front-cover PICTURE block from a JPEG/PNG image
+func NewFLACPictureFromFile(imagePath string) (*FLACPicture, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cover: %w", err)
+	}
+
+	pic := &FLACPicture{
+		Type:     FLACPictureFrontCover,
+		MIMEType: http.DetectContentType(data),
+		Data:     data,
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		pic.Width = uint32(cfg.Width)
+		pic.Height = uint32(cfg.Height)
+		pic.Depth = 24
+	}
+	return pic, nil
+}
@@ -0,0 +1,177 @@
+package backend
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSyntheticFLAC writes a minimal FLAC: marker, STREAMINFO, padding and fake audio frames
+func writeSyntheticFLAC(t *testing.T, path string, prefix []byte, padding int, audio []byte) {
+	t.Helper()
+	blocks := []flacBlock{{Type: flacBlockStreamInfo, Data: make([]byte, 34)}}
+	if padding > 0 {
+		blocks = append(blocks, flacBlock{Type: flacBlockPadding, Data: make([]byte, padding)})
+	}
+	var buf bytes.Buffer
+	buf.Write(prefix)
+	buf.WriteString("fLaC")
+	buf.Write(encodeFLACBlocks(blocks))
+	buf.Write(audio)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test FLAC: %v", err)
+	}
+}
+
+func TestFLACTags_InPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.flac")
+	audio := bytes.Repeat([]byte{0xFF, 0xF8, 0x01, 0x02}, 256)
+	writeSyntheticFLAC(t, path, nil, 1024, audio)
+	sizeBefore := fileSize(t, path)
+
+	err := WriteTags(path, map[string][]string{
+		"TITLE":   {"Song"},
+		"ARTISTS": {"Artist A", "Artist B"},
+	})
+	if err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	if size := fileSize(t, path); size != sizeBefore {
+		t.Errorf("expected in-place write to keep size %d, got %d", sizeBefore, size)
+	}
+	assertFLACAudio(t, path, audio)
+
+	meta, err := ReadFLACMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadFLACMetadata failed: %v", err)
+	}
+	if got := meta.Get("title"); len(got) != 1 || got[0] != "Song" {
+		t.Errorf("TITLE = %v, want [Song]", got)
+	}
+	if got := meta.Get("ARTISTS"); len(got) != 2 || got[1] != "Artist B" {
+		t.Errorf("ARTISTS = %v, want multi-value [Artist A Artist B]", got)
+	}
+}
+
+func TestFLACTags_GrowAndReplace(t *testing.T) {
+	// ID3 prefix without padding forces a full rewrite that must keep the prefix
+	prefix := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 2, 0xAA, 0xBB}
+	path := filepath.Join(t.TempDir(), "song.flac")
+	audio := []byte("audio-frames-must-survive")
+	writeSyntheticFLAC(t, path, prefix, 0, audio)
+
+	if err := WriteTags(path, map[string][]string{"GENRE": {"Rock"}, "ARTIST": {"Old"}}); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+	if err := WriteTags(path, map[string][]string{"ARTIST": {"New"}}); err != nil {
+		t.Fatalf("second WriteTags failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(data, prefix) {
+		t.Error("ID3 prefix was not preserved")
+	}
+	assertFLACAudio(t, path, audio)
+
+	meta, err := ReadFLACMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadFLACMetadata failed: %v", err)
+	}
+	if got := meta.Get("ARTIST"); len(got) != 1 || got[0] != "New" {
+		t.Errorf("ARTIST = %v, want [New]", got)
+	}
+	if got := meta.Get("GENRE"); len(got) != 1 || got[0] != "Rock" {
+		t.Errorf("GENRE = %v, want [Rock]", got)
+	}
+}
+
+func TestFLACTags_Picture(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "song.flac")
+	writeSyntheticFLAC(t, path, nil, 16, []byte("frames"))
+
+	coverPath := filepath.Join(dir, "cover.jpg")
+	cover := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0}, 64)...)
+	if err := os.WriteFile(coverPath, cover, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := EmbedCoverArt(path, coverPath); err != nil {
+			t.Fatalf("EmbedCoverArt failed: %v", err)
+		}
+	}
+
+	meta, err := ReadFLACMetadata(path)
+	if err != nil {
+		t.Fatalf("ReadFLACMetadata failed: %v", err)
+	}
+	if len(meta.Pictures) != 1 {
+		t.Fatalf("expected 1 picture after embedding twice, got %d", len(meta.Pictures))
+	}
+	pic := meta.Pictures[0]
+	if pic.Type != FLACPictureFrontCover || pic.MIMEType != "image/jpeg" || !bytes.Equal(pic.Data, cover) {
+		t.Errorf("unexpected picture: type=%d mime=%s size=%d", pic.Type, pic.MIMEType, len(pic.Data))
+	}
+	assertFLACAudio(t, path, []byte("frames"))
+}
+
+func TestReadFLACMetadata_NotFLAC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.flac")
+	os.WriteFile(path, []byte("not a flac file"), 0644)
+	if _, err := ReadFLACMetadata(path); err == nil {
+		t.Error("expected error for non-FLAC input")
+	}
+}
+
+func TestMetadataToTags(t *testing.T) {
+	tags := MetadataToTags(&Metadata{
+		Title:      "Song",
+		Artist:     "A x B",
+		Artists:    []string{"A", "B"},
+		Year:       2020,
+		Track:      7,
+		TrackTotal: 120,
+		Disc:       2,
+	})
+	if tags["TITLE"][0] != "Song" || tags["DATE"][0] != "2020" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if tags["TRACKNUMBER"][0] != "7" || tags["TRACKTOTAL"][0] != "120" || tags["DISCNUMBER"][0] != "2" {
+		t.Errorf("unexpected numbering tags: %v", tags)
+	}
+	if len(tags["ARTISTS"]) != 2 {
+		t.Errorf("expected multi-value ARTISTS, got %v", tags["ARTISTS"])
+	}
+	if _, ok := tags["ALBUM"]; ok {
+		t.Error("empty fields should not produce tags")
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	return stat.Size()
+}
+
+func assertFLACAudio(t *testing.T, path string, audio []byte) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	layout, err := readFLACLayout(f)
+	if err != nil {
+		t.Fatalf("readFLACLayout failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data[layout.audioOffset:], audio) {
+		t.Error("audio frames were modified")
+	}
+}
@@ -0,0 +1,137 @@
+// Package format formats and parses the durations and file sizes shown to
+// and typed by users: "3:45", "1:02:03", "1.2 GB".
+//
+// Parsing accepts what people type in the languages the frontend supports:
+// a decimal comma ("1,5 GB") and the French octet units ("1,5 Go").
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Duration formats seconds as M:SS, or H:MM:SS from an hour
+func Duration(seconds float64) string {
+	h := int(seconds) / 3600
+	m := (int(seconds) % 3600) / 60
+	s := int(seconds) % 60
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// ParseDuration parses "SS", "MM:SS" or "HH:MM:SS" (seconds may have a
+// fraction, with a decimal point or comma) or a Go duration such as "1h30m"
+// into seconds
+func ParseDuration(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("invalid duration %q, expected HH:MM:SS, MM:SS, seconds or 1h2m3s", s)
+	if s == "" {
+		return 0, invalid
+	}
+
+	if strings.IndexFunc(s, unicode.IsLetter) >= 0 {
+		d, err := time.ParseDuration(strings.ReplaceAll(s, ",", "."))
+		if err != nil || d < 0 {
+			return 0, invalid
+		}
+		return d.Seconds(), nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, invalid
+	}
+	seconds := 0.0
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if last {
+			part = strings.Replace(part, ",", ".", 1)
+		}
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 || (!last && strings.Contains(part, ".")) || (i > 0 && value >= 60) {
+			return 0, invalid
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, nil
+}
+
+// Size units, in powers of 1024 as FileSize shows them
+const (
+	KB int64 = 1 << (10 * (iota + 1))
+	MB
+	GB
+	TB
+)
+
+// sizeUnits maps lower-case unit names, English and French ("octet"), to
+// their size in bytes
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1, "o": 1,
+	"k": KB, "kb": KB, "kib": KB, "ko": KB,
+	"m": MB, "mb": MB, "mib": MB, "mo": MB,
+	"g": GB, "gb": GB, "gib": GB, "go": GB,
+	"t": TB, "tb": TB, "tib": TB, "to": TB,
+}
+
+// FileSize formats bytes as "512 B", "1.5 KB", "1.2 GB"
+func FileSize(bytes int64) string {
+	return FileSizeIn(bytes, "en")
+}
+
+// FileSizeIn formats bytes for a language of the stage catalog: French,
+// German and Spanish use a decimal comma, French the octet units ("1,2 Go")
+func FileSizeIn(bytes int64, lang string) string {
+	byteUnit := "B"
+	if lang == "fr" {
+		byteUnit = "o"
+	}
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d %s", bytes, byteUnit)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	value := fmt.Sprintf("%.1f", float64(bytes)/float64(div))
+	if lang == "fr" || lang == "de" || lang == "es" {
+		value = strings.Replace(value, ".", ",", 1)
+	}
+	return fmt.Sprintf("%s %c%s", value, "KMGTPE"[exp], byteUnit)
+}
+
+// ParseSize parses a size such as "1.2 GB", "500MB", "1,5 Go" or "4096"
+// (bytes) into bytes. Units are powers of 1024 and case-insensitive; a comma
+// is a decimal separator, not a thousands separator.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("invalid size %q, expected a number with an optional unit such as 500 MB or 1.2 GB", s)
+
+	split := strings.IndexFunc(s, unicode.IsLetter)
+	if split < 0 {
+		split = len(s)
+	}
+	number := strings.Replace(strings.TrimSpace(s[:split]), ",", ".", 1)
+	multiplier, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[split:]))]
+	if !ok {
+		return 0, invalid
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) {
+		return 0, invalid
+	}
+	bytes := math.Round(value * float64(multiplier))
+	if bytes > math.MaxInt64 {
+		return 0, invalid
+	}
+	return int64(bytes), nil
+}
@@ -0,0 +1,167 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		seconds  float64
+		expected string
+	}{
+		{0, "0:00"},
+		{30, "0:30"},
+		{60, "1:00"},
+		{90, "1:30"},
+		{3600, "1:00:00"},
+		{3661, "1:01:01"},
+		{213, "3:33"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%.0fs", tt.seconds), func(t *testing.T) {
+			result := Duration(tt.seconds)
+			if result != tt.expected {
+				t.Errorf("Duration(%v) = %q, want %q", tt.seconds, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"90", 90},
+		{"1:30", 90},
+		{"3:45", 225},
+		{"1:02:03", 3723},
+		{" 4:05.5 ", 245.5},
+		{"4:05,5", 245.5},
+		{"0", 0},
+		{"1h30m", 5400},
+		{"90s", 90},
+		{"1,5m", 90},
+	}
+	for _, tt := range tests {
+		if got, err := ParseDuration(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "1:2:3:4", "1:75", "-5", "1.5:00", "abc", "-1m"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q) accepted", bad)
+		}
+	}
+}
+
+func TestFileSize(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{100, "100 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{1048576, "1.0 MB"},
+		{1073741824, "1.0 GB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := FileSize(tt.bytes)
+			if result != tt.expected {
+				t.Errorf("FileSize(%d) = %q, want %q", tt.bytes, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileSizeIn(t *testing.T) {
+	tests := []struct {
+		lang, want string
+	}{
+		{"en", "1.5 GB"},
+		{"de", "1,5 GB"},
+		{"es", "1,5 GB"},
+		{"fr", "1,5 Go"},
+	}
+	for _, tt := range tests {
+		if got := FileSizeIn(3*GB/2, tt.lang); got != tt.want {
+			t.Errorf("FileSizeIn(1.5 GB, %s) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+	if got := FileSizeIn(12, "fr"); got != "12 o" {
+		t.Errorf("FileSizeIn(12, fr) = %q", got)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"4096", 4096},
+		{"512 B", 512},
+		{"1 KB", 1024},
+		{"500MB", 500 * MB},
+		{"1.5 GB", 3 * GB / 2},
+		{"1,5 Go", 3 * GB / 2},
+		{"2 mo", 2 * MB},
+		{"1 TiB", TB},
+		{" 3 g ", 3 * GB},
+	}
+	for _, tt := range tests {
+		if got, err := ParseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "GB", "-1 MB", "1 XB", "1.2.3 MB", "NaN"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) accepted", bad)
+		}
+	}
+
+	// Sizes round-trip through FileSize
+	for _, size := range []int64{512, 3 * GB / 2, 250 * MB} {
+		if got, err := ParseSize(FileSize(size)); err != nil || got != size {
+			t.Errorf("ParseSize(FileSize(%d)) = %d, %v", size, got, err)
+		}
+	}
+}
+
+func TestSecondsJSON(t *testing.T) {
+	var v struct {
+		Min Seconds `json:"min"`
+		Max Seconds `json:"max"`
+	}
+	if err := json.Unmarshal([]byte(`{"min": 60, "max": "1:30:00"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Min != 60 || v.Max != 5400 {
+		t.Errorf("decoded %+v, want 60 and 5400", v)
+	}
+	if err := json.Unmarshal([]byte(`{"min": "soon"}`), &v); err == nil {
+		t.Error("decoded an invalid duration")
+	}
+	if data, _ := json.Marshal(v); string(data) != `{"min":60,"max":5400}` {
+		t.Errorf("encoded %s, want numbers", data)
+	}
+}
+
+func TestMegabytesJSON(t *testing.T) {
+	var v struct {
+		Size Megabytes `json:"size"`
+	}
+	for in, want := range map[string]Megabytes{`200`: 200, `"200"`: 200, `"2 GB"`: 2048, `"1,5 Go"`: 1536} {
+		if err := json.Unmarshal([]byte(`{"size": `+in+`}`), &v); err != nil || v.Size != want {
+			t.Errorf("decoded %s = %d, %v, want %d", in, v.Size, err, want)
+		}
+	}
+}
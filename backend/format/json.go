@@ -0,0 +1,84 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Seconds is a whole number of seconds. It decodes from a JSON number or a
+// duration string ParseDuration accepts ("3:45", "1h30m") and encodes as a
+// number.
+type Seconds int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(`"`)) {
+		var n float64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*s = Seconds(math.Round(n))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	seconds, err := ParseSeconds(text)
+	if err != nil {
+		return err
+	}
+	*s = seconds
+	return nil
+}
+
+// ParseSeconds parses a duration ParseDuration accepts into whole seconds
+func ParseSeconds(s string) (Seconds, error) {
+	seconds, err := ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return Seconds(math.Round(seconds)), nil
+}
+
+// Megabytes is a size in MB (1024 KB). It decodes from a JSON number of MB
+// or a size string ParseSize accepts ("2 GB") and encodes as a number.
+type Megabytes int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (m *Megabytes) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(`"`)) {
+		var n float64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*m = Megabytes(math.Round(n))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	mb, err := ParseMegabytes(text)
+	if err != nil {
+		return err
+	}
+	*m = mb
+	return nil
+}
+
+// ParseMegabytes parses a size ParseSize accepts into MB; a plain number
+// is taken as MB
+func ParseMegabytes(s string) (Megabytes, error) {
+	if strings.IndexFunc(s, unicode.IsLetter) < 0 {
+		s += " MB"
+	}
+	size, err := ParseSize(s)
+	if err != nil {
+		return 0, err
+	}
+	return Megabytes(math.Round(float64(size) / float64(MB))), nil
+}
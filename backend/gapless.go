@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Gapless playback. Album tracks are cut sample-accurately by the encoder;
+// re-encoding them or letting a lossy decoder keep its priming samples puts
+// clicks and gaps between tracks. FLAC sources are therefore copied untouched
+// (only metadata blocks are rewritten), and lossy sources carrying iTunSMPB
+// gapless info are trimmed to exactly the samples the encoder recorded.
+
+// GaplessInfo describes encoder delay/padding for a lossy stream (iTunSMPB)
+type GaplessInfo struct {
+	EncoderDelay   int   `json:"encoderDelay"`   // Priming samples at the start
+	EncoderPadding int   `json:"encoderPadding"` // Padding samples at the end
+	TotalSamples   int64 `json:"totalSamples"`   // Valid samples between delay and padding
+}
+
+// ParseITunSMPB parses an iTunSMPB value such as
+// " 00000000 00000840 000001CA 00000000008A1E76 00000000 ..."
+func ParseITunSMPB(value string) (*GaplessInfo, bool) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return nil, false
+	}
+
+	delay, err1 := strconv.ParseInt(fields[1], 16, 64)
+	padding, err2 := strconv.ParseInt(fields[2], 16, 64)
+	samples, err3 := strconv.ParseInt(fields[3], 16, 64)
+	if err1 != nil || err2 != nil || err3 != nil || samples <= 0 {
+		return nil, false
+	}
+	return &GaplessInfo{
+		EncoderDelay:   int(delay),
+		EncoderPadding: int(padding),
+		TotalSamples:   samples,
+	}, true
+}
+
+// findITunSMPB looks up the iTunSMPB tag in ffprobe tags (key case varies)
+func findITunSMPB(tags map[string]string) *GaplessInfo {
+	for key, value := range tags {
+		if strings.EqualFold(key, "iTunSMPB") {
+			if info, ok := ParseITunSMPB(value); ok {
+				return info
+			}
+		}
+	}
+	return nil
+}
+
+// gaplessInputArgs returns ffmpeg input options and an audio filter that drop
+// the encoder delay/padding of a lossy source. Edit lists are ignored so the
+// delay isn't removed twice for MP4 inputs.
+func gaplessInputArgs(info *MediaInfo) (inputArgs []string, filter string) {
+	if info == nil || info.Gapless == nil {
+		return nil, ""
+	}
+	g := info.Gapless
+	if strings.Contains(info.Format, "mp4") || strings.Contains(info.Format, "mov") {
+		inputArgs = []string{"-ignore_editlist", "1"}
+	}
+	filter = fmt.Sprintf("atrim=start_sample=%d:end_sample=%d,asetpts=PTS-STARTPTS",
+		g.EncoderDelay, int64(g.EncoderDelay)+g.TotalSamples)
+	return inputArgs, filter
+}
+
+// FLACStreamInfo holds the fields of a FLAC STREAMINFO block relevant to gapless playback
+type FLACStreamInfo struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	TotalSamples  int64
+	MD5           [16]byte
+}
+
+// ReadFLACStreamInfo reads the STREAMINFO block of a FLAC file
+func ReadFLACStreamInfo(path string) (*FLACStreamInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC: %w", err)
+	}
+	defer f.Close()
+
+	layout, err := readFLACLayout(f)
+	if err != nil {
+		return nil, err
+	}
+	return parseFLACStreamInfo(layout.blocks[0].Data)
+}
+
+// parseFLACStreamInfo decodes a 34-byte STREAMINFO block
+func parseFLACStreamInfo(data []byte) (*FLACStreamInfo, error) {
+	if len(data) < 34 {
+		return nil, fmt.Errorf("STREAMINFO too short: %d bytes", len(data))
+	}
+	// Bytes 10-17: 20 bits sample rate, 3 bits channels-1, 5 bits bps-1, 36 bits total samples
+	packed := binary.BigEndian.Uint64(data[10:18])
+	info := &FLACStreamInfo{
+		SampleRate:    int(packed >> 44),
+		Channels:      int(packed>>41&0x7) + 1,
+		BitsPerSample: int(packed>>36&0x1f) + 1,
+		TotalSamples:  int64(packed & 0xfffffffff),
+	}
+	copy(info.MD5[:], data[18:34])
+	return info, nil
+}
+
+// CopyFLACWithMetadata copies a FLAC file and writes tags and cover natively.
+// STREAMINFO, padding and audio frames are kept exactly as the encoder wrote
+// them, so gapless album tracks stay sample-accurate.
+func CopyFLACWithMetadata(audioPath, outputPath string, metadata *Metadata, coverPath string) error {
+	if err := copyFile(audioPath, outputPath); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("failed to copy FLAC: %w", err)
+	}
+
+	meta, err := ReadFLACMetadata(outputPath)
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+	for key, values := range MetadataToTags(metadata) {
+		meta.Set(key, values...)
+	}
+	if coverPath != "" && fileExists(coverPath) {
+		pic, err := NewFLACPictureFromFile(coverPath)
+		if err != nil {
+			os.Remove(outputPath)
+			return err
+		}
+		meta.SetPicture(*pic)
+	}
+	if err := WriteFLACMetadata(outputPath, meta); err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
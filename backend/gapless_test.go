@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeStreamInfo builds a STREAMINFO block for the given stream parameters
+func encodeStreamInfo(sampleRate, channels, bps int, totalSamples int64, md5 byte) []byte {
+	data := make([]byte, 34)
+	binary.BigEndian.PutUint16(data[0:2], 4096) // min block size
+	binary.BigEndian.PutUint16(data[2:4], 4096) // max block size
+	packed := uint64(sampleRate)<<44 | uint64(channels-1)<<41 | uint64(bps-1)<<36 | uint64(totalSamples)
+	binary.BigEndian.PutUint64(data[10:18], packed)
+	for i := 18; i < 34; i++ {
+		data[i] = md5
+	}
+	return data
+}
+
+// writeGaplessTrack writes one track of a gapless pair: its sample count is
+// not a multiple of the block size, so the last frame is short
+func writeGaplessTrack(t *testing.T, path string, totalSamples int64, md5 byte, audio []byte) []byte {
+	t.Helper()
+	streamInfo := encodeStreamInfo(44100, 2, 16, totalSamples, md5)
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	buf.Write(encodeFLACBlocks([]flacBlock{
+		{Type: flacBlockStreamInfo, Data: streamInfo},
+		{Type: flacBlockPadding, Data: make([]byte, 4096)},
+	}))
+	buf.Write(audio)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test FLAC: %v", err)
+	}
+	return streamInfo
+}
+
+func TestParseITunSMPB(t *testing.T) {
+	info, ok := ParseITunSMPB(" 00000000 00000840 000001CA 00000000008A1E76 00000000 00000000 00000000 00000000")
+	if !ok {
+		t.Fatal("expected valid iTunSMPB")
+	}
+	if info.EncoderDelay != 2112 || info.EncoderPadding != 458 || info.TotalSamples != 0x8A1E76 {
+		t.Errorf("unexpected gapless info: %+v", info)
+	}
+
+	for _, bad := range []string{"", "00000000 00000840", "00000000 zz 000001CA 00000000008A1E76", " 00000000 00000840 000001CA 0000000000000000"} {
+		if _, ok := ParseITunSMPB(bad); ok {
+			t.Errorf("ParseITunSMPB(%q) should fail", bad)
+		}
+	}
+}
+
+func TestGaplessInputArgs(t *testing.T) {
+	if args, filter := gaplessInputArgs(&MediaInfo{Format: "mov,mp4,m4a,3gp,3g2,mj2"}); args != nil || filter != "" {
+		t.Errorf("expected no gapless handling without iTunSMPB, got %v %q", args, filter)
+	}
+
+	info := &MediaInfo{
+		Format:  "mov,mp4,m4a,3gp,3g2,mj2",
+		Gapless: &GaplessInfo{EncoderDelay: 2112, EncoderPadding: 458, TotalSamples: 441000},
+	}
+	args, filter := gaplessInputArgs(info)
+	if len(args) != 2 || args[0] != "-ignore_editlist" {
+		t.Errorf("expected edit lists to be ignored for MP4, got %v", args)
+	}
+	if want := "atrim=start_sample=2112:end_sample=443112,asetpts=PTS-STARTPTS"; filter != want {
+		t.Errorf("filter = %q, want %q", filter, want)
+	}
+
+	info.Format = "matroska,webm"
+	if args, _ := gaplessInputArgs(info); args != nil {
+		t.Errorf("-ignore_editlist is MP4-only, got %v", args)
+	}
+}
+
+func TestFindITunSMPB_CaseInsensitive(t *testing.T) {
+	info := findITunSMPB(map[string]string{"ITUNSMPB": " 00000000 00000840 000001CA 0000000000001000"})
+	if info == nil || info.TotalSamples != 4096 {
+		t.Errorf("expected tag lookup to ignore key case, got %+v", info)
+	}
+}
+
+func TestReadFLACStreamInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.flac")
+	writeGaplessTrack(t, path, 9876543, 0xAB, []byte("frames"))
+
+	info, err := ReadFLACStreamInfo(path)
+	if err != nil {
+		t.Fatalf("ReadFLACStreamInfo failed: %v", err)
+	}
+	if info.SampleRate != 44100 || info.Channels != 2 || info.BitsPerSample != 16 || info.TotalSamples != 9876543 {
+		t.Errorf("unexpected stream info: %+v", info)
+	}
+	if info.MD5[0] != 0xAB || info.MD5[15] != 0xAB {
+		t.Errorf("MD5 not decoded: %x", info.MD5)
+	}
+}
+
+func TestCopyFLACWithMetadata_GaplessPair(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two consecutive album tracks split mid-block; the sample counts must be
+	// carried over exactly for the transition to stay seamless
+	tracks := []struct {
+		name    string
+		samples int64
+		md5     byte
+		audio   []byte
+	}{
+		{"01.flac", 44100*187 + 1234, 0x11, bytes.Repeat([]byte{0xFF, 0xF8, 0x69, 0x08}, 512)},
+		{"02.flac", 44100*203 + 2862, 0x22, bytes.Repeat([]byte{0xFF, 0xF8, 0x69, 0x18}, 768)},
+	}
+
+	for i, tr := range tracks {
+		src := filepath.Join(dir, "src-"+tr.name)
+		dst := filepath.Join(dir, "out", tr.name)
+		streamInfo := writeGaplessTrack(t, src, tr.samples, tr.md5, tr.audio)
+
+		metadata := &Metadata{Title: "Track", Artist: "Artist", Album: "Album", Track: i + 1}
+		if err := CopyFLACWithMetadata(src, dst, metadata, ""); err != nil {
+			t.Fatalf("CopyFLACWithMetadata(%s) failed: %v", tr.name, err)
+		}
+
+		if size := fileSize(t, dst); size != fileSize(t, src) {
+			t.Errorf("%s: tags should fit in the encoder padding (size %d -> %d)", tr.name, fileSize(t, src), size)
+		}
+		assertFLACAudio(t, dst, tr.audio)
+
+		f, err := os.Open(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layout, err := readFLACLayout(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("readFLACLayout failed: %v", err)
+		}
+		if !bytes.Equal(layout.blocks[0].Data, streamInfo) {
+			t.Errorf("%s: STREAMINFO was modified", tr.name)
+		}
+
+		meta, err := ReadFLACMetadata(dst)
+		if err != nil {
+			t.Fatalf("ReadFLACMetadata failed: %v", err)
+		}
+		if got := meta.Get("TRACKNUMBER"); len(got) != 1 || got[0] != string(rune('1'+i)) {
+			t.Errorf("%s: TRACKNUMBER = %v", tr.name, got)
+		}
+
+		info, _ := ReadFLACStreamInfo(dst)
+		if info.TotalSamples != tr.samples {
+			t.Errorf("%s: total samples = %d, want %d", tr.name, info.TotalSamples, tr.samples)
+		}
+	}
+}
+
+func TestCopyFLACWithMetadata_NotFLAC(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "audio.m4a")
+	dst := filepath.Join(dir, "audio.flac")
+	os.WriteFile(src, []byte("not a flac"), 0644)
+
+	if err := CopyFLACWithMetadata(src, dst, &Metadata{Title: "x"}, ""); err == nil {
+		t.Fatal("expected error for non-FLAC input")
+	}
+	if fileExists(dst) {
+		t.Error("partial output should be removed on failure")
+	}
+}
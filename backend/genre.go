@@ -0,0 +1,276 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Genre enrichment from community tags. Last.fm is queried first when an API
+// key is configured; MusicBrainz recording/artist tags are the keyless fallback.
+
+const lastFMAPIBase = "https://ws.audioscrobbler.com/2.0/"
+
+// genreMinLastFMCount is the minimum Last.fm tag weight (0-100) considered a genre
+const genreMinLastFMCount = 10
+
+// genreNoiseTags are common user tags that don't describe a genre
+var genreNoiseTags = map[string]bool{
+	"seen live":            true,
+	"favorites":            true,
+	"favourites":           true,
+	"favorite":             true,
+	"favourite":            true,
+	"my favorite":          true,
+	"love":                 true,
+	"loved":                true,
+	"awesome":              true,
+	"beautiful":            true,
+	"best":                 true,
+	"albums i own":         true,
+	"under 2000 listeners": true,
+	"spotify":              true,
+	"youtube":              true,
+	"music video":          true,
+	"female vocalists":     true,
+	"male vocalists":       true,
+}
+
+var genreDecadePattern = regexp.MustCompile(`^(\d{2}|\d{4})s?$`)
+
+// genreHTTPClient is a dedicated HTTP client for tag lookups
+var genreHTTPClient = &http.Client{
+	Timeout:   15 * time.Second,
+	Transport: sharedTransport,
+}
+
+// GenreService looks up a track's genre from Last.fm and MusicBrainz tags
+type GenreService struct {
+	lastFMAPIKey  string
+	lastFMBaseURL string
+	mbBaseURL     string
+	client        *http.Client
+}
+
+// NewGenreService creates a genre service. lastFMAPIKey may be empty, in
+// which case only MusicBrainz is queried.
+func NewGenreService(lastFMAPIKey string) *GenreService {
+	return &GenreService{
+		lastFMAPIKey:  lastFMAPIKey,
+		lastFMBaseURL: lastFMAPIBase,
+		mbBaseURL:     musicBrainzAPIBase,
+		client:        genreHTTPClient,
+	}
+}
+
+// genreTag is a tag name with its relative weight
+type genreTag struct {
+	Name  string
+	Count int
+}
+
+// LookupGenre returns the best genre for a track, or an empty string if none was found
+func (g *GenreService) LookupGenre(artist, title string) (string, error) {
+	if artist == "" || title == "" {
+		return "", fmt.Errorf("artist and title are required")
+	}
+
+	var lastErr error
+	if g.lastFMAPIKey != "" {
+		tags, err := g.lastFMTopTags("track.gettoptags", artist, title)
+		if err == nil {
+			if genre := pickGenre(tags, artist, genreMinLastFMCount); genre != "" {
+				return genre, nil
+			}
+		} else {
+			lastErr = err
+		}
+
+		// Track tags are sparse for less popular songs; artist tags usually exist
+		tags, err = g.lastFMTopTags("artist.gettoptags", artist, "")
+		if err == nil {
+			if genre := pickGenre(tags, artist, genreMinLastFMCount); genre != "" {
+				return genre, nil
+			}
+		} else {
+			lastErr = err
+		}
+	}
+
+	tags, err := g.musicBrainzTags(artist, title)
+	if err == nil {
+		if genre := pickGenre(tags, artist, 1); genre != "" {
+			return genre, nil
+		}
+	} else {
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", nil
+}
+
+type lastFMTopTagsResponse struct {
+	TopTags struct {
+		Tag []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"tag"`
+	} `json:"toptags"`
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+}
+
+// lastFMTopTags calls a Last.fm *.gettoptags method
+func (g *GenreService) lastFMTopTags(method, artist, title string) ([]genreTag, error) {
+	params := url.Values{}
+	params.Set("method", method)
+	params.Set("artist", artist)
+	if title != "" {
+		params.Set("track", title)
+	}
+	params.Set("autocorrect", "1")
+	params.Set("api_key", g.lastFMAPIKey)
+	params.Set("format", "json")
+
+	req, err := http.NewRequest("GET", g.lastFMBaseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Last.fm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result lastFMTopTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse Last.fm response: %w", err)
+	}
+	if result.Error != 0 {
+		return nil, fmt.Errorf("Last.fm error %d: %s", result.Error, result.Message)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Last.fm error: %d", resp.StatusCode)
+	}
+
+	tags := make([]genreTag, 0, len(result.TopTags.Tag))
+	for _, t := range result.TopTags.Tag {
+		tags = append(tags, genreTag{Name: t.Name, Count: t.Count})
+	}
+	return tags, nil
+}
+
+type mbTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type mbRecordingSearch struct {
+	Recordings []struct {
+		ID           string  `json:"id"`
+		Score        int     `json:"score"`
+		Tags         []mbTag `json:"tags"`
+		ArtistCredit []struct {
+			Artist struct {
+				ID   string  `json:"id"`
+				Tags []mbTag `json:"tags"`
+			} `json:"artist"`
+		} `json:"artist-credit"`
+	} `json:"recordings"`
+}
+
+// musicBrainzTags searches for the recording and returns its tags, falling
+// back to the primary artist's tags when the recording has none
+func (g *GenreService) musicBrainzTags(artist, title string) ([]genreTag, error) {
+	query := fmt.Sprintf(`recording:"%s" AND artist:"%s"`, escapeLucene(title), escapeLucene(artist))
+
+	var search mbRecordingSearch
+	if err := musicBrainzGet(g.client, g.mbBaseURL, "/recording", url.Values{"query": {query}, "limit": {"5"}}, &search); err != nil {
+		return nil, err
+	}
+
+	for _, rec := range search.Recordings {
+		if rec.Score < 80 {
+			continue
+		}
+		if len(rec.Tags) > 0 {
+			return toGenreTags(rec.Tags), nil
+		}
+		if len(rec.ArtistCredit) > 0 && len(rec.ArtistCredit[0].Artist.Tags) > 0 {
+			return toGenreTags(rec.ArtistCredit[0].Artist.Tags), nil
+		}
+	}
+	return nil, nil
+}
+
+func toGenreTags(tags []mbTag) []genreTag {
+	result := make([]genreTag, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, genreTag{Name: t.Name, Count: t.Count})
+	}
+	return result
+}
+
+// escapeLucene escapes characters that have meaning in a quoted Lucene phrase
+func escapeLucene(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// pickGenre returns the highest weighted tag that looks like a genre
+func pickGenre(tags []genreTag, artist string, minCount int) string {
+	sorted := make([]genreTag, len(tags))
+	copy(sorted, tags)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Count > sorted[j].Count
+	})
+
+	artistLower := strings.ToLower(strings.TrimSpace(artist))
+	for _, t := range sorted {
+		if t.Count < minCount {
+			break
+		}
+		name := strings.ToLower(strings.TrimSpace(t.Name))
+		if name == "" || name == artistLower || genreNoiseTags[name] || genreDecadePattern.MatchString(name) {
+			continue
+		}
+		return formatGenre(name)
+	}
+	return ""
+}
+
+// genreAcronyms are tags that should be upper-cased rather than title-cased
+var genreAcronyms = map[string]string{
+	"edm":       "EDM",
+	"idm":       "IDM",
+	"ebm":       "EBM",
+	"rnb":       "R&B",
+	"r&b":       "R&B",
+	"uk garage": "UK Garage",
+}
+
+// formatGenre title-cases a tag ("hip-hop" -> "Hip-Hop", "j-pop" -> "J-Pop")
+func formatGenre(tag string) string {
+	if acronym, ok := genreAcronyms[tag]; ok {
+		return acronym
+	}
+	words := strings.Fields(tag)
+	for i, w := range words {
+		parts := strings.Split(w, "-")
+		for j, p := range parts {
+			if p != "" {
+				parts[j] = strings.ToUpper(p[:1]) + p[1:]
+			}
+		}
+		words[i] = strings.Join(parts, "-")
+	}
+	return strings.Join(words, " ")
+}
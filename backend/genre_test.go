@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPickGenre(t *testing.T) {
+	tags := []genreTag{
+		{Name: "seen live", Count: 100},
+		{Name: "Daft Punk", Count: 90},
+		{Name: "2000s", Count: 80},
+		{Name: "french house", Count: 70},
+		{Name: "electronic", Count: 60},
+	}
+	if got := pickGenre(tags, "Daft Punk", 10); got != "French House" {
+		t.Errorf("pickGenre() = %q, want %q", got, "French House")
+	}
+
+	weak := []genreTag{{Name: "rock", Count: 5}}
+	if got := pickGenre(weak, "Someone", 10); got != "" {
+		t.Errorf("expected tags below threshold to be ignored, got %q", got)
+	}
+}
+
+func TestFormatGenre(t *testing.T) {
+	tests := map[string]string{
+		"hip-hop":   "Hip-Hop",
+		"j-pop":     "J-Pop",
+		"edm":       "EDM",
+		"rnb":       "R&B",
+		"pop":       "Pop",
+		"dream pop": "Dream Pop",
+	}
+	for input, want := range tests {
+		if got := formatGenre(input); got != want {
+			t.Errorf("formatGenre(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestGenreService_LastFMTrackThenArtist(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Query().Get("method")
+		methods = append(methods, method)
+		if r.URL.Query().Get("api_key") != "key" {
+			t.Errorf("expected api_key to be sent")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if method == "track.gettoptags" {
+			w.Write([]byte(`{"toptags":{"tag":[]}}`))
+			return
+		}
+		w.Write([]byte(`{"toptags":{"tag":[{"name":"synthpop","count":100},{"name":"80s","count":90}]}}`))
+	}))
+	defer server.Close()
+
+	g := NewGenreService("key")
+	g.lastFMBaseURL = server.URL
+	g.client = server.Client()
+
+	genre, err := g.LookupGenre("a-ha", "Take On Me")
+	if err != nil {
+		t.Fatalf("LookupGenre failed: %v", err)
+	}
+	if genre != "Synthpop" {
+		t.Errorf("genre = %q, want %q", genre, "Synthpop")
+	}
+	if len(methods) != 2 || methods[1] != "artist.gettoptags" {
+		t.Errorf("expected track then artist lookup, got %v", methods)
+	}
+}
+
+func TestGenreService_MusicBrainzFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recording" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"recordings":[
+			{"id":"low","score":50,"tags":[{"name":"polka","count":9}]},
+			{"id":"hit","score":100,"tags":[{"name":"rock","count":2},{"name":"alternative rock","count":5}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	g := NewGenreService("")
+	g.mbBaseURL = server.URL
+	g.client = server.Client()
+
+	genre, err := g.LookupGenre("Radiohead", "Creep")
+	if err != nil {
+		t.Fatalf("LookupGenre failed: %v", err)
+	}
+	if genre != "Alternative Rock" {
+		t.Errorf("genre = %q, want %q", genre, "Alternative Rock")
+	}
+}
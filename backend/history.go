@@ -2,6 +2,7 @@ package backend
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -18,7 +19,7 @@ type HistoryEntry struct {
 	VideoURL    string    `json:"videoUrl"`
 	Title       string    `json:"title"`
 	Artist      string    `json:"artist"`
-	AudioSource string    `json:"audioSource"` // tidal, qobuz, amazon, extracted
+	AudioSource string    `json:"audioSource"` // tidal, qobuz, amazon, youtube-music, extracted
 	Quality     string    `json:"quality"`
 	OutputPath  string    `json:"outputPath"`
 	Thumbnail   string    `json:"thumbnail,omitempty"`
@@ -27,12 +28,31 @@ type HistoryEntry struct {
 	CompletedAt time.Time `json:"completedAt"`
 	Status      string    `json:"status"` // complete, error
 	Error       string    `json:"error,omitempty"`
+
+	// Lossy fallback audio kept under Config.FallbackPolicy "upgrade"
+	NeedsUpgrade bool `json:"needsUpgrade,omitempty"`
+
+	Notes string   `json:"notes,omitempty"` // Free-form user notes
+	Tags  []string `json:"tags,omitempty"`  // User tags, e.g. "wedding"
+
+	StageDurations map[string]float64 `json:"stageDurations,omitempty"` // Seconds spent per queue status
+	Audit          *HistoryAudit      `json:"audit,omitempty"`          // Timeline, sources and transfer stats
+
+	SyncStatus string `json:"syncStatus,omitempty"` // rclone sync state: pending, syncing, synced, failed
+	SyncError  string `json:"syncError,omitempty"`
+}
+
+// historyFile is the format of history.json
+type historyFile struct {
+	SchemaVersion int            `json:"schemaVersion"` // See migrations.go
+	Entries       []HistoryEntry `json:"entries"`
 }
 
 // History manages the download history
 type History struct {
 	entries  []HistoryEntry
 	filePath string
+	saveErr  error // Set when history.json is from a newer version
 	mu       sync.RWMutex
 }
 
@@ -59,6 +79,11 @@ func (h *History) load() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if err := migrateForLoad(h.filePath, historySchema); err != nil {
+		h.entries = []HistoryEntry{}
+		h.saveErr = err
+		return
+	}
 	data, err := os.ReadFile(h.filePath)
 	if err != nil {
 		// File doesn't exist or can't be read, start with empty history
@@ -66,20 +91,27 @@ func (h *History) load() {
 		return
 	}
 
-	if err := json.Unmarshal(data, &h.entries); err != nil {
+	var file historyFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Entries == nil {
 		h.entries = []HistoryEntry{}
+		return
 	}
+	h.entries = file.Entries
 }
 
 // save writes history to disk
 func (h *History) save() error {
+	if h.saveErr != nil {
+		return fmt.Errorf("history not saved: %w", h.saveErr)
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(h.filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(h.entries, "", "  ")
+	data, err := json.MarshalIndent(historyFile{SchemaVersion: historySchema.Current, Entries: h.entries}, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -105,6 +137,28 @@ func (h *History) Add(entry HistoryEntry) error {
 	return h.save()
 }
 
+// AddEntries adds several entries (e.g. restored ones) with a single write,
+// keeping the list newest first
+func (h *History) AddEntries(entries []HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+		if entry.CompletedAt.IsZero() {
+			entry.CompletedAt = time.Now()
+		}
+		h.entries = append(h.entries, entry)
+	}
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.entries[i].CompletedAt.After(h.entries[j].CompletedAt)
+	})
+
+	return h.save()
+}
+
 // AddFromQueueItem creates a history entry from a completed queue item
 func (h *History) AddFromQueueItem(item *QueueItem, status string, errorMsg string) error {
 	entry := HistoryEntry{
@@ -121,6 +175,15 @@ func (h *History) AddFromQueueItem(item *QueueItem, status string, errorMsg stri
 		CompletedAt: time.Now(),
 		Status:      status,
 		Error:       errorMsg,
+		Notes:       item.Notes,
+		Tags:        item.Tags,
+
+		NeedsUpgrade: item.NeedsUpgrade,
+
+		StageDurations: item.StageDurations,
+		Audit:          newHistoryAudit(item),
+		SyncStatus:     item.SyncStatus,
+		SyncError:      item.SyncError,
 	}
 
 	return h.Add(entry)
@@ -137,7 +200,7 @@ func (h *History) GetAll() []HistoryEntry {
 	return result
 }
 
-// Search searches history by title or artist
+// Search searches history by title, artist, notes or tags
 func (h *History) Search(query string) []HistoryEntry {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -145,10 +208,9 @@ func (h *History) Search(query string) []HistoryEntry {
 	query = strings.ToLower(query)
 	var results []HistoryEntry
 
-	for _, entry := range h.entries {
-		if strings.Contains(strings.ToLower(entry.Title), query) ||
-			strings.Contains(strings.ToLower(entry.Artist), query) {
-			results = append(results, entry)
+	for i := range h.entries {
+		if h.entries[i].matches(query) {
+			results = append(results, h.entries[i])
 		}
 	}
 
@@ -185,6 +247,120 @@ func (h *History) FilterByStatus(status string) []HistoryEntry {
 	return results
 }
 
+// History page sizes
+const (
+	DefaultHistoryPageSize = 50
+	MaxHistoryPageSize     = 500
+)
+
+// HistoryQuery selects one page of history entries
+type HistoryQuery struct {
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`            // 0 = DefaultHistoryPageSize, capped at MaxHistoryPageSize
+	Sort   string `json:"sort,omitempty"`   // "date" (default), "title", "artist", "size", "duration"
+	Order  string `json:"order,omitempty"`  // "desc" (default) or "asc"
+	Search string `json:"search,omitempty"` // Matches title, artist, notes or tags
+	Status string `json:"status,omitempty"` // complete, error
+	Source string `json:"source,omitempty"` // Audio source
+	Tag    string `json:"tag,omitempty"`    // Entries carrying this tag (case-insensitive)
+
+	NeedsUpgrade bool `json:"needsUpgrade,omitempty"` // Only entries saved with lossy fallback audio to download again
+}
+
+// HistoryPage is one page of a history query
+type HistoryPage struct {
+	Entries []HistoryEntry `json:"entries"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	Total   int            `json:"total"`   // Entries matching the filters
+	All     int            `json:"all"`     // Entries in history
+	HasMore bool           `json:"hasMore"` // More matching entries after this page
+}
+
+// historySortKeys compare two entries in ascending order
+var historySortKeys = map[string]func(a, b *HistoryEntry) bool{
+	"date":     func(a, b *HistoryEntry) bool { return a.CompletedAt.Before(b.CompletedAt) },
+	"title":    func(a, b *HistoryEntry) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) },
+	"artist":   func(a, b *HistoryEntry) bool { return strings.ToLower(a.Artist) < strings.ToLower(b.Artist) },
+	"size":     func(a, b *HistoryEntry) bool { return a.FileSize < b.FileSize },
+	"duration": func(a, b *HistoryEntry) bool { return a.Duration < b.Duration },
+}
+
+// Query returns a filtered, sorted page of history entries
+func (h *History) Query(query HistoryQuery) (*HistoryPage, error) {
+	if query.Sort == "" {
+		query.Sort = "date"
+	}
+	less, ok := historySortKeys[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort %q: must be date, title, artist, size or duration", query.Sort)
+	}
+	switch query.Order {
+	case "":
+		query.Order = "desc"
+	case "asc", "desc":
+	default:
+		return nil, fmt.Errorf("invalid order %q: must be asc or desc", query.Order)
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultHistoryPageSize
+	}
+	query.Limit = min(query.Limit, MaxHistoryPageSize)
+	search := strings.ToLower(query.Search)
+
+	h.mu.RLock()
+	all := len(h.entries)
+	matches := make([]*HistoryEntry, 0, len(h.entries))
+	for i := range h.entries {
+		entry := &h.entries[i]
+		if query.Status != "" && entry.Status != query.Status {
+			continue
+		}
+		if query.Source != "" && entry.AudioSource != query.Source {
+			continue
+		}
+		if query.Tag != "" && !hasTag(entry.Tags, query.Tag) {
+			continue
+		}
+		if query.NeedsUpgrade && !entry.NeedsUpgrade {
+			continue
+		}
+		if search != "" && !entry.matches(search) {
+			continue
+		}
+		matches = append(matches, entry)
+	}
+
+	// Entries are stored newest first; the stable sort keeps that order for ties
+	sort.SliceStable(matches, func(i, j int) bool {
+		if query.Order == "asc" {
+			return less(matches[i], matches[j])
+		}
+		return less(matches[j], matches[i])
+	})
+
+	page := &HistoryPage{
+		Entries: []HistoryEntry{},
+		Offset:  query.Offset,
+		Limit:   query.Limit,
+		Total:   len(matches),
+		All:     all,
+	}
+	if query.Offset < len(matches) {
+		end := min(query.Offset+query.Limit, len(matches))
+		for _, entry := range matches[query.Offset:end] {
+			page.Entries = append(page.Entries, *entry)
+		}
+		page.HasMore = end < len(matches)
+	}
+	h.mu.RUnlock()
+
+	return page, nil
+}
+
 // GetByID returns a single entry by ID
 func (h *History) GetByID(id string) *HistoryEntry {
 	h.mu.RLock()
@@ -200,6 +376,22 @@ func (h *History) GetByID(id string) *HistoryEntry {
 	return nil
 }
 
+// SetSyncStatus records the rclone sync outcome on the newest entry for outputPath
+func (h *History) SetSyncStatus(outputPath, status, errorMsg string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.entries {
+		if h.entries[i].OutputPath == outputPath {
+			h.entries[i].SyncStatus = status
+			h.entries[i].SyncError = errorMsg
+			return h.save()
+		}
+	}
+
+	return nil
+}
+
 // Delete removes an entry by ID
 func (h *History) Delete(id string) error {
 	h.mu.Lock()
@@ -308,3 +500,53 @@ func (h *History) SortByDate(ascending bool) []HistoryEntry {
 
 	return result
 }
+
+// RedownloadOverrides changes how a history entry is fetched again.
+// Empty fields keep the original settings.
+type RedownloadOverrides struct {
+	Quality             string   `json:"quality,omitempty"`             // e.g. "2160p", "best"
+	AudioSourcePriority []string `json:"audioSourcePriority,omitempty"` // e.g. ["qobuz", "tidal"]
+	OutputMode          string   `json:"outputMode,omitempty"`          // "video" or "audio"
+	NamingTemplate      string   `json:"namingTemplate,omitempty"`      // Template or preset name
+}
+
+// RedownloadRequest builds a download request for a history entry with
+// optional overrides applied. overrides may be nil.
+func RedownloadRequest(entry *HistoryEntry, overrides *RedownloadOverrides) (DownloadRequest, error) {
+	request := DownloadRequest{
+		VideoURL: entry.VideoURL,
+		Quality:  entry.Quality,
+	}
+	if overrides == nil {
+		return request, nil
+	}
+
+	if overrides.Quality != "" {
+		if !containsString(validVideoQualities, overrides.Quality) {
+			return request, fmt.Errorf("invalid quality %q: must be one of %s", overrides.Quality, strings.Join(validVideoQualities, ", "))
+		}
+		request.Quality = overrides.Quality
+	}
+
+	if err := ValidateAudioSources(overrides.AudioSourcePriority); err != nil {
+		return request, err
+	}
+	request.AudioSourcePriority = overrides.AudioSourcePriority
+
+	switch overrides.OutputMode {
+	case "", OutputModeVideo, OutputModeAudio:
+		request.OutputMode = overrides.OutputMode
+	default:
+		return request, fmt.Errorf("invalid output mode %q: must be video or audio", overrides.OutputMode)
+	}
+
+	if template := strings.TrimSpace(overrides.NamingTemplate); template != "" {
+		template = resolveNamingTemplate(template)
+		if err := ValidateTemplate(template); err != nil {
+			return request, fmt.Errorf("invalid naming template: %w", err)
+		}
+		request.NamingTemplate = template
+	}
+
+	return request, nil
+}
@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRedownloadRequest(t *testing.T) {
+	entry := &HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Quality: "720p", AudioSource: "extracted"}
+
+	req, err := RedownloadRequest(entry, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.VideoURL != entry.VideoURL || req.Quality != "720p" {
+		t.Errorf("without overrides got %+v", req)
+	}
+
+	req, err = RedownloadRequest(entry, &RedownloadOverrides{
+		Quality:             "2160p",
+		AudioSourcePriority: []string{"qobuz", "tidal"},
+		OutputMode:          OutputModeVideo,
+		NamingTemplate:      "album",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Quality != "2160p" || len(req.AudioSourcePriority) != 2 || req.OutputMode != OutputModeVideo {
+		t.Errorf("overrides not applied: %+v", req)
+	}
+	if req.NamingTemplate != "{artist}/{album}/{title}" {
+		t.Errorf("NamingTemplate = %q, want the preset expanded", req.NamingTemplate)
+	}
+
+	for _, bad := range []*RedownloadOverrides{
+		{Quality: "8k"},
+		{AudioSourcePriority: []string{"napster"}},
+		{OutputMode: "dvd"},
+		{NamingTemplate: "no placeholders"},
+	} {
+		if _, err := RedownloadRequest(entry, bad); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestAddToQueue_CopiesOverrides(t *testing.T) {
+	q := newTestQueue()
+	id, err := q.AddToQueue(DownloadRequest{
+		VideoURL:       "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Quality:        "2160p",
+		OutputMode:     OutputModeAudio,
+		NamingTemplate: "{artist} - {title}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := q.GetItem(id)
+	if item.Quality != "2160p" || item.OutputMode != OutputModeAudio || item.NamingTemplate != "{artist} - {title}" {
+		t.Errorf("request settings not copied: %+v", item)
+	}
+	if got := itemNamingTemplate(item, &Config{NamingTemplate: "{title}"}); got != "{artist} - {title}" {
+		t.Errorf("itemNamingTemplate = %q", got)
+	}
+}
+
+func TestHistoryQuery(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &History{}
+	for i := 0; i < 10; i++ {
+		status := "complete"
+		if i%3 == 0 {
+			status = "error"
+		}
+		// Stored newest first, like History.Add
+		h.entries = append([]HistoryEntry{{
+			ID:          fmt.Sprint(i),
+			Title:       fmt.Sprintf("Song %02d", i),
+			Artist:      "Artist",
+			Status:      status,
+			FileSize:    int64(100 - i),
+			CompletedAt: base.Add(time.Duration(i) * time.Hour),
+
+			NeedsUpgrade: i == 4,
+		}}, h.entries...)
+	}
+
+	page, err := h.Query(HistoryQuery{Limit: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 10 || page.All != 10 || !page.HasMore || len(page.Entries) != 4 || page.Entries[0].ID != "9" {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	page, _ = h.Query(HistoryQuery{Offset: 8, Limit: 4})
+	if len(page.Entries) != 2 || page.HasMore || page.Entries[1].ID != "0" {
+		t.Errorf("last page = %+v", page)
+	}
+
+	page, _ = h.Query(HistoryQuery{Status: "error", Sort: "size", Order: "asc"})
+	if page.Total != 4 || page.All != 10 || page.Entries[0].ID != "9" || page.Entries[3].ID != "0" {
+		t.Errorf("filtered page = %+v", page)
+	}
+
+	page, _ = h.Query(HistoryQuery{Search: "song 05"})
+	if page.Total != 1 || page.Entries[0].ID != "5" {
+		t.Errorf("search page = %+v", page)
+	}
+
+	page, _ = h.Query(HistoryQuery{NeedsUpgrade: true})
+	if page.Total != 1 || page.Entries[0].ID != "4" {
+		t.Errorf("upgrade page = %+v", page)
+	}
+
+	page, _ = h.Query(HistoryQuery{Offset: 50})
+	if len(page.Entries) != 0 || page.Total != 10 {
+		t.Errorf("past-the-end page = %+v", page)
+	}
+
+	for _, bad := range []HistoryQuery{{Sort: "color"}, {Order: "sideways"}, {Offset: -1}} {
+		if _, err := h.Query(bad); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"youflac/backend/format"
+)
+
+// =============================================================================
+// HTTP file downloads
+// =============================================================================
+
+// Downloader fetches files over HTTP for the audio services and the cover
+// art fetchers. A download stops when its context is cancelled; network
+// errors and 5xx/429 answers are retried with a growing delay, resuming the
+// partial file with a range request where the server allows it; large files
+// are split into parallel range requests (see segmented_download.go); and
+// the result is verified against what the provider promised (see
+// download_verify.go), downloading again when it is truncated or corrupt.
+type Downloader struct {
+	Client      *http.Client
+	Connections int                    // Parallel range requests per file, <= 1 = single connection
+	Attempts    int                    // Tries per file, 0 = downloadVerifyAttempts
+	Progress    func(DownloadProgress) // Called as bytes arrive, from one goroutine at a time (optional)
+}
+
+// downloadRetryDelay is the wait before the second attempt after a network
+// error; it grows with every attempt. Replaced in tests.
+var downloadRetryDelay = time.Second
+
+// downloadStatusError is a download server answer other than the content
+type downloadStatusError struct {
+	Code int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("download server returned %d", e.Code)
+}
+
+// isRetryableDownloadError reports whether another attempt at the download
+// may succeed: the connection broke, the server was overloaded or the file
+// came out truncated or corrupt
+func isRetryableDownloadError(err error) bool {
+	if isVerificationError(err) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var statusErr *downloadStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500 || statusErr.Code == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Download downloads downloadURL to outputPath and checks the result against
+// want and what the server announced
+func (d *Downloader) Download(ctx context.Context, downloadURL, outputPath string, want ExpectedFile) error {
+	attempts := d.Attempts
+	if attempts <= 0 {
+		attempts = downloadVerifyAttempts
+	}
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Transport: sharedTransport}
+	}
+	policy := StreamURLPolicy()
+	if err := policy.Check(downloadURL); err != nil {
+		return fmt.Errorf("download URL rejected: %w", err)
+	}
+	client = policyClient(client, policy)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var announced ExpectedFile
+		announced, err = d.fetch(ctx, client, downloadURL, outputPath)
+		if err == nil {
+			err = VerifyDownloadedFile(outputPath, want.merge(announced))
+			if isVerificationError(err) {
+				os.Remove(outputPath) // Nothing to resume
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			os.Remove(outputPath)
+			return ctx.Err()
+		}
+		if attempt >= attempts || !isRetryableDownloadError(err) {
+			break
+		}
+
+		slog.Warn("download failed, retrying", "file", filepath.Base(outputPath), "attempt", attempt, "err", err)
+		if !isVerificationError(err) {
+			select {
+			case <-ctx.Done():
+				os.Remove(outputPath)
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * downloadRetryDelay):
+			}
+		}
+	}
+	// Segment parts stay on disk for a later download of the same file
+	os.Remove(outputPath)
+	return err
+}
+
+// fetch downloads the file once and returns what the server said it would be
+func (d *Downloader) fetch(ctx context.Context, client *http.Client, downloadURL, outputPath string) (ExpectedFile, error) {
+	transfer := &transferCounter{report: d.Progress, started: time.Now()}
+	if d.Connections > 1 {
+		size, ranged, err := probeRangeSupport(ctx, client, downloadURL)
+		if err != nil {
+			slog.Debug("range probe failed, downloading over one connection", "err", err)
+		} else if ranged && size >= minSegmentedSize {
+			err := downloadSegmented(ctx, client, downloadURL, outputPath, size, min(d.Connections, maxDownloadConnections), transfer)
+			return ExpectedFile{Size: size}, err
+		}
+	}
+	return downloadSingle(ctx, client, downloadURL, outputPath, transfer)
+}
+
+// downloadSingle downloads the file over one connection, continuing a
+// partial file from an earlier attempt when the server supports ranges
+func downloadSingle(ctx context.Context, client *http.Client, downloadURL, outputPath string, transfer *transferCounter) (ExpectedFile, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return ExpectedFile{}, fmt.Errorf("failed to create download request: %w", err)
+	}
+	have := int64(0)
+	if info, err := os.Stat(outputPath); err == nil && info.Size() > 0 {
+		have = info.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ExpectedFile{}, fmt.Errorf("failed to start download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var announced ExpectedFile
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		announced = expectedFromResponse(resp)
+		have = 0
+	case resp.StatusCode == http.StatusPartialContent && have > 0 && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", have)):
+		// The checksum headers of a range describe the range, not the file
+		announced.Size = contentRangeTotal(resp.Header.Get("Content-Range"))
+		flags = os.O_WRONLY | os.O_APPEND
+		slog.Debug("resuming download", "file", filepath.Base(outputPath), "offset", have)
+	default:
+		if have > 0 {
+			os.Remove(outputPath) // Start over on the next attempt
+		}
+		return ExpectedFile{}, &downloadStatusError{Code: resp.StatusCode}
+	}
+
+	outFile, err := os.OpenFile(outputPath, flags, 0644)
+	if err != nil {
+		return announced, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer outFile.Close()
+
+	total := announced.Size
+	if total <= 0 && resp.ContentLength > 0 {
+		total = have + resp.ContentLength
+	}
+	transfer.begin(have, total)
+	if _, err = io.Copy(outFile, transfer.reader(resp.Body)); err != nil {
+		// The partial file is resumed by the next attempt
+		return announced, fmt.Errorf("download interrupted: %w", err)
+	}
+
+	return announced, nil
+}
+
+// contentRangeTotal returns the file size from a "bytes a-b/total" header,
+// 0 if unknown
+func contentRangeTotal(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	size, err := strconv.ParseInt(total, 10, 64)
+	if !ok || err != nil || size <= 0 {
+		return 0
+	}
+	return size
+}
+
+// transferCounter sums the bytes of all connections of one download and
+// reports them as DownloadProgress
+type transferCounter struct {
+	mu      sync.Mutex
+	report  func(DownloadProgress)
+	started time.Time
+	resumed int64 // Bytes on disk before this attempt, not counted for the speed
+	done    int64
+	total   int64
+}
+
+// begin sets the bytes already on disk and the file size (0 if unknown)
+func (c *transferCounter) begin(have, total int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumed, c.done, c.total = have, have, total
+	c.started = time.Now()
+}
+
+// add counts n downloaded bytes
+func (c *transferCounter) add(n int64) {
+	if c.report == nil || n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done += n
+
+	progress := DownloadProgress{Downloaded: c.done, Total: c.total}
+	if c.total > 0 {
+		progress.Percent = min(100, float64(c.done)*100/float64(c.total))
+	}
+	if elapsed := time.Since(c.started).Seconds(); elapsed > 0 {
+		progress.Speed = float64(c.done-c.resumed) / elapsed
+	}
+	if progress.Speed > 0 && c.total > c.done {
+		progress.ETA = format.Duration(float64(c.total-c.done) / progress.Speed)
+	}
+	c.report(progress)
+}
+
+// reader counts what is read from r
+func (c *transferCounter) reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, counter: c}
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *transferCounter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.counter.add(int64(n))
+	return n, err
+}
+
+// downloadContext returns ctx, or the background context for services that
+// were given none
+func downloadContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
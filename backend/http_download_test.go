@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// noRetryDelay makes failed downloads retry immediately
+func noRetryDelay(t *testing.T) {
+	orig := downloadRetryDelay
+	downloadRetryDelay = 0
+	t.Cleanup(func() { downloadRetryDelay = orig })
+}
+
+func TestDownloader_RetriesServerErrors(t *testing.T) {
+	noRetryDelay(t)
+	content := testContent(5000)
+	var requests atomic.Int32
+	var gone atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(content)
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes, content differs", len(got))
+	}
+
+	// Client errors are final
+	gone.Store(true)
+	var statusErr *downloadStatusError
+	if err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{}); !errors.As(err, &statusErr) || statusErr.Code != 404 {
+		t.Errorf("Download = %v, want a 404 status error", err)
+	}
+}
+
+func TestDownloader_ResumesSingleConnection(t *testing.T) {
+	noRetryDelay(t)
+	content := testContent(10000)
+	var mu sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Write(content[:4000]) // Hang up early
+			return
+		}
+		http.ServeContent(w, r, "track.flac", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, content differs", len(got))
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=4000-" {
+		t.Errorf("ranges = %q, want a plain GET and the rest from byte 4000", ranges)
+	}
+}
+
+func TestDownloader_Progress(t *testing.T) {
+	content := testContent(minSegmentedSize + 1000)
+	ts, _ := rangeServer(t, content)
+
+	for _, connections := range []int{1, 4} {
+		var last DownloadProgress
+		var calls int
+		d := &Downloader{Client: ts.Client(), Connections: connections, Progress: func(p DownloadProgress) {
+			calls++
+			last = p
+		}}
+		out := filepath.Join(t.TempDir(), "track.flac")
+		if err := d.Download(context.Background(), ts.URL, out, ExpectedFile{}); err != nil {
+			t.Fatalf("Download: %v", err)
+		}
+		if calls == 0 || last.Downloaded != int64(len(content)) || last.Total != int64(len(content)) || last.Percent != 100 {
+			t.Errorf("%d connections: %d progress calls, last = %+v", connections, calls, last)
+		}
+	}
+}
+
+func TestDownloader_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write(make([]byte, 10))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	d := &Downloader{Client: ts.Client()}
+	if err := d.Download(ctx, ts.URL, out, ExpectedFile{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Download = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("cancelled download left behind")
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\xff\xd8\xff image"))
+	}))
+	defer ts.Close()
+
+	path, contentType, cleanup, err := fetchImage(ts.URL + "/maxresdefault.jpg")
+	if err != nil || contentType != "image/jpeg" {
+		t.Fatalf("fetchImage = %q, %v, want image/jpeg", contentType, err)
+	}
+	if got, _ := os.ReadFile(path); !strings.HasSuffix(string(got), " image") {
+		t.Errorf("fetched %q", got)
+	}
+	cleanup()
+	if _, err := os.Stat(path); err == nil {
+		t.Error("cleanup left the image behind")
+	}
+
+	// Local files are converted in place
+	if path, _, _, err := fetchImage("/tmp/cover.png"); err != nil || path != "/tmp/cover.png" {
+		t.Errorf("fetchImage(local) = %q, %v", path, err)
+	}
+}
+
+func TestFetchImage_RejectsNonImages(t *testing.T) {
+	noRetryDelay(t)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("<!DOCTYPE html><html><body>Access denied</body></html>"))
+	}))
+	defer ts.Close()
+
+	_, _, _, err := fetchImage(ts.URL + "/hqdefault.jpg")
+	if err == nil || !strings.Contains(err.Error(), "not an image: got text/html") {
+		t.Errorf("fetchImage(HTML) = %v, want a not-an-image error", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("HTML page fetched %d times, want 1", requests.Load())
+	}
+}
+
+func TestFetchImage_Retries(t *testing.T) {
+	noRetryDelay(t)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("\x89PNG\r\n\x1a\n image"))
+	}))
+	defer ts.Close()
+
+	ConfigureCoverCache(&Config{CoverCacheMB: DefaultCoverCacheMB, ArtworkAttempts: 2})
+	t.Cleanup(func() { ConfigureCoverCache(GetDefaultConfig()) })
+	if _, _, _, err := fetchImage(ts.URL + "/cover.png"); err == nil {
+		t.Error("fetchImage succeeded after 2 of 2 attempts failed")
+	}
+
+	requests.Store(0)
+	ConfigureCoverCache(&Config{CoverCacheMB: DefaultCoverCacheMB, ArtworkAttempts: 3})
+	_, contentType, cleanup, err := fetchImage(ts.URL + "/cover.png")
+	if err != nil || contentType != "image/png" {
+		t.Fatalf("fetchImage = %q, %v, want image/png on the third attempt", contentType, err)
+	}
+	cleanup()
+}
+
+func TestConvertCoverArt_CopiesJPEG(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\xff\xd8\xff jpeg"))
+	}))
+	defer ts.Close()
+
+	// No ffmpeg needed for an image that already is a JPEG
+	out := filepath.Join(t.TempDir(), "poster.jpg")
+	if err := convertCoverArt(ts.URL+"/maxresdefault.jpg", out); err != nil {
+		t.Fatalf("convertCoverArt: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "\xff\xd8\xff jpeg" {
+		t.Errorf("poster = %q", got)
+	}
+}
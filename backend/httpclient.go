@@ -13,7 +13,9 @@ import (
 )
 
 // NewHTTPClient returns an *http.Client configured with the given timeout
-// and optionally routed through a proxy.
+// and optionally routed through a proxy. Requests get the configured
+// User-Agent (see ConfigureUserAgents); clients without a proxy share one
+// connection pool.
 //
 // proxyURL examples:
 //   - "" (empty) — no proxy
@@ -26,33 +28,37 @@ func NewHTTPClient(timeout time.Duration, proxyURL string) (*http.Client, error)
 		proxyURL = env
 	}
 
+	if proxyURL == "" {
+		return &http.Client{
+			Timeout:   timeout,
+			Transport: sharedTransport,
+		}, nil
+	}
+
 	transport := &http.Transport{}
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
 
-	if proxyURL != "" {
-		parsed, err := url.Parse(proxyURL)
+	switch parsed.Scheme {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(parsed)
+	case "socks5":
+		dialer, err := proxy.FromURL(parsed, proxy.Direct)
 		if err != nil {
-			return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+			return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
 		}
-
-		switch parsed.Scheme {
-		case "http", "https":
-			transport.Proxy = http.ProxyURL(parsed)
-		case "socks5":
-			dialer, err := proxy.FromURL(parsed, proxy.Direct)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
-			}
-			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return dialer.Dial(network, addr)
-			}
-		default:
-			return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, or socks5)", parsed.Scheme)
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
 		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, or socks5)", parsed.Scheme)
 	}
 
 	return &http.Client{
 		Timeout:   timeout,
-		Transport: transport,
+		Transport: &userAgentTransport{base: transport},
 	}, nil
 }
 
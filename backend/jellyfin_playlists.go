@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Jellyfin playlists
+// =============================================================================
+
+// Besides collections, Jellyfin has real playlists: ordered, playable as a
+// queue and listed in the music section. They live in its data directory
+// too, one folder per playlist with a playlist.xml listing the item paths.
+// BuildJellyfinPlaylists writes one for every playlist folder of the
+// library, in playlist order. Like collections, only the playlists YouFlac
+// wrote (see the manifest) are rewritten or removed.
+
+// playlistsManifest is the file in the playlists directory listing the
+// playlist folders YouFlac wrote
+const playlistsManifest = ".youflac-playlists.json"
+
+// jellyfinPlaylistsMu serializes BuildJellyfinPlaylists
+var jellyfinPlaylistsMu sync.Mutex
+
+// PlaylistReport is the result of BuildJellyfinPlaylists
+type PlaylistReport struct {
+	Playlists int      `json:"playlists"` // Playlists written
+	Items     int      `json:"items"`     // Items in those playlists
+	Removed   int      `json:"removed"`   // Playlists removed because their files are gone
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// jellyfinPlaylist is the playlist.xml format of Jellyfin's playlists
+type jellyfinPlaylist struct {
+	XMLName    xml.Name                 `xml:"Item"`
+	Added      string                   `xml:"Added,omitempty"`
+	LockData   bool                     `xml:"LockData"`
+	LocalTitle string                   `xml:"LocalTitle"`
+	Items      []jellyfinCollectionItem `xml:"PlaylistItems>PlaylistItem"`
+	MediaType  string                   `xml:"PlaylistMediaType"` // "Video" or "Audio"
+}
+
+// BuildJellyfinPlaylists writes a Jellyfin playlist for every playlist
+// folder of the library and removes the ones whose files are all gone
+func BuildJellyfinPlaylists(config *Config) (*PlaylistReport, error) {
+	report := &PlaylistReport{}
+	if config == nil || config.JellyfinPlaylistsDir == "" {
+		return report, nil
+	}
+
+	files, err := libraryMediaFiles(config, LibraryViewsFromConfig(config))
+	if err != nil {
+		return nil, err
+	}
+	playlists := make(map[string][]string)
+	for _, file := range files {
+		if name := playlistFolderOf(config, file); name != "" {
+			playlists[name] = append(playlists[name], file)
+		}
+	}
+
+	jellyfinPlaylistsMu.Lock()
+	defer jellyfinPlaylistsMu.Unlock()
+
+	dir := config.JellyfinPlaylistsDir
+	if err := MkdirOutput(dir); err != nil {
+		return nil, fmt.Errorf("failed to create playlists directory: %w", err)
+	}
+	previous := loadCollectionManifest(dir, playlistsManifest)
+
+	var written []string
+	for _, name := range slices.Sorted(maps.Keys(playlists)) {
+		folder := SanitizeFileName(name)
+		if slices.Contains(written, folder) {
+			continue
+		}
+		path := filepath.Join(dir, folder)
+		if _, err := os.Stat(filepath.Join(path, "playlist.xml")); err == nil && !slices.Contains(previous.Collections, folder) {
+			report.Errors = append(report.Errors, fmt.Sprintf("skipped %q: Jellyfin already has a playlist with this name", name))
+			continue
+		}
+		if err := writeJellyfinPlaylist(path, name, playlists[name], config.JellyfinPathMap); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		written = append(written, folder)
+		report.Playlists++
+		report.Items += len(playlists[name])
+	}
+
+	for _, folder := range previous.Collections {
+		if slices.Contains(written, folder) {
+			continue
+		}
+		path := filepath.Join(dir, folder)
+		if err := os.Remove(filepath.Join(path, "playlist.xml")); err != nil && !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to remove playlist %s: %v", folder, err))
+			written = append(written, folder) // Try again next time
+			continue
+		}
+		os.Remove(path) // Only if Jellyfin left nothing else in it
+		report.Removed++
+	}
+
+	slices.Sort(written)
+	if err := saveCollectionManifest(dir, playlistsManifest, &collectionManifest{Collections: written}); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to save playlists manifest: %v", err))
+	}
+	return report, nil
+}
+
+// writeJellyfinPlaylist writes the playlist.xml of one playlist folder.
+// Files are in playlist order (sorted by their "01 - " prefix); a playlist
+// of FLAC files only is an audio playlist, anything else a video playlist.
+func writeJellyfinPlaylist(path, name string, files []string, pathMap string) error {
+	doc := jellyfinPlaylist{
+		Added:      time.Now().UTC().Format("01/02/2006 15:04:05"),
+		LocalTitle: name,
+		MediaType:  "Audio",
+	}
+	for _, file := range files {
+		doc.Items = append(doc.Items, jellyfinCollectionItem{Path: mapJellyfinPath(file, pathMap)})
+		if !strings.EqualFold(filepath.Ext(file), ".flac") {
+			doc.MediaType = "Video"
+		}
+	}
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate playlist %s: %w", name, err)
+	}
+	content := append([]byte(xml.Header), output...)
+	if err := WriteOutputFile(filepath.Join(path, "playlist.xml"), content); err != nil {
+		return fmt.Errorf("failed to write playlist %s: %w", name, err)
+	}
+	return nil
+}
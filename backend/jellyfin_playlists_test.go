@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTestPlaylist(t *testing.T, path string) jellyfinPlaylist {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc jellyfinPlaylist
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestBuildJellyfinPlaylists(t *testing.T) {
+	root := t.TempDir()
+	library := filepath.Join(root, "library")
+	playlists := filepath.Join(root, "jellyfin", "playlists")
+	writeTestFile(t, filepath.Join(library, "Road Trip", "02 - Justice - D.A.N.C.E.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Road Trip", "01 - Daft Punk - One More Time.mkv"), 8)
+	writeTestFile(t, filepath.Join(library, "Chill", "01 - Air - La Femme d'Argent.flac"), 8)
+	writeTestFile(t, filepath.Join(library, "Daft Punk - Around the World.mkv"), 8)
+
+	config := &Config{
+		OutputDirectory:      library,
+		JellyfinPlaylistsDir: playlists,
+		JellyfinPathMap:      library + "=/media/mv",
+	}
+	report, err := BuildJellyfinPlaylists(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Playlists != 2 || report.Items != 3 || len(report.Errors) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	doc := readTestPlaylist(t, filepath.Join(playlists, "Road Trip", "playlist.xml"))
+	want := []string{
+		"/media/mv/Road Trip/01 - Daft Punk - One More Time.mkv",
+		"/media/mv/Road Trip/02 - Justice - D.A.N.C.E.mkv",
+	}
+	if doc.LocalTitle != "Road Trip" || doc.MediaType != "Video" || len(doc.Items) != len(want) {
+		t.Fatalf("playlist = %+v", doc)
+	}
+	for i, path := range want {
+		if doc.Items[i].Path != path {
+			t.Errorf("item %d = %q, want %q", i, doc.Items[i].Path, path)
+		}
+	}
+	if doc := readTestPlaylist(t, filepath.Join(playlists, "Chill", "playlist.xml")); doc.MediaType != "Audio" {
+		t.Errorf("FLAC playlist media type = %q, want Audio", doc.MediaType)
+	}
+
+	// A playlist made in Jellyfin is not touched; a deleted playlist folder
+	// removes its playlist
+	writeTestFile(t, filepath.Join(playlists, "Favourites", "playlist.xml"), 4)
+	if err := os.RemoveAll(filepath.Join(library, "Chill")); err != nil {
+		t.Fatal(err)
+	}
+	report, err = BuildJellyfinPlaylists(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Playlists != 1 || report.Removed != 1 {
+		t.Errorf("report = %+v, want Road Trip written and Chill removed", report)
+	}
+	if _, err := os.Stat(filepath.Join(playlists, "Chill")); !os.IsNotExist(err) {
+		t.Errorf("removed playlist still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(playlists, "Favourites", "playlist.xml")); err != nil {
+		t.Errorf("Jellyfin's own playlist was removed: %v", err)
+	}
+}
+
+func TestBuildJellyfinPlaylists_Disabled(t *testing.T) {
+	report, err := BuildJellyfinPlaylists(&Config{OutputDirectory: t.TempDir()})
+	if err != nil || report.Playlists != 0 {
+		t.Errorf("BuildJellyfinPlaylists() = %+v, %v, want nothing without a playlists directory", report, err)
+	}
+}
@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+)
+
+// =============================================================================
+// Audio/video length reconciliation
+// =============================================================================
+
+// The lossless audio is not always the edit the video uses: an album version
+// with a longer outro, a radio edit against an extended video. Muxed as they
+// are, the file ends on a frozen picture or silent video. Config.LengthMode,
+// or DownloadRequest.LengthMode per item, decides what happens instead:
+//
+//   - trim: the file ends with the shorter stream (the audio is cut
+//     sample-accurately, the video by stream copy)
+//   - freeze / black: a longer audio is kept and the video extended with its
+//     last frame or black (re-encodes the video); a longer video gets silence
+//   - chapters: both are kept as they are, with chapters marking where the
+//     video or the audio ends
+//   - off: muxed as they are
+
+// Length reconciliation modes
+const (
+	LengthModeOff      = "off"
+	LengthModeTrim     = "trim"
+	LengthModeFreeze   = "freeze"
+	LengthModeBlack    = "black"
+	LengthModeChapters = "chapters"
+)
+
+var validLengthModes = []string{LengthModeOff, LengthModeTrim, LengthModeFreeze, LengthModeBlack, LengthModeChapters}
+
+// lengthMismatchTolerance is the difference in seconds left alone; codec
+// delay and the leading-silence sync move the streams by less
+const lengthMismatchTolerance = 2.0
+
+// validateLengthMode checks a per-item mode ("" = Config.LengthMode)
+func validateLengthMode(mode string) error {
+	if mode != "" && !slices.Contains(validLengthModes, mode) {
+		return fmt.Errorf("invalid length mode %q: must be one of %v", mode, validLengthModes)
+	}
+	return nil
+}
+
+// LengthReconciliation is what ReconcileLength did to the inputs of a mux
+type LengthReconciliation struct {
+	VideoPath     string    `json:"videoPath"` // Input to mux instead of the original
+	AudioPath     string    `json:"audioPath"`
+	VideoDuration float64   `json:"videoDuration"`
+	AudioDuration float64   `json:"audioDuration"`
+	Action        string    `json:"action,omitempty"` // e.g. "trimmed audio by 42.0s", "" = nothing needed
+	Chapters      []Chapter `json:"chapters,omitempty"`
+}
+
+// ReconcileLength compares the lengths of videoPath and audioPath and, when
+// they differ by more than the tolerance, applies mode. New files are
+// written to workDir.
+func ReconcileLength(ctx context.Context, videoPath, audioPath, workDir, mode string) (*LengthReconciliation, error) {
+	rec := &LengthReconciliation{VideoPath: videoPath, AudioPath: audioPath}
+	if mode == "" || mode == LengthModeOff {
+		return rec, nil
+	}
+
+	videoInfo, err := GetMediaInfo(videoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video info: %w", err)
+	}
+	audioInfo, err := GetMediaInfo(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio info: %w", err)
+	}
+	rec.VideoDuration, rec.AudioDuration = videoInfo.Duration, audioInfo.Duration
+	if rec.VideoDuration <= 0 || rec.AudioDuration <= 0 {
+		return rec, nil
+	}
+	diff := rec.AudioDuration - rec.VideoDuration
+	if math.Abs(diff) <= lengthMismatchTolerance {
+		return rec, nil
+	}
+	audioLonger := diff > 0
+
+	switch mode {
+	case LengthModeTrim:
+		if audioLonger {
+			rec.AudioPath = filepath.Join(workDir, "audio-trimmed.flac")
+			err = TrimAudioToClip(ctx, audioPath, rec.AudioPath, &Clip{End: rec.VideoDuration})
+			rec.Action = fmt.Sprintf("trimmed audio by %.1fs", diff)
+		} else {
+			rec.VideoPath = filepath.Join(workDir, "video-trimmed"+filepath.Ext(videoPath))
+			err = trimVideo(ctx, videoPath, rec.VideoPath, rec.AudioDuration)
+			rec.Action = fmt.Sprintf("trimmed video by %.1fs", -diff)
+		}
+
+	case LengthModeFreeze, LengthModeBlack:
+		if audioLonger {
+			rec.VideoPath = filepath.Join(workDir, "video-padded.mkv")
+			err = padVideo(ctx, videoPath, rec.VideoPath, diff, mode == LengthModeBlack)
+			rec.Action = fmt.Sprintf("extended video by %.1fs (%s)", diff, mode)
+		} else {
+			rec.AudioPath = filepath.Join(workDir, "audio-padded.flac")
+			err = padAudio(ctx, audioPath, rec.AudioPath, rec.VideoDuration)
+			rec.Action = fmt.Sprintf("padded audio with %.1fs of silence", -diff)
+		}
+
+	case LengthModeChapters:
+		shorter := min(rec.VideoDuration, rec.AudioDuration)
+		tail, extra := "Video only", "video"
+		if audioLonger {
+			tail, extra = "Audio only", "audio"
+		}
+		rec.Chapters = []Chapter{
+			{Title: "Video", StartTime: 0, EndTime: shorter},
+			{Title: tail, StartTime: shorter, EndTime: max(rec.VideoDuration, rec.AudioDuration)},
+		}
+		rec.Action = fmt.Sprintf("kept %.1fs of extra %s, marked with chapters", math.Abs(diff), extra)
+
+	default:
+		return nil, fmt.Errorf("unknown length mode %q", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// trimVideo cuts videoPath at duration seconds without re-encoding
+func trimVideo(ctx context.Context, videoPath, outputPath string, duration float64) error {
+	return runFFmpeg(ctx, "video trim",
+		"-y", "-i", videoPath,
+		"-map", "0", "-t", fmt.Sprintf("%.3f", duration), "-c", "copy",
+		outputPath)
+}
+
+// padVideo extends videoPath by seconds, repeating its last frame or with
+// black. The video is re-encoded; its audio is copied.
+func padVideo(ctx context.Context, videoPath, outputPath string, seconds float64, black bool) error {
+	filter := fmt.Sprintf("tpad=stop_mode=clone:stop_duration=%.3f", seconds)
+	if black {
+		filter = fmt.Sprintf("tpad=stop_mode=add:stop_duration=%.3f:color=black", seconds)
+	}
+	return runFFmpeg(ctx, "video padding",
+		"-y", "-i", videoPath,
+		"-map", "0:v:0", "-map", "0:a?",
+		"-vf", filter,
+		"-c:v", "libx264", "-crf", "18", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		outputPath)
+}
+
+// padAudio extends audioPath with silence to duration seconds, as FLAC
+func padAudio(ctx context.Context, audioPath, outputPath string, duration float64) error {
+	filter := fmt.Sprintf("apad=whole_dur=%.6f", duration)
+	args := []string{"-y", "-i", audioPath, "-af", filter}
+	args = append(args, flacEncodeArgs()...)
+	args = append(args, outputPath)
+	if err := runFFmpeg(ctx, "audio padding", args...); err != nil {
+		return err
+	}
+	info, err := GetMediaInfo(audioPath)
+	if err == nil && isLosslessCodec(info.AudioCodec) {
+		return verifyFLACEncode(ctx, audioPath, nil, filter, outputPath)
+	}
+	return nil
+}
+
+// runFFmpeg runs ffmpeg with args, reporting what failed as task
+func runFFmpeg(ctx context.Context, task string, args ...string) error {
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v - %s", task, err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateLengthMode(t *testing.T) {
+	for _, mode := range []string{"", LengthModeOff, LengthModeTrim, LengthModeFreeze, LengthModeBlack, LengthModeChapters} {
+		if err := validateLengthMode(mode); err != nil {
+			t.Errorf("validateLengthMode(%q) = %v", mode, err)
+		}
+	}
+	if err := validateLengthMode("stretch"); err == nil {
+		t.Error("validateLengthMode accepted an unknown mode")
+	}
+
+	q := newTestQueue()
+	if _, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc", LengthMode: "stretch"}); err == nil {
+		t.Error("AddToQueue accepted an unknown length mode")
+	}
+}
+
+func TestReconcileLength_Off(t *testing.T) {
+	// Nothing is probed with the mode off
+	rec, err := ReconcileLength(context.Background(), "/missing/video.mp4", "/missing/audio.flac", t.TempDir(), LengthModeOff)
+	if err != nil || rec.VideoPath != "/missing/video.mp4" || rec.AudioPath != "/missing/audio.flac" || rec.Action != "" {
+		t.Errorf("ReconcileLength(off) = %+v, %v", rec, err)
+	}
+}
+
+func TestReconcileLength(t *testing.T) {
+	if err := CheckFFmpegInstalled(); err != nil {
+		t.Skip("FFmpeg not installed")
+	}
+
+	tmpDir := t.TempDir()
+	videoPath := filepath.Join(tmpDir, "video.mkv")
+	if out, err := exec.Command(GetFFmpegPath(), "-y",
+		"-f", "lavfi", "-i", "testsrc=duration=3:size=320x240:rate=25",
+		"-c:v", "libx264", videoPath).CombinedOutput(); err != nil {
+		t.Fatalf("Could not create test video: %v - %s", err, out)
+	}
+	// An album version 5 s longer than the video
+	audioPath := filepath.Join(tmpDir, "audio.flac")
+	if out, err := exec.Command(GetFFmpegPath(), "-y",
+		"-f", "lavfi", "-i", "sine=frequency=440:duration=8",
+		"-c:a", "flac", audioPath).CombinedOutput(); err != nil {
+		t.Fatalf("Could not create test audio: %v - %s", err, out)
+	}
+
+	rec, err := ReconcileLength(context.Background(), videoPath, audioPath, tmpDir, LengthModeTrim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := GetMediaInfo(rec.AudioPath); err != nil || info.Duration > 3.5 {
+		t.Errorf("trimmed audio = %+v, %v, want the video length", info, err)
+	}
+
+	rec, err = ReconcileLength(context.Background(), videoPath, audioPath, tmpDir, LengthModeChapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.AudioPath != audioPath || len(rec.Chapters) != 2 || rec.Chapters[1].Title != "Audio only" {
+		t.Errorf("chapters = %+v", rec)
+	}
+
+	rec, err = ReconcileLength(context.Background(), videoPath, audioPath, tmpDir, LengthModeFreeze)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := GetMediaInfo(rec.VideoPath); err != nil || info.Duration < 7.5 {
+		t.Errorf("padded video = %+v, %v, want the audio length", info, err)
+	}
+}
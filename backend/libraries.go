@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Libraries
+// =============================================================================
+
+// Config.Libraries splits the output into named libraries by output mode,
+// e.g. ["MusicVideos:video=/media/music-videos",
+// "Music:audio=/media/music={albumartist}/{album}/{track} - {title}"]:
+// music videos (MKV) go to one directory and audio-only FLACs to another,
+// each with its own naming template (Config.NamingTemplate when none is
+// given). An item lands in the library of its output mode; a mode without a
+// library uses OutputDirectory, and per-artist overrides still win over the
+// library directory.
+//
+// The file index covers every library, but skip detection only looks at the
+// item's own (see IndexScope): the FLAC of a song in Music doesn't stop its
+// music video from being downloaded to MusicVideos.
+
+// Library is a named output directory for the items of one output mode
+type Library struct {
+	Name       string `json:"name"`
+	OutputMode string `json:"outputMode"` // OutputModeVideo or OutputModeAudio
+	Directory  string `json:"directory"`
+	Template   string `json:"template,omitempty"` // Naming template, "" = Config.NamingTemplate
+}
+
+// parseLibrary splits "name:mode=directory[=template]"
+func parseLibrary(entry string) (Library, bool) {
+	head, rest, ok := strings.Cut(entry, "=")
+	name, mode, hasMode := strings.Cut(head, ":")
+	dir, template, _ := strings.Cut(rest, "=")
+	lib := Library{
+		Name:       strings.TrimSpace(name),
+		OutputMode: strings.ToLower(strings.TrimSpace(mode)),
+		Directory:  strings.TrimSpace(dir),
+		Template:   strings.TrimSpace(template),
+	}
+	return lib, ok && hasMode && lib.Name != "" && lib.Directory != ""
+}
+
+// String returns the Config.Libraries entry of lib
+func (lib Library) String() string {
+	entry := lib.Name + ":" + lib.OutputMode + "=" + lib.Directory
+	if lib.Template != "" {
+		entry += "=" + lib.Template
+	}
+	return entry
+}
+
+// LibrariesFromConfig returns the configured libraries
+func LibrariesFromConfig(config *Config) []Library {
+	if config == nil {
+		return nil
+	}
+	var libraries []Library
+	for _, entry := range config.Libraries {
+		if lib, ok := parseLibrary(entry); ok {
+			libraries = append(libraries, lib)
+		}
+	}
+	return libraries
+}
+
+// itemOutputMode returns what item produces: audio for audio-only requests
+// and for items whose video couldn't be downloaded, else video
+func itemOutputMode(item *QueueItem) string {
+	if item != nil && (item.AudioOnly || item.OutputMode == OutputModeAudio) {
+		return OutputModeAudio
+	}
+	return OutputModeVideo
+}
+
+// LibraryFor returns the library item goes to, or nil when no library is
+// configured for its output mode
+func LibraryFor(config *Config, item *QueueItem) *Library {
+	mode := itemOutputMode(item)
+	for _, lib := range LibrariesFromConfig(config) {
+		if lib.OutputMode == mode {
+			return &lib
+		}
+	}
+	return nil
+}
+
+// IndexScope limits file index lookups to one library: files under the
+// directories of the other libraries are left out. Files outside every
+// library (OutputDirectory, per-artist overrides) are always in scope. The
+// zero value covers the whole index.
+type IndexScope struct {
+	exclude []string
+}
+
+// LibraryIndexScope returns the scope of the directory item goes to: its
+// library's, else OutputDirectory. Without libraries it is the whole index.
+func LibraryIndexScope(config *Config, item *QueueItem) IndexScope {
+	target := config.OutputDirectory
+	if lib := LibraryFor(config, item); lib != nil {
+		target = lib.Directory
+	} else if target == "" {
+		target = GetDefaultOutputDirectory()
+	}
+	var scope IndexScope
+	for _, lib := range LibrariesFromConfig(config) {
+		// OutputDirectory may well be one of the libraries
+		if !isWithin(lib.Directory, target) {
+			scope.exclude = append(scope.exclude, filepath.Clean(lib.Directory))
+		}
+	}
+	return scope
+}
+
+// contains reports whether path is in the scope
+func (s IndexScope) contains(path string) bool {
+	for _, dir := range s.exclude {
+		if isWithin(dir, path) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryFor(t *testing.T) {
+	config := &Config{
+		OutputDirectory: "/media/mv",
+		NamingTemplate:  "{artist}/{title}/{title}",
+		Libraries:       []string{"Music:audio=/media/music={albumartist}/{album}/{title}"},
+	}
+
+	audio := &QueueItem{OutputMode: OutputModeAudio}
+	if lib := LibraryFor(config, audio); lib == nil || lib.Name != "Music" || lib.Directory != "/media/music" {
+		t.Fatalf("LibraryFor(audio) = %+v", lib)
+	}
+	if got := planOutputDir(audio, &Metadata{Artist: "Björk"}, config); got != "/media/music" {
+		t.Errorf("audio planOutputDir = %q", got)
+	}
+	if got := itemNamingTemplate(audio, config); got != "{albumartist}/{album}/{title}" {
+		t.Errorf("audio template = %q", got)
+	}
+
+	// Items whose video failed end up as FLACs in the audio library too
+	if lib := LibraryFor(config, &QueueItem{AudioOnly: true}); lib == nil || lib.Name != "Music" {
+		t.Errorf("LibraryFor(audio only) = %+v", lib)
+	}
+
+	// No video library: music videos stay in OutputDirectory
+	video := &QueueItem{PlaylistName: "Mix"}
+	if lib := LibraryFor(config, video); lib != nil {
+		t.Errorf("LibraryFor(video) = %+v, want none", lib)
+	}
+	if got := planOutputDir(video, &Metadata{Artist: "Björk"}, config); got != filepath.Join("/media/mv", "Mix") {
+		t.Errorf("video planOutputDir = %q", got)
+	}
+	if got := itemNamingTemplate(video, config); got != config.NamingTemplate {
+		t.Errorf("video template = %q", got)
+	}
+
+	// The request's own template and artist overrides still win
+	if got := itemNamingTemplate(&QueueItem{OutputMode: OutputModeAudio, NamingTemplate: "{title}"}, config); got != "{title}" {
+		t.Errorf("item template = %q", got)
+	}
+	config.ArtistPathOverrides = []string{"Björk=/mnt/archive"}
+	if got := planOutputDir(audio, &Metadata{Artist: "Björk"}, config); got != "/mnt/archive" {
+		t.Errorf("override planOutputDir = %q", got)
+	}
+
+	if dirs := LibraryDirectories(config); len(dirs) != 3 || dirs[1] != "/media/music" || dirs[2] != "/mnt/archive" {
+		t.Errorf("LibraryDirectories = %v", dirs)
+	}
+}
+
+func TestFileIndex_LibraryScope(t *testing.T) {
+	root := t.TempDir()
+	videos, music := filepath.Join(root, "mv"), filepath.Join(root, "music")
+	mkv := filepath.Join(videos, "Artist", "Song.mkv")
+	flac := filepath.Join(music, "Artist", "Song.flac")
+	writeTestFile(t, mkv, 10)
+	writeTestFile(t, flac, 10)
+	config := &Config{
+		OutputDirectory: videos,
+		Libraries:       []string{"MusicVideos:video=" + videos, "Music:audio=" + music},
+	}
+
+	fi := NewFileIndex(t.TempDir())
+	fi.AddEntry(FileIndexEntry{Path: flac, Title: "Song", Artist: "Artist", YouTubeID: "dQw4w9WgXcQ"})
+
+	// The FLAC doesn't stop the music video from being downloaded
+	videoScope := LibraryIndexScope(config, &QueueItem{})
+	if got := fi.FindVideo(videoScope, "dQw4w9WgXcQ", "Song", "Artist", 0); got != nil {
+		t.Errorf("video lookup found %s in the audio library", got.Path)
+	}
+	audioScope := LibraryIndexScope(config, &QueueItem{OutputMode: OutputModeAudio})
+	if got := fi.FindVideo(audioScope, "dQw4w9WgXcQ", "Song", "Artist", 0); got == nil || got.Path != flac {
+		t.Errorf("audio lookup = %+v", got)
+	}
+
+	fi.AddEntry(FileIndexEntry{Path: mkv, Title: "Song", Artist: "Artist"})
+	if got := fi.FindVideo(videoScope, "", "Song", "Artist", 0); got == nil || got.Path != mkv {
+		t.Errorf("video lookup = %+v", got)
+	}
+
+	// Without libraries every file counts
+	if got := fi.FindVideo(LibraryIndexScope(&Config{OutputDirectory: videos}, &QueueItem{}), "dQw4w9WgXcQ", "", "", 0); got == nil || got.Path != flac {
+		t.Errorf("unscoped lookup = %+v", got)
+	}
+}
+
+func TestConfigValidate_Libraries(t *testing.T) {
+	c := GetDefaultConfig()
+	c.Libraries = []string{
+		" MusicVideos : Video = /media/mv ",
+		"Music:audio=/media/music=album",
+		"Other:audio=/media/other",      // Second audio library
+		"Nested:video=/media/music/mv",  // Inside Music
+		"Clips:clip=/media/clips",       // Unknown mode
+		"NoMode=/media/x",               // Malformed
+		"Bad:video=/media/bad={nothing", // Invalid template
+		"",
+	}
+	v := c.Validate()
+	want := []string{"MusicVideos:video=/media/mv", "Music:audio=/media/music={artist}/{album}/{title}"}
+	if len(c.Libraries) != 2 || c.Libraries[0] != want[0] || c.Libraries[1] != want[1] {
+		t.Errorf("libraries = %q, want %q", c.Libraries, want)
+	}
+	if !hasIssue(v.Warnings, "libraries") {
+		t.Error("expected libraries warnings")
+	}
+}
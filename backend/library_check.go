@@ -0,0 +1,472 @@
+package backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Library Consistency Check
+// =============================================================================
+
+// CheckLibrary verifies that every media file in the library has the sidecars
+// the pipeline would have written for it (NFO, poster, lyrics) and, in repair
+// mode, regenerates missing ones from the embedded tags, the embedded cover and
+// online lookups. Sidecars left behind by renamed files are re-attached when
+// the match is unambiguous; nothing is ever deleted.
+
+// Sidecar issue kinds
+const (
+	SidecarMissingNFO    = "missing_nfo"
+	SidecarStaleNFO      = "stale_nfo"
+	SidecarMissingPoster = "missing_poster"
+	SidecarMissingLyrics = "missing_lyrics"
+	SidecarOrphaned      = "orphaned" // Sidecar whose media file was renamed or removed
+)
+
+// SidecarIssue is one problem found by CheckLibrary
+type SidecarIssue struct {
+	MediaPath string `json:"mediaPath,omitempty"`
+	Path      string `json:"path"` // Sidecar path (expected or found)
+	Kind      string `json:"kind"`
+	Detail    string `json:"detail,omitempty"`
+	Fixed     bool   `json:"fixed"`
+	Error     string `json:"error,omitempty"` // Why a repair failed
+}
+
+// LibraryCheckReport summarizes a library consistency check
+type LibraryCheckReport struct {
+	Directory   string         `json:"directory"`
+	MediaFiles  int            `json:"mediaFiles"`
+	Issues      []SidecarIssue `json:"issues"`
+	Fixed       int            `json:"fixed"`
+	Repair      bool           `json:"repair"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+}
+
+// LibraryCheckOptions selects which sidecars are expected and whether to fix them
+type LibraryCheckOptions struct {
+	Repair  bool
+	NFO     bool
+	Poster  bool
+	Lyrics  bool     // Expect .lrc/.txt files
+	History *History // Optional: maps files back to their YouTube video for poster lookups
+}
+
+// LibraryCheckOptionsFromConfig expects the sidecars the current config would produce
+func LibraryCheckOptionsFromConfig(config *Config, repair bool) LibraryCheckOptions {
+	return LibraryCheckOptions{
+		Repair: repair,
+		NFO:    config.GenerateNFO,
+		Poster: true,
+		Lyrics: config.LyricsEnabled && LyricsEmbedMode(config.LyricsEmbedMode) != LyricsEmbedFile,
+	}
+}
+
+// sidecarSuffixes are the sidecar files checked for orphans
+var sidecarSuffixes = []string{".nfo", "-poster.jpg", ".lrc", ProvenanceSuffix}
+
+// sidecarBase strips a known sidecar suffix from path, returning "" for other files
+func sidecarBase(path string) string {
+	lower := strings.ToLower(path)
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return path[:len(path)-len(suffix)]
+		}
+	}
+	return ""
+}
+
+func sidecarSuffix(path string) string {
+	lower := strings.ToLower(path)
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+func mediaBase(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// sidecarPosterPath returns the poster path the processor writes next to a media file
+func sidecarPosterPath(mediaPath string) string {
+	return mediaBase(mediaPath) + "-poster.jpg"
+}
+
+// CheckLibrary scans dir for media files and checks their sidecars
+func CheckLibrary(dir string, opts LibraryCheckOptions) (*LibraryCheckReport, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read library directory: %w", err)
+	}
+
+	report := &LibraryCheckReport{
+		Directory:   dir,
+		Issues:      []SidecarIssue{},
+		Repair:      opts.Repair,
+		GeneratedAt: time.Now(),
+	}
+
+	mediaByDir := make(map[string][]string)
+	sidecarsByDir := make(map[string][]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		d := filepath.Dir(path)
+		if indexedExtensions[strings.ToLower(filepath.Ext(path))] {
+			mediaByDir[d] = append(mediaByDir[d], path)
+		} else if sidecarBase(path) != "" {
+			sidecarsByDir[d] = append(sidecarsByDir[d], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan library: %w", err)
+	}
+
+	videoIDs := make(map[string]string)
+	if opts.History != nil {
+		for _, entry := range opts.History.GetAll() {
+			if entry.OutputPath == "" || entry.VideoURL == "" {
+				continue
+			}
+			if id, err := ParseYouTubeURL(entry.VideoURL); err == nil {
+				videoIDs[entry.OutputPath] = id
+			}
+		}
+	}
+
+	// Re-attach orphaned sidecars first so the per-file checks see them
+	for d, sidecars := range sidecarsByDir {
+		report.checkOrphans(sidecars, mediaByDir[d], opts.Repair)
+	}
+
+	for _, files := range mediaByDir {
+		for _, path := range files {
+			report.MediaFiles++
+			report.checkMediaFile(path, videoIDs[path], opts)
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Path < report.Issues[j].Path
+	})
+	for _, issue := range report.Issues {
+		if issue.Fixed {
+			report.Fixed++
+		}
+	}
+	return report, nil
+}
+
+// CheckLibraries runs CheckLibrary over dirs (see LibraryDirectories) and
+// merges the reports under the first directory. Directories after the first
+// that don't exist yet are skipped.
+func CheckLibraries(dirs []string, opts LibraryCheckOptions) (*LibraryCheckReport, error) {
+	var merged *LibraryCheckReport
+	for i, dir := range outermostDirectories(dirs) {
+		if _, err := os.Stat(dir); err != nil && i > 0 {
+			continue
+		}
+		report, err := CheckLibrary(dir, opts)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = report
+			continue
+		}
+		merged.MediaFiles += report.MediaFiles
+		merged.Fixed += report.Fixed
+		merged.Issues = append(merged.Issues, report.Issues...)
+	}
+	return merged, nil
+}
+
+func (r *LibraryCheckReport) add(issue SidecarIssue, err error) {
+	if err != nil {
+		issue.Error = err.Error()
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// checkOrphans reports sidecars without a media file. When exactly one media
+// file in the same folder lacks that kind of sidecar, the media file was most
+// likely renamed and the sidecar is moved to match it.
+func (r *LibraryCheckReport) checkOrphans(sidecars, media []string, repair bool) {
+	bases := make(map[string]bool)
+	for _, m := range media {
+		bases[mediaBase(m)] = true
+	}
+
+	for _, sidecar := range sidecars {
+		if bases[sidecarBase(sidecar)] {
+			continue
+		}
+		suffix := sidecarSuffix(sidecar)
+		issue := SidecarIssue{Path: sidecar, Kind: SidecarOrphaned, Detail: "no media file with a matching name"}
+
+		var candidates []string
+		for _, m := range media {
+			if _, err := os.Stat(mediaBase(m) + suffix); os.IsNotExist(err) {
+				candidates = append(candidates, m)
+			}
+		}
+		if len(candidates) != 1 {
+			r.add(issue, nil)
+			continue
+		}
+
+		issue.MediaPath = candidates[0]
+		issue.Detail = "belongs to " + filepath.Base(candidates[0])
+		var err error
+		if repair {
+			target := mediaBase(candidates[0]) + suffix
+			if err = MoveFile(sidecar, target); err == nil {
+				issue.Fixed = true
+			}
+		}
+		r.add(issue, err)
+	}
+}
+
+func (r *LibraryCheckReport) checkMediaFile(path, videoID string, opts LibraryCheckOptions) {
+	metadata := readLibraryMetadata(path)
+	mediaInfo, _ := GetMediaInfo(path)
+	if mediaInfo != nil {
+		metadata.Duration = mediaInfo.Duration
+	}
+
+	nfoPath := GenerateNFOPath(path)
+	existing, nfoErr := readNFOFile(nfoPath)
+	if existing != nil {
+		for _, id := range existing.UniqueID {
+			if id.Type == "youtube" && videoID == "" {
+				videoID = id.Value
+			}
+		}
+	}
+	metadata.YouTubeID = videoID
+
+	if opts.NFO {
+		switch {
+		case os.IsNotExist(nfoErr):
+			issue := SidecarIssue{MediaPath: path, Path: nfoPath, Kind: SidecarMissingNFO}
+			var err error
+			if opts.Repair {
+				if err = WriteNFO(metadata, nfoPath, &NFOOptions{IncludeFileInfo: true, MediaInfo: mediaInfo}); err == nil {
+					issue.Fixed = true
+				}
+			}
+			r.add(issue, err)
+		case nfoErr != nil:
+			r.add(SidecarIssue{MediaPath: path, Path: nfoPath, Kind: SidecarStaleNFO, Detail: "unreadable NFO"}, nfoErr)
+		default:
+			if drift := nfoDrift(existing, metadata); len(drift) > 0 {
+				issue := SidecarIssue{MediaPath: path, Path: nfoPath, Kind: SidecarStaleNFO, Detail: strings.Join(drift, ", ")}
+				var err error
+				if opts.Repair {
+					if err = rewriteNFO(existing, metadata, nfoPath); err == nil {
+						issue.Fixed = true
+					}
+				}
+				r.add(issue, err)
+			}
+		}
+	}
+
+	if opts.Poster {
+		posterPath := sidecarPosterPath(path)
+		if _, err := os.Stat(posterPath); os.IsNotExist(err) {
+			issue := SidecarIssue{MediaPath: path, Path: posterPath, Kind: SidecarMissingPoster}
+			var err error
+			if opts.Repair {
+				if err = restorePoster(path, videoID, posterPath); err == nil {
+					issue.Fixed = true
+				}
+			}
+			r.add(issue, err)
+		}
+	}
+
+	if opts.Lyrics && !hasLyricsSidecar(path) {
+		issue := SidecarIssue{MediaPath: path, Path: mediaBase(path) + ".lrc", Kind: SidecarMissingLyrics}
+		var err error
+		if opts.Repair {
+			var saved string
+			if saved, err = restoreLyrics(path, metadata); err == nil {
+				issue.Path = saved
+				issue.Fixed = true
+			}
+		}
+		r.add(issue, err)
+	}
+}
+
+// readLibraryMetadata reads a media file's embedded tags, falling back to the
+// file name for title and artist
+func readLibraryMetadata(path string) *Metadata {
+	metadata := &Metadata{}
+	if tags := extractMKVTags(path); tags != nil {
+		metadata.Title = tags["title"]
+		metadata.Artist = tags["artist"]
+		metadata.AlbumArtist = tags["album_artist"]
+		metadata.Album = tags["album"]
+		metadata.Genre = tags["genre"]
+		if isrc := tags["isrc"]; isrc != "" {
+			metadata.ISRC = normalizeISRC(isrc)
+		}
+		if date := tags["date"]; len(date) >= 4 {
+			metadata.Year, _ = strconv.Atoi(date[:4])
+		}
+	}
+	if metadata.Title == "" || metadata.Artist == "" {
+		title, artist := ParseFilename(path)
+		if metadata.Title == "" {
+			metadata.Title = title
+		}
+		if metadata.Artist == "" {
+			metadata.Artist = artist
+		}
+	}
+	return metadata
+}
+
+func readNFOFile(path string) (*MusicVideoNFO, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nfo MusicVideoNFO
+	if err := xml.Unmarshal(data, &nfo); err != nil {
+		return nil, fmt.Errorf("failed to parse NFO: %w", err)
+	}
+	return &nfo, nil
+}
+
+// nfoDrift lists NFO fields that no longer match the media file
+func nfoDrift(nfo *MusicVideoNFO, metadata *Metadata) []string {
+	var drift []string
+	if metadata.Title != "" && nfo.Title != metadata.Title {
+		drift = append(drift, "title")
+	}
+	if metadata.Artist != "" && nfo.Artist != metadata.Artist {
+		drift = append(drift, "artist")
+	}
+	if metadata.Album != "" && nfo.Album != metadata.Album {
+		drift = append(drift, "album")
+	}
+	if metadata.ISRC != "" {
+		found := false
+		for _, id := range nfo.UniqueID {
+			if id.Type == "isrc" && normalizeISRC(id.Value) == metadata.ISRC {
+				found = true
+			}
+		}
+		if !found {
+			drift = append(drift, "isrc")
+		}
+	}
+	if metadata.Duration > 0 && math.Abs(float64(nfo.Runtime)-metadata.Duration/60) > 1 {
+		drift = append(drift, "runtime")
+	}
+	return drift
+}
+
+// rewriteNFO updates the drifted fields of an existing NFO, keeping everything
+// else (plot, tags, thumbs, date added) as it was
+func rewriteNFO(nfo *MusicVideoNFO, metadata *Metadata, nfoPath string) error {
+	if metadata.Title != "" {
+		nfo.Title = metadata.Title
+	}
+	if metadata.Artist != "" {
+		nfo.Artist = metadata.Artist
+	}
+	if metadata.Album != "" {
+		nfo.Album = metadata.Album
+	}
+	if metadata.Duration > 0 {
+		nfo.Runtime = int(metadata.Duration / 60)
+	}
+	if metadata.ISRC != "" {
+		ids := nfo.UniqueID[:0]
+		for _, id := range nfo.UniqueID {
+			if id.Type != "isrc" {
+				ids = append(ids, id)
+			}
+		}
+		nfo.UniqueID = append(ids, UniqueID{Type: "isrc", Value: metadata.ISRC})
+	}
+
+	output, err := xml.MarshalIndent(nfo, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate NFO: %w", err)
+	}
+	return WriteOutputFile(nfoPath, append([]byte(xml.Header), output...))
+}
+
+// restorePoster extracts the embedded cover art, or downloads the YouTube
+// thumbnail when the file has none
+func restorePoster(mediaPath, videoID, posterPath string) error {
+	err := extractCoverArt(mediaPath, posterPath)
+	if err == nil {
+		return nil
+	}
+	slog.Debug("no embedded cover to restore poster from", "path", mediaPath, "err", err)
+
+	if videoID == "" {
+		return fmt.Errorf("no embedded cover and no known YouTube video")
+	}
+	return DownloadPoster(youtubeThumbnailURL(videoID, "hqdefault"), posterPath)
+}
+
+// extractCoverArt writes a media file's attached picture to outputPath
+func extractCoverArt(mediaPath, outputPath string) error {
+	ffmpegPath := GetFFmpegPath()
+	if ffmpegPath == "" {
+		return fmt.Errorf("ffmpeg not found")
+	}
+
+	// 0:v selects all video streams, -0:V drops the ones that aren't attached pictures
+	cmd := newCommand(ffmpegPath, "-y", "-i", mediaPath, "-map", "0:v", "-map", "-0:V", "-frames:v", "1", "-q:v", "2", outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("failed to extract cover art: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+func hasLyricsSidecar(mediaPath string) bool {
+	for _, ext := range []string{".lrc", ".txt"} {
+		if _, err := os.Stat(mediaBase(mediaPath) + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreLyrics fetches lyrics online and saves them next to the media file
+func restoreLyrics(mediaPath string, metadata *Metadata) (string, error) {
+	if metadata.Artist == "" || metadata.Title == "" {
+		return "", fmt.Errorf("artist and title are unknown")
+	}
+	lyrics, err := FetchLyricsWithAlbum(metadata.Artist, metadata.Title, metadata.Album)
+	if err != nil {
+		return "", err
+	}
+	if lyrics.HasSync {
+		return SaveLRCFile(lyrics, mediaPath)
+	}
+	return SavePlainLyricsFile(lyrics, mediaPath)
+}
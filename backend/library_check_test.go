@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func issueKinds(report *LibraryCheckReport) map[string]int {
+	kinds := make(map[string]int)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+	}
+	return kinds
+}
+
+func TestCheckLibrary_ReportOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Artist - Song.mkv"), 10)
+
+	report, err := CheckLibrary(dir, LibraryCheckOptions{NFO: true, Poster: true, Lyrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := issueKinds(report)
+	if report.MediaFiles != 1 || kinds[SidecarMissingNFO] != 1 || kinds[SidecarMissingPoster] != 1 || kinds[SidecarMissingLyrics] != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Artist - Song.nfo")); !os.IsNotExist(err) {
+		t.Error("report-only check wrote an NFO")
+	}
+}
+
+func TestCheckLibrary_RepairNFO(t *testing.T) {
+	dir := t.TempDir()
+	media := filepath.Join(dir, "Artist - Song.mkv")
+	writeTestFile(t, media, 10)
+
+	report, err := CheckLibrary(dir, LibraryCheckOptions{NFO: true, Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Fixed != 1 {
+		t.Fatalf("expected the NFO to be regenerated, got %+v", report.Issues)
+	}
+	nfo, err := readNFOFile(GenerateNFOPath(media))
+	if err != nil || nfo.Title != "Song" || nfo.Artist != "Artist" {
+		t.Fatalf("regenerated NFO = %+v, %v", nfo, err)
+	}
+
+	// A drifted title is corrected without touching the other fields
+	nfo.Title = "Old Name"
+	nfo.Plot = "keep me"
+	if err := rewriteNFO(nfo, &Metadata{}, GenerateNFOPath(media)); err != nil {
+		t.Fatal(err)
+	}
+	report, _ = CheckLibrary(dir, LibraryCheckOptions{NFO: true, Repair: true})
+	if kinds := issueKinds(report); kinds[SidecarStaleNFO] != 1 || report.Fixed != 1 {
+		t.Fatalf("expected a fixed stale NFO, got %+v", report.Issues)
+	}
+	nfo, _ = readNFOFile(GenerateNFOPath(media))
+	if nfo.Title != "Song" || nfo.Plot != "keep me" {
+		t.Errorf("rewritten NFO = %+v", nfo)
+	}
+}
+
+func TestCheckLibrary_Orphans(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Artist - New Name.mkv"), 10)
+	writeTestFile(t, filepath.Join(dir, "Artist - Old Name-poster.jpg"), 10)
+	writeTestFile(t, filepath.Join(dir, "Artist - Old Name.lrc"), 10)
+
+	report, err := CheckLibrary(dir, LibraryCheckOptions{Poster: true, Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := issueKinds(report); kinds[SidecarOrphaned] != 2 || kinds[SidecarMissingPoster] != 0 || report.Fixed != 2 {
+		t.Fatalf("expected both sidecars re-attached, got %+v", report.Issues)
+	}
+	for _, name := range []string{"Artist - New Name-poster.jpg", "Artist - New Name.lrc"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not moved: %v", name, err)
+		}
+	}
+
+	// With two candidates the sidecar is only reported
+	writeTestFile(t, filepath.Join(dir, "Artist - Other.mkv"), 10)
+	writeTestFile(t, filepath.Join(dir, "Gone.nfo"), 10)
+	report, _ = CheckLibrary(dir, LibraryCheckOptions{Repair: true})
+	if len(report.Issues) != 1 || report.Issues[0].Fixed || !strings.HasSuffix(report.Issues[0].Path, "Gone.nfo") {
+		t.Errorf("ambiguous orphan should be left alone, got %+v", report.Issues)
+	}
+}
+
+func TestCheckLibraries_AllRoots(t *testing.T) {
+	videos := t.TempDir()
+	music := t.TempDir()
+	writeTestFile(t, filepath.Join(videos, "Artist - Song.mkv"), 10)
+	writeTestFile(t, filepath.Join(videos, "Nested", "Artist - Other.mkv"), 10)
+	writeTestFile(t, filepath.Join(music, "Artist - Song.flac"), 10)
+
+	// The nested directory is walked once, the missing one is skipped
+	dirs := []string{videos, filepath.Join(videos, "Nested"), music, filepath.Join(t.TempDir(), "missing")}
+	report, err := CheckLibraries(dirs, LibraryCheckOptions{NFO: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Directory != videos || report.MediaFiles != 3 || issueKinds(report)[SidecarMissingNFO] != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
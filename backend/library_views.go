@@ -0,0 +1,406 @@
+package backend
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Library views (link farms)
+// =============================================================================
+
+// A library view is a second directory tree that presents the library with
+// another naming template, e.g. "/mnt/views/by-year={year}/{artist} - {title}".
+// Every media file and its sidecars get a symlink (or hardlink) in each view,
+// so no data is duplicated. Completed downloads are linked right away and
+// SyncLibraryViews brings the views up to date after files were added,
+// renamed or deleted elsewhere.
+//
+// Each view directory holds a manifest of the links it created. Only those
+// links are ever removed, so files the user put in a view are left alone.
+
+// Link types for Config.LibraryViewLinks
+const (
+	ViewLinkSymlink  = "symlink"  // Works across filesystems; breaks if the library moves
+	ViewLinkHardlink = "hardlink" // Same filesystem only; survives renames in the library
+)
+
+// libraryViewManifest is the file in each view directory listing its links
+const libraryViewManifest = ".youflac-view.json"
+
+// libraryViewsMu serializes changes to the view directories and manifests
+var libraryViewsMu sync.Mutex
+
+// LibraryView is one configured view
+type LibraryView struct {
+	Directory string `json:"directory"`
+	Template  string `json:"template"`
+}
+
+// viewManifest records the links of one view by library file
+type viewManifest struct {
+	Template string              `json:"template"`
+	LinkType string              `json:"linkType"`
+	Links    map[string][]string `json:"links"` // Library file -> links (media file first, then sidecars)
+}
+
+// LibraryViewReport is the result of SyncLibraryViews
+type LibraryViewReport struct {
+	Views   int      `json:"views"`
+	Files   int      `json:"files"`   // Library files seen
+	Linked  int      `json:"linked"`  // Links created
+	Removed int      `json:"removed"` // Stale links removed
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// parseLibraryView splits "directory=template"
+func parseLibraryView(entry string) (LibraryView, bool) {
+	dir, template, ok := strings.Cut(entry, "=")
+	view := LibraryView{Directory: strings.TrimSpace(dir), Template: strings.TrimSpace(template)}
+	return view, ok && view.Directory != "" && view.Template != ""
+}
+
+// LibraryViewsFromConfig returns the configured views
+func LibraryViewsFromConfig(config *Config) []LibraryView {
+	if config == nil {
+		return nil
+	}
+	var views []LibraryView
+	for _, entry := range config.LibraryViews {
+		if view, ok := parseLibraryView(entry); ok {
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+// viewLinkType returns the configured link type
+func viewLinkType(config *Config) string {
+	if config.LibraryViewLinks == ViewLinkHardlink {
+		return ViewLinkHardlink
+	}
+	return ViewLinkSymlink
+}
+
+func loadViewManifest(dir string) *viewManifest {
+	manifest := &viewManifest{Links: map[string][]string{}}
+	data, err := os.ReadFile(filepath.Join(dir, libraryViewManifest))
+	if err != nil {
+		return manifest
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		slog.Warn("ignoring unreadable library view manifest", "dir", dir, "err", err)
+		return &viewManifest{Links: map[string][]string{}}
+	}
+	if manifest.Links == nil {
+		manifest.Links = map[string][]string{}
+	}
+	return manifest
+}
+
+func saveViewManifest(dir string, manifest *viewManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteOutputFile(filepath.Join(dir, libraryViewManifest), data)
+}
+
+// LinkIntoLibraryViews links a newly written library file (and its sidecars)
+// into every configured view. metadata may be nil; the tags are read then.
+func LinkIntoLibraryViews(config *Config, path string, metadata *Metadata) error {
+	views := LibraryViewsFromConfig(config)
+	if len(views) == 0 {
+		return nil
+	}
+	source, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if metadata == nil {
+		metadata = readViewMetadata(source)
+	}
+
+	libraryViewsMu.Lock()
+	defer libraryViewsMu.Unlock()
+
+	var firstErr error
+	for _, view := range views {
+		manifest := loadViewManifest(view.Directory)
+		removeViewLinks(manifest, source)
+		links, err := createViewLinks(view, viewLinkType(config), source, metadata)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if len(links) > 0 {
+			manifest.Links[source] = links
+		}
+		manifest.Template = view.Template
+		manifest.LinkType = viewLinkType(config)
+		if err := saveViewManifest(view.Directory, manifest); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to save view manifest: %w", err)
+		}
+	}
+	return firstErr
+}
+
+// RemoveFromLibraryViews removes the view links of a library file that was deleted
+func RemoveFromLibraryViews(config *Config, path string) {
+	source, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	libraryViewsMu.Lock()
+	defer libraryViewsMu.Unlock()
+	for _, view := range LibraryViewsFromConfig(config) {
+		manifest := loadViewManifest(view.Directory)
+		if removeViewLinks(manifest, source) > 0 {
+			if err := saveViewManifest(view.Directory, manifest); err != nil {
+				slog.Warn("failed to save view manifest", "dir", view.Directory, "err", err)
+			}
+		}
+	}
+}
+
+// SyncLibraryViews rebuilds every view from the library directories: new
+// files are linked, links of files that are gone are removed and a view
+// whose template or link type changed is rebuilt from scratch. Files that
+// are already linked are not probed again, so a sync of an unchanged
+// library only costs a directory walk.
+func SyncLibraryViews(config *Config) (*LibraryViewReport, error) {
+	views := LibraryViewsFromConfig(config)
+	report := &LibraryViewReport{Views: len(views)}
+	if len(views) == 0 {
+		return report, nil
+	}
+
+	sources, err := libraryMediaFiles(config, views)
+	if err != nil {
+		return nil, err
+	}
+	report.Files = len(sources)
+	current := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		current[source] = true
+	}
+
+	libraryViewsMu.Lock()
+	defer libraryViewsMu.Unlock()
+
+	linkType := viewLinkType(config)
+	metadataCache := make(map[string]*Metadata)
+	for _, view := range views {
+		manifest := loadViewManifest(view.Directory)
+		rebuild := manifest.Template != view.Template || manifest.LinkType != linkType
+
+		for source := range manifest.Links {
+			if rebuild || !current[source] || !viewLinksIntact(manifest.Links[source]) {
+				report.Removed += removeViewLinks(manifest, source)
+			}
+		}
+		for _, source := range sources {
+			if _, ok := manifest.Links[source]; ok {
+				continue
+			}
+			metadata, ok := metadataCache[source]
+			if !ok {
+				metadata = readViewMetadata(source)
+				metadataCache[source] = metadata
+			}
+			links, err := createViewLinks(view, linkType, source, metadata)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+			if len(links) > 0 {
+				manifest.Links[source] = links
+				report.Linked += len(links)
+			}
+		}
+
+		manifest.Template = view.Template
+		manifest.LinkType = linkType
+		if err := saveViewManifest(view.Directory, manifest); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to save view manifest in %s: %v", view.Directory, err))
+		}
+	}
+	return report, nil
+}
+
+// libraryMediaFiles returns the absolute paths of all media files in the
+// library directories, skipping the view directories themselves
+func libraryMediaFiles(config *Config, views []LibraryView) ([]string, error) {
+	skip := make(map[string]bool, len(views))
+	for _, view := range views {
+		if dir, err := filepath.Abs(view.Directory); err == nil {
+			skip[dir] = true
+		}
+	}
+
+	var files []string
+	for _, root := range LibraryDirectories(config) {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+		}
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				if skip[path] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Mode().IsRegular() && indexedExtensions[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// createViewLinks links source and its sidecars into view
+func createViewLinks(view LibraryView, linkType, source string, metadata *Metadata) ([]string, error) {
+	target := GenerateFilePath(metadata, view.Template, view.Directory, filepath.Ext(source))
+	if _, err := os.Lstat(target); err == nil {
+		target = ResolveConflict(target)
+	}
+	if err := MkdirOutput(filepath.Dir(target)); err != nil {
+		return nil, fmt.Errorf("failed to create view directory: %w", err)
+	}
+
+	var links []string
+	targetBase := mediaBase(target)
+	sourceBase := mediaBase(source)
+	for _, file := range outputFiles(source) {
+		link := targetBase + strings.TrimPrefix(file, sourceBase)
+		if err := linkFile(linkType, file, link); err != nil {
+			if file == source {
+				return nil, fmt.Errorf("failed to link %s: %w", source, err)
+			}
+			slog.Debug("failed to link sidecar into view", "file", file, "err", err)
+			continue
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// linkFile creates link pointing at file. A hardlink that fails (e.g. the
+// view is on another filesystem) falls back to a symlink.
+func linkFile(linkType, file, link string) error {
+	if linkType == ViewLinkHardlink {
+		err := os.Link(file, link)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("hardlink failed, using a symlink", "file", file, "err", err)
+	}
+	return os.Symlink(file, link)
+}
+
+// removeViewLinks deletes the links of source and drops it from the manifest
+func removeViewLinks(manifest *viewManifest, source string) int {
+	removed := 0
+	for _, link := range manifest.Links[source] {
+		if err := os.Remove(link); err == nil || os.IsNotExist(err) {
+			removed++
+			removeEmptyParents(filepath.Dir(link))
+		}
+	}
+	delete(manifest.Links, source)
+	return removed
+}
+
+// removeEmptyParents removes dir and its parents while they are empty; the
+// walk stops at the first non-empty directory (a view root always holds
+// its manifest)
+func removeEmptyParents(dir string) {
+	for {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// viewLinksIntact reports whether the media link of a manifest entry still exists
+func viewLinksIntact(links []string) bool {
+	if len(links) == 0 {
+		return false
+	}
+	_, err := os.Lstat(links[0])
+	return err == nil
+}
+
+// readViewMetadata reads the tags used by view templates from a library
+// file, falling back to the file name
+func readViewMetadata(path string) *Metadata {
+	metadata := &Metadata{}
+	if tags := extractMKVTags(path); tags != nil {
+		metadata.Title = tags["title"]
+		metadata.Artist = tags["artist"]
+		metadata.AlbumArtist = cmp.Or(tags["album_artist"], tags["albumartist"])
+		metadata.Album = tags["album"]
+		metadata.Genre = tags["genre"]
+		metadata.Year = leadingInt(cmp.Or(tags["date"], tags["year"]))
+		metadata.Track = leadingInt(cmp.Or(tags["track"], tags["tracknumber"]))
+		metadata.Disc = leadingInt(cmp.Or(tags["disc"], tags["discnumber"]))
+	}
+	if metadata.Title == "" || metadata.Artist == "" {
+		title, artist := ParseFilename(path)
+		metadata.Title = cmp.Or(metadata.Title, title)
+		metadata.Artist = cmp.Or(metadata.Artist, artist)
+	}
+	if metadata.Title == "" {
+		metadata.Title = filepath.Base(mediaBase(path))
+	}
+	return metadata
+}
+
+// leadingInt parses the number at the start of a tag like "2019-05-03" or "3/12"
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		s = s[:end]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// overlappingLibraryDirectory returns the library directory that contains
+// dir or lies inside it, or ""
+func overlappingLibraryDirectory(config *Config, dir string) string {
+	dir = filepath.Clean(dir)
+	for _, root := range LibraryDirectories(config) {
+		root = filepath.Clean(root)
+		if isWithin(root, dir) || isWithin(dir, root) {
+			return root
+		}
+	}
+	return ""
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncLibraryViews(t *testing.T) {
+	root := t.TempDir()
+	library := filepath.Join(root, "library")
+	viewDir := filepath.Join(root, "views", "by-artist")
+	song := filepath.Join(library, "Daft Punk - One More Time.flac")
+	writeTestFile(t, song, 16)
+	writeTestFile(t, filepath.Join(library, "Daft Punk - One More Time.lrc"), 4)
+
+	config := &Config{
+		OutputDirectory: library,
+		LibraryViews:    []string{viewDir + "={artist}/{title}"},
+	}
+	report, err := SyncLibraryViews(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 1 || report.Linked != 2 || len(report.Errors) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	link := filepath.Join(viewDir, "Daft Punk", "One More Time.flac")
+	if target, err := os.Readlink(link); err != nil || target != song {
+		t.Errorf("link = %q, %v", target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(viewDir, "Daft Punk", "One More Time.lrc")); err != nil {
+		t.Errorf("sidecar not linked: %v", err)
+	}
+
+	// Unchanged library: nothing to do
+	if report, _ := SyncLibraryViews(config); report.Linked != 0 || report.Removed != 0 {
+		t.Errorf("second sync = %+v", report)
+	}
+
+	// Template change rebuilds the view
+	config.LibraryViews = []string{viewDir + "={title}"}
+	if report, _ := SyncLibraryViews(config); report.Linked != 2 || report.Removed != 2 {
+		t.Errorf("rebuild = %+v", report)
+	}
+	if _, err := os.Lstat(filepath.Join(viewDir, "Daft Punk")); !os.IsNotExist(err) {
+		t.Errorf("old artist folder should be gone: %v", err)
+	}
+
+	// Files removed from the library lose their links; other files stay
+	foreign := filepath.Join(viewDir, "notes.txt")
+	writeTestFile(t, foreign, 1)
+	os.Remove(song)
+	if report, _ := SyncLibraryViews(config); report.Removed != 2 {
+		t.Errorf("cleanup = %+v", report)
+	}
+	if _, err := os.Lstat(filepath.Join(viewDir, "One More Time.flac")); !os.IsNotExist(err) {
+		t.Errorf("stale link kept: %v", err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("foreign file removed: %v", err)
+	}
+}
+
+func TestLinkIntoLibraryViews(t *testing.T) {
+	root := t.TempDir()
+	viewDir := filepath.Join(root, "by-year")
+	song := filepath.Join(root, "library", "a.mkv")
+	writeTestFile(t, song, 8)
+
+	config := &Config{
+		OutputDirectory:  filepath.Join(root, "library"),
+		LibraryViews:     []string{viewDir + "={year}/{artist} - {title}"},
+		LibraryViewLinks: ViewLinkHardlink,
+	}
+	metadata := &Metadata{Title: "Song", Artist: "Band", Year: 1999}
+	if err := LinkIntoLibraryViews(config, song, metadata); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(viewDir, "1999", "Band - Song.mkv")
+	info, err := os.Lstat(link)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("hardlink = %v, %v", info, err)
+	}
+
+	RemoveFromLibraryViews(config, song)
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("link kept after removal: %v", err)
+	}
+	if _, err := os.Stat(song); err != nil {
+		t.Errorf("library file removed: %v", err)
+	}
+}
+
+func TestConfigValidate_LibraryViews(t *testing.T) {
+	c := GetDefaultConfig()
+	c.OutputDirectory = "/srv/music"
+	c.LibraryViewLinks = "HARDLINK"
+	c.LibraryViews = []string{
+		"/srv/views/year = {year}/{title}",
+		"/srv/music/views={year}",
+		"/srv/views/none=static",
+		"missing-template",
+	}
+	v := c.Validate()
+	if len(c.LibraryViews) != 1 || c.LibraryViews[0] != "/srv/views/year={year}/{title}" {
+		t.Errorf("views = %q", c.LibraryViews)
+	}
+	if c.LibraryViewLinks != ViewLinkHardlink {
+		t.Errorf("link type = %q", c.LibraryViewLinks)
+	}
+	if len(v.Warnings) != 3 {
+		t.Errorf("warnings = %+v", v.Warnings)
+	}
+}
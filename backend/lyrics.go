@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -49,7 +48,8 @@ type lrclibSearchResult struct {
 
 // lyricsHTTPClient is a dedicated HTTP client for lyrics API calls
 var lyricsHTTPClient = &http.Client{
-	Timeout: 15 * time.Second,
+	Timeout:   15 * time.Second,
+	Transport: sharedTransport,
 }
 
 // FetchLyrics fetches lyrics from LRCLIB for a track
@@ -108,7 +108,6 @@ func FetchLyricsByDuration(artist, title, album string, durationSec int) (*Lyric
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "YouFlac/1.0 (https://github.com/youflac)")
 
 	resp, err := lyricsHTTPClient.Do(req)
 	if err != nil {
@@ -148,7 +147,6 @@ func searchLRCLIB(artist, title, album string) (*LyricsResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "YouFlac/1.0 (https://github.com/youflac)")
 
 	resp, err := lyricsHTTPClient.Do(req)
 	if err != nil {
@@ -211,7 +209,6 @@ func getLRCLIBDirect(artist, title, album string) (*LyricsResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "YouFlac/1.0 (https://github.com/youflac)")
 
 	resp, err := lyricsHTTPClient.Do(req)
 	if err != nil {
@@ -302,13 +299,8 @@ func SaveLRCFile(lyrics *LyricsResult, mediaFilePath string) (string, error) {
 	// Add synced lyrics
 	content.WriteString(lyrics.SyncedLyrics)
 
-	// Ensure parent directory exists
-	if err := os.MkdirAll(filepath.Dir(lrcPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Write file
-	if err := os.WriteFile(lrcPath, []byte(content.String()), 0644); err != nil {
+	// Write file (creating the parent directory if needed)
+	if err := WriteOutputFile(lrcPath, []byte(content.String())); err != nil {
 		return "", fmt.Errorf("failed to write LRC file: %w", err)
 	}
 
@@ -335,19 +327,15 @@ func SavePlainLyricsFile(lyrics *LyricsResult, mediaFilePath string) (string, er
 
 	content.WriteString(lyrics.PlainText)
 
-	if err := os.MkdirAll(filepath.Dir(txtPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	if err := os.WriteFile(txtPath, []byte(content.String()), 0644); err != nil {
+	if err := WriteOutputFile(txtPath, []byte(content.String())); err != nil {
 		return "", fmt.Errorf("failed to write lyrics file: %w", err)
 	}
 
 	return txtPath, nil
 }
 
-// EmbedLyricsInFile embeds lyrics into a media file using ffmpeg
-// Supports FLAC (vorbis comments) and MKV (subtitle track)
+// EmbedLyricsInFile embeds lyrics into a media file
+// Supports FLAC (vorbis comments, written natively) and MKV (subtitle track via ffmpeg)
 func EmbedLyricsInFile(mediaPath string, lyrics *LyricsResult) error {
 	ext := strings.ToLower(filepath.Ext(mediaPath))
 
@@ -363,11 +351,6 @@ func EmbedLyricsInFile(mediaPath string, lyrics *LyricsResult) error {
 
 // embedLyricsInFLAC adds lyrics as a FLAC vorbis comment
 func embedLyricsInFLAC(flacPath string, lyrics *LyricsResult) error {
-	ffmpegPath := GetFFmpegPath()
-
-	// Create temp file
-	tempPath := flacPath + ".tmp"
-
 	// Use the synced lyrics if available, otherwise plain
 	lyricsText := lyrics.SyncedLyrics
 	if lyricsText == "" {
@@ -378,6 +361,27 @@ func embedLyricsInFLAC(flacPath string, lyrics *LyricsResult) error {
 		return fmt.Errorf("no lyrics to embed")
 	}
 
+	tags := map[string][]string{"LYRICS": {lyricsText}}
+	// If we have synced lyrics, also add as UNSYNCEDLYRICS for compatibility
+	if lyrics.SyncedLyrics != "" && lyrics.PlainText != "" {
+		tags["UNSYNCEDLYRICS"] = []string{lyrics.PlainText}
+	}
+
+	err := FLACTagWriter{}.WriteTags(flacPath, tags)
+	if err == nil {
+		return nil
+	}
+	slog.Debug("native FLAC tag write failed, falling back to ffmpeg", "path", flacPath, "err", err)
+	return embedLyricsInFLACFFmpeg(flacPath, lyrics, lyricsText)
+}
+
+// embedLyricsInFLACFFmpeg re-muxes the FLAC with ffmpeg to add lyrics
+func embedLyricsInFLACFFmpeg(flacPath string, lyrics *LyricsResult, lyricsText string) error {
+	ffmpegPath := GetFFmpegPath()
+
+	// Create temp file
+	tempPath := flacPath + ".tmp"
+
 	// FFmpeg args to copy and add lyrics metadata
 	args := []string{
 		"-y",
@@ -395,7 +399,7 @@ func embedLyricsInFLAC(flacPath string, lyrics *LyricsResult) error {
 		)
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommand(ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -405,10 +409,11 @@ func embedLyricsInFLAC(flacPath string, lyrics *LyricsResult) error {
 	}
 
 	// Replace original
-	if err := os.Rename(tempPath, flacPath); err != nil {
+	if err := MoveFile(tempPath, flacPath); err != nil {
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
+	FinishOutputFile(flacPath)
 
 	return nil
 }
@@ -423,8 +428,12 @@ func embedLyricsInMKV(mkvPath string, lyrics *LyricsResult) error {
 	}
 
 	// Create temp SRT file
-	tempDir := os.TempDir()
-	srtPath := filepath.Join(tempDir, "lyrics.srt")
+	srtFile, err := os.CreateTemp(GetTempDirectory(), "lyrics-*.srt")
+	if err != nil {
+		return fmt.Errorf("failed to create SRT file: %w", err)
+	}
+	srtFile.Close()
+	srtPath := srtFile.Name()
 	defer os.Remove(srtPath)
 
 	var srtContent string
@@ -456,7 +465,7 @@ func embedLyricsInMKV(mkvPath string, lyrics *LyricsResult) error {
 		tempMKV,
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := newCommand(ffmpegPath, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -465,10 +474,11 @@ func embedLyricsInMKV(mkvPath string, lyrics *LyricsResult) error {
 		return fmt.Errorf("ffmpeg mux failed: %v - %s", err, stderr.String())
 	}
 
-	if err := os.Rename(tempMKV, mkvPath); err != nil {
+	if err := MoveFile(tempMKV, mkvPath); err != nil {
 		os.Remove(tempMKV)
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
+	FinishOutputFile(mkvPath)
 
 	return nil
 }
@@ -630,7 +640,7 @@ func HasLyrics(mediaPath string) (bool, error) {
 		mediaPath,
 	}
 
-	cmd := exec.Command(ffprobePath, args...)
+	cmd := newCommand(ffprobePath, args...)
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
@@ -670,7 +680,7 @@ func ExtractLyrics(mediaPath string) (*LyricsResult, error) {
 		mediaPath,
 	}
 
-	cmd := exec.Command(ffprobePath, args...)
+	cmd := newCommand(ffprobePath, args...)
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
@@ -0,0 +1,12 @@
+package backend
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The test servers listen on loopback, which the URL policy blocks
+	allowPrivateURLs = true
+	os.Exit(m.Run())
+}
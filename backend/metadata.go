@@ -16,6 +16,9 @@ func GetMKVMetadataArgs(metadata *Metadata) []string {
 	if metadata.Artist != "" {
 		args = append(args, "-metadata", fmt.Sprintf("artist=%s", metadata.Artist))
 	}
+	if metadata.AlbumArtist != "" {
+		args = append(args, "-metadata", fmt.Sprintf("album_artist=%s", metadata.AlbumArtist))
+	}
 	if metadata.Album != "" {
 		args = append(args, "-metadata", fmt.Sprintf("album=%s", metadata.Album))
 	}
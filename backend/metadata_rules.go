@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// Metadata rewrite rules
+// =============================================================================
+
+// Config.MetadataRules rewrites titles, artists and albums with regular
+// expressions before anything uses them, so matching, file names, tags,
+// lyrics lookups and NFOs all see the same cleaned-up names. An entry is
+// "[field:]pattern=replacement":
+//
+//	title:\s*\(Remastered( \d{4})?\)=
+//	artist:^(.+), The$=The $1
+//	\s*\[Official (Music )?Video\]=
+//
+// field is title, artist or album; without it the rule applies to all three.
+// The pattern uses Go's regexp syntax (add (?i) to ignore case) and cannot
+// contain "=" (write \x3D instead); the replacement may use $1 or ${name}.
+// Rules run in order, each on the result of the previous one. Runs of spaces
+// are collapsed afterwards, and a rule that would empty a field is ignored.
+
+// Fields a metadata rule can target
+const (
+	MetadataFieldTitle  = "title"
+	MetadataFieldArtist = "artist"
+	MetadataFieldAlbum  = "album"
+)
+
+var metadataRuleFields = []string{MetadataFieldTitle, MetadataFieldArtist, MetadataFieldAlbum}
+
+// spaceRunPattern matches runs of whitespace left behind by a rewrite
+var spaceRunPattern = regexp.MustCompile(`\s{2,}`)
+
+// MetadataRule is a compiled Config.MetadataRules entry
+type MetadataRule struct {
+	Entry       string   // As configured
+	Fields      []string // Fields the rule applies to
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// MetadataRewrite is the result of applying metadata rules
+type MetadataRewrite struct {
+	Title   string   `json:"title"`
+	Artist  string   `json:"artist"`
+	Album   string   `json:"album"`
+	Applied []string `json:"applied,omitempty"` // Rules that changed something, in order
+}
+
+// ParseMetadataRule compiles one "[field:]pattern=replacement" entry
+func ParseMetadataRule(entry string) (*MetadataRule, error) {
+	rule := &MetadataRule{Entry: entry, Fields: metadataRuleFields}
+	expr, replacement, ok := strings.Cut(entry, "=")
+	if !ok {
+		return nil, fmt.Errorf("expected [field:]pattern=replacement")
+	}
+	for _, field := range metadataRuleFields {
+		if rest, found := strings.CutPrefix(expr, field+":"); found {
+			rule.Fields = []string{field}
+			expr = rest
+			break
+		}
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	rule.Pattern = pattern
+	rule.Replacement = replacement
+	return rule, nil
+}
+
+// MetadataRulesFromConfig compiles Config.MetadataRules, skipping entries
+// that do not compile (Validate removes those)
+func MetadataRulesFromConfig(config *Config) []*MetadataRule {
+	if config == nil {
+		return nil
+	}
+	var rules []*MetadataRule
+	for _, entry := range config.MetadataRules {
+		if rule, err := ParseMetadataRule(entry); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// RewriteMetadata applies rules to a title, artist and album
+func RewriteMetadata(rules []*MetadataRule, title, artist, album string) MetadataRewrite {
+	result := MetadataRewrite{Title: title, Artist: artist, Album: album}
+	for _, rule := range rules {
+		changed := false
+		for _, field := range rule.Fields {
+			value := result.field(field)
+			if *value == "" {
+				continue
+			}
+			rewritten := rule.Pattern.ReplaceAllString(*value, rule.Replacement)
+			rewritten = strings.TrimSpace(spaceRunPattern.ReplaceAllString(rewritten, " "))
+			if rewritten != "" && rewritten != *value {
+				*value = rewritten
+				changed = true
+			}
+		}
+		if changed {
+			result.Applied = append(result.Applied, rule.Entry)
+		}
+	}
+	return result
+}
+
+func (r *MetadataRewrite) field(name string) *string {
+	switch name {
+	case MetadataFieldArtist:
+		return &r.Artist
+	case MetadataFieldAlbum:
+		return &r.Album
+	default:
+		return &r.Title
+	}
+}
+
+// TryMetadataRules compiles entries and applies them to a sample, for
+// checking rules before saving them. Entries that do not compile are
+// reported in errors and left out.
+func TryMetadataRules(entries []string, title, artist, album string) (MetadataRewrite, []string) {
+	var rules []*MetadataRule
+	var errors []string
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		rule, err := ParseMetadataRule(entry)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%q: %v", entry, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return RewriteMetadata(rules, title, artist, album), errors
+}
@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMetadataRule(t *testing.T) {
+	rule, err := ParseMetadataRule(`title:\s*\(Remastered( \d{4})?\)=`)
+	if err != nil {
+		t.Fatalf("ParseMetadataRule: %v", err)
+	}
+	if !slices.Equal(rule.Fields, []string{MetadataFieldTitle}) || rule.Replacement != "" {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	rule, err = ParseMetadataRule(`(?:feat\.|ft\.)=feat.`)
+	if err != nil {
+		t.Fatalf("ParseMetadataRule: %v", err)
+	}
+	if len(rule.Fields) != 3 || rule.Replacement != "feat." {
+		t.Errorf("rule without field = %+v, want all fields", rule)
+	}
+
+	for _, entry := range []string{"no separator", "title:=x", "title:(unclosed=x"} {
+		if _, err := ParseMetadataRule(entry); err == nil {
+			t.Errorf("ParseMetadataRule(%q) succeeded, want error", entry)
+		}
+	}
+}
+
+func TestRewriteMetadata(t *testing.T) {
+	var rules []*MetadataRule
+	for _, entry := range []string{
+		`title:\s*\(Remastered( \d{4})?\)=`,
+		`artist:^(.+), The$=The $1`,
+		`(?i)\[official (music )?video\]=`,
+		`album:^.*$=`, // Would empty the album: ignored
+	} {
+		rule, err := ParseMetadataRule(entry)
+		if err != nil {
+			t.Fatalf("ParseMetadataRule(%q): %v", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	got := RewriteMetadata(rules, "Money (Remastered 2011)  [Official Video]", "Beatles, The", "Abbey Road")
+	if got.Title != "Money" || got.Artist != "The Beatles" || got.Album != "Abbey Road" {
+		t.Errorf("RewriteMetadata = %+v", got)
+	}
+	if len(got.Applied) != 3 {
+		t.Errorf("Applied = %v, want the first three rules", got.Applied)
+	}
+
+	got = RewriteMetadata(rules, "Plain Title", "Artist", "")
+	if got.Title != "Plain Title" || len(got.Applied) != 0 {
+		t.Errorf("untouched metadata = %+v", got)
+	}
+}
+
+func TestTryMetadataRules(t *testing.T) {
+	got, errors := TryMetadataRules([]string{`title: \(Live\)=`, `title:(bad=`, ""}, "Song (Live)", "Artist", "")
+	if got.Title != "Song" {
+		t.Errorf("Title = %q, want Song", got.Title)
+	}
+	if len(errors) != 1 {
+		t.Errorf("errors = %v, want one", errors)
+	}
+}
+
+func TestConfigValidate_MetadataRules(t *testing.T) {
+	config := GetDefaultConfig()
+	config.MetadataRules = []string{`title:\s*\(Remastered\)=`, `title:(bad=`, " "}
+	validation := config.Validate()
+	if !hasIssue(validation.Warnings, "metadataRules") {
+		t.Errorf("expected a metadataRules warning, got %+v", validation.Warnings)
+	}
+	if len(config.MetadataRules) != 1 {
+		t.Errorf("MetadataRules = %v, want the valid rule only", config.MetadataRules)
+	}
+}
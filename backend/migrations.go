@@ -0,0 +1,186 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// =============================================================================
+// Data file migrations
+// =============================================================================
+
+// queue.json, history.json and fileindex.json carry a schemaVersion. Each
+// loader calls migrateForLoad first, which upgrades an older file in place
+// by running the ordered migrations of its schema on the raw JSON, so
+// fields the current structs don't know about survive the upgrade. The
+// original file is copied to <file>.v<version>-<time>.bak before anything
+// is changed.
+//
+// To change a format: bump the schema's Current, append a migration that
+// produces the new version and make the Go types match it.
+
+// dataSchema describes the versions of one data file
+type dataSchema struct {
+	Name       string
+	Current    int
+	Migrations []dataMigration // Sorted by Version
+}
+
+// dataMigration upgrades a document to Version. doc is the decoded JSON
+// (objects are map[string]any, numbers json.Number).
+type dataMigration struct {
+	Version     int
+	Description string
+	Migrate     func(doc any) (any, error)
+}
+
+// errNewerSchema is returned for files written by a newer version of the app
+var errNewerSchema = errors.New("data file was written by a newer version")
+
+// Schemas. Version 1 is every file written before schema versions existed.
+var (
+	queueSchema = &dataSchema{
+		Name:    "queue",
+		Current: 2,
+		Migrations: []dataMigration{
+			{Version: 2, Description: "add schemaVersion", Migrate: migrateAddSchemaVersion},
+		},
+	}
+	historySchema = &dataSchema{
+		Name:    "history",
+		Current: 2,
+		Migrations: []dataMigration{
+			{Version: 2, Description: "wrap the entry list in a versioned object", Migrate: migrateWrapEntries},
+		},
+	}
+	fileIndexSchema = &dataSchema{
+		Name:    "fileindex",
+		Current: 2,
+		Migrations: []dataMigration{
+			{Version: 2, Description: "wrap the entry list in a versioned object", Migrate: migrateWrapEntries},
+		},
+	}
+)
+
+// migrateAddSchemaVersion is the first migration of object files; the
+// version itself is set by migrateDataFile
+func migrateAddSchemaVersion(doc any) (any, error) {
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("expected a JSON object, got %T", doc)
+	}
+	return doc, nil
+}
+
+// migrateWrapEntries turns a bare JSON array into {"entries": [...]}
+func migrateWrapEntries(doc any) (any, error) {
+	switch d := doc.(type) {
+	case []any:
+		return map[string]any{"entries": d}, nil
+	case nil:
+		return map[string]any{"entries": []any{}}, nil
+	}
+	return nil, fmt.Errorf("expected a JSON array, got %T", doc)
+}
+
+// schemaVersionOf returns the schemaVersion of a decoded document (1 when absent)
+func schemaVersionOf(doc any) (int, error) {
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return 1, nil
+	}
+	raw, ok := obj["schemaVersion"]
+	if !ok {
+		return 1, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid schemaVersion %v", raw)
+	}
+	version, err := n.Int64()
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid schemaVersion %v", raw)
+	}
+	return int(version), nil
+}
+
+// migrateDataFile upgrades the file at path to schema.Current. A missing
+// file is fine; a damaged one is left alone for the loader to report.
+func migrateDataFile(path string, schema *dataSchema) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", schema.Name, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Keep large integers (sizes, timestamps) exact
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", schema.Name, err)
+	}
+
+	version, err := schemaVersionOf(doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", schema.Name, err)
+	}
+	if version == schema.Current {
+		return nil
+	}
+	if version > schema.Current {
+		return fmt.Errorf("%s schema %d (supported: %d): %w", schema.Name, version, schema.Current, errNewerSchema)
+	}
+
+	for _, m := range schema.Migrations {
+		if m.Version <= version {
+			continue
+		}
+		if doc, err = m.Migrate(doc); err != nil {
+			return fmt.Errorf("failed to migrate %s to schema %d (%s): %w", schema.Name, m.Version, m.Description, err)
+		}
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return fmt.Errorf("failed to migrate %s: migrations produced %T, not an object", schema.Name, doc)
+	}
+	obj["schemaVersion"] = schema.Current
+
+	migrated, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal migrated %s: %w", schema.Name, err)
+	}
+
+	backup := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+	if err := writeFileAtomic(backup, data, 0644); err != nil {
+		return fmt.Errorf("failed to back up %s before migrating: %w", schema.Name, err)
+	}
+	if err := writeFileAtomic(path, migrated, 0644); err != nil {
+		return fmt.Errorf("failed to write migrated %s: %w", schema.Name, err)
+	}
+	slog.Info("migrated data file", "file", schema.Name, "from", version, "to", schema.Current, "backup", backup)
+	return nil
+}
+
+// migrateForLoad runs migrateDataFile for a loader. Other failures are
+// logged and the loader reads the file as it is, but a file from a newer
+// version is refused: reading it and saving it back at the current schema
+// would drop the fields this version doesn't know. Loaders then keep the
+// returned error and fail every save with it, so the file stays untouched.
+func migrateForLoad(path string, schema *dataSchema) error {
+	err := migrateDataFile(path, schema)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, errNewerSchema) {
+		slog.Error("refusing to load data file from a newer version", "path", path, "err", err)
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	slog.Warn("data file migration failed", "path", path, "err", err)
+	return nil
+}
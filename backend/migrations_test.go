@@ -0,0 +1,147 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateDataFile_History(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.json")
+	legacy := `[{"id": "a", "title": "Song", "fileSize": 9007199254740993, "futureField": {"x": 1}}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateDataFile(path, historySchema); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var doc struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		Entries       []json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != historySchema.Current || len(doc.Entries) != 1 {
+		t.Fatalf("migrated = %s", data)
+	}
+	entry := string(doc.Entries[0])
+	if !strings.Contains(entry, `"futureField"`) || !strings.Contains(entry, "9007199254740993") {
+		t.Errorf("fields lost in migration: %s", entry)
+	}
+
+	backups, _ := filepath.Glob(path + ".v1-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+	if backup, _ := os.ReadFile(backups[0]); string(backup) != legacy {
+		t.Errorf("backup = %s", backup)
+	}
+
+	// Already current: nothing is rewritten
+	if err := migrateDataFile(path, historySchema); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := filepath.Glob(path + ".v*.bak"); len(backups) != 1 {
+		t.Errorf("second run made a backup: %v", backups)
+	}
+}
+
+func TestMigrateDataFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if err := migrateDataFile(filepath.Join(dir, "missing.json"), queueSchema); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+
+	newer := filepath.Join(dir, "queue.json")
+	os.WriteFile(newer, []byte(`{"schemaVersion": 99, "items": []}`), 0644)
+	if err := migrateDataFile(newer, queueSchema); !errors.Is(err, errNewerSchema) {
+		t.Errorf("newer schema err = %v", err)
+	}
+
+	wrongShape := filepath.Join(dir, "history.json")
+	os.WriteFile(wrongShape, []byte(`"text"`), 0644)
+	if err := migrateDataFile(wrongShape, historySchema); err == nil {
+		t.Error("expected an error for a string document")
+	}
+	if data, _ := os.ReadFile(wrongShape); string(data) != `"text"` {
+		t.Errorf("failed migration changed the file: %s", data)
+	}
+}
+
+func TestFileIndexLoad_LegacyFormat(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"path": "/music/a.mkv", "title": "Song", "artist": "Band", "size": 1}]`
+	os.WriteFile(filepath.Join(dir, "fileindex.json"), []byte(legacy), 0644)
+
+	fi := NewFileIndex(dir)
+	if err := fi.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Count() != 1 {
+		t.Errorf("count = %d", fi.Count())
+	}
+}
+
+func TestQueueLoad_LegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	os.WriteFile(path, []byte(`{"items": [{"id": "x", "status": "complete", "playlistPosition": 3}], "updatedAt": "2025-01-01T00:00:00Z"}`), 0644)
+
+	q := newTestQueue()
+	if err := q.loadQueueFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem("x"); item == nil || item.PlaylistPosition != 3 {
+		t.Fatalf("item = %+v", item)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"schemaVersion": 2`) {
+		t.Errorf("queue file not migrated: %s", data)
+	}
+}
+
+func TestLoad_NewerSchemaIsNotOverwritten(t *testing.T) {
+	dir := t.TempDir()
+	newer := `{"schemaVersion": 99, "items": [], "entries": [], "futureField": true}`
+
+	queuePath := filepath.Join(dir, "queue.json")
+	os.WriteFile(queuePath, []byte(newer), 0644)
+	q := newTestQueue()
+	if err := q.loadQueueFile(queuePath); !errors.Is(err, errNewerSchema) {
+		t.Errorf("queue load err = %v", err)
+	}
+	q.AddToQueue(DownloadRequest{})
+	if err := q.saveQueueFile(queuePath); !errors.Is(err, errNewerSchema) {
+		t.Errorf("queue save err = %v", err)
+	}
+
+	fi := NewFileIndex(dir)
+	os.WriteFile(filepath.Join(dir, "fileindex.json"), []byte(newer), 0644)
+	if err := fi.Load(); !errors.Is(err, errNewerSchema) {
+		t.Errorf("file index load err = %v", err)
+	}
+	fi.AddEntry(FileIndexEntry{Path: "/music/a.mkv", Title: "Song", Artist: "Band"})
+	if err := fi.Save(); !errors.Is(err, errNewerSchema) {
+		t.Errorf("file index save err = %v", err)
+	}
+
+	h := &History{filePath: filepath.Join(dir, "history.json")}
+	os.WriteFile(h.filePath, []byte(newer), 0644)
+	h.load()
+	if err := h.Add(HistoryEntry{Title: "Song"}); !errors.Is(err, errNewerSchema) {
+		t.Errorf("history add err = %v", err)
+	}
+
+	for _, name := range []string{"queue.json", "fileindex.json", "history.json"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != newer {
+			t.Errorf("%s was rewritten: %s", name, data)
+		}
+	}
+}
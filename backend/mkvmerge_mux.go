@@ -0,0 +1,324 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mkvmerge muxing backend. Compared to ffmpeg it writes track names, language
+// and default/forced flags, attachments and global tags in a single pass.
+
+// Mux backends accepted in Config.MuxBackend
+const (
+	MuxBackendFFmpeg   = "ffmpeg"
+	MuxBackendMKVMerge = "mkvmerge"
+)
+
+// DefaultVideoTrackName is the track name given to the video stream
+const DefaultVideoTrackName = "Official Video"
+
+// GetMKVMergePath returns path to mkvmerge binary
+func GetMKVMergePath() string {
+	bundledPaths := []string{
+		filepath.Join(getAppDataDir(), "bin", "mkvmerge"),
+		filepath.Join(getAppDataDir(), "bin", "mkvmerge.exe"),
+	}
+
+	for _, p := range bundledPaths {
+		if fileExists(p) {
+			return p
+		}
+	}
+
+	if path, err := exec.LookPath("mkvmerge"); err == nil {
+		return path
+	}
+
+	return "mkvmerge"
+}
+
+// CheckMKVMergeInstalled verifies mkvmerge is available
+func CheckMKVMergeInstalled() error {
+	cmd := newCommand(GetMKVMergePath(), "--version")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mkvmerge not found or not executable: %w", err)
+	}
+	return nil
+}
+
+// AudioTrackName builds a descriptive track name such as "FLAC 24/96"
+func AudioTrackName(info *MediaInfo) string {
+	if info == nil || info.AudioCodec == "" {
+		return ""
+	}
+
+	name := strings.ToUpper(info.AudioCodec)
+	if info.SampleRate <= 0 {
+		return name
+	}
+
+	rate := fmt.Sprintf("%g", float64(info.SampleRate)/1000)
+	if info.BitDepth > 0 {
+		name += fmt.Sprintf(" %d/%s", info.BitDepth, rate)
+	} else {
+		name += " " + rate + "kHz"
+	}
+	if info.Channels > 2 {
+		name += fmt.Sprintf(" %dch", info.Channels)
+	}
+	return name
+}
+
+// mkvmergeIdentify is the subset of `mkvmerge -J` output we need
+type mkvmergeIdentify struct {
+	Tracks []struct {
+		ID   int    `json:"id"`
+		Type string `json:"type"` // "video", "audio", "subtitles"
+	} `json:"tracks"`
+}
+
+// identifyMKVMergeTracks returns the first track ID of each type ("video", "audio")
+func identifyMKVMergeTracks(mkvmergePath, path string) (map[string]int, error) {
+	cmd := newCommand(mkvmergePath, "-J", path)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("mkvmerge identify failed: %v - %s", err, stderr.String())
+	}
+
+	var ident mkvmergeIdentify
+	if err := json.Unmarshal(stdout.Bytes(), &ident); err != nil {
+		return nil, fmt.Errorf("failed to parse mkvmerge identify output: %w", err)
+	}
+
+	ids := make(map[string]int)
+	for _, t := range ident.Tracks {
+		if _, ok := ids[t.Type]; !ok {
+			ids[t.Type] = t.ID
+		}
+	}
+	return ids, nil
+}
+
+// Matroska global tags XML (mkvmerge --global-tags)
+type mkvTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	Tag     struct {
+		Targets struct {
+			TargetTypeValue int `xml:"TargetTypeValue"`
+		} `xml:"Targets"`
+		Simple []mkvSimpleTag `xml:"Simple"`
+	} `xml:"Tag"`
+}
+
+type mkvSimpleTag struct {
+	Name   string `xml:"Name"`
+	String string `xml:"String"`
+}
+
+// writeMKVTagsFile writes metadata as a Matroska tags XML file
+func writeMKVTagsFile(metadata map[string]string) (string, error) {
+	var tags mkvTags
+	tags.Tag.Targets.TargetTypeValue = 50 // album/movie level
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if metadata[k] == "" {
+			continue
+		}
+		tags.Tag.Simple = append(tags.Tag.Simple, mkvSimpleTag{Name: strings.ToUpper(k), String: metadata[k]})
+	}
+	if len(tags.Tag.Simple) == 0 {
+		return "", nil
+	}
+
+	data, err := xml.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	f, err := os.CreateTemp(GetTempDirectory(), "youflac-tags-*.xml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create tags file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(xml.Header + string(data)); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write tags file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// mkvmergeInputs holds the resolved inputs for a mkvmerge run
+type mkvmergeInputs struct {
+	VideoPath    string
+	VideoTrackID int
+	AudioTrackID int // original audio in the video file, used with KeepOriginalAudio
+	AudioPath    string
+	TagsPath     string
+	SyncMs       int64 // delay applied to the FLAC track
+}
+
+// buildMKVMergeArgs builds the mkvmerge command line
+func buildMKVMergeArgs(outputPath string, in mkvmergeInputs, opts MuxOptions) []string {
+	args := []string{"-o", outputPath}
+
+	if title := opts.Metadata["title"]; title != "" {
+		args = append(args, "--title", title)
+	}
+	if in.TagsPath != "" {
+		args = append(args, "--global-tags", in.TagsPath)
+	}
+
+	lang := opts.AudioLanguage
+	if lang == "" {
+		lang = "und"
+	}
+
+	// Video input: keep only the video track (plus its audio in dual-audio mode)
+	videoName := opts.VideoTrackName
+	if videoName == "" {
+		videoName = DefaultVideoTrackName
+	}
+	vid := in.VideoTrackID
+	args = append(args,
+		"--video-tracks", fmt.Sprint(vid),
+		"--no-subtitles", "--no-attachments", "--no-chapters", "--no-global-tags",
+		"--track-name", fmt.Sprintf("%d:%s", vid, videoName),
+		"--default-track", fmt.Sprintf("%d:yes", vid),
+	)
+	if opts.KeepOriginalAudio {
+		aid := in.AudioTrackID
+		args = append(args,
+			"--audio-tracks", fmt.Sprint(aid),
+			"--language", fmt.Sprintf("%d:%s", aid, lang),
+			"--track-name", fmt.Sprintf("%d:%s", aid, OriginalAudioTrackName),
+			"--default-track", fmt.Sprintf("%d:no", aid),
+		)
+	} else {
+		args = append(args, "--no-audio")
+	}
+	args = append(args, in.VideoPath)
+
+	// FLAC input: default audio track
+	args = append(args, "--language", "0:"+lang)
+	if opts.AudioTrackName != "" {
+		args = append(args, "--track-name", "0:"+opts.AudioTrackName)
+	}
+	args = append(args,
+		"--default-track", "0:yes",
+		"--forced-track", "0:no",
+	)
+	if in.SyncMs != 0 {
+		args = append(args, "--sync", fmt.Sprintf("0:%d", in.SyncMs))
+	}
+	args = append(args, in.AudioPath)
+
+	// Surround input: extra non-default track after the FLAC
+	hasSurround := opts.SurroundAudioPath != "" && fileExists(opts.SurroundAudioPath)
+	if hasSurround {
+		args = append(args,
+			"--language", "0:"+lang,
+			"--track-name", "0:"+SurroundTrackName,
+			"--default-track", "0:no",
+			opts.SurroundAudioPath,
+		)
+	}
+
+	// Keep the FLAC ahead of the other audio tracks so players pick it first
+	if opts.KeepOriginalAudio || hasSurround {
+		order := []string{fmt.Sprintf("0:%d", vid), "1:0"}
+		if hasSurround {
+			order = append(order, "2:0")
+		}
+		if opts.KeepOriginalAudio {
+			order = append(order, fmt.Sprintf("0:%d", in.AudioTrackID))
+		}
+		args = append(args, "--track-order", strings.Join(order, ","))
+	}
+
+	if opts.CoverArtPath != "" && fileExists(opts.CoverArtPath) {
+		mime := "image/jpeg"
+		name := "cover.jpg"
+		if strings.EqualFold(filepath.Ext(opts.CoverArtPath), ".png") {
+			mime = "image/png"
+			name = "cover.png"
+		}
+		args = append(args,
+			"--attachment-mime-type", mime,
+			"--attachment-name", name,
+			"--attach-file", opts.CoverArtPath,
+		)
+	}
+
+	return args
+}
+
+// muxWithMKVMerge muxes video + FLAC with mkvmerge. itsOffset (seconds) delays the FLAC.
+func muxWithMKVMerge(videoPath, audioPath, outputPath string, opts MuxOptions, itsOffset float64) error {
+	if !opts.Overwrite && fileExists(outputPath) {
+		return fmt.Errorf("output file already exists: %s", outputPath)
+	}
+
+	mkvmergePath := GetMKVMergePath()
+	ids, err := identifyMKVMergeTracks(mkvmergePath, videoPath)
+	if err != nil {
+		return err
+	}
+	videoID, ok := ids["video"]
+	if !ok {
+		return fmt.Errorf("no video track found in %s", videoPath)
+	}
+	audioID, hasAudio := ids["audio"]
+	if opts.KeepOriginalAudio && !hasAudio {
+		opts.KeepOriginalAudio = false
+	}
+
+	tagsPath, err := writeMKVTagsFile(opts.Metadata)
+	if err != nil {
+		return err
+	}
+	if tagsPath != "" {
+		defer os.Remove(tagsPath)
+	}
+
+	args := buildMKVMergeArgs(outputPath, mkvmergeInputs{
+		VideoPath:    videoPath,
+		VideoTrackID: videoID,
+		AudioTrackID: audioID,
+		AudioPath:    audioPath,
+		TagsPath:     tagsPath,
+		SyncMs:       int64(itsOffset * 1000),
+	}, opts)
+
+	cmd := newCommand(mkvmergePath, args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout // mkvmerge reports errors on stdout
+
+	if err := cmd.Run(); err != nil {
+		// Exit code 1 means warnings only; the output file is complete
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && fileExists(outputPath) {
+			return nil
+		}
+		os.Remove(outputPath)
+		return fmt.Errorf("mkvmerge failed: %v - %s", err, stdout.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAudioTrackName(t *testing.T) {
+	tests := []struct {
+		info *MediaInfo
+		want string
+	}{
+		{&MediaInfo{AudioCodec: "flac", SampleRate: 96000, BitDepth: 24, Channels: 2}, "FLAC 24/96"},
+		{&MediaInfo{AudioCodec: "flac", SampleRate: 44100, BitDepth: 16, Channels: 2}, "FLAC 16/44.1"},
+		{&MediaInfo{AudioCodec: "opus", SampleRate: 48000, Channels: 2}, "OPUS 48kHz"},
+		{&MediaInfo{AudioCodec: "eac3", SampleRate: 48000, Channels: 6}, "EAC3 48kHz 6ch"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := AudioTrackName(tt.info); got != tt.want {
+			t.Errorf("AudioTrackName() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestBuildMKVMergeArgs(t *testing.T) {
+	opts := DefaultMuxOptions()
+	opts.Metadata["title"] = "Song"
+	opts.AudioLanguage = "eng"
+	opts.AudioTrackName = "FLAC 24/96"
+
+	args := buildMKVMergeArgs("/out/song.mkv", mkvmergeInputs{
+		VideoPath:    "/tmp/video.mp4",
+		VideoTrackID: 1,
+		AudioPath:    "/tmp/audio.flac",
+		SyncMs:       120,
+	}, opts)
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"-o /out/song.mkv",
+		"--title Song",
+		"--video-tracks 1",
+		"--no-audio /tmp/video.mp4",
+		"--track-name 1:Official Video",
+		"--language 0:eng",
+		"--track-name 0:FLAC 24/96",
+		"--default-track 0:yes",
+		"--sync 0:120 /tmp/audio.flac",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected args to contain %q, got: %s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "--attach-file") {
+		t.Error("no attachment expected without cover art")
+	}
+}
+
+func TestBuildMKVMergeArgs_KeepOriginalAudio(t *testing.T) {
+	opts := DefaultMuxOptions()
+	opts.KeepOriginalAudio = true
+
+	args := buildMKVMergeArgs("/out/song.mkv", mkvmergeInputs{
+		VideoPath:    "/tmp/video.mp4",
+		VideoTrackID: 0,
+		AudioTrackID: 1,
+		AudioPath:    "/tmp/audio.flac",
+	}, opts)
+	joined := strings.Join(args, " ")
+
+	if strings.Contains(joined, "--no-audio") {
+		t.Error("original audio should not be dropped in dual-audio mode")
+	}
+	for _, want := range []string{
+		"--audio-tracks 1",
+		"--track-name 1:" + OriginalAudioTrackName,
+		"--default-track 1:no",
+		"--track-order 0:0,1:0,0:1",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected args to contain %q, got: %s", want, joined)
+		}
+	}
+}
+
+func TestBuildMKVMergeArgs_Surround(t *testing.T) {
+	surround := filepath.Join(t.TempDir(), "surround.mp4")
+	os.WriteFile(surround, []byte("eac3"), 0644)
+
+	opts := DefaultMuxOptions()
+	opts.SurroundAudioPath = surround
+
+	args := buildMKVMergeArgs("/out/song.mkv", mkvmergeInputs{
+		VideoPath:    "/tmp/video.mp4",
+		VideoTrackID: 0,
+		AudioPath:    "/tmp/audio.flac",
+	}, opts)
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"--track-name 0:" + SurroundTrackName + " --default-track 0:no " + surround,
+		"--track-order 0:0,1:0,2:0",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected args to contain %q, got: %s", want, joined)
+		}
+	}
+}
+
+func TestWriteMKVTagsFile(t *testing.T) {
+	path, err := writeMKVTagsFile(map[string]string{"title": "A & B", "album_artist": "A", "genre": ""})
+	if err != nil {
+		t.Fatalf("writeMKVTagsFile failed: %v", err)
+	}
+	defer os.Remove(path)
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	if !strings.Contains(content, "<Name>TITLE</Name>") || !strings.Contains(content, "A &amp; B") {
+		t.Errorf("unexpected tags XML: %s", content)
+	}
+	if strings.Contains(content, "GENRE") {
+		t.Error("empty values should be skipped")
+	}
+
+	if path, _ := writeMKVTagsFile(nil); path != "" {
+		t.Error("expected no tags file for empty metadata")
+	}
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// Cross-device moves. os.Rename fails with EXDEV when source and destination
+// are on different filesystems (temp dir on a local disk, library on an NFS
+// or SMB mount). MoveFile falls back to copying into a temp file next to the
+// destination, verifying its size, renaming it into place and only then
+// removing the source.
+
+// renameFile is os.Rename, swapped out in tests to simulate EXDEV
+var renameFile = os.Rename
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, the Windows equivalent of EXDEV
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDevice reports whether err is a rename failure across filesystems
+func isCrossDevice(err error) bool {
+	if errors.Is(err, syscall.EXDEV) {
+		return true
+	}
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && errno == errorNotSameDevice
+}
+
+// MoveFile renames src to dst, copying across filesystems when needed. dst
+// is replaced if it exists.
+func MoveFile(src, dst string) error {
+	err := renameFile(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	if err := copyFileVerified(src, dst); err != nil {
+		return fmt.Errorf("failed to move %s across filesystems: %w", filepath.Base(src), err)
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove %s after copying: %w", src, err)
+	}
+	return nil
+}
+
+// copyFileVerified copies src into a temp file in dst's directory, checks the
+// copied size and renames it over dst, so dst never holds a partial file
+func copyFileVerified(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	stat, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.part")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	n, err := io.Copy(tmp, in)
+	if err != nil {
+		return fail(err)
+	}
+	if n != stat.Size() {
+		return fail(fmt.Errorf("short copy: %d of %d bytes", n, stat.Size()))
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	os.Chmod(tmpPath, stat.Mode().Perm())
+	os.Chtimes(tmpPath, stat.ModTime(), stat.ModTime())
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
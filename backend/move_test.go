@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMoveFile_SameDevice(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.flac")
+	dst := filepath.Join(dir, "b.flac")
+	writeTestFile(t, src, 128)
+
+	if err := MoveFile(src, dst); err != nil {
+		t.Fatalf("MoveFile: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source should be gone")
+	}
+	if info, err := os.Stat(dst); err != nil || info.Size() != 128 {
+		t.Errorf("destination = %v, %v", info, err)
+	}
+}
+
+func TestMoveFile_CrossDeviceFallback(t *testing.T) {
+	renameFile = func(src, dst string) error {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: syscall.EXDEV}
+	}
+	defer func() { renameFile = os.Rename }()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tmp", "out.mkv")
+	dst := filepath.Join(dir, "library", "out.mkv")
+	data := bytes.Repeat([]byte("youflac"), 1000)
+	os.MkdirAll(filepath.Dir(src), 0755)
+	os.MkdirAll(filepath.Dir(dst), 0755)
+	os.WriteFile(src, data, 0640)
+	os.WriteFile(dst, []byte("old"), 0644)
+
+	if err := MoveFile(src, dst); err != nil {
+		t.Fatalf("MoveFile: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("source should be removed after the copy")
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("destination content mismatch (%d bytes, %v)", len(got), err)
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dst))
+	if len(entries) != 1 {
+		t.Errorf("leftover temp files: %v", entries)
+	}
+}
+
+func TestMoveFile_OtherErrorsAreReturned(t *testing.T) {
+	dir := t.TempDir()
+	err := MoveFile(filepath.Join(dir, "missing"), filepath.Join(dir, "dst"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want not-exist", err)
+	}
+}
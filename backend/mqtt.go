@@ -0,0 +1,437 @@
+package backend
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// MQTT
+// =============================================================================
+
+// MQTT mirrors queue events and notifications to a broker for home
+// automation (e.g. Home Assistant). It speaks the small part of MQTT 3.1.1 a
+// publisher needs: CONNECT, QoS 0 PUBLISH, PINGREQ and DISCONNECT. Topics,
+// under Config.MQTTTopicPrefix:
+//
+//	<prefix>/availability      "online"/"offline" (retained, last will)
+//	<prefix>/queue/<event>     added, status, removed, completed, error
+//	<prefix>/queue/state       queue counts (retained)
+//	<prefix>/notification      batch and failure digests
+//
+// Progress ticks are not mirrored; "status" is published when an item moves
+// to another stage. Messages are dropped while the broker is unreachable.
+
+// DefaultMQTTTopicPrefix is used when Config.MQTTTopicPrefix is empty
+const DefaultMQTTTopicPrefix = "youflac"
+
+const (
+	mqttKeepAlive   = 60 * time.Second
+	mqttDialTimeout = 10 * time.Second
+	mqttRetryDelay  = 30 * time.Second
+	mqttQueueSize   = 256
+)
+
+// MQTT packet types (upper nibble of the fixed header)
+const (
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttPingreq    = 0xC0
+	mqttDisconnect = 0xE0
+)
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// MQTTPublisher keeps one broker connection and publishes from a buffered
+// channel, so queue processing never waits on the network
+type MQTTPublisher struct {
+	broker   *url.URL
+	prefix   string
+	username string
+	password string
+	clientID string
+
+	messages chan mqttMessage
+	done     chan struct{}
+	stopped  chan struct{}
+
+	mu         sync.Mutex
+	lastStatus map[string]QueueStatus // Per item, to publish stage changes only
+}
+
+// ParseMQTTBroker validates a broker URL: mqtt://host[:1883] or mqtts://host[:8883]
+func ParseMQTTBroker(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid MQTT broker %q, expected e.g. mqtt://homeassistant:1883", raw)
+	}
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "1883")
+		}
+	case "mqtts", "ssl", "tls":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "8883")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported MQTT scheme %q (use mqtt or mqtts)", u.Scheme)
+	}
+	return u, nil
+}
+
+// NewMQTTPublisher starts a publisher for config; it connects on first use
+func NewMQTTPublisher(config *Config) (*MQTTPublisher, error) {
+	broker, err := ParseMQTTBroker(config.MQTTBrokerURL)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(strings.TrimSpace(config.MQTTTopicPrefix), "/")
+	if prefix == "" {
+		prefix = DefaultMQTTTopicPrefix
+	}
+	username, password := config.MQTTUsername, config.MQTTPassword
+	if broker.User != nil && username == "" {
+		username = broker.User.Username()
+		password, _ = broker.User.Password()
+	}
+
+	p := &MQTTPublisher{
+		broker:     broker,
+		prefix:     prefix,
+		username:   username,
+		password:   password,
+		clientID:   fmt.Sprintf("youflac-%d", time.Now().UnixNano()%1e9),
+		messages:   make(chan mqttMessage, mqttQueueSize),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		lastStatus: make(map[string]QueueStatus),
+	}
+	go p.run()
+	return p, nil
+}
+
+// Publish queues a message under the topic prefix. It never blocks; when the
+// buffer is full the message is dropped.
+func (p *MQTTPublisher) Publish(topic string, payload []byte, retain bool) {
+	select {
+	case p.messages <- mqttMessage{topic: p.prefix + "/" + topic, payload: payload, retain: retain}:
+	default:
+		slog.Debug("MQTT buffer full, dropping message", "topic", topic)
+	}
+}
+
+// Close publishes "offline" and disconnects
+func (p *MQTTPublisher) Close() {
+	close(p.done)
+	<-p.stopped
+}
+
+func (p *MQTTPublisher) run() {
+	defer close(p.stopped)
+
+	var conn net.Conn
+	var retryAt time.Time
+	ping := time.NewTicker(mqttKeepAlive / 2)
+	defer ping.Stop()
+
+	disconnect := func() {
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+	send := func(packet []byte) {
+		if conn == nil {
+			if time.Now().Before(retryAt) {
+				return
+			}
+			c, err := p.connect()
+			if err != nil {
+				slog.Warn("MQTT connect failed", "broker", p.broker.Host, "err", err)
+				retryAt = time.Now().Add(mqttRetryDelay)
+				return
+			}
+			conn = c
+		}
+		conn.SetWriteDeadline(time.Now().Add(mqttDialTimeout))
+		if _, err := conn.Write(packet); err != nil {
+			slog.Warn("MQTT publish failed, reconnecting", "err", err)
+			disconnect()
+		}
+	}
+
+	for {
+		select {
+		case msg := <-p.messages:
+			send(mqttPublishPacket(msg.topic, msg.payload, msg.retain))
+		case <-ping.C:
+			if conn != nil {
+				send([]byte{mqttPingreq, 0})
+			}
+		case <-p.done:
+			if conn != nil {
+				conn.Write(mqttPublishPacket(p.prefix+"/availability", []byte("offline"), true))
+				conn.Write([]byte{mqttDisconnect, 0})
+			}
+			disconnect()
+			return
+		}
+	}
+}
+
+// connect dials the broker, sends CONNECT with an "offline" last will and
+// publishes "online"
+func (p *MQTTPublisher) connect() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: mqttDialTimeout}
+	var conn net.Conn
+	var err error
+	switch p.broker.Scheme {
+	case "mqtts", "ssl", "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", p.broker.Host, &tls.Config{ServerName: p.broker.Hostname()})
+	default:
+		conn, err = dialer.Dial("tcp", p.broker.Host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(mqttDialTimeout))
+	if _, err := conn.Write(p.connectPacket()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(reader, ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no CONNACK: %w", err)
+	}
+	if ack[0] != mqttConnack || ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("broker refused connection (code %d)", ack[3])
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Drain PINGRESPs; a read error means the broker went away
+	go func() {
+		io.Copy(io.Discard, reader)
+		conn.Close()
+	}()
+
+	if _, err := conn.Write(mqttPublishPacket(p.prefix+"/availability", []byte("online"), true)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (p *MQTTPublisher) connectPacket() []byte {
+	flags := byte(0x02)  // Clean session
+	flags |= 0x04 | 0x20 // Will flag, will retain (QoS 0)
+	var payload []byte
+	payload = appendMQTTString(payload, p.clientID)
+	payload = appendMQTTString(payload, p.prefix+"/availability")
+	payload = appendMQTTString(payload, "offline")
+	if p.username != "" {
+		flags |= 0x80
+		payload = appendMQTTString(payload, p.username)
+		if p.password != "" {
+			flags |= 0x40
+			payload = appendMQTTString(payload, p.password)
+		}
+	}
+
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	keepAlive := int(mqttKeepAlive / time.Second)
+	body = append(body, 4, flags, byte(keepAlive>>8), byte(keepAlive))
+	body = append(body, payload...)
+	return mqttPacket(mqttConnect, body)
+}
+
+func mqttPublishPacket(topic string, payload []byte, retain bool) []byte {
+	header := byte(mqttPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+	return mqttPacket(header, body)
+}
+
+// mqttPacket prepends the fixed header with the variable-length remaining length
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+// mqttItemPayload is the JSON published for queue events
+type mqttItemPayload struct {
+	ID         string      `json:"id"`
+	Status     QueueStatus `json:"status"`
+	Title      string      `json:"title,omitempty"`
+	Artist     string      `json:"artist,omitempty"`
+	Playlist   string      `json:"playlist,omitempty"`
+	Progress   int         `json:"progress"`
+	Stage      string      `json:"stage,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Warning    string      `json:"warning,omitempty"` // Set on queue/warning
+	OutputPath string      `json:"outputPath,omitempty"`
+}
+
+// PublishQueueEvent mirrors one queue event. Progress-only updates are
+// skipped; it reports whether the queue counts may have changed.
+func (p *MQTTPublisher) PublishQueueEvent(event QueueEvent, item *QueueItem) bool {
+	topic := event.Type
+	p.mu.Lock()
+	switch event.Type {
+	case "updated":
+		if item == nil || p.lastStatus[event.ItemID] == item.Status {
+			p.mu.Unlock()
+			return false
+		}
+		topic = "status"
+		p.lastStatus[event.ItemID] = item.Status
+	case "removed":
+		delete(p.lastStatus, event.ItemID)
+	default:
+		if item != nil {
+			p.lastStatus[event.ItemID] = item.Status
+		}
+	}
+	p.mu.Unlock()
+
+	payload := mqttItemPayload{ID: event.ItemID, Status: event.Status, Error: event.Error}
+	if item != nil {
+		payload = mqttItemPayload{
+			ID:         item.ID,
+			Status:     item.Status,
+			Title:      item.Title,
+			Artist:     item.Artist,
+			Playlist:   item.PlaylistName,
+			Progress:   item.Progress,
+			Stage:      item.Stage,
+			Error:      item.Error,
+			OutputPath: item.OutputPath,
+		}
+	}
+	payload.Warning = event.Warning
+	data, _ := json.Marshal(payload)
+	p.Publish("queue/"+topic, data, false)
+	return true
+}
+
+var (
+	mqttPublisher      *MQTTPublisher
+	mqttPublisherKey   string // Settings the publisher was built from
+	mqttPublisherMutex sync.Mutex
+)
+
+// ConfigureMQTT starts, restarts or stops the publisher to match config
+func ConfigureMQTT(config *Config) {
+	key := strings.Join([]string{config.MQTTBrokerURL, config.MQTTTopicPrefix, config.MQTTUsername, config.MQTTPassword}, "\x00")
+
+	mqttPublisherMutex.Lock()
+	defer mqttPublisherMutex.Unlock()
+	if key == mqttPublisherKey && (mqttPublisher != nil) == (config.MQTTBrokerURL != "") {
+		return
+	}
+	if mqttPublisher != nil {
+		mqttPublisher.Close()
+		mqttPublisher = nil
+	}
+	mqttPublisherKey = key
+	if strings.TrimSpace(config.MQTTBrokerURL) == "" {
+		return
+	}
+	p, err := NewMQTTPublisher(config)
+	if err != nil {
+		slog.Warn("MQTT disabled", "err", err)
+		return
+	}
+	mqttPublisher = p
+}
+
+func getMQTTPublisher() *MQTTPublisher {
+	mqttPublisherMutex.Lock()
+	defer mqttPublisherMutex.Unlock()
+	return mqttPublisher
+}
+
+// publishMQTTEvent mirrors a queue event and the resulting queue counts
+func (q *Queue) publishMQTTEvent(event QueueEvent) {
+	p := getMQTTPublisher()
+	if p == nil {
+		return
+	}
+	item := event.Item
+	if item == nil && event.Type != "removed" {
+		item = q.GetItem(event.ItemID)
+	}
+	if !p.PublishQueueEvent(event, item) {
+		return
+	}
+
+	// Counted from the status buckets: copying the queue on every
+	// transition is too much for large playlists
+	state := map[string]int{}
+	q.mutex.RLock()
+	for status, ids := range q.byStatus {
+		if len(ids) > 0 {
+			state[string(status)] = len(ids)
+		}
+	}
+	q.mutex.RUnlock()
+	data, _ := json.Marshal(state)
+	p.Publish("queue/state", data, true)
+}
+
+// MQTTNotifier publishes notification digests to <prefix>/notification
+type MQTTNotifier struct{}
+
+func (MQTTNotifier) Name() string { return "mqtt" }
+
+func (MQTTNotifier) Notify(n Notification) error {
+	p := getMQTTPublisher()
+	if p == nil {
+		return fmt.Errorf("MQTT broker not configured")
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	p.Publish("notification", data, false)
+	return nil
+}
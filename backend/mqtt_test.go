@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type brokerPacket struct {
+	header  byte
+	topic   string
+	payload string
+	body    []byte
+}
+
+// fakeMQTTBroker accepts one client, acknowledges CONNECT and reports every packet
+func fakeMQTTBroker(t *testing.T) (addr string, packets chan brokerPacket) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	packets = make(chan brokerPacket, 64)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			length, mult := 0, 1
+			for {
+				b, err := r.ReadByte()
+				if err != nil {
+					return
+				}
+				length += int(b&0x7f) * mult
+				mult *= 128
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+
+			p := brokerPacket{header: header, body: body}
+			switch header & 0xf0 {
+			case mqttConnect:
+				conn.Write([]byte{mqttConnack, 2, 0, 0})
+			case mqttPublish:
+				n := int(body[0])<<8 | int(body[1])
+				p.topic = string(body[2 : 2+n])
+				p.payload = string(body[2+n:])
+			}
+			packets <- p
+		}
+	}()
+	return ln.Addr().String(), packets
+}
+
+func nextPublish(t *testing.T, packets chan brokerPacket) brokerPacket {
+	t.Helper()
+	for {
+		select {
+		case p := <-packets:
+			if p.header&0xf0 == mqttPublish {
+				return p
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for PUBLISH")
+		}
+	}
+}
+
+func TestMQTTPacket_RemainingLength(t *testing.T) {
+	for n, want := range map[int][]byte{
+		0:     {0x30, 0x00},
+		127:   {0x30, 0x7f},
+		128:   {0x30, 0x80, 0x01},
+		16383: {0x30, 0xff, 0x7f},
+		16384: {0x30, 0x80, 0x80, 0x01},
+	} {
+		got := mqttPacket(mqttPublish, make([]byte, n))
+		if string(got[:len(want)]) != string(want) || len(got) != len(want)+n {
+			t.Errorf("length %d: header % x", n, got[:len(want)])
+		}
+	}
+}
+
+func TestParseMQTTBroker(t *testing.T) {
+	if u, err := ParseMQTTBroker("mqtt://ha.local"); err != nil || u.Host != "ha.local:1883" {
+		t.Errorf("mqtt default port = %v, %v", u, err)
+	}
+	if u, err := ParseMQTTBroker("mqtts://ha.local"); err != nil || u.Host != "ha.local:8883" {
+		t.Errorf("mqtts default port = %v, %v", u, err)
+	}
+	for _, bad := range []string{"http://ha.local", "mqtt://", "ha.local:1883"} {
+		if _, err := ParseMQTTBroker(bad); err == nil {
+			t.Errorf("ParseMQTTBroker(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMQTTPublisher_QueueEvents(t *testing.T) {
+	addr, packets := fakeMQTTBroker(t)
+	p, err := NewMQTTPublisher(&Config{MQTTBrokerURL: "mqtt://" + addr, MQTTTopicPrefix: "home/youflac/", MQTTUsername: "ha", MQTTPassword: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	item := &QueueItem{ID: "a", Title: "Song", Artist: "Artist", Status: StatusPending}
+	p.PublishQueueEvent(QueueEvent{Type: "added", ItemID: "a", Item: item}, item)
+
+	connect := <-packets
+	if connect.header != mqttConnect || !strings.Contains(string(connect.body), "home/youflac/availability") ||
+		!strings.Contains(string(connect.body), "secret") {
+		t.Fatalf("CONNECT = %q", connect.body)
+	}
+	if online := nextPublish(t, packets); online.topic != "home/youflac/availability" || online.payload != "online" || online.header&0x01 == 0 {
+		t.Errorf("availability = %+v", online)
+	}
+	added := nextPublish(t, packets)
+	var payload mqttItemPayload
+	json.Unmarshal([]byte(added.payload), &payload)
+	if added.topic != "home/youflac/queue/added" || payload.Title != "Song" || payload.Status != StatusPending {
+		t.Errorf("added = %+v", added)
+	}
+
+	// Progress ticks within a stage are not mirrored, stage changes are
+	item.Progress = 40
+	if p.PublishQueueEvent(QueueEvent{Type: "updated", ItemID: "a"}, item) {
+		t.Error("progress-only update should be skipped")
+	}
+	item.Status = StatusDownloadingVideo
+	if !p.PublishQueueEvent(QueueEvent{Type: "updated", ItemID: "a"}, item) {
+		t.Error("status change should be published")
+	}
+	if status := nextPublish(t, packets); status.topic != "home/youflac/queue/status" || !strings.Contains(status.payload, `"downloading_video"`) {
+		t.Errorf("status = %+v", status)
+	}
+
+	ConfigureMQTT(&Config{})
+	if err := (MQTTNotifier{}).Notify(Notification{Subject: "x"}); err == nil {
+		t.Error("notifier should fail without a broker")
+	}
+}
@@ -3,10 +3,11 @@ package backend
 import (
 	"encoding/xml"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,12 +19,18 @@ import (
 type Metadata struct {
 	Title       string   `json:"title"`
 	Artist      string   `json:"artist"`
+	AlbumArtist string   `json:"albumArtist,omitempty"`
+	Artists     []string `json:"artists,omitempty"` // Individual artists from a multi-artist credit
 	Album       string   `json:"album"`
+	AlbumID     string   `json:"albumId,omitempty"` // Store album, e.g. "tidal:12345" (TIDAL_ALBUM_ID tag)
 	Year        int      `json:"year,omitempty"`
+	ReleaseDate string   `json:"releaseDate,omitempty"` // "2006-01-02" (or just the year) from the audio service, NFO <premiered>
 	ISRC        string   `json:"isrc,omitempty"`
 	Duration    float64  `json:"duration,omitempty"`
 	Genre       string   `json:"genre,omitempty"`
 	Track       int      `json:"track,omitempty"`
+	TrackTotal  int      `json:"trackTotal,omitempty"` // Playlist or album length; sets the {track} padding
+	Disc        int      `json:"disc,omitempty"`
 	Description string   `json:"description,omitempty"`
 	YouTubeID   string   `json:"youtubeId,omitempty"`
 	YouTubeURL  string   `json:"youtubeUrl,omitempty"`
@@ -31,6 +38,7 @@ type Metadata struct {
 	Directors   []string `json:"directors,omitempty"`
 	Studios     []string `json:"studios,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
+	Explicit    bool     `json:"explicit,omitempty"` // Explicit lyrics (ITUNESADVISORY tag, {explicit} placeholder)
 }
 
 // FolderLayout defines how files are organized
@@ -97,6 +105,17 @@ type OrganizeResult struct {
 	DirectoryCreated bool `json:"directoryCreated"`
 }
 
+// itemNamingTemplate returns the item's naming template override, or the configured one
+func itemNamingTemplate(item *QueueItem, config *Config) string {
+	if item != nil && item.NamingTemplate != "" {
+		return item.NamingTemplate
+	}
+	if lib := LibraryFor(config, item); lib != nil && lib.Template != "" {
+		return lib.Template
+	}
+	return config.NamingTemplate
+}
+
 // GenerateFilePath generates full file path based on template
 func GenerateFilePath(metadata *Metadata, template, baseDir, extension string) string {
 	if template == "" {
@@ -116,6 +135,7 @@ func ApplyTemplate(template string, metadata *Metadata) string {
 	path := template
 
 	// Basic replacements (only sanitize non-empty values)
+	path = strings.ReplaceAll(path, "{albumartist}", sanitizeOrEmpty(albumArtistOrArtist(metadata)))
 	path = strings.ReplaceAll(path, "{artist}", sanitizeOrEmpty(metadata.Artist))
 	path = strings.ReplaceAll(path, "{title}", sanitizeOrEmpty(metadata.Title))
 	path = strings.ReplaceAll(path, "{album}", sanitizeOrEmpty(metadata.Album))
@@ -127,25 +147,57 @@ func ApplyTemplate(template string, metadata *Metadata) string {
 	}
 	path = strings.ReplaceAll(path, "{year}", yearStr)
 
-	// Track number with padding
+	// Track number, padded to the width of the track total so 100+ entry
+	// playlists still sort
 	trackStr := ""
 	if metadata.Track > 0 {
-		trackStr = fmt.Sprintf("%02d", metadata.Track)
+		trackStr = fmt.Sprintf("%0*d", trackNumberWidth(metadata), metadata.Track)
 	}
 	path = strings.ReplaceAll(path, "{track}", trackStr)
 
+	discStr := ""
+	if metadata.Disc > 0 {
+		discStr = strconv.Itoa(metadata.Disc)
+	}
+	path = strings.ReplaceAll(path, "{disc}", discStr)
+
 	// Genre
 	path = strings.ReplaceAll(path, "{genre}", sanitizeOrEmpty(metadata.Genre))
 
 	// YouTube ID
 	path = strings.ReplaceAll(path, "{youtube_id}", metadata.YouTubeID)
 
+	// User tags: {tag} is the first one, {tags} all of them
+	firstTag := ""
+	if len(metadata.Tags) > 0 {
+		firstTag = metadata.Tags[0]
+	}
+	path = strings.ReplaceAll(path, "{tags}", sanitizeOrEmpty(strings.Join(metadata.Tags, ", ")))
+	path = strings.ReplaceAll(path, "{tag}", sanitizeOrEmpty(firstTag))
+
+	// Explicit marker, empty for clean tracks
+	path = strings.ReplaceAll(path, "{explicit}", explicitLabel(metadata))
+
 	// Clean up empty segments and multiple slashes
 	path = cleanupPath(path)
 
 	return path
 }
 
+// trackNumberWidth is the zero-padded width of {track}: at least 2 digits,
+// more when the track total (or the track itself) needs them
+func trackNumberWidth(metadata *Metadata) int {
+	return max(2, len(strconv.Itoa(max(metadata.Track, metadata.TrackTotal))))
+}
+
+// albumArtistOrArtist falls back to the track artist when no album artist is set
+func albumArtistOrArtist(metadata *Metadata) string {
+	if metadata.AlbumArtist != "" {
+		return metadata.AlbumArtist
+	}
+	return metadata.Artist
+}
+
 // sanitizeOrEmpty sanitizes the filename but returns empty string for empty input
 // This allows cleanupPath to remove empty segments
 func sanitizeOrEmpty(name string) string {
@@ -228,9 +280,22 @@ func GenerateFlatPath(metadata *Metadata, baseDir string) string {
 // PlaylistTemplate is the template for playlist items with track numbers
 const PlaylistTemplate = "{track} - {artist} - {title}/{track} - {artist} - {title}"
 
+// PlaylistFlatTemplate puts every playlist item directly in the playlist folder
+const PlaylistFlatTemplate = "{track} - {artist} - {title}"
+
+// Playlist folder layouts for Config.PlaylistLayout
+const (
+	PlaylistLayoutNested = "nested" // One folder per track (PlaylistTemplate)
+	PlaylistLayoutFlat   = "flat"   // All tracks in the playlist folder (PlaylistFlatTemplate)
+)
+
 // GeneratePlaylistFilePath generates file path for playlist items with track number prefix
-// Format: "01 - Artist - Title/01 - Artist - Title.mkv"
-func GeneratePlaylistFilePath(metadata *Metadata, baseDir, extension string) string {
+// Format: "01 - Artist - Title/01 - Artist - Title.mkv", or "01 - Artist - Title.mkv"
+// with PlaylistLayoutFlat
+func GeneratePlaylistFilePath(metadata *Metadata, baseDir, extension, layout string) string {
+	if layout == PlaylistLayoutFlat {
+		return GenerateFilePath(metadata, PlaylistFlatTemplate, baseDir, extension)
+	}
 	return GenerateFilePath(metadata, PlaylistTemplate, baseDir, extension)
 }
 
@@ -267,7 +332,7 @@ func ValidateTemplate(template string) error {
 	}
 
 	// Check for at least one placeholder
-	placeholders := []string{"{artist}", "{title}", "{album}", "{year}", "{track}", "{genre}", "{youtube_id}"}
+	placeholders := []string{"{artist}", "{albumartist}", "{title}", "{album}", "{year}", "{track}", "{disc}", "{genre}", "{youtube_id}", "{tag}", "{tags}", "{explicit}"}
 	hasPlaceholder := false
 	for _, p := range placeholders {
 		if strings.Contains(template, p) {
@@ -309,7 +374,7 @@ func GenerateFanartPath(mkvPath string) string {
 // CreateDirectoryStructure creates necessary directories for the output path
 func CreateDirectoryStructure(outputPath string) error {
 	dir := filepath.Dir(outputPath)
-	return os.MkdirAll(dir, 0755)
+	return MkdirOutput(dir)
 }
 
 // OrganizeOutput creates directory structure and returns paths for all output files
@@ -325,7 +390,7 @@ func OrganizeOutput(metadata *Metadata, layout FolderLayout, baseDir, customTemp
 	// Create directory structure
 	dir := filepath.Dir(mkvPath)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := MkdirOutput(dir); err != nil {
 			return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 		result.DirectoryCreated = true
@@ -343,15 +408,18 @@ func OrganizeOutput(metadata *Metadata, layout FolderLayout, baseDir, customTemp
 type MusicVideoNFO struct {
 	XMLName       xml.Name       `xml:"musicvideo"`
 	Title         string         `xml:"title"`
+	SortTitle     string         `xml:"sorttitle,omitempty"`
 	Artist        string         `xml:"artist"`
 	Album         string         `xml:"album,omitempty"`
 	Year          int            `xml:"year,omitempty"`
+	Premiered     string         `xml:"premiered,omitempty"`
 	Runtime       int            `xml:"runtime,omitempty"` // in minutes
 	Plot          string         `xml:"plot,omitempty"`
 	Genre         string         `xml:"genre,omitempty"`
 	Directors     []string       `xml:"director,omitempty"`
 	Studios       []string       `xml:"studio,omitempty"`
 	Tags          []string       `xml:"tag,omitempty"`
+	MPAA          string         `xml:"mpaa,omitempty"` // Content rating, "Explicit" for explicit tracks
 	UniqueID      []UniqueID     `xml:"uniqueid,omitempty"`
 	Thumb         []NFOThumb     `xml:"thumb,omitempty"`
 	Fanart        *NFOFanart     `xml:"fanart,omitempty"`
@@ -404,11 +472,58 @@ type AudioStreamInfo struct {
 	Channels int    `xml:"channels,omitempty"`
 }
 
+// Optional NFO fields (Config.NFOFields)
+const (
+	NFOFieldPremiered = "premiered" // Release date, Jellyfin sorts and filters on it
+	NFOFieldSortTitle = "sorttitle" // Title without a leading "The "
+	NFOFieldPlot      = "plot"      // Video description
+	NFOFieldTags      = "tag"       // Queue item tags
+	NFOFieldDateAdded = "dateadded" // Time the NFO was written
+	NFOFieldFileInfo  = "fileinfo"  // Stream details (codecs, resolution)
+)
+
+// NFOFields lists the optional NFO fields, all emitted by default
+var NFOFields = []string{NFOFieldPremiered, NFOFieldSortTitle, NFOFieldPlot, NFOFieldTags, NFOFieldDateAdded, NFOFieldFileInfo}
+
 // NFOOptions configures NFO generation
 type NFOOptions struct {
 	IncludeFileInfo  bool        `json:"includeFileInfo"`
 	IncludeThumbnail bool        `json:"includeThumbnail"`
 	MediaInfo        *MediaInfo  `json:"mediaInfo,omitempty"`
+	Fields           []string    `json:"fields,omitempty"` // Optional fields to emit, nil = all (see NFOFields)
+}
+
+// includes reports whether the optional field is emitted
+func (o *NFOOptions) includes(field string) bool {
+	return o == nil || o.Fields == nil || slices.Contains(o.Fields, field)
+}
+
+// SortTitle returns the title Jellyfin and Kodi should sort on: without a
+// leading "The "
+func SortTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if len(title) > 4 && strings.EqualFold(title[:4], "the ") {
+		return strings.TrimSpace(title[4:])
+	}
+	return title
+}
+
+// ParseReleaseDate normalizes a release date from an audio service
+// ("2006-01-02", "2006-01-02T15:04:05Z", "2006") to "2006-01-02" or "2006"
+// and returns its year; "" and 0 if it isn't a date
+func ParseReleaseDate(s string) (string, int) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 10 {
+		if t, err := time.Parse("2006-01-02", s[:10]); err == nil {
+			return s[:10], t.Year()
+		}
+	}
+	if len(s) == 4 {
+		if year, err := strconv.Atoi(s); err == nil && year > 0 {
+			return s, year
+		}
+	}
+	return "", 0
 }
 
 // GenerateNFO creates NFO XML content for a music video
@@ -422,12 +537,31 @@ func GenerateNFO(metadata *Metadata, opts *NFOOptions) ([]byte, error) {
 		Artist:    metadata.Artist,
 		Album:     metadata.Album,
 		Year:      metadata.Year,
-		Plot:      metadata.Description,
 		Genre:     metadata.Genre,
 		Directors: metadata.Directors,
 		Studios:   metadata.Studios,
-		Tags:      metadata.Tags,
-		DateAdded: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if opts.includes(NFOFieldSortTitle) {
+		nfo.SortTitle = SortTitle(metadata.Title)
+	}
+	if opts.includes(NFOFieldPremiered) {
+		// Jellyfin wants a full date; a bare year becomes January 1st
+		nfo.Premiered = metadata.ReleaseDate
+		if len(nfo.Premiered) == 4 {
+			nfo.Premiered += "-01-01"
+		}
+	}
+	if opts.includes(NFOFieldPlot) {
+		nfo.Plot = metadata.Description
+	}
+	if opts.includes(NFOFieldTags) {
+		nfo.Tags = metadata.Tags
+	}
+	if opts.includes(NFOFieldDateAdded) {
+		nfo.DateAdded = time.Now().Format("2006-01-02 15:04:05")
+	}
+	if metadata.Explicit {
+		nfo.MPAA = "Explicit"
 	}
 
 	// Runtime in minutes
@@ -462,7 +596,7 @@ func GenerateNFO(metadata *Metadata, opts *NFOOptions) ([]byte, error) {
 	}
 
 	// Add file info from MediaInfo
-	if opts != nil && opts.IncludeFileInfo && opts.MediaInfo != nil {
+	if opts != nil && opts.IncludeFileInfo && opts.MediaInfo != nil && opts.includes(NFOFieldFileInfo) {
 		mi := opts.MediaInfo
 		nfo.FileInfo = &NFOFileInfo{
 			StreamDetails: &StreamDetails{
@@ -501,46 +635,43 @@ func WriteNFO(metadata *Metadata, nfoPath string, opts *NFOOptions) error {
 		return err
 	}
 
-	// Ensure directory exists
-	dir := filepath.Dir(nfoPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	return os.WriteFile(nfoPath, content, 0644)
+	return WriteOutputFile(nfoPath, content)
 }
 
-// DownloadPoster downloads thumbnail and saves as poster.jpg
+// DownloadPoster downloads thumbnail and saves as poster.jpg. YouTube
+// thumbnails are fetched in the largest size the video has. Images come
+// from the cover art cache unless Config.CoverCacheMB is 0.
 func DownloadPoster(thumbnailURL, posterPath string) error {
 	if thumbnailURL == "" {
 		return fmt.Errorf("thumbnail URL is empty")
 	}
+	thumbnailURL = bestYouTubeThumbnail(thumbnailURL)
 
 	// Ensure directory exists
 	dir := filepath.Dir(posterPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := MkdirOutput(dir); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Download using ffmpeg (handles various protocols)
-	ffmpegPath := GetFFmpegPath()
-	if ffmpegPath == "" {
-		return fmt.Errorf("ffmpeg not found, cannot download thumbnail")
-	}
-
-	// Use ffmpeg to download and convert to jpg
-	args := []string{
-		"-y",
-		"-i", thumbnailURL,
-		"-vframes", "1",
-		"-q:v", "2",
-		posterPath,
+	coverCacheMutex.Lock()
+	cacheEnabled := coverCacheMaxBytes > 0
+	coverCacheMutex.Unlock()
+	if cacheEnabled {
+		cached, err := cachedCoverArt(thumbnailURL)
+		if err == nil {
+			if err := copyFile(cached, posterPath); err != nil {
+				return fmt.Errorf("failed to copy cached poster: %w", err)
+			}
+			FinishOutputFile(posterPath)
+			return nil
+		}
+		slog.Debug("cover art cache miss failed, downloading directly", "url", thumbnailURL, "err", err)
 	}
 
-	cmd := exec.Command(ffmpegPath, args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to download poster: %w, output: %s", err, string(output))
+	if err := coverConverter(thumbnailURL, posterPath); err != nil {
+		return err
 	}
+	FinishOutputFile(posterPath)
 
 	return nil
 }
@@ -603,7 +734,7 @@ func RenameMKV(mkvPath string, metadata *Metadata, baseDir string, opts RenameOp
 	}
 
 	// Move file
-	if err := os.Rename(mkvPath, newPath); err != nil {
+	if err := MoveFile(mkvPath, newPath); err != nil {
 		result.Error = err.Error()
 		return result, err
 	}
@@ -61,6 +61,7 @@ func TestApplyTemplate(t *testing.T) {
 		{"{track} - {title}", "01 - Never Gonna Give You Up"},
 		{"{genre}/{artist}/{title}", "Pop/Rick Astley/Never Gonna Give You Up"},
 		{"{youtube_id}", "dQw4w9WgXcQ"},
+		{"{albumartist}/{title}", "Rick Astley/Never Gonna Give You Up"},
 	}
 
 	for _, tt := range tests {
@@ -73,6 +74,19 @@ func TestApplyTemplate(t *testing.T) {
 	}
 }
 
+func TestApplyTemplate_AlbumArtist(t *testing.T) {
+	metadata := &Metadata{
+		Title:       "Song",
+		Artist:      "Artist A x Artist B",
+		AlbumArtist: "Artist A",
+	}
+	result := ApplyTemplate("{albumartist}/{artist} - {title}", metadata)
+	expected := "Artist A/Artist A x Artist B - Song"
+	if result != expected {
+		t.Errorf("ApplyTemplate() = %q, want %q", result, expected)
+	}
+}
+
 func TestApplyTemplate_MissingFields(t *testing.T) {
 	metadata := &Metadata{
 		Title:  "Song Title",
@@ -175,6 +189,47 @@ func TestGenerateFlatPath(t *testing.T) {
 	}
 }
 
+func TestGeneratePlaylistFilePath(t *testing.T) {
+	metadata := &Metadata{
+		Title:  "Never Gonna Give You Up",
+		Artist: "Rick Astley",
+		Track:  3,
+	}
+	baseDir := "/music/My Playlist"
+
+	tests := []struct {
+		layout   string
+		expected string
+	}{
+		{PlaylistLayoutNested, "/music/My Playlist/03 - Rick Astley - Never Gonna Give You Up/03 - Rick Astley - Never Gonna Give You Up.mkv"},
+		{"", "/music/My Playlist/03 - Rick Astley - Never Gonna Give You Up/03 - Rick Astley - Never Gonna Give You Up.mkv"},
+		{PlaylistLayoutFlat, "/music/My Playlist/03 - Rick Astley - Never Gonna Give You Up.mkv"},
+	}
+	for _, tt := range tests {
+		if result := GeneratePlaylistFilePath(metadata, baseDir, ".mkv", tt.layout); result != tt.expected {
+			t.Errorf("GeneratePlaylistFilePath(%q) = %q, want %q", tt.layout, result, tt.expected)
+		}
+	}
+}
+
+func TestApplyTemplate_TrackPadding(t *testing.T) {
+	tests := []struct {
+		metadata Metadata
+		expected string
+	}{
+		{Metadata{Title: "Song", Track: 7}, "07 - Song"},
+		{Metadata{Title: "Song", Track: 7, TrackTotal: 12}, "07 - Song"},
+		{Metadata{Title: "Song", Track: 7, TrackTotal: 150}, "007 - Song"},
+		{Metadata{Title: "Song", Track: 123}, "123 - Song"},
+		{Metadata{Title: "Song", Track: 3, TrackTotal: 1200, Disc: 2}, "2/0003 - Song"},
+	}
+	for _, tt := range tests {
+		if result := ApplyTemplate("{disc}/{track} - {title}", &tt.metadata); result != tt.expected {
+			t.Errorf("ApplyTemplate(%+v) = %q, want %q", tt.metadata, result, tt.expected)
+		}
+	}
+}
+
 func TestGeneratePathForLayout(t *testing.T) {
 	metadata := &Metadata{
 		Title:  "Test Song",
@@ -585,6 +640,89 @@ func TestOrganizeOutput(t *testing.T) {
 	}
 }
 
+func TestGenerateNFO_PremieredAndSortTitle(t *testing.T) {
+	metadata := &Metadata{
+		Title:       "The Show Must Go On",
+		Artist:      "Queen",
+		Year:        1991,
+		ReleaseDate: "1991-10-14",
+		Description: "Official video",
+	}
+
+	content, err := GenerateNFO(metadata, nil)
+	if err != nil {
+		t.Fatalf("GenerateNFO failed: %v", err)
+	}
+	nfoStr := string(content)
+	for _, want := range []string{
+		"<sorttitle>Show Must Go On</sorttitle>",
+		"<premiered>1991-10-14</premiered>",
+		"<plot>Official video</plot>",
+		"<dateadded>",
+	} {
+		if !strings.Contains(nfoStr, want) {
+			t.Errorf("NFO should contain %s", want)
+		}
+	}
+
+	// Only the listed optional fields are emitted
+	content, err = GenerateNFO(metadata, &NFOOptions{Fields: []string{NFOFieldPremiered}})
+	if err != nil {
+		t.Fatalf("GenerateNFO failed: %v", err)
+	}
+	nfoStr = string(content)
+	if !strings.Contains(nfoStr, "<premiered>1991-10-14</premiered>") {
+		t.Error("NFO should contain premiered")
+	}
+	for _, unwanted := range []string{"<sorttitle>", "<plot>", "<dateadded>"} {
+		if strings.Contains(nfoStr, unwanted) {
+			t.Errorf("NFO should not contain %s", unwanted)
+		}
+	}
+
+	// A bare year becomes January 1st
+	content, _ = GenerateNFO(&Metadata{Title: "Innuendo", Artist: "Queen", ReleaseDate: "1991"}, nil)
+	if !strings.Contains(string(content), "<premiered>1991-01-01</premiered>") {
+		t.Error("NFO should contain premiered from a year")
+	}
+}
+
+func TestSortTitle(t *testing.T) {
+	tests := map[string]string{
+		"The Show Must Go On": "Show Must Go On",
+		"the end":             "end",
+		"Theatre":             "Theatre",
+		"The":                 "The",
+		"Innuendo":            "Innuendo",
+	}
+	for title, want := range tests {
+		if got := SortTitle(title); got != want {
+			t.Errorf("SortTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		date  string
+		year  int
+	}{
+		{"1991-10-14", "1991-10-14", 1991},
+		{"1991-10-14T00:00:00.000+0000", "1991-10-14", 1991},
+		{"1991", "1991", 1991},
+		{"", "", 0},
+		{"soon", "", 0},
+		{"1991-13-40", "", 0},
+	}
+	for _, tt := range tests {
+		date, year := ParseReleaseDate(tt.input)
+		if date != tt.date || year != tt.year {
+			t.Errorf("ParseReleaseDate(%q) = %q, %d, want %q, %d", tt.input, date, year, tt.date, tt.year)
+		}
+	}
+}
+
 func TestWriteNFO(t *testing.T) {
 	tmpDir := t.TempDir()
 	metadata := &Metadata{
@@ -0,0 +1,194 @@
+package backend
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Metadata editing
+// =============================================================================
+
+// Metadata corrected after the download has to reach three places: the NFO
+// next to the file (what Jellyfin/Kodi read), the tags embedded in the file
+// and the library index (duplicate detection). ReadNFO turns an NFO back
+// into Metadata for an editor; UpdateMediaMetadata writes the edited
+// Metadata to all three.
+
+// ReadNFO parses a musicvideo NFO written by GenerateNFO (or Kodi) into
+// Metadata
+func ReadNFO(nfoPath string) (*Metadata, error) {
+	data, err := os.ReadFile(nfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NFO: %w", err)
+	}
+
+	var nfo MusicVideoNFO
+	if err := xml.Unmarshal(data, &nfo); err != nil {
+		return nil, fmt.Errorf("failed to parse NFO: %w", err)
+	}
+
+	metadata := &Metadata{
+		Title:       strings.TrimSpace(nfo.Title),
+		Artist:      strings.TrimSpace(nfo.Artist),
+		Album:       strings.TrimSpace(nfo.Album),
+		Year:        nfo.Year,
+		ReleaseDate: strings.TrimSpace(nfo.Premiered),
+		Description: nfo.Plot,
+		Genre:       strings.TrimSpace(nfo.Genre),
+		Directors:   nfo.Directors,
+		Studios:     nfo.Studios,
+		Tags:        nfo.Tags,
+		Duration:    float64(nfo.Runtime * 60),
+		Explicit:    strings.EqualFold(nfo.MPAA, "Explicit"),
+	}
+	if info := nfo.FileInfo; info != nil && info.StreamDetails != nil && info.StreamDetails.Video != nil && info.StreamDetails.Video.DurationInSeconds > 0 {
+		metadata.Duration = float64(info.StreamDetails.Video.DurationInSeconds)
+	}
+	for _, id := range nfo.UniqueID {
+		value := strings.TrimSpace(id.Value)
+		switch strings.ToLower(id.Type) {
+		case "youtube":
+			metadata.YouTubeID = value
+			metadata.YouTubeURL = fmt.Sprintf("https://www.youtube.com/watch?v=%s", value)
+		case "isrc":
+			metadata.ISRC = value
+		}
+	}
+	for _, thumb := range nfo.Thumb {
+		if thumb.Aspect == "" || thumb.Aspect == "poster" {
+			metadata.Thumbnail = strings.TrimSpace(thumb.URL)
+			break
+		}
+	}
+	return metadata, nil
+}
+
+// ReadMediaMetadata returns the metadata of a library file: the embedded
+// tags, overridden by its NFO when there is one (the NFO has no album
+// artist, track or disc numbers)
+func ReadMediaMetadata(mediaPath string) (*Metadata, error) {
+	if _, err := os.Stat(mediaPath); err != nil {
+		return nil, fmt.Errorf("file not found: %s", mediaPath)
+	}
+
+	tags := extractMKVTags(mediaPath)
+	metadata := &Metadata{
+		Title:       tags["title"],
+		Artist:      tags["artist"],
+		AlbumArtist: cmp.Or(tags["album_artist"], tags["albumartist"]),
+		Album:       tags["album"],
+		Genre:       tags["genre"],
+		ISRC:        tags["isrc"],
+		Explicit:    tags["itunesadvisory"] == "1",
+	}
+	if id := tags[strings.ToLower(AlbumIDTag)]; id != "" {
+		metadata.AlbumID = tidalAlbumPrefix + id
+	}
+	metadata.YouTubeID = youtubeIDFromTags(tags)
+	if date := cmp.Or(tags["date"], tags["year"]); len(date) >= 4 {
+		metadata.Year, _ = strconv.Atoi(date[:4])
+	}
+	track, total, _ := strings.Cut(cmp.Or(tags["track"], tags["tracknumber"]), "/")
+	metadata.Track, _ = strconv.Atoi(track)
+	metadata.TrackTotal, _ = strconv.Atoi(cmp.Or(tags["tracktotal"], total))
+	disc, _, _ := strings.Cut(cmp.Or(tags["disc"], tags["discnumber"]), "/")
+	metadata.Disc, _ = strconv.Atoi(disc)
+
+	if nfoPath := GenerateNFOPath(mediaPath); fileExists(nfoPath) {
+		nfo, err := ReadNFO(nfoPath)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Title = cmp.Or(nfo.Title, metadata.Title)
+		metadata.Artist = cmp.Or(nfo.Artist, metadata.Artist)
+		metadata.Album = cmp.Or(nfo.Album, metadata.Album)
+		metadata.Year = cmp.Or(nfo.Year, metadata.Year)
+		metadata.Genre = cmp.Or(nfo.Genre, metadata.Genre)
+		metadata.ISRC = cmp.Or(nfo.ISRC, metadata.ISRC)
+		metadata.Explicit = nfo.Explicit || metadata.Explicit
+		metadata.ReleaseDate = nfo.ReleaseDate
+		metadata.Duration = nfo.Duration
+		metadata.Description = nfo.Description
+		metadata.Directors = nfo.Directors
+		metadata.Studios = nfo.Studios
+		metadata.Tags = nfo.Tags
+		metadata.YouTubeID = cmp.Or(nfo.YouTubeID, metadata.YouTubeID)
+		metadata.YouTubeURL = nfo.YouTubeURL
+		metadata.Thumbnail = nfo.Thumbnail
+	}
+
+	if metadata.Title == "" || metadata.Artist == "" {
+		title, artist := ParseFilename(mediaPath)
+		metadata.Title = cmp.Or(metadata.Title, title)
+		metadata.Artist = cmp.Or(metadata.Artist, artist)
+	}
+	return metadata, nil
+}
+
+// editedTags is MetadataToTags with the editable fields the edit cleared,
+// which are removed from the file
+func editedTags(metadata *Metadata) map[string][]string {
+	tags := MetadataToTags(metadata)
+	for _, key := range []string{"ALBUMARTIST", "ALBUM", "GENRE", "ISRC", "DATE", "TRACKNUMBER", "TRACKTOTAL", "DISCNUMBER", "ITUNESADVISORY"} {
+		if _, ok := tags[key]; !ok {
+			tags[key] = nil
+		}
+	}
+	return tags
+}
+
+// UpdateMediaMetadata applies edited metadata to a library file: the NFO is
+// regenerated (if the file has one) with the NFO fields of config, the
+// embedded tags are rewritten and the file index entry follows. config and
+// fileIndex may be nil.
+func UpdateMediaMetadata(mediaPath string, metadata *Metadata, config *Config, fileIndex *FileIndex) error {
+	if metadata == nil || strings.TrimSpace(metadata.Title) == "" || strings.TrimSpace(metadata.Artist) == "" {
+		return fmt.Errorf("title and artist are required")
+	}
+	info, err := os.Stat(mediaPath)
+	if err != nil {
+		return fmt.Errorf("file not found: %s", mediaPath)
+	}
+
+	if nfoPath := GenerateNFOPath(mediaPath); fileExists(nfoPath) {
+		opts := &NFOOptions{IncludeThumbnail: metadata.Thumbnail != ""}
+		if config != nil {
+			opts.Fields = config.NFOFields
+		}
+		if mediaInfo, err := GetMediaInfo(mediaPath); err == nil {
+			opts.IncludeFileInfo = true
+			opts.MediaInfo = mediaInfo
+		}
+		if err := WriteNFO(metadata, nfoPath, opts); err != nil {
+			return fmt.Errorf("failed to write NFO: %w", err)
+		}
+	}
+
+	if err := WriteTags(mediaPath, editedTags(metadata)); err != nil {
+		return err
+	}
+
+	if fileIndex != nil {
+		fileIndex.RemovePath(mediaPath)
+		fileIndex.AddEntry(FileIndexEntry{
+			Path:      mediaPath,
+			Title:     metadata.Title,
+			Artist:    metadata.Artist,
+			Album:     metadata.Album,
+			AlbumID:   metadata.AlbumID,
+			Duration:  metadata.Duration,
+			ISRC:      metadata.ISRC,
+			YouTubeID: metadata.YouTubeID,
+			Size:      info.Size(),
+			IndexedAt: time.Now(),
+		})
+		fileIndex.ScheduleSave()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadNFO_RoundTrip(t *testing.T) {
+	metadata := &Metadata{
+		Title:       "Bohemian Rhapsody",
+		Artist:      "Queen",
+		Album:       "A Night at the Opera",
+		Year:        1975,
+		Genre:       "Rock",
+		ISRC:        "GBUM71029604",
+		Description: "Official video",
+		Directors:   []string{"Bruce Gowers"},
+		Studios:     []string{"EMI"},
+		Tags:        []string{"classic"},
+		YouTubeID:   "fJ9rUzIMcZQ",
+		Thumbnail:   "https://i.ytimg.com/vi/fJ9rUzIMcZQ/maxresdefault.jpg",
+		Duration:    360,
+		Explicit:    true,
+	}
+
+	nfoPath := filepath.Join(t.TempDir(), "Queen - Bohemian Rhapsody.nfo")
+	if err := WriteNFO(metadata, nfoPath, &NFOOptions{IncludeThumbnail: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadNFO(nfoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != metadata.Title || got.Artist != metadata.Artist || got.Album != metadata.Album ||
+		got.Year != metadata.Year || got.Genre != metadata.Genre || got.ISRC != metadata.ISRC ||
+		got.Description != metadata.Description || got.YouTubeID != metadata.YouTubeID ||
+		got.Thumbnail != metadata.Thumbnail || got.Duration != metadata.Duration || !got.Explicit {
+		t.Errorf("ReadNFO() = %+v, want %+v", got, metadata)
+	}
+	if !slices.Equal(got.Directors, metadata.Directors) || !slices.Equal(got.Studios, metadata.Studios) || !slices.Equal(got.Tags, metadata.Tags) {
+		t.Errorf("ReadNFO() lists = %v %v %v", got.Directors, got.Studios, got.Tags)
+	}
+	if got.YouTubeURL != "https://www.youtube.com/watch?v=fJ9rUzIMcZQ" {
+		t.Errorf("YouTubeURL = %q", got.YouTubeURL)
+	}
+}
+
+func TestReadNFO_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadNFO(filepath.Join(dir, "missing.nfo")); err == nil {
+		t.Error("ReadNFO accepted a missing file")
+	}
+	path := filepath.Join(dir, "broken.nfo")
+	if err := os.WriteFile(path, []byte("<musicvideo><title>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadNFO(path); err == nil {
+		t.Error("ReadNFO accepted broken XML")
+	}
+}
+
+func TestReadMediaMetadata_NFO(t *testing.T) {
+	dir := t.TempDir()
+	mediaPath := filepath.Join(dir, "Queen - Bohemian Rhapsody.mkv")
+	writeTestFile(t, mediaPath, 1024)
+	if err := WriteNFO(&Metadata{Title: "Bohemian Rhapsody", Artist: "Queen", Year: 1975}, GenerateNFOPath(mediaPath), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadMediaMetadata(mediaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Bohemian Rhapsody" || got.Artist != "Queen" || got.Year != 1975 {
+		t.Errorf("ReadMediaMetadata() = %+v", got)
+	}
+}
+
+func TestReadMediaMetadata_FilenameFallback(t *testing.T) {
+	mediaPath := filepath.Join(t.TempDir(), "Queen - Innuendo.mkv")
+	writeTestFile(t, mediaPath, 1024)
+	got, err := ReadMediaMetadata(mediaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Innuendo" || got.Artist != "Queen" {
+		t.Errorf("ReadMediaMetadata() = %+v", got)
+	}
+
+	if _, err := ReadMediaMetadata(filepath.Join(t.TempDir(), "missing.mkv")); err == nil {
+		t.Error("ReadMediaMetadata accepted a missing file")
+	}
+}
+
+func TestEditedTags(t *testing.T) {
+	tags := editedTags(&Metadata{Title: "Innuendo", Artist: "Queen", Year: 1991})
+	if !slices.Equal(tags["TITLE"], []string{"Innuendo"}) || !slices.Equal(tags["DATE"], []string{"1991"}) {
+		t.Errorf("editedTags() = %v", tags)
+	}
+	// Cleared fields are removed from the file
+	for _, key := range []string{"ALBUM", "GENRE", "TRACKNUMBER", "ITUNESADVISORY"} {
+		if values, ok := tags[key]; !ok || values != nil {
+			t.Errorf("editedTags()[%s] = %v, %v, want a removal", key, values, ok)
+		}
+	}
+}
+
+func TestUpdateMediaMetadata_Validation(t *testing.T) {
+	mediaPath := filepath.Join(t.TempDir(), "Queen - Innuendo.mkv")
+	writeTestFile(t, mediaPath, 1024)
+	if err := UpdateMediaMetadata(mediaPath, &Metadata{Artist: "Queen"}, nil, nil); err == nil {
+		t.Error("UpdateMediaMetadata accepted metadata without a title")
+	}
+	if err := UpdateMediaMetadata(filepath.Join(t.TempDir(), "missing.mkv"), &Metadata{Title: "Innuendo", Artist: "Queen"}, nil, nil); err == nil {
+		t.Error("UpdateMediaMetadata accepted a missing file")
+	}
+}
@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// Item notes and tags
+// =============================================================================
+
+// Notes and tags are free-form user annotations ("for wedding playlist").
+// They are set on the download request or edited later, carried from the
+// queue item into History, matched by history search and usable in naming
+// templates through {tag} and {tags}.
+
+// Limits on user annotations
+const (
+	MaxItemNotesLength = 2000
+	MaxItemTags        = 20
+	MaxTagLength       = 64
+)
+
+// NormalizeTags trims tags, collapses inner whitespace and drops empty and
+// duplicate (case-insensitive) tags, keeping the first spelling
+func NormalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// validateNotes normalizes notes and tags and checks them against the limits
+func validateNotes(notes string, tags []string) (string, []string, error) {
+	notes = strings.TrimSpace(notes)
+	if len([]rune(notes)) > MaxItemNotesLength {
+		return "", nil, fmt.Errorf("notes must be at most %d characters", MaxItemNotesLength)
+	}
+	tags = NormalizeTags(tags)
+	if len(tags) > MaxItemTags {
+		return "", nil, fmt.Errorf("at most %d tags are allowed", MaxItemTags)
+	}
+	for _, tag := range tags {
+		if len([]rune(tag)) > MaxTagLength {
+			return "", nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+	}
+	return notes, tags, nil
+}
+
+// hasTag reports whether tags contains tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	tag = strings.TrimSpace(tag)
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a lowercase search term appears in the entry's
+// title, artist, notes or tags
+func (e *HistoryEntry) matches(search string) bool {
+	if strings.Contains(strings.ToLower(e.Title), search) ||
+		strings.Contains(strings.ToLower(e.Artist), search) ||
+		strings.Contains(strings.ToLower(e.Notes), search) {
+		return true
+	}
+	for _, tag := range e.Tags {
+		if strings.Contains(strings.ToLower(tag), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetItemNotes replaces the notes and tags of a queue item
+func (q *Queue) SetItemNotes(id, notes string, tags []string) error {
+	notes, tags, err := validateNotes(notes, tags)
+	if err != nil {
+		return err
+	}
+	if q.GetItem(id) == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	q.updateItem(id, func(item *QueueItem) {
+		item.Notes = notes
+		item.Tags = tags
+	})
+	return nil
+}
+
+// SetNotes replaces the notes and tags of a history entry
+func (h *History) SetNotes(id, notes string, tags []string) error {
+	notes, tags, err := validateNotes(notes, tags)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.entries {
+		if h.entries[i].ID == id {
+			h.entries[i].Notes = notes
+			h.entries[i].Tags = tags
+			return h.save()
+		}
+	}
+	return fmt.Errorf("history entry not found: %s", id)
+}
+
+// TagCount is a user tag and the number of history entries carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Tags lists the tags used in history, most used first
+func (h *History) Tags() []TagCount {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	index := make(map[string]int)
+	result := []TagCount{}
+	for _, entry := range h.entries {
+		for _, tag := range entry.Tags {
+			key := strings.ToLower(tag)
+			if i, ok := index[key]; ok {
+				result[i].Count++
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, TagCount{Tag: tag, Count: 1})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return strings.ToLower(result[i].Tag) < strings.ToLower(result[j].Tag)
+	})
+	return result
+}
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Wedding ", "wedding", "", "road   trip", "Road Trip", "  "})
+	if strings.Join(got, "|") != "Wedding|road trip" {
+		t.Errorf("NormalizeTags = %q", got)
+	}
+	if NormalizeTags(nil) != nil {
+		t.Error("no tags should stay nil")
+	}
+}
+
+func TestQueueItemNotes(t *testing.T) {
+	q := newTestQueue()
+	if _, err := q.AddToQueue(DownloadRequest{VideoURL: "https://youtu.be/x", Tags: make([]string, MaxItemTags+1)}); err != nil {
+		t.Errorf("empty tags should be dropped before the limit check: %v", err)
+	}
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://youtu.be/y", Notes: " first dance ", Tags: []string{"wedding", "Wedding"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(id); item.Notes != "first dance" || len(item.Tags) != 1 {
+		t.Errorf("item = %q %q", item.Notes, item.Tags)
+	}
+
+	if err := q.SetItemNotes(id, "", []string{"party", "dj"}); err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(id); item.Notes != "" || strings.Join(item.Tags, ",") != "party,dj" {
+		t.Errorf("edited item = %q %q", item.Notes, item.Tags)
+	}
+	if err := q.SetItemNotes("missing", "x", nil); err == nil {
+		t.Error("unknown item should fail")
+	}
+	if err := q.SetItemNotes(id, strings.Repeat("x", MaxItemNotesLength+1), nil); err == nil {
+		t.Error("overlong notes should fail")
+	}
+}
+
+func TestHistoryNotesAndTags(t *testing.T) {
+	h := &History{filePath: filepath.Join(t.TempDir(), "history.json")}
+	h.AddFromQueueItem(&QueueItem{Title: "Song A", Artist: "X", Notes: "first dance", Tags: []string{"Wedding"}}, "complete", "")
+	h.Add(HistoryEntry{Title: "Song B", Artist: "Y", Status: "complete", Tags: []string{"wedding", "party"}})
+	h.Add(HistoryEntry{Title: "Song C", Artist: "Z", Status: "complete"})
+
+	if got := h.Search("dance"); len(got) != 1 || got[0].Title != "Song A" {
+		t.Errorf("search notes = %+v", got)
+	}
+	if got := h.Search("PART"); len(got) != 1 || got[0].Title != "Song B" {
+		t.Errorf("search tags = %+v", got)
+	}
+
+	page, err := h.Query(HistoryQuery{Tag: "WEDDING"})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("tag filter = %+v, %v", page, err)
+	}
+
+	c := h.Search("Song C")[0]
+	if err := h.SetNotes(c.ID, "encore", []string{"party"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetNotes("missing", "", nil); err == nil {
+		t.Error("unknown entry should fail")
+	}
+
+	tags := h.Tags()
+	if len(tags) != 2 || tags[0].Count != 2 || tags[1].Count != 2 || tags[0].Tag != "party" {
+		t.Errorf("tags = %+v", tags)
+	}
+
+	// Persisted
+	reloaded := &History{filePath: h.filePath}
+	reloaded.load()
+	if got := reloaded.GetByID(c.ID); got == nil || got.Notes != "encore" {
+		t.Errorf("reloaded entry = %+v", got)
+	}
+}
+
+func TestApplyTemplate_Tags(t *testing.T) {
+	metadata := &Metadata{Artist: "Artist", Title: "Song", Tags: []string{"Wedding", "Slow/Dance"}}
+	if got := ApplyTemplate("{tag}/{artist} - {title}", metadata); got != filepath.Join("Wedding", "Artist - Song") {
+		t.Errorf("{tag} = %q", got)
+	}
+	if got := ApplyTemplate("{title} [{tags}]", metadata); strings.ContainsAny(got, "/\\") || !strings.Contains(got, "Wedding, Slow") {
+		t.Errorf("{tags} = %q", got)
+	}
+	// No tags: the segment disappears
+	if got := ApplyTemplate("{tag}/{artist} - {title}", &Metadata{Artist: "Artist", Title: "Song"}); got != "Artist - Song" {
+		t.Errorf("untagged = %q", got)
+	}
+	if err := ValidateTemplate("{tag}"); err != nil {
+		t.Errorf("ValidateTemplate({tag}) = %v", err)
+	}
+}
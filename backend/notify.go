@@ -0,0 +1,410 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifications. Instead of one message per download, a digest is sent when
+// a playlist batch finishes or when several items fail in a row. Messages
+// produced during quiet hours are held and sent as one digest afterwards.
+
+// Notification is a single message sent to every configured sink
+type Notification struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers notifications to one sink
+type Notifier interface {
+	Name() string
+	Notify(n Notification) error
+}
+
+// DefaultNotifyFailureStreak is the number of consecutive failures that triggers a digest
+const DefaultNotifyFailureStreak = 3
+
+// =============================================================================
+// SMTP
+// =============================================================================
+
+// SMTPNotifier sends notifications by email. Port 465 uses implicit TLS;
+// other ports upgrade with STARTTLS when the server offers it.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (s *SMTPNotifier) Name() string { return "email" }
+
+func (s *SMTPNotifier) Notify(n Notification) error {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	msg := buildEmailMessage(s.From, s.To, n)
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	if s.Port != 465 {
+		if err := smtp.SendMail(addr, auth, s.From, s.To, msg); err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: s.Host})
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(s.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range s.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildEmailMessage formats a plain-text RFC 5322 message
+func buildEmailMessage(from string, to []string, n Notification) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", sanitizeHeader(n.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(n.Body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// sanitizeHeader strips line breaks so values can't inject extra headers
+func sanitizeHeader(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// =============================================================================
+// Apprise
+// =============================================================================
+
+// AppriseNotifier posts to an Apprise API server. With Targets set the
+// stateless /notify endpoint is used; otherwise URL should point at a
+// stateful /notify/{key} configuration.
+type AppriseNotifier struct {
+	URL     string
+	Targets []string
+	client  *http.Client
+}
+
+func (a *AppriseNotifier) Name() string { return "apprise" }
+
+func (a *AppriseNotifier) Notify(n Notification) error {
+	payload := map[string]interface{}{
+		"title": n.Subject,
+		"body":  n.Body,
+		"type":  "info",
+	}
+	if len(a.Targets) > 0 {
+		payload["urls"] = strings.Join(a.Targets, ",")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Post(a.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("apprise request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("apprise returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// NotifiersFromConfig builds the notifiers enabled in config
+func NotifiersFromConfig(config *Config) []Notifier {
+	var notifiers []Notifier
+	if config == nil {
+		return notifiers
+	}
+
+	if config.NotifyEmailTo != "" && config.SMTPHost != "" {
+		port := config.SMTPPort
+		if port == 0 {
+			port = 587
+		}
+		from := config.SMTPFrom
+		if from == "" {
+			from = config.SMTPUsername
+		}
+		notifiers = append(notifiers, &SMTPNotifier{
+			Host:     config.SMTPHost,
+			Port:     port,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     from,
+			To:       splitList(config.NotifyEmailTo),
+		})
+	}
+	if config.AppriseURL != "" {
+		notifiers = append(notifiers, &AppriseNotifier{
+			URL:     config.AppriseURL,
+			Targets: splitList(config.AppriseTargets),
+		})
+	}
+	if config.MQTTBrokerURL != "" {
+		notifiers = append(notifiers, MQTTNotifier{})
+	}
+	return notifiers
+}
+
+// splitList splits a comma- or whitespace-separated list
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	})
+}
+
+// =============================================================================
+// Digest manager
+// =============================================================================
+
+// NotificationManager turns finished queue items into digests
+type NotificationManager struct {
+	mu            sync.Mutex
+	failures      []QueueItem // current streak of consecutive failures
+	streakSent    bool        // a digest was already sent for this streak
+	held          []Notification
+	flushTimer    *time.Timer
+	notifiersFor  func(*Config) []Notifier
+	now           func() time.Time
+	notifiedBatch map[string]bool
+}
+
+// NewNotificationManager creates a manager using notifiers from config
+func NewNotificationManager() *NotificationManager {
+	return &NotificationManager{
+		notifiersFor:  NotifiersFromConfig,
+		now:           time.Now,
+		notifiedBatch: make(map[string]bool),
+	}
+}
+
+// HandleFinished is called when an item completes or fails. items is a
+// snapshot of the whole queue, used to detect when a playlist batch is done.
+func (m *NotificationManager) HandleFinished(config *Config, item *QueueItem, items []QueueItem) {
+	if item == nil || len(m.notifiersFor(config)) == 0 {
+		return
+	}
+
+	var pending []Notification
+
+	m.mu.Lock()
+	switch item.Status {
+	case StatusError:
+		m.failures = append(m.failures, *item)
+		threshold := config.NotifyFailureStreak
+		if threshold > 0 && len(m.failures) >= threshold && !m.streakSent {
+			m.streakSent = true
+			pending = append(pending, failureDigest(m.failures))
+		}
+	case StatusComplete:
+		m.failures = nil
+		m.streakSent = false
+	}
+
+	if item.PlaylistName != "" && !m.notifiedBatch[item.PlaylistName] {
+		if n, done := batchDigest(item.PlaylistName, items); done {
+			m.notifiedBatch[item.PlaylistName] = true
+			pending = append(pending, n)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, n := range pending {
+		m.send(config, n)
+	}
+}
+
+// send delivers a notification now, or holds it until quiet hours end
+func (m *NotificationManager) send(config *Config, n Notification) {
+	quiet, err := ParseDailyWindow(config.NotifyQuietHours)
+	if err != nil {
+		slog.Warn("ignoring invalid quiet hours", "value", config.NotifyQuietHours, "err", err)
+	}
+
+	now := m.now()
+	if quiet.Contains(now) {
+		m.mu.Lock()
+		m.held = append(m.held, n)
+		if m.flushTimer == nil {
+			m.flushTimer = time.AfterFunc(quiet.NextEnd(now).Sub(now), func() { m.Flush(config) })
+		}
+		m.mu.Unlock()
+		slog.Debug("notification held for quiet hours", "subject", n.Subject)
+		return
+	}
+
+	m.deliver(config, n)
+}
+
+// Flush sends held notifications as a single digest
+func (m *NotificationManager) Flush(config *Config) {
+	m.mu.Lock()
+	held := m.held
+	m.held = nil
+	if m.flushTimer != nil {
+		m.flushTimer.Stop()
+		m.flushTimer = nil
+	}
+	m.mu.Unlock()
+
+	switch len(held) {
+	case 0:
+		return
+	case 1:
+		m.deliver(config, held[0])
+	default:
+		var body strings.Builder
+		for i, n := range held {
+			if i > 0 {
+				body.WriteString("\n\n")
+			}
+			body.WriteString(n.Subject + "\n" + n.Body)
+		}
+		m.deliver(config, Notification{
+			Subject: fmt.Sprintf("YouFlac: %d notifications during quiet hours", len(held)),
+			Body:    body.String(),
+		})
+	}
+}
+
+func (m *NotificationManager) deliver(config *Config, n Notification) {
+	for _, notifier := range m.notifiersFor(config) {
+		if err := notifier.Notify(n); err != nil {
+			slog.Warn("notification failed", "sink", notifier.Name(), "err", err)
+		}
+	}
+}
+
+// failureDigest summarizes a streak of failed items
+func failureDigest(failures []QueueItem) Notification {
+	var body strings.Builder
+	for _, item := range failures {
+		fmt.Fprintf(&body, "✗ %s: %s\n", itemLabel(item), item.Error)
+	}
+	return Notification{
+		Subject: fmt.Sprintf("YouFlac: %d downloads failed in a row", len(failures)),
+		Body:    strings.TrimRight(body.String(), "\n"),
+	}
+}
+
+// batchDigest summarizes a playlist once none of its items are still pending or running
+func batchDigest(playlist string, items []QueueItem) (Notification, bool) {
+	var complete, failed []QueueItem
+	for _, item := range items {
+		if item.PlaylistName != playlist {
+			continue
+		}
+		switch item.Status {
+		case StatusComplete:
+			complete = append(complete, item)
+		case StatusError:
+			failed = append(failed, item)
+		case StatusCancelled:
+		default:
+			return Notification{}, false
+		}
+	}
+
+	total := len(complete) + len(failed)
+	var body strings.Builder
+	fmt.Fprintf(&body, "%d of %d downloads completed.\n", len(complete), total)
+	if len(failed) > 0 {
+		body.WriteString("\nFailed:\n")
+		for _, item := range failed {
+			fmt.Fprintf(&body, "✗ %s: %s\n", itemLabel(item), item.Error)
+		}
+	}
+	return Notification{
+		Subject: fmt.Sprintf("YouFlac: playlist %q finished (%d/%d)", playlist, len(complete), total),
+		Body:    strings.TrimRight(body.String(), "\n"),
+	}, true
+}
+
+func itemLabel(item QueueItem) string {
+	switch {
+	case item.Artist != "" && item.Title != "":
+		return item.Artist + " - " + item.Title
+	case item.Title != "":
+		return item.Title
+	}
+	return item.VideoURL
+}
+
+// SendTestNotification sends a test message to every configured sink,
+// ignoring quiet hours, and returns the first error
+func SendTestNotification(config *Config) error {
+	notifiers := NotifiersFromConfig(config)
+	if len(notifiers) == 0 {
+		return fmt.Errorf("no notification sinks configured")
+	}
+	n := Notification{
+		Subject: "YouFlac: test notification",
+		Body:    "Notifications are working.",
+	}
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(n); err != nil {
+			return fmt.Errorf("%s: %w", notifier.Name(), err)
+		}
+	}
+	return nil
+}
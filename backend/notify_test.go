@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (r *recordingNotifier) Name() string { return "test" }
+
+func (r *recordingNotifier) Notify(n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func newTestNotificationManager(rec *recordingNotifier, now time.Time) *NotificationManager {
+	m := NewNotificationManager()
+	m.notifiersFor = func(*Config) []Notifier { return []Notifier{rec} }
+	m.now = func() time.Time { return now }
+	return m
+}
+
+func TestNotificationManager_FailureStreak(t *testing.T) {
+	rec := &recordingNotifier{}
+	m := newTestNotificationManager(rec, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local))
+	config := GetDefaultConfig()
+
+	fail := QueueItem{Status: StatusError, Title: "Song", Artist: "Artist", Error: "no audio"}
+	for i := 0; i < 4; i++ {
+		m.HandleFinished(config, &fail, nil)
+	}
+	if len(rec.sent) != 1 || !strings.Contains(rec.sent[0].Subject, "3 downloads failed") {
+		t.Fatalf("expected one streak digest, got %+v", rec.sent)
+	}
+
+	// A success resets the streak
+	m.HandleFinished(config, &QueueItem{Status: StatusComplete}, nil)
+	for i := 0; i < 3; i++ {
+		m.HandleFinished(config, &fail, nil)
+	}
+	if len(rec.sent) != 2 {
+		t.Errorf("expected a second digest after the streak reset, got %d", len(rec.sent))
+	}
+}
+
+func TestNotificationManager_PlaylistBatch(t *testing.T) {
+	rec := &recordingNotifier{}
+	m := newTestNotificationManager(rec, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local))
+	config := GetDefaultConfig()
+
+	items := []QueueItem{
+		{ID: "1", PlaylistName: "Mix", Status: StatusComplete, Title: "A"},
+		{ID: "2", PlaylistName: "Mix", Status: StatusDownloadingVideo, Title: "B"},
+		{ID: "3", PlaylistName: "Other", Status: StatusPending},
+	}
+	m.HandleFinished(config, &items[0], items)
+	if len(rec.sent) != 0 {
+		t.Fatalf("batch still running, got %+v", rec.sent)
+	}
+
+	items[1].Status = StatusError
+	items[1].Error = "boom"
+	m.HandleFinished(config, &items[1], items)
+	if len(rec.sent) != 1 {
+		t.Fatalf("expected a batch digest, got %+v", rec.sent)
+	}
+	if n := rec.sent[0]; !strings.Contains(n.Subject, `"Mix" finished (1/2)`) || !strings.Contains(n.Body, "B: boom") {
+		t.Errorf("unexpected digest: %+v", n)
+	}
+
+	m.HandleFinished(config, &items[1], items)
+	if len(rec.sent) != 1 {
+		t.Error("batch digest should only be sent once")
+	}
+}
+
+func TestNotificationManager_QuietHoursHold(t *testing.T) {
+	rec := &recordingNotifier{}
+	m := newTestNotificationManager(rec, time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local))
+	config := GetDefaultConfig()
+	config.NotifyQuietHours = "22:00-07:00"
+	config.NotifyFailureStreak = 1
+
+	m.HandleFinished(config, &QueueItem{Status: StatusError, Title: "A", Error: "x"}, nil)
+	m.HandleFinished(config, &QueueItem{Status: StatusComplete}, nil)
+	m.HandleFinished(config, &QueueItem{Status: StatusError, Title: "B", Error: "y"}, nil)
+	if len(rec.sent) != 0 {
+		t.Fatalf("notifications should be held during quiet hours, got %+v", rec.sent)
+	}
+
+	m.Flush(config)
+	if len(rec.sent) != 1 || !strings.Contains(rec.sent[0].Subject, "2 notifications") {
+		t.Fatalf("expected one combined digest, got %+v", rec.sent)
+	}
+}
+
+func TestAppriseNotifier(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	a := &AppriseNotifier{URL: srv.URL + "/notify", Targets: []string{"mailto://a@b.c", "tgram://x/y"}}
+	if err := a.Notify(Notification{Subject: "hi", Body: "there"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if got["title"] != "hi" || got["body"] != "there" || got["urls"] != "mailto://a@b.c,tgram://x/y" {
+		t.Errorf("unexpected payload: %v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := (&AppriseNotifier{URL: failing.URL}).Notify(Notification{}); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
+
+func TestBuildEmailMessage(t *testing.T) {
+	msg := string(buildEmailMessage("me@x.org", []string{"a@x.org", "b@x.org"}, Notification{
+		Subject: "line\r\nBcc: evil@x.org",
+		Body:    "one\ntwo",
+	}))
+	if !strings.Contains(msg, "To: a@x.org, b@x.org\r\n") {
+		t.Errorf("missing recipients: %q", msg)
+	}
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Errorf("header injection not stripped: %q", msg)
+	}
+	if !strings.HasSuffix(msg, "\r\n\r\none\r\ntwo\r\n") {
+		t.Errorf("body not CRLF-normalized: %q", msg)
+	}
+}
+
+func TestConfigValidate_Notifications(t *testing.T) {
+	config := GetDefaultConfig()
+	config.NotifyEmailTo = "ok@example.com, not-an-address"
+	config.AppriseURL = "apprise:8000"
+	config.NotifyQuietHours = "late"
+
+	v := config.Validate()
+	for _, field := range []string{"notifyEmailTo", "smtpHost", "appriseUrl", "notifyQuietHours"} {
+		if !hasIssue(v.Errors, field) {
+			t.Errorf("expected an error for %s, got %v", field, v.Errors)
+		}
+	}
+}
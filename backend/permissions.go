@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Output permissions. Library files and folders get Config.FileMode and
+// Config.DirMode regardless of the process umask, and can be handed to
+// another user (Config.FileOwner, e.g. the Jellyfin account) when the server
+// runs as root or a member of that group. Every path that writes into the
+// library goes through MkdirOutput, WriteOutputFile or FinishOutputFile.
+
+// Default modes for library files and folders
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// outputPermissions is the parsed form of the permission settings
+type outputPermissions struct {
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	uid, gid int // -1 = leave unchanged
+}
+
+var (
+	outputPerms      = outputPermissions{DefaultFileMode, DefaultDirMode, -1, -1}
+	outputPermsMutex sync.RWMutex
+)
+
+// ParseFileMode parses an octal mode such as "0664" or "775"
+func ParseFileMode(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("invalid mode %q, expected octal like 0644", s)
+	}
+	return os.FileMode(n), nil
+}
+
+// ParseFileOwner parses "uid:gid", "uid" or ":gid"; missing parts are -1
+func ParseFileOwner(s string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return uid, gid, nil
+	}
+	userPart, groupPart, _ := strings.Cut(s, ":")
+	if userPart != "" {
+		if uid, err = strconv.Atoi(userPart); err != nil || uid < 0 {
+			return -1, -1, fmt.Errorf("invalid owner %q, expected numeric uid:gid like 1000:1000", s)
+		}
+	}
+	if groupPart != "" {
+		if gid, err = strconv.Atoi(groupPart); err != nil || gid < 0 {
+			return -1, -1, fmt.Errorf("invalid owner %q, expected numeric uid:gid like 1000:1000", s)
+		}
+	}
+	return uid, gid, nil
+}
+
+// ConfigureOutputPermissions applies the FileMode, DirMode and FileOwner
+// settings. Invalid values keep the defaults (Validate reports them).
+func ConfigureOutputPermissions(config *Config) {
+	perms := outputPermissions{DefaultFileMode, DefaultDirMode, -1, -1}
+	if mode, err := ParseFileMode(config.FileMode); err == nil {
+		perms.fileMode = mode
+	}
+	if mode, err := ParseFileMode(config.DirMode); err == nil {
+		perms.dirMode = mode
+	}
+	if uid, gid, err := ParseFileOwner(config.FileOwner); err == nil && runtime.GOOS != "windows" {
+		perms.uid, perms.gid = uid, gid
+	}
+
+	outputPermsMutex.Lock()
+	defer outputPermsMutex.Unlock()
+	outputPerms = perms
+}
+
+func getOutputPermissions() outputPermissions {
+	outputPermsMutex.RLock()
+	defer outputPermsMutex.RUnlock()
+	return outputPerms
+}
+
+// applyOutputPermissions sets mode and owner on one path
+func applyOutputPermissions(path string, mode os.FileMode, perms outputPermissions) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if perms.uid >= 0 || perms.gid >= 0 {
+		if err := os.Chown(path, perms.uid, perms.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirOutput creates dir and any missing parents with the configured
+// directory mode and owner. Existing directories are left alone.
+func MkdirOutput(dir string) error {
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	perms := getOutputPermissions()
+	if err := os.MkdirAll(dir, perms.dirMode); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := applyOutputPermissions(missing[i], perms.dirMode, perms); err != nil {
+			slog.Warn("failed to set folder permissions", "path", missing[i], "err", err)
+		}
+	}
+	return nil
+}
+
+// FinishOutputFile applies the configured file mode and owner to a file
+// written by an external tool (ffmpeg, mkvmerge) or replaced by a rewrite.
+// Failures are logged; the file itself is fine.
+func FinishOutputFile(path string) {
+	perms := getOutputPermissions()
+	if err := applyOutputPermissions(path, perms.fileMode, perms); err != nil {
+		slog.Warn("failed to set file permissions", "path", path, "err", err)
+	}
+}
+
+// WriteOutputFile writes a library file, creating its folder if needed
+func WriteOutputFile(path string, data []byte) error {
+	if err := MkdirOutput(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, getOutputPermissions().fileMode); err != nil {
+		return err
+	}
+	FinishOutputFile(path)
+	return nil
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestParseFileOwner(t *testing.T) {
+	tests := []struct {
+		in       string
+		uid, gid int
+		ok       bool
+	}{
+		{"", -1, -1, true},
+		{"1000:100", 1000, 100, true},
+		{"1000", 1000, -1, true},
+		{":100", -1, 100, true},
+		{"jellyfin:media", -1, -1, false},
+		{"-5:1", -1, -1, false},
+	}
+	for _, tt := range tests {
+		uid, gid, err := ParseFileOwner(tt.in)
+		if (err == nil) != tt.ok || uid != tt.uid || gid != tt.gid {
+			t.Errorf("ParseFileOwner(%q) = %d, %d, %v", tt.in, uid, gid, err)
+		}
+	}
+
+	if mode, err := ParseFileMode("664"); err != nil || mode != 0664 {
+		t.Errorf("ParseFileMode(664) = %v, %v", mode, err)
+	}
+	for _, bad := range []string{"rw-r--r--", "0888", "01777"} {
+		if _, err := ParseFileMode(bad); err == nil {
+			t.Errorf("ParseFileMode(%q) should fail", bad)
+		}
+	}
+}
+
+func TestOutputPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX modes")
+	}
+	defer ConfigureOutputPermissions(&Config{})
+
+	// Chown to our own uid/gid always succeeds, so the owner path is exercised too
+	owner := strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+	ConfigureOutputPermissions(&Config{FileMode: "0660", DirMode: "0770", FileOwner: owner})
+
+	root := t.TempDir()
+	os.Chmod(root, 0700)
+	path := filepath.Join(root, "Artist", "Song", "Song.nfo")
+	if err := WriteOutputFile(path, []byte("<musicvideo/>")); err != nil {
+		t.Fatalf("WriteOutputFile: %v", err)
+	}
+
+	for _, tt := range []struct {
+		path string
+		mode os.FileMode
+	}{
+		{path, 0660},
+		{filepath.Join(root, "Artist"), 0770},
+		{filepath.Join(root, "Artist", "Song"), 0770},
+		{root, 0700}, // existing folders are left alone
+	} {
+		info, err := os.Stat(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != tt.mode {
+			t.Errorf("%s mode = %v, want %v", tt.path, info.Mode().Perm(), tt.mode)
+		}
+	}
+
+	// Files rewritten by external tools get the mode back
+	mkv := filepath.Join(root, "Artist", "Song", "Song.mkv")
+	os.WriteFile(mkv, nil, 0600)
+	FinishOutputFile(mkv)
+	if info, _ := os.Stat(mkv); info.Mode().Perm() != 0660 {
+		t.Errorf("finished file mode = %v, want 0660", info.Mode().Perm())
+	}
+}
@@ -2,7 +2,6 @@ package backend
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 )
@@ -16,7 +15,7 @@ func GenerateM3U8(items []QueueItem, outputDir, playlistName string) error {
 		return nil
 	}
 
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+	if err := MkdirOutput(outputDir); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
@@ -54,5 +53,5 @@ func GenerateM3U8(items []QueueItem, outputDir, playlistName string) error {
 		sb.WriteString(rel + "\n")
 	}
 
-	return os.WriteFile(m3u8Path, []byte(sb.String()), 0644)
+	return WriteOutputFile(m3u8Path, []byte(sb.String()))
 }
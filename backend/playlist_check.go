@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Playlist pre-check. Before a playlist is imported every entry is checked
+// against the download history, the library index, the duration limits and
+// the availability yt-dlp reports in the flat playlist, so deleted or private
+// videos, Shorts and songs already in the library are shown to the user
+// instead of being queued to fail or download twice.
+
+// Pre-check outcomes for one playlist entry
+const (
+	PlaylistEntryNew         = "new"         // Will be queued
+	PlaylistEntryDownloaded  = "downloaded"  // Completed before (history), output still present or imported from a yt-dlp archive
+	PlaylistEntryInLibrary   = "in_library"  // Matching file found in the library index
+	PlaylistEntryUnavailable = "unavailable" // Deleted, private or members-only
+	PlaylistEntryFiltered    = "filtered"    // Outside the duration limits
+)
+
+// PlaylistEntryCheck is the pre-check result for one entry
+type PlaylistEntryCheck struct {
+	Video        PlaylistVideo `json:"video"`
+	Status       string        `json:"status"` // new, downloaded, in_library, unavailable, filtered
+	Detail       string        `json:"detail,omitempty"`
+	ExistingPath string        `json:"existingPath,omitempty"`
+}
+
+// PlaylistCheck is the pre-import summary of a playlist
+type PlaylistCheck struct {
+	PlaylistID    string               `json:"playlistId"`
+	PlaylistTitle string               `json:"playlistTitle"`
+	Entries       []PlaylistEntryCheck `json:"entries"`
+	New           int                  `json:"new"`
+	Downloaded    int                  `json:"downloaded"`
+	InLibrary     int                  `json:"inLibrary"`
+	Unavailable   int                  `json:"unavailable"`
+	Filtered      int                  `json:"filtered"`
+	Flagged       int                  `json:"flagged"` // New entries outside the duration limits (DurationPolicyFlag)
+	Partial       bool                 `json:"partial,omitempty"`
+	Continuation  string               `json:"continuation,omitempty"`
+}
+
+// unavailableTitles are the placeholder titles yt-dlp lists for removed entries
+var unavailableTitles = map[string]string{
+	"[deleted video]": "video was deleted",
+	"[private video]": "video is private",
+}
+
+// unavailableStates maps yt-dlp availability values that cannot be downloaded
+var unavailableStates = map[string]string{
+	"private":         "video is private",
+	"premium_only":    "video requires YouTube Premium",
+	"subscriber_only": "video is members-only",
+	"needs_auth":      "video requires sign-in",
+}
+
+// playlistEntryUnavailable returns why video cannot be downloaded, or ""
+func playlistEntryUnavailable(video PlaylistVideo) string {
+	if reason, ok := unavailableTitles[strings.ToLower(strings.TrimSpace(video.Title))]; ok {
+		return reason
+	}
+	return unavailableStates[video.Availability]
+}
+
+// CheckPlaylist classifies every entry of info. config, history and
+// fileIndex may be nil.
+func CheckPlaylist(info *PlaylistInfo, config *Config, history *History, fileIndex *FileIndex) *PlaylistCheck {
+	check := &PlaylistCheck{
+		PlaylistID:    info.ID,
+		PlaylistTitle: info.Title,
+		Entries:       make([]PlaylistEntryCheck, 0, len(info.Videos)),
+		Partial:       info.Partial,
+		Continuation:  info.Continuation,
+	}
+
+	// Completed downloads by video ID. Videos imported from a yt-dlp archive
+	// have no output file here but count as downloaded.
+	downloaded := map[string]string{}
+	archived := map[string]bool{}
+	if history != nil {
+		for _, entry := range history.FilterByStatus("complete") {
+			videoID, err := ParseYouTubeURL(entry.VideoURL)
+			if err != nil {
+				continue
+			}
+			if entry.AudioSource == ArchiveImportSource {
+				archived[videoID] = true
+				continue
+			}
+			if entry.OutputPath == "" || !fileExists(entry.OutputPath) {
+				continue
+			}
+			downloaded[videoID] = entry.OutputPath
+		}
+	}
+
+	// Playlist entries are queued as music videos
+	var scope IndexScope
+	if config != nil {
+		scope = LibraryIndexScope(config, &QueueItem{OutputMode: OutputModeVideo})
+	}
+
+	for _, video := range info.Videos {
+		result := PlaylistEntryCheck{Video: video, Status: PlaylistEntryNew}
+		outOfRange := DurationOutsideLimits(video.Duration, config)
+		if reason := playlistEntryUnavailable(video); reason != "" {
+			result.Status = PlaylistEntryUnavailable
+			result.Detail = reason
+			check.Unavailable++
+		} else if outOfRange != "" && skipsOutOfRange(config) {
+			result.Status = PlaylistEntryFiltered
+			result.Detail = outOfRange
+			check.Filtered++
+		} else if path, ok := downloaded[video.ID]; ok {
+			result.Status = PlaylistEntryDownloaded
+			result.ExistingPath = path
+			result.Detail = "downloaded before"
+			check.Downloaded++
+		} else if archived[video.ID] {
+			result.Status = PlaylistEntryDownloaded
+			result.Detail = "in imported yt-dlp archive"
+			check.Downloaded++
+		} else if existing := findInLibrary(fileIndex, scope, video); existing != nil {
+			result.Status = PlaylistEntryInLibrary
+			result.ExistingPath = existing.Path
+			result.Detail = fmt.Sprintf("matches %s - %s in library", existing.Artist, existing.Title)
+			check.InLibrary++
+		} else {
+			if outOfRange != "" {
+				result.Detail = outOfRange
+				check.Flagged++
+			}
+			check.New++
+		}
+		check.Entries = append(check.Entries, result)
+	}
+
+	return check
+}
+
+func findInLibrary(fileIndex *FileIndex, scope IndexScope, video PlaylistVideo) *FileIndexEntry {
+	if fileIndex == nil {
+		return nil
+	}
+	return fileIndex.FindVideo(scope, video.ID, video.Title, video.Artist, video.Duration)
+}
+
+// Importable returns the entries to queue. Downloaded and in-library entries
+// are included only with includeExisting; unavailable and filtered ones never are.
+func (c *PlaylistCheck) Importable(includeExisting bool) []PlaylistVideo {
+	var videos []PlaylistVideo
+	for _, entry := range c.Entries {
+		switch entry.Status {
+		case PlaylistEntryNew:
+			videos = append(videos, entry.Video)
+		case PlaylistEntryDownloaded, PlaylistEntryInLibrary:
+			if includeExisting {
+				videos = append(videos, entry.Video)
+			}
+		}
+	}
+	return videos
+}
@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPlaylist(t *testing.T) {
+	dir := t.TempDir()
+	downloaded := filepath.Join(dir, "Artist", "Old Song.mkv")
+	inLibrary := filepath.Join(dir, "Artist", "Library Song.flac")
+	writeTestFile(t, downloaded, 16)
+	writeTestFile(t, inLibrary, 16)
+
+	history := &History{filePath: filepath.Join(dir, "history.json")}
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=old00000001", OutputPath: downloaded, Status: "complete"})
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=gone0000001", OutputPath: filepath.Join(dir, "deleted.mkv"), Status: "complete"})
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=fail0000001", Status: "error"})
+
+	fileIndex := NewFileIndex(dir)
+	fileIndex.AddEntry(FileIndexEntry{Path: inLibrary, Title: "Library Song", Artist: "Artist"})
+
+	info := &PlaylistInfo{ID: "PL1", Title: "Mix", Videos: []PlaylistVideo{
+		{ID: "old00000001", Title: "Old Song", Artist: "Artist"},
+		{ID: "lib00000001", Title: "Library Song", Artist: "Artist"},
+		{ID: "gone0000001", Title: "Removed From Disk", Artist: "Artist"},
+		{ID: "fail0000001", Title: "Failed Before", Artist: "Artist"},
+		{ID: "del00000001", Title: "[Deleted video]"},
+		{ID: "prv00000001", Title: "Members Song", Availability: "subscriber_only"},
+	}}
+
+	check := CheckPlaylist(info, nil, history, fileIndex)
+	want := []string{
+		PlaylistEntryDownloaded, PlaylistEntryInLibrary, PlaylistEntryNew,
+		PlaylistEntryNew, PlaylistEntryUnavailable, PlaylistEntryUnavailable,
+	}
+	for i, entry := range check.Entries {
+		if entry.Status != want[i] {
+			t.Errorf("%s: status %q, want %q", entry.Video.ID, entry.Status, want[i])
+		}
+	}
+	if check.New != 2 || check.Downloaded != 1 || check.InLibrary != 1 || check.Unavailable != 2 {
+		t.Errorf("summary = %d new, %d downloaded, %d in library, %d unavailable",
+			check.New, check.Downloaded, check.InLibrary, check.Unavailable)
+	}
+	if check.Entries[0].ExistingPath != downloaded || check.Entries[1].ExistingPath != inLibrary {
+		t.Errorf("existing paths = %q, %q", check.Entries[0].ExistingPath, check.Entries[1].ExistingPath)
+	}
+
+	if got := check.Importable(false); len(got) != 2 || got[0].ID != "gone0000001" {
+		t.Errorf("importable = %+v", got)
+	}
+	if got := check.Importable(true); len(got) != 4 {
+		t.Errorf("importable with existing = %d entries, want 4", len(got))
+	}
+
+	if check := CheckPlaylist(info, nil, nil, nil); check.New != 4 || check.Unavailable != 2 {
+		t.Errorf("without history/index: %d new, %d unavailable", check.New, check.Unavailable)
+	}
+}
+
+func TestCheckPlaylist_DurationLimits(t *testing.T) {
+	info := &PlaylistInfo{Videos: []PlaylistVideo{
+		{ID: "short000001", Title: "Teaser", Duration: 30},
+		{ID: "song0000001", Title: "Song", Duration: 210},
+		{ID: "loop0000001", Title: "10 Hours", Duration: 36000},
+		{ID: "unkn0000001", Title: "No Duration"},
+	}}
+	config := &Config{MinVideoDuration: 60, MaxVideoDuration: 3600, DurationPolicy: DurationPolicySkip}
+
+	check := CheckPlaylist(info, config, nil, nil)
+	if check.Filtered != 2 || check.New != 2 || len(check.Importable(true)) != 2 {
+		t.Errorf("skip policy: %d filtered, %d new", check.Filtered, check.New)
+	}
+	if check.Entries[0].Status != PlaylistEntryFiltered || check.Entries[0].Detail != "0:30 is shorter than the minimum of 1:00" {
+		t.Errorf("short entry = %+v", check.Entries[0])
+	}
+
+	config.DurationPolicy = DurationPolicyFlag
+	check = CheckPlaylist(info, config, nil, nil)
+	if check.Filtered != 0 || check.Flagged != 2 || check.New != 4 {
+		t.Errorf("flag policy: %d filtered, %d flagged, %d new", check.Filtered, check.Flagged, check.New)
+	}
+	if check.Entries[2].Detail != "10:00:00 is longer than the maximum of 1:00:00" {
+		t.Errorf("loop entry detail = %q", check.Entries[2].Detail)
+	}
+}
@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Playlist fetching. yt-dlp prints one JSON line per entry with
+// --flat-playlist, so entries are parsed as they arrive instead of waiting
+// for the whole output. The deadline is adaptive: the first entry gets
+// playlistFirstEntryTimeout, after that the fetch continues as long as
+// entries keep coming (playlistIdleTimeout between them), up to
+// playlistMaxFetchTime. When it stops early, the entries received so far are
+// returned with a continuation token for the rest.
+
+var (
+	playlistFirstEntryTimeout = 60 * time.Second
+	playlistIdleTimeout       = 30 * time.Second
+	playlistMaxFetchTime      = 15 * time.Minute
+)
+
+// playlistIDPattern matches the IDs accepted in continuation tokens
+var playlistIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// playlistCommand starts yt-dlp; replaced in tests
+var playlistCommand = func(ctx context.Context, args ...string) *exec.Cmd {
+	return newCommandContext(ctx, "yt-dlp", args...)
+}
+
+// PlaylistFetchOptions selects a page of a playlist
+type PlaylistFetchOptions struct {
+	Start int // 1-based index of the first entry, 0 = from the beginning
+	Limit int // Maximum entries to fetch, 0 = all
+}
+
+// GetPlaylistVideos fetches all videos from a YouTube playlist
+// Uses yt-dlp --flat-playlist for fast metadata extraction
+func GetPlaylistVideos(playlistURL string) (*PlaylistInfo, error) {
+	return GetPlaylistPage(playlistURL, PlaylistFetchOptions{})
+}
+
+// GetPlaylistPage fetches part of a playlist. The result is Partial when the
+// deadline or Limit cut it short.
+func GetPlaylistPage(playlistURL string, opts PlaylistFetchOptions) (*PlaylistInfo, error) {
+	playlistID := ExtractPlaylistID(playlistURL)
+	if playlistID == "" {
+		return nil, fmt.Errorf("could not extract playlist ID from URL: %s", playlistURL)
+	}
+	return fetchPlaylist(playlistID, opts)
+}
+
+// ContinuePlaylist fetches the entries after a partial result
+func ContinuePlaylist(continuation string, limit int) (*PlaylistInfo, error) {
+	playlistID, start, err := decodePlaylistContinuation(continuation)
+	if err != nil {
+		return nil, err
+	}
+	return fetchPlaylist(playlistID, PlaylistFetchOptions{Start: start, Limit: limit})
+}
+
+func fetchPlaylist(playlistID string, opts PlaylistFetchOptions) (*PlaylistInfo, error) {
+	if key := getYouTubeAPIKey(); key != "" {
+		info, err := fetchPlaylistAPI(key, playlistID, opts)
+		if err == nil {
+			return info, nil
+		}
+		slog.Warn("YouTube API playlist listing failed, falling back to yt-dlp", "playlist", playlistID, "err", err)
+	}
+
+	// Construct canonical playlist URL
+	canonicalURL := fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID)
+
+	info, next, err := fetchPlaylistEntries(canonicalURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	info.ID = playlistID
+	if info.Partial {
+		info.Continuation = encodePlaylistContinuation(playlistID, next)
+	}
+	return info, nil
+}
+
+// fetchPlaylistEntries lists any yt-dlp playlist-like URL (playlist, channel
+// tab) with the adaptive deadline. next is the index after the last entry.
+func fetchPlaylistEntries(sourceURL string, opts PlaylistFetchOptions) (info *PlaylistInfo, next int, err error) {
+	start := max(opts.Start, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), playlistMaxFetchTime)
+	defer cancel()
+
+	args := []string{"--flat-playlist", "-j", "--no-warnings", "--playlist-start", strconv.Itoa(start)}
+	if opts.Limit > 0 {
+		args = append(args, "--playlist-end", strconv.Itoa(start+opts.Limit-1))
+	}
+	args = append(args, sourceURL)
+
+	cmd := playlistCommand(ctx, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+
+	// Adaptive deadline: every entry buys the fetch another idle window
+	idle := time.AfterFunc(playlistFirstEntryTimeout, cancel)
+	defer idle.Stop()
+
+	info = &PlaylistInfo{}
+	read := readPlaylistEntries(stdout, info, start, func() { idle.Reset(playlistIdleTimeout) })
+	waitErr := cmd.Wait()
+	stoppedEarly := ctx.Err() != nil
+
+	if len(info.Videos) == 0 {
+		if stoppedEarly {
+			return nil, 0, fmt.Errorf("failed to fetch playlist: no entries received before the deadline")
+		}
+		if waitErr != nil {
+			return nil, 0, fmt.Errorf("failed to fetch playlist: %w, output: %s", waitErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, 0, fmt.Errorf("playlist is empty or unavailable")
+	}
+
+	// Entries without an ID (deleted or private videos) still take up an index
+	next = start + read
+	switch {
+	case stoppedEarly:
+		slog.Warn("playlist fetch stopped early, returning partial results", "url", sourceURL, "entries", len(info.Videos))
+		info.Partial = true
+	case waitErr != nil:
+		// yt-dlp failed mid-way (e.g. rate limited); keep what was parsed
+		slog.Warn("playlist fetch failed mid-way, returning partial results", "url", sourceURL, "entries", len(info.Videos), "err", waitErr)
+		info.Partial = true
+	case opts.Limit > 0 && read >= opts.Limit && (info.Total == 0 || next <= info.Total):
+		info.Partial = true
+	}
+	return info, next, nil
+}
+
+// readPlaylistEntries parses yt-dlp JSON lines into info as they arrive.
+// Positions continue from start and count every parsed line, including
+// entries without an ID that are left out; onEntry is called after each
+// parsed line. Returns the number of parsed lines.
+func readPlaylistEntries(r io.Reader, info *PlaylistInfo, start int, onEntry func()) int {
+	read := 0
+
+	err := readJSONLines(r, func(line []byte) {
+		var entry struct {
+			ID               string  `json:"id"`
+			Title            string  `json:"title"`
+			Duration         float64 `json:"duration"`
+			Channel          string  `json:"channel"`
+			Uploader         string  `json:"uploader"`
+			Thumbnail        string  `json:"thumbnail"`
+			Availability     string  `json:"availability"`
+			PlaylistTitle    string  `json:"playlist_title"`
+			PlaylistUploader string  `json:"playlist_uploader"`
+			PlaylistCount    int     `json:"playlist_count"`
+			UploadDate       string  `json:"upload_date"`
+			Timestamp        int64   `json:"timestamp"`
+		}
+
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return // Skip malformed entries
+		}
+		read++
+		if onEntry != nil {
+			onEntry()
+		}
+
+		// Extract playlist info from first entry
+		if info.Title == "" && entry.PlaylistTitle != "" {
+			info.Title = entry.PlaylistTitle
+			info.Author = entry.PlaylistUploader
+		}
+		if entry.PlaylistCount > 0 {
+			info.Total = entry.PlaylistCount
+		}
+
+		if entry.ID == "" {
+			return
+		}
+
+		position := start + read - 1
+
+		artist := entry.Channel
+		if artist == "" {
+			artist = entry.Uploader
+		}
+		// Clean up "- Topic" suffix from auto-generated channels
+		artist = strings.TrimSuffix(artist, " - Topic")
+
+		// Flat listings carry an upload date for some sources only
+		uploadDate := entry.UploadDate
+		if uploadDate == "" && entry.Timestamp > 0 {
+			uploadDate = time.Unix(entry.Timestamp, 0).UTC().Format("20060102")
+		}
+
+		// Get best thumbnail
+		thumbnail := entry.Thumbnail
+		if thumbnail == "" {
+			thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID)
+		}
+
+		info.Videos = append(info.Videos, PlaylistVideo{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Artist:    artist,
+			Duration:  entry.Duration,
+			Thumbnail: thumbnail,
+			URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
+			Position:  position, // Assign 1-based position
+
+			Availability: entry.Availability,
+			UploadDate:   uploadDate,
+		})
+	})
+	if err != nil {
+		// The entries read so far are kept; the rest of the listing is lost
+		slog.Warn("playlist listing cut short", "entries", len(info.Videos), "err", err)
+		info.Partial = true
+	}
+	return read
+}
+
+// encodePlaylistContinuation packs the playlist ID and next index into an opaque token
+func encodePlaylistContinuation(playlistID string, next int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(playlistID + ":" + strconv.Itoa(next)))
+}
+
+func decodePlaylistContinuation(token string) (string, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid continuation token")
+	}
+	playlistID, startStr, ok := strings.Cut(string(data), ":")
+	start, err := strconv.Atoi(startStr)
+	if !ok || err != nil || start < 1 || playlistID == "" || !playlistIDPattern.MatchString(playlistID) {
+		return "", 0, fmt.Errorf("invalid continuation token")
+	}
+	return playlistID, start, nil
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPlaylistEntries(t *testing.T) {
+	input := `{"id":"aaa","title":"One","channel":"Artist - Topic","playlist_title":"Mix","playlist_uploader":"Me","playlist_count":3}
+not json
+{"title":"[Deleted video]"}
+
+{"id":"bbb","title":"Two","uploader":"Other","thumbnail":"https://example.com/b.jpg"}
+`
+	info := &PlaylistInfo{}
+	calls := 0
+	read := readPlaylistEntries(strings.NewReader(input), info, 11, func() { calls++ })
+
+	if len(info.Videos) != 2 || calls != 3 || read != 3 {
+		t.Fatalf("got %d videos, %d callbacks, %d lines read", len(info.Videos), calls, read)
+	}
+	if info.Title != "Mix" || info.Author != "Me" || info.Total != 3 {
+		t.Errorf("playlist info = %q %q %d", info.Title, info.Author, info.Total)
+	}
+	// The deleted video keeps its index
+	first, second := info.Videos[0], info.Videos[1]
+	if first.Position != 11 || second.Position != 13 {
+		t.Errorf("positions = %d, %d, want 11, 13", first.Position, second.Position)
+	}
+	if first.Artist != "Artist" || first.Thumbnail != "https://i.ytimg.com/vi/aaa/hqdefault.jpg" {
+		t.Errorf("first = %+v", first)
+	}
+	if second.Artist != "Other" || second.URL != "https://www.youtube.com/watch?v=bbb" {
+		t.Errorf("second = %+v", second)
+	}
+}
+
+func TestPlaylistContinuation(t *testing.T) {
+	token := encodePlaylistContinuation("PLabc_123-x", 201)
+	id, start, err := decodePlaylistContinuation(token)
+	if err != nil || id != "PLabc_123-x" || start != 201 {
+		t.Fatalf("round trip = %q, %d, %v", id, start, err)
+	}
+
+	for _, bad := range []string{"", "!!!", encodePlaylistContinuation("PL1", 0), encodePlaylistContinuation("PL/../x", 5)} {
+		if _, _, err := decodePlaylistContinuation(bad); err == nil {
+			t.Errorf("token %q should be rejected", bad)
+		}
+	}
+}
+
+// fakePlaylistCommand replaces yt-dlp with a shell script for one test
+func fakePlaylistCommand(t *testing.T, script string) *[]string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake yt-dlp is a shell script")
+	}
+	var gotArgs []string
+	playlistCommand = func(ctx context.Context, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.CommandContext(ctx, "sh", "-c", script)
+	}
+	t.Cleanup(func() {
+		playlistCommand = func(ctx context.Context, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "yt-dlp", args...)
+		}
+	})
+	return &gotArgs
+}
+
+func TestFetchPlaylist_IdleDeadlineReturnsPartial(t *testing.T) {
+	fakePlaylistCommand(t, `echo '{"id":"v1","playlist_title":"Big","playlist_count":500}'; echo '{"id":"v2"}'; exec sleep 10`)
+	defer func(first, idle time.Duration) {
+		playlistFirstEntryTimeout, playlistIdleTimeout = first, idle
+	}(playlistFirstEntryTimeout, playlistIdleTimeout)
+	playlistFirstEntryTimeout, playlistIdleTimeout = 5*time.Second, 200*time.Millisecond
+
+	started := time.Now()
+	info, err := fetchPlaylist("PLbig", PlaylistFetchOptions{})
+	if err != nil {
+		t.Fatalf("fetchPlaylist: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("fetch took %v, idle deadline not applied", elapsed)
+	}
+	if !info.Partial || len(info.Videos) != 2 || info.Total != 500 {
+		t.Fatalf("info = partial %v, %d videos, total %d", info.Partial, len(info.Videos), info.Total)
+	}
+	id, start, err := decodePlaylistContinuation(info.Continuation)
+	if err != nil || id != "PLbig" || start != 3 {
+		t.Errorf("continuation = %q, %d, %v", id, start, err)
+	}
+}
+
+func TestFetchPlaylist_Pages(t *testing.T) {
+	args := fakePlaylistCommand(t, `echo '{"id":"v3","playlist_count":4}'; echo '{"id":"v4"}'`)
+
+	info, err := ContinuePlaylist(encodePlaylistContinuation("PLpage", 3), 2)
+	if err != nil {
+		t.Fatalf("ContinuePlaylist: %v", err)
+	}
+	if got := strings.Join(*args, " "); !strings.Contains(got, "--playlist-start 3 --playlist-end 4") {
+		t.Errorf("yt-dlp args = %s", got)
+	}
+	if info.Videos[0].Position != 3 || info.Partial || info.Continuation != "" {
+		t.Errorf("last page = %+v", info)
+	}
+
+	// A full page of a longer playlist has more to fetch, even when an
+	// entry of it has no ID
+	fakePlaylistCommand(t, `echo '{"id":"v1","playlist_count":4}'; echo '{"title":"[Private video]"}'`)
+	info, err = GetPlaylistPage("https://www.youtube.com/playlist?list=PLpage", PlaylistFetchOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetPlaylistPage: %v", err)
+	}
+	if !info.Partial || info.Continuation != encodePlaylistContinuation("PLpage", 3) {
+		t.Errorf("first page = %+v", info)
+	}
+}
+
+func TestFetchPlaylist_NoEntries(t *testing.T) {
+	fakePlaylistCommand(t, `echo 'ERROR: playlist does not exist' >&2; exit 1`)
+	if _, err := fetchPlaylist("PLgone", PlaylistFetchOptions{}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("err = %v", err)
+	}
+}
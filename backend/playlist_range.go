@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Range-limited playlist import. Huge auto-generated playlists (mixes,
+// "liked" lists, channel uploads) rarely need importing whole: a
+// PlaylistRange imports a slice of positions (items 50-100), only entries
+// uploaded since a date, or queues the entries last-first. The position
+// range is passed on to the fetch, so entries outside it are not listed at
+// all; the date filter can only use the upload dates the listing reports,
+// so entries without one are kept.
+
+// PlaylistRange selects the part of a playlist to import. The zero value
+// selects the whole playlist in order.
+type PlaylistRange struct {
+	From    int    `json:"from,omitempty"`    // 1-based first position, 0 = from the start
+	To      int    `json:"to,omitempty"`      // 1-based last position (inclusive), 0 = to the end
+	Reverse bool   `json:"reverse,omitempty"` // Queue the last selected entry first
+	After   string `json:"after,omitempty"`   // Only entries uploaded on or after this date (YYYY-MM-DD)
+}
+
+// Validate checks the range and normalizes After to YYYYMMDD
+func (r *PlaylistRange) Validate() error {
+	if r.From < 0 || r.To < 0 {
+		return fmt.Errorf("playlist range positions must not be negative")
+	}
+	if r.To > 0 && r.To < max(r.From, 1) {
+		return fmt.Errorf("playlist range ends (%d) before it starts (%d)", r.To, r.From)
+	}
+	if r.After != "" {
+		date, err := parsePlaylistDate(r.After)
+		if err != nil {
+			return err
+		}
+		r.After = date
+	}
+	return nil
+}
+
+// parsePlaylistDate accepts YYYY-MM-DD or YYYYMMDD and returns YYYYMMDD
+func parsePlaylistDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("20060102"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+}
+
+// FetchOptions returns the fetch options covering the range. limit caps the
+// entries fetched per page (0 = the whole range).
+func (r PlaylistRange) FetchOptions(limit int) PlaylistFetchOptions {
+	opts := PlaylistFetchOptions{Start: r.From, Limit: limit}
+	if r.To > 0 {
+		size := r.To - max(r.From, 1) + 1
+		if opts.Limit == 0 || size < opts.Limit {
+			opts.Limit = size
+		}
+	}
+	return opts
+}
+
+// Select narrows info.Videos to the range, in queue order. A fetch that
+// stopped at the end of the range is not partial: the rest of the playlist
+// was not asked for.
+func (r PlaylistRange) Select(info *PlaylistInfo) {
+	last := 0
+	selected := make([]PlaylistVideo, 0, len(info.Videos))
+	for _, video := range info.Videos {
+		last = max(last, video.Position)
+		if video.Position < r.From || (r.To > 0 && video.Position > r.To) {
+			continue
+		}
+		if r.After != "" && video.UploadDate != "" && video.UploadDate < r.After {
+			continue
+		}
+		selected = append(selected, video)
+	}
+	if r.Reverse {
+		slices.Reverse(selected)
+	}
+	info.Videos = selected
+
+	if info.Partial && r.To > 0 && last >= r.To {
+		info.Partial = false
+		info.Continuation = ""
+	}
+}
+
+// GetPlaylistRange fetches the part of a playlist rng selects; limit caps
+// the entries fetched (0 = the whole range). A partial result's
+// continuation goes to ContinuePlaylistRange with the same range.
+func GetPlaylistRange(playlistURL string, rng PlaylistRange, limit int) (*PlaylistInfo, error) {
+	info, err := GetPlaylistPage(playlistURL, rng.FetchOptions(limit))
+	if err != nil {
+		return nil, err
+	}
+	rng.Select(info)
+	return info, nil
+}
+
+// ContinuePlaylistRange fetches the entries of rng after a partial result
+func ContinuePlaylistRange(continuation string, rng PlaylistRange, limit int) (*PlaylistInfo, error) {
+	playlistID, start, err := decodePlaylistContinuation(continuation)
+	if err != nil {
+		return nil, err
+	}
+	if rng.To > 0 && start > rng.To {
+		return nil, fmt.Errorf("playlist range %d-%d is already fetched", max(rng.From, 1), rng.To)
+	}
+	rest := rng
+	rest.From = max(rng.From, start)
+	info, err := fetchPlaylist(playlistID, rest.FetchOptions(limit))
+	if err != nil {
+		return nil, err
+	}
+	rng.Select(info)
+	return info, nil
+}
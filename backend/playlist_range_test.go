@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlaylistRangeValidate(t *testing.T) {
+	rng := PlaylistRange{From: 50, To: 100, After: "2024-03-01"}
+	if err := rng.Validate(); err != nil || rng.After != "20240301" {
+		t.Fatalf("Validate = %v, After = %q", err, rng.After)
+	}
+	for _, bad := range []PlaylistRange{{From: -1}, {To: -5}, {From: 10, To: 5}, {After: "March 1st"}, {After: "2024-13-01"}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("range %+v should be rejected", bad)
+		}
+	}
+}
+
+func TestPlaylistRangeFetchOptions(t *testing.T) {
+	tests := []struct {
+		rng   PlaylistRange
+		limit int
+		want  PlaylistFetchOptions
+	}{
+		{PlaylistRange{}, 0, PlaylistFetchOptions{}},
+		{PlaylistRange{}, 25, PlaylistFetchOptions{Limit: 25}},
+		{PlaylistRange{From: 50, To: 100}, 0, PlaylistFetchOptions{Start: 50, Limit: 51}},
+		{PlaylistRange{From: 50, To: 100}, 20, PlaylistFetchOptions{Start: 50, Limit: 20}},
+		{PlaylistRange{To: 10}, 0, PlaylistFetchOptions{Limit: 10}},
+		{PlaylistRange{From: 7}, 0, PlaylistFetchOptions{Start: 7}},
+	}
+	for _, tt := range tests {
+		if got := tt.rng.FetchOptions(tt.limit); got != tt.want {
+			t.Errorf("%+v.FetchOptions(%d) = %+v, want %+v", tt.rng, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestPlaylistRangeSelect(t *testing.T) {
+	videos := []PlaylistVideo{
+		{ID: "a", Position: 1, UploadDate: "20230101"},
+		{ID: "b", Position: 2, UploadDate: "20240301"},
+		{ID: "c", Position: 3},
+		{ID: "d", Position: 4, UploadDate: "20240501"},
+	}
+	ids := func(info *PlaylistInfo) []string {
+		var out []string
+		for _, v := range info.Videos {
+			out = append(out, v.ID)
+		}
+		return out
+	}
+
+	info := &PlaylistInfo{Videos: slices.Clone(videos)}
+	PlaylistRange{From: 2, To: 3}.Select(info)
+	if got := ids(info); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("positions 2-3 = %v", got)
+	}
+
+	info = &PlaylistInfo{Videos: slices.Clone(videos)}
+	PlaylistRange{After: "20240301", Reverse: true}.Select(info)
+	if got := ids(info); !slices.Equal(got, []string{"d", "c", "b"}) {
+		t.Errorf("after 2024-03-01 reversed = %v, want undated entries kept", got)
+	}
+
+	// A page that reached the end of the range is complete
+	info = &PlaylistInfo{Videos: slices.Clone(videos), Partial: true, Continuation: "token"}
+	PlaylistRange{To: 4}.Select(info)
+	if info.Partial || info.Continuation != "" {
+		t.Errorf("range end reached but result still partial")
+	}
+	info = &PlaylistInfo{Videos: slices.Clone(videos), Partial: true, Continuation: "token"}
+	PlaylistRange{To: 10}.Select(info)
+	if !info.Partial {
+		t.Errorf("range end not reached but result complete")
+	}
+}
+
+func TestGetPlaylistRange(t *testing.T) {
+	args := fakePlaylistCommand(t, `echo '{"id":"v50","playlist_title":"Big","playlist_count":500,"upload_date":"20240101"}'; echo '{"id":"v51","timestamp":1717200000}'; echo '{"id":"v52"}'`)
+
+	info, err := GetPlaylistRange("https://www.youtube.com/playlist?list=PLbig", PlaylistRange{From: 50, To: 52, After: "20240201"}, 0)
+	if err != nil {
+		t.Fatalf("GetPlaylistRange: %v", err)
+	}
+	if !slices.Contains(*args, "--playlist-start") || !slices.Contains(*args, "50") || !slices.Contains(*args, "52") {
+		t.Errorf("yt-dlp args = %v, want the range passed on", *args)
+	}
+	if len(info.Videos) != 2 || info.Videos[0].ID != "v51" || info.Videos[0].UploadDate != "20240601" || info.Videos[0].Position != 51 {
+		t.Errorf("videos = %+v, want v51 (dated by timestamp) and v52", info.Videos)
+	}
+	if info.Partial {
+		t.Error("whole range fetched but result is partial")
+	}
+}
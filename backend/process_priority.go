@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"sync"
+)
+
+// =============================================================================
+// Child process priority
+// =============================================================================
+
+// yt-dlp and ffmpeg can take every core and saturate the disk of a small
+// NAS, starving the other services on it. Config.ChildProcessPriority lowers
+// the CPU and I/O priority of every process YouFlac spawns: on Linux and
+// macOS they are started through nice (and ionice where it exists), on
+// Windows with a lower priority class. Hard CPU or memory caps are left to
+// the container or service manager (docker --cpus/--memory, systemd
+// CPUQuota=/MemoryMax=), which can limit YouFlac and its children together.
+
+// Child process priorities (Config.ChildProcessPriority)
+const (
+	ProcessPriorityNormal = "normal" // Same priority as YouFlac
+	ProcessPriorityLow    = "low"    // nice 10, lowest best-effort I/O; Windows "below normal"
+	ProcessPriorityIdle   = "idle"   // nice 19, idle I/O class; Windows "idle"
+)
+
+var validProcessPriorities = []string{ProcessPriorityNormal, ProcessPriorityLow, ProcessPriorityIdle}
+
+var (
+	processPriorityMutex sync.RWMutex
+	processPriority      = ProcessPriorityNormal
+	processPriorityArgs  []string // Command line prefix, e.g. ["nice", "-n", "10"]
+)
+
+// ConfigureProcessPriority applies Config.ChildProcessPriority to the
+// processes started from now on
+func ConfigureProcessPriority(config *Config) {
+	priority := config.ChildProcessPriority
+	if !slices.Contains(validProcessPriorities, priority) {
+		priority = ProcessPriorityNormal
+	}
+	prefix := priorityPrefix(priority)
+
+	processPriorityMutex.Lock()
+	defer processPriorityMutex.Unlock()
+	processPriority = priority
+	processPriorityArgs = prefix
+}
+
+// newCommand is exec.Command with the configured child process priority
+// and environment (see childEnv)
+func newCommand(name string, args ...string) *exec.Cmd {
+	priority, path, args := withPriority(name, args)
+	cmd := exec.Command(path, args...)
+	cmd.Env = childEnv(name)
+	setPriorityClass(cmd, priority)
+	return cmd
+}
+
+// newCommandContext is exec.CommandContext with the configured child
+// process priority and environment (see childEnv)
+func newCommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	priority, path, args := withPriority(name, args)
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = childEnv(name)
+	setPriorityClass(cmd, priority)
+	return cmd
+}
+
+// withPriority returns the configured priority and the command line running
+// name with it
+func withPriority(name string, args []string) (string, string, []string) {
+	processPriorityMutex.RLock()
+	priority, prefix := processPriority, processPriorityArgs
+	processPriorityMutex.RUnlock()
+
+	if len(prefix) == 0 {
+		return priority, name, args
+	}
+	return priority, prefix[0], append(append(slices.Clone(prefix[1:]), name), args...)
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package backend
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewCommand_Priority(t *testing.T) {
+	if _, err := exec.LookPath("nice"); err != nil {
+		t.Skip("nice not installed")
+	}
+	t.Cleanup(func() { ConfigureProcessPriority(&Config{ChildProcessPriority: ProcessPriorityNormal}) })
+
+	ConfigureProcessPriority(&Config{ChildProcessPriority: ProcessPriorityNormal})
+	if cmd := newCommand("ffmpeg", "-version"); len(cmd.Args) != 2 || cmd.Args[0] != "ffmpeg" {
+		t.Errorf("normal priority args = %v, want the command unchanged", cmd.Args)
+	}
+
+	ConfigureProcessPriority(&Config{ChildProcessPriority: ProcessPriorityIdle})
+	cmd := newCommandContext(context.Background(), "ffmpeg", "-version")
+	if filepath.Base(cmd.Args[0]) != "nice" || cmd.Args[1] != "-n" || cmd.Args[2] != "19" {
+		t.Errorf("idle priority args = %v, want nice -n 19 first", cmd.Args)
+	}
+	if n := len(cmd.Args); cmd.Args[n-2] != "ffmpeg" || cmd.Args[n-1] != "-version" {
+		t.Errorf("idle priority args = %v, want the command last", cmd.Args)
+	}
+
+	ConfigureProcessPriority(&Config{ChildProcessPriority: "turbo"})
+	if cmd := newCommand("ffmpeg"); len(cmd.Args) != 1 {
+		t.Errorf("unknown priority args = %v, want the command unchanged", cmd.Args)
+	}
+}
+
+func TestNewCommand_PriorityRuns(t *testing.T) {
+	if _, err := exec.LookPath("nice"); err != nil {
+		t.Skip("nice not installed")
+	}
+	t.Cleanup(func() { ConfigureProcessPriority(&Config{ChildProcessPriority: ProcessPriorityNormal}) })
+
+	ConfigureProcessPriority(&Config{ChildProcessPriority: ProcessPriorityLow})
+	out, err := newCommand("echo", "ok").Output()
+	if err != nil || string(out) != "ok\n" {
+		t.Errorf("low priority echo = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !windows
+
+package backend
+
+import (
+	"log/slog"
+	"os/exec"
+)
+
+// priorityPrefix returns the nice/ionice command line prefix for priority.
+// Tools that aren't installed are left out.
+func priorityPrefix(priority string) []string {
+	var niceArgs, ioniceArgs []string
+	switch priority {
+	case ProcessPriorityLow:
+		niceArgs = []string{"-n", "10"}
+		ioniceArgs = []string{"-c", "2", "-n", "7"}
+	case ProcessPriorityIdle:
+		niceArgs = []string{"-n", "19"}
+		ioniceArgs = []string{"-c", "3"}
+	default:
+		return nil
+	}
+
+	var prefix []string
+	if path, err := exec.LookPath("nice"); err == nil {
+		prefix = append(append(prefix, path), niceArgs...)
+	} else {
+		slog.Warn("nice not found, child process CPU priority unchanged")
+	}
+	if path, err := exec.LookPath("ionice"); err == nil {
+		prefix = append(append(prefix, path), ioniceArgs...)
+	}
+	return prefix
+}
+
+// setPriorityClass is only needed on Windows; nice and ionice do it here
+func setPriorityClass(cmd *exec.Cmd, priority string) {}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package backend
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Process creation flags selecting the priority class
+const (
+	belowNormalPriorityClass = 0x00004000
+	idlePriorityClass        = 0x00000040
+)
+
+// priorityPrefix is only needed on Unix; the priority class does it here
+func priorityPrefix(priority string) []string {
+	return nil
+}
+
+// setPriorityClass starts cmd with the Windows priority class for priority
+func setPriorityClass(cmd *exec.Cmd, priority string) {
+	var flags uint32
+	switch priority {
+	case ProcessPriorityLow:
+		flags = belowNormalPriorityClass
+	case ProcessPriorityIdle:
+		flags = idlePriorityClass
+	default:
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= flags
+}
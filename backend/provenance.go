@@ -0,0 +1,268 @@
+package backend
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// =============================================================================
+// Provenance sidecar
+// =============================================================================
+
+// Every download gets a "<basename>.youflac.json" next to its media file
+// recording where it came from: the request, the video and its yt-dlp
+// format, the audio source and service, checksums and timestamps. It is
+// enough to audit a library file later, download it again with the same
+// settings or rebuild the history of a library.
+
+// AppVersion is the YouFlac version recorded in provenance files and
+// reported by the API. Release builds set it with
+// -ldflags "-X youflac/backend.AppVersion=v2.1.0".
+var AppVersion = "2.0.0"
+
+// ProvenanceSuffix replaces the media extension in the sidecar name
+const ProvenanceSuffix = ".youflac.json"
+
+// ProvenanceSchemaVersion is the version of the Provenance format
+const ProvenanceSchemaVersion = 1
+
+// Provenance is the content of a provenance sidecar
+type Provenance struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	AppVersion    string `json:"appVersion"`
+	ItemID        string `json:"itemId"`
+
+	// Tags written to the media file
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	AlbumArtist string `json:"albumArtist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	AlbumID     string `json:"albumId,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	ISRC        string `json:"isrc,omitempty"`
+	Explicit    bool   `json:"explicit,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	TrackTotal  int    `json:"trackTotal,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+
+	// What was asked for: queueing Request again downloads the same track
+	// with the same settings
+	Request          DownloadRequest `json:"request"`
+	PlaylistName     string          `json:"playlistName,omitempty"`
+	PlaylistPosition int             `json:"playlistPosition,omitempty"`
+
+	Video ProvenanceVideo  `json:"video"`
+	Audio ProvenanceAudio  `json:"audio"`
+	Files []ProvenanceFile `json:"files"`
+
+	SyncAdjustment *SyncAdjustment `json:"syncAdjustment,omitempty"`
+	Warnings       []string        `json:"warnings,omitempty"`
+
+	QueuedAt    time.Time `json:"queuedAt"`
+	StartedAt   time.Time `json:"startedAt,omitempty"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// ProvenanceVideo describes the video stream of a download
+type ProvenanceVideo struct {
+	URL       string  `json:"url,omitempty"`      // Video downloaded (an alternative upload if the original was unavailable)
+	ID        string  `json:"id,omitempty"`       // YouTube ID of the requested video, also in the YOUTUBE_ID tag
+	FormatID  string  `json:"formatId,omitempty"` // yt-dlp format(s), e.g. "137+140"
+	Quality   string  `json:"quality,omitempty"`  // Requested quality tier
+	Duration  float64 `json:"duration,omitempty"`
+	AudioOnly bool    `json:"audioOnly,omitempty"` // No video was downloaded
+}
+
+// ProvenanceAudio describes the audio stream of a download
+type ProvenanceAudio struct {
+	Source          string   `json:"source,omitempty"`  // tidal, qobuz, amazon, deezer, tidal-search or extracted
+	Service         string   `json:"service,omitempty"` // tidal-hifi, lucida, orpheusdl or ffmpeg
+	URL             string   `json:"url,omitempty"`     // Store page of the track
+	Quality         string   `json:"quality,omitempty"` // Quality obtained
+	SourcesTried    []string `json:"sourcesTried,omitempty"`
+	MatchScore      int      `json:"matchScore,omitempty"`
+	MatchConfidence string   `json:"matchConfidence,omitempty"`
+}
+
+// ProvenanceFile is a file with its checksum
+type ProvenanceFile struct {
+	Role   string `json:"role"` // "media" (the library file) or "audio" (the downloaded audio before muxing)
+	Name   string `json:"name"` // Base name
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ProvenancePath returns the provenance sidecar path of a media file
+func ProvenancePath(mediaPath string) string {
+	return mediaBase(mediaPath) + ProvenanceSuffix
+}
+
+// NewProvenance builds the provenance of a finished item. mediaPath is the
+// library file, metadata the tags written to it. The downloaded audio file
+// (item.AudioPath) is hashed as well while it still exists.
+func NewProvenance(item *QueueItem, metadata *Metadata, mediaPath string) (*Provenance, error) {
+	p := &Provenance{
+		SchemaVersion: ProvenanceSchemaVersion,
+		AppVersion:    AppVersion,
+		ItemID:        item.ID,
+		Title:         item.Title,
+		Artist:        item.Artist,
+		AlbumArtist:   item.AlbumArtist,
+		Album:         item.Album,
+		Request: DownloadRequest{
+			VideoURL:            item.VideoURL,
+			SpotifyURL:          item.SpotifyURL,
+			Quality:             item.Quality,
+			AudioSourcePriority: item.AudioSourcePriority,
+			AlbumArtist:         item.AlbumArtist,
+			NamingTemplate:      item.NamingTemplate,
+			OutputMode:          item.OutputMode,
+			Notes:               item.Notes,
+			Tags:                item.Tags,
+		},
+		PlaylistName:     item.PlaylistName,
+		PlaylistPosition: item.PlaylistPosition,
+		Video: ProvenanceVideo{
+			URL:       item.VideoURL,
+			ID:        metadata.YouTubeID,
+			FormatID:  item.VideoFormat,
+			Quality:   item.Quality,
+			Duration:  item.Duration,
+			AudioOnly: item.AudioOnly,
+		},
+		Audio: ProvenanceAudio{
+			Source:          item.AudioSource,
+			Service:         item.AudioService,
+			URL:             item.AudioURL,
+			Quality:         item.ActualQuality,
+			SourcesTried:    item.SourcesTried,
+			MatchScore:      item.MatchScore,
+			MatchConfidence: item.MatchConfidence,
+		},
+		SyncAdjustment: item.SyncAdjustment,
+		Warnings:       item.Warnings,
+		QueuedAt:       item.CreatedAt,
+		StartedAt:      item.StartedAt,
+		CompletedAt:    time.Now(),
+	}
+	if metadata != nil {
+		p.Title = metadata.Title
+		p.Artist = metadata.Artist
+		p.AlbumArtist = metadata.AlbumArtist
+		p.Album = metadata.Album
+		p.AlbumID = metadata.AlbumID
+		p.Genre = metadata.Genre
+		p.ISRC = metadata.ISRC
+		p.Explicit = metadata.Explicit
+		p.Track = metadata.Track
+		p.TrackTotal = metadata.TrackTotal
+		p.Disc = metadata.Disc
+	}
+	if item.SubstitutedVideoURL != "" {
+		p.Video.URL = item.SubstitutedVideoURL
+	}
+	if item.AudioOnly {
+		p.Video.URL = ""
+		p.Video.FormatID = ""
+	}
+
+	media, err := provenanceFile("media", mediaPath)
+	if err != nil {
+		return nil, err
+	}
+	p.Files = append(p.Files, media)
+	if item.AudioPath != "" {
+		if audio, err := provenanceFile("audio", item.AudioPath); err == nil {
+			p.Files = append(p.Files, audio)
+		}
+	}
+	return p, nil
+}
+
+// provenanceFile hashes path
+func provenanceFile(role, path string) (ProvenanceFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ProvenanceFile{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return ProvenanceFile{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return ProvenanceFile{Role: role, Name: filepath.Base(path), Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// WriteProvenance writes the provenance sidecar of a finished item
+func WriteProvenance(item *QueueItem, metadata *Metadata, mediaPath string) error {
+	p, err := NewProvenance(item, metadata, mediaPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance: %w", err)
+	}
+	return WriteOutputFile(ProvenancePath(mediaPath), data)
+}
+
+// ReadProvenance reads the provenance sidecar of a media file
+func ReadProvenance(mediaPath string) (*Provenance, error) {
+	return readProvenanceFile(ProvenancePath(mediaPath))
+}
+
+func readProvenanceFile(path string) (*Provenance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Provenance
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Metadata returns the tags recorded in the provenance
+func (p *Provenance) Metadata() *Metadata {
+	metadata := &Metadata{
+		Title:       p.Title,
+		Artist:      p.Artist,
+		AlbumArtist: p.AlbumArtist,
+		Album:       p.Album,
+		AlbumID:     p.AlbumID,
+		Genre:       p.Genre,
+		ISRC:        p.ISRC,
+		Explicit:    p.Explicit,
+		Duration:    p.Video.Duration,
+		Track:       cmp.Or(p.Track, p.PlaylistPosition),
+		TrackTotal:  p.TrackTotal,
+		Disc:        p.Disc,
+		YouTubeURL:  p.Video.URL,
+		Tags:        p.Request.Tags,
+	}
+	metadata.YouTubeID = p.Video.ID
+	if metadata.YouTubeID == "" && p.Video.URL != "" {
+		metadata.YouTubeID, _ = ParseYouTubeURL(p.Video.URL)
+	}
+	return metadata
+}
+
+// mediaFile returns the checksum entry of the library file
+func (p *Provenance) mediaFile() (ProvenanceFile, bool) {
+	for _, f := range p.Files {
+		if f.Role == "media" {
+			return f, true
+		}
+	}
+	return ProvenanceFile{}, false
+}
@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteProvenance(t *testing.T) {
+	dir := t.TempDir()
+	media := filepath.Join(dir, "Daft Punk - One More Time.mkv")
+	audio := filepath.Join(dir, "tmp", "track.flac")
+	writeTestFile(t, media, 3)
+	writeTestFile(t, audio, 5)
+
+	item := &QueueItem{
+		ID:                  "item-1",
+		VideoURL:            "https://www.youtube.com/watch?v=FGBhQbmPwH8",
+		SubstitutedVideoURL: "https://www.youtube.com/watch?v=A2VpR8HahKc",
+		Title:               "One More Time (Official Video)",
+		Quality:             "1080p",
+		VideoFormat:         "137+140",
+		AudioSource:         "tidal",
+		AudioService:        "tidal-hifi",
+		AudioURL:            "https://tidal.com/browse/track/1",
+		ActualQuality:       "16-bit/44.1kHz",
+		AudioPath:           audio,
+		Tags:                []string{"party"},
+		CreatedAt:           time.Now().Add(-time.Minute),
+	}
+	metadata := &Metadata{Title: "One More Time", Artist: "Daft Punk", ISRC: "GBDUW0000053", YouTubeID: "FGBhQbmPwH8"}
+	if err := WriteProvenance(item, metadata, media); err != nil {
+		t.Fatalf("WriteProvenance() error = %v", err)
+	}
+
+	p, err := ReadProvenance(media)
+	if err != nil {
+		t.Fatalf("ReadProvenance() error = %v", err)
+	}
+	if p.AppVersion != AppVersion || p.Title != "One More Time" || p.ISRC != "GBDUW0000053" {
+		t.Errorf("provenance = %+v", p)
+	}
+	if p.Request.VideoURL != item.VideoURL || p.Video.URL != item.SubstitutedVideoURL || p.Video.FormatID != "137+140" {
+		t.Errorf("request/video = %+v / %+v, want the original request and the substituted video", p.Request, p.Video)
+	}
+	// The ID is the requested video's, for duplicate detection of later requests
+	if p.Video.ID != "FGBhQbmPwH8" || p.Metadata().YouTubeID != "FGBhQbmPwH8" {
+		t.Errorf("video ID = %q, metadata %q, want the requested video's", p.Video.ID, p.Metadata().YouTubeID)
+	}
+	if p.Audio.Service != "tidal-hifi" || p.Audio.URL != item.AudioURL {
+		t.Errorf("audio = %+v", p.Audio)
+	}
+	if len(p.Files) != 2 || p.Files[0].Role != "media" || p.Files[0].Size != 3 || p.Files[1].Name != "track.flac" {
+		t.Fatalf("files = %+v", p.Files)
+	}
+	// sha256 of three zero bytes
+	if p.Files[0].SHA256 != "709e80c88487a2411e1ee4dfb9f22a861492d20c4765150c0c794abd70f8147c" {
+		t.Errorf("sha256 = %s", p.Files[0].SHA256)
+	}
+
+	if got := outputFiles(media); len(got) != 2 || got[1] != ProvenancePath(media) {
+		t.Errorf("outputFiles() = %v, want the sidecar included", got)
+	}
+}
@@ -0,0 +1,373 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Library quality report
+// =============================================================================
+
+// BuildQualityReport runs the analyzer over every media file in the library
+// and flags files whose lossless audio probably came from a lossy source:
+// an upscale already caught by AnalyzeAudio, a spectrum that stops well
+// below the Nyquist frequency (MP3/AAC encoders low-pass around 16-19 kHz)
+// or audio that was extracted from YouTube. The report is exported as CSV
+// or JSON so low-quality rips can be found and replaced.
+
+// Spectral analysis settings
+const (
+	spectralAnalysisSeconds = 30    // Decoded from the middle of the track
+	spectralSilenceDB       = -85.0 // Band RMS below this counts as empty
+	transcodeCutoffHz       = 19000 // Lossless audio cut off below this is suspect
+	qualityReportWorkers    = 4     // Files analyzed in parallel
+	spectralWindowSize      = 4096  // FFT size of the band filters
+)
+
+// spectralBands are the lower edges (Hz) of the bands whose energy is measured
+var spectralBands = []int{11000, 13000, 15000, 16000, 17000, 18000, 19000, 20000, 21000}
+
+// QualityReportEntry is one analyzed file
+type QualityReportEntry struct {
+	Path               string   `json:"path"`
+	Codec              string   `json:"codec"`
+	BitDepth           int      `json:"bitDepth"`
+	SampleRate         int      `json:"sampleRate"`
+	Channels           int      `json:"channels"`
+	Bitrate            int      `json:"bitrate"`                  // bits per second
+	Duration           float64  `json:"duration"`                 // seconds
+	SpectralCutoff     int      `json:"spectralCutoff,omitempty"` // Hz, highest band with content; 0 = not measured
+	FileSize           int64    `json:"fileSize"`
+	Source             string   `json:"source,omitempty"` // Audio source from the download history
+	QualityScore       int      `json:"qualityScore"`
+	SuspectedTranscode bool     `json:"suspectedTranscode"`
+	Reasons            []string `json:"reasons,omitempty"` // Why the file is suspect
+	Error              string   `json:"error,omitempty"`   // Analysis failure
+}
+
+// QualityReport is the result of BuildQualityReport
+type QualityReport struct {
+	Directory   string               `json:"directory"`
+	Files       int                  `json:"files"`
+	Suspected   int                  `json:"suspected"`
+	Failed      int                  `json:"failed"`
+	Entries     []QualityReportEntry `json:"entries"`
+	GeneratedAt time.Time            `json:"generatedAt"`
+}
+
+// QualityReportOptions controls BuildQualityReport
+type QualityReportOptions struct {
+	Spectral bool     // Measure the spectral cutoff (decodes part of every file)
+	History  *History // Optional: fills in the audio source of downloaded files
+}
+
+// BuildQualityReport analyzes every media file under dir
+func BuildQualityReport(dir string, opts QualityReportOptions) (*QualityReport, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read library directory: %w", err)
+	}
+
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && indexedExtensions[strings.ToLower(filepath.Ext(path))] {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan library: %w", err)
+	}
+	sort.Strings(paths)
+
+	sources := make(map[string]string)
+	if opts.History != nil {
+		for _, entry := range opts.History.GetAll() {
+			if entry.OutputPath != "" && entry.AudioSource != "" {
+				sources[entry.OutputPath] = entry.AudioSource
+			}
+		}
+	}
+
+	report := &QualityReport{
+		Directory:   dir,
+		Files:       len(paths),
+		Entries:     make([]QualityReportEntry, len(paths)),
+		GeneratedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for range qualityReportWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				report.Entries[i] = analyzeLibraryFile(paths[i], sources[paths[i]], opts.Spectral)
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, entry := range report.Entries {
+		if entry.Error != "" {
+			report.Failed++
+		}
+		if entry.SuspectedTranscode {
+			report.Suspected++
+		}
+	}
+	return report, nil
+}
+
+// BuildLibraryQualityReport runs BuildQualityReport over dirs (see
+// LibraryDirectories) and merges the reports under the first directory.
+// Directories after the first that don't exist yet are skipped.
+func BuildLibraryQualityReport(dirs []string, opts QualityReportOptions) (*QualityReport, error) {
+	var merged *QualityReport
+	for i, dir := range outermostDirectories(dirs) {
+		if _, err := os.Stat(dir); err != nil && i > 0 {
+			continue
+		}
+		report, err := BuildQualityReport(dir, opts)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = report
+			continue
+		}
+		merged.Files += report.Files
+		merged.Suspected += report.Suspected
+		merged.Failed += report.Failed
+		merged.Entries = append(merged.Entries, report.Entries...)
+	}
+	return merged, nil
+}
+
+// analyzeLibraryFile builds the report entry for one file
+func analyzeLibraryFile(path, source string, spectral bool) QualityReportEntry {
+	entry := QualityReportEntry{Path: path, Source: source}
+
+	analysis, err := AnalyzeAudio(path)
+	if err != nil {
+		entry.Error = err.Error()
+		if info, statErr := os.Stat(path); statErr == nil {
+			entry.FileSize = info.Size()
+		}
+		return entry
+	}
+	entry.Codec = analysis.Codec
+	entry.BitDepth = analysis.BitsPerSample
+	entry.SampleRate = analysis.SampleRate
+	entry.Channels = analysis.Channels
+	entry.Bitrate = analysis.Bitrate
+	entry.Duration = analysis.Duration
+	entry.FileSize = analysis.FileSize
+	entry.QualityScore = analysis.QualityScore
+
+	if spectral && isLosslessCodec(analysis.Codec) {
+		cutoff, err := measureSpectralCutoff(path, analysis.SampleRate, analysis.Duration)
+		if err != nil {
+			slog.Debug("spectral analysis failed", "path", path, "err", err)
+		} else {
+			entry.SpectralCutoff = cutoff
+		}
+	}
+
+	entry.Reasons = transcodeReasons(&entry, analysis.FakeLossless)
+	entry.SuspectedTranscode = len(entry.Reasons) > 0
+	return entry
+}
+
+// transcodeReasons lists why a lossless file looks like it came from a lossy source
+func transcodeReasons(entry *QualityReportEntry, fakeLossless bool) []string {
+	if !isLosslessCodec(entry.Codec) {
+		return nil
+	}
+	var reasons []string
+	if fakeLossless {
+		reasons = append(reasons, "bitrate too low for lossless audio")
+	}
+	if entry.SpectralCutoff > 0 && entry.SpectralCutoff < transcodeCutoffHz && entry.SampleRate >= 44100 {
+		reasons = append(reasons, fmt.Sprintf("spectrum ends at %.1f kHz", float64(entry.SpectralCutoff)/1000))
+	}
+	if entry.Source == "extracted" {
+		reasons = append(reasons, "audio extracted from the YouTube video")
+	}
+	if entry.Source == YouTubeAudioSource {
+		reasons = append(reasons, "audio from the lossy YouTube Music stream")
+	}
+	return reasons
+}
+
+// bandLevelRe matches the overall RMS level printed by the named astats instances
+var bandLevelRe = regexp.MustCompile(`\[astats@band(\d+) @ [^\]]+\] RMS level dB: (-?[\d.]+|-inf)`)
+
+// parseBandLevels extracts the RMS level (dB) per band from the ffmpeg log
+func parseBandLevels(output string) map[int]float64 {
+	levels := make(map[int]float64)
+	for _, m := range bandLevelRe.FindAllStringSubmatch(output, -1) {
+		band, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		level := math.Inf(-1)
+		if m[2] != "-inf" {
+			if level, err = strconv.ParseFloat(m[2], 64); err != nil {
+				continue
+			}
+		}
+		levels[band] = level
+	}
+	return levels
+}
+
+// spectralCutoff returns the highest band edge that still has content, or
+// the Nyquist frequency when the top measured band is not empty
+func spectralCutoff(levels map[int]float64, nyquist int) int {
+	cutoff := 0
+	for i, band := range spectralBands {
+		level, ok := levels[band]
+		if !ok || band >= nyquist {
+			break
+		}
+		if level < spectralSilenceDB {
+			break
+		}
+		cutoff = band
+		if i == len(spectralBands)-1 {
+			cutoff = nyquist
+		}
+	}
+	return cutoff
+}
+
+// measureSpectralCutoff decodes spectralAnalysisSeconds from the middle of
+// path and measures the energy above each band edge with a brick-wall FFT
+// high-pass, all in a single ffmpeg run
+func measureSpectralCutoff(path string, sampleRate int, duration float64) (int, error) {
+	if sampleRate <= 0 {
+		return 0, fmt.Errorf("unknown sample rate")
+	}
+	nyquist := sampleRate / 2
+
+	var bands []int
+	for _, band := range spectralBands {
+		if band < nyquist {
+			bands = append(bands, band)
+		}
+	}
+	if len(bands) == 0 {
+		return 0, fmt.Errorf("sample rate %d Hz is too low for spectral analysis", sampleRate)
+	}
+
+	var graph strings.Builder
+	fmt.Fprintf(&graph, "[0:a:0]aformat=sample_fmts=fltp:channel_layouts=mono,asplit=%d", len(bands))
+	for i := range bands {
+		fmt.Fprintf(&graph, "[s%d]", i)
+	}
+	for i, band := range bands {
+		// Bin b covers b*sr/win_size Hz; everything below the band edge is zeroed
+		keep := fmt.Sprintf("gte(b*sr/%d\\,%d)", spectralWindowSize, band)
+		fmt.Fprintf(&graph, ";[s%d]afftfilt=win_size=%d:real='re*%s':imag='im*%s',astats@band%d=measure_perchannel=none:measure_overall=RMS_level,anullsink",
+			i, spectralWindowSize, keep, keep, band)
+	}
+
+	start := max(duration/2-spectralAnalysisSeconds/2, 0)
+	args := []string{
+		"-nostats",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-t", strconv.Itoa(spectralAnalysisSeconds),
+		"-i", path,
+		"-filter_complex", graph.String(),
+		"-f", "null", "-",
+	}
+	cmd := newCommand(GetFFmpegPath(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("spectral analysis failed: %v - %s", err, stderr.String())
+	}
+
+	levels := parseBandLevels(stderr.String())
+	if len(levels) == 0 {
+		return 0, fmt.Errorf("no band levels in ffmpeg output")
+	}
+	return spectralCutoff(levels, nyquist), nil
+}
+
+// qualityReportColumns is the CSV header
+var qualityReportColumns = []string{
+	"path", "codec", "bit_depth", "sample_rate", "channels", "bitrate", "duration",
+	"spectral_cutoff", "file_size", "source", "quality_score", "suspected_transcode", "reasons", "error",
+}
+
+// WriteCSV writes the report as CSV, one row per file
+func (r *QualityReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(qualityReportColumns); err != nil {
+		return err
+	}
+	for _, e := range r.Entries {
+		row := []string{
+			e.Path,
+			e.Codec,
+			strconv.Itoa(e.BitDepth),
+			strconv.Itoa(e.SampleRate),
+			strconv.Itoa(e.Channels),
+			strconv.Itoa(e.Bitrate),
+			strconv.FormatFloat(e.Duration, 'f', 2, 64),
+			strconv.Itoa(e.SpectralCutoff),
+			strconv.FormatInt(e.FileSize, 10),
+			e.Source,
+			strconv.Itoa(e.QualityScore),
+			strconv.FormatBool(e.SuspectedTranscode),
+			strings.Join(e.Reasons, "; "),
+			e.Error,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SaveQualityReport writes the report to path as JSON (.json) or CSV (anything else)
+func SaveQualityReport(report *QualityReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteCSV(f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return f.Close()
+}
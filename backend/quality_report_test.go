@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"encoding/csv"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseBandLevels(t *testing.T) {
+	output := `[astats@band16000 @ 0x55d1c0] RMS level dB: -42.173
+[astats@band17000 @ 0x55d1c1] RMS level dB: -97.500
+[astats@band18000 @ 0x55d1c2] RMS level dB: -inf
+[Parsed_astats_3 @ 0x55d1c3] RMS level dB: -10.0`
+	levels := parseBandLevels(output)
+	if len(levels) != 3 {
+		t.Fatalf("levels = %v", levels)
+	}
+	if levels[16000] != -42.173 || levels[17000] != -97.5 || !math.IsInf(levels[18000], -1) {
+		t.Errorf("levels = %v", levels)
+	}
+}
+
+func TestSpectralCutoff(t *testing.T) {
+	full := map[int]float64{}
+	for _, band := range spectralBands {
+		full[band] = -60
+	}
+	if got := spectralCutoff(full, 22050); got != 22050 {
+		t.Errorf("full spectrum = %d", got)
+	}
+
+	mp3 := map[int]float64{11000: -40, 13000: -45, 15000: -50, 16000: -55, 17000: -120, 18000: math.Inf(-1)}
+	if got := spectralCutoff(mp3, 22050); got != 16000 {
+		t.Errorf("16 kHz low-pass = %d", got)
+	}
+
+	if got := spectralCutoff(map[int]float64{11000: -100}, 22050); got != 0 {
+		t.Errorf("silence = %d", got)
+	}
+}
+
+func TestTranscodeReasons(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry QualityReportEntry
+		fake  bool
+		want  int
+	}{
+		{"clean flac", QualityReportEntry{Codec: "flac", SampleRate: 44100, SpectralCutoff: 22050}, false, 0},
+		{"low-passed flac", QualityReportEntry{Codec: "flac", SampleRate: 44100, SpectralCutoff: 16000}, false, 1},
+		{"fake lossless", QualityReportEntry{Codec: "flac", SampleRate: 44100}, true, 1},
+		{"extracted audio", QualityReportEntry{Codec: "flac", SampleRate: 48000, Source: "extracted"}, false, 1},
+		{"youtube music audio", QualityReportEntry{Codec: "flac", SampleRate: 48000, SpectralCutoff: 20000, Source: YouTubeAudioSource}, false, 1},
+		{"lossy codec", QualityReportEntry{Codec: "aac", SampleRate: 44100, SpectralCutoff: 16000}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transcodeReasons(&tt.entry, tt.fake); len(got) != tt.want {
+				t.Errorf("reasons = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQualityReportWriteCSV(t *testing.T) {
+	report := &QualityReport{Entries: []QualityReportEntry{
+		{Path: "/music/a, b.flac", Codec: "flac", BitDepth: 16, SampleRate: 44100, SpectralCutoff: 16000,
+			SuspectedTranscode: true, Reasons: []string{"spectrum ends at 16.0 kHz", "audio extracted from the YouTube video"}},
+	}}
+	var sb strings.Builder
+	if err := report.WriteCSV(&sb); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[1]) != len(qualityReportColumns) {
+		t.Fatalf("rows = %v", rows)
+	}
+	if rows[1][0] != "/music/a, b.flac" || rows[1][7] != "16000" || rows[1][11] != "true" {
+		t.Errorf("row = %v", rows[1])
+	}
+}
@@ -3,6 +3,8 @@ package backend
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 	"sync"
 	"time"
 
@@ -24,6 +26,7 @@ const (
 	StatusError            QueueStatus = "error"
 	StatusCancelled        QueueStatus = "cancelled"
 	StatusPaused           QueueStatus = "paused"
+	StatusAwaitingApproval QueueStatus = "awaiting_approval" // Review-before-download mode, see ApproveItem
 )
 
 // QueueItem represents a single download in the queue
@@ -34,13 +37,18 @@ type QueueItem struct {
 	Title            string      `json:"title"`
 	Artist           string      `json:"artist"`
 	Album            string      `json:"album,omitempty"`
+	AlbumID          string      `json:"albumId,omitempty"`          // Store album of the audio, e.g. "tidal:12345" (non-playlist items)
+	TrackNumber      int         `json:"trackNumber,omitempty"`      // Position on that album
+	AlbumArtist      string      `json:"albumArtist,omitempty"`      // e.g. "Various Artists" for compilations
 	PlaylistName     string      `json:"playlistName,omitempty"`     // Playlist folder name
 	PlaylistPosition int         `json:"playlistPosition,omitempty"` // Position in playlist (1-based)
+	TrackTotal       int         `json:"trackTotal,omitempty"`       // Playlist/album length (TRACKTOTAL, {track} padding)
+	Disc             int         `json:"disc,omitempty"`             // Disc number (DISCNUMBER, {disc})
 	Thumbnail        string      `json:"thumbnail,omitempty"`
 	Duration         float64     `json:"duration,omitempty"`
 	Status           QueueStatus `json:"status"`
 	Progress         int         `json:"progress"` // 0-100
-	Stage            string      `json:"stage"`    // Human-readable current stage
+	Stage            string      `json:"stage"`    // Human-readable current stage (English)
 	Error            string      `json:"error,omitempty"`
 	OutputPath       string      `json:"outputPath,omitempty"`
 	VideoPath        string      `json:"videoPath,omitempty"` // Temp video file
@@ -50,6 +58,27 @@ type QueueItem struct {
 	StartedAt        time.Time   `json:"startedAt,omitempty"`
 	CompletedAt      time.Time   `json:"completedAt,omitempty"`
 
+	// Catalog key of Stage for localized display ("" = free-form text) and
+	// its placeholder values
+	StageCode   StageCode         `json:"stageCode,omitempty"`
+	StageParams map[string]string `json:"stageParams,omitempty"`
+
+	// Bytes, speed and ETA of the running video or audio download, nil otherwise
+	Transfer *DownloadProgress `json:"transfer,omitempty"`
+
+	// Stage timing, used for ETA predictions
+	StageStartedAt time.Time          `json:"stageStartedAt,omitempty"`
+	StageDurations map[string]float64 `json:"stageDurations,omitempty"` // Seconds spent per status
+
+	// Audit trail, copied to History when the item finishes
+	Timeline        []StageRecord `json:"timeline,omitempty"`
+	SourcesTried    []string      `json:"sourcesTried,omitempty"`
+	AudioService    string        `json:"audioService,omitempty"` // Service that served the audio: "tidal-hifi", "lucida", "orpheusdl"
+	AudioURL        string        `json:"audioUrl,omitempty"`     // Store page of the downloaded audio track
+	VideoFormat     string        `json:"videoFormat,omitempty"`  // yt-dlp format(s) of the downloaded video, e.g. "137+140"
+	Retries         int           `json:"retries,omitempty"`
+	BytesDownloaded int64         `json:"bytesDownloaded,omitempty"`
+
 	// Matching info
 	MatchScore      int    `json:"matchScore,omitempty"`
 	MatchConfidence string `json:"matchConfidence,omitempty"`
@@ -58,15 +87,80 @@ type QueueItem struct {
 	ActualQuality   string `json:"actualQuality,omitempty"` // Actual quality obtained (may differ from requested)
 	Explicit        bool   `json:"explicit,omitempty"`      // Track has explicit content flag
 
+	// Per-item audio source order (empty = use Config.AudioSourcePriority)
+	AudioSourcePriority []string `json:"audioSourcePriority,omitempty"`
+
 	// Audio-only fallback (video unavailable)
 	AudioOnly bool `json:"audioOnly,omitempty"`
 
+	// Saved with lossy fallback audio under Config.FallbackPolicy "upgrade";
+	// worth downloading again once a lossless source has the track
+	NeedsUpgrade bool `json:"needsUpgrade,omitempty"`
+
+	// Queue items that must complete before this one starts; it fails when
+	// one of them fails (see queue_dependencies.go)
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Section of the video to download (see clip.go), nil = all of it
+	Clip *Clip `json:"clip,omitempty"`
+
+	// User notes and tags, copied to History when the item finishes
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+
+	// Set when the video is outside the duration limits and Config.DurationPolicy is "flag"
+	DurationFlag string `json:"durationFlag,omitempty"`
+
+	// Non-fatal problems (no lyrics, cover or NFO missing, silence trim
+	// skipped, ...): the item completes without what they describe
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Leading-silence A/V sync correction applied while muxing, for auditing
+	SyncAdjustment *SyncAdjustment `json:"syncAdjustment,omitempty"`
+	PreviewPath    string          `json:"previewPath,omitempty"` // Short clip for checking sync (Config.SyncPreview)
+
+	// Dry run: Plan is filled in instead of downloading
+	DryRun bool          `json:"dryRun,omitempty"`
+	Plan   *DownloadPlan `json:"plan,omitempty"`
+
+	// Per-item overrides (empty = use Config)
+	NamingTemplate string `json:"namingTemplate,omitempty"`
+	OutputMode     string `json:"outputMode,omitempty"`
+	LengthMode     string `json:"lengthMode,omitempty"`
+
+	// Alternative uploads found when the original video was unavailable
+	AlternativeVideos   []VideoInfo `json:"alternativeVideos,omitempty"`
+	SubstitutedVideoURL string      `json:"substitutedVideoUrl,omitempty"` // Alternative actually downloaded (auto mode)
+
+	// Remote storage uploads (Config.StorageTargets)
+	UploadedTo  []string `json:"uploadedTo,omitempty"`  // Targets holding a verified copy
+	UploadError string   `json:"uploadError,omitempty"` // Upload failure; the local files are kept
+
+	// rclone sync (Config.RcloneRemote), runs after the item completes
+	SyncStatus string    `json:"syncStatus,omitempty"` // pending, syncing, synced, failed
+	SyncError  string    `json:"syncError,omitempty"`
+	SyncedAt   time.Time `json:"syncedAt,omitempty"`
+
 	// Diagnostics de matching (peuplés si erreur ou match incertain)
 	MatchCandidates  []AudioCandidate  `json:"matchCandidates,omitempty"`
 	MatchDiagnostics *MatchDiagnostics `json:"matchDiagnostics,omitempty"`
 
+	// Times the watchdog restarted the item because it stalled (see queue_watchdog.go)
+	Stalls int `json:"stalls,omitempty"`
+
 	// Cancel channel (not serialized)
 	cancelFunc context.CancelFunc `json:"-"`
+
+	// Last update of the item while processing, for the watchdog
+	lastActivity time.Time
+
+	// Set while a worker runs the item; it isn't dispatched again until the
+	// worker returned (see processItem)
+	running bool
+
+	// Set when the watchdog stopped the running worker: the worker's updates
+	// are dropped and the item is restarted or failed once it returned
+	stall *stallAction
 }
 
 // MatchDiagnostics contains diagnostic information about why a match failed
@@ -81,6 +175,7 @@ type RetryOverrideRequest struct {
 	Artist   string `json:"artist,omitempty"`
 	Title    string `json:"title,omitempty"`
 	MusicURL string `json:"musicUrl,omitempty"` // Direct Spotify/Tidal/Qobuz URL
+	VideoURL string `json:"videoUrl,omitempty"` // Replacement YouTube video (e.g. one of AlternativeVideos)
 }
 
 // DownloadRequest is the input for adding items to queue
@@ -88,16 +183,60 @@ type DownloadRequest struct {
 	VideoURL   string `json:"videoUrl"`
 	SpotifyURL string `json:"spotifyUrl,omitempty"`
 	Quality    string `json:"quality,omitempty"` // "best", "1080p", "720p", "480p"
+
+	// AudioSourcePriority overrides Config.AudioSourcePriority for this item (e.g. ["qobuz"])
+	AudioSourcePriority []string `json:"audioSourcePriority,omitempty"`
+
+	// AlbumArtist overrides the album artist derived from the track credit
+	AlbumArtist string `json:"albumArtist,omitempty"`
+
+	// DryRun plans the download without fetching or writing anything
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Per-item overrides of Config.NamingTemplate and the output container
+	NamingTemplate string `json:"namingTemplate,omitempty"`
+	OutputMode     string `json:"outputMode,omitempty"` // "video" (MKV, default) or "audio" (FLAC only)
+	LengthMode     string `json:"lengthMode,omitempty"` // Audio/video length mismatch: "off", "trim", "freeze", "black" or "chapters"
+
+	// Free-form notes and user tags, e.g. "for wedding playlist"
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+
+	// RequireApproval holds the item in StatusAwaitingApproval until approved
+	// (always the case with Config.ApprovalMode "all")
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// Track total and disc number for multi-disc albums and large playlists
+	TrackTotal int `json:"trackTotal,omitempty"`
+	Disc       int `json:"disc,omitempty"`
+
+	// IDs of queue items that must complete before this one starts
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Download only this part of the video, e.g. "1:02:10" to "1:06:45"
+	ClipStart string `json:"clipStart,omitempty"`
+	ClipEnd   string `json:"clipEnd,omitempty"`
 }
 
+// Output modes for DownloadRequest.OutputMode
+const (
+	OutputModeVideo = "video"
+	OutputModeAudio = "audio"
+)
+
 // QueueEvent is emitted to frontend for progress updates
 type QueueEvent struct {
-	Type     string      `json:"type"` // "added", "updated", "removed", "completed", "error"
+	Type     string      `json:"type"` // "added", "updated", "removed", "completed", "error", "warning"
 	ItemID   string      `json:"itemId"`
 	Item     *QueueItem  `json:"item,omitempty"`
 	Progress int         `json:"progress,omitempty"`
 	Status   QueueStatus `json:"status,omitempty"`
 	Error    string      `json:"error,omitempty"`
+	Warning  string      `json:"warning,omitempty"` // The new warning of a "warning" event
+
+	// Current stage, so progress can be rendered without the full item
+	StageCode   StageCode         `json:"stageCode,omitempty"`
+	StageParams map[string]string `json:"stageParams,omitempty"`
 }
 
 // QueueProgressCallback is called when progress updates occur
@@ -106,36 +245,57 @@ type QueueProgressCallback func(event QueueEvent)
 // Queue manages the download queue with concurrent workers
 type Queue struct {
 	items        []QueueItem
+	positions    map[string]int                      // Item ID -> index in items
+	byStatus     map[QueueStatus]map[string]struct{} // Item IDs by status
 	mutex        sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
 	maxConc      int // Max concurrent downloads
 	onProgress   QueueProgressCallback
+	events       eventBus // Subscribers besides onProgress (chat bots)
 	workerWG     sync.WaitGroup
-	jobChan      chan string // Channel of item IDs to process
+	jobChan      chan string         // Unbuffered: the dispatcher blocks until a worker is free
+	wake         chan struct{}       // Tells the dispatcher new pending work may be available
+	ready        *pendingHeap        // Pending IDs the dispatcher may start, by position
+	waiters      map[string][]string // Parked pending IDs by the item they wait for
+	dispatched   map[string]bool     // Pending IDs handed to a worker that hasn't started them yet
 	processing   bool
 	processMutex sync.Mutex
+	saveMutex    sync.Mutex // Serializes queue.json writes, see saveQueueFile
+	saveErr      error      // Set when queue.json is from a newer version; guarded by saveMutex
 
-	// Configuration
-	config *Config
+	// Configuration; workers take one snapshot per item
+	configs *ConfigStore
 
 	// File index for duplicate detection
 	fileIndex *FileIndex
 
 	// History for tracking completed downloads
 	history *History
+
+	// Email/Apprise/MQTT digests for finished batches and failure streaks
+	notifications *NotificationManager
+
+	// Config.Schedule is ignored until this time (manual override)
+	scheduleOverride time.Time
 }
 
 // NewQueue creates a new download queue
 func NewQueue(ctx context.Context, maxConcurrent int) *Queue {
 	ctx, cancel := context.WithCancel(ctx)
-	return &Queue{
-		items:   make([]QueueItem, 0),
+	q := &Queue{
 		ctx:     ctx,
 		cancel:  cancel,
 		maxConc: maxConcurrent,
-		jobChan: make(chan string, 100),
+		jobChan: make(chan string),
+		wake:    make(chan struct{}, 1),
+
+		configs:       NewConfigStore(nil),
+		dispatched:    make(map[string]bool),
+		notifications: NewNotificationManager(),
 	}
+	q.setItems(make([]QueueItem, 0))
+	return q
 }
 
 // SetProgressCallback sets the callback for progress events
@@ -145,11 +305,23 @@ func (q *Queue) SetProgressCallback(cb QueueProgressCallback) {
 	q.onProgress = cb
 }
 
-// SetConfig sets the configuration for downloads
+// SetConfig publishes a copy of config for downloads. Items already being
+// processed keep the snapshot they started with.
 func (q *Queue) SetConfig(config *Config) {
+	q.mutex.RLock()
+	configs := q.configs
+	q.mutex.RUnlock()
+	configs.Set(config)
+	q.signal() // The download schedule may have changed
+}
+
+// SetConfigStore makes the queue read its configuration from a store shared
+// with the caller, so saved settings reach the workers without SetConfig
+func (q *Queue) SetConfigStore(configs *ConfigStore) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
-	q.config = config
+	q.configs = configs
+	q.signal()
 }
 
 // SetFileIndex sets the file index for duplicate detection
@@ -175,24 +347,89 @@ func (q *Queue) emit(event QueueEvent) {
 	if cb != nil {
 		cb(event)
 	}
+	q.events.publish(event)
+	q.publishMQTTEvent(event)
+
+	if event.Type == "completed" || event.Type == "error" {
+		go q.notifyFinished(event.ItemID)
+	}
+}
+
+// notifyFinished passes a finished item to the notification manager
+func (q *Queue) notifyFinished(id string) {
+	q.mutex.RLock()
+	config := q.configs.Get()
+	q.mutex.RUnlock()
+	if config == nil {
+		config = &defaultConfig
+	}
+
+	item := q.GetItem(id)
+	if item == nil {
+		return
+	}
+	q.notifications.HandleFinished(config, item, q.GetQueue())
 }
 
 // AddToQueue adds a new download request to the queue
 func (q *Queue) AddToQueue(request DownloadRequest) (string, error) {
+	if err := ValidateAudioSources(request.AudioSourcePriority); err != nil {
+		return "", err
+	}
+	notes, tags, err := validateNotes(request.Notes, request.Tags)
+	if err != nil {
+		return "", err
+	}
+	clip, err := ParseClip(request.ClipStart, request.ClipEnd)
+	if err != nil {
+		return "", err
+	}
+	if err := validateLengthMode(request.LengthMode); err != nil {
+		return "", err
+	}
+
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
+	if err := q.checkDependencies("", request.DependsOn); err != nil {
+		return "", err
+	}
 
 	item := QueueItem{
-		ID:         uuid.New().String(),
-		VideoURL:   request.VideoURL,
-		SpotifyURL: request.SpotifyURL,
-		Status:     StatusPending,
-		Progress:   0,
-		Stage:      "Waiting...",
-		CreatedAt:  time.Now(),
+		ID:                  uuid.New().String(),
+		VideoURL:            request.VideoURL,
+		SpotifyURL:          request.SpotifyURL,
+		AudioSourcePriority: request.AudioSourcePriority,
+		AlbumArtist:         request.AlbumArtist,
+		TrackTotal:          request.TrackTotal,
+		Disc:                request.Disc,
+		DryRun:              request.DryRun,
+		Quality:             request.Quality,
+		NamingTemplate:      request.NamingTemplate,
+		OutputMode:          request.OutputMode,
+		LengthMode:          request.LengthMode,
+		Notes:               notes,
+		Tags:                tags,
+		DependsOn:           request.DependsOn,
+		Clip:                clip,
+		Status:              StatusPending,
+		Progress:            0,
+		Stage:               "Waiting...",
+		StageCode:           StageWaiting,
+		CreatedAt:           time.Now(),
+	}
+	if len(item.DependsOn) > 0 {
+		item.setStage(StageWaitingDependency)
+	}
+	awaiting := requiresApproval(request, q.configs.Get())
+	if awaiting {
+		item.Status = StatusAwaitingApproval
+		item.setStage(StageApprovalPlanning)
 	}
 
-	q.items = append(q.items, item)
+	q.appendItem(item)
+	if awaiting {
+		go q.planForApproval(item.ID)
+	}
 
 	// Emit event
 	go q.emit(QueueEvent{
@@ -211,26 +448,69 @@ func (q *Queue) AddToQueueWithMetadata(request DownloadRequest, videoInfo *Video
 
 // AddToQueueWithPlaylist adds an item with metadata and playlist name
 func (q *Queue) AddToQueueWithPlaylist(request DownloadRequest, videoInfo *VideoInfo, playlistName string, playlistPosition int) (string, error) {
+	if err := ValidateAudioSources(request.AudioSourcePriority); err != nil {
+		return "", err
+	}
+	notes, tags, err := validateNotes(request.Notes, request.Tags)
+	if err != nil {
+		return "", err
+	}
+	clip, err := ParseClip(request.ClipStart, request.ClipEnd)
+	if err != nil {
+		return "", err
+	}
+	if err := validateLengthMode(request.LengthMode); err != nil {
+		return "", err
+	}
+
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
+	if err := q.checkDependencies("", request.DependsOn); err != nil {
+		return "", err
+	}
 
 	item := QueueItem{
-		ID:               uuid.New().String(),
-		VideoURL:         request.VideoURL,
-		SpotifyURL:       request.SpotifyURL,
-		Title:            videoInfo.Title,
-		Artist:           videoInfo.Artist,
-		Thumbnail:        videoInfo.Thumbnail,
-		Duration:         videoInfo.Duration,
-		PlaylistName:     playlistName,
-		PlaylistPosition: playlistPosition,
-		Status:           StatusPending,
-		Progress:         0,
-		Stage:            "Waiting...",
-		CreatedAt:        time.Now(),
-	}
-
-	q.items = append(q.items, item)
+		ID:                  uuid.New().String(),
+		VideoURL:            request.VideoURL,
+		SpotifyURL:          request.SpotifyURL,
+		Title:               videoInfo.Title,
+		Artist:              videoInfo.Artist,
+		Thumbnail:           videoInfo.Thumbnail,
+		Duration:            videoInfo.Duration,
+		PlaylistName:        playlistName,
+		PlaylistPosition:    playlistPosition,
+		AudioSourcePriority: request.AudioSourcePriority,
+		AlbumArtist:         request.AlbumArtist,
+		TrackTotal:          request.TrackTotal,
+		Disc:                request.Disc,
+		DryRun:              request.DryRun,
+		Quality:             request.Quality,
+		NamingTemplate:      request.NamingTemplate,
+		OutputMode:          request.OutputMode,
+		LengthMode:          request.LengthMode,
+		Notes:               notes,
+		Tags:                tags,
+		DependsOn:           request.DependsOn,
+		Clip:                clip,
+		Status:              StatusPending,
+		Progress:            0,
+		Stage:               "Waiting...",
+		StageCode:           StageWaiting,
+		CreatedAt:           time.Now(),
+	}
+	if len(item.DependsOn) > 0 {
+		item.setStage(StageWaitingDependency)
+	}
+	awaiting := requiresApproval(request, q.configs.Get())
+	if awaiting {
+		item.Status = StatusAwaitingApproval
+		item.setStage(StageApprovalPlanning)
+	}
+
+	q.appendItem(item)
+	if awaiting {
+		go q.planForApproval(item.ID)
+	}
 
 	go q.emit(QueueEvent{
 		Type:   "added",
@@ -257,12 +537,10 @@ func (q *Queue) GetItem(id string) *QueueItem {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
-	for i := range q.items {
-		if q.items[i].ID == id {
-			// Return a copy
-			item := q.items[i]
-			return &item
-		}
+	if item := q.itemByID(id); item != nil {
+		// Return a copy
+		cp := *item
+		return &cp
 	}
 	return nil
 }
@@ -272,13 +550,7 @@ func (q *Queue) GetPendingCount() int {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
-	count := 0
-	for _, item := range q.items {
-		if item.Status == StatusPending {
-			count++
-		}
-	}
-	return count
+	return q.countStatus(StatusPending)
 }
 
 // GetActiveCount returns the number of currently processing items
@@ -286,50 +558,52 @@ func (q *Queue) GetActiveCount() int {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
-	count := 0
-	for _, item := range q.items {
-		switch item.Status {
-		case StatusFetchingInfo, StatusDownloadingVideo, StatusDownloadingAudio, StatusMuxing, StatusOrganizing:
-			count++
-		}
-	}
-	return count
+	return q.countStatus(pipelineStages...)
 }
 
-// updateItem updates an item and emits event
-func (q *Queue) updateItem(id string, updater func(*QueueItem)) {
+// updateItem updates an item and emits event. Returns false when the item
+// is gone or the update came too late: the watchdog stopped the worker
+// processing it.
+func (q *Queue) updateItem(id string, updater func(*QueueItem)) bool {
 	q.mutex.Lock()
 
 	var updated *QueueItem
-	for i := range q.items {
-		if q.items[i].ID == id {
-			updater(&q.items[i])
-			item := q.items[i]
-			updated = &item
-			break
-		}
+	if item := q.itemByID(id); item != nil && item.stall == nil {
+		prev := item.Status
+		updater(item)
+		item.lastActivity = time.Now()
+		q.statusChanged(id, prev, item.Status)
+		trackStage(item, prev, time.Now())
+		cp := *item
+		updated = &cp
 	}
 
 	q.mutex.Unlock()
 
 	if updated != nil {
 		q.emit(QueueEvent{
-			Type:     "updated",
-			ItemID:   id,
-			Item:     updated,
-			Progress: updated.Progress,
-			Status:   updated.Status,
+			Type:        "updated",
+			ItemID:      id,
+			Item:        updated,
+			Progress:    updated.Progress,
+			Status:      updated.Status,
+			StageCode:   updated.StageCode,
+			StageParams: updated.StageParams,
 		})
 	}
+	return updated != nil
 }
 
-// UpdateStatus updates the status of a queue item
+// UpdateStatus updates the status of a queue item. A non-empty stage is
+// free-form text without a catalog code; see UpdateStage.
 func (q *Queue) UpdateStatus(id string, status QueueStatus, progress int, stage string) {
 	q.updateItem(id, func(item *QueueItem) {
 		item.Status = status
 		item.Progress = progress
 		if stage != "" {
 			item.Stage = stage
+			item.StageCode = ""
+			item.StageParams = nil
 		}
 		if status == StatusComplete {
 			item.CompletedAt = time.Now()
@@ -337,14 +611,50 @@ func (q *Queue) UpdateStatus(id string, status QueueStatus, progress int, stage
 	})
 }
 
+// UpdateStage updates the status of a queue item with a catalog stage.
+// params are alternating placeholder name/value pairs.
+func (q *Queue) UpdateStage(id string, status QueueStatus, progress int, code StageCode, params ...string) {
+	q.updateItem(id, func(item *QueueItem) {
+		item.Status = status
+		item.Progress = progress
+		item.setStage(code, params...)
+		if status == StatusComplete {
+			item.CompletedAt = time.Now()
+		}
+	})
+}
+
+// AddWarning records a non-fatal problem on a queue item and emits a
+// "warning" event for it
+func (q *Queue) AddWarning(id, format string, args ...any) {
+	warning := fmt.Sprintf(format, args...)
+	slog.Warn("item warning", "id", id, "warning", warning)
+
+	var status QueueStatus
+	if !q.updateItem(id, func(item *QueueItem) {
+		item.Warnings = append(item.Warnings, warning)
+		status = item.Status
+	}) {
+		return
+	}
+	q.emit(QueueEvent{Type: "warning", ItemID: id, Status: status, Warning: warning})
+}
+
 // SetItemError sets an error on a queue item
 func (q *Queue) SetItemError(id string, err error) {
-	q.updateItem(id, func(item *QueueItem) {
+	q.failItem(id, err, StageError)
+}
+
+// failItem sets an error on a queue item with a stage code telling why
+func (q *Queue) failItem(id string, err error, code StageCode, params ...string) {
+	if !q.updateItem(id, func(item *QueueItem) {
 		item.Status = StatusError
 		item.Error = err.Error()
-		item.Stage = "Error"
+		item.setStage(code, params...)
 		item.CompletedAt = time.Now()
-	})
+	}) {
+		return
+	}
 
 	// Save to history as failed
 	q.mutex.RLock()
@@ -376,20 +686,20 @@ func (q *Queue) RemoveFromQueue(id string) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	for i, item := range q.items {
-		if item.ID == id {
-			// Cancel if processing
-			if item.cancelFunc != nil {
-				item.cancelFunc()
-			}
-			q.items = append(q.items[:i], q.items[i+1:]...)
-
-			go q.emit(QueueEvent{
-				Type:   "removed",
-				ItemID: id,
-			})
-			return nil
+	if i := q.indexOf(id); i >= 0 {
+		// Cancel if processing
+		if q.items[i].cancelFunc != nil {
+			q.items[i].cancelFunc()
+		}
+		if q.items[i].PreviewPath != "" {
+			os.Remove(q.items[i].PreviewPath)
 		}
+		q.removeAt(i)
+
+		go q.emit(QueueEvent{
+			Type:   "removed",
+			ItemID: id,
+		})
 	}
 	return nil
 }
@@ -399,17 +709,16 @@ func (q *Queue) CancelItem(id string) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	for i := range q.items {
-		if q.items[i].ID == id {
-			if q.items[i].cancelFunc != nil {
-				q.items[i].cancelFunc()
-			}
-			q.items[i].Status = StatusCancelled
-			q.items[i].Stage = "Cancelled"
-			return nil
-		}
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if item.cancelFunc != nil {
+		item.cancelFunc()
 	}
-	return fmt.Errorf("item not found: %s", id)
+	q.setStatus(item, StatusCancelled)
+	item.setStage(StageCancelled)
+	return nil
 }
 
 // PauseItem cancels the in-progress download and marks the item as paused.
@@ -418,26 +727,26 @@ func (q *Queue) PauseItem(id string) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	for i := range q.items {
-		if q.items[i].ID == id {
-			// Only active items can be paused
-			switch q.items[i].Status {
-			case StatusPending, StatusFetchingInfo, StatusDownloadingVideo,
-				StatusDownloadingAudio, StatusMuxing, StatusOrganizing:
-			default:
-				return fmt.Errorf("item %s is not in a pausable state (%s)", id, q.items[i].Status)
-			}
-			if q.items[i].cancelFunc != nil {
-				q.items[i].cancelFunc()
-			}
-			q.items[i].Status = StatusPaused
-			q.items[i].Stage = "Paused"
-			item := q.items[i]
-			go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &item})
-			return nil
-		}
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+
+	// Only active items can be paused
+	switch item.Status {
+	case StatusPending, StatusFetchingInfo, StatusDownloadingVideo,
+		StatusDownloadingAudio, StatusMuxing, StatusOrganizing:
+	default:
+		return fmt.Errorf("item %s is not in a pausable state (%s)", id, item.Status)
 	}
-	return fmt.Errorf("item not found: %s", id)
+	if item.cancelFunc != nil {
+		item.cancelFunc()
+	}
+	q.setStatus(item, StatusPaused)
+	item.setStage(StagePaused)
+	cp := *item
+	go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
 }
 
 // ResumeItem re-queues a paused item by resetting it to pending.
@@ -445,21 +754,20 @@ func (q *Queue) ResumeItem(id string) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	for i := range q.items {
-		if q.items[i].ID == id {
-			if q.items[i].Status != StatusPaused {
-				return fmt.Errorf("item %s is not paused (status: %s)", id, q.items[i].Status)
-			}
-			q.items[i].Status = StatusPending
-			q.items[i].Progress = 0
-			q.items[i].Stage = "Waiting... (resumed)"
-			q.items[i].cancelFunc = nil
-			item := q.items[i]
-			go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &item})
-			return nil
-		}
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
 	}
-	return fmt.Errorf("item not found: %s", id)
+	if item.Status != StatusPaused {
+		return fmt.Errorf("item %s is not paused (status: %s)", id, item.Status)
+	}
+	q.setStatus(item, StatusPending)
+	item.Progress = 0
+	item.setStage(StageWaitingResumed)
+	item.cancelFunc = nil
+	cp := *item
+	go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
 }
 
 // PauseAll pauses all active and pending items. Returns the count of items paused.
@@ -475,8 +783,8 @@ func (q *Queue) PauseAll() int {
 			if q.items[i].cancelFunc != nil {
 				q.items[i].cancelFunc()
 			}
-			q.items[i].Status = StatusPaused
-			q.items[i].Stage = "Paused"
+			q.setStatus(&q.items[i], StatusPaused)
+			q.items[i].setStage(StagePaused)
 			item := q.items[i]
 			go q.emit(QueueEvent{Type: "updated", ItemID: item.ID, Item: &item})
 			count++
@@ -493,9 +801,9 @@ func (q *Queue) ResumeAll() int {
 	count := 0
 	for i := range q.items {
 		if q.items[i].Status == StatusPaused {
-			q.items[i].Status = StatusPending
+			q.setStatus(&q.items[i], StatusPending)
 			q.items[i].Progress = 0
-			q.items[i].Stage = "Waiting... (resumed)"
+			q.items[i].setStage(StageWaitingResumed)
 			q.items[i].cancelFunc = nil
 			item := q.items[i]
 			go q.emit(QueueEvent{Type: "updated", ItemID: item.ID, Item: &item})
@@ -516,10 +824,13 @@ func (q *Queue) ClearCompleted() int {
 		if item.Status != StatusComplete && item.Status != StatusError && item.Status != StatusCancelled {
 			filtered = append(filtered, item)
 		} else {
+			if item.PreviewPath != "" {
+				os.Remove(item.PreviewPath)
+			}
 			removed++
 		}
 	}
-	q.items = filtered
+	q.setItems(filtered)
 	return removed
 }
 
@@ -529,10 +840,8 @@ func (q *Queue) GetFailedItems() []QueueItem {
 	defer q.mutex.RUnlock()
 
 	var failed []QueueItem
-	for _, item := range q.items {
-		if item.Status == StatusError {
-			failed = append(failed, item)
-		}
+	for _, id := range q.idsWithStatus(StatusError) {
+		failed = append(failed, *q.itemByID(id))
 	}
 	return failed
 }
@@ -545,10 +854,12 @@ func (q *Queue) RetryFailed() int {
 	retried := 0
 	for i := range q.items {
 		if q.items[i].Status == StatusError {
-			q.items[i].Status = StatusPending
+			q.setStatus(&q.items[i], StatusPending)
 			q.items[i].Progress = 0
 			q.items[i].Error = ""
-			q.items[i].Stage = "Waiting... (retry)"
+			q.items[i].setStage(StageWaitingRetry)
+			q.items[i].Retries++
+			q.items[i].Stalls = 0
 			retried++
 
 			item := q.items[i]
@@ -563,29 +874,33 @@ func (q *Queue) RetryWithOverride(id string, req RetryOverrideRequest) (*QueueIt
 	q.mutex.Lock()
 
 	var found *QueueItem
-	for i := range q.items {
-		if q.items[i].ID == id {
-			item := &q.items[i]
-			if req.MusicURL != "" {
-				item.SpotifyURL = req.MusicURL
-			}
-			if req.Artist != "" {
-				item.Artist = req.Artist
-			}
-			if req.Title != "" {
-				item.Title = req.Title
-			}
-			item.Status = StatusPending
-			item.Progress = 0
-			item.Error = ""
-			item.Stage = "Waiting... (retry with override)"
-			item.MatchCandidates = nil
-			item.MatchDiagnostics = nil
-			item.cancelFunc = nil
-			cp := *item
-			found = &cp
-			break
+	if item := q.itemByID(id); item != nil {
+		if req.MusicURL != "" {
+			item.SpotifyURL = req.MusicURL
+		}
+		if req.Artist != "" {
+			item.Artist = req.Artist
+		}
+		if req.Title != "" {
+			item.Title = req.Title
 		}
+		if req.VideoURL != "" {
+			item.VideoURL = req.VideoURL
+			item.AudioOnly = false
+			item.AlternativeVideos = nil
+			item.SubstitutedVideoURL = ""
+		}
+		q.setStatus(item, StatusPending)
+		item.Progress = 0
+		item.Error = ""
+		item.setStage(StageWaitingRetryOverride)
+		item.Retries++
+		item.Stalls = 0
+		item.MatchCandidates = nil
+		item.MatchDiagnostics = nil
+		item.cancelFunc = nil
+		cp := *item
+		found = &cp
 	}
 
 	q.mutex.Unlock()
@@ -610,7 +925,7 @@ func (q *Queue) ClearAll() {
 		}
 	}
 
-	q.items = make([]QueueItem, 0)
+	q.setItems(make([]QueueItem, 0))
 }
 
 // MoveItem moves an item to a new position in the queue
@@ -618,15 +933,7 @@ func (q *Queue) MoveItem(id string, newIndex int) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
-	// Find current index
-	currentIndex := -1
-	for i, item := range q.items {
-		if item.ID == id {
-			currentIndex = i
-			break
-		}
-	}
-
+	currentIndex := q.indexOf(id)
 	if currentIndex == -1 {
 		return fmt.Errorf("item not found: %s", id)
 	}
@@ -635,13 +942,7 @@ func (q *Queue) MoveItem(id string, newIndex int) error {
 		return fmt.Errorf("invalid index: %d", newIndex)
 	}
 
-	// Remove from current position
-	item := q.items[currentIndex]
-	q.items = append(q.items[:currentIndex], q.items[currentIndex+1:]...)
-
-	// Insert at new position
-	q.items = append(q.items[:newIndex], append([]QueueItem{item}, q.items[newIndex:]...)...)
-
+	q.moveItem(currentIndex, newIndex)
 	return nil
 }
 
@@ -666,7 +967,12 @@ func (q *Queue) StartProcessing() {
 	}
 
 	// Start dispatcher
+	q.workerWG.Add(1)
 	go q.dispatcher()
+
+	// Start watchdog for stalled items
+	q.workerWG.Add(1)
+	go q.watchdog()
 }
 
 // StopProcessing stops all workers
@@ -679,7 +985,6 @@ func (q *Queue) StopProcessing() {
 	q.processMutex.Unlock()
 
 	q.cancel()
-	close(q.jobChan)
 	q.workerWG.Wait()
 
 	q.processMutex.Lock()
@@ -687,30 +992,110 @@ func (q *Queue) StopProcessing() {
 	q.processMutex.Unlock()
 }
 
-// dispatcher finds pending items and sends them to workers
+// scheduleRecheckInterval is how often a closed download schedule is re-checked
+const scheduleRecheckInterval = 30 * time.Second
+
+// dispatcher hands pending items to workers in queue order. It sleeps until
+// signal reports new pending work, and blocks on the unbuffered job channel
+// while every worker is busy, so each pending item is sent exactly once.
 func (q *Queue) dispatcher() {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
+	defer q.workerWG.Done()
 
 	for {
+		// Outside the download schedule, running items finish but nothing new starts
+		if !q.scheduleOpen(time.Now()) {
+			if !q.waitForWork(scheduleRecheckInterval) {
+				return
+			}
+			continue
+		}
+
+		id := q.nextPending()
+		if id == "" {
+			if !q.waitForWork(0) {
+				return
+			}
+			continue
+		}
+
 		select {
+		case q.jobChan <- id:
 		case <-q.ctx.Done():
 			return
-		case <-ticker.C:
-			// Find pending items
-			q.mutex.RLock()
-			for _, item := range q.items {
-				if item.Status == StatusPending {
-					select {
-					case q.jobChan <- item.ID:
-					default:
-						// Channel full, skip
+		}
+	}
+}
+
+// signal wakes the dispatcher. Never blocks; callers may hold q.mutex.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+// waitForWork blocks until signal is called, timeout passes (if > 0) or
+// processing stops. Returns false once the queue is stopped.
+func (q *Queue) waitForWork(timeout time.Duration) bool {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case <-q.ctx.Done():
+		return false
+	case <-q.wake:
+		return true
+	case <-timer:
+		return true
+	}
+}
+
+// nextPending returns the first pending item not already handed to a worker
+// whose prerequisites are complete and marks it dispatched, or "" if there is
+// none. Pending items whose prerequisites failed are failed along the way.
+func (q *Queue) nextPending() string {
+	q.mutex.Lock()
+	next := ""
+	blocked := make(map[string]error)
+	for next == "" {
+		id := q.ready.pop()
+		if id == "" {
+			break
+		}
+		item := q.itemByID(id)
+		switch {
+		case q.dispatched[id]:
+			// Its worker starts it anyway
+		case item.running:
+			q.park(id, id)
+		default:
+			state, err := q.dependencyStateOf(item)
+			switch state {
+			case dependenciesFailed:
+				blocked[id] = err
+			case dependenciesWaiting:
+				for _, depID := range item.DependsOn {
+					if dep := q.itemByID(depID); dep != nil && !isFinished(dep.Status) {
+						q.park(id, depID)
 					}
 				}
+			default:
+				q.dispatched[id] = true
+				next = id
 			}
-			q.mutex.RUnlock()
 		}
 	}
+	q.mutex.Unlock()
+
+	for id, err := range blocked {
+		q.SetItemError(id, err)
+	}
+	return next
 }
 
 // worker processes items from the job channel
@@ -721,10 +1106,7 @@ func (q *Queue) worker(workerID int) {
 		select {
 		case <-q.ctx.Done():
 			return
-		case itemID, ok := <-q.jobChan:
-			if !ok {
-				return
-			}
+		case itemID := <-q.jobChan:
 			q.processItem(itemID)
 		}
 	}
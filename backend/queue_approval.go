@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Approval queue
+// =============================================================================
+
+// In review-before-download mode new items wait in StatusAwaitingApproval.
+// A dry-run plan is computed in the background so the reviewer sees resolved
+// metadata, candidate matches and the output path; nothing is downloaded
+// until the item is approved.
+
+// Approval modes for Config.ApprovalMode
+const (
+	ApprovalOff           = "off"
+	ApprovalSubscriptions = "subscriptions" // Channel archives and the artist watchlist
+	ApprovalAll           = "all"
+)
+
+// approvalPlanner plans items awaiting approval; replaced in tests
+var approvalPlanner = PlanDownload
+
+// requiresApproval reports whether a new item from request waits for approval
+func requiresApproval(request DownloadRequest, config *Config) bool {
+	if request.DryRun {
+		return false
+	}
+	return request.RequireApproval || (config != nil && config.ApprovalMode == ApprovalAll)
+}
+
+// subscriptionApproval reports whether items queued by channel syncs and
+// the watchlist wait for approval
+func subscriptionApproval(config *Config) bool {
+	return config != nil && (config.ApprovalMode == ApprovalSubscriptions || config.ApprovalMode == ApprovalAll)
+}
+
+// planForApproval fills in the plan of an item awaiting approval
+func (q *Queue) planForApproval(id string) {
+	item := q.GetItem(id)
+	if item == nil || item.Status != StatusAwaitingApproval {
+		return
+	}
+	q.mutex.RLock()
+	config := q.configs.Get()
+	fileIndex := q.fileIndex
+	q.mutex.RUnlock()
+	if config == nil {
+		config = &defaultConfig
+	}
+
+	plan := approvalPlanner(item, config, fileIndex)
+
+	q.updateItem(id, func(item *QueueItem) {
+		if item.Status != StatusAwaitingApproval {
+			return // Approved or rejected while planning
+		}
+		item.Plan = plan
+		if item.Title == "" {
+			item.Title = plan.Title
+			item.Artist = plan.Artist
+			item.Duration = plan.Duration
+		}
+		item.MatchCandidates = plan.Candidates
+		item.AudioSource = plan.AudioSource
+		item.setStage(StageApproval)
+		switch {
+		case plan.ExistingFile != "":
+			item.setStage(StageApprovalInLibrary)
+		case !plan.WillDownload:
+			item.setStage(StageApprovalWouldFail)
+		}
+	})
+}
+
+// ApproveItem starts an item awaiting approval. A non-empty musicURL picks
+// the audio match (e.g. one of the plan's candidates) like a retry override.
+func (q *Queue) ApproveItem(id, musicURL string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if item.Status != StatusAwaitingApproval {
+		return fmt.Errorf("item %s is not awaiting approval (%s)", id, item.Status)
+	}
+	if musicURL = strings.TrimSpace(musicURL); musicURL != "" {
+		item.SpotifyURL = musicURL
+	}
+	q.approve(item)
+	cp := *item
+	go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
+}
+
+// ApproveAll starts every item awaiting approval and returns how many
+func (q *Queue) ApproveAll() int {
+	q.mutex.Lock()
+	var approved []QueueItem
+	for _, id := range q.idsWithStatus(StatusAwaitingApproval) {
+		if item := q.itemByID(id); item != nil {
+			q.approve(item)
+			approved = append(approved, *item)
+		}
+	}
+	q.mutex.Unlock()
+
+	for i := range approved {
+		q.emit(QueueEvent{Type: "updated", ItemID: approved[i].ID, Item: &approved[i]})
+	}
+	return len(approved)
+}
+
+// approve moves an item to pending; callers hold q.mutex
+func (q *Queue) approve(item *QueueItem) {
+	item.Plan = nil
+	item.MatchCandidates = nil
+	item.AudioSource = ""
+	q.setStatus(item, StatusPending)
+	item.Progress = 0
+	item.setStage(StageWaiting)
+}
+
+// RejectItem cancels an item awaiting approval
+func (q *Queue) RejectItem(id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	item := q.itemByID(id)
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if item.Status != StatusAwaitingApproval {
+		return fmt.Errorf("item %s is not awaiting approval (%s)", id, item.Status)
+	}
+	q.setStatus(item, StatusCancelled)
+	item.setStage(StageRejected)
+	item.CompletedAt = time.Now()
+	cp := *item
+	go q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
+}
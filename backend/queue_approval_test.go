@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+// stubApprovalPlanner replaces the network-bound planner for one test
+func stubApprovalPlanner(t *testing.T) {
+	approvalPlanner = func(item *QueueItem, config *Config, fileIndex *FileIndex) *DownloadPlan {
+		return &DownloadPlan{
+			Title:        "Planned Title",
+			Artist:       "Planned Artist",
+			Candidates:   []AudioCandidate{{Platform: "tidal", URL: "https://tidal.com/browse/track/1"}},
+			WillDownload: true,
+		}
+	}
+	t.Cleanup(func() { approvalPlanner = PlanDownload })
+}
+
+// waitForStage polls until the item's stage is stage
+func waitForStage(t *testing.T, q *Queue, id, stage string) *QueueItem {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		item := q.GetItem(id)
+		if item != nil && item.Stage == stage {
+			return item
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("item stage = %+v, want %q", item, stage)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestApprovalQueue(t *testing.T) {
+	stubApprovalPlanner(t)
+	q := newTestQueue()
+
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://youtu.be/aaaaaaaaaaa", RequireApproval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := waitForStage(t, q, id, "Awaiting approval")
+	if item.Status != StatusAwaitingApproval || item.Title != "Planned Title" || len(item.MatchCandidates) != 1 || item.Plan == nil {
+		t.Fatalf("planned item = %+v", item)
+	}
+	if q.GetPendingCount() != 0 || q.GetStats().AwaitingApproval != 1 {
+		t.Error("item awaiting approval should not be pending")
+	}
+
+	if err := q.ApproveItem(id, "https://tidal.com/browse/track/1"); err != nil {
+		t.Fatal(err)
+	}
+	item = q.GetItem(id)
+	if item.Status != StatusPending || item.SpotifyURL != "https://tidal.com/browse/track/1" || item.Plan != nil {
+		t.Errorf("approved item = %+v", item)
+	}
+	if err := q.ApproveItem(id, ""); err == nil {
+		t.Error("approving a pending item should fail")
+	}
+
+	rejected, _ := q.AddToQueue(DownloadRequest{VideoURL: "https://youtu.be/bbbbbbbbbbb", RequireApproval: true})
+	waitForStage(t, q, rejected, "Awaiting approval")
+	if err := q.RejectItem(rejected); err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(rejected); item.Status != StatusCancelled || item.Stage != "Rejected" {
+		t.Errorf("rejected item = %+v", item)
+	}
+
+	// Dry runs never wait for approval
+	dry, _ := q.AddToQueue(DownloadRequest{VideoURL: "https://youtu.be/ccccccccccc", RequireApproval: true, DryRun: true})
+	if q.GetItem(dry).Status != StatusPending {
+		t.Error("dry run should not wait for approval")
+	}
+}
+
+func TestApprovalMode(t *testing.T) {
+	stubApprovalPlanner(t)
+	q := newTestQueue()
+	q.SetConfig(&Config{ApprovalMode: ApprovalAll})
+
+	for _, videoURL := range []string{"https://youtu.be/aaaaaaaaaaa", "https://youtu.be/bbbbbbbbbbb"} {
+		id, err := q.AddToQueue(DownloadRequest{VideoURL: videoURL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForStage(t, q, id, "Awaiting approval")
+	}
+	if q.GetStats().AwaitingApproval != 2 {
+		t.Fatalf("stats = %+v", q.GetStats())
+	}
+	if n := q.ApproveAll(); n != 2 || q.GetPendingCount() != 2 {
+		t.Errorf("ApproveAll = %d, %d pending", n, q.GetPendingCount())
+	}
+
+	if subscriptionApproval(&Config{ApprovalMode: ApprovalOff}) || !subscriptionApproval(&Config{ApprovalMode: ApprovalSubscriptions}) {
+		t.Error("subscriptionApproval")
+	}
+	if requiresApproval(DownloadRequest{}, &Config{ApprovalMode: ApprovalSubscriptions}) {
+		t.Error("manual adds should not wait in subscriptions mode")
+	}
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"os"
+	"time"
+)
+
+// =============================================================================
+// Audit Trail
+// =============================================================================
+
+// StageRecord is one step in an item's processing timeline
+type StageRecord struct {
+	Status    QueueStatus `json:"status"`
+	Detail    string      `json:"detail,omitempty"` // Stage message when the step began, or the error
+	StartedAt time.Time   `json:"startedAt"`
+	EndedAt   time.Time   `json:"endedAt,omitempty"`
+	Seconds   float64     `json:"seconds,omitempty"`
+}
+
+// HistoryAudit explains how a download went: how long each stage took,
+// which sources were tried and which service actually served the audio
+type HistoryAudit struct {
+	Timeline        []StageRecord `json:"timeline,omitempty"`
+	SourcesTried    []string      `json:"sourcesTried,omitempty"`
+	AudioService    string        `json:"audioService,omitempty"` // e.g. "tidal-hifi", "lucida", "orpheusdl"
+	Retries         int           `json:"retries,omitempty"`
+	BytesDownloaded int64         `json:"bytesDownloaded,omitempty"` // Video + audio bytes fetched
+	Warnings        []string      `json:"warnings,omitempty"`        // Non-fatal problems, see QueueItem.Warnings
+	QueuedAt        time.Time     `json:"queuedAt"`
+	StartedAt       time.Time     `json:"startedAt,omitempty"`
+	TotalSeconds    float64       `json:"totalSeconds,omitempty"` // From processing start to completion
+}
+
+// recordTimeline closes the open timeline step and starts a new one for the
+// item's current status. Called whenever the status changes.
+func recordTimeline(item *QueueItem, now time.Time) {
+	if n := len(item.Timeline); n > 0 && item.Timeline[n-1].EndedAt.IsZero() {
+		last := &item.Timeline[n-1]
+		last.EndedAt = now
+		last.Seconds = now.Sub(last.StartedAt).Seconds()
+	}
+
+	record := StageRecord{Status: item.Status, Detail: item.Stage, StartedAt: now}
+	switch item.Status {
+	case StatusPending:
+		return
+	case StatusError:
+		record.Detail = item.Error
+		record.EndedAt = now
+	case StatusComplete, StatusCancelled, StatusPaused:
+		record.EndedAt = now
+	}
+	item.Timeline = append(item.Timeline, record)
+}
+
+// newHistoryAudit builds the audit trail stored with a history entry
+func newHistoryAudit(item *QueueItem) *HistoryAudit {
+	audit := &HistoryAudit{
+		Timeline:        item.Timeline,
+		SourcesTried:    item.SourcesTried,
+		AudioService:    item.AudioService,
+		Retries:         item.Retries,
+		BytesDownloaded: item.BytesDownloaded,
+		Warnings:        item.Warnings,
+		QueuedAt:        item.CreatedAt,
+		StartedAt:       item.StartedAt,
+	}
+	if !item.StartedAt.IsZero() {
+		end := item.CompletedAt
+		if end.IsZero() {
+			end = time.Now()
+		}
+		audit.TotalSeconds = end.Sub(item.StartedAt).Seconds()
+	}
+	return audit
+}
+
+// pathSize returns the size of path, or 0 if it can't be read
+func pathSize(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return stat.Size()
+}
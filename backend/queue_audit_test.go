@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordTimeline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := &QueueItem{Timeline: []StageRecord{{Status: StatusFetchingInfo, StartedAt: start}}}
+
+	item.Status, item.Stage = StatusDownloadingVideo, "Downloading video..."
+	recordTimeline(item, start.Add(3*time.Second))
+	item.Status, item.Error = StatusError, "video unavailable"
+	recordTimeline(item, start.Add(10*time.Second))
+
+	if len(item.Timeline) != 3 {
+		t.Fatalf("timeline = %+v, want 3 steps", item.Timeline)
+	}
+	if item.Timeline[0].Seconds != 3 || item.Timeline[1].Seconds != 7 {
+		t.Errorf("durations = %v, %v; want 3, 7", item.Timeline[0].Seconds, item.Timeline[1].Seconds)
+	}
+	if last := item.Timeline[2]; last.Status != StatusError || last.Detail != "video unavailable" || last.EndedAt.IsZero() {
+		t.Errorf("error step = %+v", last)
+	}
+}
+
+func TestQueueAuditTrail(t *testing.T) {
+	q := newTestQueue()
+	q.setItems([]QueueItem{{
+		ID:        "a",
+		Status:    StatusFetchingInfo,
+		CreatedAt: time.Now().Add(-time.Minute),
+		StartedAt: time.Now(),
+		Timeline:  []StageRecord{{Status: StatusFetchingInfo, StartedAt: time.Now()}},
+	}})
+
+	q.UpdateStatus("a", StatusDownloadingAudio, 50, "Downloading from tidal...")
+	q.SetItemError("a", errors.New("no source"))
+	if n := q.RetryFailed(); n != 1 {
+		t.Fatalf("RetryFailed = %d", n)
+	}
+
+	item := q.GetItem("a")
+	if item.Retries != 1 {
+		t.Errorf("Retries = %d, want 1", item.Retries)
+	}
+	item.SourcesTried = []string{"song.link", "tidal"}
+	item.AudioService = "lucida"
+	item.BytesDownloaded = 1234
+
+	audit := newHistoryAudit(item)
+	if len(audit.Timeline) != 3 || audit.Timeline[1].Detail != "Downloading from tidal..." {
+		t.Errorf("timeline = %+v", audit.Timeline)
+	}
+	if audit.Retries != 1 || audit.AudioService != "lucida" || audit.BytesDownloaded != 1234 || audit.TotalSeconds <= 0 {
+		t.Errorf("audit = %+v", audit)
+	}
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// Queue item dependencies
+// =============================================================================
+
+// An item can depend on other queue items (QueueItem.DependsOn), e.g. an
+// intro video that has to land in the library before the album tracks. The
+// dispatcher skips a pending item until all of its prerequisites are
+// complete, and fails it once one of them fails or is cancelled, which in
+// turn fails the items depending on it. A prerequisite that is no longer in
+// the queue (cleared after completing, or removed) does not hold anything up.
+
+// dependencyState is how far the prerequisites of an item are
+type dependencyState int
+
+const (
+	dependenciesMet dependencyState = iota
+	dependenciesWaiting
+	dependenciesFailed
+)
+
+// dependencyStateOf reports whether item may start. For a failed state it
+// also returns the error the item fails with. Callers hold q.mutex.
+func (q *Queue) dependencyStateOf(item *QueueItem) (dependencyState, error) {
+	state := dependenciesMet
+	for _, depID := range item.DependsOn {
+		dep := q.itemByID(depID)
+		if dep == nil {
+			continue
+		}
+		switch dep.Status {
+		case StatusComplete:
+		case StatusError:
+			return dependenciesFailed, fmt.Errorf("prerequisite %q failed: %s", dependencyName(dep), dep.Error)
+		case StatusCancelled:
+			return dependenciesFailed, fmt.Errorf("prerequisite %q was cancelled", dependencyName(dep))
+		default:
+			state = dependenciesWaiting
+		}
+	}
+	return state, nil
+}
+
+// dependencyName is how an error refers to a prerequisite
+func dependencyName(item *QueueItem) string {
+	switch {
+	case item.Title != "" && item.Artist != "":
+		return item.Artist + " - " + item.Title
+	case item.Title != "":
+		return item.Title
+	case item.VideoURL != "":
+		return item.VideoURL
+	}
+	return item.ID
+}
+
+// checkDependencies validates the prerequisites of item id: they must be
+// queued, and depending on them must not close a cycle. Callers hold q.mutex.
+func (q *Queue) checkDependencies(id string, deps []string) error {
+	for _, depID := range deps {
+		if depID == id {
+			return fmt.Errorf("item cannot depend on itself")
+		}
+		if q.itemByID(depID) == nil {
+			return fmt.Errorf("prerequisite not found: %s", depID)
+		}
+		if q.dependsOn(depID, id, map[string]bool{}) {
+			return fmt.Errorf("dependency on %s would create a cycle", depID)
+		}
+	}
+	return nil
+}
+
+// dependsOn reports whether item id depends on target, directly or through
+// other items. Callers hold q.mutex.
+func (q *Queue) dependsOn(id, target string, seen map[string]bool) bool {
+	if seen[id] {
+		return false
+	}
+	seen[id] = true
+	item := q.itemByID(id)
+	if item == nil {
+		return false
+	}
+	for _, depID := range item.DependsOn {
+		if depID == target || q.dependsOn(depID, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetItemDependencies replaces the prerequisites of a queue item. Items
+// already running or finished keep their result.
+func (q *Queue) SetItemDependencies(id string, deps []string) error {
+	deps = slices.Compact(slices.Sorted(slices.Values(deps)))
+
+	q.mutex.Lock()
+	// Check and update under one lock so concurrent calls can't both pass
+	// the cycle check and park each other forever
+	if err := q.checkDependencies(id, deps); err != nil {
+		q.mutex.Unlock()
+		return err
+	}
+	item := q.itemByID(id)
+	if item == nil {
+		q.mutex.Unlock()
+		return fmt.Errorf("item not found: %s", id)
+	}
+
+	var updated *QueueItem
+	if item.stall == nil {
+		prev := item.Status
+		item.DependsOn = deps
+		// It may be parked on a prerequisite it no longer has
+		if item.Status == StatusPending {
+			q.ready.add(id)
+		}
+		if item.Status == StatusPending && len(deps) > 0 {
+			item.setStage(StageWaitingDependency)
+		} else if item.Status == StatusPending && item.StageCode == StageWaitingDependency {
+			item.setStage(StageWaiting)
+		}
+		item.lastActivity = time.Now()
+		trackStage(item, prev, time.Now())
+		cp := *item
+		updated = &cp
+	}
+	q.mutex.Unlock()
+
+	if updated != nil {
+		q.emit(QueueEvent{
+			Type:        "updated",
+			ItemID:      id,
+			Item:        updated,
+			Progress:    updated.Progress,
+			Status:      updated.Status,
+			StageCode:   updated.StageCode,
+			StageParams: updated.StageParams,
+		})
+	}
+	q.signal()
+	return nil
+}
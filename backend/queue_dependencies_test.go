@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQueueDependencies_Order(t *testing.T) {
+	q := newTestQueue()
+	a, err := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: "Intro", Artist: "Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := q.AddToQueue(DownloadRequest{DependsOn: []string{a}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(b); item.StageCode != StageWaitingDependency {
+		t.Errorf("stage = %q, want %q", item.StageCode, StageWaitingDependency)
+	}
+	// Moving the dependent first does not let it start first
+	if err := q.MoveItem(b, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := q.nextPending(); id != a {
+		t.Fatalf("nextPending = %q, want the prerequisite", id)
+	}
+	if id := q.nextPending(); id != "" {
+		t.Fatalf("nextPending = %q while the prerequisite runs", id)
+	}
+	q.UpdateStatus(a, StatusComplete, 100, "")
+	if id := q.nextPending(); id != b {
+		t.Errorf("nextPending = %q, want the dependent once the prerequisite completed", id)
+	}
+}
+
+func TestQueueDependencies_FailureCascades(t *testing.T) {
+	q := newTestQueue()
+	a, _ := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: "Intro", Artist: "Artist"})
+	b, _ := q.AddToQueue(DownloadRequest{DependsOn: []string{a}})
+	c, _ := q.AddToQueue(DownloadRequest{DependsOn: []string{b}})
+	other, _ := q.AddToQueue(DownloadRequest{})
+
+	q.nextPending()
+	q.SetItemError(a, fmt.Errorf("no_lossless_source"))
+
+	if id := q.nextPending(); id != other {
+		t.Errorf("nextPending = %q, want the independent item", id)
+	}
+	item := q.GetItem(b)
+	if item.Status != StatusError || !strings.Contains(item.Error, `"Artist - Intro" failed: no_lossless_source`) {
+		t.Errorf("dependent = %s %q, want an error naming the prerequisite", item.Status, item.Error)
+	}
+
+	// The next pass fails the items depending on the failed dependent
+	q.nextPending()
+	if item := q.GetItem(c); item.Status != StatusError {
+		t.Errorf("second-level dependent = %s, want error", item.Status)
+	}
+}
+
+func TestQueueDependencies_Validation(t *testing.T) {
+	q := newTestQueue()
+	a, _ := q.AddToQueue(DownloadRequest{})
+	b, _ := q.AddToQueue(DownloadRequest{DependsOn: []string{a}})
+
+	if _, err := q.AddToQueue(DownloadRequest{DependsOn: []string{"missing"}}); err == nil {
+		t.Error("AddToQueue accepted an unknown prerequisite")
+	}
+	if err := q.SetItemDependencies(a, []string{a}); err == nil {
+		t.Error("SetItemDependencies accepted a self-dependency")
+	}
+	if err := q.SetItemDependencies(a, []string{b}); err == nil {
+		t.Error("SetItemDependencies accepted a cycle")
+	}
+
+	if err := q.SetItemDependencies(b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(b); len(item.DependsOn) != 0 || item.StageCode != StageWaiting {
+		t.Errorf("dependent = %v %q, want no prerequisites and waiting", item.DependsOn, item.StageCode)
+	}
+
+	// A prerequisite removed from the queue no longer holds anything up
+	if err := q.SetItemDependencies(b, []string{a, a}); err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(b); len(item.DependsOn) != 1 {
+		t.Errorf("DependsOn = %v, want duplicates dropped", item.DependsOn)
+	}
+	q.RemoveFromQueue(a)
+	if id := q.nextPending(); id != b {
+		t.Errorf("nextPending = %q, want the dependent of a removed item", id)
+	}
+}
+
+func TestQueueDependencies_ConcurrentCycle(t *testing.T) {
+	for range 50 {
+		q := newTestQueue()
+		a, _ := q.AddToQueue(DownloadRequest{})
+		b, _ := q.AddToQueue(DownloadRequest{})
+
+		errs := make(chan error, 2)
+		go func() { errs <- q.SetItemDependencies(a, []string{b}) }()
+		go func() { errs <- q.SetItemDependencies(b, []string{a}) }()
+		failed := 0
+		for range 2 {
+			if <-errs != nil {
+				failed++
+			}
+		}
+		if failed != 1 {
+			t.Fatalf("%d of two opposite dependencies were rejected, want exactly one", failed)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package backend
+
+import (
+	"sort"
+	"time"
+)
+
+// =============================================================================
+// Queue ETA
+// =============================================================================
+
+// pipelineStages are the active statuses in the order an item goes through them
+var pipelineStages = []QueueStatus{
+	StatusFetchingInfo,
+	StatusDownloadingVideo,
+	StatusDownloadingAudio,
+	StatusMuxing,
+	StatusOrganizing,
+}
+
+// etaSampleSize is how many recent completed downloads feed the rolling averages
+const etaSampleSize = 50
+
+func stageIndex(status QueueStatus) int {
+	for i, s := range pipelineStages {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// trackStage adds the time spent in prev to StageDurations and the audit
+// timeline when an item moves to a different status, and starts timing the new stage
+func trackStage(item *QueueItem, prev QueueStatus, now time.Time) {
+	if item.Status == prev {
+		return
+	}
+	recordTimeline(item, now)
+	if stageIndex(prev) >= 0 && !item.StageStartedAt.IsZero() {
+		if item.StageDurations == nil {
+			item.StageDurations = make(map[string]float64)
+		}
+		item.StageDurations[string(prev)] += now.Sub(item.StageStartedAt).Seconds()
+	}
+	if stageIndex(item.Status) >= 0 {
+		item.StageStartedAt = now
+	} else {
+		item.StageStartedAt = time.Time{}
+	}
+}
+
+// stageAverage is the rolling average for one pipeline stage. Download and
+// mux times grow with track length, so a per-media-second rate is kept
+// alongside the plain mean.
+type stageAverage struct {
+	totalSeconds float64
+	samples      int
+	rateSum      float64 // stage seconds per second of media
+	rateSamples  int
+}
+
+// ETAModel predicts per-stage durations from recent History entries
+type ETAModel struct {
+	stages  map[QueueStatus]*stageAverage
+	Samples int
+}
+
+// NewETAModel builds a model from history entries (newest first). Only
+// completed downloads that recorded stage durations are used.
+func NewETAModel(entries []HistoryEntry) *ETAModel {
+	m := &ETAModel{stages: make(map[QueueStatus]*stageAverage)}
+	for _, e := range entries {
+		if e.Status != "complete" || len(e.StageDurations) == 0 {
+			continue
+		}
+		m.Samples++
+		for stage, seconds := range e.StageDurations {
+			avg := m.stages[QueueStatus(stage)]
+			if avg == nil {
+				avg = &stageAverage{}
+				m.stages[QueueStatus(stage)] = avg
+			}
+			avg.totalSeconds += seconds
+			avg.samples++
+			if e.Duration > 0 {
+				avg.rateSum += seconds / e.Duration
+				avg.rateSamples++
+			}
+		}
+		if m.Samples >= etaSampleSize {
+			break
+		}
+	}
+	return m
+}
+
+// stageSeconds predicts how long one stage takes for media of the given length
+func (m *ETAModel) stageSeconds(stage QueueStatus, mediaSeconds float64) float64 {
+	avg := m.stages[stage]
+	if avg == nil || avg.samples == 0 {
+		return 0
+	}
+	if mediaSeconds > 0 && avg.rateSamples > 0 {
+		return avg.rateSum / float64(avg.rateSamples) * mediaSeconds
+	}
+	return avg.totalSeconds / float64(avg.samples)
+}
+
+// PredictDuration returns the predicted total processing time for an item
+func (m *ETAModel) PredictDuration(item *QueueItem) float64 {
+	var total float64
+	for _, stage := range pipelineStages {
+		total += m.stageSeconds(stage, item.Duration)
+	}
+	return total
+}
+
+// PredictRemaining returns the predicted time left for an item, taking the
+// stages it has already finished and time spent in the current one into account
+func (m *ETAModel) PredictRemaining(item *QueueItem, now time.Time) float64 {
+	current := stageIndex(item.Status)
+	if current < 0 {
+		if item.Status == StatusPending {
+			return m.PredictDuration(item)
+		}
+		return 0
+	}
+
+	remaining := m.stageSeconds(item.Status, item.Duration)
+	if !item.StageStartedAt.IsZero() {
+		remaining -= now.Sub(item.StageStartedAt).Seconds()
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	for _, stage := range pipelineStages[current+1:] {
+		remaining += m.stageSeconds(stage, item.Duration)
+	}
+	return remaining
+}
+
+// fillEstimates adds ETA fields to stats. Items are scheduled in queue order
+// onto maxConc workers, active items first, to predict when the queue drains.
+// Caller must hold q.mutex.
+func (q *Queue) fillEstimates(stats *QueueStats, model *ETAModel, now time.Time) {
+	if model == nil || model.Samples == 0 {
+		return
+	}
+	stats.EstimateSamples = model.Samples
+	stats.ItemEstimates = make(map[string]float64)
+
+	workers := make([]float64, max(q.maxConc, 1))
+	schedule := func(seconds float64) {
+		sort.Float64s(workers)
+		workers[0] += seconds
+	}
+
+	for i := range q.items {
+		item := &q.items[i]
+		if stageIndex(item.Status) >= 0 {
+			stats.ItemEstimates[item.ID] = model.PredictDuration(item)
+			schedule(model.PredictRemaining(item, now))
+		}
+	}
+	for i := range q.items {
+		item := &q.items[i]
+		if item.Status == StatusPending {
+			predicted := model.PredictDuration(item)
+			stats.ItemEstimates[item.ID] = predicted
+			schedule(predicted)
+		}
+	}
+
+	for _, w := range workers {
+		stats.ETASeconds = max(stats.ETASeconds, w)
+	}
+	if stats.ETASeconds > 0 {
+		finish := now.Add(time.Duration(stats.ETASeconds * float64(time.Second)))
+		stats.EstimatedFinish = &finish
+	}
+}
@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func etaHistory() []HistoryEntry {
+	return []HistoryEntry{
+		{Status: "complete", Duration: 200, StageDurations: map[string]float64{
+			"fetching_info": 2, "downloading_video": 20, "downloading_audio": 10, "muxing": 4, "organizing": 4,
+		}},
+		{Status: "complete", Duration: 200, StageDurations: map[string]float64{
+			"fetching_info": 4, "downloading_video": 40, "downloading_audio": 10, "muxing": 4, "organizing": 6,
+		}},
+		{Status: "error", StageDurations: map[string]float64{"fetching_info": 500}},
+		{Status: "complete"}, // recorded before stage timing existed
+	}
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+
+func TestETAModel_Predict(t *testing.T) {
+	m := NewETAModel(etaHistory())
+	if m.Samples != 2 {
+		t.Fatalf("Samples = %d, want 2 (errors and untimed entries ignored)", m.Samples)
+	}
+
+	// Averages are 3+30+10+4+5 = 52s for a 200s track, scaled by length
+	if got := m.PredictDuration(&QueueItem{Duration: 200}); !approx(got, 52) {
+		t.Errorf("PredictDuration(200s) = %v, want 52", got)
+	}
+	if got := m.PredictDuration(&QueueItem{Duration: 400}); !approx(got, 104) {
+		t.Errorf("PredictDuration(400s) = %v, want 104", got)
+	}
+	if got := m.PredictDuration(&QueueItem{}); !approx(got, 52) {
+		t.Errorf("PredictDuration(unknown length) = %v, want plain mean 52", got)
+	}
+
+	now := time.Now()
+	muxing := &QueueItem{Status: StatusMuxing, Duration: 200, StageStartedAt: now.Add(-3 * time.Second)}
+	if got := m.PredictRemaining(muxing, now); !approx(got, 6) {
+		t.Errorf("PredictRemaining(muxing) = %v, want 1s of mux + 5s organizing", got)
+	}
+	if got := m.PredictRemaining(&QueueItem{Status: StatusComplete}, now); got != 0 {
+		t.Errorf("PredictRemaining(complete) = %v, want 0", got)
+	}
+}
+
+func TestTrackStage(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := &QueueItem{Status: StatusFetchingInfo, StageStartedAt: start}
+
+	item.Status = StatusDownloadingVideo
+	trackStage(item, StatusFetchingInfo, start.Add(2*time.Second))
+	item.Status = StatusComplete
+	trackStage(item, StatusDownloadingVideo, start.Add(12*time.Second))
+
+	if item.StageDurations["fetching_info"] != 2 || item.StageDurations["downloading_video"] != 10 {
+		t.Errorf("StageDurations = %v", item.StageDurations)
+	}
+	if !item.StageStartedAt.IsZero() {
+		t.Error("stage timer should stop once the item is finished")
+	}
+}
+
+func TestGetStats_ETA(t *testing.T) {
+	q := newTestQueue()
+	q.maxConc = 2
+
+	if stats := q.GetStats(); stats.ETASeconds != 0 || stats.ItemEstimates != nil {
+		t.Errorf("no history should mean no estimate, got %+v", stats)
+	}
+
+	h := &History{entries: etaHistory(), filePath: t.TempDir() + "/history.json"}
+	q.SetHistory(h)
+	q.setItems([]QueueItem{
+		{ID: "1", Status: StatusPending, Duration: 200},
+		{ID: "2", Status: StatusPending, Duration: 200},
+		{ID: "3", Status: StatusPending, Duration: 200},
+		{ID: "4", Status: StatusComplete, Duration: 200},
+	})
+
+	stats := q.GetStats()
+	if len(stats.ItemEstimates) != 3 || !approx(stats.ItemEstimates["1"], 52) {
+		t.Errorf("ItemEstimates = %v", stats.ItemEstimates)
+	}
+	// Three 52s items on two workers finish after 104s
+	if !approx(stats.ETASeconds, 104) || stats.EstimatedFinish == nil {
+		t.Errorf("ETASeconds = %v, finish = %v", stats.ETASeconds, stats.EstimatedFinish)
+	}
+}
@@ -0,0 +1,225 @@
+package backend
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// =============================================================================
+// Queue Index
+// =============================================================================
+
+// The queue keeps its items in a slice (display and dispatch order) plus an
+// index of ID -> slice position and of IDs by status, so item lookups and
+// dispatch don't scan multi-thousand-item playlists. Pending items the
+// dispatcher may start are also kept in a heap by queue position; items
+// that wait for another item are parked until that item finishes, so
+// nextPending doesn't re-check them on every call. All helpers below must be
+// called with q.mutex held (a read lock is enough for the lookups).
+
+// indexOf returns the slice position of the item with the given ID, or -1
+func (q *Queue) indexOf(id string) int {
+	if i, ok := q.positions[id]; ok {
+		return i
+	}
+	return -1
+}
+
+// itemByID returns a pointer into q.items, or nil if the ID is unknown
+func (q *Queue) itemByID(id string) *QueueItem {
+	if i := q.indexOf(id); i >= 0 {
+		return &q.items[i]
+	}
+	return nil
+}
+
+// setItems replaces all items and rebuilds the index
+func (q *Queue) setItems(items []QueueItem) {
+	q.items = items
+	q.positions = make(map[string]int, len(items))
+	q.byStatus = make(map[QueueStatus]map[string]struct{})
+	q.ready = &pendingHeap{index: make(map[string]int), positions: q.positions}
+	q.waiters = make(map[string][]string)
+	for i := range q.items {
+		q.positions[q.items[i].ID] = i
+		q.addToBucket(q.items[i].ID, q.items[i].Status)
+	}
+}
+
+// appendItem adds an item to the end of the queue
+func (q *Queue) appendItem(item QueueItem) {
+	q.items = append(q.items, item)
+	q.positions[item.ID] = len(q.items) - 1
+	q.addToBucket(item.ID, item.Status)
+}
+
+// removeAt removes the item at slice position i
+func (q *Queue) removeAt(i int) {
+	item := q.items[i]
+	q.removeFromBucket(item.ID, item.Status)
+	delete(q.positions, item.ID)
+
+	// Removing an item keeps the order of the others, so the heap stays valid
+	q.items = append(q.items[:i], q.items[i+1:]...)
+	q.reindexFrom(i)
+	q.release(item.ID)
+}
+
+// moveItem moves the item at slice position from to position to
+func (q *Queue) moveItem(from, to int) {
+	item := q.items[from]
+	q.items = append(q.items[:from], q.items[from+1:]...)
+	q.items = append(q.items[:to], append([]QueueItem{item}, q.items[to:]...)...)
+	q.reindexFrom(min(from, to))
+	// Only the moved item changed places relative to the others
+	if i, ok := q.ready.index[item.ID]; ok {
+		heap.Fix(q.ready, i)
+	}
+}
+
+// reindexFrom refreshes slice positions from i onwards after items moved
+func (q *Queue) reindexFrom(i int) {
+	for ; i < len(q.items); i++ {
+		q.positions[q.items[i].ID] = i
+	}
+}
+
+// setStatus changes an item's status and keeps the status buckets in sync
+func (q *Queue) setStatus(item *QueueItem, status QueueStatus) {
+	prev := item.Status
+	item.Status = status
+	q.statusChanged(item.ID, prev, status)
+}
+
+// statusChanged moves an item between status buckets after its Status field
+// was changed directly (e.g. by an updateItem callback)
+func (q *Queue) statusChanged(id string, prev, status QueueStatus) {
+	if prev == status {
+		return
+	}
+	q.removeFromBucket(id, prev)
+	q.addToBucket(id, status)
+}
+
+func (q *Queue) addToBucket(id string, status QueueStatus) {
+	bucket := q.byStatus[status]
+	if bucket == nil {
+		bucket = make(map[string]struct{})
+		q.byStatus[status] = bucket
+	}
+	bucket[id] = struct{}{}
+	if status == StatusPending {
+		q.ready.add(id)
+	} else if isFinished(status) {
+		q.release(id)
+	}
+	// A finished item may unblock or fail pending items that depend on it
+	if status == StatusPending || (isFinished(status) && len(q.byStatus[StatusPending]) > 0) {
+		q.signal()
+	}
+}
+
+func (q *Queue) removeFromBucket(id string, status QueueStatus) {
+	delete(q.byStatus[status], id)
+	if status == StatusPending {
+		q.ready.remove(id)
+	}
+}
+
+// park sets pending item id aside until item waitFor finishes, is removed or
+// (if it is id itself) its worker returns
+func (q *Queue) park(id, waitFor string) {
+	q.waiters[waitFor] = append(q.waiters[waitFor], id)
+}
+
+// release makes the items parked on id ready again if they are still pending
+func (q *Queue) release(id string) {
+	parked, ok := q.waiters[id]
+	if !ok {
+		return
+	}
+	delete(q.waiters, id)
+	for _, waiter := range parked {
+		if _, pending := q.byStatus[StatusPending][waiter]; pending {
+			q.ready.add(waiter)
+		}
+	}
+	q.signal()
+}
+
+// countStatus returns how many items have any of the given statuses
+func (q *Queue) countStatus(statuses ...QueueStatus) int {
+	count := 0
+	for _, status := range statuses {
+		count += len(q.byStatus[status])
+	}
+	return count
+}
+
+// idsWithStatus returns the IDs of items with the given status in queue order
+func (q *Queue) idsWithStatus(status QueueStatus) []string {
+	bucket := q.byStatus[status]
+	ids := make([]string, 0, len(bucket))
+	for id := range bucket {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return q.positions[ids[i]] < q.positions[ids[j]]
+	})
+	return ids
+}
+
+// pendingHeap holds pending IDs ordered by queue position. It implements
+// heap.Interface; use add, remove and pop.
+type pendingHeap struct {
+	ids       []string
+	index     map[string]int // ID -> index in ids
+	positions map[string]int // The queue's ID -> slice position index
+}
+
+func (h *pendingHeap) Len() int { return len(h.ids) }
+
+func (h *pendingHeap) Less(i, j int) bool {
+	return h.positions[h.ids[i]] < h.positions[h.ids[j]]
+}
+
+func (h *pendingHeap) Swap(i, j int) {
+	h.ids[i], h.ids[j] = h.ids[j], h.ids[i]
+	h.index[h.ids[i]] = i
+	h.index[h.ids[j]] = j
+}
+
+func (h *pendingHeap) Push(x any) {
+	id := x.(string)
+	h.index[id] = len(h.ids)
+	h.ids = append(h.ids, id)
+}
+
+func (h *pendingHeap) Pop() any {
+	id := h.ids[len(h.ids)-1]
+	h.ids = h.ids[:len(h.ids)-1]
+	delete(h.index, id)
+	return id
+}
+
+// add inserts id unless it is already in the heap
+func (h *pendingHeap) add(id string) {
+	if _, ok := h.index[id]; !ok {
+		heap.Push(h, id)
+	}
+}
+
+// remove drops id if it is in the heap
+func (h *pendingHeap) remove(id string) {
+	if i, ok := h.index[id]; ok {
+		heap.Remove(h, i)
+	}
+}
+
+// pop removes and returns the ID first in queue order, or "" if empty
+func (h *pendingHeap) pop() string {
+	if len(h.ids) == 0 {
+		return ""
+	}
+	return heap.Pop(h).(string)
+}
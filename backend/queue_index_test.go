@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkQueueIndex verifies positions and status buckets match q.items
+func checkQueueIndex(t *testing.T, q *Queue) {
+	t.Helper()
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if len(q.positions) != len(q.items) {
+		t.Fatalf("%d positions for %d items", len(q.positions), len(q.items))
+	}
+	bucketed := 0
+	for i, item := range q.items {
+		if q.positions[item.ID] != i {
+			t.Errorf("position of %s = %d, want %d", item.ID, q.positions[item.ID], i)
+		}
+		if _, ok := q.byStatus[item.Status][item.ID]; !ok {
+			t.Errorf("%s missing from %s bucket", item.ID, item.Status)
+		}
+	}
+	for _, bucket := range q.byStatus {
+		bucketed += len(bucket)
+	}
+	if bucketed != len(q.items) {
+		t.Errorf("%d bucketed IDs for %d items", bucketed, len(q.items))
+	}
+	for i, id := range q.ready.ids {
+		if _, ok := q.byStatus[StatusPending][id]; !ok {
+			t.Errorf("%s is ready but not pending", id)
+		}
+		if i > 0 && q.ready.Less(i, (i-1)/2) {
+			t.Errorf("ready heap out of order at %d", i)
+		}
+	}
+}
+
+func TestQueueIndex_StaysConsistent(t *testing.T) {
+	q := newTestQueue()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: fmt.Sprintf("Song %d", i), Artist: "Artist"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	checkQueueIndex(t, q)
+
+	if err := q.MoveItem(ids[4], 0); err != nil {
+		t.Fatal(err)
+	}
+	q.RemoveFromQueue(ids[2])
+	q.UpdateStatus(ids[0], StatusDownloadingAudio, 50, "")
+	q.SetItemError(ids[1], fmt.Errorf("failed"))
+	q.PauseItem(ids[3])
+	checkQueueIndex(t, q)
+
+	if got := q.GetPendingCount(); got != 1 {
+		t.Errorf("GetPendingCount = %d, want 1", got)
+	}
+	if got := q.GetActiveCount(); got != 1 {
+		t.Errorf("GetActiveCount = %d, want 1", got)
+	}
+	if failed := q.GetFailedItems(); len(failed) != 1 || failed[0].ID != ids[1] {
+		t.Errorf("GetFailedItems = %v", failed)
+	}
+
+	q.mutex.RLock()
+	pending := q.idsWithStatus(StatusPending)
+	q.mutex.RUnlock()
+	if len(pending) != 1 || pending[0] != ids[4] {
+		t.Errorf("pending = %v, want [%s]", pending, ids[4])
+	}
+
+	q.RetryFailed()
+	q.ResumeAll()
+	q.mutex.RLock()
+	pending = q.idsWithStatus(StatusPending)
+	q.mutex.RUnlock()
+	// Dispatch order follows queue order: ids[4] was moved to the front
+	if want := []string{ids[4], ids[1], ids[3]}; fmt.Sprint(pending) != fmt.Sprint(want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+
+	q.ClearCompleted()
+	q.ClearAll()
+	checkQueueIndex(t, q)
+}
+
+func TestQueueIndex_DispatchOrder(t *testing.T) {
+	q := newTestQueue()
+	var ids []string
+	for i := 0; i < 4; i++ {
+		id, err := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: fmt.Sprintf("Song %d", i), Artist: "Artist"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	// ids[0] waits for ids[3]; ids[2] moves to the front
+	if err := q.SetItemDependencies(ids[0], []string{ids[3]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.MoveItem(ids[2], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for id := q.nextPending(); id != ""; id = q.nextPending() {
+		got = append(got, id)
+	}
+	if want := []string{ids[2], ids[1], ids[3]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("dispatched %v, want %v", got, want)
+	}
+
+	// The parked item is ready once it no longer waits
+	if err := q.SetItemDependencies(ids[0], nil); err != nil {
+		t.Fatal(err)
+	}
+	if id := q.nextPending(); id != ids[0] {
+		t.Errorf("nextPending = %q, want the released item", id)
+	}
+	checkQueueIndex(t, q)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,8 +16,9 @@ import (
 
 // QueueState represents the serializable state of the queue
 type QueueState struct {
-	Items     []QueueItem `json:"items"`
-	UpdatedAt time.Time   `json:"updatedAt"`
+	SchemaVersion int         `json:"schemaVersion"` // See migrations.go
+	Items         []QueueItem `json:"items"`
+	UpdatedAt     time.Time   `json:"updatedAt"`
 }
 
 // GetQueueFilePath returns the path to the queue state file
@@ -26,25 +28,46 @@ func GetQueueFilePath() string {
 
 // SaveQueue persists the queue to disk
 func (q *Queue) SaveQueue() error {
-	q.mutex.RLock()
-	defer q.mutex.RUnlock()
+	return q.saveQueueFile(GetQueueFilePath())
+}
 
-	state := QueueState{
-		Items:     q.items,
-		UpdatedAt: time.Now(),
+// saveQueueFile writes the queue to path without ever leaving a partially
+// written file behind: the state goes to a temp file that is synced and then
+// renamed over path. The previous file is kept as path.bak. Saves are
+// serialized, so an older snapshot never lands after a newer one.
+func (q *Queue) saveQueueFile(queuePath string) error {
+	q.saveMutex.Lock()
+	defer q.saveMutex.Unlock()
+	if q.saveErr != nil {
+		return fmt.Errorf("queue not saved: %w", q.saveErr)
 	}
 
+	q.mutex.RLock()
+	state := QueueState{
+		SchemaVersion: queueSchema.Current,
+		Items:         q.items,
+		UpdatedAt:     time.Now(),
+	}
 	data, err := json.MarshalIndent(state, "", "  ")
+	q.mutex.RUnlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal queue: %w", err)
 	}
 
-	queuePath := GetQueueFilePath()
 	if err := os.MkdirAll(filepath.Dir(queuePath), 0755); err != nil {
 		return fmt.Errorf("failed to create queue directory: %w", err)
 	}
 
-	if err := os.WriteFile(queuePath, data, 0644); err != nil {
+	// Copy the current file into the backup, unless it is already damaged
+	// (that would overwrite the last good backup). Copying rather than
+	// renaming keeps path in place until the new state replaces it.
+	if current, err := os.ReadFile(queuePath); err == nil && json.Valid(current) {
+		if err := writeFileAtomic(queuePath+".bak", current, 0644); err != nil {
+			return fmt.Errorf("failed to back up queue file: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(queuePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write queue file: %w", err)
 	}
 
@@ -53,38 +76,83 @@ func (q *Queue) SaveQueue() error {
 
 // LoadQueue loads the queue from disk
 func (q *Queue) LoadQueue() error {
-	queuePath := GetQueueFilePath()
+	return q.loadQueueFile(GetQueueFilePath())
+}
 
-	data, err := os.ReadFile(queuePath)
+// loadQueueFile loads the queue from path, falling back to path.bak when the
+// file is missing or damaged. A file from a newer version is not loaded
+// and later saves fail instead of overwriting it.
+func (q *Queue) loadQueueFile(queuePath string) error {
+	if err := migrateForLoad(queuePath, queueSchema); err != nil {
+		q.saveMutex.Lock()
+		q.saveErr = err
+		q.saveMutex.Unlock()
+		return err
+	}
+	state, err := readQueueState(queuePath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No queue file, start fresh
+		backup, backupErr := readQueueState(queuePath + ".bak")
+		if backupErr != nil {
+			if os.IsNotExist(err) && os.IsNotExist(backupErr) {
+				return nil // No queue file, start fresh
+			}
+			return err
 		}
-		return fmt.Errorf("failed to read queue file: %w", err)
-	}
-
-	var state QueueState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return fmt.Errorf("failed to unmarshal queue: %w", err)
+		if !os.IsNotExist(err) {
+			slog.Warn("queue file damaged, recovered from backup", "path", queuePath, "err", err)
+		}
+		state = backup
 	}
 
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 
 	// Reset in-progress items to pending (they were interrupted)
+	var unplanned []string
 	for i := range state.Items {
 		switch state.Items[i].Status {
 		case StatusFetchingInfo, StatusDownloadingVideo, StatusDownloadingAudio, StatusMuxing, StatusOrganizing:
 			state.Items[i].Status = StatusPending
 			state.Items[i].Progress = 0
-			state.Items[i].Stage = "Waiting... (resumed)"
+			state.Items[i].setStage(StageWaitingResumed)
+		case StatusAwaitingApproval:
+			if state.Items[i].Plan == nil {
+				unplanned = append(unplanned, state.Items[i].ID)
+			}
 		}
 	}
 
-	q.items = state.Items
+	q.setItems(state.Items)
+	for _, id := range unplanned {
+		go q.planForApproval(id)
+	}
 	return nil
 }
 
+// readQueueState reads and validates one queue state file
+func readQueueState(path string) (*QueueState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read queue file: %w", err)
+	}
+
+	var state QueueState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue: %w", err)
+	}
+	seen := make(map[string]bool, len(state.Items))
+	for _, item := range state.Items {
+		if item.ID == "" || seen[item.ID] {
+			return nil, fmt.Errorf("invalid queue file: missing or duplicate item ID %q", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return &state, nil
+}
+
 // AutoSave starts periodic auto-saving of the queue
 func (q *Queue) AutoSave(interval time.Duration) {
 	go func() {
@@ -134,6 +202,42 @@ func copyFile(src, dst string) error {
 	return destFile.Sync()
 }
 
+// writeFileAtomic writes data to a temp file in path's directory, syncs it
+// and renames it over path, so readers see either the old or the new content
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
 // =============================================================================
 // Queue Statistics
 // =============================================================================
@@ -146,10 +250,27 @@ type QueueStats struct {
 	Completed int `json:"completed"`
 	Failed    int `json:"failed"`
 	Cancelled int `json:"cancelled"`
+
+	AwaitingApproval int `json:"awaitingApproval"`
+
+	// Predictions from recent History (omitted until there is history to learn from)
+	ETASeconds      float64            `json:"etaSeconds,omitempty"`      // Time until the queue is drained
+	EstimatedFinish *time.Time         `json:"estimatedFinish,omitempty"` // Wall-clock time the queue should finish
+	ItemEstimates   map[string]float64 `json:"itemEstimates,omitempty"`   // Predicted processing seconds per unfinished item
+	EstimateSamples int                `json:"estimateSamples,omitempty"` // Completed downloads the averages are based on
 }
 
 // GetStats returns queue statistics
 func (q *Queue) GetStats() QueueStats {
+	q.mutex.RLock()
+	history := q.history
+	q.mutex.RUnlock()
+
+	var model *ETAModel
+	if history != nil {
+		model = NewETAModel(history.GetRecent(etaSampleSize * 4))
+	}
+
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
@@ -169,8 +290,11 @@ func (q *Queue) GetStats() QueueStats {
 			stats.Failed++
 		case StatusCancelled:
 			stats.Cancelled++
+		case StatusAwaitingApproval:
+			stats.AwaitingApproval++
 		}
 	}
 
+	q.fillEstimates(&stats, model, time.Now())
 	return stats
 }
@@ -1,12 +1,18 @@
 package backend
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
+
+	"youflac/backend/format"
 )
 
 // processItem runs the full download pipeline for a single queue item.
@@ -17,23 +23,42 @@ func (q *Queue) processItem(id string) {
 
 	// Store cancel func
 	q.mutex.Lock()
-	for i := range q.items {
-		if q.items[i].ID == id {
-			// Skip if not pending
-			if q.items[i].Status != StatusPending {
-				q.mutex.Unlock()
-				cancel()
-				return
+	delete(q.dispatched, id)
+	if started := q.itemByID(id); started != nil {
+		// Skip if not pending, or while its last worker is still returning
+		if started.Status != StatusPending || started.running {
+			if started.Status == StatusPending {
+				q.park(id, id)
 			}
-			q.items[i].cancelFunc = cancel
-			q.items[i].Status = StatusFetchingInfo
-			q.items[i].StartedAt = time.Now()
-			q.items[i].Stage = "Fetching video info..."
-			break
+			q.mutex.Unlock()
+			cancel()
+			return
 		}
+		started.running = true
+		started.cancelFunc = cancel
+		q.setStatus(started, StatusFetchingInfo)
+		started.StartedAt = time.Now()
+		started.StageStartedAt = started.StartedAt
+		started.lastActivity = started.StartedAt
+		started.StageDurations = nil
+		started.Timeline = []StageRecord{{Status: StatusFetchingInfo, Detail: "Fetching video info...", StartedAt: started.StartedAt}}
+		started.SourcesTried = nil
+		started.AudioService = ""
+		started.AudioURL = ""
+		started.VideoFormat = ""
+		started.Explicit = false
+		started.Transfer = nil
+		started.AlbumID = ""
+		started.NeedsUpgrade = false
+		started.TrackNumber = 0
+		started.BytesDownloaded = 0
+		started.Warnings = nil
+		started.setStage(StageFetchingInfo)
 	}
 	q.mutex.Unlock()
 
+	// Runs last, once the temp directory is gone
+	defer q.finishRun(id)
 	defer cancel()
 
 	// Get item info
@@ -51,15 +76,20 @@ func (q *Queue) processItem(id string) {
 
 	// Load config
 	q.mutex.RLock()
-	config := q.config
+	config := q.configs.Get()
 	q.mutex.RUnlock()
 
 	if config == nil {
 		config = &defaultConfig
 	}
 
+	if item.DryRun || config.DryRun {
+		q.processDryRun(id, item, config)
+		return
+	}
+
 	// Create temp directory for this download
-	tempDir := filepath.Join(os.TempDir(), "youflac", id)
+	tempDir := filepath.Join(GetTempDirectory(), id)
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		q.SetItemError(id, fmt.Errorf("failed to create temp dir: %w", err))
 		return
@@ -75,7 +105,7 @@ func (q *Queue) processItem(id string) {
 
 	if item.Title == "" {
 		// Normal flow: fetch from YouTube
-		q.UpdateStatus(id, StatusFetchingInfo, 5, "Parsing URL...")
+		q.UpdateStage(id, StatusFetchingInfo, 5, StageParsingURL)
 
 		videoID, err = ParseYouTubeURL(item.VideoURL)
 		if err != nil {
@@ -115,6 +145,33 @@ func (q *Queue) processItem(id string) {
 		}
 	}
 
+	// Metadata rules rewrite the names once, before matching, naming,
+	// tagging, lyrics and NFO generation all read them
+	if rules := MetadataRulesFromConfig(config); len(rules) > 0 {
+		rewrite := RewriteMetadata(rules, videoInfo.Title, videoInfo.Artist, item.Album)
+		if len(rewrite.Applied) > 0 {
+			videoInfo.Title = rewrite.Title
+			videoInfo.Artist = rewrite.Artist
+			q.updateItem(id, func(item *QueueItem) {
+				item.Title = rewrite.Title
+				item.Artist = rewrite.Artist
+				item.Album = rewrite.Album
+			})
+			slog.Debug("metadata rules applied", "title", rewrite.Title, "artist", rewrite.Artist, "rules", len(rewrite.Applied))
+		}
+	}
+
+	// Duration limits (Shorts, loops); imports filter earlier, single adds here
+	if reason := DurationOutsideLimits(videoInfo.Duration, config); reason != "" {
+		if skipsOutOfRange(config) {
+			q.SetItemError(id, fmt.Errorf("skipped: video %s", reason))
+			return
+		}
+		q.updateItem(id, func(item *QueueItem) {
+			item.DurationFlag = reason
+		})
+	}
+
 	// ==========================================================================
 	// Stage 1.5: Check for Existing File (Skip Detection)
 	// ==========================================================================
@@ -129,28 +186,26 @@ func (q *Queue) processItem(id string) {
 	q.mutex.RUnlock()
 
 	if fileIndex != nil && videoInfo.Title != "" {
-		existingFile := fileIndex.FindMatch(videoInfo.Title, videoInfo.Artist)
+		existingFile := fileIndex.FindVideo(LibraryIndexScope(config, item), videoID, videoInfo.Title, videoInfo.Artist, videoInfo.Duration)
 		if existingFile != nil {
-			q.UpdateStatus(id, StatusOrganizing, 80, "Found existing file...")
-
-			// Determine target path
-			outputDir := config.OutputDirectory
-			if outputDir == "" {
-				outputDir = GetDefaultOutputDirectory()
-			}
+			q.UpdateStage(id, StatusOrganizing, 80, StageFoundExisting)
 
 			// Get current item for playlist info
 			item = q.GetItem(id)
-			if item != nil && item.PlaylistName != "" {
-				playlistFolder := SanitizeFileName(item.PlaylistName)
-				outputDir = filepath.Join(outputDir, playlistFolder)
-			}
 
 			muxMetadata := &Metadata{
-				Title:  videoInfo.Title,
-				Artist: videoInfo.Artist,
-				Track:  item.PlaylistPosition,
+				Title:      videoInfo.Title,
+				Artist:     videoInfo.Artist,
+				Track:      item.PlaylistPosition,
+				TrackTotal: item.TrackTotal,
+				Disc:       item.Disc,
+				Tags:       item.Tags,
+				YouTubeID:  videoID,
 			}
+			ApplyArtistCredit(muxMetadata, item.AlbumArtist, config)
+
+			// Determine target path (artist override, playlist subfolder)
+			outputDir := planOutputDir(item, muxMetadata, config)
 
 			// Use original file extension when copying
 			existingExt := filepath.Ext(existingFile.Path)
@@ -160,58 +215,63 @@ func (q *Queue) processItem(id string) {
 
 			var targetPath string
 			if item.PlaylistPosition > 0 {
-				targetPath = GeneratePlaylistFilePath(muxMetadata, outputDir, existingExt)
+				targetPath = GeneratePlaylistFilePath(muxMetadata, outputDir, existingExt, config.PlaylistLayout)
 			} else {
-				targetPath = GenerateFilePath(muxMetadata, config.NamingTemplate, outputDir, existingExt)
+				targetPath = GenerateFilePath(muxMetadata, itemNamingTemplate(item, config), outputDir, existingExt)
 			}
 
 			// Check if it's the same path (already in correct location)
 			if existingFile.Path == targetPath {
 				// Already in correct location, just mark complete
-				q.updateItem(id, func(item *QueueItem) {
+				if q.updateItem(id, func(item *QueueItem) {
 					item.Status = StatusComplete
 					item.Progress = 100
-					item.Stage = "Skipped (already exists)"
+					item.setStage(StageSkippedExisting)
 					item.OutputPath = existingFile.Path
 					item.CompletedAt = time.Now()
-				})
-				q.emit(QueueEvent{
-					Type:     "completed",
-					ItemID:   id,
-					Progress: 100,
-					Status:   StatusComplete,
-				})
+				}) {
+					q.emit(QueueEvent{
+						Type:     "completed",
+						ItemID:   id,
+						Progress: 100,
+						Status:   StatusComplete,
+					})
+				}
 				slog.Info("skipped, already exists", "path", existingFile.Path)
 				return
 			}
 
 			// Copy file to new location
-			q.UpdateStatus(id, StatusOrganizing, 90, "Copying existing file...")
+			q.UpdateStage(id, StatusOrganizing, 90, StageCopyingExisting)
+			MkdirOutput(filepath.Dir(targetPath))
 			if err := copyFile(existingFile.Path, targetPath); err == nil {
+				FinishOutputFile(targetPath)
 				// Update file index with new entry
 				fileIndex.AddEntry(FileIndexEntry{
 					Path:      targetPath,
 					Title:     videoInfo.Title,
 					Artist:    videoInfo.Artist,
 					Duration:  existingFile.Duration,
+					YouTubeID: cmp.Or(videoID, existingFile.YouTubeID),
 					Size:      existingFile.Size,
 					IndexedAt: time.Now(),
 				})
-				go fileIndex.Save()
+				fileIndex.ScheduleSave()
 
-				q.updateItem(id, func(item *QueueItem) {
+				if q.updateItem(id, func(item *QueueItem) {
 					item.Status = StatusComplete
 					item.Progress = 100
-					item.Stage = "Copied from existing"
+					item.setStage(StageCopiedExisting)
 					item.OutputPath = targetPath
 					item.CompletedAt = time.Now()
-				})
-				q.emit(QueueEvent{
-					Type:     "completed",
-					ItemID:   id,
-					Progress: 100,
-					Status:   StatusComplete,
-				})
+				}) {
+					q.emit(QueueEvent{
+						Type:     "completed",
+						ItemID:   id,
+						Progress: 100,
+						Status:   StatusComplete,
+					})
+				}
 				slog.Info("copied from existing", "src", existingFile.Path, "dst", targetPath)
 				return
 			}
@@ -232,27 +292,85 @@ func (q *Queue) processItem(id string) {
 	var videoPath string
 	audioOnly := false
 
-	// Download video from YouTube
-	q.UpdateStatus(id, StatusDownloadingVideo, 10, "Downloading video...")
+	// Per-item settings (e.g. a re-download from history) override the config
+	videoQuality := config.VideoQuality
+	if item.Quality != "" {
+		videoQuality = item.Quality
+	}
 
-	videoPath, err = DownloadVideo(videoID, config.VideoQuality, tempDir, config.CookiesBrowser)
-	if err != nil {
-		// Don't fail immediately - try audio-only fallback
-		slog.Warn("video download failed, trying audio-only fallback", "err", err)
-		q.UpdateStatus(id, StatusDownloadingAudio, 40, "Video unavailable, downloading audio only...")
+	if item.OutputMode == OutputModeAudio {
+		// Audio-only output requested: don't fetch the video at all
+		q.UpdateStage(id, StatusDownloadingAudio, 40, StageSkippingVideo)
 		audioOnly = true
-		videoPath = ""
-
 		q.updateItem(id, func(item *QueueItem) {
 			item.AudioOnly = true
 		})
 	} else {
-		q.UpdateStatus(id, StatusDownloadingVideo, 40, "Video downloaded")
-		slog.Debug("video downloaded", "path", videoPath)
+		// Download video from YouTube
+		q.UpdateStage(id, StatusDownloadingVideo, 10, StageDownloadingVideo)
+
+		var video *VideoDownload
+		video, err = DownloadVideoClip(itemCtx, videoID, videoQuality, tempDir, config.CookiesBrowser, item.Clip, q.transferProgress(id, 10, 40))
+		if err != nil && itemCtx.Err() == nil && config.AlternativeVideoMode != AlternativeVideoOff {
+			// Original upload removed/blocked - look for another upload of the same track
+			slog.Warn("video download failed, searching for alternative upload", "err", err)
+			q.UpdateStage(id, StatusDownloadingVideo, 15, StageSearchingAlternative)
+
+			alternatives, altErr := FindAlternativeVideos(videoInfo, videoID, config.CookiesBrowser)
+			if altErr != nil {
+				slog.Debug("alternative video search failed", "err", altErr)
+			} else if len(alternatives) > 0 {
+				q.updateItem(id, func(item *QueueItem) {
+					item.AlternativeVideos = alternatives
+				})
 
-		q.updateItem(id, func(item *QueueItem) {
-			item.VideoPath = videoPath
-		})
+				if config.AlternativeVideoMode == AlternativeVideoAuto {
+					alt := alternatives[0]
+					q.UpdateStage(id, StatusDownloadingVideo, 20, StageDownloadingAlternative)
+					altVideo, dlErr := DownloadVideoClip(itemCtx, alt.ID, videoQuality, tempDir, config.CookiesBrowser, item.Clip, q.transferProgress(id, 20, 40))
+					if dlErr == nil {
+						slog.Info("substituted alternative video", "original", videoID, "alternative", alt.ID)
+						video = altVideo
+						err = nil
+						q.updateItem(id, func(item *QueueItem) {
+							item.SubstitutedVideoURL = alt.URL
+						})
+						q.AddWarning(id, "original video unavailable, used alternative upload %s", alt.URL)
+					} else {
+						slog.Warn("alternative video download failed", "id", alt.ID, "err", dlErr)
+					}
+				}
+			}
+		}
+		if itemCtx.Err() != nil {
+			return // Cancelled, paused or stalled: yt-dlp was killed
+		}
+		if err != nil {
+			// Don't fail immediately - try audio-only fallback
+			slog.Warn("video download failed, trying audio-only fallback", "err", err)
+			q.UpdateStage(id, StatusDownloadingAudio, 40, StageVideoUnavailable)
+			audioOnly = true
+			videoPath = ""
+
+			q.updateItem(id, func(item *QueueItem) {
+				item.AudioOnly = true
+				item.Transfer = nil
+			})
+			q.AddWarning(id, "video unavailable, saving audio only: %v", err)
+		} else {
+			videoPath = video.Path
+			q.updateItem(id, func(item *QueueItem) {
+				item.Transfer = nil
+			})
+			q.UpdateStage(id, StatusDownloadingVideo, 40, StageVideoDownloaded)
+			slog.Debug("video downloaded", "path", videoPath, "format", video.FormatID)
+
+			q.updateItem(id, func(item *QueueItem) {
+				item.VideoPath = videoPath
+				item.VideoFormat = video.FormatID
+				item.BytesDownloaded += pathSize(videoPath)
+			})
+		}
 	}
 
 	// ==========================================================================
@@ -264,168 +382,135 @@ func (q *Queue) processItem(id string) {
 	default:
 	}
 
-	q.UpdateStatus(id, StatusDownloadingAudio, 40, "Finding audio match...")
+	q.UpdateStage(id, StatusDownloadingAudio, 40, StageFindingAudio)
 
 	audioPath := ""
 
 	// Create metadata for NFO
 	metadata := &Metadata{
-		Title:    videoInfo.Title,
-		Artist:   videoInfo.Artist,
-		Duration: videoInfo.Duration,
+		Title:     videoInfo.Title,
+		Artist:    videoInfo.Artist,
+		Duration:  videoInfo.Duration,
+		Tags:      item.Tags,
+		YouTubeID: videoID,
 	}
 
 	// Try to find and download FLAC audio using multi-service cascade
-	audioDownloaded := false
-
-	// Initialize download services with shared HTTP client (proxy + timeout from config)
-	timeoutMinutes := config.DownloadTimeoutMinutes
-	if timeoutMinutes <= 0 {
-		timeoutMinutes = 10
+	cascade := NewAudioCascade(config, tempDir)
+	cascade.OnStage = func(progress int, code StageCode, params ...string) {
+		q.UpdateStage(id, StatusDownloadingAudio, progress, code, params...)
 	}
-	downloadTimeout := time.Duration(timeoutMinutes * float64(time.Minute))
-	httpClient, err := NewHTTPClient(downloadTimeout, config.ProxyURL)
-	if err != nil {
-		slog.Warn("failed to create HTTP client with proxy, falling back to default", "err", err)
-		httpClient, _ = NewHTTPClient(downloadTimeout, "")
-	}
-	tidalHifiService := NewTidalHifiService(httpClient)
-	lucidaService := NewLucidaService(httpClient)
-	orpheusService := NewOrpheusDLService()
-
-	// Diagnostics tracking
-	var sourcesTried []string
-	var songlinkCandidates []AudioCandidate
-
-	// Get audio links via songlink
-	if item.SpotifyURL != "" || item.VideoURL != "" {
-		q.UpdateStatus(id, StatusDownloadingAudio, 45, "Resolving audio sources...")
-		slog.Debug("resolving audio sources", "url", item.VideoURL)
-
-		sourceURL := item.VideoURL
-		if item.SpotifyURL != "" {
-			sourceURL = item.SpotifyURL
-		}
-
-		slog.Debug("calling ResolveMusicURL", "url", sourceURL)
-		sourcesTried = append(sourcesTried, "song.link")
-		links, err := ResolveMusicURL(sourceURL)
-		slog.Debug("ResolveMusicURL result", "err", err, "hasLinks", links != nil)
-		if err == nil && links != nil {
-			// Build candidates for diagnostics
-			songlinkCandidates = buildCandidatesFromSongLink(links)
-
-			// Try each audio source in priority order
-			for _, source := range config.AudioSourcePriority {
-				select {
-				case <-itemCtx.Done():
-					return
-				default:
-				}
-
-				var downloadURL string
-				switch source {
-				case "tidal":
-					downloadURL = links.URLs.TidalURL
-				case "qobuz":
-					downloadURL = links.URLs.QobuzURL
-				case "amazon":
-					downloadURL = links.URLs.AmazonURL
-				case "deezer":
-					downloadURL = links.URLs.DeezerURL
-				}
 
-				if downloadURL == "" {
-					continue
-				}
+	// Per-item source order overrides the global priority; Tidal search is
+	// skipped when the item explicitly restricts sources and excludes Tidal
+	if len(item.AudioSourcePriority) > 0 {
+		cascade.Sources = item.AudioSourcePriority
+		cascade.TidalSearch = slices.Contains(item.AudioSourcePriority, "tidal")
+		slog.Debug("using per-item audio source priority", "sources", cascade.Sources)
+	}
 
-				slog.Debug("trying audio source", "source", source, "url", downloadURL)
-				q.UpdateStatus(id, StatusDownloadingAudio, 50, fmt.Sprintf("Downloading from %s...", source))
-				sourcesTried = append(sourcesTried, source)
-
-				// Service cascade for FLAC download
-				var result *AudioDownloadResult
-				var downloadErr error
-
-				// 1. Try TidalHifiService FIRST for Tidal URLs (vogel.qqdl.site - works!)
-				if source == "tidal" && tidalHifiService.IsAvailable() {
-					slog.Debug("trying TidalHifi API", "source", source)
-					q.UpdateStatus(id, StatusDownloadingAudio, 51, "Downloading FLAC from Tidal...")
-					result, downloadErr = tidalHifiService.Download(downloadURL, tempDir, "flac")
-					if downloadErr != nil {
-						slog.Debug("TidalHifi failed", "err", downloadErr)
-					}
-				}
+	sourceURL := item.VideoURL
+	if item.SpotifyURL != "" {
+		sourceURL = item.SpotifyURL
+	}
+	cascade.OnProgress = q.transferProgress(id, 50, 65)
+	audio := cascade.Run(itemCtx, sourceURL, videoInfo.Artist, videoInfo.Title)
+	q.updateItem(id, func(item *QueueItem) {
+		item.Transfer = nil
+	})
+	if itemCtx.Err() != nil {
+		return
+	}
 
-				// 2. Try Lucida (web API) if TidalHifi failed or not Tidal
-				if result == nil {
-					slog.Debug("trying Lucida", "source", source)
-					result, downloadErr = lucidaService.Download(downloadURL, tempDir, "flac")
-					if downloadErr != nil {
-						slog.Debug("Lucida failed", "err", downloadErr)
-					}
-				}
+	// ISRC of the downloaded recording, if any service reported it
+	trackISRC := audio.ISRC
+	// Tidal track the audio came from (used to look for a surround mix)
+	tidalTrackURL := audio.TidalTrackURL
+	tidalHifiService := cascade.TidalService()
 
-				// 3. Try OrpheusDL/Streamrip (Python subprocess) as last resort
-				if result == nil && orpheusService.IsAvailable() {
-					slog.Debug("trying OrpheusDL/Streamrip", "source", source)
-					q.UpdateStatus(id, StatusDownloadingAudio, 52, fmt.Sprintf("Trying OrpheusDL for %s...", source))
-					result, downloadErr = orpheusService.Download(downloadURL, tempDir, "flac")
-					if downloadErr != nil {
-						slog.Debug("OrpheusDL failed", "err", downloadErr)
-					}
-				}
+	// Diagnostics tracking
+	sourcesTried := audio.SourcesTried
+	songlinkCandidates := audio.Candidates
 
-				// Success!
-				if result != nil {
-					actualQuality := result.Track.Quality
-					slog.Info("FLAC downloaded", "source", source, "path", result.FilePath, "quality", actualQuality)
-					audioDownloaded = true
-					audioPath = result.FilePath
-					if actualQuality != "" && isQualityDowngrade(config.PreferredQuality, actualQuality) {
-						slog.Warn("quality downgraded", "requested", config.PreferredQuality, "actual", actualQuality, "source", source)
-					}
-					q.updateItem(id, func(item *QueueItem) {
-						item.AudioSource = source
-						item.AudioPath = audioPath
-						item.ActualQuality = actualQuality
-					})
-					break
-				}
+	audioDownloaded := audio.Audio != nil
+	if audioDownloaded {
+		audioPath = audio.Audio.FilePath
+		q.updateItem(id, func(item *QueueItem) {
+			item.AudioSource = audio.Source
+			item.AudioService = audio.Service
+			item.AudioURL = audio.TrackURL
+			item.AudioPath = audioPath
+			if audio.Source != "tidal-search" && audio.Audio.Track != nil {
+				item.ActualQuality = audio.Audio.Track.Quality
 			}
+			item.Explicit = audio.Audio.Track != nil && audio.Audio.Track.Explicit
+			item.BytesDownloaded += pathSize(audioPath)
+		})
+		if track := audio.Audio.Track; track != nil && track.Quality != "" && isQualityDowngrade(config.PreferredQuality, track.Quality) {
+			q.AddWarning(id, "quality downgraded from %s to %s", config.PreferredQuality, track.Quality)
+		}
+		// Playlist items are numbered by their playlist, singles by their album
+		if track := audio.Audio.Track; track != nil && item.PlaylistPosition == 0 {
+			q.recordAlbum(id, track, config)
 		}
 	}
 
-	// If songlink resolution failed or no FLAC sources found, try TidalHifi search
-	if !audioDownloaded && videoInfo.Artist != "" && videoInfo.Title != "" {
-		slog.Debug("trying TidalHifi search", "artist", videoInfo.Artist, "title", videoInfo.Title)
-		q.UpdateStatus(id, StatusDownloadingAudio, 55, "Searching Tidal for track...")
-		sourcesTried = append(sourcesTried, "tidal_search")
+	q.updateItem(id, func(item *QueueItem) {
+		item.SourcesTried = sourcesTried
+	})
 
-		if tidalHifiService.IsAvailable() {
-			result, err := tidalHifiService.DownloadBySearch(videoInfo.Artist, videoInfo.Title, tempDir)
-			if err == nil && result != nil {
-				slog.Info("FLAC found via Tidal search", "path", result.FilePath)
-				audioDownloaded = true
-				audioPath = result.FilePath
-				q.updateItem(id, func(item *QueueItem) {
-					item.AudioSource = "tidal-search"
-					item.AudioPath = audioPath
-				})
-			} else {
-				slog.Warn("Tidal search failed", "err", err)
-			}
+	// No lossless source: Config.FallbackPolicy decides between lossy audio
+	// and failing the item
+	fallbackPolicy := cmp.Or(config.FallbackPolicy, FallbackYouTubeMusic)
+	if !audioDownloaded && fallbackPolicy == FallbackFail {
+		diag := &MatchDiagnostics{
+			SourcesTried:  sourcesTried,
+			FailureReason: "no_lossless_source",
 		}
+		q.updateItem(id, func(item *QueueItem) {
+			item.MatchCandidates = songlinkCandidates
+			item.MatchDiagnostics = diag
+		})
+		q.SetItemError(id, fmt.Errorf("no lossless source found (tried %s) and the fallback policy is %q", strings.Join(sourcesTried, ", "), fallbackPolicy))
+		return
 	}
 
+	// Lossy fallback: YouTube Music's audio-only stream beats the video's AAC
+	if !audioDownloaded && videoID != "" && fallbackTriesYouTubeMusic(fallbackPolicy) {
+		q.UpdateStage(id, StatusDownloadingAudio, 50, StageDownloadingYTMusic)
+		ytAudio, err := DownloadYouTubeMusicAudio(itemCtx, videoID, tempDir, config.CookiesBrowser, q.transferProgress(id, 50, 55))
+		q.updateItem(id, func(item *QueueItem) {
+			item.Transfer = nil
+		})
+		if itemCtx.Err() != nil {
+			return // Cancelled, paused or stalled: yt-dlp was killed
+		}
+		if err != nil {
+			slog.Warn("YouTube Music audio download failed", "video", videoID, "err", err)
+		} else {
+			audioDownloaded = true
+			audioPath = ytAudio.Path
+			q.updateItem(id, func(item *QueueItem) {
+				item.AudioSource = YouTubeAudioSource
+				item.AudioService = "yt-dlp"
+				item.AudioURL = ytAudio.URL
+				item.AudioPath = audioPath
+				item.ActualQuality = ytAudio.Quality()
+				item.BytesDownloaded += pathSize(audioPath)
+			})
+			q.AddWarning(id, "no lossless source found (tried %s), used lossy YouTube Music audio (%s)", strings.Join(sourcesTried, ", "), ytAudio.Quality())
+		}
+	}
+
+	audioExtracted := false
 	if !audioDownloaded {
 		// Fallback: extract audio from video (only if video exists)
 		if videoPath != "" {
-			q.UpdateStatus(id, StatusDownloadingAudio, 55, "Extracting audio from video...")
+			q.UpdateStage(id, StatusDownloadingAudio, 55, StageExtractingAudio)
 			// Use .mka (Matroska audio) which supports any codec (opus, aac, etc.)
 			audioPath = filepath.Join(tempDir, "audio.mka")
 
-			err = ExtractAudioFromVideo(videoPath, audioPath)
+			err = ExtractAudioFromVideo(itemCtx, videoPath, audioPath)
 			if err != nil {
 				// Populate diagnostics before setting error
 				diag := &MatchDiagnostics{
@@ -440,10 +525,13 @@ func (q *Queue) processItem(id string) {
 				return
 			}
 
+			audioExtracted = true
 			q.updateItem(id, func(item *QueueItem) {
 				item.AudioSource = "extracted"
+				item.AudioService = "ffmpeg"
 				item.AudioPath = audioPath
 			})
+			q.AddWarning(id, "no lossless source found (tried %s), audio extracted from the video", strings.Join(sourcesTried, ", "))
 		} else {
 			// Audio-only mode but no audio was downloaded from services — populate diagnostics
 			diag := &MatchDiagnostics{
@@ -459,6 +547,53 @@ func (q *Queue) processItem(id string) {
 		}
 	}
 
+	// A clip needs the same section of the audio; audio extracted from the
+	// clipped video has it already
+	if clip := item.Clip; clip != nil && !audioExtracted {
+		q.UpdateStage(id, StatusDownloadingAudio, 65, StageTrimmingClip, "clip", clip.String())
+		clipPath := filepath.Join(tempDir, "audio-clip.flac")
+		if err := TrimAudioToClip(itemCtx, audioPath, clipPath, clip); err != nil {
+			q.SetItemError(id, fmt.Errorf("failed to trim audio to the clip: %w", err))
+			return
+		}
+		audioPath = clipPath
+		q.updateItem(id, func(item *QueueItem) {
+			item.AudioPath = audioPath
+		})
+	}
+
+	if audio.Audio == nil && fallbackPolicy == FallbackUpgrade {
+		q.updateItem(id, func(item *QueueItem) {
+			item.NeedsUpgrade = true
+		})
+	}
+
+	// Optional surround mix (Dolby Atmos / 360RA) from the same Tidal track,
+	// not for clips
+	var surroundPath string
+	if tidalTrackURL != "" && !audioOnly && item.Clip == nil && config.SurroundMode != "" && config.SurroundMode != SurroundOff {
+		tidalHifiService.SetDownloadContext(itemCtx, q.transferProgress(id, 65, 70))
+		surround, err := tidalHifiService.DownloadSurround(tidalTrackURL, tempDir)
+		q.updateItem(id, func(item *QueueItem) {
+			item.Transfer = nil
+		})
+		if err != nil {
+			slog.Info("no surround mix", "url", tidalTrackURL, "error", err)
+		} else if config.SurroundMode == SurroundPrefer {
+			slog.Info("using surround mix", "path", surround.FilePath, "quality", surround.Track.Quality)
+			q.updateItem(id, func(item *QueueItem) {
+				item.AudioPath = surround.FilePath
+				item.ActualQuality = surround.Track.Quality
+				item.BytesDownloaded += pathSize(surround.FilePath)
+			})
+		} else {
+			surroundPath = surround.FilePath
+			q.updateItem(id, func(item *QueueItem) {
+				item.BytesDownloaded += pathSize(surroundPath)
+			})
+		}
+	}
+
 	// ==========================================================================
 	// Stage 4: Mux Video + Audio
 	// ==========================================================================
@@ -468,33 +603,60 @@ func (q *Queue) processItem(id string) {
 	default:
 	}
 
-	q.UpdateStatus(id, StatusMuxing, 70, "Muxing video and audio...")
-
-	// Determine output path
-	outputDir := config.OutputDirectory
-	if outputDir == "" {
-		outputDir = GetDefaultOutputDirectory()
-	}
+	q.UpdateStage(id, StatusMuxing, 70, StageMuxing)
 
 	// Get current item for updated paths
 	item = q.GetItem(id)
 
-	// If item is part of a playlist, create playlist subfolder
-	if item.PlaylistName != "" {
-		playlistFolder := SanitizeFileName(item.PlaylistName)
-		outputDir = filepath.Join(outputDir, playlistFolder)
-	}
-
 	// Create metadata for muxing
 	muxMetadata := &Metadata{
-		Title:     videoInfo.Title,
-		Artist:    videoInfo.Artist,
-		Album:     item.Album,
-		Thumbnail: videoInfo.Thumbnail,
-		Duration:  videoInfo.Duration,
-		Track:     item.PlaylistPosition, // Use playlist position as track number
+		Title:      videoInfo.Title,
+		Artist:     videoInfo.Artist,
+		Album:      item.Album,
+		Thumbnail:  videoInfo.Thumbnail,
+		Duration:   videoInfo.Duration,
+		ISRC:       trackISRC,
+		Track:      cmp.Or(item.PlaylistPosition, item.TrackNumber), // Playlist position, else album track
+		TrackTotal: item.TrackTotal,
+		Disc:       item.Disc,
+		Tags:       item.Tags,
+		Explicit:   item.Explicit,
+		AlbumID:    item.AlbumID,
+		YouTubeID:  videoID,
+	}
+	if item.Clip != nil {
+		muxMetadata.Duration = item.Clip.Length(videoInfo.Duration)
+		metadata.Duration = muxMetadata.Duration
+	}
+	// Release date of the downloaded track: DATE tag, {year} and <premiered>
+	if audio.Audio != nil && audio.Audio.Track != nil {
+		if date, year := ParseReleaseDate(audio.Audio.Track.ReleaseDate); date != "" {
+			muxMetadata.ReleaseDate, muxMetadata.Year = date, year
+			metadata.ReleaseDate, metadata.Year = date, year
+		}
+	}
+	metadata.ISRC = trackISRC
+	metadata.Explicit = item.Explicit
+	metadata.Album = item.Album
+	metadata.AlbumID = item.AlbumID
+	ApplyArtistCredit(muxMetadata, item.AlbumArtist, config)
+	ApplyArtistCredit(metadata, item.AlbumArtist, config)
+
+	// Genre from community tags (used by tags, NFO and the {genre} placeholder)
+	if config.GenreEnrichment {
+		genre, err := NewGenreService(config.LastFMAPIKey).LookupGenre(videoInfo.Artist, videoInfo.Title)
+		if err != nil {
+			q.AddWarning(id, "genre lookup failed: %v", err)
+		} else if genre != "" {
+			muxMetadata.Genre = genre
+			metadata.Genre = genre
+		}
 	}
 
+	// Output directory: per-artist override or OutputDirectory, plus the
+	// playlist subfolder for playlist items
+	outputDir := planOutputDir(item, muxMetadata, config)
+
 	// Generate output path using naming template
 	// Use .flac extension for audio-only, .mkv for video+audio
 	outputExt := ".mkv"
@@ -504,15 +666,15 @@ func (q *Queue) processItem(id string) {
 
 	var outputPath string
 	if item.PlaylistPosition > 0 {
-		// Playlist item: use track number prefix format "01 - Artist - Title"
-		outputPath = GeneratePlaylistFilePath(muxMetadata, outputDir, outputExt)
+		// Playlist item: use track number prefix format "01 - Artist - Title", nested or flat per config
+		outputPath = GeneratePlaylistFilePath(muxMetadata, outputDir, outputExt, config.PlaylistLayout)
 	} else {
 		// Regular item: use configured naming template
-		outputPath = GenerateFilePath(muxMetadata, config.NamingTemplate, outputDir, outputExt)
+		outputPath = GenerateFilePath(muxMetadata, itemNamingTemplate(item, config), outputDir, outputExt)
 	}
 
 	// Ensure output directory exists
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+	if err := MkdirOutput(filepath.Dir(outputPath)); err != nil {
 		q.SetItemError(id, fmt.Errorf("failed to create output directory: %w", err))
 		return
 	}
@@ -522,32 +684,106 @@ func (q *Queue) processItem(id string) {
 		outputPath = ResolveConflict(outputPath)
 	}
 
-	// Download cover if embedding
+	// Thumbnail and lyrics don't depend on the output file, so they are
+	// fetched while the file is muxed. Every asset is optional: a failure
+	// leaves a warning on the item instead of failing it.
+	assets := newAssetGroup(maxAssetFetches)
+	defer assets.Wait() // Error returns must not remove tempDir under a running job
+
+	var thumbnailPath string
+	thumbnailDone := closedChan()
+	if videoInfo.Thumbnail != "" {
+		thumbnailDone = assets.Go("thumbnail", func() error {
+			path := filepath.Join(tempDir, "cover.jpg")
+			if err := DownloadPoster(videoInfo.Thumbnail, path); err != nil {
+				return err
+			}
+			thumbnailPath = path
+			return nil
+		})
+	}
+
+	var lyrics *LyricsResult
+	var lyricsDone <-chan struct{}
+	if config.LyricsEnabled && videoInfo.Artist != "" && videoInfo.Title != "" {
+		lyricsDone = assets.Go("lyrics", func() error {
+			found, err := FetchLyrics(videoInfo.Artist, videoInfo.Title)
+			if err != nil {
+				return fmt.Errorf("no lyrics found: %w", err)
+			}
+			lyrics = found
+			return nil
+		})
+	}
+
+	// The cover is embedded by the mux
+	<-thumbnailDone
 	var coverPath string
-	if config.EmbedCoverArt && videoInfo.Thumbnail != "" {
-		coverPath = filepath.Join(tempDir, "cover.jpg")
-		if err := DownloadPoster(videoInfo.Thumbnail, coverPath); err != nil {
-			coverPath = "" // Failed to download, proceed without cover
-		}
+	if config.EmbedCoverArt {
+		coverPath = thumbnailPath
 	}
 
 	var result *MuxResult
 	if audioOnly {
 		// Audio-only fallback: create FLAC file
-		q.UpdateStatus(id, StatusMuxing, 80, "Creating FLAC file...")
-		result, err = CreateFLACWithMetadata(item.AudioPath, outputPath, muxMetadata, coverPath)
+		q.UpdateStage(id, StatusMuxing, 80, StageCreatingFLAC)
+		result, err = CreateFLACWithMetadata(itemCtx, item.AudioPath, outputPath, muxMetadata, coverPath)
 		if err != nil {
 			q.SetItemError(id, fmt.Errorf("failed to create FLAC: %w", err))
 			return
 		}
 	} else {
 		// Normal case: mux video + audio into MKV
-		q.UpdateStatus(id, StatusMuxing, 80, "Creating MKV file...")
-		result, err = MuxVideoWithFLAC(item.VideoPath, item.AudioPath, outputPath, muxMetadata, coverPath, nil)
+		q.UpdateStage(id, StatusMuxing, 80, StageCreatingMKV)
+		muxOpts := muxOptionsFromConfig(config)
+		muxOpts.SurroundAudioPath = surroundPath
+
+		// Album and video edits of different length (Config.LengthMode)
+		videoInput, audioInput := item.VideoPath, item.AudioPath
+		var chapters []Chapter
+		if rec, err := ReconcileLength(itemCtx, videoInput, audioInput, tempDir, cmp.Or(item.LengthMode, config.LengthMode)); err != nil {
+			q.AddWarning(id, "audio/video length reconciliation failed, muxing as is: %v", err)
+		} else if rec.Action != "" {
+			videoInput, audioInput, chapters = rec.VideoPath, rec.AudioPath, rec.Chapters
+			q.AddWarning(id, "audio is %s, video is %s: %s", format.Duration(rec.AudioDuration), format.Duration(rec.VideoDuration), rec.Action)
+		}
+
+		result, err = MuxVideoWithFLACOptions(itemCtx, videoInput, audioInput, outputPath, muxMetadata, coverPath, muxOpts, nil)
 		if err != nil {
 			q.SetItemError(id, fmt.Errorf("failed to mux: %w", err))
 			return
 		}
+		if len(chapters) > 0 {
+			if err := AddChapters(result.OutputPath, chapters); err != nil {
+				q.AddWarning(id, "failed to add chapters: %v", err)
+			} else {
+				result.HasChapters = true
+			}
+		}
+		if result.Sync != (SyncAdjustment{}) {
+			sync := result.Sync
+			q.updateItem(id, func(item *QueueItem) {
+				item.SyncAdjustment = &sync
+			})
+		}
+		if result.Sync.Skipped > 0 {
+			q.AddWarning(id, "silence trim skipped, %.2fs of excess leading silence kept", result.Sync.Skipped)
+		}
+		if result.Sync.TrimError != "" {
+			q.AddWarning(id, "silence trim failed, audio may lag the video: %s", result.Sync.TrimError)
+		}
+
+		if config.SyncPreview {
+			q.UpdateStage(id, StatusMuxing, 82, StageCreatingPreview)
+			previewPath := previewPathFor(id)
+			if _, err := GenerateSyncPreview(result.OutputPath, previewPath); err != nil {
+				q.AddWarning(id, "sync preview not created: %v", err)
+			} else {
+				q.updateItem(id, func(item *QueueItem) {
+					item.PreviewPath = previewPath
+				})
+			}
+		}
 	}
 
 	// ==========================================================================
@@ -559,57 +795,14 @@ func (q *Queue) processItem(id string) {
 	default:
 	}
 
-	if config.LyricsEnabled && videoInfo.Artist != "" && videoInfo.Title != "" {
-		q.UpdateStatus(id, StatusOrganizing, 85, "Fetching lyrics...")
-
-		lyrics, lyricsErr := FetchLyrics(videoInfo.Artist, videoInfo.Title)
-		if lyricsErr == nil && lyrics != nil {
-			embedMode := LyricsEmbedMode(config.LyricsEmbedMode)
-			if embedMode == "" {
-				embedMode = LyricsEmbedLRC // Default to LRC file
+	if lyricsDone != nil {
+		q.UpdateStage(id, StatusOrganizing, 85, StageFetchingLyrics)
+		<-lyricsDone
+		if lyrics != nil {
+			// Runs before the NFO job, which reads the rewritten file
+			if err := writeLyrics(result.OutputPath, lyrics, LyricsEmbedMode(config.LyricsEmbedMode)); err != nil {
+				q.AddWarning(id, "lyrics: %v", err)
 			}
-
-			switch embedMode {
-			case LyricsEmbedFile:
-				if err := EmbedLyricsInFile(result.OutputPath, lyrics); err != nil {
-					slog.Warn("failed to embed lyrics", "err", err)
-				} else {
-					slog.Debug("lyrics embedded in file")
-				}
-			case LyricsEmbedLRC:
-				if lyrics.HasSync {
-					if lrcPath, err := SaveLRCFile(lyrics, result.OutputPath); err != nil {
-						slog.Warn("failed to save LRC file", "err", err)
-					} else {
-						slog.Debug("LRC file saved", "path", lrcPath)
-					}
-				} else if lyrics.PlainText != "" {
-					if txtPath, err := SavePlainLyricsFile(lyrics, result.OutputPath); err != nil {
-						slog.Warn("failed to save lyrics file", "err", err)
-					} else {
-						slog.Debug("lyrics file saved", "path", txtPath)
-					}
-				}
-			case LyricsEmbedBoth:
-				// Save LRC/TXT file
-				if lyrics.HasSync {
-					if lrcPath, err := SaveLRCFile(lyrics, result.OutputPath); err == nil {
-						slog.Debug("LRC file saved", "path", lrcPath)
-					}
-				} else if lyrics.PlainText != "" {
-					if txtPath, err := SavePlainLyricsFile(lyrics, result.OutputPath); err == nil {
-						slog.Debug("lyrics file saved", "path", txtPath)
-					}
-				}
-				// Also embed in file
-				if err := EmbedLyricsInFile(result.OutputPath, lyrics); err != nil {
-					slog.Warn("failed to embed lyrics", "err", err)
-				} else {
-					slog.Debug("lyrics embedded in file")
-				}
-			}
-		} else if lyricsErr != nil {
-			slog.Debug("lyrics not found", "err", lyricsErr)
 		}
 	}
 
@@ -622,30 +815,69 @@ func (q *Queue) processItem(id string) {
 	default:
 	}
 
-	q.UpdateStatus(id, StatusOrganizing, 90, "Organizing files...")
+	q.UpdateStage(id, StatusOrganizing, 90, StageOrganizing)
 
 	// Generate NFO if enabled
 	if config.GenerateNFO {
-		nfoPath := outputPath[:len(outputPath)-4] + ".nfo"
-		nfoOpts := &NFOOptions{
-			IncludeFileInfo: true,
-		}
+		assets.Go("nfo", func() error {
+			nfoOpts := &NFOOptions{
+				IncludeFileInfo: true,
+				Fields:          config.NFOFields,
+			}
 
-		// Get file info for NFO
-		if mediaInfo, err := GetMediaInfo(result.OutputPath); err == nil {
-			nfoOpts.MediaInfo = mediaInfo
-		}
+			// Get file info for NFO
+			if mediaInfo, err := GetMediaInfo(result.OutputPath); err == nil {
+				nfoOpts.MediaInfo = mediaInfo
+			}
+
+			return WriteNFO(metadata, mediaBase(outputPath)+".nfo", nfoOpts)
+		})
+	}
 
-		if err := WriteNFO(metadata, nfoPath, nfoOpts); err != nil {
-			// Non-fatal, just log
-			slog.Warn("failed to write NFO", "err", err)
+	// Templates with an album folder share the album cover at the album
+	// level; otherwise a poster goes alongside the media file, from the
+	// thumbnail downloaded for the cover
+	var albumDir, albumCoverURL string
+	if item.PlaylistPosition == 0 && muxMetadata.Album != "" {
+		albumDir = albumDirectory(itemNamingTemplate(item, config), outputPath)
+	}
+	if audio.Audio != nil && audio.Audio.Track != nil {
+		albumCoverURL = audio.Audio.Track.CoverURL
+	}
+	if albumDir != "" && (albumCoverURL != "" || fileExists(filepath.Join(albumDir, AlbumFolderArt))) {
+		assets.Go("album art", func() error {
+			return WriteAlbumArt(albumCoverURL, albumDir, filepath.Dir(outputPath))
+		})
+	} else if thumbnailPath != "" {
+		assets.Go("poster", func() error {
+			return copyFile(thumbnailPath, mediaBase(outputPath)+"-poster.jpg")
+		})
+	}
+
+	for _, w := range assets.Wait() {
+		q.AddWarning(id, "%s: %s", w.Asset, w.Message)
+	}
+
+	// Provenance sidecar, before the upload so remote copies carry it
+	if config.ProvenanceSidecar {
+		if err := WriteProvenance(q.GetItem(id), muxMetadata, result.OutputPath); err != nil {
+			q.AddWarning(id, "provenance: %v", err)
 		}
 	}
 
-	// Download poster alongside MKV
-	if videoInfo.Thumbnail != "" {
-		posterPath := outputPath[:len(outputPath)-4] + "-poster.jpg"
-		DownloadPoster(videoInfo.Thumbnail, posterPath) // Ignore error, non-fatal
+	// Library permissions and owner (mux output and lyrics rewrites use the process defaults)
+	for _, file := range outputFiles(result.OutputPath) {
+		FinishOutputFile(file)
+	}
+
+	// Upload to remote storage; failures keep the local files and don't fail the item
+	var upload StorageUpload
+	if len(config.StorageTargets) > 0 {
+		q.UpdateStage(id, StatusOrganizing, 95, StageUploading)
+		upload = UploadOutput(config, libraryRootFor(config, result.OutputPath), result.OutputPath)
+		if upload.Err != nil {
+			q.AddWarning(id, "upload failed, files kept locally: %v", upload.Err)
+		}
 	}
 
 	// ==========================================================================
@@ -656,7 +888,7 @@ func (q *Queue) processItem(id string) {
 	q.mutex.RLock()
 	fi := q.fileIndex
 	q.mutex.RUnlock()
-	if fi != nil && videoInfo != nil {
+	if fi != nil && videoInfo != nil && !upload.Moved {
 		stat, _ := os.Stat(result.OutputPath)
 		var fileSize int64
 		if stat != nil {
@@ -666,27 +898,54 @@ func (q *Queue) processItem(id string) {
 			Path:      result.OutputPath,
 			Title:     videoInfo.Title,
 			Artist:    videoInfo.Artist,
+			Album:     item.Album,
+			AlbumID:   item.AlbumID,
 			Duration:  videoInfo.Duration,
+			ISRC:      trackISRC,
+			YouTubeID: videoID,
 			Size:      fileSize,
 			IndexedAt: time.Now(),
 		})
-		go fi.Save()
+		fi.ScheduleSave()
+	}
+
+	// Link into the alternate library views
+	if !upload.Moved {
+		if err := LinkIntoLibraryViews(config, result.OutputPath, muxMetadata); err != nil {
+			slog.Warn("failed to update library views", "path", result.OutputPath, "err", err)
+		}
 	}
 
 	// Get file size for history
 	var fileSize int64
 	if stat, err := os.Stat(result.OutputPath); err == nil {
 		fileSize = stat.Size()
+	} else if result.FileSize > 0 {
+		fileSize = result.FileSize
 	}
 
-	q.updateItem(id, func(item *QueueItem) {
+	if !q.updateItem(id, func(item *QueueItem) {
 		item.Status = StatusComplete
 		item.Progress = 100
-		item.Stage = "Complete"
+		item.setStage(StageComplete)
+		if upload.Moved {
+			item.setStage(StageCompleteRemote)
+		} else if upload.Err != nil {
+			item.setStage(StageCompleteUploadFailed)
+		}
 		item.OutputPath = result.OutputPath
 		item.FileSize = fileSize
+		item.UploadedTo = upload.Targets
+		if upload.Err != nil {
+			item.UploadError = upload.Err.Error()
+		}
+		if config.RcloneRemote != "" && !upload.Moved {
+			item.SyncStatus = SyncPending
+		}
 		item.CompletedAt = time.Now()
-	})
+	}) {
+		return // Stopped by the watchdog meanwhile
+	}
 
 	// Save to history
 	q.mutex.RLock()
@@ -698,6 +957,17 @@ func (q *Queue) processItem(id string) {
 			history.AddFromQueueItem(item, "complete", "")
 		}
 	}
+	// Let yt-dlp based tools sharing the archive skip the video
+	if config.DownloadArchive != "" && item != nil {
+		if err := appendToDownloadArchive(config.DownloadArchive, item.VideoURL); err != nil {
+			q.AddWarning(id, "%v", err)
+		}
+	}
+
+	// Mirror to the rclone remote in the background; the worker moves on
+	if config.RcloneRemote != "" && !upload.Moved {
+		go q.syncItem(id)
+	}
 
 	q.emit(QueueEvent{
 		Type:     "completed",
@@ -707,7 +977,94 @@ func (q *Queue) processItem(id string) {
 	})
 }
 
+// finishRun marks the worker of id as returned. An item the watchdog stopped
+// meanwhile is restarted or failed now that the worker can't interfere.
+func (q *Queue) finishRun(id string) {
+	q.mutex.Lock()
+	var stall *stallAction
+	if item := q.itemByID(id); item != nil {
+		item.running = false
+		stall, item.stall = item.stall, nil
+		q.release(id)
+	}
+	q.mutex.Unlock()
+
+	if stall != nil {
+		q.resolveStall(id, *stall)
+	}
+	q.signal()
+}
+
 // ExtractAudioFromVideo extracts the audio track from a video file.
-func ExtractAudioFromVideo(videoPath, audioPath string) error {
-	return ExtractAudioStream(videoPath, audioPath)
+func ExtractAudioFromVideo(ctx context.Context, videoPath, audioPath string) error {
+	return ExtractAudioStream(ctx, videoPath, audioPath)
+}
+
+// writeLyrics saves lyrics next to mediaPath and/or embeds them, per mode
+// (default: LRC file). Synced lyrics go to .lrc, plain text to .txt.
+func writeLyrics(mediaPath string, lyrics *LyricsResult, mode LyricsEmbedMode) error {
+	if mode == "" {
+		mode = LyricsEmbedLRC
+	}
+
+	var errs []error
+	if mode == LyricsEmbedLRC || mode == LyricsEmbedBoth {
+		if lyrics.HasSync {
+			if lrcPath, err := SaveLRCFile(lyrics, mediaPath); err != nil {
+				errs = append(errs, fmt.Errorf("failed to save LRC file: %w", err))
+			} else {
+				slog.Debug("LRC file saved", "path", lrcPath)
+			}
+		} else if lyrics.PlainText != "" {
+			if txtPath, err := SavePlainLyricsFile(lyrics, mediaPath); err != nil {
+				errs = append(errs, fmt.Errorf("failed to save lyrics file: %w", err))
+			} else {
+				slog.Debug("lyrics file saved", "path", txtPath)
+			}
+		}
+	}
+	if mode == LyricsEmbedFile || mode == LyricsEmbedBoth {
+		if err := EmbedLyricsInFile(mediaPath, lyrics); err != nil {
+			errs = append(errs, fmt.Errorf("failed to embed lyrics: %w", err))
+		} else {
+			slog.Debug("lyrics embedded in file")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// transferProgressInterval limits how often download progress is published
+const transferProgressInterval = 500 * time.Millisecond
+
+// transferProgress returns the download progress callback of item id. It
+// maps the download onto item progress from..to and publishes the transfer
+// stats, at most every transferProgressInterval. Of a format-merged yt-dlp
+// download the video file is most of the bytes, the audio stream the last
+// tenth.
+func (q *Queue) transferProgress(id string, from, to int) func(DownloadProgress) {
+	var last time.Time
+	return func(p DownloadProgress) {
+		fraction := p.Percent / 100
+		switch {
+		case p.File == 1:
+			fraction *= 0.9
+		case p.File > 1:
+			fraction = 0.9 + fraction*0.1
+		}
+		if now := time.Now(); now.Sub(last) >= transferProgressInterval {
+			last = now
+			progress := from + int(float64(to-from)*fraction)
+			q.updateItem(id, func(item *QueueItem) {
+				item.Progress = progress
+				item.Transfer = &p
+			})
+		}
+	}
+}
+
+// closedChan returns a channel that is already closed
+func closedChan() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
 }
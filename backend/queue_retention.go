@@ -0,0 +1,169 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Queue Retention
+// =============================================================================
+
+// RetentionPolicy limits how many finished items stay in the queue. Finished
+// items are already recorded in History, so pruning only tidies the queue view.
+type RetentionPolicy struct {
+	MaxItems int           `json:"maxItems"` // Keep at most this many finished items (0 = no limit)
+	MaxAge   time.Duration `json:"maxAge"`   // Drop finished items older than this (0 = no limit)
+}
+
+// Enabled reports whether the policy removes anything
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxItems > 0 || p.MaxAge > 0
+}
+
+// DefaultJanitorInterval is how often the retention policy is applied
+const DefaultJanitorInterval = 5 * time.Minute
+
+var (
+	retentionCountPattern = regexp.MustCompile(`^(\d+)\s*(items?)?$`)
+	retentionAgePattern   = regexp.MustCompile(`^(\d+)\s*(m|mins?|minutes?|h|hours?|d|days?|w|weeks?)$`)
+)
+
+// ParseRetentionPolicy parses values such as "keep 200 items or 7 days",
+// "200", "7d" or "24h, 500 items". An empty string means keep forever.
+func ParseRetentionPolicy(s string) (RetentionPolicy, error) {
+	var policy RetentionPolicy
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimPrefix(s, "keep"))
+	if s == "" {
+		return policy, nil
+	}
+
+	parts := strings.FieldsFunc(strings.ReplaceAll(s, " or ", ","), func(r rune) bool { return r == ',' })
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if m := retentionCountPattern.FindStringSubmatch(part); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			policy.MaxItems = n
+			continue
+		}
+		if m := retentionAgePattern.FindStringSubmatch(part); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			var unit time.Duration
+			switch m[2][0] {
+			case 'm':
+				unit = time.Minute
+			case 'h':
+				unit = time.Hour
+			case 'd':
+				unit = 24 * time.Hour
+			case 'w':
+				unit = 7 * 24 * time.Hour
+			}
+			policy.MaxAge = time.Duration(n) * unit
+			continue
+		}
+		return RetentionPolicy{}, fmt.Errorf("invalid retention %q, expected e.g. \"keep 200 items or 7 days\"", part)
+	}
+	return policy, nil
+}
+
+// isFinished reports whether an item has reached a terminal status
+func isFinished(status QueueStatus) bool {
+	return status == StatusComplete || status == StatusError || status == StatusCancelled
+}
+
+// ApplyRetention removes finished items that fall outside the policy and
+// returns their IDs. Items still pending or in progress are never touched.
+func (q *Queue) ApplyRetention(policy RetentionPolicy, now time.Time) []string {
+	if !policy.Enabled() {
+		return nil
+	}
+
+	q.mutex.Lock()
+	var finished []QueueItem
+	for _, item := range q.items {
+		if isFinished(item.Status) {
+			finished = append(finished, item)
+		}
+	}
+
+	// Newest first, so the count limit keeps the most recent items
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finishedAt(finished[i]).After(finishedAt(finished[j]))
+	})
+
+	drop := make(map[string]bool)
+	for i, item := range finished {
+		tooMany := policy.MaxItems > 0 && i >= policy.MaxItems
+		tooOld := policy.MaxAge > 0 && now.Sub(finishedAt(item)) > policy.MaxAge
+		if tooMany || tooOld {
+			drop[item.ID] = true
+		}
+	}
+
+	var removed []string
+	if len(drop) > 0 {
+		kept := make([]QueueItem, 0, len(q.items)-len(drop))
+		for _, item := range q.items {
+			if drop[item.ID] {
+				removed = append(removed, item.ID)
+				continue
+			}
+			kept = append(kept, item)
+		}
+		q.setItems(kept)
+	}
+	q.mutex.Unlock()
+
+	for _, id := range removed {
+		q.emit(QueueEvent{Type: "removed", ItemID: id})
+	}
+	return removed
+}
+
+// finishedAt falls back to CreatedAt for items restored without a completion time
+func finishedAt(item QueueItem) time.Time {
+	if !item.CompletedAt.IsZero() {
+		return item.CompletedAt
+	}
+	return item.CreatedAt
+}
+
+// StartJanitor periodically applies Config.CompletedRetention. The config is
+// re-read on every tick so changes in settings take effect without a restart.
+func (q *Queue) StartJanitor(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-ticker.C:
+				q.mutex.RLock()
+				config := q.configs.Get()
+				q.mutex.RUnlock()
+				if config == nil {
+					continue
+				}
+
+				policy, err := ParseRetentionPolicy(config.CompletedRetention)
+				if err != nil {
+					slog.Warn("invalid completed retention", "value", config.CompletedRetention, "err", err)
+					continue
+				}
+				if removed := q.ApplyRetention(policy, time.Now()); len(removed) > 0 {
+					slog.Info("pruned finished queue items", "count", len(removed))
+				}
+			}
+		}
+	}()
+}
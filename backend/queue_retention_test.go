@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want RetentionPolicy
+	}{
+		{"", RetentionPolicy{}},
+		{"keep 200 items or 7 days", RetentionPolicy{MaxItems: 200, MaxAge: 7 * 24 * time.Hour}},
+		{"200", RetentionPolicy{MaxItems: 200}},
+		{"12h", RetentionPolicy{MaxAge: 12 * time.Hour}},
+		{"2 weeks, 50 items", RetentionPolicy{MaxItems: 50, MaxAge: 14 * 24 * time.Hour}},
+	} {
+		got, err := ParseRetentionPolicy(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("ParseRetentionPolicy(%q) = %+v, %v; want %+v", tc.in, got, err, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"forever", "7 fortnights", "keep -1"} {
+		if _, err := ParseRetentionPolicy(bad); err == nil {
+			t.Errorf("ParseRetentionPolicy(%q) should fail", bad)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	q := newTestQueue()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	q.setItems([]QueueItem{
+		{ID: "old", Status: StatusComplete, CompletedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "pending", Status: StatusPending, CreatedAt: now.Add(-30 * 24 * time.Hour)},
+		{ID: "a", Status: StatusComplete, CompletedAt: now.Add(-3 * time.Hour)},
+		{ID: "b", Status: StatusError, CompletedAt: now.Add(-2 * time.Hour)},
+		{ID: "c", Status: StatusCancelled, CompletedAt: now.Add(-1 * time.Hour)},
+	})
+
+	removed := q.ApplyRetention(RetentionPolicy{MaxItems: 2, MaxAge: 7 * 24 * time.Hour}, now)
+	if len(removed) != 2 {
+		t.Fatalf("removed %v, want old and a", removed)
+	}
+
+	var ids []string
+	for _, item := range q.GetQueue() {
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 3 || ids[0] != "pending" || ids[1] != "b" || ids[2] != "c" {
+		t.Errorf("remaining items = %v, want [pending b c]", ids)
+	}
+
+	if removed := q.ApplyRetention(RetentionPolicy{}, now); removed != nil {
+		t.Errorf("disabled policy removed %v", removed)
+	}
+}
@@ -22,7 +22,7 @@ func addErrorItem(q *Queue, artist, title, videoURL, spotifyURL string) string {
 		Error:      "all_download_attempts_failed",
 	}
 	q.mutex.Lock()
-	q.items = append(q.items, item)
+	q.appendItem(item)
 	q.mutex.Unlock()
 	return item.ID
 }
@@ -145,7 +145,7 @@ func TestRetryWithOverride_ResetsMatchState(t *testing.T) {
 		},
 	}
 	q.mutex.Lock()
-	q.items = append(q.items, item)
+	q.appendItem(item)
 	q.mutex.Unlock()
 
 	result, err := q.RetryWithOverride("test-id-2", RetryOverrideRequest{MusicURL: "https://tidal.com/track/999"})
@@ -163,3 +163,28 @@ func TestRetryWithOverride_ResetsMatchState(t *testing.T) {
 		t.Errorf("expected SpotifyURL to be set to override URL")
 	}
 }
+
+func TestRetryWithOverride_VideoURL(t *testing.T) {
+	q := newTestQueue()
+	id := addErrorItem(q, "Artist", "Title", "https://youtube.com/watch?v=removed0000", "")
+	q.updateItem(id, func(item *QueueItem) {
+		item.AudioOnly = true
+		item.AlternativeVideos = []VideoInfo{{ID: "alt00000000"}}
+	})
+
+	altURL := "https://www.youtube.com/watch?v=alt00000000"
+	result, err := q.RetryWithOverride(id, RetryOverrideRequest{VideoURL: altURL})
+	if err != nil {
+		t.Fatalf("RetryWithOverride returned error: %v", err)
+	}
+
+	if result.VideoURL != altURL {
+		t.Errorf("expected VideoURL %q, got %q", altURL, result.VideoURL)
+	}
+	if result.AudioOnly {
+		t.Error("expected AudioOnly to be reset when a new video is chosen")
+	}
+	if result.AlternativeVideos != nil {
+		t.Error("expected AlternativeVideos to be cleared after retry")
+	}
+}
@@ -103,6 +103,30 @@ func TestAddToQueueWithMetadata(t *testing.T) {
 	}
 }
 
+func TestAddToQueueWithSourcePriority(t *testing.T) {
+	q := NewQueue(context.Background(), 1)
+
+	id, err := q.AddToQueue(DownloadRequest{
+		VideoURL:            "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		AudioSourcePriority: []string{"qobuz"},
+	})
+	if err != nil {
+		t.Fatalf("AddToQueue failed: %v", err)
+	}
+
+	item := q.GetItem(id)
+	if len(item.AudioSourcePriority) != 1 || item.AudioSourcePriority[0] != "qobuz" {
+		t.Errorf("Expected per-item priority [qobuz], got %v", item.AudioSourcePriority)
+	}
+
+	if _, err := q.AddToQueue(DownloadRequest{
+		VideoURL:            "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		AudioSourcePriority: []string{"napster"},
+	}); err == nil {
+		t.Error("Expected error for unknown audio source")
+	}
+}
+
 func TestRemoveFromQueue(t *testing.T) {
 	ctx := context.Background()
 	q := NewQueue(ctx, 2)
@@ -449,7 +473,7 @@ func TestSaveAndLoadQueue(t *testing.T) {
 	var loadedState QueueState
 	json.Unmarshal(loadData, &loadedState)
 	q2.mutex.Lock()
-	q2.items = loadedState.Items
+	q2.setItems(loadedState.Items)
 	q2.mutex.Unlock()
 
 	items := q2.GetQueue()
@@ -503,7 +527,7 @@ func TestLoadQueueResetsInProgress(t *testing.T) {
 			state.Items[i].Stage = "Waiting... (resumed)"
 		}
 	}
-	q.items = state.Items
+	q.setItems(state.Items)
 	q.mutex.Unlock()
 
 	items := q.GetQueue()
@@ -766,3 +790,170 @@ func TestRetryFailed(t *testing.T) {
 		t.Errorf("error should be cleared after retry, got %q", item.Error)
 	}
 }
+
+func TestDispatcher_SendsEachPendingItemOnce(t *testing.T) {
+	q := newTestQueue()
+	q.workerWG.Add(1)
+	go q.dispatcher()
+	defer func() {
+		q.cancel()
+		q.workerWG.Wait()
+	}()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, _ := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: fmt.Sprintf("Song %d", i), Artist: "Artist"})
+		ids = append(ids, id)
+	}
+
+	// Nothing consumes the jobs, so the items stay pending; each must still
+	// be handed out exactly once, in queue order
+	for i, want := range ids {
+		select {
+		case got := <-q.jobChan:
+			if got != want {
+				t.Errorf("job %d = %s, want %s", i, got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("job %d not dispatched", i)
+		}
+	}
+	select {
+	case got := <-q.jobChan:
+		t.Errorf("item %s dispatched twice", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// An item added later wakes the dispatcher immediately
+	late, _ := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: "Late", Artist: "Artist"})
+	select {
+	case got := <-q.jobChan:
+		if got != late {
+			t.Errorf("got %s, want %s", got, late)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late item not dispatched")
+	}
+}
+
+func TestQueuePersistence_AtomicWithBackup(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	q := newTestQueue()
+
+	first, _ := q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: "One", Artist: "Artist"})
+	if err := q.saveQueueFile(queuePath); err != nil {
+		t.Fatal(err)
+	}
+	q.AddToQueueWithMetadata(DownloadRequest{}, &VideoInfo{Title: "Two", Artist: "Artist"})
+	if err := q.saveQueueFile(queuePath); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _ := filepath.Glob(queuePath + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+
+	// Backup holds the previous save
+	backup, err := readQueueState(queuePath + ".bak")
+	if err != nil || len(backup.Items) != 1 || backup.Items[0].ID != first {
+		t.Fatalf("backup = %+v, %v", backup, err)
+	}
+
+	// A truncated queue file falls back to the backup
+	os.WriteFile(queuePath, []byte(`{"items": [{"id": "x"`), 0644)
+	q2 := newTestQueue()
+	if err := q2.loadQueueFile(queuePath); err != nil {
+		t.Fatalf("load with damaged file: %v", err)
+	}
+	if items := q2.GetQueue(); len(items) != 1 || items[0].ID != first {
+		t.Errorf("recovered items = %v", items)
+	}
+
+	// Saving over the damaged file must not replace the good backup
+	if err := q2.saveQueueFile(queuePath); err != nil {
+		t.Fatal(err)
+	}
+	if backup, err := readQueueState(queuePath + ".bak"); err != nil || len(backup.Items) != 1 {
+		t.Errorf("backup replaced by damaged file: %+v, %v", backup, err)
+	}
+}
+
+func TestQueuePersistence_ConcurrentSaves(t *testing.T) {
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	q := newTestQueue()
+	q.AddToQueue(DownloadRequest{})
+	if err := q.saveQueueFile(queuePath); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := q.saveQueueFile(queuePath); err != nil {
+				t.Error(err)
+			}
+			// The queue file is always there, even mid-save
+			if _, err := readQueueState(queuePath); err != nil {
+				t.Errorf("queue file unreadable during saves: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestQueuePersistence_LoadMissing(t *testing.T) {
+	dir := t.TempDir()
+	q := newTestQueue()
+	if err := q.loadQueueFile(filepath.Join(dir, "queue.json")); err != nil {
+		t.Errorf("missing queue file should start fresh, got %v", err)
+	}
+
+	damaged := filepath.Join(dir, "damaged.json")
+	os.WriteFile(damaged, []byte("not json"), 0644)
+	if err := q.loadQueueFile(damaged); err == nil {
+		t.Error("damaged file without backup should fail")
+	}
+}
+
+func TestAddWarning(t *testing.T) {
+	q := newTestQueue()
+	q.mutex.Lock()
+	q.appendItem(QueueItem{ID: "warn-1", Status: StatusOrganizing})
+	q.mutex.Unlock()
+
+	var mu sync.Mutex
+	var warningEvents []QueueEvent
+	q.SetProgressCallback(func(event QueueEvent) {
+		if event.Type == "warning" {
+			mu.Lock()
+			warningEvents = append(warningEvents, event)
+			mu.Unlock()
+		}
+	})
+
+	q.AddWarning("warn-1", "lyrics: %s", "no lyrics found")
+	q.AddWarning("warn-1", "poster: 404")
+
+	item := q.GetItem("warn-1")
+	want := []string{"lyrics: no lyrics found", "poster: 404"}
+	if len(item.Warnings) != len(want) || item.Warnings[0] != want[0] || item.Warnings[1] != want[1] {
+		t.Errorf("Warnings = %v, want %v", item.Warnings, want)
+	}
+	if item.Status != StatusOrganizing {
+		t.Errorf("Status = %s, a warning must not change it", item.Status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(warningEvents) != 2 {
+		t.Fatalf("got %d warning events, want 2", len(warningEvents))
+	}
+	if e := warningEvents[0]; e.ItemID != "warn-1" || e.Warning != want[0] || e.Status != StatusOrganizing {
+		t.Errorf("warning event = %+v", e)
+	}
+
+	if audit := newHistoryAudit(item); len(audit.Warnings) != 2 {
+		t.Errorf("history audit warnings = %v, want %v", audit.Warnings, want)
+	}
+}
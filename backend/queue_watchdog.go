@@ -0,0 +1,125 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// =============================================================================
+// Stalled item watchdog
+// =============================================================================
+
+// A hung yt-dlp or ffmpeg (a stream that stops sending, a server that never
+// answers) keeps an item in an active status forever and holds a worker.
+// Every update of a processing item counts as activity; an item without any
+// for Config.StallTimeoutMinutes is stopped: its context is cancelled, which
+// kills the processes running for it. Once its worker returned it is
+// restarted, up to Config.StallRetries times, or fails with the "stalled"
+// stage code.
+//
+// Progress is reported while data flows, but some steps (a long re-encode)
+// report nothing until they finish, so the timeout has to be longer than the
+// slowest of them.
+
+// stallCheckInterval is how often the watchdog looks for stalled items
+const stallCheckInterval = time.Minute
+
+// activeStatuses are the statuses of items being processed by a worker
+var activeStatuses = []QueueStatus{
+	StatusFetchingInfo, StatusDownloadingVideo, StatusDownloadingAudio, StatusMuxing, StatusOrganizing,
+}
+
+// watchdog checks for stalled items until processing stops
+func (q *Queue) watchdog() {
+	defer q.workerWG.Done()
+
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.checkStalled(now)
+		}
+	}
+}
+
+// stallAction is what happens to a stalled item once its worker returned
+type stallAction struct {
+	minutes string // Stall timeout, for the stage and error
+	fail    bool   // Out of retries: fail the item instead of restarting it
+}
+
+// checkStalled stops the items without activity for the stall timeout and
+// restarts or fails them. Returns the number of stalled items.
+//
+// A stopped worker can take a moment to return, or never return from a step
+// that ignores its context. Until it does, its updates are dropped and the
+// item keeps its status; it is restarted or failed once the worker returned
+// (see finishRun), so two workers never run it at once.
+func (q *Queue) checkStalled(now time.Time) int {
+	q.mutex.Lock()
+	config := q.configs.Get()
+	if config == nil || config.StallTimeoutMinutes <= 0 {
+		q.mutex.Unlock()
+		return 0
+	}
+	timeout := time.Duration(config.StallTimeoutMinutes * float64(time.Minute))
+	minutes := strconv.FormatFloat(config.StallTimeoutMinutes, 'f', -1, 64)
+
+	stopped := 0
+	idle := make(map[string]stallAction)
+	for _, status := range activeStatuses {
+		for _, id := range q.idsWithStatus(status) {
+			item := q.itemByID(id)
+			if item.cancelFunc == nil || now.Sub(item.lastActivity) < timeout {
+				continue
+			}
+			slog.Warn("item stalled, stopping it", "id", id, "status", item.Status, "idle", now.Sub(item.lastActivity).Round(time.Second))
+			item.cancelFunc()
+			item.cancelFunc = nil
+			item.Stalls++
+			stall := stallAction{minutes: minutes, fail: item.Stalls > config.StallRetries}
+			stopped++
+			if item.running {
+				item.stall = &stall
+				continue
+			}
+			idle[id] = stall
+		}
+	}
+	q.mutex.Unlock()
+
+	for id, stall := range idle {
+		q.resolveStall(id, stall)
+	}
+	return stopped
+}
+
+// resolveStall restarts or fails a stalled item whose worker returned. Items
+// cancelled, paused or removed in the meantime are left alone.
+func (q *Queue) resolveStall(id string, stall stallAction) {
+	q.mutex.Lock()
+	item := q.itemByID(id)
+	if item == nil || !slices.Contains(activeStatuses, item.Status) {
+		q.mutex.Unlock()
+		return
+	}
+	if stall.fail {
+		q.mutex.Unlock()
+		q.failItem(id, fmt.Errorf("stalled: no progress for %s minutes", stall.minutes), StageStalled, "minutes", stall.minutes)
+		return
+	}
+	q.setStatus(item, StatusPending)
+	item.Progress = 0
+	item.Transfer = nil
+	item.setStage(StageWaitingStalled, "minutes", stall.minutes)
+	restarted := *item
+	q.mutex.Unlock()
+
+	q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &restarted})
+}
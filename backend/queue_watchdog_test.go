@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestItem puts an item in an active status as a worker would and
+// returns a pointer reporting whether it was cancelled
+func startTestItem(q *Queue, id string) *bool {
+	cancelled := new(bool)
+	q.UpdateStage(id, StatusDownloadingVideo, 10, StageDownloadingVideo)
+	q.mutex.Lock()
+	q.itemByID(id).cancelFunc = func() { *cancelled = true }
+	q.mutex.Unlock()
+	return cancelled
+}
+
+func TestCheckStalled(t *testing.T) {
+	q := newTestQueue()
+	q.SetConfig(&Config{StallTimeoutMinutes: 10, StallRetries: 1})
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled := startTestItem(q, id)
+	if n := q.checkStalled(time.Now().Add(5 * time.Minute)); n != 0 || *cancelled {
+		t.Fatalf("checkStalled = %d before the timeout", n)
+	}
+
+	// First stall: restarted
+	if n := q.checkStalled(time.Now().Add(11 * time.Minute)); n != 1 || !*cancelled {
+		t.Fatalf("checkStalled = %d, cancelled = %v, want the item stopped", n, *cancelled)
+	}
+	item := q.GetItem(id)
+	if item.Status != StatusPending || item.StageCode != StageWaitingStalled || item.Stalls != 1 {
+		t.Errorf("restarted item = %s %s stalls=%d", item.Status, item.StageCode, item.Stalls)
+	}
+
+	// Second stall: failed as stalled
+	startTestItem(q, id)
+	if n := q.checkStalled(time.Now().Add(11 * time.Minute)); n != 1 {
+		t.Fatalf("checkStalled = %d, want the item stopped again", n)
+	}
+	item = q.GetItem(id)
+	if item.Status != StatusError || item.StageCode != StageStalled || !strings.Contains(item.Error, "stalled") {
+		t.Errorf("failed item = %s %s %q", item.Status, item.StageCode, item.Error)
+	}
+
+	// A retry starts counting stalls again
+	q.RetryFailed()
+	if item := q.GetItem(id); item.Stalls != 0 {
+		t.Errorf("stalls after retry = %d, want 0", item.Stalls)
+	}
+}
+
+func TestCheckStalled_Disabled(t *testing.T) {
+	q := newTestQueue()
+	q.SetConfig(&Config{StallTimeoutMinutes: 0})
+	id, _ := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc"})
+	cancelled := startTestItem(q, id)
+	if n := q.checkStalled(time.Now().Add(24 * time.Hour)); n != 0 || *cancelled {
+		t.Errorf("checkStalled = %d with the watchdog off", n)
+	}
+}
+
+// A stopped worker that keeps running (a step ignoring its context) can't
+// clobber the item: its updates are dropped and the item is only dispatched
+// again once the worker returned
+func TestCheckStalled_WorkerStillRunning(t *testing.T) {
+	q := newTestQueue()
+	q.SetConfig(&Config{StallTimeoutMinutes: 10, StallRetries: 1})
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dispatched and started like processItem does
+	if got := q.nextPending(); got != id {
+		t.Fatalf("nextPending = %q, want %q", got, id)
+	}
+	q.mutex.Lock()
+	delete(q.dispatched, id)
+	q.itemByID(id).running = true
+	q.mutex.Unlock()
+	cancelled := startTestItem(q, id)
+
+	if n := q.checkStalled(time.Now().Add(11 * time.Minute)); n != 1 || !*cancelled {
+		t.Fatalf("checkStalled = %d, cancelled = %v, want the item stopped", n, *cancelled)
+	}
+
+	// The worker hasn't noticed and carries on
+	q.UpdateStage(id, StatusMuxing, 80, StageMuxing)
+	q.SetItemError(id, context.Canceled)
+	if item := q.GetItem(id); item.Status != StatusDownloadingVideo || item.Error != "" {
+		t.Errorf("stale worker updated the item: %s %q", item.Status, item.Error)
+	}
+	if got := q.nextPending(); got != "" {
+		t.Errorf("nextPending = %q while the stopped worker runs", got)
+	}
+
+	// Restarted once it returned
+	q.finishRun(id)
+	item := q.GetItem(id)
+	if item.Status != StatusPending || item.StageCode != StageWaitingStalled || item.running {
+		t.Errorf("after the worker returned: %s %s running=%v", item.Status, item.StageCode, item.running)
+	}
+	if got := q.nextPending(); got != id {
+		t.Errorf("nextPending = %q, want the restarted item", got)
+	}
+}
+
+// A paused worker is still returning when the item is resumed
+func TestNextPending_WaitsForWorker(t *testing.T) {
+	q := newTestQueue()
+	id, _ := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc"})
+	startTestItem(q, id)
+	q.mutex.Lock()
+	q.itemByID(id).running = true
+	q.mutex.Unlock()
+
+	q.PauseItem(id)
+	q.ResumeItem(id)
+	if got := q.nextPending(); got != "" {
+		t.Errorf("nextPending = %q before the paused worker returned", got)
+	}
+	q.finishRun(id)
+	if got := q.nextPending(); got != id {
+		t.Errorf("nextPending = %q, want %q", got, id)
+	}
+}
+
+func TestExtractAudioStream_KilledOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	// Fake ffmpeg that hangs
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := ExtractAudioStream(ctx, "video.mp4", filepath.Join(t.TempDir(), "audio.m4a")); err == nil {
+		t.Fatal("expected an error from the killed ffmpeg")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("ffmpeg ran for %s after the context was cancelled", elapsed)
+	}
+}
@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// rclone sync. When Config.RcloneRemote is set, every completed item is
+// mirrored to the remote in the background with "rclone copyto", one call
+// per file (output plus sidecars). rclone verifies sizes/checksums itself.
+// Sync state is kept on the queue item and its history entry, and failed
+// syncs can be retried without downloading again.
+
+// Sync states for QueueItem.SyncStatus and HistoryEntry.SyncStatus
+const (
+	SyncPending = "pending"
+	SyncRunning = "syncing"
+	SyncDone    = "synced"
+	SyncFailed  = "failed"
+)
+
+// GetRclonePath returns path to rclone binary
+func GetRclonePath() string {
+	bundledPaths := []string{
+		filepath.Join(getAppDataDir(), "bin", "rclone"),
+		filepath.Join(getAppDataDir(), "bin", "rclone.exe"),
+	}
+
+	for _, p := range bundledPaths {
+		if fileExists(p) {
+			return p
+		}
+	}
+
+	if path, err := exec.LookPath("rclone"); err == nil {
+		return path
+	}
+
+	return "rclone"
+}
+
+// rcloneDestination returns the remote path for a local file. Without a path
+// template the layout under root is mirrored; with one, the template (e.g.
+// "{albumartist}/{album}") names the remote folder and the file name is kept.
+func rcloneDestination(config *Config, root string, item *QueueItem, file string) string {
+	remote := strings.TrimSuffix(config.RcloneRemote, "/")
+
+	var rel string
+	if config.RclonePathTemplate != "" {
+		metadata := &Metadata{
+			Title:       item.Title,
+			Artist:      item.Artist,
+			Album:       item.Album,
+			AlbumArtist: item.AlbumArtist,
+			Track:       item.PlaylistPosition,
+			TrackTotal:  item.TrackTotal,
+			Disc:        item.Disc,
+		}
+		rel = path.Join(filepath.ToSlash(ApplyTemplate(config.RclonePathTemplate, metadata)), filepath.Base(file))
+	} else {
+		var err error
+		rel, err = filepath.Rel(root, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(file)
+		}
+		rel = filepath.ToSlash(rel)
+	}
+
+	if strings.HasSuffix(remote, ":") {
+		return remote + rel
+	}
+	return remote + "/" + rel
+}
+
+// RcloneCopy copies each file to its destination with "rclone copyto"
+func RcloneCopy(ctx context.Context, files, destinations []string) error {
+	for i, file := range files {
+		cmd := newCommandContext(ctx, GetRclonePath(), "copyto", file, destinations[i], "--retries", "3")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("rclone copyto %s failed: %w, output: %s", filepath.Base(file), err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return nil
+}
+
+// syncItem mirrors a completed item to Config.RcloneRemote and records the
+// outcome on the item and its history entry
+func (q *Queue) syncItem(id string) {
+	q.mutex.RLock()
+	config := q.configs.Get()
+	history := q.history
+	q.mutex.RUnlock()
+	if config == nil || config.RcloneRemote == "" {
+		return
+	}
+
+	item := q.GetItem(id)
+	if item == nil || item.OutputPath == "" {
+		return
+	}
+
+	root := libraryRootFor(config, item.OutputPath)
+	files := outputFiles(item.OutputPath)
+	destinations := make([]string, len(files))
+	for i, file := range files {
+		destinations[i] = rcloneDestination(config, root, item, file)
+	}
+
+	q.updateItem(id, func(item *QueueItem) {
+		item.SyncStatus = SyncRunning
+		item.SyncError = ""
+	})
+
+	err := RcloneCopy(q.ctx, files, destinations)
+
+	status, errMsg := SyncDone, ""
+	if err != nil {
+		status, errMsg = SyncFailed, err.Error()
+		slog.Warn("rclone sync failed", "id", id, "remote", config.RcloneRemote, "err", err)
+	} else {
+		slog.Info("synced with rclone", "id", id, "remote", destinations[0])
+	}
+
+	q.updateItem(id, func(item *QueueItem) {
+		item.SyncStatus = status
+		item.SyncError = errMsg
+		if err == nil {
+			item.SyncedAt = time.Now()
+		}
+	})
+	if history != nil {
+		history.SetSyncStatus(item.OutputPath, status, errMsg)
+	}
+}
+
+// RetrySync runs the rclone sync again for a completed item
+func (q *Queue) RetrySync(id string) error {
+	q.mutex.RLock()
+	config := q.configs.Get()
+	item := q.itemByID(id)
+	var status QueueStatus
+	var syncStatus string
+	if item != nil {
+		status, syncStatus = item.Status, item.SyncStatus
+	}
+	q.mutex.RUnlock()
+
+	if item == nil {
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if config == nil || config.RcloneRemote == "" {
+		return fmt.Errorf("rclone sync is not configured")
+	}
+	if status != StatusComplete {
+		return fmt.Errorf("item %s is not complete", id)
+	}
+	if syncStatus == SyncRunning {
+		return fmt.Errorf("item %s is already syncing", id)
+	}
+
+	go q.syncItem(id)
+	return nil
+}
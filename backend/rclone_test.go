@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRcloneDestination(t *testing.T) {
+	root := filepath.Join("/music")
+	item := &QueueItem{Title: "Song", Artist: "Artist feat. Guest", AlbumArtist: "Artist", Album: "Album"}
+	file := filepath.Join(root, "Artist", "Song", "Song.mkv")
+
+	config := &Config{RcloneRemote: "gdrive:"}
+	if got := rcloneDestination(config, root, item, file); got != "gdrive:Artist/Song/Song.mkv" {
+		t.Errorf("mirrored destination = %q", got)
+	}
+
+	config = &Config{RcloneRemote: "gdrive:Music/", RclonePathTemplate: "{albumartist}/{album}"}
+	if got := rcloneDestination(config, root, item, file); got != "gdrive:Music/Artist/Album/Song.mkv" {
+		t.Errorf("templated destination = %q", got)
+	}
+}
+
+func TestQueueSyncItem(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake rclone is a shell script")
+	}
+
+	// Fake rclone: "copyto SRC remote:PATH" copies SRC under $RCLONE_FAKE_REMOTE
+	bin := t.TempDir()
+	remote := t.TempDir()
+	script := "#!/bin/sh\n[ \"$1\" = copyto ] || exit 2\n[ -n \"$RCLONE_FAIL_SYNC\" ] && { echo quota exceeded >&2; exit 1; }\n" +
+		"dst=\"$RCLONE_FAKE_REMOTE/${3#*:}\"\nmkdir -p \"$(dirname \"$dst\")\" && cp \"$2\" \"$dst\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "rclone"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("RCLONE_FAKE_REMOTE", remote)
+	t.Setenv("RCLONE_FAIL_SYNC", "1")
+
+	root := t.TempDir()
+	mkv := filepath.Join(root, "Artist", "Song.mkv")
+	writeTestFile(t, mkv, 64)
+	writeTestFile(t, filepath.Join(root, "Artist", "Song.nfo"), 8)
+
+	history := &History{filePath: filepath.Join(t.TempDir(), "history.json")}
+	q := newTestQueue()
+	q.SetConfig(&Config{OutputDirectory: root, RcloneRemote: "remote:"})
+	q.SetHistory(history)
+	q.appendItem(QueueItem{ID: "a", Status: StatusComplete, OutputPath: mkv, SyncStatus: SyncPending})
+	history.AddFromQueueItem(q.GetItem("a"), "complete", "")
+
+	q.syncItem("a")
+	if item := q.GetItem("a"); item.SyncStatus != SyncFailed || item.SyncError == "" {
+		t.Fatalf("after failure: status %q, error %q", item.SyncStatus, item.SyncError)
+	}
+	if entry := history.GetAll()[0]; entry.SyncStatus != SyncFailed {
+		t.Errorf("history sync status = %q", entry.SyncStatus)
+	}
+
+	t.Setenv("RCLONE_FAIL_SYNC", "")
+	q.syncItem("a")
+	if item := q.GetItem("a"); item.SyncStatus != SyncDone || item.SyncError != "" || item.SyncedAt.IsZero() {
+		t.Errorf("after retry: %+v", item)
+	}
+	for _, name := range []string{"Song.mkv", "Song.nfo"} {
+		if _, err := os.Stat(filepath.Join(remote, "Artist", name)); err != nil {
+			t.Errorf("%s not synced: %v", name, err)
+		}
+	}
+	if entry := history.GetAll()[0]; entry.SyncStatus != SyncDone || entry.SyncError != "" {
+		t.Errorf("history entry = %+v", entry)
+	}
+
+	q.setStatus(q.itemByID("a"), StatusError)
+	if err := q.RetrySync("a"); err == nil {
+		t.Error("RetrySync should refuse items that are not complete")
+	}
+}
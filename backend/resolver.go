@@ -0,0 +1,436 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cross-platform URL resolution with pluggable resolvers, caching and failover.
+// song.link is the primary resolver; MusicBrainz URL relations act as a fallback
+// when song.link is rate limiting or doesn't know the track.
+
+// MusicResolver converts a music URL into links for other platforms
+type MusicResolver interface {
+	Name() string
+	Resolve(musicURL string) (*SongLinkTrackInfo, error)
+}
+
+// Resolver names accepted in Config.MusicResolvers
+const (
+	ResolverSongLink    = "songlink"
+	ResolverMusicBrainz = "musicbrainz"
+)
+
+const (
+	resolverCacheTTL        = 24 * time.Hour
+	resolverCacheMaxEntries = 2000
+	musicBrainzAPIBase      = "https://musicbrainz.org/ws/2"
+)
+
+// ============================================================================
+// song.link (Odesli)
+// ============================================================================
+
+// SongLinkResolver resolves URLs through the song.link API.
+// With an Odesli API key the shared client-side rate limit is skipped.
+type SongLinkResolver struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewSongLinkResolver creates a song.link resolver (apiKey may be empty)
+func NewSongLinkResolver(apiKey string) *SongLinkResolver {
+	return &SongLinkResolver{
+		apiKey:  apiKey,
+		baseURL: songLinkAPIBase,
+		client:  httpClient,
+	}
+}
+
+func (s *SongLinkResolver) Name() string {
+	return ResolverSongLink
+}
+
+func (s *SongLinkResolver) Resolve(musicURL string) (*SongLinkTrackInfo, error) {
+	if s.apiKey == "" {
+		waitForRateLimit()
+	}
+
+	params := url.Values{}
+	params.Set("url", musicURL)
+	if s.apiKey != "" {
+		params.Set("key", s.apiKey)
+	}
+	apiURL := s.baseURL + "?" + params.Encode()
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, fmt.Errorf("rate limited by song.link API, please wait")
+	}
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var response SongLinkResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return parseSongLinkResponse(&response), nil
+}
+
+// ============================================================================
+// MusicBrainz URL relations
+// ============================================================================
+
+// MusicBrainzResolver looks up the recording linked to a URL and returns the
+// streaming links attached to that recording. MusicBrainz allows 1 req/s.
+type MusicBrainzResolver struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewMusicBrainzResolver creates a MusicBrainz resolver
+func NewMusicBrainzResolver() *MusicBrainzResolver {
+	return &MusicBrainzResolver{
+		baseURL: musicBrainzAPIBase,
+		client:  httpClient,
+	}
+}
+
+func (m *MusicBrainzResolver) Name() string {
+	return ResolverMusicBrainz
+}
+
+type mbURLLookup struct {
+	ID        string `json:"id"`
+	Resource  string `json:"resource"`
+	Relations []struct {
+		TargetType string `json:"target-type"`
+		Recording  *struct {
+			ID string `json:"id"`
+		} `json:"recording"`
+	} `json:"relations"`
+}
+
+type mbRecording struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	ISRCs        []string `json:"isrcs"`
+	ArtistCredit []struct {
+		Name       string `json:"name"`
+		JoinPhrase string `json:"joinphrase"`
+	} `json:"artist-credit"`
+	Relations []struct {
+		TargetType string `json:"target-type"`
+		URL        *struct {
+			Resource string `json:"resource"`
+		} `json:"url"`
+	} `json:"relations"`
+}
+
+func (m *MusicBrainzResolver) Resolve(musicURL string) (*SongLinkTrackInfo, error) {
+	resource := normalizeMusicBrainzResource(musicURL)
+
+	var lookup mbURLLookup
+	if err := m.get("/url", url.Values{"resource": {resource}, "inc": {"recording-rels"}}, &lookup); err != nil {
+		return nil, err
+	}
+
+	recordingID := ""
+	for _, rel := range lookup.Relations {
+		if rel.TargetType == "recording" && rel.Recording != nil {
+			recordingID = rel.Recording.ID
+			break
+		}
+	}
+	if recordingID == "" {
+		return nil, fmt.Errorf("no recording linked to %s on MusicBrainz", resource)
+	}
+
+	var rec mbRecording
+	if err := m.get("/recording/"+recordingID, url.Values{"inc": {"url-rels isrcs artist-credits"}}, &rec); err != nil {
+		return nil, err
+	}
+
+	info := &SongLinkTrackInfo{
+		Title: rec.Title,
+		Type:  "song",
+	}
+	var artist strings.Builder
+	for _, credit := range rec.ArtistCredit {
+		artist.WriteString(credit.Name)
+		artist.WriteString(credit.JoinPhrase)
+	}
+	info.Artist = artist.String()
+	if len(rec.ISRCs) > 0 {
+		info.ISRC = rec.ISRCs[0]
+	}
+
+	for _, rel := range rec.Relations {
+		if rel.TargetType != "url" || rel.URL == nil {
+			continue
+		}
+		assignPlatformURL(&info.URLs, rel.URL.Resource)
+	}
+
+	return info, nil
+}
+
+func (m *MusicBrainzResolver) get(path string, params url.Values, out interface{}) error {
+	return musicBrainzGet(m.client, m.baseURL, path, params, out)
+}
+
+// MusicBrainz asks clients to stay at or below one request per second
+var (
+	mbLastRequest time.Time
+	mbMutex       sync.Mutex
+)
+
+// musicBrainzGet performs a throttled MusicBrainz web service request and decodes the JSON response
+func musicBrainzGet(client *http.Client, baseURL, path string, params url.Values, out interface{}) error {
+	mbMutex.Lock()
+	if elapsed := time.Since(mbLastRequest); elapsed < time.Second {
+		time.Sleep(time.Second - elapsed)
+	}
+	mbLastRequest = time.Now()
+	mbMutex.Unlock()
+
+	params.Set("fmt", "json")
+	req, err := http.NewRequest("GET", baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("MusicBrainz request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return fmt.Errorf("not found on MusicBrainz")
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("MusicBrainz error: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse MusicBrainz response: %w", err)
+	}
+	return nil
+}
+
+// normalizeMusicBrainzResource rewrites URLs to the canonical form MusicBrainz stores
+func normalizeMusicBrainzResource(musicURL string) string {
+	if videoID, err := ParseYouTubeURL(musicURL); err == nil {
+		if strings.Contains(musicURL, "music.youtube.com") {
+			return "https://music.youtube.com/watch?v=" + videoID
+		}
+		return "https://www.youtube.com/watch?v=" + videoID
+	}
+	if id, kind, err := ParseSpotifyURL(musicURL); err == nil {
+		return fmt.Sprintf("https://open.spotify.com/%s/%s", kind, id)
+	}
+	return musicURL
+}
+
+// assignPlatformURL stores a streaming URL in the matching SongLinkURLs field
+func assignPlatformURL(urls *SongLinkURLs, resource string) {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+
+	switch {
+	case strings.HasSuffix(host, "tidal.com"):
+		urls.TidalURL = resource
+	case strings.HasSuffix(host, "qobuz.com"):
+		urls.QobuzURL = resource
+	case strings.HasPrefix(host, "music.amazon."):
+		urls.AmazonURL = resource
+	case strings.HasSuffix(host, "deezer.com"):
+		urls.DeezerURL = resource
+	case host == "open.spotify.com":
+		urls.SpotifyURL = resource
+	case host == "music.apple.com":
+		urls.AppleMusicURL = resource
+	case host == "music.youtube.com":
+		urls.YouTubeMusicURL = resource
+	case host == "youtube.com" || host == "youtu.be":
+		urls.YouTubeURL = resource
+	case host == "soundcloud.com":
+		urls.SoundCloudURL = resource
+	}
+}
+
+// ============================================================================
+// Chain with caching and failover
+// ============================================================================
+
+type resolverCacheEntry struct {
+	info      *SongLinkTrackInfo
+	source    string
+	expiresAt time.Time
+}
+
+// ResolverChain tries each resolver in order and caches successful results
+type ResolverChain struct {
+	resolvers []MusicResolver
+	ttl       time.Duration
+
+	mu    sync.Mutex
+	cache map[string]resolverCacheEntry
+}
+
+// NewResolverChain creates a chain over the given resolvers
+func NewResolverChain(resolvers ...MusicResolver) *ResolverChain {
+	return &ResolverChain{
+		resolvers: resolvers,
+		ttl:       resolverCacheTTL,
+		cache:     make(map[string]resolverCacheEntry),
+	}
+}
+
+func (c *ResolverChain) Name() string {
+	names := make([]string, len(c.resolvers))
+	for i, r := range c.resolvers {
+		names[i] = r.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Resolve returns a cached result when available, otherwise the first
+// successful resolver result. Errors from all resolvers are combined.
+func (c *ResolverChain) Resolve(musicURL string) (*SongLinkTrackInfo, error) {
+	key := strings.TrimSpace(musicURL)
+
+	c.mu.Lock()
+	if entry, ok := c.cache[key]; ok {
+		if time.Now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			slog.Debug("resolver cache hit", "url", key, "source", entry.source)
+			cp := *entry.info
+			return &cp, nil
+		}
+		delete(c.cache, key)
+	}
+	c.mu.Unlock()
+
+	if len(c.resolvers) == 0 {
+		return nil, fmt.Errorf("no music resolvers configured")
+	}
+
+	var errs []string
+	for _, r := range c.resolvers {
+		info, err := r.Resolve(key)
+		if err != nil {
+			slog.Debug("resolver failed", "resolver", r.Name(), "err", err)
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Name(), err))
+			continue
+		}
+		c.store(key, info, r.Name())
+		cp := *info
+		return &cp, nil
+	}
+
+	return nil, fmt.Errorf("all resolvers failed: %s", strings.Join(errs, "; "))
+}
+
+func (c *ResolverChain) store(key string, info *SongLinkTrackInfo, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.cache) >= resolverCacheMaxEntries {
+		// Drop expired entries first, then anything if still full
+		for k, e := range c.cache {
+			if now.After(e.expiresAt) {
+				delete(c.cache, k)
+			}
+		}
+		for k := range c.cache {
+			if len(c.cache) < resolverCacheMaxEntries {
+				break
+			}
+			delete(c.cache, k)
+		}
+	}
+
+	c.cache[key] = resolverCacheEntry{info: info, source: source, expiresAt: now.Add(c.ttl)}
+}
+
+// ClearCache drops all cached resolutions
+func (c *ResolverChain) ClearCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = make(map[string]resolverCacheEntry)
+}
+
+var (
+	musicResolver      MusicResolver = NewResolverChain(NewSongLinkResolver(""), NewMusicBrainzResolver())
+	musicResolverMutex sync.RWMutex
+)
+
+// ConfigureMusicResolvers rebuilds the global resolver chain from config.
+// Unknown resolver names are ignored; an empty list keeps the defaults.
+func ConfigureMusicResolvers(config *Config) {
+	names := config.MusicResolvers
+	if len(names) == 0 {
+		names = []string{ResolverSongLink, ResolverMusicBrainz}
+	}
+
+	var resolvers []MusicResolver
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case ResolverSongLink:
+			resolvers = append(resolvers, NewSongLinkResolver(config.OdesliAPIKey))
+		case ResolverMusicBrainz:
+			resolvers = append(resolvers, NewMusicBrainzResolver())
+		default:
+			slog.Warn("unknown music resolver ignored", "name", name)
+		}
+	}
+
+	SetMusicResolver(NewResolverChain(resolvers...))
+}
+
+// SetMusicResolver replaces the resolver used by ResolveMusicURL
+func SetMusicResolver(r MusicResolver) {
+	musicResolverMutex.Lock()
+	defer musicResolverMutex.Unlock()
+	musicResolver = r
+}
+
+func getMusicResolver() MusicResolver {
+	musicResolverMutex.RLock()
+	defer musicResolverMutex.RUnlock()
+	return musicResolver
+}
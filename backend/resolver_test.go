@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeResolver struct {
+	name  string
+	info  *SongLinkTrackInfo
+	err   error
+	calls int
+}
+
+func (f *fakeResolver) Name() string { return f.name }
+
+func (f *fakeResolver) Resolve(musicURL string) (*SongLinkTrackInfo, error) {
+	f.calls++
+	return f.info, f.err
+}
+
+func TestResolverChain_Failover(t *testing.T) {
+	primary := &fakeResolver{name: "primary", err: fmt.Errorf("rate limited")}
+	secondary := &fakeResolver{name: "secondary", info: &SongLinkTrackInfo{Title: "Song", URLs: SongLinkURLs{TidalURL: "https://tidal.com/browse/track/1"}}}
+
+	chain := NewResolverChain(primary, secondary)
+	info, err := chain.Resolve("https://open.spotify.com/track/abc")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.URLs.TidalURL == "" {
+		t.Error("expected result from secondary resolver")
+	}
+	if primary.calls != 1 || secondary.calls != 1 {
+		t.Errorf("expected one call each, got primary=%d secondary=%d", primary.calls, secondary.calls)
+	}
+}
+
+func TestResolverChain_Cache(t *testing.T) {
+	r := &fakeResolver{name: "only", info: &SongLinkTrackInfo{Title: "Song"}}
+	chain := NewResolverChain(r)
+
+	for i := 0; i < 3; i++ {
+		if _, err := chain.Resolve("https://open.spotify.com/track/abc"); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+	if r.calls != 1 {
+		t.Errorf("expected cached results after first call, got %d calls", r.calls)
+	}
+
+	chain.ClearCache()
+	chain.Resolve("https://open.spotify.com/track/abc")
+	if r.calls != 2 {
+		t.Errorf("expected resolver to be called again after ClearCache, got %d calls", r.calls)
+	}
+}
+
+func TestResolverChain_AllFail(t *testing.T) {
+	chain := NewResolverChain(
+		&fakeResolver{name: "a", err: fmt.Errorf("boom")},
+		&fakeResolver{name: "b", err: fmt.Errorf("nope")},
+	)
+	_, err := chain.Resolve("https://example.com")
+	if err == nil {
+		t.Fatal("expected error when all resolvers fail")
+	}
+	if !strings.Contains(err.Error(), "a: boom") || !strings.Contains(err.Error(), "b: nope") {
+		t.Errorf("error should mention each resolver, got %q", err)
+	}
+}
+
+func TestMusicBrainzResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/url":
+			if got := r.URL.Query().Get("resource"); got != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+				t.Errorf("unexpected resource %q", got)
+			}
+			fmt.Fprint(w, `{"id":"u1","relations":[{"target-type":"recording","recording":{"id":"rec-1"}}]}`)
+		case r.URL.Path == "/recording/rec-1":
+			fmt.Fprint(w, `{
+				"id":"rec-1","title":"Never Gonna Give You Up","isrcs":["GBARL9300135"],
+				"artist-credit":[{"name":"Rick Astley","joinphrase":""}],
+				"relations":[
+					{"target-type":"url","url":{"resource":"https://tidal.com/browse/track/1234"}},
+					{"target-type":"url","url":{"resource":"https://www.qobuz.com/us-en/album/x/y"}},
+					{"target-type":"url","url":{"resource":"https://www.deezer.com/track/99"}}
+				]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mb := &MusicBrainzResolver{baseURL: server.URL, client: server.Client()}
+	info, err := mb.Resolve("https://youtu.be/dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if info.Title != "Never Gonna Give You Up" || info.Artist != "Rick Astley" {
+		t.Errorf("unexpected metadata: %+v", info)
+	}
+	if info.ISRC != "GBARL9300135" {
+		t.Errorf("expected ISRC, got %q", info.ISRC)
+	}
+	if info.URLs.TidalURL == "" || info.URLs.QobuzURL == "" || info.URLs.DeezerURL == "" {
+		t.Errorf("expected tidal/qobuz/deezer URLs, got %+v", info.URLs)
+	}
+}
+
+func TestSongLinkResolver_APIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "secret" {
+			t.Errorf("expected API key in query, got %q", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"entityUniqueId":"TIDAL_SONG::1","linksByPlatform":{"tidal":{"url":"https://tidal.com/browse/track/1","entityUniqueId":"TIDAL_SONG::1"}},"entitiesByUniqueId":{"TIDAL_SONG::1":{"title":"Song","artistName":"Artist"}}}`)
+	}))
+	defer server.Close()
+
+	r := &SongLinkResolver{apiKey: "secret", baseURL: server.URL, client: server.Client()}
+	info, err := r.Resolve("https://open.spotify.com/track/abc")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if info.URLs.TidalURL != "https://tidal.com/browse/track/1" || info.Title != "Song" {
+		t.Errorf("unexpected result: %+v", info)
+	}
+}
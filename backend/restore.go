@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Restore from provenance sidecars
+// =============================================================================
+
+// RestoreFromProvenance rebuilds the data directory from a library: every
+// provenance sidecar (see provenance.go) becomes a history entry and a file
+// index entry, and media files without an NFO get one again. It is meant for
+// users who lost their data directory but kept their media files; run it
+// while the server is stopped, since the server keeps its own copy of the
+// history in memory.
+
+// RestoreOptions selects what RestoreFromProvenance rebuilds
+type RestoreOptions struct {
+	History      *History   // nil = leave the history alone
+	FileIndex    *FileIndex // nil = leave the file index alone
+	NFO          bool       // Write missing NFO files
+	OverwriteNFO bool       // Rewrite existing NFO files as well
+	Verify       bool       // Compare media checksums with the sidecars (reads every file)
+	DryRun       bool       // Report what would be restored, write nothing
+}
+
+// RestoreReport is the result of RestoreFromProvenance
+type RestoreReport struct {
+	Sidecars   int      `json:"sidecars"`             // Provenance files found
+	History    int      `json:"history"`              // History entries added
+	Indexed    int      `json:"indexed"`              // File index entries added
+	NFOs       int      `json:"nfos"`                 // NFO files written
+	Skipped    int      `json:"skipped"`              // Already in the history
+	Mismatched []string `json:"mismatched,omitempty"` // Media files that changed since they were downloaded (Verify)
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// RestoreFromProvenance scans dirs for provenance sidecars and restores
+// what opts selects from them
+func RestoreFromProvenance(dirs []string, opts RestoreOptions) (*RestoreReport, error) {
+	report := &RestoreReport{}
+
+	var sidecars []string
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), ProvenanceSuffix) {
+				sidecars = append(sidecars, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+	}
+	slices.Sort(sidecars)
+	report.Sidecars = len(sidecars)
+
+	known := make(map[string]bool)
+	if opts.History != nil {
+		for _, entry := range opts.History.GetAll() {
+			known[filepath.Clean(entry.OutputPath)] = true
+		}
+	}
+
+	var entries []HistoryEntry
+	for _, sidecar := range sidecars {
+		p, err := readProvenanceFile(sidecar)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		media, err := provenanceMediaPath(sidecar, p)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		stat, err := os.Stat(media)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to read %s: %v", media, err))
+			continue
+		}
+
+		if opts.Verify {
+			if recorded, ok := p.mediaFile(); ok {
+				current, err := provenanceFile("media", media)
+				if err != nil {
+					report.Errors = append(report.Errors, err.Error())
+				} else if current.SHA256 != recorded.SHA256 {
+					report.Mismatched = append(report.Mismatched, media)
+				}
+			}
+		}
+
+		if opts.History != nil {
+			if known[filepath.Clean(media)] {
+				report.Skipped++
+			} else {
+				known[filepath.Clean(media)] = true
+				entries = append(entries, historyEntryFromProvenance(p, media, stat.Size()))
+			}
+		}
+
+		if opts.FileIndex != nil {
+			report.Indexed++
+			if !opts.DryRun {
+				opts.FileIndex.AddEntry(FileIndexEntry{
+					Path:      media,
+					Title:     p.Title,
+					Artist:    p.Artist,
+					Album:     p.Album,
+					AlbumID:   p.AlbumID,
+					Duration:  p.Video.Duration,
+					ISRC:      p.ISRC,
+					YouTubeID: p.Metadata().YouTubeID,
+					Size:      stat.Size(),
+					IndexedAt: time.Now(),
+				})
+			}
+		}
+
+		if opts.NFO || opts.OverwriteNFO {
+			nfoPath := mediaBase(media) + ".nfo"
+			if _, err := os.Stat(nfoPath); err == nil && !opts.OverwriteNFO {
+				continue
+			}
+			report.NFOs++
+			if opts.DryRun {
+				continue
+			}
+			nfoOpts := &NFOOptions{IncludeFileInfo: true}
+			if mediaInfo, err := GetMediaInfo(media); err == nil {
+				nfoOpts.MediaInfo = mediaInfo
+			}
+			if err := WriteNFO(p.Metadata(), nfoPath, nfoOpts); err != nil {
+				report.NFOs--
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to write %s: %v", nfoPath, err))
+			}
+		}
+	}
+
+	report.History = len(entries)
+	if opts.DryRun {
+		return report, nil
+	}
+	if opts.History != nil && len(entries) > 0 {
+		if err := opts.History.AddEntries(entries); err != nil {
+			return report, fmt.Errorf("failed to save history: %w", err)
+		}
+	}
+	if opts.FileIndex != nil && report.Indexed > 0 {
+		if err := opts.FileIndex.Flush(); err != nil {
+			return report, fmt.Errorf("failed to save file index: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// provenanceMediaPath finds the media file a sidecar belongs to: the file
+// named in the sidecar, or a file with the sidecar's base name if the media
+// file was renamed together with its sidecars
+func provenanceMediaPath(sidecar string, p *Provenance) (string, error) {
+	dir := filepath.Dir(sidecar)
+	base := strings.TrimSuffix(sidecar, ProvenanceSuffix)
+	if media, ok := p.mediaFile(); ok {
+		for _, candidate := range []string{filepath.Join(dir, media.Name), base + filepath.Ext(media.Name)} {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+	for _, ext := range slices.Sorted(maps.Keys(indexedExtensions)) {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, nil
+		}
+	}
+	return "", fmt.Errorf("no media file for %s", sidecar)
+}
+
+// historyEntryFromProvenance rebuilds the history entry of a download
+func historyEntryFromProvenance(p *Provenance, media string, size int64) HistoryEntry {
+	entry := HistoryEntry{
+		VideoURL:    p.Request.VideoURL,
+		Title:       p.Title,
+		Artist:      p.Artist,
+		AudioSource: p.Audio.Source,
+		Quality:     p.Video.Quality,
+		OutputPath:  media,
+		Duration:    p.Video.Duration,
+		FileSize:    size,
+		CompletedAt: p.CompletedAt,
+		Status:      "complete",
+		Notes:       p.Request.Notes,
+		Tags:        p.Request.Tags,
+		Audit: &HistoryAudit{
+			SourcesTried: p.Audio.SourcesTried,
+			AudioService: p.Audio.Service,
+			Warnings:     p.Warnings,
+			QueuedAt:     p.QueuedAt,
+			StartedAt:    p.StartedAt,
+		},
+	}
+	if !p.StartedAt.IsZero() {
+		entry.Audit.TotalSeconds = p.CompletedAt.Sub(p.StartedAt).Seconds()
+	}
+	return entry
+}
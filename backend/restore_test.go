@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreFromProvenance(t *testing.T) {
+	root := t.TempDir()
+	library := filepath.Join(root, "library")
+	first := filepath.Join(library, "Daft Punk", "One More Time", "One More Time.mkv")
+	second := filepath.Join(library, "Justice - Genesis.flac")
+	writeTestFile(t, first, 4)
+	writeTestFile(t, second, 6)
+	for _, media := range []string{first, second} {
+		item := &QueueItem{
+			ID:          "item",
+			VideoURL:    "https://www.youtube.com/watch?v=FGBhQbmPwH8",
+			AudioSource: "qobuz",
+			Quality:     "best",
+			Notes:       "restored",
+			CreatedAt:   time.Now().Add(-time.Hour),
+		}
+		if err := WriteProvenance(item, &Metadata{Title: filepath.Base(mediaBase(media)), Artist: "Artist"}, media); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second file was renamed together with its sidecar
+	renamed := filepath.Join(library, "Justice - Genesis (Live).flac")
+	if err := os.Rename(second, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(ProvenancePath(second), ProvenancePath(renamed)); err != nil {
+		t.Fatal(err)
+	}
+	// And the first one changed after the download
+	writeTestFile(t, first, 5)
+	writeTestFile(t, mediaBase(renamed)+".nfo", 1)
+
+	history := &History{filePath: filepath.Join(root, "data", "history.json")}
+	history.Add(HistoryEntry{OutputPath: renamed, Title: "Genesis", Status: "complete"})
+	index := NewFileIndex(filepath.Join(root, "data"))
+
+	opts := RestoreOptions{History: history, FileIndex: index, NFO: true, Verify: true, DryRun: true}
+	report, err := RestoreFromProvenance([]string{library}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sidecars != 2 || report.History != 1 || report.Skipped != 1 || report.Indexed != 2 || report.NFOs != 1 || len(report.Errors) != 0 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0] != first {
+		t.Errorf("mismatched = %v, want %s", report.Mismatched, first)
+	}
+	if len(history.GetAll()) != 1 || index.Count() != 0 {
+		t.Fatal("dry run changed the history or the index")
+	}
+
+	opts.DryRun = false
+	if _, err := RestoreFromProvenance([]string{library}, opts); err != nil {
+		t.Fatal(err)
+	}
+	entries := history.GetAll()
+	if len(entries) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(entries))
+	}
+	restored := entries[1] // Completed before the existing entry was added
+	if restored.OutputPath != first || restored.AudioSource != "qobuz" || restored.Notes != "restored" || restored.Status != "complete" {
+		t.Errorf("restored entry = %+v", restored)
+	}
+	if index.Count() != 2 {
+		t.Errorf("index has %d entries, want 2", index.Count())
+	}
+	if _, err := os.Stat(mediaBase(first) + ".nfo"); err != nil {
+		t.Errorf("NFO not written: %v", err)
+	}
+
+	// Running again adds nothing
+	report, err = RestoreFromProvenance([]string{library}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.History != 0 || report.Skipped != 2 || report.NFOs != 0 {
+		t.Errorf("second run = %+v", report)
+	}
+}
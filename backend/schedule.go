@@ -0,0 +1,229 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Daily windows
+// =============================================================================
+
+// DailyWindow is a time-of-day range such as 22:00-07:00 (may wrap past midnight)
+type DailyWindow struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+// ParseDailyWindow parses "HH:MM-HH:MM"; an empty string returns nil
+func ParseDailyWindow(s string) (*DailyWindow, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("time window %q must look like 22:00-07:00", s)
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("time window %q starts and ends at the same time", s)
+	}
+	return &DailyWindow{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether t falls inside the window (local time)
+func (w *DailyWindow) Contains(t time.Time) bool {
+	if w == nil {
+		return false
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextStart returns the next time the window opens after t
+func (w *DailyWindow) NextStart(t time.Time) time.Time {
+	return nextClock(t, w.Start)
+}
+
+// NextEnd returns the next time the window closes after t
+func (w *DailyWindow) NextEnd(t time.Time) time.Time {
+	return nextClock(t, w.End)
+}
+
+func nextClock(t time.Time, offset time.Duration) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	next := midnight.Add(offset)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// =============================================================================
+// Download schedule
+// =============================================================================
+
+// DownloadSchedule restricts when the dispatcher starts new downloads.
+// Downloads already running when the window closes are allowed to finish.
+type DownloadSchedule struct {
+	Windows []DailyWindow
+	Pause   bool // true = windows are blocked hours, false = the only allowed hours
+}
+
+// ParseDownloadSchedule parses Config.Schedule:
+//
+//	"01:00-07:00"               only process the queue between 01:00 and 07:00
+//	"01:00-07:00, 13:00-14:00"  several allowed windows
+//	"pause 17:00-23:00"         process at any time except 17:00-23:00
+//
+// An empty string means no restriction and returns nil.
+func ParseDownloadSchedule(s string) (*DownloadSchedule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil, nil
+	}
+
+	schedule := &DownloadSchedule{}
+	if rest, ok := strings.CutPrefix(s, "pause"); ok {
+		schedule.Pause = true
+		s = strings.TrimSpace(rest)
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		w, err := ParseDailyWindow(part)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil {
+			schedule.Windows = append(schedule.Windows, *w)
+		}
+	}
+	if len(schedule.Windows) == 0 {
+		return nil, fmt.Errorf("schedule %q has no time windows", s)
+	}
+	return schedule, nil
+}
+
+// Allows reports whether new downloads may start at t
+func (s *DownloadSchedule) Allows(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	inWindow := false
+	for i := range s.Windows {
+		if s.Windows[i].Contains(t) {
+			inWindow = true
+			break
+		}
+	}
+	return inWindow != s.Pause
+}
+
+// NextChange returns when Allows(t) next flips, checking every window edge
+func (s *DownloadSchedule) NextChange(t time.Time) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	current := s.Allows(t)
+
+	var edges []time.Time
+	for i := range s.Windows {
+		edges = append(edges, s.Windows[i].NextStart(t), s.Windows[i].NextEnd(t))
+	}
+	var next time.Time
+	for _, edge := range edges {
+		if s.Allows(edge) != current && (next.IsZero() || edge.Before(next)) {
+			next = edge
+		}
+	}
+	return next
+}
+
+// ScheduleStatus reports whether the dispatcher is currently starting downloads
+type ScheduleStatus struct {
+	Schedule      string     `json:"schedule"`                // Config.Schedule, "" = always on
+	Open          bool       `json:"open"`                    // New downloads may start now
+	OverrideUntil *time.Time `json:"overrideUntil,omitempty"` // Manual override in effect until this time
+	NextChange    *time.Time `json:"nextChange,omitempty"`    // When Open next flips (ignoring the override)
+}
+
+// OverrideSchedule ignores Config.Schedule for d, so the queue runs outside
+// its window. A zero or negative d clears the override.
+func (q *Queue) OverrideSchedule(d time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if d <= 0 {
+		q.scheduleOverride = time.Time{}
+		return
+	}
+	q.scheduleOverride = time.Now().Add(d)
+	q.signal()
+}
+
+// GetScheduleStatus returns the current schedule state
+func (q *Queue) GetScheduleStatus() ScheduleStatus {
+	now := time.Now()
+
+	q.mutex.RLock()
+	config := q.configs.Get()
+	override := q.scheduleOverride
+	q.mutex.RUnlock()
+
+	status := ScheduleStatus{Open: true}
+	if config != nil {
+		status.Schedule = config.Schedule
+	}
+
+	schedule, err := ParseDownloadSchedule(status.Schedule)
+	if err != nil {
+		return status
+	}
+	status.Open = schedule.Allows(now)
+	if next := schedule.NextChange(now); !next.IsZero() {
+		status.NextChange = &next
+	}
+	if override.After(now) {
+		status.Open = true
+		status.OverrideUntil = &override
+	}
+	return status
+}
+
+// scheduleOpen is checked by the dispatcher before handing out new work
+func (q *Queue) scheduleOpen(now time.Time) bool {
+	q.mutex.RLock()
+	config := q.configs.Get()
+	override := q.scheduleOverride
+	q.mutex.RUnlock()
+
+	if override.After(now) || config == nil || config.Schedule == "" {
+		return true
+	}
+	schedule, err := ParseDownloadSchedule(config.Schedule)
+	if err != nil {
+		slog.Warn("ignoring invalid download schedule", "value", config.Schedule, "err", err)
+		return true
+	}
+	return schedule.Allows(now)
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDailyWindow(t *testing.T) {
+	w, err := ParseDailyWindow("22:00-07:30")
+	if err != nil {
+		t.Fatalf("ParseDailyWindow failed: %v", err)
+	}
+	day := func(h, m int) time.Time { return time.Date(2024, 5, 1, h, m, 0, 0, time.Local) }
+
+	for _, tc := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{day(23, 0), true},
+		{day(3, 0), true},
+		{day(7, 29), true},
+		{day(7, 30), false},
+		{day(12, 0), false},
+	} {
+		if got := w.Contains(tc.t); got != tc.want {
+			t.Errorf("Contains(%s) = %v, want %v", tc.t.Format("15:04"), got, tc.want)
+		}
+	}
+	if end := w.NextEnd(day(23, 0)); !end.Equal(time.Date(2024, 5, 2, 7, 30, 0, 0, time.Local)) {
+		t.Errorf("NextEnd = %v", end)
+	}
+
+	if w, err := ParseDailyWindow(""); w != nil || err != nil {
+		t.Errorf("empty window = %v, %v", w, err)
+	}
+	for _, bad := range []string{"22:00", "25:00-07:00", "08:00-08:00"} {
+		if _, err := ParseDailyWindow(bad); err == nil {
+			t.Errorf("ParseDailyWindow(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDownloadSchedule(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2024, 5, 1, h, m, 0, 0, time.Local) }
+
+	night, err := ParseDownloadSchedule("01:00-07:00")
+	if err != nil {
+		t.Fatalf("ParseDownloadSchedule failed: %v", err)
+	}
+	if !night.Allows(day(3, 0)) || night.Allows(day(12, 0)) {
+		t.Error("allowed window not enforced")
+	}
+	if next := night.NextChange(day(12, 0)); !next.Equal(time.Date(2024, 5, 2, 1, 0, 0, 0, time.Local)) {
+		t.Errorf("NextChange = %v, want the window opening at 01:00 tomorrow", next)
+	}
+
+	paused, err := ParseDownloadSchedule("Pause 17:00-23:00, 07:00-09:00")
+	if err != nil {
+		t.Fatalf("ParseDownloadSchedule failed: %v", err)
+	}
+	if paused.Allows(day(18, 0)) || paused.Allows(day(8, 0)) || !paused.Allows(day(12, 0)) {
+		t.Error("pause windows not enforced")
+	}
+	if next := paused.NextChange(day(12, 0)); !next.Equal(day(17, 0)) {
+		t.Errorf("NextChange = %v, want 17:00", next)
+	}
+
+	if s, err := ParseDownloadSchedule(""); s != nil || err != nil || !s.Allows(day(0, 0)) {
+		t.Errorf("empty schedule = %v, %v", s, err)
+	}
+	for _, bad := range []string{"pause", "nightly", "01:00-07:00, later"} {
+		if _, err := ParseDownloadSchedule(bad); err == nil {
+			t.Errorf("ParseDownloadSchedule(%q) should fail", bad)
+		}
+	}
+}
+
+func TestQueueScheduleOverride(t *testing.T) {
+	q := newTestQueue()
+	config := GetDefaultConfig()
+	now := time.Now()
+	// A one-minute window that ended an hour ago is closed now
+	start := now.Add(-2 * time.Hour)
+	config.Schedule = start.Format("15:04") + "-" + start.Add(time.Minute).Format("15:04")
+	q.SetConfig(config)
+
+	if q.scheduleOpen(now) || q.GetScheduleStatus().Open {
+		t.Fatal("queue should be closed outside the schedule")
+	}
+
+	q.OverrideSchedule(time.Hour)
+	status := q.GetScheduleStatus()
+	if !q.scheduleOpen(now) || !status.Open || status.OverrideUntil == nil {
+		t.Errorf("override not applied: %+v", status)
+	}
+
+	q.OverrideSchedule(0)
+	if q.scheduleOpen(now) {
+		t.Error("clearing the override should close the queue again")
+	}
+}
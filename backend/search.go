@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Unified search
+// =============================================================================
+
+// Search runs a free-text query against YouTube and Tidal at the same time
+// and returns one ranked list, so the UI can offer a search-first add flow
+// instead of requiring a pasted URL. Videos are paired with their best FLAC
+// match from the audio results; a video that has one ranks above one that
+// does not.
+
+// Search result kinds
+const (
+	SearchKindVideo = "video"
+	SearchKindAudio = "audio"
+)
+
+// Search limits
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 25
+)
+
+// SearchResult is one candidate of a unified search
+type SearchResult struct {
+	Kind      string  `json:"kind"`     // "video" or "audio"
+	Platform  string  `json:"platform"` // youtube, tidal
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist"`
+	Album     string  `json:"album,omitempty"`
+	Duration  float64 `json:"duration"` // in seconds
+	Thumbnail string  `json:"thumbnail,omitempty"`
+	URL       string  `json:"url"`
+	ISRC      string  `json:"isrc,omitempty"`
+	Score     float64 `json:"score"` // 0.0 to 1.0, results are sorted by it
+
+	// Videos only: the best audio result for this video, if it is a valid match
+	MatchedAudioURL   string  `json:"matchedAudioUrl,omitempty"`
+	MatchedConfidence float64 `json:"matchedConfidence,omitempty"`
+}
+
+// SearchResponse is the result of UnifiedSearch
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Errors  []string       `json:"errors,omitempty"` // Per-source failures; the other source's results are still returned
+}
+
+// Search backends, replaced in tests
+var (
+	searchYouTubeFunc = SearchYouTubeWithCookies
+	searchTidalFunc   = func(query string) ([]TidalTrackResponse, error) {
+		return NewTidalHifiService(httpClient).SearchTracks(query)
+	}
+)
+
+// UnifiedSearch queries YouTube and Tidal concurrently and merges the results,
+// ranked by relevance to the query. limit caps each source (<= 0 = default).
+// It only fails when both sources do.
+func UnifiedSearch(query string, limit int, cookiesBrowser string) (*SearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	var (
+		wg                 sync.WaitGroup
+		videos             []VideoInfo
+		tracks             []TidalTrackResponse
+		videoErr, audioErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		videos, videoErr = searchYouTubeFunc(query, limit, cookiesBrowser)
+	}()
+	go func() {
+		defer wg.Done()
+		tracks, audioErr = searchTidalFunc(query)
+	}()
+	wg.Wait()
+
+	if videoErr != nil && audioErr != nil {
+		return nil, fmt.Errorf("search failed: youtube: %v; tidal: %v", videoErr, audioErr)
+	}
+
+	resp := &SearchResponse{Query: query}
+	if videoErr != nil {
+		slog.Warn("youtube search failed", "query", query, "err", videoErr)
+		resp.Errors = append(resp.Errors, fmt.Sprintf("youtube: %v", videoErr))
+	}
+	if audioErr != nil {
+		slog.Warn("tidal search failed", "query", query, "err", audioErr)
+		resp.Errors = append(resp.Errors, fmt.Sprintf("tidal: %v", audioErr))
+	}
+	if len(tracks) > limit {
+		tracks = tracks[:limit]
+	}
+
+	resp.Results = rankSearchResults(query, videos, tracks)
+	return resp, nil
+}
+
+// rankSearchResults converts both result sets to SearchResults, pairs each
+// video with its best audio match and sorts everything by score
+func rankSearchResults(query string, videos []VideoInfo, tracks []TidalTrackResponse) []SearchResult {
+	candidates := make([]AudioCandidate, 0, len(tracks))
+	results := make([]SearchResult, 0, len(videos)+len(tracks))
+
+	for _, track := range tracks {
+		artist := track.Artist.Name
+		if artist == "" && len(track.Artists) > 0 {
+			artist = track.Artists[0].Name
+		}
+		trackURL := fmt.Sprintf("https://tidal.com/browse/track/%d", track.ID)
+		result := SearchResult{
+			Kind:     SearchKindAudio,
+			Platform: "tidal",
+			ID:       fmt.Sprintf("%d", track.ID),
+			Title:    track.Title,
+			Artist:   artist,
+			Album:    track.Album.Title,
+			Duration: float64(track.Duration),
+			URL:      trackURL,
+			ISRC:     track.ISRC,
+			Score:    searchRelevance(query, track.Title, artist),
+		}
+		if track.Album.Cover != "" {
+			result.Thumbnail = fmt.Sprintf("https://resources.tidal.com/images/%s/640x640.jpg", strings.ReplaceAll(track.Album.Cover, "-", "/"))
+		}
+		results = append(results, result)
+		candidates = append(candidates, AudioCandidate{
+			Platform: "tidal",
+			URL:      trackURL,
+			Title:    track.Title,
+			Artist:   artist,
+			Album:    track.Album.Title,
+			ISRC:     track.ISRC,
+			Duration: float64(track.Duration),
+			Priority: 1,
+		})
+	}
+
+	opts := DefaultMatchOptions()
+	for i := range videos {
+		video := &videos[i]
+		result := SearchResult{
+			Kind:      SearchKindVideo,
+			Platform:  "youtube",
+			ID:        video.ID,
+			Title:     video.Title,
+			Artist:    video.Artist,
+			Duration:  video.Duration,
+			Thumbnail: video.Thumbnail,
+			URL:       video.URL,
+			ISRC:      video.ISRC,
+		}
+
+		var best *MatchResult
+		for j := range candidates {
+			match := matchSingle(video, &candidates[j], opts)
+			if match.IsValid && (best == nil || match.Confidence > best.Confidence) {
+				best = &match
+			}
+		}
+
+		// A video only scores fully when it can actually be paired with FLAC
+		relevance := searchRelevance(query, video.Title, video.Artist)
+		if best != nil {
+			result.MatchedAudioURL = best.Audio.URL
+			result.MatchedConfidence = best.Confidence
+			result.Score = relevance*0.7 + best.Confidence*0.3
+		} else {
+			result.Score = relevance * 0.7
+		}
+		results = append(results, result)
+	}
+
+	// Stable: equal scores keep source order (YouTube and Tidal's own ranking)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// searchRelevance scores how well a result matches a free-text query, which
+// may name the title alone or the artist and title in either order. Video
+// titles that already carry the artist ("Artist - Title") match on the title.
+func searchRelevance(query, title, artist string) float64 {
+	score := ComputeTitleSimilarity(query, title)
+	if artist != "" {
+		score = max(score,
+			ComputeTitleSimilarity(query, artist+" "+title),
+			ComputeTitleSimilarity(query, title+" "+artist),
+		)
+	}
+	return score
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func stubSearch(t *testing.T, videos []VideoInfo, videoErr error, tracks []TidalTrackResponse, audioErr error) {
+	t.Helper()
+	origYouTube, origTidal := searchYouTubeFunc, searchTidalFunc
+	t.Cleanup(func() { searchYouTubeFunc, searchTidalFunc = origYouTube, origTidal })
+	searchYouTubeFunc = func(query string, maxResults int, cookiesBrowser string) ([]VideoInfo, error) {
+		return videos, videoErr
+	}
+	searchTidalFunc = func(query string) ([]TidalTrackResponse, error) {
+		return tracks, audioErr
+	}
+}
+
+func tidalTrack(id int, title, artist string, duration int) TidalTrackResponse {
+	var track TidalTrackResponse
+	track.ID = id
+	track.Title = title
+	track.Artist.Name = artist
+	track.Duration = duration
+	track.Album.Cover = "aa-bb"
+	return track
+}
+
+func TestUnifiedSearch(t *testing.T) {
+	stubSearch(t,
+		[]VideoInfo{
+			{ID: "other", Title: "Completely Unrelated Vlog", Artist: "Someone", Duration: 600, URL: "https://www.youtube.com/watch?v=other"},
+			{ID: "mv", Title: "Blinding Lights (Official Video)", Artist: "The Weeknd", Duration: 201, Thumbnail: "thumb.jpg", URL: "https://www.youtube.com/watch?v=mv"},
+		}, nil,
+		[]TidalTrackResponse{tidalTrack(42, "Blinding Lights", "The Weeknd", 200)}, nil,
+	)
+
+	resp, err := UnifiedSearch("  the weeknd blinding lights ", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Query != "the weeknd blinding lights" || len(resp.Results) != 3 || len(resp.Errors) != 0 {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	top := resp.Results[0]
+	if top.Kind != SearchKindAudio || top.URL != "https://tidal.com/browse/track/42" || top.Thumbnail != "https://resources.tidal.com/images/aa/bb/640x640.jpg" {
+		t.Errorf("top result = %+v", top)
+	}
+	video := resp.Results[1]
+	if video.ID != "mv" || video.MatchedAudioURL != top.URL || video.MatchedConfidence == 0 {
+		t.Errorf("video should be paired with the Tidal track: %+v", video)
+	}
+	if last := resp.Results[2]; last.ID != "other" || last.MatchedAudioURL != "" {
+		t.Errorf("unrelated video should rank last: %+v", last)
+	}
+}
+
+func TestUnifiedSearchPartialFailure(t *testing.T) {
+	stubSearch(t,
+		[]VideoInfo{{ID: "mv", Title: "Song", Artist: "Artist", Duration: 180}}, nil,
+		nil, errors.New("tidal down"),
+	)
+	resp, err := UnifiedSearch("artist song", 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || len(resp.Errors) != 1 {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	stubSearch(t, nil, errors.New("yt-dlp missing"), nil, errors.New("tidal down"))
+	if _, err := UnifiedSearch("artist song", 5, ""); err == nil {
+		t.Error("search should fail when both sources fail")
+	}
+	if _, err := UnifiedSearch("   ", 5, ""); err == nil {
+		t.Error("empty query should fail")
+	}
+}
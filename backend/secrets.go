@@ -0,0 +1,606 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encrypted secrets storage. API keys, service credentials and uploaded
+// cookies are kept in secrets.enc in the data dir, sealed with AES-256-GCM.
+// The key comes from the OS keychain on desktop, from a passphrase
+// (YOUFLAC_SECRETS_PASSPHRASE) in server mode, or, when neither is available,
+// from a private key file next to the store.
+
+// Secret names
+const (
+	SecretOdesliAPIKey      = "odesli_api_key"
+	SecretLastFMAPIKey      = "lastfm_api_key"
+	SecretYouTubeCookies    = "youtube_cookies" // Netscape cookies.txt content
+	SecretSMTPPassword      = "smtp_password"
+	SecretS3SecretAccessKey = "s3_secret_access_key"
+	SecretWebDAVPassword    = "webdav_password"
+	SecretYouTubeAPIKey     = "youtube_api_key"
+	SecretMQTTPassword      = "mqtt_password"
+	SecretDiscordBotToken   = "discord_bot_token"
+	SecretTelegramBotToken  = "telegram_bot_token"
+	SecretShortcutKey       = "shortcut_key"
+)
+
+// KnownSecrets lists the secret names accepted by the API. Each one is read
+// back by a service through its config field (configSecretFields) or, for
+// cookies, by yt-dlp; the audio sources need no account credentials.
+var KnownSecrets = []string{
+	SecretOdesliAPIKey,
+	SecretLastFMAPIKey,
+	SecretYouTubeCookies,
+	SecretSMTPPassword,
+	SecretS3SecretAccessKey,
+	SecretWebDAVPassword,
+	SecretYouTubeAPIKey,
+	SecretMQTTPassword,
+	SecretDiscordBotToken,
+	SecretTelegramBotToken,
+	SecretShortcutKey,
+}
+
+// SecretsPassphraseEnv holds the passphrase used in server mode
+const SecretsPassphraseEnv = "YOUFLAC_SECRETS_PASSPHRASE"
+
+const (
+	secretsFileName = "secrets.enc"
+	secretsKeyFile  = "secrets.key"
+	secretsVersion  = 1
+
+	keychainService = "youflac"
+	keychainAccount = "secrets-key"
+
+	pbkdf2Iterations = 600000
+)
+
+// KeyProvider derives the store encryption key
+type KeyProvider interface {
+	// Name identifies the key source; it is recorded in the store file
+	Name() string
+	// Key returns a 32-byte key for the given per-file salt
+	Key(salt []byte) ([]byte, error)
+}
+
+// secretsFile is the on-disk format; byte slices are base64 in JSON
+type secretsFile struct {
+	Version   int    `json:"version"`
+	KeySource string `json:"keySource"`
+	Salt      []byte `json:"salt"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// SecretStore is an encrypted name -> value store
+type SecretStore struct {
+	mu     sync.Mutex
+	path   string
+	keys   KeyProvider
+	values map[string]string
+	loaded bool
+}
+
+// NewSecretStore creates a store in dataDir using the given key provider
+func NewSecretStore(dataDir string, keys KeyProvider) *SecretStore {
+	return &SecretStore{
+		path: filepath.Join(dataDir, secretsFileName),
+		keys: keys,
+	}
+}
+
+var (
+	secretStoresMu sync.Mutex
+	secretStores   = make(map[string]*SecretStore)
+)
+
+// DefaultSecretStore returns the shared store for the current data dir
+func DefaultSecretStore() *SecretStore {
+	dataDir := GetDataPathWithEnv()
+
+	secretStoresMu.Lock()
+	defer secretStoresMu.Unlock()
+	if store, ok := secretStores[dataDir]; ok {
+		return store
+	}
+	store := NewSecretStore(dataDir, DefaultKeyProvider(dataDir))
+	secretStores[dataDir] = store
+	return store
+}
+
+// Exists reports whether the store file has been created
+func (s *SecretStore) Exists() bool {
+	return fileExists(s.path)
+}
+
+// KeySource returns the name of the key provider
+func (s *SecretStore) KeySource() string {
+	return s.keys.Name()
+}
+
+// Get returns a secret, or "" if it isn't set
+func (s *SecretStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	return s.values[name], nil
+}
+
+// Set stores a secret; an empty value deletes it
+func (s *SecretStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if value == "" {
+		if _, ok := s.values[name]; !ok {
+			return nil
+		}
+		delete(s.values, name)
+	} else {
+		if s.values[name] == value {
+			return nil
+		}
+		s.values[name] = value
+	}
+	return s.save()
+}
+
+// Delete removes a secret
+func (s *SecretStore) Delete(name string) error {
+	return s.Set(name, "")
+}
+
+// Names returns the names of the stored secrets, sorted
+func (s *SecretStore) Names() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load decrypts the store file once; a missing file is an empty store
+func (s *SecretStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.values = make(map[string]string)
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	var file secretsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	if file.KeySource != s.keys.Name() {
+		return fmt.Errorf("secrets were encrypted with %q but the current key source is %q", file.KeySource, s.keys.Name())
+	}
+
+	gcm, err := s.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, []byte(file.KeySource))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets (wrong key or passphrase?)")
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return fmt.Errorf("failed to parse secrets: %w", err)
+	}
+	s.values = values
+	s.loaded = true
+	return nil
+}
+
+// save encrypts the values with a fresh salt and nonce and replaces the file atomically
+func (s *SecretStore) save() error {
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+
+	file := secretsFile{
+		Version:   secretsVersion,
+		KeySource: s.keys.Name(),
+		Salt:      make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := s.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plain, []byte(file.KeySource))
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	return nil
+}
+
+func (s *SecretStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := s.keys.Key(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// =============================================================================
+// Key providers
+// =============================================================================
+
+// DefaultKeyProvider picks the passphrase in server mode, then the OS
+// keychain, then a key file in dataDir
+func DefaultKeyProvider(dataDir string) KeyProvider {
+	if passphrase := os.Getenv(SecretsPassphraseEnv); passphrase != "" {
+		return PassphraseKeyProvider(passphrase)
+	}
+	storePath := filepath.Join(dataDir, secretsFileName)
+	if keychainAvailable() {
+		return &masterKeyProvider{name: "keychain", store: keychainMasterKey{}, storePath: storePath}
+	}
+	return &masterKeyProvider{name: "keyfile", store: fileMasterKey{path: filepath.Join(dataDir, secretsKeyFile)}, storePath: storePath}
+}
+
+// PassphraseKeyProvider derives the key from a passphrase with PBKDF2-SHA256
+type PassphraseKeyProvider string
+
+func (p PassphraseKeyProvider) Name() string { return "passphrase" }
+
+func (p PassphraseKeyProvider) Key(salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, string(p), salt, pbkdf2Iterations, 32)
+}
+
+// masterKeyStore loads or creates a random 32-byte master key
+type masterKeyStore interface {
+	load() ([]byte, error) // returns nil, nil when no key exists yet
+	store(key []byte) error
+}
+
+// masterKeyProvider expands a random master key per salt with HKDF
+type masterKeyProvider struct {
+	name      string
+	store     masterKeyStore
+	storePath string // secrets.enc; no new key is generated while it exists
+
+	mu     sync.Mutex
+	master []byte
+}
+
+func (m *masterKeyProvider) Name() string { return m.name }
+
+func (m *masterKeyProvider) Key(salt []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.master == nil {
+		master, err := m.store.load()
+		if err != nil {
+			return nil, err
+		}
+		if master == nil {
+			// A new key would make the existing store undecryptable
+			if m.storePath != "" && fileExists(m.storePath) {
+				return nil, fmt.Errorf("the %s has no secrets key but %s exists; restore the key or delete the file to start over", m.name, m.storePath)
+			}
+			master = make([]byte, 32)
+			if _, err := rand.Read(master); err != nil {
+				return nil, fmt.Errorf("failed to generate master key: %w", err)
+			}
+			if err := m.store.store(master); err != nil {
+				return nil, err
+			}
+		}
+		m.master = master
+	}
+	return hkdf.Key(sha256.New, m.master, salt, "youflac secrets", 32)
+}
+
+// fileMasterKey keeps the master key hex-encoded in a 0600 file
+type fileMasterKey struct {
+	path string
+}
+
+func (f fileMasterKey) load() ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return decodeMasterKey(string(data))
+}
+
+func (f fileMasterKey) store(key []byte) error {
+	slog.Warn("no OS keychain available, storing the secrets key in a file; set "+SecretsPassphraseEnv+" to use a passphrase", "path", f.path)
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(f.path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// keychainMasterKey keeps the master key in the macOS Keychain or the
+// freedesktop Secret Service (via secret-tool)
+type keychainMasterKey struct{}
+
+// keychainAvailable reports whether a supported keychain CLI is installed
+func keychainAvailable() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("security")
+		return err == nil
+	case "linux":
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return false
+		}
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	}
+	return false
+}
+
+// securityItemNotFound is the exit status of security(1) for a missing item
+const securityItemNotFound = 44
+
+func (keychainMasterKey) load() ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = newCommand("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	} else {
+		cmd = newCommand("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		// Only "item not found" means there is no key yet. A locked
+		// keychain or denied access must not lead to a new key.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && keychainItemMissing(exitErr, out) {
+			return nil, nil
+		}
+		if exitErr != nil && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("keychain lookup failed: %w - %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("keychain lookup failed: %w", err)
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return nil, nil
+	}
+	return decodeMasterKey(string(out))
+}
+
+// keychainItemMissing reports whether a failed lookup means the entry
+// doesn't exist: status 44 from security, status 1 without any output from
+// secret-tool
+func keychainItemMissing(exitErr *exec.ExitError, out []byte) bool {
+	if runtime.GOOS == "darwin" {
+		return exitErr.ExitCode() == securityItemNotFound
+	}
+	return exitErr.ExitCode() == 1 && len(bytes.TrimSpace(out)) == 0 && len(bytes.TrimSpace(exitErr.Stderr)) == 0
+}
+
+func (keychainMasterKey) store(key []byte) error {
+	encoded := hex.EncodeToString(key)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// -w without a value makes security read the password (twice) from
+		// stdin, keeping the key off the command line
+		cmd = newCommand("security", "add-generic-password", "-U", "-s", keychainService, "-a", keychainAccount, "-w")
+		cmd.Stdin = strings.NewReader(encoded + "\n" + encoded + "\n")
+	} else {
+		cmd = newCommand("secret-tool", "store", "--label=YouFlac secrets key", "service", keychainService, "account", keychainAccount)
+		cmd.Stdin = strings.NewReader(encoded)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keychain store failed: %v - %s", err, stderr.String())
+	}
+	return nil
+}
+
+func decodeMasterKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("stored secrets key is corrupt")
+	}
+	return key, nil
+}
+
+// SetSecret stores a known secret in the default store; an empty value deletes it.
+// If config is non-nil, the matching config field is updated too.
+func SetSecret(name, value string, config *Config) error {
+	if !containsString(KnownSecrets, name) {
+		return fmt.Errorf("unknown secret %q", name)
+	}
+	if name == SecretYouTubeCookies && value != "" {
+		if err := ValidateCookiesFile(value); err != nil {
+			return err
+		}
+	}
+	if err := DefaultSecretStore().Set(name, value); err != nil {
+		return err
+	}
+	if config != nil {
+		for _, f := range configSecretFields {
+			if f.name == name {
+				*f.field(config) = value
+			}
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Config and yt-dlp integration
+// =============================================================================
+
+// configSecretFields maps secret names to the Config fields they back
+var configSecretFields = []struct {
+	name  string
+	field func(*Config) *string
+}{
+	{SecretOdesliAPIKey, func(c *Config) *string { return &c.OdesliAPIKey }},
+	{SecretLastFMAPIKey, func(c *Config) *string { return &c.LastFMAPIKey }},
+	{SecretSMTPPassword, func(c *Config) *string { return &c.SMTPPassword }},
+	{SecretS3SecretAccessKey, func(c *Config) *string { return &c.S3SecretAccessKey }},
+	{SecretWebDAVPassword, func(c *Config) *string { return &c.WebDAVPassword }},
+	{SecretYouTubeAPIKey, func(c *Config) *string { return &c.YouTubeAPIKey }},
+	{SecretMQTTPassword, func(c *Config) *string { return &c.MQTTPassword }},
+	{SecretDiscordBotToken, func(c *Config) *string { return &c.DiscordBotToken }},
+	{SecretTelegramBotToken, func(c *Config) *string { return &c.TelegramBotToken }},
+	{SecretShortcutKey, func(c *Config) *string { return &c.ShortcutKey }},
+}
+
+// FillConfigSecrets sets empty secret fields from the store. The store is
+// only opened if it exists, so configs without secrets never touch the keychain.
+func FillConfigSecrets(config *Config) {
+	store := DefaultSecretStore()
+	if !store.Exists() {
+		return
+	}
+	for _, f := range configSecretFields {
+		field := f.field(config)
+		if *field != "" {
+			continue
+		}
+		value, err := store.Get(f.name)
+		if err != nil {
+			slog.Warn("failed to read secret", "name", f.name, "err", err)
+			return
+		}
+		*field = value
+	}
+}
+
+// RedactSecrets returns a copy of config with every secret field blanked,
+// for configs sent over the API. Saving it back keeps the stored secrets.
+func RedactSecrets(config *Config) *Config {
+	redacted := config.Clone()
+	for _, f := range configSecretFields {
+		*f.field(redacted) = ""
+	}
+	return redacted
+}
+
+// stripConfigSecrets moves secret fields into the store and returns a copy
+// of config without them, ready to be written as plain JSON. Empty fields
+// leave the stored secret unchanged; only SetSecret with "" deletes one.
+func stripConfigSecrets(config *Config) (*Config, error) {
+	stripped := *config
+	store := DefaultSecretStore()
+	for _, f := range configSecretFields {
+		field := f.field(&stripped)
+		if *field == "" {
+			continue
+		}
+		if err := store.Set(f.name, *field); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", f.name, err)
+		}
+		*field = ""
+	}
+	return &stripped, nil
+}
+
+// storedCookiesFile writes uploaded YouTube cookies to a private temp file
+// for yt-dlp's --cookies. Returns "" if no cookies are stored.
+func storedCookiesFile() (path string, cleanup func(), err error) {
+	cleanup = func() {}
+	store := DefaultSecretStore()
+	if !store.Exists() {
+		return "", cleanup, nil
+	}
+	cookies, err := store.Get(SecretYouTubeCookies)
+	if err != nil || cookies == "" {
+		return "", cleanup, err
+	}
+
+	f, err := os.CreateTemp(GetTempDirectory(), "youflac-cookies-*.txt")
+	if err != nil {
+		return "", cleanup, fmt.Errorf("failed to create cookies file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(cookies); err != nil {
+		os.Remove(f.Name())
+		return "", cleanup, fmt.Errorf("failed to write cookies file: %w", err)
+	}
+	name := f.Name()
+	return name, func() { os.Remove(name) }, nil
+}
+
+// ValidateCookiesFile checks that content looks like a Netscape cookies.txt export
+func ValidateCookiesFile(content string) error {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#HttpOnly_")) {
+			continue
+		}
+		if len(strings.Split(line, "\t")) >= 7 {
+			return nil
+		}
+	}
+	return fmt.Errorf("not a Netscape cookies.txt file (expected tab-separated cookie lines)")
+}
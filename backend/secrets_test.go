@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSecretStore(t *testing.T, dir string) *SecretStore {
+	t.Helper()
+	return NewSecretStore(dir, &masterKeyProvider{
+		name:      "keyfile",
+		store:     fileMasterKey{path: filepath.Join(dir, secretsKeyFile)},
+		storePath: filepath.Join(dir, secretsFileName),
+	})
+}
+
+func TestSecretStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := newTestSecretStore(t, dir)
+
+	if store.Exists() {
+		t.Fatal("new store should not exist on disk")
+	}
+	if err := store.Set(SecretOdesliAPIKey, "odesli-key-value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, secretsFileName))
+	if strings.Contains(string(data), "odesli-key-value") {
+		t.Error("secret was written in plaintext")
+	}
+	if stat, _ := os.Stat(filepath.Join(dir, secretsFileName)); stat.Mode().Perm() != 0600 {
+		t.Errorf("secrets file mode = %v, want 0600", stat.Mode().Perm())
+	}
+
+	// A fresh store with the same key file decrypts it
+	reopened := newTestSecretStore(t, dir)
+	if got, err := reopened.Get(SecretOdesliAPIKey); err != nil || got != "odesli-key-value" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	if err := reopened.Delete(SecretOdesliAPIKey); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if names, _ := newTestSecretStore(t, dir).Names(); len(names) != 0 {
+		t.Errorf("expected no secrets after delete, got %v", names)
+	}
+}
+
+func TestSecretStore_LostKeyIsNotReplaced(t *testing.T) {
+	dir := t.TempDir()
+	if err := newTestSecretStore(t, dir).Set(SecretOdesliAPIKey, "odesli-key-value"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, secretsKeyFile))
+
+	if _, err := newTestSecretStore(t, dir).Get(SecretOdesliAPIKey); err == nil {
+		t.Error("expected an error for a store whose key is gone")
+	}
+	if fileExists(filepath.Join(dir, secretsKeyFile)) {
+		t.Error("a new key was generated while secrets.enc exists")
+	}
+}
+
+func TestSecretStore_WrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewSecretStore(dir, PassphraseKeyProvider("correct horse")).Set(SecretLastFMAPIKey, "lastfm-key"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if _, err := NewSecretStore(dir, PassphraseKeyProvider("wrong")).Get(SecretLastFMAPIKey); err == nil {
+		t.Error("expected decryption to fail with the wrong passphrase")
+	}
+	if _, err := newTestSecretStore(t, dir).Get(SecretLastFMAPIKey); err == nil || !strings.Contains(err.Error(), "key source") {
+		t.Errorf("expected key source mismatch error, got %v", err)
+	}
+	if got, err := NewSecretStore(dir, PassphraseKeyProvider("correct horse")).Get(SecretLastFMAPIKey); err != nil || got != "lastfm-key" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+}
+
+func TestConfigSecrets_StrippedFromJSON(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("CONFIG_DIR", filepath.Join(home, "data"))
+	t.Setenv(SecretsPassphraseEnv, "test passphrase")
+
+	config := GetDefaultConfig()
+	config.LastFMAPIKey = "lastfm-secret"
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if config.LastFMAPIKey != "lastfm-secret" {
+		t.Error("SaveConfig must not modify the caller's config")
+	}
+
+	data, _ := os.ReadFile(GetConfigPath())
+	if strings.Contains(string(data), "lastfm-secret") {
+		t.Error("API key was written to config.json")
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.LastFMAPIKey != "lastfm-secret" {
+		t.Errorf("LastFMAPIKey = %q, want it restored from the secret store", loaded.LastFMAPIKey)
+	}
+}
+
+func TestStoredCookiesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CONFIG_DIR", home)
+	t.Setenv(SecretsPassphraseEnv, "test passphrase")
+
+	if path, cleanup, err := storedCookiesFile(); path != "" || err != nil {
+		t.Fatalf("expected no cookies file without a store, got %q %v", path, err)
+	} else {
+		cleanup()
+	}
+
+	cookies := "# Netscape HTTP Cookie File\n.youtube.com\tTRUE\t/\tTRUE\t0\tSID\tabc\n"
+	if err := SetSecret(SecretYouTubeCookies, cookies, nil); err != nil {
+		t.Fatalf("SetSecret failed: %v", err)
+	}
+
+	args, cleanup, err := ytdlpCookieArgs("")
+	if err != nil || len(args) != 2 || args[0] != "--cookies" {
+		t.Fatalf("ytdlpCookieArgs = %v, %v", args, err)
+	}
+	data, _ := os.ReadFile(args[1])
+	if string(data) != cookies {
+		t.Error("cookies file content mismatch")
+	}
+	cleanup()
+	if fileExists(args[1]) {
+		t.Error("cleanup should remove the temporary cookies file")
+	}
+
+	// A configured browser takes precedence
+	if args, cleanup, _ := ytdlpCookieArgs("firefox"); len(args) != 2 || args[0] != "--cookies-from-browser" {
+		t.Errorf("expected browser cookies, got %v", args)
+	} else {
+		cleanup()
+	}
+}
+
+func TestSetSecret_Rejects(t *testing.T) {
+	if err := SetSecret("not_a_secret", "x", nil); err == nil {
+		t.Error("expected unknown secret name to be rejected")
+	}
+	if err := SetSecret(SecretYouTubeCookies, "just some text", nil); err == nil {
+		t.Error("expected invalid cookies file to be rejected")
+	}
+}
@@ -0,0 +1,185 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeServer serves content with range support and records the Range
+// header of every request
+func rangeServer(t *testing.T, content []byte) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "track.flac", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+// download fetches url to out over the given number of connections
+func download(client *http.Client, url, out string, connections int, want ExpectedFile) error {
+	d := &Downloader{Client: client, Connections: connections}
+	return d.Download(context.Background(), url, out, want)
+}
+
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func TestSplitSegments(t *testing.T) {
+	segments := splitSegments(10, 3)
+	want := [][2]int64{{0, 2}, {3, 5}, {6, 9}}
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %v", segments)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segments = %v, want %v", segments, want)
+		}
+	}
+	if got := splitSegments(2, 8); len(got) != 2 {
+		t.Errorf("2 bytes in %d segments, want 2", len(got))
+	}
+}
+
+func TestDownloader_Segmented(t *testing.T) {
+	content := testContent(minSegmentedSize + 12345)
+	ts, requests := rangeServer(t, content)
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 4, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	got, _ := os.ReadFile(out)
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, content differs", len(got))
+	}
+	// Probe plus one request per segment
+	if n := len(requests()); n != 5 {
+		t.Errorf("%d requests, want 5: %v", n, requests())
+	}
+	for _, leftover := range []string{out + ".part0", out + ".parts.json"} {
+		if _, err := os.Stat(leftover); err == nil {
+			t.Errorf("%s left behind", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestDownloader_SingleConnection(t *testing.T) {
+	content := testContent(minSegmentedSize + 1)
+	ts, requests := rangeServer(t, content)
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 1, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got := requests(); len(got) != 1 || got[0] != "" {
+		t.Errorf("requests = %q, want one plain GET", got)
+	}
+
+	// Small files are not split
+	small := filepath.Join(t.TempDir(), "small.flac")
+	ts, requests = rangeServer(t, content[:1000])
+	if err := download(ts.Client(), ts.URL, small, 4, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got := requests(); len(got) != 2 || got[1] != "" {
+		t.Errorf("requests = %q, want probe and one plain GET", got)
+	}
+}
+
+func TestDownloader_NoRangeSupport(t *testing.T) {
+	content := testContent(minSegmentedSize + 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content) // Ignores Range
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 4, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes, content differs", len(got))
+	}
+}
+
+func TestDownloader_ResumesSegments(t *testing.T) {
+	content := testContent(minSegmentedSize * 2)
+	ts, requests := rangeServer(t, content)
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	// An earlier attempt left segment 0 half done and segment 1 complete
+	size := int64(len(content))
+	segments := splitSegments(size, 2)
+	state, _ := json.Marshal(segmentState{Size: size, Segments: segments})
+	os.WriteFile(out+".parts.json", state, 0644)
+	os.WriteFile(out+".part0", content[:1000], 0644)
+	os.WriteFile(out+".part1", content[segments[1][0]:], 0644)
+
+	if err := download(ts.Client(), ts.URL, out, 8, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, content differs", len(got))
+	}
+	got := requests()
+	if len(got) != 2 || !strings.HasPrefix(got[1], "bytes=1000-") {
+		t.Errorf("requests = %q, want probe and the rest of segment 0 only", got)
+	}
+}
+
+func TestDownloader_RetriesBrokenSegment(t *testing.T) {
+	content := testContent(minSegmentedSize + 500)
+	size, half := len(content), len(content)/2
+	var mu sync.Mutex
+	broken := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		breakNow := !broken && r.Header.Get("Range") == fmt.Sprintf("bytes=0-%d", half-1)
+		broken = broken || breakNow
+		mu.Unlock()
+		if breakNow {
+			// Promise the whole segment, send 100 bytes and hang up
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", half-1, size))
+			w.Header().Set("Content-Length", fmt.Sprint(half))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[:100])
+			return
+		}
+		http.ServeContent(w, r, "track.flac", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	out := filepath.Join(t.TempDir(), "track.flac")
+
+	if err := download(ts.Client(), ts.URL, out, 2, ExpectedFile{}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes, content differs", len(got))
+	}
+	if !broken {
+		t.Error("segment was never broken off")
+	}
+}
@@ -0,0 +1,185 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// First-run setup. The frontend onboarding wizard uses these checks to walk
+// the user through missing binaries, the output folder and service
+// reachability before writing the first config file.
+
+// BinaryStatus describes one external tool the app shells out to
+type BinaryStatus struct {
+	Name     string `json:"name"`
+	Found    bool   `json:"found"`
+	Required bool   `json:"required"`
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Hint     string `json:"hint,omitempty"` // How to install it when missing
+}
+
+// SetupStatus is everything the wizard needs to decide which steps to show
+type SetupStatus struct {
+	NeedsSetup         bool           `json:"needsSetup"`   // No config yet, or a required binary is missing
+	ConfigExists       bool           `json:"configExists"` // A config file was found on disk
+	ConfigPath         string         `json:"configPath"`
+	Binaries           []BinaryStatus `json:"binaries"`
+	SuggestedOutputDir string         `json:"suggestedOutputDir"`
+	OutputDirWritable  bool           `json:"outputDirWritable"`
+	OutputDirError     string         `json:"outputDirError,omitempty"`
+}
+
+// setupBinary describes how to locate and version-check a tool
+type setupBinary struct {
+	name        string
+	required    bool
+	path        func() string
+	versionFlag string
+	hint        string
+}
+
+var setupBinaries = []setupBinary{
+	{"ffmpeg", true, GetFFmpegPath, "-version", "Install FFmpeg from https://ffmpeg.org/download.html or your package manager"},
+	{"ffprobe", true, GetFFprobePath, "-version", "ffprobe ships with FFmpeg"},
+	{"yt-dlp", true, func() string { return "yt-dlp" }, "--version", "Install yt-dlp with `pip install yt-dlp` or from https://github.com/yt-dlp/yt-dlp"},
+	{"mkvmerge", false, GetMKVMergePath, "--version", "Optional: install MKVToolNix to use the mkvmerge mux backend"},
+}
+
+// binaryVersionTimeout bounds each version check so a hung binary can't stall setup
+const binaryVersionTimeout = 5 * time.Second
+
+// DetectBinaries checks every external tool and reports its path and version
+func DetectBinaries() []BinaryStatus {
+	statuses := make([]BinaryStatus, 0, len(setupBinaries))
+	for _, b := range setupBinaries {
+		statuses = append(statuses, detectBinary(b.name, b.path(), b.versionFlag, b.required, b.hint))
+	}
+	return statuses
+}
+
+// detectBinary resolves a binary and reads the first line of its version output
+func detectBinary(name, path, versionFlag string, required bool, hint string) BinaryStatus {
+	status := BinaryStatus{Name: name, Required: required}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		status.Hint = hint
+		return status
+	}
+	status.Path = resolved
+
+	ctx, cancel := context.WithTimeout(context.Background(), binaryVersionTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := newCommandContext(ctx, resolved, versionFlag)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		status.Hint = hint
+		return status
+	}
+
+	status.Found = true
+	if line, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n"); line != "" {
+		status.Version = strings.TrimSpace(line)
+	}
+	return status
+}
+
+// SuggestOutputDirectory returns the output folder to pre-fill in the wizard
+func SuggestOutputDirectory() string {
+	if config, err := LoadConfig(); err == nil && config.OutputDirectory != "" {
+		return config.OutputDirectory
+	}
+	return GetDefaultOutputDirectory()
+}
+
+// TestOutputDirectory checks that path is allowed, creates it if needed and
+// verifies a file can be written to it
+func TestOutputDirectory(path string) error {
+	if path == "" {
+		return fmt.Errorf("output directory is empty")
+	}
+	if err := ValidateOutputDirectory(path); err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+		return fmt.Errorf("%s is a file, not a directory", path)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(path, ".youflac-write-test-*")
+	if err != nil {
+		return fmt.Errorf("output directory is not writable: %w", err)
+	}
+	name := f.Name()
+	_, writeErr := f.WriteString("youflac")
+	f.Close()
+	os.Remove(name)
+	if writeErr != nil {
+		return fmt.Errorf("output directory is not writable: %w", writeErr)
+	}
+	return nil
+}
+
+// GetSetupStatus gathers the state shown on the first wizard screen
+func GetSetupStatus() *SetupStatus {
+	status := &SetupStatus{
+		ConfigPath:         GetConfigPath(),
+		Binaries:           DetectBinaries(),
+		SuggestedOutputDir: SuggestOutputDirectory(),
+	}
+	status.ConfigExists = fileExists(status.ConfigPath)
+	status.NeedsSetup = !status.ConfigExists
+
+	for _, b := range status.Binaries {
+		if b.Required && !b.Found {
+			status.NeedsSetup = true
+		}
+	}
+
+	if err := TestOutputDirectory(status.SuggestedOutputDir); err != nil {
+		status.OutputDirError = err.Error()
+	} else {
+		status.OutputDirWritable = true
+	}
+	return status
+}
+
+// ProbeAudioServices reports which audio services are reachable
+func ProbeAudioServices(proxyURL string) map[string]ServiceStatus {
+	return CheckServiceStatus(proxyURL)
+}
+
+// CompleteSetup validates the wizard's config, checks the output folder and
+// writes the initial config file. The returned validation carries warnings
+// (and errors, if the config was rejected).
+func CompleteSetup(config *Config) (*ConfigValidation, error) {
+	validation := config.Validate()
+	if err := validation.Err(); err != nil {
+		return validation, err
+	}
+
+	outputDir := config.OutputDirectory
+	if outputDir == "" {
+		outputDir = GetDefaultOutputDirectory()
+	}
+	if err := TestOutputDirectory(outputDir); err != nil {
+		validation.errorf("outputDirectory", "%v", err)
+		return validation, validation.Err()
+	}
+
+	if err := SaveConfig(config); err != nil {
+		return validation, fmt.Errorf("failed to save config: %w", err)
+	}
+	return validation, nil
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestOutputDirectory_CreatesAndWrites(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Music", "Videos")
+	if err := TestOutputDirectory(dir); err != nil {
+		t.Fatalf("TestOutputDirectory failed: %v", err)
+	}
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		t.Fatal("expected output directory to be created")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("write test file was left behind: %v", entries)
+	}
+}
+
+func TestTestOutputDirectory_Rejects(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(file, []byte("x"), 0644)
+
+	for _, path := range []string{"", "/etc/youflac", file} {
+		if err := TestOutputDirectory(path); err == nil {
+			t.Errorf("TestOutputDirectory(%q) should fail", path)
+		}
+	}
+}
+
+func TestDetectBinary_Missing(t *testing.T) {
+	status := detectBinary("nope", "youflac-no-such-binary", "--version", true, "install it")
+	if status.Found || status.Hint != "install it" {
+		t.Errorf("unexpected status for missing binary: %+v", status)
+	}
+}
+
+func TestGetSetupStatus_NoConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("OUTPUT_DIR", filepath.Join(home, "out"))
+
+	status := GetSetupStatus()
+	if status.ConfigExists || !status.NeedsSetup {
+		t.Errorf("expected setup to be needed without a config file: %+v", status)
+	}
+	if status.SuggestedOutputDir != filepath.Join(home, "out") || !status.OutputDirWritable {
+		t.Errorf("unexpected output suggestion: %q writable=%v (%s)", status.SuggestedOutputDir, status.OutputDirWritable, status.OutputDirError)
+	}
+	if len(status.Binaries) == 0 {
+		t.Error("expected binary checks")
+	}
+}
+
+func TestCompleteSetup(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+
+	config := GetDefaultConfig()
+	config.OutputDirectory = filepath.Join(home, "videos")
+	config.Theme = "Dark"
+
+	validation, err := CompleteSetup(config)
+	if err != nil {
+		t.Fatalf("CompleteSetup failed: %v", err)
+	}
+	if !validation.Valid() {
+		t.Errorf("unexpected errors: %v", validation.Errors)
+	}
+
+	saved, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if saved.OutputDirectory != config.OutputDirectory || saved.Theme != "dark" {
+		t.Errorf("saved config not normalized: %+v", saved)
+	}
+
+	config.NamingTemplate = "nothing"
+	if _, err := CompleteSetup(config); err == nil {
+		t.Error("expected invalid config to be rejected")
+	}
+}
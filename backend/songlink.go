@@ -1,11 +1,8 @@
 package backend
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"regexp"
 	"sync"
 	"time"
@@ -14,10 +11,7 @@ import (
 // song.link (Odesli) API integration for cross-platform URL resolution
 // API Docs: https://odesli.co/
 
-const (
-	songLinkAPIBase = "https://api.song.link/v1-alpha.1/links"
-	userAgent       = "MKV-Video/1.0"
-)
+const songLinkAPIBase = "https://api.song.link/v1-alpha.1/links"
 
 // SongLinkResponse represents the full API response from song.link
 type SongLinkResponse struct {
@@ -83,7 +77,8 @@ var (
 	requestMutex sync.Mutex
 	minInterval  = 7 * time.Second // ~8.5 requests/min for safety
 	httpClient   = &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: sharedTransport,
 	}
 )
 
@@ -139,44 +134,9 @@ func waitForRateLimit() {
 
 // ResolveMusicURL converts any music platform URL to cross-platform URLs
 // Supports: Spotify, Tidal, Qobuz, Apple Music, Deezer, YouTube Music, SoundCloud
+// Resolution goes through the configured resolver chain (see ConfigureMusicResolvers).
 func ResolveMusicURL(musicURL string) (*SongLinkTrackInfo, error) {
-	waitForRateLimit()
-
-	// Build API URL
-	apiURL := fmt.Sprintf("%s?url=%s", songLinkAPIBase, url.QueryEscape(musicURL))
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", userAgent)
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 429 {
-		return nil, fmt.Errorf("rate limited by song.link API, please wait")
-	}
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var response SongLinkResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return parseSongLinkResponse(&response), nil
+	return getMusicResolver().Resolve(musicURL)
 }
 
 // parseSongLinkResponse extracts useful info from the API response
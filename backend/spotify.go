@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"regexp"
 	"strings"
 )
@@ -36,7 +35,7 @@ func GetSpotifyTrackInfo(trackID string) (*SpotifyTrackInfo, error) {
 	// Use embed API which doesn't require authentication
 	embedURL := fmt.Sprintf("https://open.spotify.com/oembed?url=https://open.spotify.com/track/%s", trackID)
 
-	resp, err := http.Get(embedURL)
+	resp, err := httpClient.Get(embedURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Spotify embed: %w", err)
 	}
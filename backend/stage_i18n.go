@@ -0,0 +1,349 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// Stage messages
+// =============================================================================
+
+// Queue items carry their current stage twice: QueueItem.StageCode (plus
+// StageParams) is a stable key the frontend looks up in its language's
+// catalog, and QueueItem.Stage is the English text for logs, MQTT, history
+// and clients that do not translate. Messages use {name} placeholders that
+// are filled from StageParams.
+
+// StageCode identifies a human-readable queue stage message
+type StageCode string
+
+// Stage codes
+const (
+	StageWaiting              StageCode = "waiting"
+	StageWaitingResumed       StageCode = "waiting_resumed"
+	StageWaitingRetry         StageCode = "waiting_retry"
+	StageWaitingRetryOverride StageCode = "waiting_retry_override"
+	StageWaitingDependency    StageCode = "waiting_dependency"
+	StageWaitingStalled       StageCode = "waiting_stalled" // {minutes}
+	StageApprovalPlanning     StageCode = "approval_planning"
+	StageApproval             StageCode = "approval"
+	StageApprovalInLibrary    StageCode = "approval_in_library"
+	StageApprovalWouldFail    StageCode = "approval_would_fail"
+	StageRejected             StageCode = "rejected"
+	StageDryRunComplete       StageCode = "dry_run_complete"
+	StageDryRunWouldFail      StageCode = "dry_run_would_fail"
+	StageError                StageCode = "error"
+	StageStalled              StageCode = "stalled" // {minutes}
+	StageCancelled            StageCode = "cancelled"
+	StagePaused               StageCode = "paused"
+
+	StageFetchingInfo           StageCode = "fetching_info"
+	StageParsingURL             StageCode = "parsing_url"
+	StageFoundExisting          StageCode = "found_existing"
+	StageSkippedExisting        StageCode = "skipped_existing"
+	StageCopyingExisting        StageCode = "copying_existing"
+	StageCopiedExisting         StageCode = "copied_existing"
+	StageSkippingVideo          StageCode = "skipping_video"
+	StageDownloadingVideo       StageCode = "downloading_video"
+	StageSearchingAlternative   StageCode = "searching_alternative"
+	StageDownloadingAlternative StageCode = "downloading_alternative"
+	StageVideoUnavailable       StageCode = "video_unavailable"
+	StageVideoDownloaded        StageCode = "video_downloaded"
+	StageFindingAudio           StageCode = "finding_audio"
+	StageResolvingSources       StageCode = "resolving_sources"
+	StageDownloadingFrom        StageCode = "downloading_from" // {source}
+	StageDownloadingTidal       StageCode = "downloading_tidal"
+	StageTryingOrpheus          StageCode = "trying_orpheus" // {source}
+	StageSearchingTidal         StageCode = "searching_tidal"
+	StageDownloadingYTMusic     StageCode = "downloading_ytmusic"
+	StageExtractingAudio        StageCode = "extracting_audio"
+	StageTrimmingClip           StageCode = "trimming_clip" // {clip}
+	StageMuxing                 StageCode = "muxing"
+	StageCreatingFLAC           StageCode = "creating_flac"
+	StageCreatingMKV            StageCode = "creating_mkv"
+	StageCreatingPreview        StageCode = "creating_preview"
+	StageFetchingLyrics         StageCode = "fetching_lyrics"
+	StageOrganizing             StageCode = "organizing"
+	StageUploading              StageCode = "uploading"
+	StageComplete               StageCode = "complete"
+	StageCompleteRemote         StageCode = "complete_remote"
+	StageCompleteUploadFailed   StageCode = "complete_upload_failed"
+)
+
+// DefaultStageLanguage is the catalog used for QueueItem.Stage and as the
+// fallback for missing translations
+const DefaultStageLanguage = "en"
+
+// stageCatalogs maps a language to its stage messages
+var stageCatalogs = map[string]map[StageCode]string{
+	"en": {
+		StageWaiting:                "Waiting...",
+		StageWaitingResumed:         "Waiting... (resumed)",
+		StageWaitingRetry:           "Waiting... (retry)",
+		StageWaitingRetryOverride:   "Waiting... (retry with override)",
+		StageWaitingDependency:      "Waiting for prerequisites...",
+		StageWaitingStalled:         "Stalled for {minutes} min, restarting...",
+		StageApprovalPlanning:       "Awaiting approval: planning...",
+		StageApproval:               "Awaiting approval",
+		StageApprovalInLibrary:      "Awaiting approval: already in library",
+		StageApprovalWouldFail:      "Awaiting approval: would fail",
+		StageRejected:               "Rejected",
+		StageDryRunComplete:         "Dry run complete",
+		StageDryRunWouldFail:        "Dry run: would fail",
+		StageError:                  "Error",
+		StageStalled:                "Stalled: no progress for {minutes} min",
+		StageCancelled:              "Cancelled",
+		StagePaused:                 "Paused",
+		StageFetchingInfo:           "Fetching video info...",
+		StageParsingURL:             "Parsing URL...",
+		StageFoundExisting:          "Found existing file...",
+		StageSkippedExisting:        "Skipped (already exists)",
+		StageCopyingExisting:        "Copying existing file...",
+		StageCopiedExisting:         "Copied from existing",
+		StageSkippingVideo:          "Audio-only output, skipping video...",
+		StageDownloadingVideo:       "Downloading video...",
+		StageSearchingAlternative:   "Video unavailable, searching for alternative upload...",
+		StageDownloadingAlternative: "Downloading alternative upload...",
+		StageVideoUnavailable:       "Video unavailable, downloading audio only...",
+		StageVideoDownloaded:        "Video downloaded",
+		StageFindingAudio:           "Finding audio match...",
+		StageResolvingSources:       "Resolving audio sources...",
+		StageDownloadingFrom:        "Downloading from {source}...",
+		StageDownloadingTidal:       "Downloading FLAC from Tidal...",
+		StageTryingOrpheus:          "Trying OrpheusDL for {source}...",
+		StageSearchingTidal:         "Searching Tidal for track...",
+		StageDownloadingYTMusic:     "No lossless source, downloading YouTube Music audio...",
+		StageExtractingAudio:        "Extracting audio from video...",
+		StageTrimmingClip:           "Trimming audio to {clip}...",
+		StageMuxing:                 "Muxing video and audio...",
+		StageCreatingFLAC:           "Creating FLAC file...",
+		StageCreatingMKV:            "Creating MKV file...",
+		StageCreatingPreview:        "Creating sync preview...",
+		StageFetchingLyrics:         "Fetching lyrics...",
+		StageOrganizing:             "Organizing files...",
+		StageUploading:              "Uploading to remote storage...",
+		StageComplete:               "Complete",
+		StageCompleteRemote:         "Complete (moved to remote storage)",
+		StageCompleteUploadFailed:   "Complete (upload failed)",
+	},
+	"fr": {
+		StageWaiting:                "En attente...",
+		StageWaitingResumed:         "En attente... (reprise)",
+		StageWaitingRetry:           "En attente... (nouvel essai)",
+		StageWaitingRetryOverride:   "En attente... (nouvel essai avec remplacement)",
+		StageWaitingDependency:      "En attente des prérequis...",
+		StageWaitingStalled:         "Bloqué depuis {minutes} min, redémarrage...",
+		StageApprovalPlanning:       "En attente de validation : planification...",
+		StageApproval:               "En attente de validation",
+		StageApprovalInLibrary:      "En attente de validation : déjà dans la bibliothèque",
+		StageApprovalWouldFail:      "En attente de validation : échouerait",
+		StageRejected:               "Refusé",
+		StageDryRunComplete:         "Simulation terminée",
+		StageDryRunWouldFail:        "Simulation : échouerait",
+		StageError:                  "Erreur",
+		StageStalled:                "Bloqué : aucune progression depuis {minutes} min",
+		StageCancelled:              "Annulé",
+		StagePaused:                 "En pause",
+		StageFetchingInfo:           "Récupération des infos de la vidéo...",
+		StageParsingURL:             "Analyse de l'URL...",
+		StageFoundExisting:          "Fichier existant trouvé...",
+		StageSkippedExisting:        "Ignoré (existe déjà)",
+		StageCopyingExisting:        "Copie du fichier existant...",
+		StageCopiedExisting:         "Copié depuis l'existant",
+		StageSkippingVideo:          "Sortie audio seule, vidéo ignorée...",
+		StageDownloadingVideo:       "Téléchargement de la vidéo...",
+		StageSearchingAlternative:   "Vidéo indisponible, recherche d'une autre mise en ligne...",
+		StageDownloadingAlternative: "Téléchargement de l'autre mise en ligne...",
+		StageVideoUnavailable:       "Vidéo indisponible, téléchargement de l'audio seul...",
+		StageVideoDownloaded:        "Vidéo téléchargée",
+		StageFindingAudio:           "Recherche de l'audio correspondant...",
+		StageResolvingSources:       "Résolution des sources audio...",
+		StageDownloadingFrom:        "Téléchargement depuis {source}...",
+		StageDownloadingTidal:       "Téléchargement du FLAC depuis Tidal...",
+		StageTryingOrpheus:          "Essai d'OrpheusDL pour {source}...",
+		StageSearchingTidal:         "Recherche du titre sur Tidal...",
+		StageDownloadingYTMusic:     "Aucune source sans perte, téléchargement de l'audio YouTube Music...",
+		StageExtractingAudio:        "Extraction de l'audio de la vidéo...",
+		StageTrimmingClip:           "Découpage de l'audio à {clip}...",
+		StageMuxing:                 "Multiplexage vidéo et audio...",
+		StageCreatingFLAC:           "Création du fichier FLAC...",
+		StageCreatingMKV:            "Création du fichier MKV...",
+		StageCreatingPreview:        "Création de l'aperçu de synchronisation...",
+		StageFetchingLyrics:         "Récupération des paroles...",
+		StageOrganizing:             "Organisation des fichiers...",
+		StageUploading:              "Envoi vers le stockage distant...",
+		StageComplete:               "Terminé",
+		StageCompleteRemote:         "Terminé (déplacé vers le stockage distant)",
+		StageCompleteUploadFailed:   "Terminé (échec de l'envoi)",
+	},
+	"de": {
+		StageWaiting:                "Warten...",
+		StageWaitingResumed:         "Warten... (fortgesetzt)",
+		StageWaitingRetry:           "Warten... (erneuter Versuch)",
+		StageWaitingRetryOverride:   "Warten... (erneuter Versuch mit Überschreibung)",
+		StageWaitingDependency:      "Warten auf Voraussetzungen...",
+		StageWaitingStalled:         "Seit {minutes} Min. hängengeblieben, Neustart...",
+		StageApprovalPlanning:       "Wartet auf Freigabe: Planung...",
+		StageApproval:               "Wartet auf Freigabe",
+		StageApprovalInLibrary:      "Wartet auf Freigabe: bereits in der Bibliothek",
+		StageApprovalWouldFail:      "Wartet auf Freigabe: würde fehlschlagen",
+		StageRejected:               "Abgelehnt",
+		StageDryRunComplete:         "Probelauf abgeschlossen",
+		StageDryRunWouldFail:        "Probelauf: würde fehlschlagen",
+		StageError:                  "Fehler",
+		StageStalled:                "Hängengeblieben: seit {minutes} Min. kein Fortschritt",
+		StageCancelled:              "Abgebrochen",
+		StagePaused:                 "Pausiert",
+		StageFetchingInfo:           "Videoinformationen werden abgerufen...",
+		StageParsingURL:             "URL wird analysiert...",
+		StageFoundExisting:          "Vorhandene Datei gefunden...",
+		StageSkippedExisting:        "Übersprungen (bereits vorhanden)",
+		StageCopyingExisting:        "Vorhandene Datei wird kopiert...",
+		StageCopiedExisting:         "Aus vorhandener Datei kopiert",
+		StageSkippingVideo:          "Nur-Audio-Ausgabe, Video wird übersprungen...",
+		StageDownloadingVideo:       "Video wird heruntergeladen...",
+		StageSearchingAlternative:   "Video nicht verfügbar, alternativer Upload wird gesucht...",
+		StageDownloadingAlternative: "Alternativer Upload wird heruntergeladen...",
+		StageVideoUnavailable:       "Video nicht verfügbar, nur Audio wird heruntergeladen...",
+		StageVideoDownloaded:        "Video heruntergeladen",
+		StageFindingAudio:           "Passendes Audio wird gesucht...",
+		StageResolvingSources:       "Audioquellen werden aufgelöst...",
+		StageDownloadingFrom:        "Download von {source}...",
+		StageDownloadingTidal:       "FLAC wird von Tidal heruntergeladen...",
+		StageTryingOrpheus:          "OrpheusDL wird für {source} versucht...",
+		StageSearchingTidal:         "Titel wird auf Tidal gesucht...",
+		StageDownloadingYTMusic:     "Keine verlustfreie Quelle, YouTube-Music-Audio wird heruntergeladen...",
+		StageExtractingAudio:        "Audio wird aus dem Video extrahiert...",
+		StageTrimmingClip:           "Audio wird auf {clip} zugeschnitten...",
+		StageMuxing:                 "Video und Audio werden zusammengeführt...",
+		StageCreatingFLAC:           "FLAC-Datei wird erstellt...",
+		StageCreatingMKV:            "MKV-Datei wird erstellt...",
+		StageCreatingPreview:        "Synchronisationsvorschau wird erstellt...",
+		StageFetchingLyrics:         "Songtexte werden abgerufen...",
+		StageOrganizing:             "Dateien werden organisiert...",
+		StageUploading:              "Upload in den Remote-Speicher...",
+		StageComplete:               "Fertig",
+		StageCompleteRemote:         "Fertig (in den Remote-Speicher verschoben)",
+		StageCompleteUploadFailed:   "Fertig (Upload fehlgeschlagen)",
+	},
+	"es": {
+		StageWaiting:                "En espera...",
+		StageWaitingResumed:         "En espera... (reanudado)",
+		StageWaitingRetry:           "En espera... (reintento)",
+		StageWaitingRetryOverride:   "En espera... (reintento con cambios)",
+		StageWaitingDependency:      "Esperando requisitos previos...",
+		StageWaitingStalled:         "Atascado durante {minutes} min, reiniciando...",
+		StageApprovalPlanning:       "Pendiente de aprobación: planificando...",
+		StageApproval:               "Pendiente de aprobación",
+		StageApprovalInLibrary:      "Pendiente de aprobación: ya está en la biblioteca",
+		StageApprovalWouldFail:      "Pendiente de aprobación: fallaría",
+		StageRejected:               "Rechazado",
+		StageDryRunComplete:         "Simulación completada",
+		StageDryRunWouldFail:        "Simulación: fallaría",
+		StageError:                  "Error",
+		StageStalled:                "Atascado: sin progreso durante {minutes} min",
+		StageCancelled:              "Cancelado",
+		StagePaused:                 "En pausa",
+		StageFetchingInfo:           "Obteniendo información del vídeo...",
+		StageParsingURL:             "Analizando la URL...",
+		StageFoundExisting:          "Archivo existente encontrado...",
+		StageSkippedExisting:        "Omitido (ya existe)",
+		StageCopyingExisting:        "Copiando el archivo existente...",
+		StageCopiedExisting:         "Copiado del existente",
+		StageSkippingVideo:          "Salida solo audio, omitiendo el vídeo...",
+		StageDownloadingVideo:       "Descargando el vídeo...",
+		StageSearchingAlternative:   "Vídeo no disponible, buscando otra subida...",
+		StageDownloadingAlternative: "Descargando la otra subida...",
+		StageVideoUnavailable:       "Vídeo no disponible, descargando solo el audio...",
+		StageVideoDownloaded:        "Vídeo descargado",
+		StageFindingAudio:           "Buscando el audio correspondiente...",
+		StageResolvingSources:       "Resolviendo fuentes de audio...",
+		StageDownloadingFrom:        "Descargando desde {source}...",
+		StageDownloadingTidal:       "Descargando FLAC desde Tidal...",
+		StageTryingOrpheus:          "Probando OrpheusDL para {source}...",
+		StageSearchingTidal:         "Buscando la pista en Tidal...",
+		StageDownloadingYTMusic:     "Sin fuente sin pérdida, descargando el audio de YouTube Music...",
+		StageExtractingAudio:        "Extrayendo el audio del vídeo...",
+		StageTrimmingClip:           "Recortando el audio a {clip}...",
+		StageMuxing:                 "Multiplexando vídeo y audio...",
+		StageCreatingFLAC:           "Creando el archivo FLAC...",
+		StageCreatingMKV:            "Creando el archivo MKV...",
+		StageCreatingPreview:        "Creando la vista previa de sincronización...",
+		StageFetchingLyrics:         "Obteniendo la letra...",
+		StageOrganizing:             "Organizando archivos...",
+		StageUploading:              "Subiendo al almacenamiento remoto...",
+		StageComplete:               "Completado",
+		StageCompleteRemote:         "Completado (movido al almacenamiento remoto)",
+		StageCompleteUploadFailed:   "Completado (falló la subida)",
+	},
+}
+
+// StageLanguages returns the languages with a stage catalog, sorted
+func StageLanguages() []string {
+	langs := make([]string, 0, len(stageCatalogs))
+	for lang := range stageCatalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// StageCatalog returns the stage messages for lang ("fr", "fr-CA" and
+// "fr_CA" all select "fr"). Codes missing from the language fall back to
+// English, so the catalog is always complete.
+func StageCatalog(lang string) map[StageCode]string {
+	catalog := make(map[StageCode]string, len(stageCatalogs[DefaultStageLanguage]))
+	for code, msg := range stageCatalogs[DefaultStageLanguage] {
+		catalog[code] = msg
+	}
+	for code, msg := range stageCatalogs[stageLanguage(lang)] {
+		catalog[code] = msg
+	}
+	return catalog
+}
+
+// StageMessage renders code in lang with params filled into its placeholders.
+// Unknown codes render as the code itself.
+func StageMessage(code StageCode, params map[string]string, lang string) string {
+	msg, ok := stageCatalogs[stageLanguage(lang)][code]
+	if !ok {
+		msg, ok = stageCatalogs[DefaultStageLanguage][code]
+	}
+	if !ok {
+		return string(code)
+	}
+	for name, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", value)
+	}
+	return msg
+}
+
+// stageLanguage reduces a language tag to its primary subtag
+func stageLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// stageParams builds StageParams from alternating name/value pairs
+func stageParams(pairs ...string) map[string]string {
+	if len(pairs) < 2 {
+		return nil
+	}
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+	return params
+}
+
+// setStage sets the item's stage code, params and English stage text.
+// params are alternating name/value pairs.
+func (item *QueueItem) setStage(code StageCode, params ...string) {
+	item.StageCode = code
+	item.StageParams = stageParams(params...)
+	item.Stage = StageMessage(code, item.StageParams, DefaultStageLanguage)
+}
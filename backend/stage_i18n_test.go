@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestStageCatalogsComplete(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{\w+\}`)
+	english := stageCatalogs[DefaultStageLanguage]
+	for _, lang := range StageLanguages() {
+		catalog := stageCatalogs[lang]
+		for code, msg := range english {
+			translated, ok := catalog[code]
+			if !ok {
+				t.Errorf("%s: missing %s", lang, code)
+				continue
+			}
+			if want, got := placeholder.FindAllString(msg, -1), placeholder.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %s placeholders = %v, want %v", lang, code, got, want)
+			}
+		}
+		if len(catalog) != len(english) {
+			t.Errorf("%s has %d messages, English has %d", lang, len(catalog), len(english))
+		}
+	}
+}
+
+func TestStageMessage(t *testing.T) {
+	params := map[string]string{"source": "qobuz"}
+	if got := StageMessage(StageDownloadingFrom, params, "en"); got != "Downloading from qobuz..." {
+		t.Errorf("en = %q", got)
+	}
+	if got := StageMessage(StageDownloadingFrom, params, "fr-CA"); got != "Téléchargement depuis qobuz..." {
+		t.Errorf("fr-CA = %q", got)
+	}
+	if got := StageMessage(StageComplete, nil, "xx"); got != "Complete" {
+		t.Errorf("unknown language should fall back to English, got %q", got)
+	}
+	if got := StageMessage("no_such_stage", nil, "en"); got != "no_such_stage" {
+		t.Errorf("unknown code = %q", got)
+	}
+	if StageCatalog("de_AT")[StagePaused] != "Pausiert" {
+		t.Error("de_AT should use the German catalog")
+	}
+}
+
+func TestUpdateStage(t *testing.T) {
+	q := newTestQueue()
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := q.GetItem(id); item.StageCode != StageWaiting || item.Stage != "Waiting..." {
+		t.Errorf("new item stage = %q (%q)", item.StageCode, item.Stage)
+	}
+
+	events := make(chan QueueEvent, 10)
+	q.SetProgressCallback(func(e QueueEvent) { events <- e })
+	q.UpdateStage(id, StatusDownloadingAudio, 50, StageDownloadingFrom, "source", "tidal")
+	item := q.GetItem(id)
+	if item.Stage != "Downloading from tidal..." || item.StageParams["source"] != "tidal" {
+		t.Errorf("item = %q %v", item.Stage, item.StageParams)
+	}
+	for e := range events {
+		if e.Type != "updated" {
+			continue // The async "added" event
+		}
+		if e.StageCode != StageDownloadingFrom || e.StageParams["source"] != "tidal" {
+			t.Errorf("event = %+v", e)
+		}
+		break
+	}
+
+	// Free-form text drops the code
+	q.UpdateStatus(id, StatusDownloadingAudio, 55, "Custom step")
+	if item := q.GetItem(id); item.StageCode != "" || item.StageParams != nil || item.Stage != "Custom step" {
+		t.Errorf("item = %+v", item)
+	}
+}
@@ -0,0 +1,628 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Remote storage. After a download is organized locally, the output file and
+// its sidecars can be uploaded to one or more remote targets (SFTP to a NAS,
+// an S3 bucket, a WebDAV share). Each upload is retried and verified by
+// comparing the remote size with the local one. In "move" mode the local
+// files are removed only once every target has a verified copy.
+
+// Storage modes for Config.StorageMode
+const (
+	StorageModeCopy = "copy" // Keep the local files after uploading
+	StorageModeMove = "move" // Remove the local files once every upload is verified
+)
+
+// DefaultStorageRetries is the number of extra attempts per file and target
+const DefaultStorageRetries = 3
+
+// storageRetryDelay is the first backoff delay, doubled after each attempt
+var storageRetryDelay = 2 * time.Second
+
+// StorageBackend stores files on one remote target
+type StorageBackend interface {
+	// Name identifies the target in logs and queue items, e.g. "s3://bucket/prefix"
+	Name() string
+	// Upload copies localPath to remotePath (slash-separated, relative to the target root)
+	Upload(localPath, remotePath string) error
+	// Size returns the size of a remote file, used to verify uploads
+	Size(remotePath string) (int64, error)
+}
+
+// NewStorageBackend parses a target URL:
+//
+//	sftp://user@nas:22/volume1/music
+//	s3://bucket/prefix
+//	webdav://host/remote.php/dav/files/me (webdavs:// for HTTPS)
+func NewStorageBackend(target string, config *Config) (StorageBackend, error) {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage target %q: %w", target, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid storage target %q: missing host", target)
+	}
+	if config == nil {
+		config = &defaultConfig
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "sftp":
+		// sftp would take a leading "-" as an option (-oProxyCommand=...)
+		if strings.HasPrefix(u.Hostname(), "-") || strings.HasPrefix(u.User.Username(), "-") {
+			return nil, fmt.Errorf("invalid storage target %q: host and user must not start with '-'", target)
+		}
+		return &SFTPStorage{
+			Host:    u.Hostname(),
+			Port:    u.Port(),
+			User:    u.User.Username(),
+			Root:    u.Path,
+			KeyFile: config.SFTPKeyFile,
+		}, nil
+	case "s3":
+		region := config.S3Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := strings.TrimSuffix(config.S3Endpoint, "/")
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		return &S3Storage{
+			Endpoint:  endpoint,
+			Region:    region,
+			Bucket:    u.Host,
+			Prefix:    strings.Trim(u.Path, "/"),
+			AccessKey: config.S3AccessKeyID,
+			SecretKey: config.S3SecretAccessKey,
+		}, nil
+	case "webdav", "webdavs":
+		scheme := "http"
+		if strings.ToLower(u.Scheme) == "webdavs" {
+			scheme = "https"
+		}
+		username := config.WebDAVUsername
+		if u.User != nil && u.User.Username() != "" {
+			username = u.User.Username()
+		}
+		return &WebDAVStorage{
+			BaseURL:  scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/"),
+			Username: username,
+			Password: config.WebDAVPassword,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported storage target %q (use sftp://, s3://, webdav:// or webdavs://)", target)
+}
+
+// StorageBackendsFromConfig builds the backends for Config.StorageTargets
+func StorageBackendsFromConfig(config *Config) ([]StorageBackend, error) {
+	var backends []StorageBackend
+	for _, target := range config.StorageTargets {
+		backend, err := NewStorageBackend(target, config)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// uploadVerified uploads a file and checks the remote size, retrying with
+// exponential backoff on failure
+func uploadVerified(backend StorageBackend, localPath, remotePath string, retries int) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	delay := storageRetryDelay
+	for attempt := 0; ; attempt++ {
+		err = backend.Upload(localPath, remotePath)
+		if err == nil {
+			var size int64
+			size, err = backend.Size(remotePath)
+			if err == nil && size != stat.Size() {
+				err = fmt.Errorf("size mismatch after upload: remote %d bytes, local %d bytes", size, stat.Size())
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("failed to upload %s to %s: %w", filepath.Base(localPath), backend.Name(), err)
+		}
+		slog.Debug("upload failed, retrying", "target", backend.Name(), "path", remotePath, "attempt", attempt+1, "err", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// outputFiles returns the output file followed by the sidecars written next to it
+func outputFiles(outputPath string) []string {
+	files := []string{outputPath}
+	base := mediaBase(outputPath)
+	for _, suffix := range []string{".nfo", "-poster.jpg", ".lrc", ".txt", ProvenanceSuffix} {
+		if _, err := os.Stat(base + suffix); err == nil {
+			files = append(files, base+suffix)
+		}
+	}
+	return files
+}
+
+// StorageUpload is the result of uploading one download to the configured targets
+type StorageUpload struct {
+	Targets []string // Targets holding a verified copy
+	Moved   bool     // Local files were removed (move mode)
+	Err     error    // First failure, if any target failed
+}
+
+// UploadOutput uploads outputPath and its sidecars to every configured
+// target. Remote paths mirror the layout under root.
+func UploadOutput(config *Config, root, outputPath string) StorageUpload {
+	var result StorageUpload
+	backends, err := StorageBackendsFromConfig(config)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(backends) == 0 {
+		return result
+	}
+
+	retries := config.StorageRetries
+	if retries < 0 {
+		retries = 0
+	}
+	files := outputFiles(outputPath)
+
+	for _, backend := range backends {
+		var uploadErr error
+		for _, file := range files {
+			rel, err := filepath.Rel(root, file)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				rel = filepath.Base(file)
+			}
+			if uploadErr = uploadVerified(backend, file, filepath.ToSlash(rel), retries); uploadErr != nil {
+				break
+			}
+		}
+		if uploadErr != nil {
+			if result.Err == nil {
+				result.Err = uploadErr
+			}
+			slog.Warn("upload to remote storage failed", "target", backend.Name(), "err", uploadErr)
+			continue
+		}
+		result.Targets = append(result.Targets, backend.Name())
+		slog.Info("uploaded to remote storage", "target", backend.Name(), "files", len(files))
+	}
+
+	if config.StorageMode == StorageModeMove && result.Err == nil {
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				slog.Warn("failed to remove local file after upload", "path", file, "err", err)
+			}
+		}
+		result.Moved = true
+	}
+	return result
+}
+
+// =============================================================================
+// SFTP
+// =============================================================================
+
+// SFTPStorage uploads with the system sftp client in batch mode, so host keys
+// and agent/key authentication come from the user's OpenSSH setup
+type SFTPStorage struct {
+	Host    string
+	Port    string
+	User    string
+	Root    string
+	KeyFile string // Optional private key, otherwise the ssh agent/defaults are used
+}
+
+func (s *SFTPStorage) Name() string {
+	host := s.Host
+	if s.Port != "" {
+		host += ":" + s.Port
+	}
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+	return "sftp://" + host + s.Root
+}
+
+func (s *SFTPStorage) remote(remotePath string) string {
+	root := s.Root
+	if root == "" {
+		root = "."
+	}
+	return path.Join(root, remotePath)
+}
+
+func (s *SFTPStorage) Upload(localPath, remotePath string) error {
+	target := s.remote(remotePath)
+
+	var batch strings.Builder
+	// "-" prefix: ignore errors for directories that already exist
+	var dirs []string
+	for dir := path.Dir(target); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fmt.Fprintf(&batch, "-mkdir %s\n", sftpQuote(dirs[i]))
+	}
+	fmt.Fprintf(&batch, "put %s %s\n", sftpQuote(localPath), sftpQuote(target))
+
+	_, err := s.run(batch.String())
+	return err
+}
+
+func (s *SFTPStorage) Size(remotePath string) (int64, error) {
+	out, err := s.run(fmt.Sprintf("ls -l %s\n", sftpQuote(s.remote(remotePath))))
+	if err != nil {
+		return 0, err
+	}
+	return parseSFTPListingSize(out)
+}
+
+func (s *SFTPStorage) run(batch string) (string, error) {
+	args := []string{"-b", "-", "-o", "BatchMode=yes"}
+	if s.Port != "" {
+		args = append(args, "-P", s.Port)
+	}
+	if s.KeyFile != "" {
+		args = append(args, "-i", s.KeyFile)
+	}
+	host := s.Host
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+	args = append(args, "--", host)
+
+	cmd := newCommand("sftp", args...)
+	cmd.Stdin = strings.NewReader(batch)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("sftp failed: %w, output: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// sftpQuote quotes an argument for an sftp batch file
+func sftpQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// parseSFTPListingSize reads the size column from sftp "ls -l" output.
+// Batch mode echoes commands prefixed with "sftp>", which are skipped.
+func parseSFTPListingSize(out string) (int64, error) {
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "sftp>") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[4], 10, 64); err == nil {
+			return size, nil
+		}
+	}
+	return 0, fmt.Errorf("no file size in sftp listing")
+}
+
+// =============================================================================
+// S3
+// =============================================================================
+
+// S3Storage uploads to an S3-compatible bucket (AWS, MinIO, Backblaze B2,
+// Wasabi...) with path-style URLs and SigV4 signing
+type S3Storage struct {
+	Endpoint  string // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000"
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func (s *S3Storage) Name() string {
+	if s.Prefix == "" {
+		return "s3://" + s.Bucket
+	}
+	return "s3://" + s.Bucket + "/" + s.Prefix
+}
+
+func (s *S3Storage) objectURL(remotePath string) (*url.URL, error) {
+	key := path.Join(s.Prefix, remotePath)
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", s.Endpoint, err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + key
+	u.RawPath = awsURIEncode(u.Path, false)
+	return u, nil
+}
+
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	signS3Request(req, payloadHash, s.AccessKey, s.SecretKey, s.Region, now())
+
+	client := s.client
+	if client == nil {
+		// Uploads of large MKVs can take a while; no overall timeout
+		client = &http.Client{}
+	}
+	return client.Do(req)
+}
+
+func (s *S3Storage) Upload(localPath, remotePath string) error {
+	u, err := s.objectURL(remotePath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = stat.Size()
+	resp, err := s.do(req, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return fmt.Errorf("S3 upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("S3 returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *S3Storage) Size(remotePath string) (int64, error) {
+	u, err := s.objectURL(remotePath)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return 0, fmt.Errorf("S3 HEAD failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("S3 HEAD returned status %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3Request adds AWS Signature Version 4 headers to req. The host and
+// every header already set on req are signed.
+func signS3Request(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, as
+// SigV4 requires. Slashes are kept unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// =============================================================================
+// WebDAV
+// =============================================================================
+
+// WebDAVStorage uploads to a WebDAV share (Nextcloud, ownCloud, Apache, rclone serve...)
+type WebDAVStorage struct {
+	BaseURL  string
+	Username string
+	Password string
+	client   *http.Client
+}
+
+func (w *WebDAVStorage) Name() string {
+	if strings.HasPrefix(w.BaseURL, "https://") {
+		return "webdavs://" + strings.TrimPrefix(w.BaseURL, "https://")
+	}
+	return "webdav://" + strings.TrimPrefix(w.BaseURL, "http://")
+}
+
+func (w *WebDAVStorage) url(remotePath string) string {
+	return w.BaseURL + "/" + awsURIEncode(strings.TrimPrefix(remotePath, "/"), false)
+}
+
+func (w *WebDAVStorage) do(method, remotePath string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, w.url(remotePath), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	client := w.client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return client.Do(req)
+}
+
+func (w *WebDAVStorage) Upload(localPath, remotePath string) error {
+	// Create parent collections; 405 means the collection already exists
+	dirs := strings.Split(path.Dir(strings.TrimPrefix(remotePath, "/")), "/")
+	for i := range dirs {
+		if dirs[i] == "." {
+			break
+		}
+		resp, err := w.do("MKCOL", strings.Join(dirs[:i+1], "/")+"/", nil, 0)
+		if err != nil {
+			return fmt.Errorf("WebDAV MKCOL failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("WebDAV MKCOL returned status %d", resp.StatusCode)
+		}
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.do(http.MethodPut, remotePath, f, stat.Size())
+	if err != nil {
+		return fmt.Errorf("WebDAV upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("WebDAV PUT returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WebDAVStorage) Size(remotePath string) (int64, error) {
+	resp, err := w.do(http.MethodHead, remotePath, nil, 0)
+	if err != nil {
+		return 0, fmt.Errorf("WebDAV HEAD failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("WebDAV HEAD returned status %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
+}
@@ -0,0 +1,191 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// =============================================================================
+// A/V sync preview
+// =============================================================================
+
+// With Config.SyncPreview a short, low-bitrate MP4 is cut from the muxed MKV
+// around the first chorus, where a drifting or wrong FLAC is easiest to hear.
+// It is served by the API so a match can be checked (and rejected) without
+// fetching the full file.
+
+// PreviewClipSeconds is the length of a sync preview clip
+const PreviewClipSeconds = 15
+
+// previewRoot overrides the preview directory in tests ("" = GetDataPath()/previews)
+var previewRoot string
+
+// GetPreviewDir returns the directory holding sync preview clips
+func GetPreviewDir() string {
+	if previewRoot != "" {
+		return previewRoot
+	}
+	return filepath.Join(GetDataPath(), "previews")
+}
+
+// previewPathFor returns the preview clip path for a queue item
+func previewPathFor(id string) string {
+	return filepath.Join(GetPreviewDir(), id+".mp4")
+}
+
+// loudnessPoint is one short-term (3 s window) EBU R128 loudness reading
+type loudnessPoint struct {
+	Time float64 // End of the window, seconds
+	LUFS float64
+}
+
+var ebur128Re = regexp.MustCompile(`t:\s*([\d.]+)\s.*?S:\s*(-?[\d.]+)`)
+
+// parseShortTermLoudness extracts the short-term loudness readings from the
+// ebur128 filter log
+func parseShortTermLoudness(output string) []loudnessPoint {
+	var points []loudnessPoint
+	for _, m := range ebur128Re.FindAllStringSubmatch(output, -1) {
+		t, err1 := strconv.ParseFloat(m[1], 64)
+		lufs, err2 := strconv.ParseFloat(m[2], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		points = append(points, loudnessPoint{Time: t, LUFS: lufs})
+	}
+	return points
+}
+
+// chorusStart picks where a preview clip of clipLen seconds starts: shortly
+// before the first point that comes close to the track's peak short-term
+// loudness, which is usually the first chorus. Without loudness readings it
+// falls back to a third of the way in.
+func chorusStart(points []loudnessPoint, duration, clipLen float64) float64 {
+	const (
+		nearPeakLU = 1.5 // "Close to the peak"
+		leadIn     = 5.0 // Short-term window (3 s) plus a little run-up
+	)
+	if duration <= clipLen {
+		return 0
+	}
+
+	start := duration / 3
+	if len(points) > 0 {
+		peak := points[0].LUFS
+		for _, p := range points {
+			peak = max(peak, p.LUFS)
+		}
+		for _, p := range points {
+			if p.LUFS >= peak-nearPeakLU {
+				start = p.Time - leadIn
+				break
+			}
+		}
+	}
+	return min(max(start, 0), duration-clipLen)
+}
+
+// measureShortTermLoudness runs the ebur128 filter over the first audio stream
+func measureShortTermLoudness(path string) ([]loudnessPoint, error) {
+	cmd := newCommand(GetFFmpegPath(), "-nostats", "-i", path, "-map", "0:a:0", "-af", "ebur128", "-f", "null", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to measure loudness: %v - %s", err, stderr.String())
+	}
+	return parseShortTermLoudness(stderr.String()), nil
+}
+
+// GenerateSyncPreview cuts a PreviewClipSeconds MP4 (480p H.264 + AAC) from
+// mkvPath around the first chorus. Returns the clip's start time.
+func GenerateSyncPreview(mkvPath, previewPath string) (float64, error) {
+	info, err := GetMediaInfo(mkvPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get media info: %w", err)
+	}
+	if !info.HasVideo || !info.HasAudio {
+		return 0, fmt.Errorf("preview needs both video and audio")
+	}
+
+	points, err := measureShortTermLoudness(mkvPath)
+	if err != nil {
+		slog.Debug("loudness analysis failed, using a fixed preview offset", "path", mkvPath, "err", err)
+	}
+	start := chorusStart(points, info.Duration, PreviewClipSeconds)
+
+	if err := os.MkdirAll(filepath.Dir(previewPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	args := []string{
+		"-y",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-i", mkvPath,
+		"-t", strconv.Itoa(PreviewClipSeconds),
+		"-map", "0:v:0",
+		"-map", "0:a:0",
+		"-vf", "scale=-2:480",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "28",
+		"-c:a", "aac", "-b:a", "160k",
+		"-movflags", "+faststart",
+		previewPath,
+	}
+	cmd := newCommand(GetFFmpegPath(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(previewPath)
+		return 0, fmt.Errorf("failed to create preview: %v - %s", err, stderr.String())
+	}
+	return start, nil
+}
+
+// RejectMatch undoes a completed download whose audio turned out to be the
+// wrong track or out of sync: the output and its sidecars are deleted, the
+// file leaves the duplicate index and the item fails so it can be retried
+// with a different match.
+func (q *Queue) RejectMatch(id string) error {
+	q.mutex.Lock()
+	item := q.itemByID(id)
+	if item == nil {
+		q.mutex.Unlock()
+		return fmt.Errorf("item not found: %s", id)
+	}
+	if item.Status != StatusComplete || item.OutputPath == "" {
+		q.mutex.Unlock()
+		return fmt.Errorf("item %s is not a completed download (%s)", id, item.Status)
+	}
+	outputPath := item.OutputPath
+	previewPath := item.PreviewPath
+	q.setStatus(item, StatusError)
+	item.Error = "Match rejected after review"
+	item.setStage(StageRejected)
+	item.OutputPath = ""
+	item.FileSize = 0
+	item.PreviewPath = ""
+	item.CompletedAt = time.Now()
+	fileIndex := q.fileIndex
+	cp := *item
+	q.mutex.Unlock()
+
+	for _, file := range outputFiles(outputPath) {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			slog.Warn("failed to remove rejected output", "path", file, "err", err)
+		}
+	}
+	if previewPath != "" {
+		os.Remove(previewPath)
+	}
+	if fileIndex != nil && fileIndex.RemovePath(outputPath) {
+		fileIndex.ScheduleSave()
+	}
+	RemoveFromLibraryViews(q.configs.Get(), outputPath)
+
+	q.emit(QueueEvent{Type: "updated", ItemID: id, Item: &cp})
+	return nil
+}
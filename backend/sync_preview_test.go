@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestChorusStart(t *testing.T) {
+	log := `[Parsed_ebur128_0 @ 0x1] t: 10.0     TARGET:-23 LUFS    M: -20.1 S: -22.0     I: -21.0 LUFS       LRA:   0.0 LU
+[Parsed_ebur128_0 @ 0x1] t: 40.0     TARGET:-23 LUFS    M: -9.0 S: -10.0     I: -15.0 LUFS       LRA:   4.0 LU
+[Parsed_ebur128_0 @ 0x1] t: 60.0     TARGET:-23 LUFS    M: -8.0 S: -9.0     I: -13.0 LUFS       LRA:   5.0 LU`
+	points := parseShortTermLoudness(log)
+	if len(points) != 3 || points[1].Time != 40 || points[1].LUFS != -10 {
+		t.Fatalf("points = %+v", points)
+	}
+
+	if got := chorusStart(points, 200, PreviewClipSeconds); got != 35 {
+		t.Errorf("chorusStart = %g, want 35", got)
+	}
+	if got := chorusStart(nil, 180, PreviewClipSeconds); got != 60 {
+		t.Errorf("fallback chorusStart = %g, want 60", got)
+	}
+	if got := chorusStart(points, 45, PreviewClipSeconds); got != 30 {
+		t.Errorf("clip should end inside the track, got %g", got)
+	}
+	if got := chorusStart(points, 10, PreviewClipSeconds); got != 0 {
+		t.Errorf("short track chorusStart = %g, want 0", got)
+	}
+}
+
+func TestRejectMatch(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "Artist - Song.mkv")
+	previewPath := filepath.Join(dir, "preview.mp4")
+	writeTestFile(t, outputPath, 100)
+	writeTestFile(t, filepath.Join(dir, "Artist - Song.nfo"), 10)
+	writeTestFile(t, previewPath, 10)
+
+	fi := NewFileIndex(dir)
+	fi.AddEntry(FileIndexEntry{Path: outputPath, Title: "Song", Artist: "Artist"})
+	q := newTestQueue()
+	q.SetFileIndex(fi)
+	q.mutex.Lock()
+	q.appendItem(QueueItem{ID: "done", Title: "Song", Artist: "Artist", Status: StatusComplete, OutputPath: outputPath, PreviewPath: previewPath})
+	q.appendItem(QueueItem{ID: "pending", Status: StatusPending})
+	q.mutex.Unlock()
+
+	if err := q.RejectMatch("pending"); err == nil {
+		t.Error("pending items cannot be rejected")
+	}
+	if err := q.RejectMatch("done"); err != nil {
+		t.Fatal(err)
+	}
+
+	item := q.GetItem("done")
+	if item.Status != StatusError || item.OutputPath != "" || item.PreviewPath != "" {
+		t.Errorf("rejected item = %+v", item)
+	}
+	for _, path := range []string{outputPath, filepath.Join(dir, "Artist - Song.nfo"), previewPath} {
+		if fileExists(path) {
+			t.Errorf("%s should be removed", path)
+		}
+	}
+	if fi.FindMatch("Song", "Artist", 0) != nil {
+		t.Error("rejected file should leave the index")
+	}
+
+	// The item can be retried with a different match
+	if _, err := q.RetryWithOverride("done", RetryOverrideRequest{MusicURL: "https://tidal.com/browse/track/2"}); err != nil {
+		t.Errorf("retry after reject: %v", err)
+	}
+}
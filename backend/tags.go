@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// YouTubeIDTag is the tag holding the ID of the YouTube video a file was
+// made from, for duplicate detection by ID
+const YouTubeIDTag = "YOUTUBE_ID"
+
+// TagWriter updates metadata tags in an existing media file.
+// Tag keys are Vorbis-style (TITLE, ARTIST, ...); several values for one key
+// become a multi-value tag where the container supports it. An empty value
+// list removes the tag.
+type TagWriter interface {
+	WriteTags(path string, tags map[string][]string) error
+	EmbedCover(path, coverPath string) error
+}
+
+// FLACTagWriter edits FLAC metadata blocks natively without touching audio frames
+type FLACTagWriter struct{}
+
+func (FLACTagWriter) WriteTags(path string, tags map[string][]string) error {
+	meta, err := ReadFLACMetadata(path)
+	if err != nil {
+		return err
+	}
+	for key, values := range tags {
+		meta.Set(key, values...)
+	}
+	return WriteFLACMetadata(path, meta)
+}
+
+func (FLACTagWriter) EmbedCover(path, coverPath string) error {
+	pic, err := NewFLACPictureFromFile(coverPath)
+	if err != nil {
+		return err
+	}
+	meta, err := ReadFLACMetadata(path)
+	if err != nil {
+		return err
+	}
+	meta.SetPicture(*pic)
+	return WriteFLACMetadata(path, meta)
+}
+
+// FFmpegTagWriter re-muxes the file with ffmpeg (used for MKV/MP4)
+type FFmpegTagWriter struct{}
+
+// ffmpegTagKeys maps Vorbis keys to the names the mux writes them under
+var ffmpegTagKeys = map[string]string{
+	"ALBUMARTIST": "album_artist",
+	"TRACKNUMBER": "track",
+	"DISCNUMBER":  "disc",
+}
+
+func (FFmpegTagWriter) WriteTags(path string, tags map[string][]string) error {
+	metadata := make(map[string]string, len(tags))
+	for key, values := range tags {
+		name, ok := ffmpegTagKeys[key]
+		if !ok {
+			name = strings.ToLower(key)
+		}
+		// Containers without multi-value support get a joined value
+		metadata[name] = strings.Join(values, "; ")
+	}
+	// The mux writes the track total as "3/12"
+	if track, total := metadata["track"], metadata["tracktotal"]; track != "" && total != "" {
+		metadata["track"] = track + "/" + total
+	}
+	return embedMetadataFFmpeg(path, metadata)
+}
+
+func (FFmpegTagWriter) EmbedCover(path, coverPath string) error {
+	return EmbedCoverArt(path, coverPath)
+}
+
+// TagWriterFor returns the tag writer for a file based on its extension
+func TagWriterFor(path string) TagWriter {
+	if strings.EqualFold(filepath.Ext(path), ".flac") {
+		return FLACTagWriter{}
+	}
+	return FFmpegTagWriter{}
+}
+
+// WriteTags updates tags in a media file using the best available writer
+func WriteTags(path string, tags map[string][]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := TagWriterFor(path).WriteTags(path, tags); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	return nil
+}
+
+// MetadataToTags converts Metadata into Vorbis-style tag values.
+// Only non-empty fields are included so existing tags are left untouched.
+func MetadataToTags(metadata *Metadata) map[string][]string {
+	tags := make(map[string][]string)
+	if metadata == nil {
+		return tags
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			tags[key] = []string{value}
+		}
+	}
+	set("TITLE", metadata.Title)
+	set("ARTIST", metadata.Artist)
+	set("ALBUMARTIST", metadata.AlbumArtist)
+	set("ALBUM", metadata.Album)
+	set("GENRE", metadata.Genre)
+	set("ISRC", metadata.ISRC)
+	set(AlbumIDTag, albumIDTagValue(metadata.AlbumID))
+	set(YouTubeIDTag, metadata.YouTubeID)
+	if metadata.Year > 0 {
+		set("DATE", strconv.Itoa(metadata.Year))
+	}
+	if metadata.Track > 0 {
+		set("TRACKNUMBER", strconv.Itoa(metadata.Track))
+	}
+	if metadata.TrackTotal > 0 {
+		set("TRACKTOTAL", strconv.Itoa(metadata.TrackTotal))
+	}
+	if metadata.Disc > 0 {
+		set("DISCNUMBER", strconv.Itoa(metadata.Disc))
+	}
+	if metadata.Explicit {
+		set("ITUNESADVISORY", "1")
+	}
+	if len(metadata.Artists) > 1 {
+		tags["ARTISTS"] = metadata.Artists
+	}
+	return tags
+}
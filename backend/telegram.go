@@ -0,0 +1,548 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Telegram bot
+// =============================================================================
+
+// The Telegram bot queues the YouTube and Spotify links sent to it from the
+// chats in Config.TelegramChatIDs and reports back when each one finishes:
+// the file itself when it fits Config.TelegramMaxUploadMB, otherwise a link
+// under Config.TelegramLinkBaseURL (or the path on the server). Commands:
+//
+//	/queue          unfinished downloads requested from this chat
+//	/cancel [n]     cancel the n-th item of /queue (default: the latest)
+//
+// Updates are read with getUpdates long polling, so no public webhook
+// address is needed. Queue events come from the queue's event bus.
+
+const (
+	telegramAPIBase     = "https://api.telegram.org"
+	telegramPollTimeout = 50 // Seconds getUpdates waits for new messages
+	telegramRetryDelay  = 30 * time.Second
+	telegramQueueSize   = 256
+
+	// TelegramUploadLimitMB is the Bot API's limit for files sent by bots
+	TelegramUploadLimitMB = 50
+)
+
+// TelegramBot is one bot session driving the queue
+type TelegramBot struct {
+	token       string
+	chats       map[int64]bool
+	maxUpload   int64  // Bytes; 0 = always send a link
+	linkBaseURL string // Where the library is served, "" = report the path
+	config      *Config
+	queue       *Queue
+	apiBase     string
+	client      *http.Client
+
+	mu      sync.Mutex
+	tracked map[string]*telegramTrackedItem // Queue item ID -> requesting chat
+	nextSeq int
+
+	events      chan QueueEvent
+	unsubscribe func()
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// telegramTrackedItem is an item queued from a chat
+type telegramTrackedItem struct {
+	chatID int64
+	seq    int // Request order, for /queue and /cancel
+}
+
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+// NewTelegramBot creates a bot for the chats in config. Call Start to connect.
+func NewTelegramBot(config *Config, queue *Queue) (*TelegramBot, error) {
+	token := strings.TrimSpace(config.TelegramBotToken)
+	if token == "" {
+		return nil, fmt.Errorf("telegram bot token is not set")
+	}
+	chats := make(map[int64]bool, len(config.TelegramChatIDs))
+	for _, raw := range config.TelegramChatIDs {
+		id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telegram chat ID %q", raw)
+		}
+		chats[id] = true
+	}
+	if len(chats) == 0 {
+		return nil, fmt.Errorf("no telegram chats configured")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TelegramBot{
+		token:       token,
+		chats:       chats,
+		maxUpload:   int64(min(max(config.TelegramMaxUploadMB, 0), TelegramUploadLimitMB)) << 20,
+		linkBaseURL: strings.TrimRight(config.TelegramLinkBaseURL, "/"),
+		config:      config,
+		queue:       queue,
+		apiBase:     telegramAPIBase,
+		client:      &http.Client{}, // Long polls and uploads set their own deadlines
+		tracked:     make(map[string]*telegramTrackedItem),
+		events:      make(chan QueueEvent, telegramQueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}, nil
+}
+
+// Start subscribes to queue events and starts polling in the background
+func (b *TelegramBot) Start() {
+	b.unsubscribe = b.queue.Subscribe(func(event QueueEvent) {
+		if event.Type != "completed" && event.Type != "error" && event.Type != "removed" {
+			return
+		}
+		select {
+		case b.events <- event:
+		default: // Telegram is slow or unreachable; drop rather than block the queue
+		}
+	})
+	b.wg.Add(2)
+	go b.runPolling()
+	go b.runEvents()
+}
+
+// Close stops polling and waits for the bot's goroutines
+func (b *TelegramBot) Close() {
+	if b.unsubscribe != nil {
+		b.unsubscribe()
+	}
+	b.cancel()
+	b.wg.Wait()
+}
+
+// runPolling reads updates until the bot is closed
+func (b *TelegramBot) runPolling() {
+	defer b.wg.Done()
+	var offset int64
+	for b.ctx.Err() == nil {
+		var updates []telegramUpdate
+		params := map[string]any{"offset": offset, "timeout": telegramPollTimeout, "allowed_updates": []string{"message"}}
+		if err := b.call("getUpdates", params, &updates, (telegramPollTimeout+10)*time.Second); err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			slog.Warn("telegram polling failed", "err", err)
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-time.After(telegramRetryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				b.handleMessage(u.Message)
+			}
+		}
+	}
+}
+
+// handleMessage runs a command or queues the links of a message from a
+// whitelisted chat
+func (b *TelegramBot) handleMessage(m *telegramMessage) {
+	chatID := m.Chat.ID
+	if !b.chats[chatID] {
+		return
+	}
+
+	fields := strings.Fields(m.Text)
+	if len(fields) == 0 {
+		return
+	}
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@") // "/queue@YouFlacBot" in groups
+	switch command {
+	case "/queue":
+		b.reply(chatID, b.queueSummary(chatID))
+		return
+	case "/cancel":
+		b.reply(chatID, b.cancelRequest(chatID, fields[1:]))
+		return
+	case "/start", "/help":
+		b.reply(chatID, "Send me YouTube or Spotify links to download them.\n/queue - your unfinished downloads\n/cancel [n] - cancel a download (default: the latest)")
+		return
+	}
+
+	var lines []string
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "http://") && !strings.HasPrefix(f, "https://") {
+			continue
+		}
+		req, err := DownloadRequestForLink(f, b.config)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("Not queued: %s (%v)", f, err))
+			continue
+		}
+		req.Notes = "Requested on Telegram"
+		id, err := b.queue.AddToQueue(req)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("Not queued: %s (%v)", f, err))
+			continue
+		}
+		b.mu.Lock()
+		b.nextSeq++
+		b.tracked[id] = &telegramTrackedItem{chatID: chatID, seq: b.nextSeq}
+		b.mu.Unlock()
+		lines = append(lines, "Queued: "+f)
+	}
+	if len(lines) > 0 {
+		b.reply(chatID, strings.Join(lines, "\n"))
+	}
+}
+
+// DownloadRequestForLink turns a YouTube or Spotify link into a queue
+// request. Spotify tracks are matched to their YouTube upload through
+// song.link and a YouTube search (see SelectVideoVariant).
+func DownloadRequestForLink(link string, config *Config) (DownloadRequest, error) {
+	if ValidateYouTubeURL(link) == nil {
+		return DownloadRequest{VideoURL: link}, nil
+	}
+	if !IsSpotifyURL(link) {
+		return DownloadRequest{}, fmt.Errorf("not a YouTube or Spotify link")
+	}
+
+	info, err := ResolveSpotifyURL(link)
+	if err != nil {
+		return DownloadRequest{}, fmt.Errorf("failed to resolve Spotify link: %w", err)
+	}
+	video := info.URLs.YouTubeURL
+	if video == "" {
+		video = info.URLs.YouTubeMusicURL
+	}
+	video = SelectVideoVariant(&VideoInfo{Title: info.Title, Artist: info.Artist}, video, config)
+	if video == "" || ValidateYouTubeURL(video) != nil {
+		return DownloadRequest{}, fmt.Errorf("no YouTube video found for this track")
+	}
+	return DownloadRequest{VideoURL: video, SpotifyURL: link}, nil
+}
+
+// chatItems returns the tracked unfinished items of a chat in request order
+func (b *TelegramBot) chatItems(chatID int64) []QueueItem {
+	b.mu.Lock()
+	type entry struct {
+		id  string
+		seq int
+	}
+	var entries []entry
+	for id, t := range b.tracked {
+		if t.chatID == chatID {
+			entries = append(entries, entry{id, t.seq})
+		}
+	}
+	b.mu.Unlock()
+	slices.SortFunc(entries, func(a, b entry) int { return a.seq - b.seq })
+
+	var items []QueueItem
+	for _, e := range entries {
+		if item := b.queue.GetItem(e.id); item != nil && !isTelegramFinished(item.Status) {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+func isTelegramFinished(status QueueStatus) bool {
+	return status == StatusComplete || status == StatusError || status == StatusCancelled
+}
+
+// queueSummary answers /queue
+func (b *TelegramBot) queueSummary(chatID int64) string {
+	items := b.chatItems(chatID)
+	if len(items) == 0 {
+		return "Nothing queued from this chat."
+	}
+	var sb strings.Builder
+	for i, item := range items {
+		stage := item.Stage
+		if item.StageCode != "" {
+			stage = StageMessage(item.StageCode, item.StageParams, DefaultStageLanguage)
+		}
+		fmt.Fprintf(&sb, "%d. %s - %s (%d%%)\n", i+1, telegramItemTitle(&item), stage, item.Progress)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// cancelRequest answers /cancel [n]
+func (b *TelegramBot) cancelRequest(chatID int64, args []string) string {
+	items := b.chatItems(chatID)
+	if len(items) == 0 {
+		return "Nothing to cancel."
+	}
+	n := len(items)
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 || n > len(items) {
+			return fmt.Sprintf("Use /cancel 1-%d (see /queue).", len(items))
+		}
+	}
+	item := items[n-1]
+	if err := b.queue.CancelItem(item.ID); err != nil {
+		return "Could not cancel: " + err.Error()
+	}
+	b.mu.Lock()
+	delete(b.tracked, item.ID)
+	b.mu.Unlock()
+	return "Cancelled: " + telegramItemTitle(&item)
+}
+
+func telegramItemTitle(item *QueueItem) string {
+	switch {
+	case item.Artist != "" && item.Title != "":
+		return item.Artist + " - " + item.Title
+	case item.Title != "":
+		return item.Title
+	}
+	return item.VideoURL
+}
+
+// runEvents reports finished items to the chat that requested them
+func (b *TelegramBot) runEvents() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case event := <-b.events:
+			b.handleEvent(event)
+		}
+	}
+}
+
+func (b *TelegramBot) handleEvent(event QueueEvent) {
+	b.mu.Lock()
+	tracked := b.tracked[event.ItemID]
+	delete(b.tracked, event.ItemID)
+	b.mu.Unlock()
+	if tracked == nil || event.Type == "removed" {
+		return
+	}
+	item := b.queue.GetItem(event.ItemID)
+	if item == nil {
+		return
+	}
+
+	title := telegramItemTitle(item)
+	if event.Type == "error" {
+		b.reply(tracked.chatID, fmt.Sprintf("Failed: %s\n%s", title, item.Error))
+		return
+	}
+
+	text := "Downloaded: " + title
+	if item.ActualQuality != "" {
+		text += " (" + item.ActualQuality + ")"
+	}
+	if len(item.Warnings) > 0 {
+		text += "\nWarnings:\n- " + strings.Join(item.Warnings, "\n- ")
+	}
+	if item.OutputPath != "" && item.FileSize > 0 && item.FileSize <= b.maxUpload {
+		err := b.sendFile(tracked.chatID, item.OutputPath, text)
+		if err == nil {
+			return
+		}
+		slog.Warn("failed to send file to telegram", "path", item.OutputPath, "err", err)
+	}
+	if link := b.fileLink(item.OutputPath); link != "" {
+		text += "\n" + link
+	}
+	b.reply(tracked.chatID, text)
+}
+
+// fileLink returns the URL of a library file under linkBaseURL, or its
+// path on the server when no base URL is configured
+func (b *TelegramBot) fileLink(path string) string {
+	if path == "" || b.linkBaseURL == "" {
+		return path
+	}
+	rel, err := filepath.Rel(libraryRootFor(b.config, path), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return b.linkBaseURL + "/" + strings.Join(segments, "/")
+}
+
+// reply sends a plain-text message
+func (b *TelegramBot) reply(chatID int64, text string) {
+	params := map[string]any{"chat_id": chatID, "text": truncateTelegram(text), "disable_web_page_preview": true}
+	if err := b.call("sendMessage", params, nil, 30*time.Second); err != nil {
+		slog.Warn("failed to send telegram message", "chat", chatID, "err", err)
+	}
+}
+
+// truncateTelegram cuts text to the 4096-character message limit
+func truncateTelegram(text string) string {
+	if r := []rune(text); len(r) > 4096 {
+		return string(r[:4095]) + "…"
+	}
+	return text
+}
+
+// sendFile uploads a file as a document with a caption
+func (b *TelegramBot) sendFile(chatID int64, path, caption string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Stream the multipart body instead of holding up to 50 MB in memory
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		mw.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+		if r := []rune(caption); len(r) > 1024 {
+			caption = string(r[:1023]) + "…"
+		}
+		mw.WriteField("caption", caption)
+		part, err := mw.CreateFormFile("document", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	ctx, cancel := context.WithTimeout(b.ctx, 10*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.methodURL("sendDocument"), pr)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return b.do(req, nil)
+}
+
+func (b *TelegramBot) methodURL(method string) string {
+	return b.apiBase + "/bot" + b.token + "/" + method
+}
+
+// call invokes a Bot API method with JSON parameters
+func (b *TelegramBot) call(method string, params, out any, timeout time.Duration) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.methodURL(method), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return b.do(req, out)
+}
+
+func (b *TelegramBot) do(req *http.Request, out any) error {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The URL holds the token; don't let it reach the logs
+		return fmt.Errorf("telegram request failed: %w", redactTelegramToken(err, b.token))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: %s", result.Description)
+	}
+	if out != nil {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return fmt.Errorf("failed to parse telegram response: %w", err)
+		}
+	}
+	return nil
+}
+
+// redactTelegramToken removes the bot token from a request error
+func redactTelegramToken(err error, token string) error {
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), token, "<token>"))
+}
+
+var (
+	telegramBot      *TelegramBot
+	telegramBotKey   string // Settings the bot was built from
+	telegramBotMutex sync.Mutex
+)
+
+// ConfigureTelegram starts, restarts or stops the Telegram bot to match config
+func ConfigureTelegram(config *Config, queue *Queue) {
+	if queue == nil {
+		return
+	}
+	key := strings.Join([]string{
+		config.TelegramBotToken,
+		strings.Join(config.TelegramChatIDs, ","),
+		strconv.Itoa(int(config.TelegramMaxUploadMB)),
+		config.TelegramLinkBaseURL,
+		strings.Join(LibraryDirectories(config), ","),
+	}, "\x00")
+
+	telegramBotMutex.Lock()
+	defer telegramBotMutex.Unlock()
+	enabled := config.TelegramBotToken != "" && len(config.TelegramChatIDs) > 0
+	if key == telegramBotKey && (telegramBot != nil) == enabled && (telegramBot == nil || telegramBot.queue == queue) {
+		return
+	}
+	if telegramBot != nil {
+		telegramBot.Close()
+		telegramBot = nil
+	}
+	telegramBotKey = key
+	if !enabled {
+		return
+	}
+	bot, err := NewTelegramBot(config.Clone(), queue)
+	if err != nil {
+		slog.Warn("Telegram bot disabled", "err", err)
+		return
+	}
+	bot.Start()
+	telegramBot = bot
+}
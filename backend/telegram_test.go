@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTelegramAPI records Bot API calls and the text or caption they carried
+type fakeTelegramAPI struct {
+	mu      sync.Mutex
+	methods []string
+	texts   []string
+}
+
+func (f *fakeTelegramAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	text := ""
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		r.ParseMultipartForm(1 << 20)
+		text = r.FormValue("caption")
+		if file, _, err := r.FormFile("document"); err == nil {
+			data, _ := io.ReadAll(file)
+			text += "|" + string(data)
+		}
+	} else {
+		var params map[string]any
+		json.NewDecoder(r.Body).Decode(&params)
+		text, _ = params["text"].(string)
+	}
+	f.mu.Lock()
+	f.methods = append(f.methods, method)
+	f.texts = append(f.texts, text)
+	f.mu.Unlock()
+	w.Write([]byte(`{"ok":true,"result":{}}`))
+}
+
+func (f *fakeTelegramAPI) last() (string, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.methods) == 0 {
+		return "", ""
+	}
+	return f.methods[len(f.methods)-1], f.texts[len(f.texts)-1]
+}
+
+func newTestTelegramBot(t *testing.T, config *Config) (*TelegramBot, *fakeTelegramAPI) {
+	t.Helper()
+	api := &fakeTelegramAPI{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	config.TelegramBotToken = "123:abc"
+	config.TelegramChatIDs = []string{"42"}
+	bot, err := NewTelegramBot(config, newTestQueue())
+	if err != nil {
+		t.Fatalf("NewTelegramBot() error = %v", err)
+	}
+	bot.apiBase = server.URL
+	return bot, api
+}
+
+func telegramText(chatID int64, text string) *telegramMessage {
+	m := &telegramMessage{Text: text}
+	m.Chat.ID = chatID
+	return m
+}
+
+func TestTelegramBot_QueueAndCancel(t *testing.T) {
+	bot, api := newTestTelegramBot(t, GetDefaultConfig())
+
+	bot.handleMessage(telegramText(7, "https://www.youtube.com/watch?v=dQw4w9WgXcQ"))
+	if n := len(bot.queue.GetQueue()); n != 0 {
+		t.Fatalf("queued %d items from a chat that is not whitelisted", n)
+	}
+
+	bot.handleMessage(telegramText(42, "listen https://www.youtube.com/watch?v=dQw4w9WgXcQ and https://example.com/x"))
+	items := bot.queue.GetQueue()
+	if len(items) != 1 {
+		t.Fatalf("queue = %d items, want 1", len(items))
+	}
+	if method, text := api.last(); method != "sendMessage" || !strings.Contains(text, "Queued:") || !strings.Contains(text, "Not queued: https://example.com/x") {
+		t.Errorf("reply = %s %q", method, text)
+	}
+
+	bot.handleMessage(telegramText(42, "/queue@YouFlacBot"))
+	if _, text := api.last(); !strings.HasPrefix(text, "1. ") {
+		t.Errorf("/queue reply = %q, want a numbered list", text)
+	}
+
+	bot.handleMessage(telegramText(42, "/cancel 2"))
+	if _, text := api.last(); !strings.Contains(text, "/cancel 1-1") {
+		t.Errorf("/cancel 2 reply = %q, want the valid range", text)
+	}
+	bot.handleMessage(telegramText(42, "/cancel"))
+	if got := bot.queue.GetItem(items[0].ID); got.Status != StatusCancelled {
+		t.Errorf("status = %s, want cancelled", got.Status)
+	}
+	bot.handleMessage(telegramText(42, "/queue"))
+	if _, text := api.last(); !strings.Contains(text, "Nothing queued") {
+		t.Errorf("/queue after cancel = %q", text)
+	}
+}
+
+func TestTelegramBot_SendsFileOrLink(t *testing.T) {
+	library := t.TempDir()
+	config := GetDefaultConfig()
+	config.OutputDirectory = library
+	config.TelegramMaxUploadMB = 1
+	config.TelegramLinkBaseURL = "https://media.example.com/music/"
+	bot, api := newTestTelegramBot(t, config)
+
+	small := filepath.Join(library, "Artist", "Small Song.flac")
+	writeTestFile(t, small, 10)
+	large := filepath.Join(library, "Artist", "Big Song.mkv")
+
+	for _, item := range []QueueItem{
+		{ID: "small", Title: "Small Song", Artist: "Artist", Status: StatusComplete, OutputPath: small, FileSize: 10},
+		{ID: "large", Title: "Big Song", Artist: "Artist", Status: StatusComplete, OutputPath: large, FileSize: 2 << 20, Warnings: []string{"poster: 404"}},
+	} {
+		bot.queue.mutex.Lock()
+		bot.queue.appendItem(item)
+		bot.queue.mutex.Unlock()
+		bot.tracked[item.ID] = &telegramTrackedItem{chatID: 42}
+	}
+
+	bot.handleEvent(QueueEvent{Type: "completed", ItemID: "small"})
+	if method, text := api.last(); method != "sendDocument" || !strings.HasPrefix(text, "Downloaded: Artist - Small Song") || !strings.HasSuffix(text, "|"+strings.Repeat("\x00", 10)) {
+		t.Errorf("small file: %s %q, want the document", method, text)
+	}
+
+	bot.handleEvent(QueueEvent{Type: "completed", ItemID: "large"})
+	method, text := api.last()
+	if method != "sendMessage" || !strings.Contains(text, "https://media.example.com/music/Artist/Big%20Song.mkv") || !strings.Contains(text, "poster: 404") {
+		t.Errorf("large file: %s %q, want a link and the warnings", method, text)
+	}
+
+	bot.handleEvent(QueueEvent{Type: "completed", ItemID: "large"})
+	if n := len(api.methods); n != 2 {
+		t.Errorf("%d calls, an untracked item must not be reported again", n)
+	}
+}
+
+func TestConfigValidate_Telegram(t *testing.T) {
+	config := GetDefaultConfig()
+	config.TelegramBotToken = "123:abc"
+	config.TelegramChatIDs = []string{"-1001234567890", "@mygroup"}
+	config.TelegramMaxUploadMB = 200
+	config.TelegramLinkBaseURL = "ftp://files"
+	result := config.Validate()
+
+	if len(config.TelegramChatIDs) != 1 || config.TelegramChatIDs[0] != "-1001234567890" {
+		t.Errorf("chat IDs = %v", config.TelegramChatIDs)
+	}
+	if config.TelegramMaxUploadMB != TelegramUploadLimitMB {
+		t.Errorf("max upload = %d, want %d", config.TelegramMaxUploadMB, TelegramUploadLimitMB)
+	}
+	if len(result.Errors) == 0 {
+		t.Error("expected an error for the link base URL")
+	}
+}
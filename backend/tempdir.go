@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"youflac/backend/format"
+)
+
+// Temp directory. Downloads, audio service output and mux scratch files go
+// to Config.TempDirectory, or os.TempDir()/youflac by default. A single
+// 4K video plus its FLAC can take several GB, which may not fit on a small
+// tmpfs, so the directory is checked for space and write access when the
+// config is applied.
+
+// MinTempFreeBytes is the free space below which a warning is logged
+const MinTempFreeBytes = 4 << 30
+
+var (
+	tempDirectory      string
+	tempDirectoryMutex sync.RWMutex
+)
+
+// DefaultTempDirectory returns the temp directory used when none is configured
+func DefaultTempDirectory() string {
+	return filepath.Join(os.TempDir(), "youflac")
+}
+
+// GetTempDirectory returns the temp directory for work files, creating it if needed
+func GetTempDirectory() string {
+	tempDirectoryMutex.RLock()
+	dir := tempDirectory
+	tempDirectoryMutex.RUnlock()
+	if dir == "" {
+		dir = DefaultTempDirectory()
+	}
+	os.MkdirAll(dir, 0755)
+	return dir
+}
+
+// ConfigureTempDirectory applies Config.TempDirectory. If the directory is
+// not usable the default is kept and the error returned; low free space is
+// only logged.
+func ConfigureTempDirectory(config *Config) error {
+	dir := config.TempDirectory
+	if dir == "" {
+		dir = DefaultTempDirectory()
+	}
+
+	free, err := CheckTempDirectory(dir)
+	if err != nil {
+		if config.TempDirectory != "" {
+			setTempDirectory("")
+			return fmt.Errorf("temp directory %s is not usable, using %s: %w", dir, DefaultTempDirectory(), err)
+		}
+		return err
+	}
+	setTempDirectory(config.TempDirectory)
+
+	if free >= 0 && free < MinTempFreeBytes {
+		slog.Warn("temp directory is low on space", "path", dir, "free", format.FileSize(free))
+	}
+	return nil
+}
+
+func setTempDirectory(dir string) {
+	tempDirectoryMutex.Lock()
+	defer tempDirectoryMutex.Unlock()
+	tempDirectory = dir
+}
+
+// CheckTempDirectory creates dir if needed, verifies a file can be written
+// to it and returns its free space (-1 if unknown)
+func CheckTempDirectory(dir string) (int64, error) {
+	if stat, err := os.Stat(dir); err == nil && !stat.IsDir() {
+		return 0, fmt.Errorf("%s is a file, not a directory", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, ".youflac-write-test-*")
+	if err != nil {
+		return 0, fmt.Errorf("temp directory is not writable: %w", err)
+	}
+	name := f.Name()
+	_, writeErr := f.WriteString("youflac")
+	f.Close()
+	os.Remove(name)
+	if writeErr != nil {
+		return 0, fmt.Errorf("temp directory is not writable: %w", writeErr)
+	}
+
+	free, err := diskFreeBytes(dir)
+	if err != nil {
+		slog.Debug("could not check free space", "path", dir, "err", err)
+		return -1, nil
+	}
+	return free, nil
+}
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigureTempDirectory(t *testing.T) {
+	defer setTempDirectory("")
+
+	dir := filepath.Join(t.TempDir(), "scratch")
+	if err := ConfigureTempDirectory(&Config{TempDirectory: dir}); err != nil {
+		t.Fatalf("ConfigureTempDirectory: %v", err)
+	}
+	if got := GetTempDirectory(); got != dir {
+		t.Errorf("GetTempDirectory() = %q, want %q", got, dir)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("temp directory was not created: %v", err)
+	}
+
+	// A file in the way is not usable: fall back to the default
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	os.WriteFile(file, nil, 0644)
+	if err := ConfigureTempDirectory(&Config{TempDirectory: file}); err == nil {
+		t.Error("expected an error for a file path")
+	}
+	if got := GetTempDirectory(); got != DefaultTempDirectory() {
+		t.Errorf("GetTempDirectory() = %q, want the default", got)
+	}
+}
+
+func TestCheckTempDirectory_ReportsFreeSpace(t *testing.T) {
+	free, err := CheckTempDirectory(t.TempDir())
+	if err != nil {
+		t.Fatalf("CheckTempDirectory: %v", err)
+	}
+	if free == 0 {
+		t.Error("free space should be reported or unknown (-1), not 0")
+	}
+}
@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThumbnailWidth is the width in pixels of generated file manager thumbnails
+const ThumbnailWidth = 320
+
+// thumbnailPosition is the fraction of a video's duration used for the frame grab
+const thumbnailPosition = 0.10
+
+var thumbnailVideoExts = map[string]bool{
+	".mkv": true, ".mp4": true, ".webm": true, ".avi": true, ".mov": true,
+}
+
+var thumbnailAudioExts = map[string]bool{
+	".flac": true, ".mp3": true, ".m4a": true,
+}
+
+// GetThumbnailCacheDir returns the directory where generated thumbnails are cached.
+// It lives under the temp dir so the image endpoint can serve it.
+func GetThumbnailCacheDir() string {
+	return filepath.Join(GetTempDirectory(), "thumbnails")
+}
+
+// SupportsThumbnail reports whether a thumbnail can be generated for the file extension
+func SupportsThumbnail(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return thumbnailVideoExts[ext] || thumbnailAudioExts[ext]
+}
+
+// thumbnailCachePath returns the cache location for a media file.
+// The key includes size and modification time so edited files get a fresh thumbnail.
+func thumbnailCachePath(mediaPath string, size int64, modTime time.Time) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s|%d|%d", mediaPath, size, modTime.UnixNano())
+	return filepath.Join(GetThumbnailCacheDir(), hex.EncodeToString(h.Sum(nil))+".jpg")
+}
+
+// thumbnailFailedPath marks a media file ffmpeg could not make a thumbnail
+// of (e.g. audio without cover art), so listings don't retry it every time
+func thumbnailFailedPath(thumbPath string) string {
+	return strings.TrimSuffix(thumbPath, ".jpg") + ".failed"
+}
+
+// CachedThumbnail returns the thumbnail of a media file if one has already
+// been generated, without running ffmpeg
+func CachedThumbnail(mediaPath string) (string, bool) {
+	if !SupportsThumbnail(mediaPath) {
+		return "", false
+	}
+	stat, err := os.Stat(mediaPath)
+	if err != nil {
+		return "", false
+	}
+	thumbPath := thumbnailCachePath(mediaPath, stat.Size(), stat.ModTime())
+	return thumbPath, fileExists(thumbPath)
+}
+
+// GenerateThumbnail returns the path of a small JPEG thumbnail for a media file,
+// generating and caching it on first use. Videos use a frame grabbed at 10% of
+// the duration; FLAC/MP3/M4A files use their embedded cover art. Files ffmpeg
+// fails on are remembered until they change.
+func GenerateThumbnail(mediaPath string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(mediaPath))
+	if !thumbnailVideoExts[ext] && !thumbnailAudioExts[ext] {
+		return "", fmt.Errorf("unsupported file type for thumbnail: %s", ext)
+	}
+
+	stat, err := os.Stat(mediaPath)
+	if err != nil {
+		return "", fmt.Errorf("file not found: %w", err)
+	}
+
+	thumbPath := thumbnailCachePath(mediaPath, stat.Size(), stat.ModTime())
+	if fileExists(thumbPath) {
+		return thumbPath, nil
+	}
+	failedPath := thumbnailFailedPath(thumbPath)
+	if fileExists(failedPath) {
+		return "", fmt.Errorf("no thumbnail could be generated for %s", filepath.Base(mediaPath))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(thumbPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail cache: %w", err)
+	}
+
+	// Write to a unique temp name first so concurrent listings never see a
+	// partial file or write over each other
+	tmp, err := os.CreateTemp(filepath.Dir(thumbPath), strings.TrimSuffix(filepath.Base(thumbPath), ".jpg")+".*.tmp.jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	scale := fmt.Sprintf("scale=%d:-2", ThumbnailWidth)
+
+	var args []string
+	if thumbnailVideoExts[ext] {
+		seek := 0.0
+		if info, err := GetMediaInfo(mediaPath); err == nil && info.Duration > 0 {
+			seek = info.Duration * thumbnailPosition
+		}
+		args = []string{
+			"-y",
+			"-ss", fmt.Sprintf("%.3f", seek),
+			"-i", mediaPath,
+			"-frames:v", "1",
+			"-vf", scale,
+			"-q:v", "4",
+			tmpPath,
+		}
+	} else {
+		// Embedded cover art is exposed by ffmpeg as an attached-picture video stream
+		args = []string{
+			"-y",
+			"-i", mediaPath,
+			"-an",
+			"-map", "0:v:0",
+			"-frames:v", "1",
+			"-vf", scale,
+			"-q:v", "4",
+			tmpPath,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := newCommandContext(ctx, GetFFmpegPath(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		// ffmpeg ran and rejected the file: don't try again until it changes
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			os.WriteFile(failedPath, nil, 0644)
+		}
+		return "", fmt.Errorf("thumbnail generation failed: %v - %s", err, stderr.String())
+	}
+
+	if err := MoveFile(tmpPath, thumbPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	return thumbPath, nil
+}
+
+// GenerateThumbnails generates thumbnails for several files using a bounded
+// number of workers. Files that fail or are unsupported are omitted from the result.
+func GenerateThumbnails(paths []string, workers int) map[string]string {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				thumb, err := GenerateThumbnail(p)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				results[p] = thumb
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, p := range paths {
+		if SupportsThumbnail(p) {
+			jobs <- p
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
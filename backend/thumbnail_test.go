@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSupportsThumbnail(t *testing.T) {
+	tests := map[string]bool{
+		"/music/Artist - Title.mkv":  true,
+		"/music/Artist - Title.MP4":  true,
+		"/music/Artist - Title.flac": true,
+		"/music/Artist - Title.nfo":  false,
+		"/music/cover.jpg":           false,
+	}
+	for path, want := range tests {
+		if got := SupportsThumbnail(path); got != want {
+			t.Errorf("SupportsThumbnail(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestThumbnailCachePath(t *testing.T) {
+	mod := time.Unix(1700000000, 0)
+
+	a := thumbnailCachePath("/music/a.mkv", 100, mod)
+	if a != thumbnailCachePath("/music/a.mkv", 100, mod) {
+		t.Error("cache path should be deterministic")
+	}
+	if a == thumbnailCachePath("/music/a.mkv", 100, mod.Add(time.Second)) {
+		t.Error("cache path should change when the file is modified")
+	}
+	if a == thumbnailCachePath("/music/b.mkv", 100, mod) {
+		t.Error("cache path should differ between files")
+	}
+	if filepath.Dir(a) != GetThumbnailCacheDir() {
+		t.Errorf("cache path %q not under %q", a, GetThumbnailCacheDir())
+	}
+}
+
+func TestGenerateThumbnailUnsupported(t *testing.T) {
+	if _, err := GenerateThumbnail("/music/info.nfo"); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+}
+
+func TestGenerateThumbnailFromVideo(t *testing.T) {
+	if err := CheckFFmpegInstalled(); err != nil {
+		t.Skip("FFmpeg not installed")
+	}
+
+	tmpDir := t.TempDir()
+	videoPath := filepath.Join(tmpDir, "test.mkv")
+	cmd := fmt.Sprintf(
+		"%s -f lavfi -i testsrc=duration=2:size=640x360:rate=30 -c:v libx264 -y %s",
+		GetFFmpegPath(),
+		videoPath,
+	)
+	if err := runCommand(cmd); err != nil {
+		t.Skipf("Could not create test video: %v", err)
+	}
+
+	thumb, err := GenerateThumbnail(videoPath)
+	if err != nil {
+		t.Fatalf("GenerateThumbnail failed: %v", err)
+	}
+	defer os.Remove(thumb)
+
+	if !fileExists(thumb) {
+		t.Fatalf("thumbnail not written: %s", thumb)
+	}
+
+	// Second call must hit the cache
+	again, err := GenerateThumbnail(videoPath)
+	if err != nil || again != thumb {
+		t.Errorf("expected cached thumbnail %q, got %q (err %v)", thumb, again, err)
+	}
+}
+
+func TestGenerateThumbnailFailureIsCached(t *testing.T) {
+	if err := CheckFFmpegInstalled(); err != nil {
+		t.Skip("FFmpeg not installed")
+	}
+
+	// FLAC without cover art: ffmpeg has no picture to grab
+	audioPath := filepath.Join(t.TempDir(), "test.flac")
+	cmd := fmt.Sprintf("%s -f lavfi -i sine=duration=1 -y %s", GetFFmpegPath(), audioPath)
+	if err := runCommand(cmd); err != nil {
+		t.Skipf("Could not create test audio: %v", err)
+	}
+
+	if _, err := GenerateThumbnail(audioPath); err == nil {
+		t.Fatal("expected an error for audio without cover art")
+	}
+	stat, _ := os.Stat(audioPath)
+	failed := thumbnailFailedPath(thumbnailCachePath(audioPath, stat.Size(), stat.ModTime()))
+	defer os.Remove(failed)
+	if !fileExists(failed) {
+		t.Error("failure was not recorded")
+	}
+	if _, ok := CachedThumbnail(audioPath); ok {
+		t.Error("CachedThumbnail reported a thumbnail for a failed file")
+	}
+}
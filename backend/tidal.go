@@ -3,6 +3,7 @@ package backend
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Tidal FLAC download - Primary audio source
@@ -63,16 +64,17 @@ func GetTidalTrackInfo(trackURL string) (*TidalTrackInfo, error) {
 	}
 
 	return &TidalTrackInfo{
-		ID:          info.ID,
-		Title:       info.Title,
-		Artist:      info.Artist,
-		Album:       info.Album,
-		ISRC:        info.ISRC,
-		Duration:    info.Duration,
-		Quality:     info.Quality,
-		CoverURL:    info.CoverURL,
-		ReleaseDate: info.ReleaseDate,
-		TrackNumber: info.TrackNumber,
+		ID:           info.ID,
+		Title:        info.Title,
+		Artist:       info.Artist,
+		Album:        info.Album,
+		ISRC:         info.ISRC,
+		Duration:     info.Duration,
+		Quality:      info.Quality,
+		CoverURL:     info.CoverURL,
+		ReleaseDate:  info.ReleaseDate,
+		TrackNumber:  info.TrackNumber,
+		ExplicitFlag: info.Explicit,
 	}, nil
 }
 
@@ -135,8 +137,18 @@ const (
 	TidalQualityLossless TidalQuality = "LOSSLESS" // 16-bit/44.1kHz FLAC
 	TidalQualityHiRes    TidalQuality = "HI_RES"   // 24-bit/96kHz MQA
 	TidalQualityMax      TidalQuality = "MAX"      // 24-bit/192kHz FLAC
+
+	TidalQualityHiResLossless TidalQuality = "HI_RES_LOSSLESS" // up to 24-bit/192kHz FLAC
 )
 
+// TidalQualityForPreference maps Config.PreferredQuality to the Tidal tier to request
+func TidalQualityForPreference(preferred string) TidalQuality {
+	if strings.ToLower(preferred) == "16bit" {
+		return TidalQualityLossless
+	}
+	return TidalQualityHiResLossless
+}
+
 // GetTidalQualityLabel returns human-readable quality label
 func GetTidalQualityLabel(quality TidalQuality) string {
 	switch quality {
@@ -150,6 +162,8 @@ func GetTidalQualityLabel(quality TidalQuality) string {
 		return "Hi-Res (24-bit/96kHz MQA)"
 	case TidalQualityMax:
 		return "Max (24-bit/192kHz FLAC)"
+	case TidalQualityHiResLossless:
+		return "Hi-Res Lossless (up to 24-bit/192kHz FLAC)"
 	default:
 		return string(quality)
 	}
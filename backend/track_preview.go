@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// Track preview
+// =============================================================================
+
+// ResolveTrack gathers everything the add dialog shows before a URL is
+// queued: the video metadata, the streaming links song.link knows about, the
+// audio candidate the matcher would pick and the file the download would be
+// written to. Nothing is downloaded and the queue is not touched.
+
+// Network lookups, replaced in tests
+var (
+	fetchVideoMetadata = GetVideoMetadata
+	resolveMusicLinks  = ResolveMusicURL
+)
+
+// TrackPreview is the resolved view of a URL before it is added to the queue
+type TrackPreview struct {
+	URL          string             `json:"url"`
+	Video        *VideoInfo         `json:"video,omitempty"` // nil when the URL has no YouTube video
+	Links        *SongLinkTrackInfo `json:"links,omitempty"` // Platform links, nil when resolution failed
+	Candidates   []AudioCandidate   `json:"candidates,omitempty"`
+	BestMatch    *MatchResult       `json:"bestMatch,omitempty"`
+	ExistingFile string             `json:"existingFile,omitempty"` // Library file the download would reuse
+	OutputPath   string             `json:"outputPath,omitempty"`
+	Warnings     []string           `json:"warnings,omitempty"`
+}
+
+// ResolveTrack resolves a YouTube or streaming-service URL into a
+// TrackPreview. The video metadata and the platform links are fetched
+// concurrently; either may fail on its own and is then reported as a
+// warning. fileIndex may be nil.
+func ResolveTrack(rawURL string, config *Config, fileIndex *FileIndex) (*TrackPreview, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if config == nil {
+		config = &defaultConfig
+	}
+	preview := &TrackPreview{URL: rawURL}
+
+	videoID := ""
+	if ValidateYouTubeURL(rawURL) == nil {
+		id, err := ParseYouTubeURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid YouTube URL: %w", err)
+		}
+		videoID = id
+	}
+
+	var (
+		wg                 sync.WaitGroup
+		video              *VideoInfo
+		links              *SongLinkTrackInfo
+		videoErr, linksErr error
+	)
+	if videoID != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			video, videoErr = fetchVideoMetadata(videoID)
+		}()
+	}
+	links, linksErr = resolveMusicLinks(rawURL)
+	wg.Wait()
+
+	// Streaming URLs only reach the video through song.link and a search
+	// for the upload Config.VideoVariant prefers
+	if videoID == "" && linksErr == nil {
+		upload := SelectVideoVariant(&VideoInfo{Title: links.Title, Artist: links.Artist}, links.URLs.YouTubeURL, config)
+		if id, err := ParseYouTubeURL(upload); upload != "" && err == nil {
+			videoID = id
+			video, videoErr = fetchVideoMetadata(videoID)
+		}
+	}
+
+	if videoErr != nil {
+		slog.Debug("track preview: video metadata failed", "url", rawURL, "err", videoErr)
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("failed to fetch video info: %v", videoErr))
+	}
+	if linksErr != nil {
+		slog.Debug("track preview: link resolution failed", "url", rawURL, "err", linksErr)
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("failed to resolve platform links: %v", linksErr))
+	}
+	if video == nil && links == nil {
+		return nil, fmt.Errorf("could not resolve %s: %s", rawURL, strings.Join(preview.Warnings, "; "))
+	}
+
+	// Merge: the video wins for what is shown, song.link fills the gaps
+	if video == nil {
+		video = &VideoInfo{Title: links.Title, Artist: links.Artist, Thumbnail: links.Thumbnail}
+	} else if links != nil {
+		if video.ISRC == "" {
+			video.ISRC = links.ISRC
+		}
+		if video.Thumbnail == "" {
+			video.Thumbnail = links.Thumbnail
+		}
+	}
+	if videoID != "" {
+		preview.Video = video
+	}
+	preview.Links = links
+
+	if links != nil {
+		preview.Candidates = buildCandidatesFromSongLink(links)
+		if len(preview.Candidates) > 0 {
+			if match, err := MatchVideoToAudio(video, preview.Candidates, nil); err == nil {
+				preview.BestMatch = match
+				if !match.IsValid {
+					preview.Warnings = append(preview.Warnings, "no audio candidate above the confidence threshold")
+				}
+			}
+		} else {
+			preview.Warnings = append(preview.Warnings, "no lossless platform links found")
+		}
+	}
+
+	// Output path as the processor would generate it
+	item := &QueueItem{}
+	metadata := &Metadata{Title: video.Title, Artist: video.Artist, Duration: video.Duration, ISRC: video.ISRC}
+	ApplyArtistCredit(metadata, "", config)
+	ext := ".mkv"
+	if videoID == "" {
+		ext = ".flac"
+		item.OutputMode = OutputModeAudio // Lands in the audio library
+	}
+	if fileIndex != nil {
+		if existing := fileIndex.FindVideo(LibraryIndexScope(config, item), videoID, video.Title, video.Artist, video.Duration); existing != nil {
+			preview.ExistingFile = existing.Path
+			if existingExt := filepath.Ext(existing.Path); existingExt != "" {
+				ext = existingExt
+			}
+		}
+	}
+	if metadata.Title != "" {
+		preview.OutputPath = planOutputPath(item, metadata, config, planOutputDir(item, metadata, config), ext)
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func stubTrackLookups(t *testing.T, video *VideoInfo, videoErr error, links *SongLinkTrackInfo, linksErr error) {
+	t.Helper()
+	origVideo, origLinks, origSearch := fetchVideoMetadata, resolveMusicLinks, searchVideoVariants
+	t.Cleanup(func() { fetchVideoMetadata, resolveMusicLinks, searchVideoVariants = origVideo, origLinks, origSearch })
+	searchVideoVariants = func(query string, maxResults int, cookiesBrowser string) ([]VideoInfo, error) {
+		return nil, nil
+	}
+	fetchVideoMetadata = func(videoID string) (*VideoInfo, error) {
+		if video == nil {
+			return nil, videoErr
+		}
+		cp := *video
+		return &cp, videoErr
+	}
+	resolveMusicLinks = func(musicURL string) (*SongLinkTrackInfo, error) {
+		return links, linksErr
+	}
+}
+
+func TestResolveTrack(t *testing.T) {
+	dir := t.TempDir()
+	config := GetDefaultConfig()
+	config.OutputDirectory = dir
+	config.NamingTemplate = "{artist} - {title}"
+
+	links := &SongLinkTrackInfo{
+		Title:  "Song",
+		Artist: "Artist",
+		ISRC:   "USAT21234567",
+		URLs:   SongLinkURLs{TidalURL: "https://tidal.com/browse/track/1", YouTubeURL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+	}
+	stubTrackLookups(t, &VideoInfo{ID: "dQw4w9WgXcQ", Title: "Song", Artist: "Artist", Duration: 200}, nil, links, nil)
+
+	preview, err := ResolveTrack("https://www.youtube.com/watch?v=dQw4w9WgXcQ", config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Video == nil || preview.Video.ISRC != "USAT21234567" {
+		t.Errorf("video should be merged with the song.link ISRC: %+v", preview.Video)
+	}
+	if preview.BestMatch == nil || !preview.BestMatch.IsValid || preview.BestMatch.Audio.Platform != "tidal" {
+		t.Errorf("best match = %+v", preview.BestMatch)
+	}
+	if want := filepath.Join(dir, "Artist - Song.mkv"); preview.OutputPath != want {
+		t.Errorf("output path = %q, want %q", preview.OutputPath, want)
+	}
+
+	// Already in the library: the existing file is reported
+	existing := filepath.Join(dir, "Artist - Song.flac")
+	writeTestFile(t, existing, 10)
+	fi := NewFileIndex(dir)
+	fi.AddEntry(FileIndexEntry{Path: existing, Title: "Song", Artist: "Artist"})
+	preview, err = ResolveTrack("https://www.youtube.com/watch?v=dQw4w9WgXcQ", config, fi)
+	if err != nil || preview.ExistingFile != existing || !strings.HasSuffix(preview.OutputPath, ".flac") {
+		t.Errorf("preview = %+v, %v", preview, err)
+	}
+}
+
+func TestResolveTrackPartialFailure(t *testing.T) {
+	config := GetDefaultConfig()
+	config.OutputDirectory = t.TempDir()
+
+	// song.link down: video info alone is still a preview
+	stubTrackLookups(t, &VideoInfo{ID: "dQw4w9WgXcQ", Title: "Song", Artist: "Artist"}, nil, nil, errors.New("rate limited"))
+	preview, err := ResolveTrack("https://youtu.be/dQw4w9WgXcQ", config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Warnings) != 1 || preview.Links != nil || preview.OutputPath == "" {
+		t.Errorf("preview = %+v", preview)
+	}
+
+	// Streaming URL without a YouTube link: song.link metadata, audio-only path
+	stubTrackLookups(t, nil, errors.New("not called"), &SongLinkTrackInfo{Title: "Song", Artist: "Artist", URLs: SongLinkURLs{TidalURL: "https://tidal.com/browse/track/1"}}, nil)
+	preview, err = ResolveTrack("https://open.spotify.com/track/abc", config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Video != nil || !strings.HasSuffix(preview.OutputPath, ".flac") || len(preview.Candidates) != 1 {
+		t.Errorf("preview = %+v", preview)
+	}
+
+	stubTrackLookups(t, nil, errors.New("gone"), nil, errors.New("rate limited"))
+	if _, err := ResolveTrack("https://youtu.be/dQw4w9WgXcQ", config, nil); err == nil {
+		t.Error("resolve should fail when nothing could be fetched")
+	}
+}
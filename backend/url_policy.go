@@ -0,0 +1,279 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// =============================================================================
+// URL policy
+// =============================================================================
+
+// Track links from song.link and the resolvers, stream URLs returned by
+// the download proxies and cover art URLs from metadata are chosen by third
+// parties, yet YouFlac fetches them and hands them to yt-dlp, ffmpeg and the
+// Python downloaders. Each kind of URL is checked against a URLPolicy first:
+// track links must be https links to the service they claim to be from, and
+// streams and images must not point into the server's own network (SSRF),
+// which Downloader also enforces on every redirect and, as a name can
+// resolve to another address by the time it is fetched (DNS rebinding), on
+// every connection it opens. Config.AllowPrivateURLs lifts the private
+// network rule for self-hosted proxies on the LAN.
+
+// URLPolicy restricts the URLs passed to HTTP clients and subprocesses
+type URLPolicy struct {
+	Schemes      []string // Allowed schemes, lower case
+	Hosts        []string // Allowed hosts, each with its subdomains; empty = any host
+	AllowPrivate bool     // Allow hosts on loopback, private and link-local networks
+}
+
+// errPrivateHost is returned for URLs into the server's own network
+var errPrivateHost = errors.New("host is on a private network")
+
+// trackURLHosts are the hosts of each music service's track links
+var trackURLHosts = map[string][]string{
+	"tidal":      {"tidal.com"},
+	"qobuz":      {"qobuz.com"},
+	"amazon":     {"amazon.com", "amazon.ca", "amazon.com.au", "amazon.com.br", "amazon.com.mx", "amazon.co.jp", "amazon.co.uk", "amazon.de", "amazon.es", "amazon.fr", "amazon.in", "amazon.it"},
+	"deezer":     {"deezer.com", "deezer.page.link"},
+	"spotify":    {"spotify.com", "spotify.link"},
+	"applemusic": {"music.apple.com"},
+	"soundcloud": {"soundcloud.com"},
+	"youtube":    {"youtube.com", "youtu.be"},
+	"lucida":     {"lucida.to", "lucida.su"},
+}
+
+var (
+	urlPolicyMutex   sync.RWMutex
+	allowPrivateURLs bool // Config.AllowPrivateURLs; set in TestMain for the loopback test servers
+)
+
+// ConfigureURLPolicy applies Config.AllowPrivateURLs
+func ConfigureURLPolicy(config *Config) {
+	urlPolicyMutex.Lock()
+	defer urlPolicyMutex.Unlock()
+	allowPrivateURLs = config.AllowPrivateURLs
+}
+
+// TrackURLPolicy returns the policy for track links of service ("tidal",
+// "qobuz", ...), or for a link of any known service when service is ""
+func TrackURLPolicy(service string) URLPolicy {
+	policy := URLPolicy{Schemes: []string{"https"}}
+	if service != "" {
+		policy.Hosts = trackURLHosts[service]
+		if policy.Hosts == nil {
+			policy.Hosts = []string{} // Unknown service: no host is allowed
+		}
+		return policy
+	}
+	policy.Hosts = []string{}
+	for _, hosts := range trackURLHosts {
+		policy.Hosts = append(policy.Hosts, hosts...)
+	}
+	return policy
+}
+
+// StreamURLPolicy returns the policy for audio streams and images: http or
+// https on any public host
+func StreamURLPolicy() URLPolicy {
+	urlPolicyMutex.RLock()
+	defer urlPolicyMutex.RUnlock()
+	return URLPolicy{Schemes: []string{"http", "https"}, AllowPrivate: allowPrivateURLs}
+}
+
+// ValidateTrackURLFor checks a track link of service against its policy
+func ValidateTrackURLFor(service, rawURL string) error {
+	if err := TrackURLPolicy(service).Check(rawURL); err != nil {
+		return fmt.Errorf("rejected %s track URL: %w", service, err)
+	}
+	return nil
+}
+
+// validateTrackURLOf checks that a track link is from one of services and
+// returns that service. Downloaders that take links of several services
+// check them this way before handing them to a subprocess or web API.
+func validateTrackURLOf(services []string, rawURL string) (string, error) {
+	for _, service := range services {
+		if ValidateTrackURLFor(service, rawURL) == nil {
+			return service, nil
+		}
+	}
+	return "", fmt.Errorf("rejected track URL: not a link of %s", strings.Join(services, ", "))
+}
+
+// Check returns an error when rawURL breaks the policy. Host names are
+// resolved to check their addresses unless private hosts are allowed or
+// the host is on the allowlist.
+func (p URLPolicy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	return p.checkURL(u)
+}
+
+func (p URLPolicy) checkURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if !slices.Contains(p.Schemes, scheme) {
+		return fmt.Errorf("scheme %q is not allowed (allowed: %s)", u.Scheme, strings.Join(p.Schemes, ", "))
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("URL has no host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("URL must not carry credentials")
+	}
+
+	if p.Hosts != nil {
+		if !slices.ContainsFunc(p.Hosts, func(allowed string) bool {
+			return host == allowed || strings.HasSuffix(host, "."+allowed)
+		}) {
+			return fmt.Errorf("host %q is not allowed", host)
+		}
+		return nil
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+	return checkPublicHost(host)
+}
+
+// urlLookupTimeout bounds the DNS lookup of a host name being checked
+const urlLookupTimeout = 5 * time.Second
+
+// lookupHost resolves host names for checkPublicHost. Replaced in tests.
+var lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// checkPublicHost returns errPrivateHost when host is, or resolves to, an
+// address that isn't on the public internet, and an error when it doesn't
+// resolve
+func checkPublicHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", errPrivateHost, host)
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", errPrivateHost, host)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), urlLookupTimeout)
+	defer cancel()
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errPrivateHost, host, addr)
+		}
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the special-purpose ranges netip.Addr's methods
+// don't cover: "this network", carrier-grade NAT, IETF protocol
+// assignments, benchmarking and NAT64
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddr reports whether addr is a public unicast address
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDialAddr is the net.Dialer Control of policyTransport: it refuses
+// connections to addresses that aren't public, whatever name they were
+// resolved from, unless private URLs are allowed
+func checkDialAddr(network, address string, _ syscall.RawConn) error {
+	urlPolicyMutex.RLock()
+	allowPrivate := allowPrivateURLs
+	urlPolicyMutex.RUnlock()
+	if allowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unexpected dial address %q: %w", address, err)
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: connection to %s refused", errPrivateHost, addrPort.Addr())
+	}
+	return nil
+}
+
+// policyTransport is sharedTransport with connections checked by
+// checkDialAddr. It has its own connection pool, so it never reuses a
+// connection opened without the check.
+var policyTransport http.RoundTripper = &userAgentTransport{base: newPolicyBaseTransport()}
+
+func newPolicyBaseTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: checkDialAddr}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// policyClient returns a copy of client that checks every redirect against
+// policy. Clients on sharedTransport also get their connections checked
+// (see policyTransport); through a proxy the proxy resolves the host, so
+// only the checks of the URLs apply.
+func policyClient(client *http.Client, policy URLPolicy) *http.Client {
+	guarded := *client
+	if policy.Hosts == nil && !policy.AllowPrivate && (client.Transport == nil || client.Transport == sharedTransport) {
+		guarded.Transport = policyTransport
+	}
+	next := client.CheckRedirect
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := policy.checkURL(req.URL); err != nil {
+			return fmt.Errorf("redirect to %s rejected: %w", req.URL.Redacted(), err)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &guarded
+}
+
+// urlSchemePattern matches a URL scheme; a single letter is a Windows drive
+var urlSchemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]+:`)
+
+// isLocalPath reports whether s is a file path rather than a URL of some
+// scheme (file:, ftp:, concat: ...) that ffmpeg would open
+func isLocalPath(s string) bool {
+	return !urlSchemePattern.MatchString(s)
+}
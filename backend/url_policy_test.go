@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// blockPrivateURLs turns the private network rule on for one test
+func blockPrivateURLs(t *testing.T) {
+	t.Helper()
+	ConfigureURLPolicy(&Config{AllowPrivateURLs: false})
+	t.Cleanup(func() { ConfigureURLPolicy(&Config{AllowPrivateURLs: true}) })
+}
+
+func TestTrackURLPolicy(t *testing.T) {
+	valid := map[string]string{
+		"tidal":  "https://listen.tidal.com/track/123",
+		"qobuz":  "https://open.qobuz.com/track/123",
+		"amazon": "https://music.amazon.co.uk/albums/B0?trackAsin=B1",
+		"deezer": "https://www.deezer.com/track/123",
+	}
+	for service, u := range valid {
+		if err := ValidateTrackURLFor(service, u); err != nil {
+			t.Errorf("%s %s: %v", service, u, err)
+		}
+	}
+
+	invalid := []struct{ service, url string }{
+		{"tidal", "https://qobuz.com/track/1"},         // Another service
+		{"tidal", "https://tidal.com.evil.example/1"},  // Look-alike host
+		{"tidal", "https://eviltidal.com/track/1"},     // Suffix without a dot
+		{"tidal", "http://tidal.com/track/1"},          // Not https
+		{"tidal", "https://user:pw@tidal.com/track/1"}, // Credentials
+		{"qobuz", "https://169.254.169.254/latest"},    // Metadata endpoint
+		{"napster", "https://napster.com/track/1"},     // Unknown service
+	}
+	for _, tc := range invalid {
+		if err := ValidateTrackURLFor(tc.service, tc.url); err == nil {
+			t.Errorf("%s %s accepted", tc.service, tc.url)
+		}
+	}
+	if err := ValidateTrackURL("https://internal.example/track/1"); err == nil {
+		t.Error("ValidateTrackURL accepted a host of no music service")
+	}
+
+	// Downloaders only take links of the services they handle
+	if service, err := validateTrackURLOf(streamripServices, "https://www.deezer.com/track/123"); err != nil || service != "deezer" {
+		t.Errorf("streamrip deezer link: %q, %v", service, err)
+	}
+	if _, err := validateTrackURLOf(streamripServices, "https://music.amazon.com/albums/B0?trackAsin=B1"); err == nil {
+		t.Error("streamrip accepted an Amazon link")
+	}
+}
+
+func TestStreamURLPolicy(t *testing.T) {
+	blockPrivateURLs(t)
+	saved := lookupHost
+	t.Cleanup(func() { lookupHost = saved })
+	lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if host == "rebind.example" {
+			return []netip.Addr{netip.MustParseAddr("10.0.0.5")}, nil
+		}
+		return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+	}
+
+	policy := StreamURLPolicy()
+	for _, u := range []string{"https://cdn.example/stream.flac", "http://93.184.216.34/a.jpg"} {
+		if err := policy.Check(u); err != nil {
+			t.Errorf("%s: %v", u, err)
+		}
+	}
+	for _, u := range []string{
+		"http://127.0.0.1:8080/", "http://localhost/", "http://[::1]/", "http://192.168.1.10/a.flac",
+		"http://169.254.169.254/latest/meta-data", "http://100.64.0.1/", "http://[::ffff:10.0.0.1]/",
+		"https://rebind.example/a.flac", "file:///etc/passwd", "ftp://cdn.example/a.flac",
+	} {
+		if err := policy.Check(u); err == nil {
+			t.Errorf("%s accepted", u)
+		}
+	}
+
+	ConfigureURLPolicy(&Config{AllowPrivateURLs: true})
+	if err := StreamURLPolicy().Check("http://192.168.1.10/a.flac"); err != nil {
+		t.Errorf("private host with AllowPrivateURLs: %v", err)
+	}
+}
+
+func TestDownloader_RejectsPrivateRedirect(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer internal.Close()
+
+	// Loopback stands in for a public host that redirects inward
+	saved := lookupHost
+	t.Cleanup(func() { lookupHost = saved })
+	lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+	}
+	public := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer public.Close()
+	publicURL := strings.Replace(public.URL, "127.0.0.1", "public.example", 1)
+
+	blockPrivateURLs(t)
+	d := &Downloader{Client: &http.Client{Transport: rewriteHostTransport{to: strings.TrimPrefix(public.URL, "http://")}}, Attempts: 1}
+	err := d.Download(context.Background(), publicURL, filepath.Join(t.TempDir(), "out"), ExpectedFile{})
+	if err == nil || !errors.Is(err, errPrivateHost) {
+		t.Errorf("Download followed a redirect to loopback: %v", err)
+	}
+	if err := d.Download(context.Background(), internal.URL, filepath.Join(t.TempDir(), "out"), ExpectedFile{}); !errors.Is(err, errPrivateHost) {
+		t.Errorf("Download fetched a loopback URL: %v", err)
+	}
+}
+
+// A name that passed the check but resolves to a private address when the
+// request is made (DNS rebinding) is refused at connect time
+func TestPolicyClient_RefusesPrivateConnections(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer internal.Close()
+
+	blockPrivateURLs(t)
+	client := policyClient(&http.Client{Transport: sharedTransport}, StreamURLPolicy())
+	resp, err := client.Get(internal.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("connected to a loopback address")
+	}
+	if !errors.Is(err, errPrivateHost) {
+		t.Errorf("err = %v, want errPrivateHost", err)
+	}
+
+	// Allowed again with AllowPrivateURLs, without a new client
+	ConfigureURLPolicy(&Config{AllowPrivateURLs: true})
+	resp, err = client.Get(internal.URL)
+	if err != nil {
+		t.Fatalf("with AllowPrivateURLs: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestStreamURLPolicy_UnresolvableHost(t *testing.T) {
+	blockPrivateURLs(t)
+	saved := lookupHost
+	t.Cleanup(func() { lookupHost = saved })
+	lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if err := StreamURLPolicy().Check("https://nowhere.example/a.flac"); err == nil {
+		t.Error("a host that doesn't resolve passed the check")
+	}
+}
+
+// rewriteHostTransport sends requests for public.example to a test server
+type rewriteHostTransport struct{ to string }
+
+func (rt rewriteHostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "public.example" || strings.HasPrefix(req.URL.Host, "public.example:") {
+		req = req.Clone(req.Context())
+		req.URL.Host = rt.to
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFetchImage_RejectsOtherProtocols(t *testing.T) {
+	for _, u := range []string{"file:///etc/passwd", "concat:/a.jpg|/b.jpg", "ftp://example.com/a.jpg"} {
+		if _, _, _, err := fetchImage(u); err == nil {
+			t.Errorf("fetchImage(%q) accepted", u)
+		}
+	}
+	if path, _, cleanup, err := fetchImage(filepath.Join(t.TempDir(), "cover.jpg")); err != nil || path == "" {
+		t.Errorf("local path rejected: %v", err)
+	} else {
+		cleanup()
+	}
+}
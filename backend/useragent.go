@@ -0,0 +1,137 @@
+package backend
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// User agents
+// =============================================================================
+
+// Every outgoing API request goes through userAgentTransport, which fills in
+// the User-Agent by host: services whose usage policies ask clients to
+// identify themselves (LRCLIB, MusicBrainz, ...) get the app user agent,
+// everything else a browser one. Both can be changed, and single services
+// or hosts overridden, with Config.UserAgent, Config.AppUserAgent and
+// Config.UserAgentOverrides. Requests that set their own User-Agent keep it.
+
+// Built-in user agents
+const (
+	DefaultBrowserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+	DefaultAppUserAgent     = "YouFlac/1.0 (https://github.com/kushiemoon-dev/youflac)"
+)
+
+// userAgentServices maps API hosts to the service names accepted as keys in
+// Config.UserAgentOverrides
+var userAgentServices = map[string]string{
+	"vogel.qqdl.site":       "tidal",
+	"lucida.to":             "lucida",
+	"lucida.su":             "lucida",
+	"api.song.link":         "songlink",
+	"musicbrainz.org":       "musicbrainz",
+	"lrclib.net":            "lrclib",
+	"ws.audioscrobbler.com": "lastfm",
+	"www.googleapis.com":    "youtube",
+	"open.spotify.com":      "spotify",
+}
+
+// appIdentifiedServices get the app user agent instead of the browser one
+var appIdentifiedServices = map[string]bool{
+	"songlink":    true,
+	"musicbrainz": true,
+	"lrclib":      true,
+	"lastfm":      true,
+}
+
+var (
+	userAgentMutex     sync.RWMutex
+	browserUserAgent   = DefaultBrowserUserAgent
+	appUserAgent       = DefaultAppUserAgent
+	userAgentOverrides = map[string]string{}
+)
+
+// ConfigureUserAgents applies Config.UserAgent, Config.AppUserAgent and
+// Config.UserAgentOverrides ("service=agent" or "host=agent" entries)
+func ConfigureUserAgents(config *Config) {
+	overrides := make(map[string]string, len(config.UserAgentOverrides))
+	for _, entry := range config.UserAgentOverrides {
+		key, agent, ok := parseUserAgentOverride(entry)
+		if !ok {
+			slog.Warn("invalid user agent override ignored", "entry", entry)
+			continue
+		}
+		overrides[key] = agent
+	}
+
+	userAgentMutex.Lock()
+	defer userAgentMutex.Unlock()
+	browserUserAgent = DefaultBrowserUserAgent
+	if ua := strings.TrimSpace(config.UserAgent); ua != "" {
+		browserUserAgent = ua
+	}
+	appUserAgent = DefaultAppUserAgent
+	if ua := strings.TrimSpace(config.AppUserAgent); ua != "" {
+		appUserAgent = ua
+	}
+	userAgentOverrides = overrides
+}
+
+// parseUserAgentOverride splits "service=agent" into a lowercase key and the agent
+func parseUserAgentOverride(entry string) (key, agent string, ok bool) {
+	key, agent, ok = strings.Cut(entry, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	agent = strings.TrimSpace(agent)
+	return key, agent, ok && key != "" && agent != ""
+}
+
+// userAgentServiceNames returns the service names accepted in overrides, sorted
+func userAgentServiceNames() []string {
+	var names []string
+	for _, service := range userAgentServices {
+		if !slices.Contains(names, service) {
+			names = append(names, service)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// UserAgentFor returns the User-Agent sent to host
+func UserAgentFor(host string) string {
+	host = strings.ToLower(host)
+	service := userAgentServices[host]
+
+	userAgentMutex.RLock()
+	defer userAgentMutex.RUnlock()
+	if ua, ok := userAgentOverrides[host]; ok {
+		return ua
+	}
+	if ua, ok := userAgentOverrides[service]; ok && service != "" {
+		return ua
+	}
+	if appIdentifiedServices[service] {
+		return appUserAgent
+	}
+	return browserUserAgent
+}
+
+// userAgentTransport sets the configured User-Agent on requests without one
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", UserAgentFor(req.URL.Hostname()))
+	}
+	return t.base.RoundTrip(req)
+}
+
+// sharedTransport is used by every API client that does not need its own
+// proxy, so they share one connection pool and the user agent handling
+var sharedTransport http.RoundTripper = &userAgentTransport{base: http.DefaultTransport.(*http.Transport).Clone()}
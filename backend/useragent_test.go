@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUserAgentFor(t *testing.T) {
+	defer ConfigureUserAgents(&defaultConfig)
+
+	ConfigureUserAgents(&defaultConfig)
+	if got := UserAgentFor("lrclib.net"); got != DefaultAppUserAgent {
+		t.Errorf("lrclib = %q", got)
+	}
+	if got := UserAgentFor("vogel.qqdl.site"); got != DefaultBrowserUserAgent {
+		t.Errorf("tidal = %q", got)
+	}
+
+	ConfigureUserAgents(&Config{
+		UserAgent:          "Browser/2",
+		AppUserAgent:       "App/2",
+		UserAgentOverrides: []string{"lrclib=MyApp/1.0 (me@example.com)", "example.com=Custom/1", "broken"},
+	})
+	for host, want := range map[string]string{
+		"lrclib.net":      "MyApp/1.0 (me@example.com)",
+		"musicbrainz.org": "App/2",
+		"lucida.to":       "Browser/2",
+		"EXAMPLE.com":     "Custom/1",
+		"unknown.host":    "Browser/2",
+	} {
+		if got := UserAgentFor(host); got != want {
+			t.Errorf("UserAgentFor(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestUserAgentTransport(t *testing.T) {
+	defer ConfigureUserAgents(&defaultConfig)
+	ConfigureUserAgents(&Config{UserAgent: "Browser/3"})
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPClient(5*time.Second, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("User-Agent", "Explicit/1")
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "Browser/3" || got[1] != "Explicit/1" {
+		t.Errorf("user agents = %v", got)
+	}
+}
+
+func TestConfigValidate_UserAgentOverrides(t *testing.T) {
+	c := GetDefaultConfig()
+	c.UserAgentOverrides = []string{" LRCLIB = MyApp/1.0 ", "nosuchservice=X/1", "cdn.example.com=Y/1", "missing-agent=", ""}
+	v := c.Validate()
+	if len(c.UserAgentOverrides) != 2 || c.UserAgentOverrides[0] != "lrclib=MyApp/1.0" || c.UserAgentOverrides[1] != "cdn.example.com=Y/1" {
+		t.Errorf("overrides = %q", c.UserAgentOverrides)
+	}
+	if len(v.Warnings) != 2 {
+		t.Errorf("warnings = %+v", v.Warnings)
+	}
+}
@@ -91,15 +91,12 @@ func ValidateAudioSources(sources []string) error {
 	return nil
 }
 
-// ValidateTrackURL validates a music service URL before exec.Command usage.
-// Only https URLs are permitted.
+// ValidateTrackURL validates a music service URL before it is handed to a
+// downloader or subprocess: only https links to a known music service are
+// permitted (see TrackURLPolicy).
 func ValidateTrackURL(rawURL string) error {
-	u, err := url.ParseRequestURI(rawURL)
-	if err != nil {
+	if err := TrackURLPolicy("").Check(rawURL); err != nil {
 		return fmt.Errorf("invalid track URL: %w", err)
 	}
-	if u.Scheme != "https" {
-		return fmt.Errorf("track URL must use https, got %q", u.Scheme)
-	}
 	return nil
 }
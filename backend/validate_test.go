@@ -196,7 +196,8 @@ func TestValidateTrackURL_Invalid(t *testing.T) {
 	}
 }
 
-// Note: "https://valid.com/track; rm -rf /" is actually safe with exec.Command
+// Note: "https://tidal.com/track; rm -rf /" is actually safe with exec.Command
 // because Go passes args directly to the OS without shell interpretation.
 // ValidateTrackURL intentionally accepts such URLs — the protection is the
-// https-only scheme check, which prevents protocol-level injection.
+// https-only scheme check, which prevents protocol-level injection, and the
+// music service host allowlist (see url_policy_test.go).
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Alternative video lookup when the selected upload is removed or blocked
+
+// Alternative video modes (Config.AlternativeVideoMode)
+const (
+	AlternativeVideoOff     = "off"     // Degrade straight to audio-only
+	AlternativeVideoSuggest = "suggest" // Record candidates on the item, user picks one via retry
+	AlternativeVideoAuto    = "auto"    // Download the best candidate automatically
+)
+
+// Alternative video thresholds
+const (
+	altDurationTolerance = 10.0 // seconds; re-uploads often differ by a few seconds of intro/outro
+	altMinTitleScore     = 0.7
+	altMinArtistScore    = 0.6
+	altMaxCandidates     = 5
+	altSearchResults     = 10
+)
+
+// FindAlternativeVideos searches YouTube for other uploads of the same track.
+// Results exclude the original video ID and are ranked best first.
+func FindAlternativeVideos(original *VideoInfo, excludeID string, cookiesBrowser string) ([]VideoInfo, error) {
+	if original == nil || original.Title == "" {
+		return nil, fmt.Errorf("missing track info for alternative search")
+	}
+
+	query := original.Title
+	if original.Artist != "" {
+		query = fmt.Sprintf("%s %s", original.Artist, original.Title)
+	}
+
+	results, err := SearchYouTubeWithCookies(query, altSearchResults, cookiesBrowser)
+	if err != nil {
+		return nil, fmt.Errorf("alternative search failed: %w", err)
+	}
+
+	return RankAlternativeVideos(original, results, excludeID), nil
+}
+
+// RankAlternativeVideos filters search results down to plausible re-uploads
+// of the original track (matching duration and artist) and sorts them by score.
+func RankAlternativeVideos(original *VideoInfo, results []VideoInfo, excludeID string) []VideoInfo {
+	type scored struct {
+		video VideoInfo
+		score float64
+	}
+
+	var ranked []scored
+	for _, v := range results {
+		if v.ID == "" || v.ID == excludeID {
+			continue
+		}
+
+		titleScore := ComputeTitleSimilarity(original.Title, v.Title)
+		if titleScore < altMinTitleScore {
+			continue
+		}
+
+		artistScore := 1.0
+		if original.Artist != "" {
+			artistScore = ComputeArtistSimilarity(original.Artist, v.Artist)
+			if artistScore < altMinArtistScore {
+				continue
+			}
+		}
+
+		// Unknown durations (0) are tolerated but score lower
+		durationScore := 0.5
+		if original.Duration > 0 && v.Duration > 0 {
+			diff := math.Abs(original.Duration - v.Duration)
+			if diff > altDurationTolerance {
+				continue
+			}
+			durationScore = 1.0 - diff/altDurationTolerance
+		}
+
+		score := titleScore*0.4 + artistScore*0.3 + durationScore*0.3
+		ranked = append(ranked, scored{video: v, score: score})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].video.ViewCount > ranked[j].video.ViewCount
+	})
+
+	if len(ranked) > altMaxCandidates {
+		ranked = ranked[:altMaxCandidates]
+	}
+
+	alternatives := make([]VideoInfo, len(ranked))
+	for i, r := range ranked {
+		alternatives[i] = r.video
+	}
+	return alternatives
+}
@@ -0,0 +1,43 @@
+package backend
+
+import "testing"
+
+func TestRankAlternativeVideos(t *testing.T) {
+	original := &VideoInfo{ID: "orig0000000", Title: "Blinding Lights", Artist: "The Weeknd", Duration: 200}
+
+	results := []VideoInfo{
+		{ID: "orig0000000", Title: "Blinding Lights", Artist: "The Weeknd", Duration: 200},
+		{ID: "cover000000", Title: "Blinding Lights", Artist: "Some Cover Band", Duration: 201},
+		{ID: "loop0000000", Title: "Blinding Lights", Artist: "The Weeknd", Duration: 3600},
+		{ID: "other000000", Title: "Save Your Tears", Artist: "The Weeknd", Duration: 200},
+		{ID: "close000000", Title: "Blinding Lights", Artist: "The Weeknd", Duration: 206},
+		{ID: "exact000000", Title: "Blinding Lights (Official Video)", Artist: "The Weeknd", Duration: 201},
+	}
+
+	got := RankAlternativeVideos(original, results, original.ID)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 alternatives, got %d: %+v", len(got), got)
+	}
+	if got[0].ID != "exact000000" {
+		t.Errorf("expected closest duration match first, got %s", got[0].ID)
+	}
+	if got[1].ID != "close000000" {
+		t.Errorf("expected second alternative close000000, got %s", got[1].ID)
+	}
+}
+
+func TestRankAlternativeVideos_UnknownDuration(t *testing.T) {
+	original := &VideoInfo{Title: "Song", Artist: "Artist"}
+	results := []VideoInfo{{ID: "a0000000000", Title: "Song", Artist: "Artist", Duration: 180}}
+
+	got := RankAlternativeVideos(original, results, "")
+	if len(got) != 1 {
+		t.Fatalf("expected candidate to be kept when original duration is unknown, got %d", len(got))
+	}
+}
+
+func TestFindAlternativeVideos_MissingInfo(t *testing.T) {
+	if _, err := FindAlternativeVideos(&VideoInfo{}, "", ""); err == nil {
+		t.Error("expected error when title is missing")
+	}
+}
@@ -0,0 +1,180 @@
+package backend
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// Music video vs. Topic upload
+// =============================================================================
+
+// A track on a streaming service usually has two YouTube uploads: the
+// official music video and the auto-generated "Artist - Topic" audio upload
+// (a still cover image). song.link tends to link the Topic one, which makes
+// a poor music video. SelectVideoVariant searches YouTube for the track and
+// picks the upload Config.VideoVariant asks for, ranking candidates by how
+// well they match the track, channel hints (Topic, VEVO, verified) and views.
+
+// Values of Config.VideoVariant
+const (
+	VideoVariantLink  = "link"  // Keep the upload song.link returns, search only without one
+	VideoVariantVideo = "video" // Prefer the official music video
+	VideoVariantTopic = "topic" // Prefer the auto-generated Topic audio upload
+)
+
+// Variant ranking thresholds
+const (
+	variantDurationTolerance = 45.0 // seconds; music videos often add an intro or outro to the track
+	variantSearchResults     = 10
+)
+
+// YouTube search, replaced in tests
+var searchVideoVariants = SearchYouTubeWithCookies
+
+// musicVideoHints mark uploads that are the music video of a track
+var musicVideoHints = []string{"official video", "official music video", "music video", "official mv", "(mv)", "[mv]"}
+
+// nonVideoHints mark uploads that are a track but not its music video
+var nonVideoHints = []string{"lyric", "visualizer", "visualiser", "official audio", "(audio)", "[audio]", "cover", "karaoke", "reaction", "sped up", "slowed"}
+
+// IsTopicChannel reports whether channel is a YouTube auto-generated
+// "Artist - Topic" channel
+func IsTopicChannel(channel string) bool {
+	return strings.HasSuffix(strings.TrimSpace(channel), " - Topic")
+}
+
+// isOfficialChannel reports whether an upload comes from a verified or VEVO channel
+func isOfficialChannel(v *VideoInfo) bool {
+	channel := strings.ToLower(v.Channel)
+	return v.Verified || strings.HasSuffix(channel, "vevo")
+}
+
+// SelectVideoVariant returns the YouTube URL to download for a streaming
+// track. linked is the upload song.link returned ("" if none); it is kept
+// when the search fails or finds nothing better.
+func SelectVideoVariant(track *VideoInfo, linked string, config *Config) string {
+	if config == nil {
+		config = &defaultConfig
+	}
+	preference := config.VideoVariant
+	if (preference == VideoVariantLink && linked != "") || track == nil || track.Title == "" {
+		return linked
+	}
+
+	query := track.Title
+	if track.Artist != "" {
+		query = fmt.Sprintf("%s %s", track.Artist, track.Title)
+	}
+	results, err := searchVideoVariants(query, variantSearchResults, config.CookiesBrowser)
+	if err != nil {
+		slog.Debug("video variant search failed", "query", query, "err", err)
+		return linked
+	}
+	ranked := RankVideoVariants(track, results, preference)
+	if len(ranked) == 0 {
+		return linked
+	}
+	slog.Debug("video variant selected", "query", query, "preference", preference, "video", ranked[0].URL, "channel", ranked[0].Channel)
+	return ranked[0].URL
+}
+
+// RankVideoVariants drops search results that are not an upload of track and
+// sorts the rest best first for preference
+func RankVideoVariants(track *VideoInfo, results []VideoInfo, preference string) []VideoInfo {
+	type scored struct {
+		video VideoInfo
+		score float64
+	}
+
+	var ranked []scored
+	for _, v := range results {
+		if v.ID == "" {
+			continue
+		}
+		titleScore := ComputeTitleSimilarity(track.Title, v.Title)
+		if titleScore < altMinTitleScore {
+			continue
+		}
+		artistScore := 1.0
+		if track.Artist != "" {
+			artistScore = ComputeArtistSimilarity(track.Artist, v.Artist)
+			if artistScore < altMinArtistScore {
+				continue
+			}
+		}
+		durationScore := 0.5
+		if track.Duration > 0 && v.Duration > 0 {
+			diff := math.Abs(track.Duration - v.Duration)
+			if diff > variantDurationTolerance {
+				continue
+			}
+			durationScore = 1.0 - diff/variantDurationTolerance
+		}
+
+		score := titleScore*0.4 + artistScore*0.3 + durationScore*0.3
+		score += variantBonus(track, &v, preference)
+		ranked = append(ranked, scored{video: v, score: score})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].video.ViewCount > ranked[j].video.ViewCount
+	})
+
+	videos := make([]VideoInfo, len(ranked))
+	for i, r := range ranked {
+		videos[i] = r.video
+	}
+	return videos
+}
+
+// variantBonus scores an upload for preference on top of its match score
+func variantBonus(track *VideoInfo, v *VideoInfo, preference string) float64 {
+	title := strings.ToLower(v.Title)
+	topic := IsTopicChannel(v.Channel)
+	musicVideo := !topic && containsAny(title, musicVideoHints)
+	// Hints the track itself carries ("Song (Lyric Version)") say nothing
+	// about the upload
+	nonVideo := containsAny(title, nonVideoHints) && !containsAny(strings.ToLower(track.Title), nonVideoHints)
+
+	var bonus float64
+	switch preference {
+	case VideoVariantTopic:
+		if topic {
+			bonus += 0.5
+		}
+	default:
+		if topic {
+			bonus -= 0.5
+		}
+		if musicVideo {
+			bonus += 0.2
+		}
+		if nonVideo {
+			bonus -= 0.3
+		}
+	}
+	if isOfficialChannel(v) {
+		bonus += 0.1
+	}
+	// Views break ties between similar uploads: up to 0.1 for a billion views
+	if v.ViewCount > 0 {
+		bonus += min(math.Log10(float64(v.ViewCount))/90, 0.1)
+	}
+	return bonus
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
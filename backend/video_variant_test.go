@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func videoVariantResults() []VideoInfo {
+	return []VideoInfo{
+		{ID: "topic", Title: "Song", Artist: "Artist", Channel: "Artist - Topic", Duration: 200, ViewCount: 2_000_000, URL: "https://www.youtube.com/watch?v=topic"},
+		{ID: "lyrics", Title: "Song (Lyric Video)", Artist: "Artist", Channel: "Artist", Duration: 201, ViewCount: 50_000_000, URL: "https://www.youtube.com/watch?v=lyrics"},
+		{ID: "video", Title: "Song (Official Music Video)", Artist: "ArtistVEVO", Channel: "ArtistVEVO", Duration: 230, ViewCount: 10_000_000, Verified: true, URL: "https://www.youtube.com/watch?v=video"},
+		{ID: "other", Title: "Another Song", Artist: "Artist", Channel: "Artist", Duration: 200, URL: "https://www.youtube.com/watch?v=other"},
+	}
+}
+
+func TestRankVideoVariants(t *testing.T) {
+	track := &VideoInfo{Title: "Song", Artist: "Artist", Duration: 200}
+
+	ranked := RankVideoVariants(track, videoVariantResults(), VideoVariantVideo)
+	if len(ranked) != 3 {
+		t.Fatalf("ranked %d uploads, want 3 (other song dropped): %+v", len(ranked), ranked)
+	}
+	if ranked[0].ID != "video" || ranked[len(ranked)-1].ID != "topic" {
+		t.Errorf("video preference order = %s, %s, %s", ranked[0].ID, ranked[1].ID, ranked[2].ID)
+	}
+
+	ranked = RankVideoVariants(track, videoVariantResults(), VideoVariantTopic)
+	if len(ranked) == 0 || ranked[0].ID != "topic" {
+		t.Errorf("topic preference picked %+v", ranked)
+	}
+}
+
+func TestSelectVideoVariant(t *testing.T) {
+	orig := searchVideoVariants
+	t.Cleanup(func() { searchVideoVariants = orig })
+	searches := 0
+	searchVideoVariants = func(query string, maxResults int, cookiesBrowser string) ([]VideoInfo, error) {
+		searches++
+		return videoVariantResults(), nil
+	}
+
+	track := &VideoInfo{Title: "Song", Artist: "Artist", Duration: 200}
+	linked := "https://www.youtube.com/watch?v=linked"
+	config := GetDefaultConfig()
+
+	if got := SelectVideoVariant(track, linked, config); got != "https://www.youtube.com/watch?v=video" {
+		t.Errorf("video preference = %q", got)
+	}
+
+	config.VideoVariant = VideoVariantLink
+	if got := SelectVideoVariant(track, linked, config); got != linked || searches != 1 {
+		t.Errorf("link preference = %q after %d searches, want the song.link upload without searching", got, searches)
+	}
+	if got := SelectVideoVariant(track, "", config); got != "https://www.youtube.com/watch?v=video" {
+		t.Errorf("link preference without link = %q", got)
+	}
+
+	// Search failure keeps the song.link upload
+	config.VideoVariant = VideoVariantVideo
+	searchVideoVariants = func(query string, maxResults int, cookiesBrowser string) ([]VideoInfo, error) {
+		return nil, errors.New("yt-dlp missing")
+	}
+	if got := SelectVideoVariant(track, linked, config); got != linked {
+		t.Errorf("failed search = %q, want %q", got, linked)
+	}
+}
+
+func TestConfigValidate_VideoVariant(t *testing.T) {
+	config := GetDefaultConfig()
+	config.VideoVariant = "mv"
+	validation := config.Validate()
+	if !hasIssue(validation.Warnings, "videoVariant") || config.VideoVariant != VideoVariantVideo {
+		t.Errorf("VideoVariant = %q, warnings %+v", config.VideoVariant, validation.Warnings)
+	}
+}
@@ -0,0 +1,597 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// =============================================================================
+// Artist watchlist
+// =============================================================================
+
+// The watchlist follows favorite artists through their YouTube channel and/or
+// their MusicBrainz release groups. A check lists the latest uploads and
+// releases, looks up a music video for each new MusicBrainz release, and
+// queues it with the artist's quality settings. With RequireApproval (or
+// Config.ApprovalMode) the queued item waits for approval. The first check of
+// an artist only records what already exists, so following an artist does
+// not queue their back catalog.
+
+// Release states
+const (
+	WatchReleaseQueued    = "queued"    // Added to the download queue
+	WatchReleaseDismissed = "dismissed" // Hidden by the user
+	WatchReleaseNoVideo   = "no_video"  // MusicBrainz release without a matching music video
+)
+
+// Release sources
+const (
+	WatchSourceYouTube     = "youtube"
+	WatchSourceMusicBrainz = "musicbrainz"
+)
+
+const (
+	// watchlistScanDepth is how many of the latest channel uploads a check lists
+	watchlistScanDepth = 30
+	// watchlistTick is how often the scheduler looks for artists due a check
+	watchlistTick = 15 * time.Minute
+	// watchlistMaxReleases caps the stored release log, oldest dropped first
+	watchlistMaxReleases = 500
+)
+
+// watchlistSearch finds music video candidates; replaced in tests
+var watchlistSearch = SearchYouTube
+
+// WatchedArtist is one followed artist
+type WatchedArtist struct {
+	ID                  string    `json:"id"`
+	Name                string    `json:"name"`
+	ChannelURL          string    `json:"channelUrl,omitempty"`    // Canonical YouTube channel URL
+	MusicBrainzID       string    `json:"musicbrainzId,omitempty"` // MusicBrainz artist MBID
+	Quality             string    `json:"quality,omitempty"`       // Video quality for this artist, "" = Config.VideoQuality
+	AudioSourcePriority []string  `json:"audioSourcePriority,omitempty"`
+	RequireApproval     bool      `json:"requireApproval"`   // Queued releases wait for approval before downloading
+	SeenIDs             []string  `json:"seenIds,omitempty"` // Video IDs and release group IDs already handled
+	CreatedAt           time.Time `json:"createdAt"`
+	LastCheckAt         time.Time `json:"lastCheckAt,omitempty"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+// WatchRelease is a new release found by a check
+type WatchRelease struct {
+	ID          string    `json:"id"`
+	ArtistID    string    `json:"artistId"`
+	Artist      string    `json:"artist"`
+	Title       string    `json:"title"`
+	Source      string    `json:"source"` // youtube, musicbrainz
+	VideoURL    string    `json:"videoUrl,omitempty"`
+	Thumbnail   string    `json:"thumbnail,omitempty"`
+	Duration    float64   `json:"duration,omitempty"`
+	ReleaseDate string    `json:"releaseDate,omitempty"` // MusicBrainz first release date
+	Status      string    `json:"status"`
+	QueueItemID string    `json:"queueItemId,omitempty"`
+	FoundAt     time.Time `json:"foundAt"`
+}
+
+// WatchCheckResult summarizes one check
+type WatchCheckResult struct {
+	Seeded   int            `json:"seeded"`   // Existing releases recorded by a first check
+	Releases []WatchRelease `json:"releases"` // New releases, queued or without a video
+	Existing int            `json:"existing"` // New releases already downloaded or in the library
+	Filtered int            `json:"filtered"` // Outside the duration limits
+}
+
+// Watchlist stores the followed artists and the releases found for them
+type Watchlist struct {
+	artists   []WatchedArtist
+	releases  []WatchRelease
+	filePath  string
+	mbBaseURL string
+	client    *http.Client
+	mu        sync.Mutex
+	checkMu   sync.Mutex // One check at a time
+}
+
+type watchlistFile struct {
+	Artists  []WatchedArtist `json:"artists"`
+	Releases []WatchRelease  `json:"releases"`
+}
+
+// NewWatchlist loads watchlist.json from the config directory
+func NewWatchlist() *Watchlist {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+
+	w := &Watchlist{
+		filePath:  filepath.Join(configDir, "youflac", "watchlist.json"),
+		mbBaseURL: musicBrainzAPIBase,
+		client:    httpClient,
+	}
+	w.load()
+	return w
+}
+
+func (w *Watchlist) load() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.artists = []WatchedArtist{}
+	w.releases = []WatchRelease{}
+	data, err := os.ReadFile(w.filePath)
+	if err != nil {
+		return
+	}
+	var file watchlistFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		slog.Warn("failed to parse watchlist", "path", w.filePath, "err", err)
+		return
+	}
+	if file.Artists != nil {
+		w.artists = file.Artists
+	}
+	if file.Releases != nil {
+		w.releases = file.Releases
+	}
+}
+
+// save writes the watchlist; callers hold w.mu
+func (w *Watchlist) save() error {
+	if err := os.MkdirAll(filepath.Dir(w.filePath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(watchlistFile{Artists: w.artists, Releases: w.releases}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(w.filePath, data, 0644)
+}
+
+// List returns all watched artists
+func (w *Watchlist) List() []WatchedArtist {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.artists)
+}
+
+// Get returns a copy of one artist, or nil
+func (w *Watchlist) Get(id string) *WatchedArtist {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, artist := range w.artists {
+		if artist.ID == id {
+			return &artist
+		}
+	}
+	return nil
+}
+
+// Releases returns the found releases, newest first. An empty status
+// returns all of them.
+func (w *Watchlist) Releases(status string) []WatchRelease {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := []WatchRelease{}
+	for i := len(w.releases) - 1; i >= 0; i-- {
+		if status == "" || w.releases[i].Status == status {
+			result = append(result, w.releases[i])
+		}
+	}
+	return result
+}
+
+// Add watches an artist. Adding an artist with the same channel or MBID
+// updates its settings instead.
+func (w *Watchlist) Add(artist WatchedArtist) (*WatchedArtist, error) {
+	artist.Name = strings.TrimSpace(artist.Name)
+	if artist.Name == "" {
+		return nil, fmt.Errorf("artist name is required")
+	}
+	if artist.ChannelURL == "" && artist.MusicBrainzID == "" {
+		return nil, fmt.Errorf("a YouTube channel or a MusicBrainz artist ID is required")
+	}
+	if artist.ChannelURL != "" {
+		canonical, err := ParseChannelURL(artist.ChannelURL)
+		if err != nil {
+			return nil, err
+		}
+		artist.ChannelURL = canonical
+	}
+	if artist.MusicBrainzID != "" {
+		mbid, err := uuid.Parse(strings.TrimSpace(artist.MusicBrainzID))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MusicBrainz artist ID %q", artist.MusicBrainzID)
+		}
+		artist.MusicBrainzID = mbid.String()
+	}
+	if artist.Quality != "" && !containsString(validVideoQualities, artist.Quality) {
+		return nil, fmt.Errorf("invalid quality %q: must be one of %s", artist.Quality, strings.Join(validVideoQualities, ", "))
+	}
+	if err := ValidateAudioSources(artist.AudioSourcePriority); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.artists {
+		existing := &w.artists[i]
+		if (artist.ChannelURL != "" && existing.ChannelURL == artist.ChannelURL) ||
+			(artist.MusicBrainzID != "" && existing.MusicBrainzID == artist.MusicBrainzID) {
+			existing.Name = artist.Name
+			existing.Quality = artist.Quality
+			existing.AudioSourcePriority = artist.AudioSourcePriority
+			existing.RequireApproval = artist.RequireApproval
+			if artist.ChannelURL != "" {
+				existing.ChannelURL = artist.ChannelURL
+			}
+			if artist.MusicBrainzID != "" {
+				existing.MusicBrainzID = artist.MusicBrainzID
+			}
+			result := *existing
+			return &result, w.save()
+		}
+	}
+
+	artist.ID = uuid.New().String()
+	artist.SeenIDs = nil
+	artist.CreatedAt = time.Now()
+	artist.LastCheckAt = time.Time{}
+	artist.LastError = ""
+	w.artists = append(w.artists, artist)
+	return &artist, w.save()
+}
+
+// Remove stops watching an artist; its found releases are dropped too
+func (w *Watchlist) Remove(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, artist := range w.artists {
+		if artist.ID != id {
+			continue
+		}
+		w.artists = append(w.artists[:i], w.artists[i+1:]...)
+		w.releases = slices.DeleteFunc(w.releases, func(r WatchRelease) bool { return r.ArtistID == id })
+		return w.save()
+	}
+	return fmt.Errorf("watched artist not found: %s", id)
+}
+
+// watchCandidate is a release found by a check before it is queued or held
+type watchCandidate struct {
+	release WatchRelease
+	seenIDs []string // IDs to record once the candidate is handled
+}
+
+// Check looks for new releases of one artist. history and fileIndex may be nil.
+func (w *Watchlist) Check(id string, q *Queue, history *History, fileIndex *FileIndex) (*WatchCheckResult, error) {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	artist := w.Get(id)
+	if artist == nil {
+		return nil, fmt.Errorf("watched artist not found: %s", id)
+	}
+	seen := make(map[string]bool, len(artist.SeenIDs))
+	for _, seenID := range artist.SeenIDs {
+		seen[seenID] = true
+	}
+	seeding := artist.LastCheckAt.IsZero()
+
+	var candidates []watchCandidate
+	var listed, newIDs, errs []string
+
+	if artist.ChannelURL != "" {
+		videos, err := w.latestUploads(artist)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for _, video := range videos {
+			listed = append(listed, video.ID)
+			if seen[video.ID] || seeding {
+				continue
+			}
+			seen[video.ID] = true
+			candidates = append(candidates, watchCandidate{
+				release: WatchRelease{
+					Source:    WatchSourceYouTube,
+					Title:     video.Title,
+					VideoURL:  video.URL,
+					Thumbnail: video.Thumbnail,
+					Duration:  video.Duration,
+				},
+				seenIDs: []string{video.ID},
+			})
+		}
+	}
+
+	if artist.MusicBrainzID != "" {
+		groups, err := w.releaseGroups(artist.MusicBrainzID)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for _, group := range groups {
+			listed = append(listed, group.ID)
+			if seen[group.ID] || seeding {
+				continue
+			}
+			seen[group.ID] = true
+			release := WatchRelease{Source: WatchSourceMusicBrainz, Title: group.Title, ReleaseDate: group.FirstReleaseDate}
+			ids := []string{group.ID}
+			video := findMusicVideo(artist.Name, group.Title)
+			switch {
+			case video == nil:
+				release.Status = WatchReleaseNoVideo
+			case seen[video.ID]:
+				// Already found through the channel
+				newIDs = append(newIDs, group.ID)
+				continue
+			default:
+				// The upload may also show up in the channel listing later
+				seen[video.ID] = true
+				ids = append(ids, video.ID)
+				release.VideoURL = video.URL
+				release.Thumbnail = video.Thumbnail
+				release.Duration = video.Duration
+			}
+			candidates = append(candidates, watchCandidate{release: release, seenIDs: ids})
+		}
+	}
+
+	if len(listed) == 0 && len(errs) > 0 {
+		checkErr := fmt.Errorf("failed to check artist: %s", strings.Join(errs, "; "))
+		w.finishCheck(id, nil, nil, checkErr)
+		return nil, checkErr
+	}
+
+	result := &WatchCheckResult{Releases: []WatchRelease{}}
+	if seeding {
+		result.Seeded = len(listed)
+		w.finishCheck(id, listed, nil, nil)
+		slog.Info("watchlist artist seeded", "artist", artist.Name, "existing", len(listed))
+		return result, nil
+	}
+
+	q.mutex.RLock()
+	config := q.configs.Get()
+	q.mutex.RUnlock()
+
+	var found []WatchRelease
+	for _, candidate := range candidates {
+		release := candidate.release
+		if release.Status != WatchReleaseNoVideo {
+			videoID, _ := ParseYouTubeURL(release.VideoURL)
+			check := CheckPlaylist(&PlaylistInfo{Videos: []PlaylistVideo{{
+				ID: videoID, Title: release.Title, Artist: artist.Name, Duration: release.Duration, URL: release.VideoURL,
+			}}}, config, history, fileIndex)
+			switch check.Entries[0].Status {
+			case PlaylistEntryDownloaded, PlaylistEntryInLibrary:
+				result.Existing++
+				newIDs = append(newIDs, candidate.seenIDs...)
+				continue
+			case PlaylistEntryFiltered:
+				result.Filtered++
+				newIDs = append(newIDs, candidate.seenIDs...)
+				continue
+			}
+			if err := queueWatchRelease(q, config, artist, &release); err != nil {
+				// Not marked as seen, so the next check tries again
+				slog.Warn("failed to queue watchlist release", "artist", artist.Name, "title", release.Title, "err", err)
+				continue
+			}
+		}
+
+		release.ID = uuid.New().String()
+		release.ArtistID = artist.ID
+		release.Artist = artist.Name
+		release.FoundAt = time.Now()
+		newIDs = append(newIDs, candidate.seenIDs...)
+		found = append(found, release)
+	}
+	result.Releases = append(result.Releases, found...)
+
+	var checkErr error
+	if len(errs) > 0 {
+		checkErr = fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	w.finishCheck(id, newIDs, found, checkErr)
+	slog.Info("watchlist artist checked", "artist", artist.Name, "new", len(found),
+		"existing", result.Existing, "filtered", result.Filtered)
+	return result, nil
+}
+
+// latestUploads lists the newest videos of the artist's channel
+func (w *Watchlist) latestUploads(artist *WatchedArtist) ([]PlaylistVideo, error) {
+	info, _, err := fetchPlaylistEntries(artist.ChannelURL+"/videos", PlaylistFetchOptions{Limit: watchlistScanDepth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channel: %w", err)
+	}
+	return info.Videos, nil
+}
+
+// mbReleaseGroup is a release group from the MusicBrainz browse API
+type mbReleaseGroup struct {
+	ID               string `json:"id"`
+	Title            string `json:"title"`
+	FirstReleaseDate string `json:"first-release-date"`
+}
+
+// releaseGroups browses all album, EP and single release groups of an artist
+func (w *Watchlist) releaseGroups(mbid string) ([]mbReleaseGroup, error) {
+	var groups []mbReleaseGroup
+	for offset := 0; ; {
+		var page struct {
+			Count  int              `json:"release-group-count"`
+			Groups []mbReleaseGroup `json:"release-groups"`
+		}
+		params := url.Values{
+			"artist": {mbid},
+			"type":   {"album|ep|single"},
+			"limit":  {"100"},
+			"offset": {strconv.Itoa(offset)},
+		}
+		if err := musicBrainzGet(w.client, w.mbBaseURL, "/release-group", params, &page); err != nil {
+			return groups, fmt.Errorf("failed to list MusicBrainz releases: %w", err)
+		}
+		groups = append(groups, page.Groups...)
+		offset += len(page.Groups)
+		if len(page.Groups) == 0 || offset >= page.Count {
+			return groups, nil
+		}
+	}
+}
+
+// findMusicVideo searches YouTube for the music video of a release, keeping
+// the first result whose title names the release
+func findMusicVideo(artist, title string) *VideoInfo {
+	results, err := watchlistSearch(artist+" "+title+" official music video", 5)
+	if err != nil {
+		slog.Debug("music video search failed", "artist", artist, "title", title, "err", err)
+		return nil
+	}
+	want := strings.ToLower(title)
+	for i := range results {
+		if strings.Contains(strings.ToLower(results[i].Title), want) {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// queueWatchRelease adds a release to the queue with the artist's settings
+func queueWatchRelease(q *Queue, config *Config, artist *WatchedArtist, release *WatchRelease) error {
+	videoID, err := ParseYouTubeURL(release.VideoURL)
+	if err != nil {
+		return err
+	}
+	request := DownloadRequest{
+		VideoURL:            release.VideoURL,
+		Quality:             artist.Quality,
+		AudioSourcePriority: artist.AudioSourcePriority,
+		RequireApproval:     artist.RequireApproval || subscriptionApproval(config),
+	}
+	if request.Quality == "" && config != nil {
+		request.Quality = config.VideoQuality
+	}
+	videoInfo := &VideoInfo{
+		ID:        videoID,
+		Title:     release.Title,
+		Artist:    artist.Name,
+		Duration:  release.Duration,
+		Thumbnail: release.Thumbnail,
+		URL:       release.VideoURL,
+	}
+	itemID, err := q.AddToQueueWithMetadata(request, videoInfo)
+	if err != nil {
+		return err
+	}
+	release.Status = WatchReleaseQueued
+	release.QueueItemID = itemID
+	return nil
+}
+
+// finishCheck records the outcome of a check on the stored artist
+func (w *Watchlist) finishCheck(id string, newIDs []string, found []WatchRelease, checkErr error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.artists {
+		artist := &w.artists[i]
+		if artist.ID != id {
+			continue
+		}
+		artist.SeenIDs = append(artist.SeenIDs, newIDs...)
+		artist.LastCheckAt = time.Now()
+		artist.LastError = ""
+		if checkErr != nil {
+			artist.LastError = checkErr.Error()
+		}
+		w.releases = append(w.releases, found...)
+		if extra := len(w.releases) - watchlistMaxReleases; extra > 0 {
+			w.releases = w.releases[extra:]
+		}
+		if err := w.save(); err != nil {
+			slog.Warn("failed to save watchlist", "err", err)
+		}
+		return
+	}
+}
+
+// Dismiss hides a release that has no music video
+func (w *Watchlist) Dismiss(releaseID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	release := w.findRelease(releaseID)
+	if release == nil {
+		return fmt.Errorf("release not found: %s", releaseID)
+	}
+	if release.Status != WatchReleaseNoVideo {
+		return fmt.Errorf("release %s is %s, only releases without a video can be dismissed", releaseID, release.Status)
+	}
+	release.Status = WatchReleaseDismissed
+	return w.save()
+}
+
+// findRelease returns a stored release; callers hold w.mu
+func (w *Watchlist) findRelease(id string) *WatchRelease {
+	for i := range w.releases {
+		if w.releases[i].ID == id {
+			return &w.releases[i]
+		}
+	}
+	return nil
+}
+
+// CheckDue checks every artist whose last check is older than interval
+func (w *Watchlist) CheckDue(interval time.Duration, q *Queue, history *History, fileIndex *FileIndex) {
+	for _, artist := range w.List() {
+		if time.Since(artist.LastCheckAt) < interval {
+			continue
+		}
+		if _, err := w.Check(artist.ID, q, history, fileIndex); err != nil {
+			slog.Warn("watchlist check failed", "artist", artist.Name, "err", err)
+		}
+	}
+}
+
+// Start periodically checks the watchlist per Config.WatchlistIntervalHours.
+// The config is re-read on every tick so changes in settings take effect
+// without a restart.
+func (w *Watchlist) Start(ctx context.Context, q *Queue, history *History, fileIndex *FileIndex) {
+	go func() {
+		ticker := time.NewTicker(watchlistTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				q.mutex.RLock()
+				config := q.configs.Get()
+				q.mutex.RUnlock()
+				if config == nil || config.WatchlistIntervalHours <= 0 {
+					continue
+				}
+				w.CheckDue(time.Duration(config.WatchlistIntervalHours*float64(time.Hour)), q, history, fileIndex)
+			}
+		}
+	}()
+}
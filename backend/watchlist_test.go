@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testArtistMBID = "5b11f4ce-a62d-471e-81fc-a69a8278c7da"
+
+func TestWatchlistAdd(t *testing.T) {
+	w := &Watchlist{filePath: filepath.Join(t.TempDir(), "watchlist.json")}
+
+	for _, bad := range []WatchedArtist{
+		{ChannelURL: "https://www.youtube.com/@artist"},
+		{Name: "Artist"},
+		{Name: "Artist", ChannelURL: "https://example.com/artist"},
+		{Name: "Artist", MusicBrainzID: "not-an-mbid"},
+		{Name: "Artist", MusicBrainzID: testArtistMBID, Quality: "8k"},
+	} {
+		if _, err := w.Add(bad); err == nil {
+			t.Errorf("Add(%+v) should fail", bad)
+		}
+	}
+
+	added, err := w.Add(WatchedArtist{Name: " Artist ", ChannelURL: "https://youtube.com/@artist/videos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Name != "Artist" || added.ChannelURL != "https://www.youtube.com/@artist" {
+		t.Errorf("added = %+v", added)
+	}
+
+	// Same channel: settings are updated in place
+	updated, err := w.Add(WatchedArtist{Name: "Artist", ChannelURL: "https://www.youtube.com/@artist", Quality: "1080p", MusicBrainzID: testArtistMBID})
+	if err != nil || updated.ID != added.ID || updated.Quality != "1080p" || updated.MusicBrainzID != testArtistMBID {
+		t.Fatalf("updated = %+v, %v", updated, err)
+	}
+	if len(w.List()) != 1 {
+		t.Errorf("watchlist has %d artists", len(w.List()))
+	}
+
+	if err := w.Remove(added.ID); err != nil || len(w.List()) != 0 {
+		t.Errorf("Remove = %v, %d left", err, len(w.List()))
+	}
+}
+
+func TestWatchlistCheck(t *testing.T) {
+	dir := t.TempDir()
+	listing := filepath.Join(dir, "listing.jsonl")
+	os.WriteFile(listing, []byte(`{"id":"vid00000001","title":"Old Song","duration":200}
+{"id":"vid00000002","title":"Older Song","duration":210}
+`), 0644)
+	fakePlaylistCommand(t, "cat "+listing)
+
+	groups := []map[string]string{{"id": "rg-old", "title": "Old Album", "first-release-date": "2020-01-01"}}
+	mb := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("artist") != testArtistMBID {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{"release-group-count": len(groups), "release-groups": groups})
+	}))
+	defer mb.Close()
+
+	oldSearch := watchlistSearch
+	watchlistSearch = func(query string, maxResults int) ([]VideoInfo, error) {
+		return []VideoInfo{
+			{ID: "unrelated01", Title: "Something Else", URL: "https://www.youtube.com/watch?v=unrelated01"},
+			{ID: "vid00000004", Title: "Artist - New Single (Official Video)", URL: "https://www.youtube.com/watch?v=vid00000004", Duration: 180},
+		}, nil
+	}
+	defer func() { watchlistSearch = oldSearch }()
+
+	w := &Watchlist{filePath: filepath.Join(dir, "watchlist.json"), mbBaseURL: mb.URL, client: http.DefaultClient}
+	artist, err := w.Add(WatchedArtist{Name: "Artist", ChannelURL: "https://www.youtube.com/@artist", MusicBrainzID: testArtistMBID, Quality: "720p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := newTestQueue()
+
+	// First check records the back catalog without queuing it
+	result, err := w.Check(artist.ID, q, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Seeded != 3 || len(result.Releases) != 0 || len(q.GetQueue()) != 0 {
+		t.Fatalf("seed = %+v, %d queued", result, len(q.GetQueue()))
+	}
+
+	// A new upload and a new release group
+	os.WriteFile(listing, []byte(`{"id":"vid00000003","title":"Brand New","duration":190}
+{"id":"vid00000001","title":"Old Song","duration":200}
+`), 0644)
+	groups = append(groups,
+		map[string]string{"id": "rg-new", "title": "New Single", "first-release-date": "2026-10-01"},
+		map[string]string{"id": "rg-novideo", "title": "B-Sides"})
+	watchlistSearchCalls := 0
+	search := watchlistSearch
+	watchlistSearch = func(query string, maxResults int) ([]VideoInfo, error) {
+		watchlistSearchCalls++
+		if watchlistSearchCalls == 2 {
+			return nil, nil // No video for B-Sides
+		}
+		return search(query, maxResults)
+	}
+
+	result, err = w.Check(artist.ID, q, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Releases) != 3 {
+		t.Fatalf("releases = %+v", result.Releases)
+	}
+	queued := q.GetQueue()
+	if len(queued) != 2 || queued[0].Quality != "720p" || queued[0].Artist != "Artist" {
+		t.Fatalf("queue = %+v", queued)
+	}
+	if got := w.Releases(WatchReleaseNoVideo); len(got) != 1 || got[0].Title != "B-Sides" {
+		t.Errorf("no-video releases = %+v", got)
+	}
+	if got := w.Releases(WatchReleaseQueued); len(got) != 2 || got[0].QueueItemID == "" {
+		t.Errorf("queued releases = %+v", got)
+	}
+
+	// Queued releases wait for approval
+	stubApprovalPlanner(t)
+	w.Add(WatchedArtist{Name: "Artist", ChannelURL: "https://www.youtube.com/@artist", RequireApproval: true})
+	w.artists[0].MusicBrainzID = "" // Channel only from here on
+	os.WriteFile(listing, []byte(`{"id":"vid00000005","title":"Held Back","duration":190}
+`), 0644)
+	result, err = w.Check(artist.ID, q, nil, nil)
+	if err != nil || len(result.Releases) != 1 || result.Releases[0].Status != WatchReleaseQueued {
+		t.Fatalf("approval check = %+v, %v", result, err)
+	}
+	if item := waitForStage(t, q, result.Releases[0].QueueItemID, "Awaiting approval"); item.Status != StatusAwaitingApproval {
+		t.Errorf("held item = %+v", item)
+	}
+
+	if err := w.Dismiss(result.Releases[0].ID); err == nil {
+		t.Error("queued release cannot be dismissed")
+	}
+	if err := w.Dismiss(w.Releases(WatchReleaseNoVideo)[0].ID); err != nil {
+		t.Errorf("dismiss release without video: %v", err)
+	}
+
+	// State survives a reload
+	reloaded := &Watchlist{filePath: w.filePath}
+	reloaded.load()
+	if len(reloaded.List()[0].SeenIDs) == 0 || len(reloaded.Releases("")) != 4 {
+		t.Errorf("reloaded = %+v", reloaded.List())
+	}
+}
@@ -1,12 +1,13 @@
 package backend
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -30,6 +31,7 @@ type VideoInfo struct {
 	Description string  `json:"description,omitempty"`
 	Channel     string  `json:"channel,omitempty"`
 	ViewCount   int64   `json:"viewCount,omitempty"`
+	Verified    bool    `json:"verified,omitempty"` // Search results only: the channel has YouTube's verified badge
 }
 
 // VideoFormat represents an available video format
@@ -53,6 +55,7 @@ type DownloadProgress struct {
 	Total       int64   `json:"total"`
 	Speed       float64 `json:"speed"`
 	ETA         string  `json:"eta"`
+	File        int     `json:"file"` // yt-dlp: 1-based file of the download, video then audio when it merges formats; 0 for HTTP downloads
 }
 
 // YouTube URL patterns
@@ -60,6 +63,7 @@ var (
 	youtubeRegex      = regexp.MustCompile(`(?:youtube\.com/watch\?v=|youtu\.be/|youtube\.com/embed/|youtube\.com/v/|youtube\.com/shorts/)([a-zA-Z0-9_-]{11})`)
 	youtubeMusicRegex = regexp.MustCompile(`music\.youtube\.com/watch\?v=([a-zA-Z0-9_-]{11})`)
 	playlistRegex     = regexp.MustCompile(`[?&]list=([a-zA-Z0-9_-]+)`)
+	youtubeIDRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
 )
 
 // ParseYouTubeURL extracts video ID from various YouTube URL formats
@@ -90,7 +94,7 @@ func ParseYouTubeURL(rawURL string) (string, error) {
 	}
 
 	// Check if it's already just a video ID
-	if len(rawURL) == 11 && regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`).MatchString(rawURL) {
+	if len(rawURL) == 11 && youtubeIDRegex.MatchString(rawURL) {
 		return rawURL, nil
 	}
 
@@ -119,6 +123,9 @@ type PlaylistVideo struct {
 	Thumbnail string  `json:"thumbnail"`
 	URL       string  `json:"url"`
 	Position  int     `json:"position"` // 1-based position in playlist
+
+	Availability string `json:"availability,omitempty"` // yt-dlp availability: public, unlisted, private, needs_auth, ...
+	UploadDate   string `json:"uploadDate,omitempty"`   // YYYYMMDD, "" when the listing does not say
 }
 
 // PlaylistInfo contains playlist metadata and videos
@@ -127,109 +134,22 @@ type PlaylistInfo struct {
 	Title  string          `json:"title"`
 	Author string          `json:"author"`
 	Videos []PlaylistVideo `json:"videos"`
-}
-
-// GetPlaylistVideos fetches all videos from a YouTube playlist
-// Uses yt-dlp --flat-playlist for fast metadata extraction
-func GetPlaylistVideos(playlistURL string) (*PlaylistInfo, error) {
-	// Extract playlist ID
-	playlistID := ExtractPlaylistID(playlistURL)
-	if playlistID == "" {
-		return nil, fmt.Errorf("could not extract playlist ID from URL: %s", playlistURL)
-	}
-
-	// Construct canonical playlist URL
-	canonicalURL := fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID)
 
-	// Use yt-dlp with --flat-playlist for fast extraction (no full video info)
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "yt-dlp",
-		"--flat-playlist",
-		"-j",
-		"--no-warnings",
-		canonicalURL,
-	)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch playlist: %w", err)
-	}
-
-	var videos []PlaylistVideo
-	var playlistTitle string
-	var playlistAuthor string
-	lines := strings.Split(string(output), "\n")
-	position := 0 // Track position in playlist
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var entry struct {
-			ID               string  `json:"id"`
-			Title            string  `json:"title"`
-			Duration         float64 `json:"duration"`
-			Channel          string  `json:"channel"`
-			Uploader         string  `json:"uploader"`
-			Thumbnail        string  `json:"thumbnail"`
-			PlaylistTitle    string  `json:"playlist_title"`
-			PlaylistUploader string  `json:"playlist_uploader"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue // Skip malformed entries
-		}
-
-		// Extract playlist info from first entry
-		if playlistTitle == "" && entry.PlaylistTitle != "" {
-			playlistTitle = entry.PlaylistTitle
-			playlistAuthor = entry.PlaylistUploader
-		}
-
-		if entry.ID == "" {
-			continue
-		}
-
-		position++ // Increment position for valid entries
-
-		artist := entry.Channel
-		if artist == "" {
-			artist = entry.Uploader
-		}
-		// Clean up "- Topic" suffix from auto-generated channels
-		artist = strings.TrimSuffix(artist, " - Topic")
-
-		// Get best thumbnail
-		thumbnail := entry.Thumbnail
-		if thumbnail == "" {
-			thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID)
-		}
-
-		videos = append(videos, PlaylistVideo{
-			ID:        entry.ID,
-			Title:     entry.Title,
-			Artist:    artist,
-			Duration:  entry.Duration,
-			Thumbnail: thumbnail,
-			URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
-			Position:  position, // Assign 1-based position
-		})
-	}
+	// Set when the fetch stopped early (deadline or page limit); pass
+	// Continuation to ContinuePlaylist for the remaining entries
+	Partial      bool   `json:"partial,omitempty"`
+	Continuation string `json:"continuation,omitempty"`
+	Total        int    `json:"total,omitempty"` // Playlist length reported by YouTube, 0 = unknown
+}
 
-	if len(videos) == 0 {
-		return nil, fmt.Errorf("playlist is empty or unavailable")
+// TrackTotal is the playlist length used for track numbering: the reported
+// total, or the highest fetched position when that is larger or unknown
+func (p *PlaylistInfo) TrackTotal() int {
+	total := max(p.Total, len(p.Videos))
+	for _, video := range p.Videos {
+		total = max(total, video.Position)
 	}
-
-	return &PlaylistInfo{
-		ID:     playlistID,
-		Title:  playlistTitle,
-		Author: playlistAuthor,
-		Videos: videos,
-	}, nil
+	return total
 }
 
 // SearchYouTube searches YouTube for videos matching a query
@@ -245,75 +165,7 @@ func SearchYouTube(query string, maxResults int) ([]VideoInfo, error) {
 	// ytsearchN:query format searches YouTube and returns N results
 	searchURL := fmt.Sprintf("ytsearch%d:%s", maxResults, query)
 
-	cmd := exec.CommandContext(ctx, "yt-dlp",
-		"--flat-playlist",
-		"-j",
-		"--no-warnings",
-		searchURL,
-	)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("search failed: %w", err)
-	}
-
-	var results []VideoInfo
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var entry struct {
-			ID        string  `json:"id"`
-			Title     string  `json:"title"`
-			Duration  float64 `json:"duration"`
-			Channel   string  `json:"channel"`
-			Uploader  string  `json:"uploader"`
-			Thumbnail string  `json:"thumbnail"`
-			ViewCount int64   `json:"view_count"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
-
-		if entry.ID == "" {
-			continue
-		}
-
-		artist := entry.Channel
-		if artist == "" {
-			artist = entry.Uploader
-		}
-		artist = strings.TrimSuffix(artist, " - Topic")
-
-		// Clean title - remove artist prefix if present
-		title := entry.Title
-		if artist != "" && strings.HasPrefix(title, artist+" - ") {
-			title = strings.TrimPrefix(title, artist+" - ")
-		}
-
-		// Get best thumbnail
-		thumbnail := entry.Thumbnail
-		if thumbnail == "" {
-			thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID)
-		}
-
-		results = append(results, VideoInfo{
-			ID:        entry.ID,
-			Title:     title,
-			Artist:    artist,
-			Duration:  entry.Duration,
-			Thumbnail: thumbnail,
-			URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
-			ViewCount: entry.ViewCount,
-		})
-	}
-
-	return results, nil
+	return searchYtdlp(ctx, []string{"--flat-playlist", "-j", "--no-warnings", searchURL})
 }
 
 // SearchYouTubeWithCookies searches YouTube with browser cookies for better results
@@ -333,82 +185,89 @@ func SearchYouTubeWithCookies(query string, maxResults int, cookiesBrowser strin
 		"--no-warnings",
 	}
 
-	// Add cookies if browser specified
-	if cookiesBrowser != "" {
-		resolvedBrowser, err := resolveCookiesBrowser(cookiesBrowser)
-		if err == nil {
-			args = append(args, "--cookies-from-browser", resolvedBrowser)
-		}
+	// Add cookies if browser specified (or uploaded cookies are stored)
+	cookieArgs, cleanup, err := ytdlpCookieArgs(cookiesBrowser)
+	if err == nil {
+		args = append(args, cookieArgs...)
 	}
+	defer cleanup()
 
 	args = append(args, searchURL)
 
-	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	return searchYtdlp(ctx, args)
+}
 
-	output, err := cmd.Output()
+// searchYtdlp runs a yt-dlp search and parses the results as they arrive
+func searchYtdlp(ctx context.Context, args []string) ([]VideoInfo, error) {
+	var results []VideoInfo
+	err := scanYtdlpJSON(ctx, args, func(line []byte) {
+		if video, ok := parseSearchEntry(line); ok {
+			results = append(results, video)
+		}
+	})
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
+	return results, nil
+}
 
-	var results []VideoInfo
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var entry struct {
-			ID        string  `json:"id"`
-			Title     string  `json:"title"`
-			Duration  float64 `json:"duration"`
-			Channel   string  `json:"channel"`
-			Uploader  string  `json:"uploader"`
-			Thumbnail string  `json:"thumbnail"`
-			ViewCount int64   `json:"view_count"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
-
-		if entry.ID == "" {
-			continue
-		}
-
-		artist := entry.Channel
-		if artist == "" {
-			artist = entry.Uploader
-		}
-		artist = strings.TrimSuffix(artist, " - Topic")
+// parseSearchEntry parses one line of yt-dlp's flat search output
+func parseSearchEntry(line []byte) (VideoInfo, bool) {
+	var entry struct {
+		ID        string  `json:"id"`
+		Title     string  `json:"title"`
+		Duration  float64 `json:"duration"`
+		Channel   string  `json:"channel"`
+		Uploader  string  `json:"uploader"`
+		Thumbnail string  `json:"thumbnail"`
+		ViewCount int64   `json:"view_count"`
+		Verified  bool    `json:"channel_is_verified"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.ID == "" {
+		return VideoInfo{}, false
+	}
 
-		title := entry.Title
-		if artist != "" && strings.HasPrefix(title, artist+" - ") {
-			title = strings.TrimPrefix(title, artist+" - ")
-		}
+	artist := entry.Channel
+	if artist == "" {
+		artist = entry.Uploader
+	}
+	artist = strings.TrimSuffix(artist, " - Topic")
 
-		thumbnail := entry.Thumbnail
-		if thumbnail == "" {
-			thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID)
-		}
+	// Clean title - remove artist prefix if present
+	title := entry.Title
+	if artist != "" && strings.HasPrefix(title, artist+" - ") {
+		title = strings.TrimPrefix(title, artist+" - ")
+	}
 
-		results = append(results, VideoInfo{
-			ID:        entry.ID,
-			Title:     title,
-			Artist:    artist,
-			Duration:  entry.Duration,
-			Thumbnail: thumbnail,
-			URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
-			ViewCount: entry.ViewCount,
-		})
+	// Get best thumbnail
+	thumbnail := entry.Thumbnail
+	if thumbnail == "" {
+		thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID)
 	}
 
-	return results, nil
+	return VideoInfo{
+		ID:        entry.ID,
+		Title:     title,
+		Artist:    artist,
+		Duration:  entry.Duration,
+		Thumbnail: thumbnail,
+		URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
+		Channel:   cmp.Or(entry.Channel, entry.Uploader),
+		ViewCount: entry.ViewCount,
+		Verified:  entry.Verified,
+	}, true
 }
 
 // GetVideoMetadata fetches video metadata using yt-dlp
 func GetVideoMetadata(videoID string) (*VideoInfo, error) {
+	if key := getYouTubeAPIKey(); key != "" {
+		info, err := getVideoMetadataAPI(key, videoID)
+		if err == nil {
+			return info, nil
+		}
+		slog.Warn("YouTube API metadata lookup failed, falling back to yt-dlp", "video", videoID, "err", err)
+	}
+
 	ctx := context.Background()
 
 	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
@@ -581,59 +440,93 @@ func resolveCookiesBrowser(browser string) (string, error) {
 	return browser, nil
 }
 
+// ytdlpCookieArgs returns the yt-dlp cookie options: browser cookies when a
+// browser is configured, otherwise uploaded cookies from the secret store.
+// cleanup removes the temporary cookies file and is always safe to call.
+func ytdlpCookieArgs(cookiesBrowser string) (args []string, cleanup func(), err error) {
+	if cookiesBrowser != "" {
+		resolved, err := resolveCookiesBrowser(cookiesBrowser)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return []string{"--cookies-from-browser", resolved}, func() {}, nil
+	}
+
+	path, cleanup, err := storedCookiesFile()
+	if err != nil {
+		slog.Warn("failed to load stored cookies", "err", err)
+		return nil, cleanup, nil
+	}
+	if path == "" {
+		return nil, cleanup, nil
+	}
+	return []string{"--cookies", path}, cleanup, nil
+}
+
+// VideoDownload is a video downloaded by DownloadVideo
+type VideoDownload struct {
+	Path     string
+	FormatID string // yt-dlp format(s) chosen, e.g. "137+140"
+	Format   string // yt-dlp description, e.g. "137 - 1920x1080 (1080p)+140 - audio only (medium)"
+}
+
 // DownloadVideo downloads video to specified path
 // quality can be: "best", "1080p", "720p", "480p", "360p"
 // cookiesBrowser can be: "firefox", "chrome", "chromium", "brave", "opera", "edge", "librewolf", or "" for none
-func DownloadVideo(videoID string, quality string, outputDir string, cookiesBrowser string) (string, error) {
-	ctx := context.Background()
+func DownloadVideo(videoID string, quality string, outputDir string, cookiesBrowser string) (*VideoDownload, error) {
+	return DownloadVideoWithProgress(videoID, quality, outputDir, cookiesBrowser, nil)
+}
 
+// DownloadVideoWithProgress is DownloadVideo reporting yt-dlp's progress to
+// progress (may be nil)
+func DownloadVideoWithProgress(videoID string, quality string, outputDir string, cookiesBrowser string, progress func(DownloadProgress)) (*VideoDownload, error) {
+	return DownloadVideoClip(context.Background(), videoID, quality, outputDir, cookiesBrowser, nil, progress)
+}
+
+// DownloadVideoClip is DownloadVideoWithProgress downloading only clip
+// (nil = the whole video). Cancelling ctx kills yt-dlp.
+func DownloadVideoClip(ctx context.Context, videoID string, quality string, outputDir string, cookiesBrowser string, clip *Clip, progress func(DownloadProgress)) (*VideoDownload, error) {
 	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
 
-	// Resolve browser name (handles librewolf -> firefox:path conversion)
-	resolvedBrowser := cookiesBrowser
-	if cookiesBrowser != "" {
-		var err error
-		resolvedBrowser, err = resolveCookiesBrowser(cookiesBrowser)
-		if err != nil {
-			return "", fmt.Errorf("failed to resolve browser cookies: %w", err)
-		}
+	// Browser cookies (librewolf -> firefox:path) or uploaded cookies from the secret store
+	cookieArgs, cleanupCookies, err := ytdlpCookieArgs(cookiesBrowser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve browser cookies: %w", err)
 	}
+	defer cleanupCookies()
+
+	// Build format selector based on quality
+	formatSelector := buildFormatSelector(quality)
 
-	// Build args for metadata fetch
+	// Build args for metadata fetch; with the format selector yt-dlp
+	// reports the format it will download
 	metadataArgs := []string{
 		"--dump-json",
+		"-f", formatSelector,
 		"--no-download",
 		"--no-playlist",
 	}
-	if resolvedBrowser != "" {
-		metadataArgs = append(metadataArgs, "--cookies-from-browser", resolvedBrowser)
-	}
+	metadataArgs = append(metadataArgs, cookieArgs...)
 	metadataArgs = append(metadataArgs, videoURL)
 
 	// Get metadata using yt-dlp directly (to support cookies)
-	metadataCmd := exec.CommandContext(ctx, "yt-dlp", metadataArgs...)
-	metadataOutput, err := metadataCmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get video info: %w", err)
-	}
-
 	var videoInfo struct {
-		Title string `json:"title"`
+		Title    string  `json:"title"`
+		FormatID string  `json:"format_id"`
+		Format   string  `json:"format"`
+		Duration float64 `json:"duration"`
 	}
-	if err := json.Unmarshal(metadataOutput, &videoInfo); err != nil {
-		return "", fmt.Errorf("failed to parse video info: %w", err)
+	if err := ytdlpDumpJSON(ctx, metadataArgs, &videoInfo); err != nil {
+		return nil, fmt.Errorf("failed to get video info: %w", err)
 	}
 
-	// Build format selector based on quality
-	formatSelector := buildFormatSelector(quality)
-
 	// Create output filename
 	safeTitle := sanitizeVideoFileName(videoInfo.Title)
 	outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.mp4", safeTitle))
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create output directory: %w", err)
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Use yt-dlp directly via exec.Command
@@ -643,25 +536,39 @@ func DownloadVideo(videoID string, quality string, outputDir string, cookiesBrow
 		"--merge-output-format", "mp4",
 		"-o", outputPath,
 	}
-	if resolvedBrowser != "" {
-		args = append(args, "--cookies-from-browser", resolvedBrowser)
+	args = append(args, ytdlpProgressArgs...)
+	args = append(args, cookieArgs...)
+	expectedDuration := videoInfo.Duration
+	if clip != nil {
+		args = append(args, clip.ytdlpArgs()...)
+		expectedDuration = clip.Length(videoInfo.Duration)
 	}
 	args = append(args, videoURL)
 
-	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	for attempt := 1; ; attempt++ {
+		if err := runYtdlpDownload(ctx, args, progress); err != nil {
+			return nil, err
+		}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("yt-dlp download failed: %w", err)
-	}
+		// Verify file exists
+		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("download completed but file not found: %s", outputPath)
+		}
 
-	// Verify file exists
-	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("download completed but file not found: %s", outputPath)
+		// yt-dlp can finish with fragments missing; a video clearly shorter
+		// than YouTube reports is downloaded again
+		err = verifyMediaDuration(outputPath, expectedDuration)
+		if err == nil {
+			break
+		}
+		if attempt == downloadVerifyAttempts {
+			return nil, fmt.Errorf("downloaded video failed verification: %w", err)
+		}
+		slog.Warn("downloaded video failed verification, retrying", "video", videoID, "attempt", attempt, "err", err)
+		os.Remove(outputPath)
 	}
 
-	return outputPath, nil
+	return &VideoDownload{Path: outputPath, FormatID: videoInfo.FormatID, Format: videoInfo.Format}, nil
 }
 
 // DownloadVideoOnly downloads only video stream (no audio)
@@ -723,6 +630,10 @@ func DownloadVideoOnly(videoID string, quality string, outputDir string) (string
 // buildFormatSelector creates yt-dlp format selector string
 func buildFormatSelector(quality string) string {
 	switch quality {
+	case "2160p":
+		return "bestvideo[height<=2160]+bestaudio/best[height<=2160]"
+	case "1440p":
+		return "bestvideo[height<=1440]+bestaudio/best[height<=1440]"
 	case "1080p":
 		return "bestvideo[height<=1080]+bestaudio/best[height<=1080]"
 	case "720p":
@@ -739,6 +650,10 @@ func buildFormatSelector(quality string) string {
 // buildVideoOnlyFormatSelector creates format selector for video-only download
 func buildVideoOnlyFormatSelector(quality string) string {
 	switch quality {
+	case "2160p":
+		return "bestvideo[height<=2160]"
+	case "1440p":
+		return "bestvideo[height<=1440]"
 	case "1080p":
 		return "bestvideo[height<=1080]"
 	case "720p":
@@ -0,0 +1,328 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// YouTube Data API v3
+// =============================================================================
+
+// With Config.YouTubeAPIKey set, playlist listing and basic video metadata
+// come from the YouTube Data API instead of one yt-dlp call per video: a
+// 500-entry playlist is 10 playlistItems pages plus 10 videos batches, about
+// 20 quota units. Formats and downloads still go through yt-dlp, and any API
+// failure (quota exceeded, bad key) falls back to yt-dlp.
+
+var (
+	youtubeAPIBase   = "https://www.googleapis.com/youtube/v3"
+	youtubeAPIClient = &http.Client{Timeout: 30 * time.Second, Transport: sharedTransport}
+
+	youtubeAPIKey   string
+	youtubeAPIMutex sync.RWMutex
+)
+
+// youtubeAPIPageSize is the maximum maxResults the API accepts
+const youtubeAPIPageSize = 50
+
+// ConfigureYouTubeAPI sets the Data API key; an empty key disables the API
+func ConfigureYouTubeAPI(config *Config) {
+	youtubeAPIMutex.Lock()
+	defer youtubeAPIMutex.Unlock()
+	youtubeAPIKey = strings.TrimSpace(config.YouTubeAPIKey)
+}
+
+func getYouTubeAPIKey() string {
+	youtubeAPIMutex.RLock()
+	defer youtubeAPIMutex.RUnlock()
+	return youtubeAPIKey
+}
+
+type youtubeAPIThumbnails struct {
+	Default  struct{ URL string } `json:"default"`
+	High     struct{ URL string } `json:"high"`
+	Maxres   struct{ URL string } `json:"maxres"`
+	Standard struct{ URL string } `json:"standard"`
+}
+
+func (t youtubeAPIThumbnails) best() string {
+	for _, u := range []string{t.Maxres.URL, t.Standard.URL, t.High.URL, t.Default.URL} {
+		if u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+type youtubeAPIVideo struct {
+	ID      string `json:"id"`
+	Snippet struct {
+		Title        string               `json:"title"`
+		Description  string               `json:"description"`
+		ChannelTitle string               `json:"channelTitle"`
+		PublishedAt  string               `json:"publishedAt"`
+		Thumbnails   youtubeAPIThumbnails `json:"thumbnails"`
+	} `json:"snippet"`
+	ContentDetails struct {
+		Duration string `json:"duration"` // ISO 8601, e.g. PT3M45S
+	} `json:"contentDetails"`
+	Statistics struct {
+		ViewCount string `json:"viewCount"`
+	} `json:"statistics"`
+	Status struct {
+		PrivacyStatus string `json:"privacyStatus"`
+	} `json:"status"`
+}
+
+// youtubeAPIGet calls one API endpoint and decodes the response into out
+func youtubeAPIGet(key, endpoint string, params url.Values, out interface{}) error {
+	params.Set("key", key)
+	resp, err := youtubeAPIClient.Get(youtubeAPIBase + "/" + endpoint + "?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("YouTube API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+				Errors  []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		reason := apiErr.Error.Message
+		if len(apiErr.Error.Errors) > 0 && apiErr.Error.Errors[0].Reason != "" {
+			reason = apiErr.Error.Errors[0].Reason
+		}
+		return fmt.Errorf("YouTube API %s returned %d: %s", endpoint, resp.StatusCode, reason)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode YouTube API response: %w", err)
+	}
+	return nil
+}
+
+// fetchYouTubeAPIVideos looks up to 50 videos in one call. Deleted and
+// private videos are missing from the result.
+func fetchYouTubeAPIVideos(key string, ids []string) (map[string]youtubeAPIVideo, error) {
+	var resp struct {
+		Items []youtubeAPIVideo `json:"items"`
+	}
+	params := url.Values{
+		"part": {"snippet,contentDetails,statistics,status"},
+		"id":   {strings.Join(ids, ",")},
+	}
+	if err := youtubeAPIGet(key, "videos", params, &resp); err != nil {
+		return nil, err
+	}
+	videos := make(map[string]youtubeAPIVideo, len(resp.Items))
+	for _, v := range resp.Items {
+		videos[v.ID] = v
+	}
+	return videos, nil
+}
+
+// splitYouTubeTitle derives artist and title like the yt-dlp path does:
+// Topic channels are named after the artist, other channels usually title
+// uploads "Artist - Title"
+func splitYouTubeTitle(title, channel string) (artist, cleanTitle string) {
+	artist = ChannelArtistName(channel)
+	if strings.HasSuffix(channel, " - Topic") {
+		return artist, title
+	}
+	if left, right, ok := strings.Cut(title, " - "); ok && left != "" && right != "" {
+		return strings.TrimSpace(left), strings.TrimSpace(right)
+	}
+	return artist, title
+}
+
+// getVideoMetadataAPI is GetVideoMetadata backed by the Data API
+func getVideoMetadataAPI(key, videoID string) (*VideoInfo, error) {
+	videos, err := fetchYouTubeAPIVideos(key, []string{videoID})
+	if err != nil {
+		return nil, err
+	}
+	v, ok := videos[videoID]
+	if !ok {
+		return nil, fmt.Errorf("video %s not found or private", videoID)
+	}
+
+	artist, title := splitYouTubeTitle(v.Snippet.Title, v.Snippet.ChannelTitle)
+	views, _ := strconv.ParseInt(v.Statistics.ViewCount, 10, 64)
+	return &VideoInfo{
+		ID:          videoID,
+		Title:       title,
+		Artist:      artist,
+		Duration:    parseISODuration(v.ContentDetails.Duration),
+		Thumbnail:   v.Snippet.Thumbnails.best(),
+		URL:         fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
+		UploadDate:  strings.ReplaceAll(strings.SplitN(v.Snippet.PublishedAt, "T", 2)[0], "-", ""),
+		Description: v.Snippet.Description,
+		Channel:     v.Snippet.ChannelTitle,
+		ViewCount:   views,
+	}, nil
+}
+
+// fetchPlaylistAPI lists a playlist through the Data API. Start and Limit
+// work like the yt-dlp path, and a cut-short page gets a continuation token.
+func fetchPlaylistAPI(key, playlistID string, opts PlaylistFetchOptions) (*PlaylistInfo, error) {
+	start := max(opts.Start, 1)
+	info := &PlaylistInfo{ID: playlistID}
+
+	var meta struct {
+		Items []struct {
+			Snippet struct {
+				Title        string `json:"title"`
+				ChannelTitle string `json:"channelTitle"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := youtubeAPIGet(key, "playlists", url.Values{"part": {"snippet"}, "id": {playlistID}}, &meta); err != nil {
+		return nil, err
+	}
+	if len(meta.Items) == 0 {
+		return nil, fmt.Errorf("playlist is empty or unavailable")
+	}
+	info.Title = meta.Items[0].Snippet.Title
+	info.Author = meta.Items[0].Snippet.ChannelTitle
+
+	pageToken := ""
+	position := 0
+	for {
+		var page struct {
+			NextPageToken string `json:"nextPageToken"`
+			PageInfo      struct {
+				TotalResults int `json:"totalResults"`
+			} `json:"pageInfo"`
+			Items []struct {
+				Snippet struct {
+					Title                  string               `json:"title"`
+					VideoOwnerChannelTitle string               `json:"videoOwnerChannelTitle"`
+					Thumbnails             youtubeAPIThumbnails `json:"thumbnails"`
+				} `json:"snippet"`
+				ContentDetails struct {
+					VideoID          string `json:"videoId"`
+					VideoPublishedAt string `json:"videoPublishedAt"`
+				} `json:"contentDetails"`
+				Status struct {
+					PrivacyStatus string `json:"privacyStatus"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		params := url.Values{
+			"part":       {"snippet,contentDetails,status"},
+			"playlistId": {playlistID},
+			"maxResults": {strconv.Itoa(youtubeAPIPageSize)},
+		}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+		if err := youtubeAPIGet(key, "playlistItems", params, &page); err != nil {
+			if len(info.Videos) > 0 {
+				// Keep what was listed, like a yt-dlp fetch that stopped mid-way
+				slog.Warn("YouTube API listing stopped early, returning partial results", "playlist", playlistID, "entries", len(info.Videos), "err", err)
+				info.Partial = true
+				info.Continuation = encodePlaylistContinuation(playlistID, position+1) // Items without a video ID count too
+				return info, nil
+			}
+			return nil, err
+		}
+		info.Total = page.PageInfo.TotalResults
+
+		// Durations come from the videos endpoint, one batch per page
+		var batch []PlaylistVideo
+		var ids []string
+		for _, item := range page.Items {
+			position++
+			if position < start || item.ContentDetails.VideoID == "" {
+				continue
+			}
+			videoID := item.ContentDetails.VideoID
+			video := PlaylistVideo{
+				ID:        videoID,
+				Title:     item.Snippet.Title,
+				Thumbnail: item.Snippet.Thumbnails.best(),
+				URL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
+				Position:  position,
+
+				UploadDate: strings.ReplaceAll(strings.SplitN(item.ContentDetails.VideoPublishedAt, "T", 2)[0], "-", ""),
+			}
+			switch {
+			case item.Snippet.Title == "Deleted video":
+				video.Title = "[Deleted video]" // Same placeholder as yt-dlp
+			case item.Status.PrivacyStatus == "private":
+				video.Availability = "private"
+			}
+			if video.Thumbnail == "" {
+				video.Thumbnail = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", videoID)
+			}
+			video.Artist, _ = splitYouTubeTitle(item.Snippet.Title, item.Snippet.VideoOwnerChannelTitle)
+			batch = append(batch, video)
+			ids = append(ids, videoID)
+		}
+		if len(ids) > 0 {
+			details, err := fetchYouTubeAPIVideos(key, ids)
+			if err != nil {
+				return nil, err
+			}
+			for i := range batch {
+				if v, ok := details[batch[i].ID]; ok {
+					batch[i].Duration = parseISODuration(v.ContentDetails.Duration)
+				}
+			}
+		}
+
+		for _, video := range batch {
+			if opts.Limit > 0 && len(info.Videos) >= opts.Limit {
+				info.Partial = true
+				info.Continuation = encodePlaylistContinuation(playlistID, video.Position)
+				return info, nil
+			}
+			info.Videos = append(info.Videos, video)
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		if opts.Limit > 0 && len(info.Videos) >= opts.Limit {
+			info.Partial = true
+			info.Continuation = encodePlaylistContinuation(playlistID, position+1)
+			return info, nil
+		}
+		pageToken = page.NextPageToken
+	}
+
+	if len(info.Videos) == 0 {
+		return nil, fmt.Errorf("playlist is empty or unavailable")
+	}
+	return info, nil
+}
+
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseISODuration converts an ISO 8601 duration such as PT1H2M3S to seconds
+func parseISODuration(s string) float64 {
+	m := isoDurationRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	var seconds float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if n, err := strconv.Atoi(m[i+1]); err == nil {
+			seconds += float64(n) * unit
+		}
+	}
+	return seconds
+}
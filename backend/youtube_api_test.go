@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseISODuration(t *testing.T) {
+	for in, want := range map[string]float64{
+		"PT3M45S":  225,
+		"PT1H2M3S": 3723,
+		"PT59S":    59,
+		"P1DT1S":   86401,
+		"PT10H":    36000,
+		"P0D":      0,
+		"bogus":    0,
+	} {
+		if got := parseISODuration(in); got != want {
+			t.Errorf("parseISODuration(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestSplitYouTubeTitle(t *testing.T) {
+	if artist, title := splitYouTubeTitle("Song", "Artist - Topic"); artist != "Artist" || title != "Song" {
+		t.Errorf("topic channel = %q, %q", artist, title)
+	}
+	if artist, title := splitYouTubeTitle("Artist - Song (Official Video)", "ArtistVEVO"); artist != "Artist" || title != "Song (Official Video)" {
+		t.Errorf("vevo upload = %q, %q", artist, title)
+	}
+	if artist, title := splitYouTubeTitle("Live Session", "Some Band"); artist != "Some Band" || title != "Live Session" {
+		t.Errorf("plain upload = %q, %q", artist, title)
+	}
+}
+
+// fakeYouTubeAPI serves a playlist of n videos, pageSize per page
+func fakeYouTubeAPI(t *testing.T, n, pageSize int, calls *[]string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("key") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":{"message":"bad key","errors":[{"reason":"keyInvalid"}]}}`)
+			return
+		}
+		endpoint := strings.TrimPrefix(r.URL.Path, "/")
+		*calls = append(*calls, endpoint)
+
+		var resp interface{}
+		switch endpoint {
+		case "playlists":
+			resp = map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"snippet": map[string]string{"title": "Big Mix", "channelTitle": "Curator"}},
+			}}
+		case "playlistItems":
+			offset := 0
+			fmt.Sscanf(q.Get("pageToken"), "page%d", &offset)
+			var items []interface{}
+			for i := offset; i < min(offset+pageSize, n); i++ {
+				title := fmt.Sprintf("Artist - Song %d", i+1)
+				privacy := "public"
+				if i == 1 {
+					title, privacy = "Private video", "private"
+				}
+				items = append(items, map[string]interface{}{
+					"snippet":        map[string]string{"title": title, "videoOwnerChannelTitle": "ArtistVEVO"},
+					"contentDetails": map[string]string{"videoId": fmt.Sprintf("vid%08d", i+1)},
+					"status":         map[string]string{"privacyStatus": privacy},
+				})
+			}
+			page := map[string]interface{}{"items": items, "pageInfo": map[string]int{"totalResults": n}}
+			if offset+pageSize < n {
+				page["nextPageToken"] = fmt.Sprintf("page%d", offset+pageSize)
+			}
+			resp = page
+		case "videos":
+			var items []interface{}
+			for _, id := range strings.Split(q.Get("id"), ",") {
+				if id == "vid00000002" {
+					continue // Private videos are not returned
+				}
+				items = append(items, map[string]interface{}{
+					"id":             id,
+					"snippet":        map[string]string{"title": "Artist - Song", "channelTitle": "ArtistVEVO", "publishedAt": "2024-03-01T10:00:00Z"},
+					"contentDetails": map[string]string{"duration": "PT3M30S"},
+					"statistics":     map[string]string{"viewCount": "1234"},
+				})
+			}
+			resp = map[string]interface{}{"items": items}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	oldBase := youtubeAPIBase
+	youtubeAPIBase = server.URL
+	t.Cleanup(func() { youtubeAPIBase = oldBase })
+}
+
+func TestFetchPlaylistAPI(t *testing.T) {
+	var calls []string
+	fakeYouTubeAPI(t, 7, 3, &calls)
+
+	info, err := fetchPlaylistAPI("test-key", "PLbig", PlaylistFetchOptions{})
+	if err != nil {
+		t.Fatalf("fetchPlaylistAPI: %v", err)
+	}
+	if info.Title != "Big Mix" || info.Author != "Curator" || info.Total != 7 || len(info.Videos) != 7 || info.Partial {
+		t.Fatalf("info = %q %q total %d, %d videos, partial %v", info.Title, info.Author, info.Total, len(info.Videos), info.Partial)
+	}
+	first := info.Videos[0]
+	if first.Title != "Artist - Song 1" || first.Artist != "Artist" || first.Duration != 210 || first.Position != 1 {
+		t.Errorf("first = %+v", first)
+	}
+	if info.Videos[1].Availability != "private" || info.Videos[6].Position != 7 {
+		t.Errorf("private = %+v, last = %+v", info.Videos[1], info.Videos[6])
+	}
+	// 1 playlists + 3 pages of items + 3 video batches
+	if len(calls) != 7 {
+		t.Errorf("API calls = %v", calls)
+	}
+
+	// Pages through the token index
+	info, err = fetchPlaylistAPI("test-key", "PLbig", PlaylistFetchOptions{Start: 3, Limit: 2})
+	if err != nil {
+		t.Fatalf("paged fetchPlaylistAPI: %v", err)
+	}
+	if len(info.Videos) != 2 || info.Videos[0].Position != 3 || !info.Partial {
+		t.Fatalf("page = %+v", info)
+	}
+	if _, next, _ := decodePlaylistContinuation(info.Continuation); next != 5 {
+		t.Errorf("continuation next = %d, want 5", next)
+	}
+}
+
+func TestYouTubeAPIFallsBackToYtDlp(t *testing.T) {
+	var calls []string
+	fakeYouTubeAPI(t, 3, 50, &calls)
+	fakePlaylistCommand(t, `echo '{"id":"fromytdlp01","title":"Song"}'`)
+
+	ConfigureYouTubeAPI(&Config{YouTubeAPIKey: "wrong-key"})
+	defer ConfigureYouTubeAPI(&Config{})
+
+	info, err := fetchPlaylist("PLx", PlaylistFetchOptions{})
+	if err != nil || len(info.Videos) != 1 || info.Videos[0].ID != "fromytdlp01" {
+		t.Fatalf("fallback = %+v, %v", info, err)
+	}
+
+	ConfigureYouTubeAPI(&Config{YouTubeAPIKey: "test-key"})
+	if info, err := fetchPlaylist("PLx", PlaylistFetchOptions{}); err != nil || len(info.Videos) != 3 {
+		t.Errorf("API listing = %+v, %v", info, err)
+	}
+
+	video, err := GetVideoMetadata("vid00000001")
+	if err != nil {
+		t.Fatalf("GetVideoMetadata: %v", err)
+	}
+	if video.Artist != "Artist" || video.Title != "Song" || video.Duration != 210 || video.UploadDate != "20240301" || video.ViewCount != 1234 {
+		t.Errorf("video = %+v", video)
+	}
+}
@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// YouTube Music audio fallback
+// =============================================================================
+
+// When no service has the track in lossless quality, the audio used to come
+// out of the downloaded video, whose muxed AAC stream runs at about 128 kbps.
+// YouTube Music serves the same recording as a separate Opus stream (about
+// 160 kbps, 256 kbps with Premium cookies) that usually sounds better, so it
+// is tried first. The result is still lossy and the item says so.
+
+// ytMusicAudioFormat prefers Opus 256 (Premium), then Opus 160, then
+// whatever audio-only stream is best
+const ytMusicAudioFormat = "774/251/bestaudio[acodec=opus]/bestaudio"
+
+// YouTubeAudioSource is QueueItem.AudioSource for YouTube Music audio
+const YouTubeAudioSource = "youtube-music"
+
+// YouTubeAudioDownload is an audio-only stream downloaded from YouTube Music
+type YouTubeAudioDownload struct {
+	Path     string  `json:"path"`
+	URL      string  `json:"url"`
+	FormatID string  `json:"formatId"`
+	Codec    string  `json:"codec"`   // "opus", "mp4a.40.2", ...
+	Bitrate  float64 `json:"bitrate"` // kbps, 0 if unknown
+}
+
+// Quality labels the stream for QueueItem.ActualQuality, e.g.
+// "Opus 160 kbps (lossy)"
+func (d *YouTubeAudioDownload) Quality() string {
+	codec := d.Codec
+	switch {
+	case codec == "opus":
+		codec = "Opus"
+	case strings.HasPrefix(codec, "mp4a"):
+		codec = "AAC"
+	case codec == "":
+		codec = "Audio"
+	}
+	if d.Bitrate > 0 {
+		return fmt.Sprintf("%s %.0f kbps (lossy)", codec, d.Bitrate)
+	}
+	return codec + " (lossy)"
+}
+
+// DownloadYouTubeMusicAudio downloads the best audio-only stream YouTube
+// Music has for videoID into outputDir. Cancelling ctx stops yt-dlp.
+func DownloadYouTubeMusicAudio(ctx context.Context, videoID, outputDir, cookiesBrowser string, progress func(DownloadProgress)) (*YouTubeAudioDownload, error) {
+	audioURL := fmt.Sprintf("https://music.youtube.com/watch?v=%s", videoID)
+
+	cookieArgs, cleanupCookies, err := ytdlpCookieArgs(cookiesBrowser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve browser cookies: %w", err)
+	}
+	defer cleanupCookies()
+
+	// The format selector makes yt-dlp report the stream it will download
+	metadataArgs := []string{"--dump-json", "-f", ytMusicAudioFormat, "--no-download", "--no-playlist"}
+	metadataArgs = append(metadataArgs, cookieArgs...)
+	metadataArgs = append(metadataArgs, audioURL)
+	var info struct {
+		FormatID string  `json:"format_id"`
+		Ext      string  `json:"ext"`
+		ACodec   string  `json:"acodec"`
+		ABR      float64 `json:"abr"`
+		Duration float64 `json:"duration"`
+	}
+	if err := ytdlpDumpJSON(ctx, metadataArgs, &info); err != nil {
+		return nil, fmt.Errorf("failed to get audio info: %w", err)
+	}
+	if info.Ext == "" {
+		info.Ext = "webm"
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	outputPath := filepath.Join(outputDir, "ytmusic-audio."+info.Ext)
+
+	args := []string{"-f", ytMusicAudioFormat, "--no-playlist", "-o", outputPath}
+	args = append(args, ytdlpProgressArgs...)
+	args = append(args, cookieArgs...)
+	args = append(args, audioURL)
+
+	for attempt := 1; ; attempt++ {
+		if err := runYtdlpDownload(ctx, args, progress); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(outputPath); err != nil {
+			return nil, fmt.Errorf("download completed but file not found: %s", outputPath)
+		}
+
+		err = verifyMediaDuration(outputPath, info.Duration)
+		if err == nil {
+			break
+		}
+		if attempt == downloadVerifyAttempts {
+			return nil, fmt.Errorf("downloaded audio failed verification: %w", err)
+		}
+		os.Remove(outputPath)
+	}
+
+	return &YouTubeAudioDownload{
+		Path:     outputPath,
+		URL:      audioURL,
+		FormatID: info.FormatID,
+		Codec:    info.ACodec,
+		Bitrate:  info.ABR,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package backend
+
+import "testing"
+
+func TestYouTubeAudioDownloadQuality(t *testing.T) {
+	tests := []struct {
+		download YouTubeAudioDownload
+		want     string
+	}{
+		{YouTubeAudioDownload{Codec: "opus", Bitrate: 160.123}, "Opus 160 kbps (lossy)"},
+		{YouTubeAudioDownload{Codec: "opus", Bitrate: 256}, "Opus 256 kbps (lossy)"},
+		{YouTubeAudioDownload{Codec: "mp4a.40.2", Bitrate: 129.5}, "AAC 130 kbps (lossy)"},
+		{YouTubeAudioDownload{}, "Audio (lossy)"},
+	}
+	for _, tt := range tests {
+		if got := tt.download.Quality(); got != tt.want {
+			t.Errorf("Quality(%+v) = %q, want %q", tt.download, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// YouTube thumbnail resolution
+// =============================================================================
+
+// yt-dlp and the YouTube APIs often report hqdefault (480x360, letterboxed)
+// even when the video has a 1280x720 maxresdefault. Before a YouTube
+// thumbnail is downloaded as a cover or poster, the sizes are tried from the
+// largest down and the first one that exists is used. Other URLs are left
+// alone.
+
+// youtubeThumbnailLadder are the thumbnail sizes tried, largest first.
+// hqdefault exists for every video.
+var youtubeThumbnailLadder = []string{"maxresdefault", "sddefault", "hqdefault"}
+
+// youtubeThumbnailBase is where thumbnails are fetched from. Replaced in tests.
+var youtubeThumbnailBase = "https://i.ytimg.com"
+
+// youtubeThumbnailCheckTimeout bounds each existence check
+const youtubeThumbnailCheckTimeout = 5 * time.Second
+
+// youtubeThumbnailPattern matches i.ytimg.com and img.youtube.com thumbnail
+// paths, JPEG (/vi/) or WebP (/vi_webp/): the video ID and the size
+var youtubeThumbnailPattern = regexp.MustCompile(`^/vi(?:_webp)?/([A-Za-z0-9_-]{11})/([a-z0-9_]+)\.(?:jpg|webp)$`)
+
+// youtubeThumbnailURL returns the JPEG thumbnail of videoID in size
+// ("hqdefault", "maxresdefault", ...)
+func youtubeThumbnailURL(videoID, size string) string {
+	return youtubeThumbnailBase + "/vi/" + videoID + "/" + size + ".jpg"
+}
+
+// parseYouTubeThumbnail returns the video ID and size of a YouTube thumbnail
+// URL; ok is false for any other URL
+func parseYouTubeThumbnail(thumbnailURL string) (videoID, size string, ok bool) {
+	u, err := url.Parse(thumbnailURL)
+	if err != nil {
+		return "", "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "i.ytimg.com" && host != "img.youtube.com" && !strings.HasSuffix(host, ".ytimg.com") {
+		return "", "", false
+	}
+	m := youtubeThumbnailPattern.FindStringSubmatch(u.Path)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// bestYouTubeThumbnail returns the largest existing size of a YouTube
+// thumbnail URL, or thumbnailURL itself when it isn't one or no larger size
+// can be confirmed. Sizes below the reported one aren't tried.
+func bestYouTubeThumbnail(thumbnailURL string) string {
+	videoID, size, ok := parseYouTubeThumbnail(thumbnailURL)
+	if !ok {
+		return thumbnailURL
+	}
+	ladder := youtubeThumbnailLadder
+	switch i := slices.Index(ladder, size); {
+	case i >= 0:
+		ladder = ladder[:i+1]
+	case strings.HasPrefix(size, "hq720"):
+		ladder = ladder[:1] // Already 1280x720, only maxresdefault can beat it
+	}
+	for _, rung := range ladder {
+		candidate := youtubeThumbnailURL(videoID, rung)
+		if youtubeThumbnailExists(candidate) {
+			if candidate != thumbnailURL {
+				slog.Debug("using larger YouTube thumbnail", "video", videoID, "size", rung, "reported", size)
+			}
+			return candidate
+		}
+	}
+	return thumbnailURL
+}
+
+// youtubeThumbnailExists reports whether a thumbnail is there. YouTube
+// answers 404 (with a grey placeholder) for sizes a video doesn't have.
+func youtubeThumbnailExists(thumbnailURL string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), youtubeThumbnailCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, thumbnailURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := coverHTTPClient.Do(req)
+	if err != nil {
+		slog.Debug("YouTube thumbnail check failed", "url", thumbnailURL, "err", err)
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeYouTubeThumbnails serves the given thumbnail sizes of every video and
+// 404s the rest
+func fakeYouTubeThumbnails(t *testing.T, sizes ...string) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, size := range sizes {
+			if strings.HasSuffix(r.URL.Path, "/"+size+".jpg") {
+				w.Write([]byte("\xff\xd8\xff image"))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	orig := youtubeThumbnailBase
+	youtubeThumbnailBase = ts.URL
+	t.Cleanup(func() { youtubeThumbnailBase = orig })
+}
+
+func TestParseYouTubeThumbnail(t *testing.T) {
+	tests := []struct {
+		url, id, size string
+		ok            bool
+	}{
+		{"https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg", "dQw4w9WgXcQ", "hqdefault", true},
+		{"https://i.ytimg.com/vi_webp/dQw4w9WgXcQ/maxresdefault.webp", "dQw4w9WgXcQ", "maxresdefault", true},
+		{"https://img.youtube.com/vi/dQw4w9WgXcQ/sddefault.jpg", "dQw4w9WgXcQ", "sddefault", true},
+		{"https://i9.ytimg.com/vi/dQw4w9WgXcQ/hq720.jpg?sqp=abc", "dQw4w9WgXcQ", "hq720", true},
+		{"https://resources.tidal.com/images/a/b/640x640.jpg", "", "", false},
+		{"https://i.ytimg.com/an/channel/avatar.jpg", "", "", false},
+	}
+	for _, tt := range tests {
+		id, size, ok := parseYouTubeThumbnail(tt.url)
+		if id != tt.id || size != tt.size || ok != tt.ok {
+			t.Errorf("parseYouTubeThumbnail(%q) = %q, %q, %v", tt.url, id, size, ok)
+		}
+	}
+}
+
+func TestBestYouTubeThumbnail(t *testing.T) {
+	const reported = "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg"
+
+	fakeYouTubeThumbnails(t, "maxresdefault", "sddefault", "hqdefault")
+	if got := bestYouTubeThumbnail(reported); !strings.HasSuffix(got, "/vi/dQw4w9WgXcQ/maxresdefault.jpg") {
+		t.Errorf("with maxresdefault: %q", got)
+	}
+
+	// Older videos have no maxresdefault
+	fakeYouTubeThumbnails(t, "sddefault", "hqdefault")
+	if got := bestYouTubeThumbnail(reported); !strings.HasSuffix(got, "/sddefault.jpg") {
+		t.Errorf("without maxresdefault: %q", got)
+	}
+
+	// Nothing larger is confirmed: the reported URL is kept
+	fakeYouTubeThumbnails(t)
+	if got := bestYouTubeThumbnail(reported); got != reported {
+		t.Errorf("nothing available: %q, want the reported URL", got)
+	}
+
+	// A larger reported size isn't downgraded
+	fakeYouTubeThumbnails(t, "sddefault", "hqdefault")
+	hq720 := "https://i.ytimg.com/vi/dQw4w9WgXcQ/hq720.jpg"
+	if got := bestYouTubeThumbnail(hq720); got != hq720 {
+		t.Errorf("hq720 = %q, want it kept", got)
+	}
+
+	// Other images are left alone
+	other := "https://example.com/cover.jpg"
+	if got := bestYouTubeThumbnail(other); got != other {
+		t.Errorf("non-YouTube URL changed to %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// yt-dlp download archives
+// =============================================================================
+
+// yt-dlp's --download-archive file lists one downloaded video per line as
+// "<extractor> <id>", e.g. "youtube dQw4w9WgXcQ". Importing one records its
+// YouTube IDs in the history as already downloaded, so channel syncs, the
+// watchlist and playlist imports skip them instead of downloading them again.
+// The other way round, the history can be exported in the same format, and
+// Config.DownloadArchive names a file that every completed download is
+// appended to, so yt-dlp based tools sharing it skip what youflac has.
+
+// ArchiveImportSource is HistoryEntry.AudioSource for entries imported from
+// a yt-dlp archive. They have no output file.
+const ArchiveImportSource = "ytdlp-archive"
+
+// ArchiveImportResult summarizes one archive import
+type ArchiveImportResult struct {
+	Imported int `json:"imported"` // New history entries
+	Known    int `json:"known"`    // Already in the history
+	Skipped  int `json:"skipped"`  // Other extractors or malformed lines
+}
+
+// ParseYtdlpArchive returns the YouTube video IDs listed in a yt-dlp archive,
+// in file order without duplicates, and how many lines were not YouTube videos
+func ParseYtdlpArchive(r io.Reader) (ids []string, skipped int, err error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		extractor, id, ok := strings.Cut(line, " ")
+		id = strings.TrimSpace(id)
+		if !ok || !strings.EqualFold(extractor, "youtube") || !youtubeIDRegex.MatchString(id) {
+			skipped++
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return ids, skipped, nil
+}
+
+// ImportYtdlpArchive marks the videos of a yt-dlp archive as downloaded.
+// Videos the history already has as complete are left alone.
+func (h *History) ImportYtdlpArchive(r io.Reader) (*ArchiveImportResult, error) {
+	ids, skipped, err := ParseYtdlpArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, entry := range h.FilterByStatus("complete") {
+		if videoID, err := ParseYouTubeURL(entry.VideoURL); err == nil {
+			known[videoID] = true
+		}
+	}
+
+	result := &ArchiveImportResult{Skipped: skipped}
+	now := time.Now()
+	var entries []HistoryEntry
+	for _, id := range ids {
+		if known[id] {
+			result.Known++
+			continue
+		}
+		entries = append(entries, HistoryEntry{
+			VideoURL:    fmt.Sprintf("https://www.youtube.com/watch?v=%s", id),
+			Title:       id,
+			AudioSource: ArchiveImportSource,
+			CompletedAt: now,
+			Status:      "complete",
+		})
+	}
+	if len(entries) == 0 {
+		return result, nil
+	}
+	if err := h.AddEntries(entries); err != nil {
+		return nil, fmt.Errorf("failed to save history: %w", err)
+	}
+	result.Imported = len(entries)
+	return result, nil
+}
+
+// ytdlpArchiveLine is the archive line of a YouTube video
+func ytdlpArchiveLine(videoID string) string {
+	return "youtube " + videoID
+}
+
+// ExportYtdlpArchive writes the completed YouTube downloads (and imported
+// archive entries) as a yt-dlp archive, oldest first, and returns how many
+// videos it lists
+func (h *History) ExportYtdlpArchive(w io.Writer) (int, error) {
+	entries := h.FilterByStatus("complete")
+	slices.Reverse(entries) // The history is newest first, archives grow at the end
+
+	seen := make(map[string]bool)
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		videoID, err := ParseYouTubeURL(entry.VideoURL)
+		if err != nil || seen[videoID] {
+			continue
+		}
+		seen[videoID] = true
+		fmt.Fprintln(bw, ytdlpArchiveLine(videoID))
+	}
+	return len(seen), bw.Flush()
+}
+
+// downloadArchiveMu serializes appends to Config.DownloadArchive
+var downloadArchiveMu sync.Mutex
+
+// appendToDownloadArchive adds a downloaded video to the yt-dlp archive at
+// path unless it is listed already. Non-YouTube URLs are ignored.
+func appendToDownloadArchive(path, videoURL string) error {
+	videoID, err := ParseYouTubeURL(videoURL)
+	if err != nil {
+		return nil
+	}
+	line := ytdlpArchiveLine(videoID)
+
+	downloadArchiveMu.Lock()
+	defer downloadArchiveMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read download archive: %w", err)
+	}
+	for _, existing := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(existing) == line {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create download archive directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open download archive: %w", err)
+	}
+	defer f.Close()
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		line = "\n" + line
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write download archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseYtdlpArchive(t *testing.T) {
+	archive := `youtube dQw4w9WgXcQ
+soundcloud 123456789
+
+youtube oHg5SJYRHA0
+youtube dQw4w9WgXcQ
+youtube not-an-id
+# comment
+`
+	ids, skipped, err := ParseYtdlpArchive(strings.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "dQw4w9WgXcQ" || ids[1] != "oHg5SJYRHA0" {
+		t.Errorf("ids = %v", ids)
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want the SoundCloud and malformed lines", skipped)
+	}
+}
+
+func TestImportYtdlpArchive(t *testing.T) {
+	dir := t.TempDir()
+	history := &History{filePath: filepath.Join(dir, "history.json")}
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=old00000001", Status: "complete"})
+
+	result, err := history.ImportYtdlpArchive(strings.NewReader("youtube old00000001\nyoutube new00000001\nvimeo 42\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || result.Known != 1 || result.Skipped != 1 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// Importing again adds nothing
+	result, err = history.ImportYtdlpArchive(strings.NewReader("youtube new00000001\n"))
+	if err != nil || result.Imported != 0 || result.Known != 1 {
+		t.Errorf("second import = %+v, %v", result, err)
+	}
+
+	// Subscriptions and playlist imports skip archived videos
+	check := CheckPlaylist(&PlaylistInfo{Videos: []PlaylistVideo{
+		{ID: "new00000001", Title: "Archived Song"},
+		{ID: "other000001", Title: "New Song"},
+	}}, nil, history, nil)
+	if check.Entries[0].Status != PlaylistEntryDownloaded || check.Entries[1].Status != PlaylistEntryNew {
+		t.Errorf("statuses = %s, %s", check.Entries[0].Status, check.Entries[1].Status)
+	}
+}
+
+func TestExportYtdlpArchive(t *testing.T) {
+	history := &History{filePath: filepath.Join(t.TempDir(), "history.json")}
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=old00000001", Status: "complete"})
+	history.Add(HistoryEntry{VideoURL: "https://youtu.be/new00000001", Status: "complete"})
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=fail0000001", Status: "error"})
+	history.Add(HistoryEntry{VideoURL: "https://www.youtube.com/watch?v=old00000001", Status: "complete"})
+
+	var sb strings.Builder
+	count, err := history.ExportYtdlpArchive(&sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "youtube old00000001\nyoutube new00000001\n"; count != 2 || sb.String() != want {
+		t.Errorf("archive = %d %q, want %q", count, sb.String(), want)
+	}
+
+	// The export imports back without changes
+	result, err := history.ImportYtdlpArchive(strings.NewReader(sb.String()))
+	if err != nil || result.Imported != 0 || result.Known != 2 {
+		t.Errorf("re-import = %+v, %v", result, err)
+	}
+}
+
+func TestAppendToDownloadArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(path, []byte("soundcloud 42"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, videoURL := range []string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ", // Listed already
+		"https://vimeo.com/42",                          // Not YouTube
+	} {
+		if err := appendToDownloadArchive(path, videoURL); err != nil {
+			t.Fatalf("appendToDownloadArchive(%s): %v", videoURL, err)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "soundcloud 42\nyoutube dQw4w9WgXcQ\n" {
+		t.Errorf("archive = %q", got)
+	}
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// =============================================================================
+// yt-dlp JSON output
+// =============================================================================
+
+// yt-dlp prints one JSON object per line (-j, --dump-json). Reading its
+// stdout line by line as it arrives keeps memory bounded by the longest
+// line rather than the whole output, which for a 5,000 entry playlist is
+// enough to run a 512 MB NAS out of memory.
+
+// ytdlpJSONLineMax bounds one line of yt-dlp JSON. A flat playlist or search
+// entry is a few KB; a full --dump-json with every format stays well under
+// a megabyte.
+const ytdlpJSONLineMax = 4 * 1024 * 1024
+
+// errNoYtdlpJSON is returned when yt-dlp exits without printing any JSON
+var errNoYtdlpJSON = errors.New("yt-dlp printed no JSON")
+
+// scanYtdlpJSON runs yt-dlp with args and calls onLine for every JSON line
+// it prints, while it runs. onLine must not keep the slice.
+func scanYtdlpJSON(ctx context.Context, args []string, onLine func(line []byte)) error {
+	cmd := newCommandContext(ctx, "yt-dlp", args...)
+	cmd.WaitDelay = ytdlpWaitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr := &tailWriter{max: ytdlpStderrTail}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanErr := readJSONLines(stdout, onLine)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%w, output: %s", err, stderr.String())
+	}
+	return scanErr
+}
+
+// ytdlpDumpJSON runs yt-dlp with args (which should include --dump-json)
+// and decodes the first JSON object it prints into v
+func ytdlpDumpJSON(ctx context.Context, args []string, v any) error {
+	var decodeErr error
+	decoded := false
+	err := scanYtdlpJSON(ctx, args, func(line []byte) {
+		if decoded || decodeErr != nil {
+			return
+		}
+		if decodeErr = json.Unmarshal(line, v); decodeErr == nil {
+			decoded = true
+		}
+	})
+	switch {
+	case err != nil:
+		return err
+	case decodeErr != nil:
+		return decodeErr
+	case !decoded:
+		return errNoYtdlpJSON
+	}
+	return nil
+}
+
+// readJSONLines calls onLine for every non-empty line of r. A line longer
+// than ytdlpJSONLineMax stops the scan with an error; the rest of r is
+// discarded so the writer isn't blocked.
+func readJSONLines(r io.Reader, onLine func(line []byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), ytdlpJSONLineMax)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		onLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		io.Copy(io.Discard, r)
+		return fmt.Errorf("reading yt-dlp output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadJSONLines(t *testing.T) {
+	input := "{\"id\":\"a\"}\n\n  \n{\"id\":\"b\"}  \r\n{\"id\":\"c\"}"
+	var got []string
+	if err := readJSONLines(strings.NewReader(input), func(line []byte) { got = append(got, string(line)) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestReadJSONLines_LineTooLong(t *testing.T) {
+	r := strings.NewReader("{\"id\":\"a\"}\n" + strings.Repeat("x", ytdlpJSONLineMax+1) + "\n{\"id\":\"b\"}\n")
+	var got int
+	err := readJSONLines(r, func(line []byte) { got++ })
+	if err == nil {
+		t.Fatal("readJSONLines accepted a line over the limit")
+	}
+	if got != 1 {
+		t.Errorf("lines before the long one = %d, want 1", got)
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d bytes left unread, want the rest drained", r.Len())
+	}
+}
+
+func TestParseSearchEntry(t *testing.T) {
+	video, ok := parseSearchEntry([]byte(`{"id":"abc","title":"Artist - Song","uploader":"Artist - Topic","view_count":42}`))
+	if !ok {
+		t.Fatal("parseSearchEntry rejected a valid entry")
+	}
+	if video.Title != "Song" || video.Artist != "Artist" || video.Channel != "Artist - Topic" || video.ViewCount != 42 {
+		t.Errorf("video = %+v", video)
+	}
+	if video.Thumbnail != "https://i.ytimg.com/vi/abc/hqdefault.jpg" {
+		t.Errorf("thumbnail = %q, want the hqdefault fallback", video.Thumbnail)
+	}
+
+	for _, line := range []string{`{"title":"no id"}`, `not json`} {
+		if _, ok := parseSearchEntry([]byte(line)); ok {
+			t.Errorf("parseSearchEntry(%s) accepted", line)
+		}
+	}
+}
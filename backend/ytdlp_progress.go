@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"youflac/backend/format"
+)
+
+// =============================================================================
+// yt-dlp progress
+// =============================================================================
+
+// DownloadVideo runs yt-dlp quietly with a progress template that prints
+// one JSON object per update on its own line, instead of passing yt-dlp's
+// progress bar through to the server's stdout where the output of several
+// workers would interleave. The lines are parsed into DownloadProgress for
+// the queue item; anything else yt-dlp prints goes to the debug log, and the
+// tail of stderr ends up in the error when the download fails.
+
+// ytdlpProgressPrefix marks the JSON progress lines on yt-dlp's stdout
+const ytdlpProgressPrefix = "[youflac-progress]"
+
+// ytdlpStderrTail is how much of yt-dlp's stderr an error message keeps
+const ytdlpStderrTail = 2048
+
+// ytdlpWaitDelay bounds the wait for yt-dlp's output once it was killed; the
+// ffmpeg it runs for merging may still hold the pipes
+const ytdlpWaitDelay = 5 * time.Second
+
+// ytdlpProgressArgs make yt-dlp print progress as JSON lines and nothing else
+var ytdlpProgressArgs = []string{
+	"--quiet", "--progress", "--newline",
+	"--progress-template", "download:" + ytdlpProgressPrefix + "%(progress)j",
+}
+
+// ytdlpProgress is yt-dlp's progress dict as printed by %(progress)j
+type ytdlpProgress struct {
+	Status             string   `json:"status"` // downloading, finished, error
+	DownloadedBytes    int64    `json:"downloaded_bytes"`
+	TotalBytes         int64    `json:"total_bytes"`
+	TotalBytesEstimate float64  `json:"total_bytes_estimate"`
+	Speed              *float64 `json:"speed"` // null while unknown
+	ETA                *float64 `json:"eta"`
+}
+
+// runYtdlpDownload runs yt-dlp with args (which should include
+// ytdlpProgressArgs), reporting its progress until it exits
+func runYtdlpDownload(ctx context.Context, args []string, progress func(DownloadProgress)) error {
+	cmd := newCommandContext(ctx, "yt-dlp", args...)
+	cmd.WaitDelay = ytdlpWaitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("yt-dlp download failed: %w", err)
+	}
+	stderr := &tailWriter{max: ytdlpStderrTail}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("yt-dlp download failed: %w", err)
+	}
+	readYtdlpProgress(stdout, progress)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("yt-dlp download failed: %w, output: %s", err, stderr.String())
+	}
+	if out := stderr.String(); out != "" {
+		slog.Debug("yt-dlp stderr", "output", out)
+	}
+	return nil
+}
+
+// readYtdlpProgress parses yt-dlp's stdout until EOF, calling onProgress
+// (may be nil) for every progress line. A format-merged download fetches
+// the video and the audio stream one after the other; File counts them.
+func readYtdlpProgress(r io.Reader, onProgress func(DownloadProgress)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	file := 1
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		data, ok := strings.CutPrefix(line, ytdlpProgressPrefix)
+		if !ok {
+			slog.Debug("yt-dlp", "output", line)
+			continue
+		}
+
+		var p ytdlpProgress
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			slog.Debug("unparseable yt-dlp progress", "line", line, "err", err)
+			continue
+		}
+
+		progress := DownloadProgress{Downloaded: p.DownloadedBytes, Total: p.TotalBytes, File: file}
+		if progress.Total <= 0 {
+			progress.Total = int64(p.TotalBytesEstimate)
+		}
+		if progress.Total > 0 {
+			progress.Percent = min(100, float64(progress.Downloaded)*100/float64(progress.Total))
+		}
+		if p.Speed != nil {
+			progress.Speed = *p.Speed
+		}
+		if p.ETA != nil {
+			progress.ETA = format.Duration(*p.ETA)
+		}
+		if p.Status == "finished" {
+			progress.Percent = 100
+			file++
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+}
+
+// tailWriter keeps the last max bytes written to it
+type tailWriter struct {
+	buf []byte
+	max int
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if over := len(w.buf) - w.max; over > 0 {
+		w.buf = w.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	return strings.TrimSpace(string(w.buf))
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadYtdlpProgress(t *testing.T) {
+	output := `[youflac-progress]{"status":"downloading","downloaded_bytes":1000,"total_bytes":4000,"speed":null,"eta":null}
+[youflac-progress]{"status":"downloading","downloaded_bytes":3000,"total_bytes":4000,"speed":2048.5,"eta":65}
+[youflac-progress]{"status":"finished","downloaded_bytes":4000,"total_bytes":4000}
+[Merger] Merging formats into "video.mp4"
+[youflac-progress]{"status":"downloading","downloaded_bytes":50,"total_bytes_estimate":200.0,"speed":100,"eta":1.5}
+[youflac-progress]not json
+`
+	var got []DownloadProgress
+	readYtdlpProgress(strings.NewReader(output), func(p DownloadProgress) { got = append(got, p) })
+
+	if len(got) != 4 {
+		t.Fatalf("got %d progress updates, want 4: %+v", len(got), got)
+	}
+	if got[0].Percent != 25 || got[0].Speed != 0 || got[0].ETA != "" || got[0].File != 1 {
+		t.Errorf("first update = %+v", got[0])
+	}
+	if got[1].Percent != 75 || got[1].Speed != 2048.5 || got[1].ETA != "1:05" {
+		t.Errorf("second update = %+v", got[1])
+	}
+	if got[2].Percent != 100 || got[2].File != 1 {
+		t.Errorf("finished update = %+v", got[2])
+	}
+	if got[3].File != 2 || got[3].Total != 200 || got[3].Percent != 25 {
+		t.Errorf("audio stream update = %+v, want file 2 with the estimated total", got[3])
+	}
+
+	// A nil callback only drains the output
+	readYtdlpProgress(strings.NewReader(output), nil)
+}
+
+func TestTailWriter(t *testing.T) {
+	w := &tailWriter{max: 10}
+	w.Write([]byte("ERROR: first line\n"))
+	w.Write([]byte("last line\n"))
+	if got := w.String(); got != "last line" {
+		t.Errorf("tail = %q", got)
+	}
+}
+
+func TestTransferProgress(t *testing.T) {
+	q := newTestQueue()
+	id, err := q.AddToQueue(DownloadRequest{VideoURL: "https://www.youtube.com/watch?v=abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := q.transferProgress(id, 10, 40)
+	report(DownloadProgress{Percent: 50, Speed: 1e6, File: 1})
+	item := q.GetItem(id)
+	if item.Progress != 23 || item.Transfer == nil || item.Transfer.Speed != 1e6 {
+		t.Errorf("progress = %d, transfer = %+v, want 23%% with the transfer stats", item.Progress, item.Transfer)
+	}
+
+	// Updates within the interval are dropped
+	report(DownloadProgress{Percent: 100, File: 1})
+	if item := q.GetItem(id); item.Progress != 23 {
+		t.Errorf("progress = %d, want the throttled update dropped", item.Progress)
+	}
+
+	// The audio stream is the last tenth
+	report = q.transferProgress(id, 10, 40)
+	report(DownloadProgress{Percent: 50, File: 2})
+	if item := q.GetItem(id); item.Progress != 38 {
+		t.Errorf("progress = %d, want 38", item.Progress)
+	}
+}
@@ -0,0 +1,154 @@
+// Command cascade-test runs only the audio resolution and download cascade
+// for one URL and writes a JSON trace of every service decision with its
+// timing. Use it to find out why a track keeps falling back to extracted
+// audio:
+//
+//	cascade-test -o trace.json https://www.youtube.com/watch?v=...
+//	cascade-test -sources qobuz,deezer -artist "Daft Punk" -title "Aerodynamic" https://open.spotify.com/track/...
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"youflac/backend"
+)
+
+func main() {
+	output := flag.String("o", "cascade-trace.json", "Trace file, - for stdout")
+	sources := flag.String("sources", "", "Comma-separated source order (default: audioSourcePriority from the config)")
+	artist := flag.String("artist", "", "Artist for the Tidal search (default: from the video)")
+	title := flag.String("title", "", "Title for the Tidal search (default: from the video)")
+	noSearch := flag.Bool("no-search", false, "Skip the Tidal search fallback")
+	keep := flag.Bool("keep", false, "Keep the downloaded audio instead of deleting it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <url>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	url := flag.Arg(0)
+
+	// Same config, logging and service setup as the server
+	config, err := backend.LoadConfigWithEnv()
+	if err != nil {
+		log.Printf("Warning: Could not load config: %v, using defaults", err)
+		config = backend.GetDefaultConfig()
+	}
+	for _, w := range config.Validate().Warnings {
+		log.Printf("Config warning: %s: %s", w.Field, w.Message)
+	}
+	backend.InitLogger("debug") // LOG_LEVEL still overrides
+	backend.ConfigureMusicResolvers(config)
+	backend.ConfigureUserAgents(config)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Artist and title for the Tidal search come from the video, like in the queue
+	if (*artist == "" || *title == "") && backend.ValidateYouTubeURL(url) == nil {
+		if info, err := backend.GetVideoMetadataFromURL(url); err != nil {
+			log.Printf("Warning: Could not fetch video info: %v", err)
+		} else {
+			if *artist == "" {
+				*artist = info.Artist
+			}
+			if *title == "" {
+				*title = info.Title
+			}
+			log.Printf("Video: %s - %s", info.Artist, info.Title)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp(backend.GetTempDirectory(), "cascade-test-")
+	if err != nil {
+		log.Fatalf("Could not create temp directory: %v", err)
+	}
+	if !*keep {
+		defer os.RemoveAll(tempDir)
+	}
+
+	trace := backend.NewCascadeTrace(url)
+	trace.OnEvent = func(e backend.CascadeEvent) {
+		line := fmt.Sprintf("[%6dms] %-12s %-8s %-10s %-8s", e.OffsetMs, e.Step, e.Source, e.Service, e.Decision)
+		if e.DurationMs > 0 {
+			line += fmt.Sprintf(" (%dms)", e.DurationMs)
+		}
+		if e.Detail != "" {
+			line += " " + e.Detail
+		}
+		if e.Error != "" {
+			line += " error: " + e.Error
+		}
+		fmt.Fprintln(os.Stderr, line)
+	}
+
+	cascade := backend.NewAudioCascade(config, tempDir)
+	cascade.Trace = trace
+	cascade.TidalSearch = !*noSearch
+	if *sources != "" {
+		cascade.Sources = strings.Split(*sources, ",")
+		if err := backend.ValidateAudioSources(cascade.Sources); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	cascade.OnStage = func(progress int, code backend.StageCode, params ...string) {
+		log.Printf("Stage: %s", backend.StageMessage(code, stageParams(params), backend.DefaultStageLanguage))
+	}
+
+	result := cascade.Run(ctx, url, *artist, *title)
+	if ctx.Err() != nil {
+		log.Printf("Interrupted")
+	}
+
+	if err := writeTrace(trace, *output); err != nil {
+		log.Fatalf("Could not write trace: %v", err)
+	}
+	if result.Audio == nil {
+		log.Printf("Result: no lossless source, the queue would extract the video's audio (tried %s)", strings.Join(result.SourcesTried, ", "))
+		if !*keep {
+			os.RemoveAll(tempDir) // Deferred calls don't run on os.Exit
+		}
+		os.Exit(1)
+	}
+	log.Printf("Result: %s via %s (%s)", result.Source, result.Service, trace.Quality)
+	if *keep {
+		log.Printf("Audio kept at %s", result.Audio.FilePath)
+	}
+}
+
+// stageParams turns "key", "value" pairs into a map
+func stageParams(pairs []string) map[string]string {
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+	return params
+}
+
+func writeTrace(trace *backend.CascadeTrace, path string) error {
+	data, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	log.Printf("Trace written to %s", path)
+	return nil
+}
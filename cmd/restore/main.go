@@ -0,0 +1,88 @@
+// Command restore rebuilds the history, the file index and missing NFO
+// files from the provenance sidecars (<name>.youflac.json) in a library, for
+// when the data directory was lost but the media files were kept. Stop the
+// server first: it keeps its own copy of the history in memory.
+//
+//	restore                          # the library directories from the config
+//	restore -dry-run -verify /mnt/music-videos
+//	restore -history=false -overwrite-nfo
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"youflac/backend"
+)
+
+func main() {
+	history := flag.Bool("history", true, "Add missing history entries")
+	index := flag.Bool("index", true, "Rebuild the file index used for duplicate detection")
+	nfo := flag.Bool("nfo", true, "Write missing NFO files")
+	overwriteNFO := flag.Bool("overwrite-nfo", false, "Rewrite existing NFO files as well")
+	verify := flag.Bool("verify", false, "Compare media checksums with the sidecars (reads every file)")
+	dryRun := flag.Bool("dry-run", false, "Report what would be restored without writing anything")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [library directory...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Same config and logging as the server
+	config, err := backend.LoadConfigWithEnv()
+	if err != nil {
+		log.Printf("Warning: Could not load config: %v, using defaults", err)
+		config = backend.GetDefaultConfig()
+	}
+	for _, w := range config.Validate().Warnings {
+		log.Printf("Config warning: %s: %s", w.Field, w.Message)
+	}
+	backend.InitLogger(config.LogLevel)
+	backend.ConfigureOutputPermissions(config)
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = backend.LibraryDirectories(config)
+	}
+
+	opts := backend.RestoreOptions{
+		NFO:          *nfo,
+		OverwriteNFO: *overwriteNFO,
+		Verify:       *verify,
+		DryRun:       *dryRun,
+	}
+	if *history {
+		opts.History = backend.NewHistory()
+	}
+	if *index {
+		opts.FileIndex = backend.NewFileIndex(backend.GetDataPathWithEnv())
+		if err := opts.FileIndex.Load(); err != nil {
+			log.Printf("Warning: Could not load file index: %v", err)
+		}
+	}
+
+	log.Printf("Scanning %v for provenance sidecars...", dirs)
+	report, err := backend.RestoreFromProvenance(dirs, opts)
+	if report != nil {
+		for _, e := range report.Errors {
+			log.Printf("Error: %s", e)
+		}
+		for _, path := range report.Mismatched {
+			log.Printf("Checksum mismatch: %s", path)
+		}
+		prefix := "Restored"
+		if *dryRun {
+			prefix = "Would restore"
+		}
+		log.Printf("%s from %d sidecar(s): %d history entries (%d already present), %d index entries, %d NFO file(s)",
+			prefix, report.Sidecars, report.History, report.Skipped, report.Indexed, report.NFOs)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if report.Sidecars == 0 {
+		os.Exit(1)
+	}
+}
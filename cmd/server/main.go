@@ -21,6 +21,15 @@ func main() {
 		config = backend.GetDefaultConfig()
 	}
 
+	// Normalize config and refuse to start on values we can't fix
+	validation := config.Validate()
+	for _, w := range validation.Warnings {
+		log.Printf("Config warning: %s: %s", w.Field, w.Message)
+	}
+	if err := validation.Err(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// Initialise structured logger (LOG_LEVEL env var overrides config)
 	backend.InitLogger(config.LogLevel)
 
@@ -38,28 +47,68 @@ func main() {
 	defer cancel()
 
 	// Initialize queue
+	// Settings are published as snapshots shared by the queue and the API
+	configs := backend.NewConfigStore(config)
 	queue := backend.NewQueue(ctx, config.ConcurrentDownloads)
+	queue.SetConfigStore(configs)
+
+	// Resolvers, output permissions, chat bots and the other services
+	if err := backend.ApplyConfig(config, queue); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
 	// Initialize history
 	history := backend.NewHistory()
+	queue.SetHistory(history)
 
 	// Initialize file index
 	dataPath := backend.GetDataPathWithEnv()
 	fileIndex := backend.NewFileIndex(dataPath)
+	if err := fileIndex.Load(); err != nil {
+		log.Printf("Warning: Could not load file index: %v", err)
+	}
+	queue.SetFileIndex(fileIndex)
 	go func() {
-		if err := fileIndex.ScanDirectory(outputDir); err != nil {
-			log.Printf("Warning: Could not scan output directory: %v", err)
+		// Artists with their own base directory are part of the library too
+		for _, dir := range backend.LibraryDirectories(config) {
+			if err := fileIndex.ScanDirectory(dir); err != nil {
+				log.Printf("Warning: Could not scan %s: %v", dir, err)
+			}
+		}
+		fileIndex.ScheduleSave()
+
+		// Catch up library views with changes made while the server was down
+		if report, err := backend.SyncLibraryViews(config); err != nil {
+			log.Printf("Warning: Could not sync library views: %v", err)
+		} else if report.Linked > 0 || report.Removed > 0 {
+			log.Printf("Library views: %d link(s) created, %d removed", report.Linked, report.Removed)
+		}
+		if report, err := backend.BuildCollections(config); err != nil {
+			log.Printf("Warning: Could not build Jellyfin collections: %v", err)
+		} else if report.Collections > 0 || report.Removed > 0 {
+			log.Printf("Jellyfin collections: %d written, %d removed", report.Collections, report.Removed)
+		}
+		if report, err := backend.BuildJellyfinPlaylists(config); err != nil {
+			log.Printf("Warning: Could not build Jellyfin playlists: %v", err)
+		} else if report.Playlists > 0 || report.Removed > 0 {
+			log.Printf("Jellyfin playlists: %d written, %d removed", report.Playlists, report.Removed)
 		}
 	}()
 
 	// Create and configure server
-	server := api.NewServer(config, queue, history, fileIndex)
+	server := api.NewServer(configs, queue, history, fileIndex)
 
 	// Set queue progress callback to broadcast via WebSocket
 	queue.SetProgressCallback(func(event backend.QueueEvent) {
 		server.BroadcastQueueEvent(event)
 	})
 
+	// Prune old finished items per the retention policy
+	queue.StartJanitor(backend.DefaultJanitorInterval)
+
+	// Check watched artists for new releases
+	server.StartWatchlist(ctx)
+
 	// Start queue processing
 	queue.StartProcessing()
 
@@ -73,6 +122,7 @@ func main() {
 		cancel()
 		queue.StopProcessing()
 		queue.SaveQueue()
+		fileIndex.Flush()
 		server.Shutdown()
 	}()
 
@@ -82,6 +132,16 @@ func main() {
 		port = "8080"
 	}
 
+	// Optional gRPC API for typed clients (see internal/api/grpc.go)
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		go func() {
+			log.Printf("gRPC API listening on :%s", grpcPort)
+			if err := server.ListenGRPC(":" + grpcPort); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Server listening on :%s", port)
 	if err := server.Listen(":" + port); err != nil {
 		log.Fatalf("Server error: %v", err)
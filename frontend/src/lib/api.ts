@@ -337,10 +337,17 @@ export async function SaveQueue(): Promise<void> {
 
 // ============== Playlist API ==============
 
-export async function AddPlaylistToQueue(url: string, quality?: string): Promise<string[]> {
+export interface PlaylistRange {
+  from?: number; // 1-based first position
+  to?: number; // 1-based last position (inclusive)
+  reverse?: boolean;
+  after?: string; // YYYY-MM-DD: only entries uploaded on or after
+}
+
+export async function AddPlaylistToQueue(url: string, quality?: string, range?: PlaylistRange): Promise<string[]> {
   const res = await api<{ ids: string[]; playlistTitle: string }>('/playlist', {
     method: 'POST',
-    body: JSON.stringify({ url, quality }),
+    body: JSON.stringify({ url, quality, ...range }),
   });
   return res.ids;
 }
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"youflac/backend"
+	pb "youflac/internal/api/proto/youflac/v1"
+)
+
+// watchQueueBuffer is how many events a WatchQueue stream can fall behind
+// before events are dropped for it
+const watchQueueBuffer = 256
+
+// grpcServices implements the services of proto/youflac/v1 on top of the
+// same backend calls as the REST handlers
+type grpcServices struct {
+	pb.UnimplementedQueueServiceServer
+	pb.UnimplementedHistoryServiceServer
+	pb.UnimplementedConfigServiceServer
+
+	s *Server
+}
+
+// newGRPCServer returns a gRPC server with the queue, history and config
+// services of s registered
+func newGRPCServer(s *Server) *grpc.Server {
+	srv := grpc.NewServer()
+	services := &grpcServices{s: s}
+	pb.RegisterQueueServiceServer(srv, services)
+	pb.RegisterHistoryServiceServer(srv, services)
+	pb.RegisterConfigServiceServer(srv, services)
+	return srv
+}
+
+// ListenGRPC serves the gRPC API on addr until Shutdown
+func (s *Server) ListenGRPC(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeGRPC(lis)
+}
+
+// ServeGRPC serves the gRPC API on lis until Shutdown
+func (s *Server) ServeGRPC(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// ============== Queue Service ==============
+
+func (g *grpcServices) AddToQueue(ctx context.Context, req *pb.AddToQueueRequest) (*pb.QueueItem, error) {
+	request := backend.DownloadRequest{
+		VideoURL:            req.VideoUrl,
+		SpotifyURL:          req.SpotifyUrl,
+		Quality:             req.Quality,
+		AudioSourcePriority: req.AudioSourcePriority,
+		AlbumArtist:         req.AlbumArtist,
+		DryRun:              req.DryRun,
+		NamingTemplate:      req.NamingTemplate,
+		OutputMode:          req.OutputMode,
+		Notes:               req.Notes,
+		Tags:                req.Tags,
+		RequireApproval:     req.RequireApproval,
+	}
+	if err := backend.ValidateYouTubeURL(request.VideoURL); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid video URL: %v", err)
+	}
+	if err := backend.ValidateAudioSources(request.AudioSourcePriority); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	id, err := g.s.queue.AddToQueue(request)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return g.queueItem(id)
+}
+
+func (g *grpcServices) ListQueue(ctx context.Context, req *pb.ListQueueRequest) (*pb.ListQueueResponse, error) {
+	resp := &pb.ListQueueResponse{}
+	for _, item := range g.s.queue.GetQueue() {
+		if req.Status == "" || string(item.Status) == req.Status {
+			resp.Items = append(resp.Items, queueItemToProto(&item))
+		}
+	}
+	return resp, nil
+}
+
+func (g *grpcServices) GetQueueItem(ctx context.Context, req *pb.QueueItemRequest) (*pb.QueueItem, error) {
+	return g.queueItem(req.Id)
+}
+
+func (g *grpcServices) RemoveFromQueue(ctx context.Context, req *pb.QueueItemRequest) (*pb.Empty, error) {
+	if err := g.s.queue.RemoveFromQueue(req.Id); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+func (g *grpcServices) CancelQueueItem(ctx context.Context, req *pb.QueueItemRequest) (*pb.Empty, error) {
+	if err := g.s.queue.CancelItem(req.Id); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+func (g *grpcServices) PauseQueueItem(ctx context.Context, req *pb.QueueItemRequest) (*pb.Empty, error) {
+	if err := g.s.queue.PauseItem(req.Id); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+func (g *grpcServices) ResumeQueueItem(ctx context.Context, req *pb.QueueItemRequest) (*pb.Empty, error) {
+	if err := g.s.queue.ResumeItem(req.Id); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+func (g *grpcServices) RetryFailed(ctx context.Context, _ *pb.Empty) (*pb.CountResponse, error) {
+	return &pb.CountResponse{Count: int32(g.s.queue.RetryFailed())}, nil
+}
+
+func (g *grpcServices) ClearCompleted(ctx context.Context, _ *pb.Empty) (*pb.CountResponse, error) {
+	return &pb.CountResponse{Count: int32(g.s.queue.ClearCompleted())}, nil
+}
+
+func (g *grpcServices) GetQueueStats(ctx context.Context, _ *pb.Empty) (*pb.QueueStats, error) {
+	stats := g.s.queue.GetStats()
+	return &pb.QueueStats{
+		Total:            int32(stats.Total),
+		Pending:          int32(stats.Pending),
+		Active:           int32(stats.Active),
+		Completed:        int32(stats.Completed),
+		Failed:           int32(stats.Failed),
+		Cancelled:        int32(stats.Cancelled),
+		AwaitingApproval: int32(stats.AwaitingApproval),
+		EtaSeconds:       stats.ETASeconds,
+	}, nil
+}
+
+// WatchQueue streams queue events until the client goes away. Like the
+// WebSocket hub, a client that can't keep up misses events rather than
+// holding up the queue.
+func (g *grpcServices) WatchQueue(_ *pb.Empty, stream grpc.ServerStreamingServer[pb.QueueEvent]) error {
+	events := make(chan backend.QueueEvent, watchQueueBuffer)
+	unsubscribe := g.s.queue.Subscribe(func(event backend.QueueEvent) {
+		select {
+		case events <- event:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	// Tell the client it is subscribed before the first event
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(queueEventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// queueItem returns the queue item id as a message
+func (g *grpcServices) queueItem(id string) (*pb.QueueItem, error) {
+	item := g.s.queue.GetItem(id)
+	if item == nil {
+		return nil, status.Errorf(codes.NotFound, "item not found: %s", id)
+	}
+	return queueItemToProto(item), nil
+}
+
+// ============== History Service ==============
+
+func (g *grpcServices) ListHistory(ctx context.Context, req *pb.ListHistoryRequest) (*pb.ListHistoryResponse, error) {
+	page, err := g.s.history.Query(backend.HistoryQuery{Offset: int(req.Offset), Limit: int(req.Limit)})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return historyToProto(page.Entries, page.Total), nil
+}
+
+func (g *grpcServices) SearchHistory(ctx context.Context, req *pb.SearchHistoryRequest) (*pb.ListHistoryResponse, error) {
+	entries := g.s.history.GetAll()
+	if req.Query != "" {
+		entries = g.s.history.Search(req.Query)
+	}
+	return historyToProto(entries, len(entries)), nil
+}
+
+func (g *grpcServices) DeleteHistoryEntry(ctx context.Context, req *pb.HistoryEntryRequest) (*pb.Empty, error) {
+	if g.s.history.GetByID(req.Id) == nil {
+		return nil, status.Errorf(codes.NotFound, "history entry not found: %s", req.Id)
+	}
+	if err := g.s.history.Delete(req.Id); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+func (g *grpcServices) RedownloadFromHistory(ctx context.Context, req *pb.HistoryEntryRequest) (*pb.QueueItem, error) {
+	entry := g.s.history.GetByID(req.Id)
+	if entry == nil {
+		return nil, status.Errorf(codes.NotFound, "history entry not found: %s", req.Id)
+	}
+	request, err := backend.RedownloadRequest(entry, nil)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := g.s.queue.AddToQueue(request)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return g.queueItem(id)
+}
+
+func (g *grpcServices) ClearHistory(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
+	if err := g.s.history.Clear(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Empty{}, nil
+}
+
+// ============== Config Service ==============
+
+func (g *grpcServices) GetConfig(ctx context.Context, _ *pb.Empty) (*pb.Config, error) {
+	config, err := backend.LoadConfig()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	data, err := json.Marshal(backend.RedactSecrets(config))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Config{Json: data}, nil
+}
+
+func (g *grpcServices) SaveConfig(ctx context.Context, req *pb.Config) (*pb.ConfigValidation, error) {
+	var config backend.Config
+	if err := json.Unmarshal(req.Json, &config); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config JSON: %v", err)
+	}
+
+	validation, err := validateConfigForSave(&config)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := g.s.applyConfig(&config, validation); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return configValidationToProto(validation), nil
+}
+
+func (g *grpcServices) ValidateConfig(ctx context.Context, req *pb.Config) (*pb.ConfigValidation, error) {
+	var config backend.Config
+	if err := json.Unmarshal(req.Json, &config); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config JSON: %v", err)
+	}
+	return configValidationToProto(config.Validate()), nil
+}
+
+// ============== Conversions ==============
+
+func queueItemToProto(item *backend.QueueItem) *pb.QueueItem {
+	return &pb.QueueItem{
+		Id:               item.ID,
+		VideoUrl:         item.VideoURL,
+		SpotifyUrl:       item.SpotifyURL,
+		Title:            item.Title,
+		Artist:           item.Artist,
+		Album:            item.Album,
+		Thumbnail:        item.Thumbnail,
+		Duration:         item.Duration,
+		Status:           string(item.Status),
+		Progress:         int32(item.Progress),
+		Stage:            item.Stage,
+		Error:            item.Error,
+		Warnings:         slices.Clone(item.Warnings),
+		OutputPath:       item.OutputPath,
+		FileSize:         item.FileSize,
+		AudioSource:      item.AudioSource,
+		AudioService:     item.AudioService,
+		ActualQuality:    item.ActualQuality,
+		PlaylistName:     item.PlaylistName,
+		PlaylistPosition: int32(item.PlaylistPosition),
+		Tags:             slices.Clone(item.Tags),
+		Notes:            item.Notes,
+		CreatedAt:        timestampToProto(item.CreatedAt),
+		CompletedAt:      timestampToProto(item.CompletedAt),
+	}
+}
+
+func queueEventToProto(event backend.QueueEvent) *pb.QueueEvent {
+	msg := &pb.QueueEvent{
+		Type:     event.Type,
+		ItemId:   event.ItemID,
+		Progress: int32(event.Progress),
+		Status:   string(event.Status),
+		Error:    event.Error,
+		Warning:  event.Warning,
+	}
+	if event.Item != nil {
+		msg.Item = queueItemToProto(event.Item)
+	}
+	return msg
+}
+
+func historyToProto(entries []backend.HistoryEntry, total int) *pb.ListHistoryResponse {
+	resp := &pb.ListHistoryResponse{Total: int32(total)}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &pb.HistoryEntry{
+			Id:          e.ID,
+			VideoUrl:    e.VideoURL,
+			Title:       e.Title,
+			Artist:      e.Artist,
+			AudioSource: e.AudioSource,
+			Quality:     e.Quality,
+			OutputPath:  e.OutputPath,
+			Thumbnail:   e.Thumbnail,
+			Duration:    e.Duration,
+			FileSize:    e.FileSize,
+			CompletedAt: timestampToProto(e.CompletedAt),
+			Status:      e.Status,
+			Error:       e.Error,
+			Notes:       e.Notes,
+			Tags:        slices.Clone(e.Tags),
+		})
+	}
+	return resp
+}
+
+func configValidationToProto(v *backend.ConfigValidation) *pb.ConfigValidation {
+	msg := &pb.ConfigValidation{}
+	for _, w := range v.Warnings {
+		msg.Warnings = append(msg.Warnings, &pb.ConfigValidation_Issue{Field: w.Field, Message: w.Message})
+	}
+	for _, e := range v.Errors {
+		msg.Errors = append(msg.Errors, &pb.ConfigValidation_Issue{Field: e.Field, Message: e.Message})
+	}
+	return msg
+}
+
+// timestampToProto leaves zero times unset
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
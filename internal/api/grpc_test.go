@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"youflac/backend"
+	pb "youflac/internal/api/proto/youflac/v1"
+)
+
+// newTestGRPC serves the gRPC API of a fresh server in memory. Settings,
+// history and queue files go to temporary directories.
+func newTestGRPC(t *testing.T) (*Server, *grpc.ClientConn) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	config := backend.GetDefaultConfig()
+	config.OutputDirectory = t.TempDir()
+	configs := backend.NewConfigStore(config)
+	queue := backend.NewQueue(context.Background(), 1)
+	queue.SetConfigStore(configs)
+	history := backend.NewHistory()
+	queue.SetHistory(history)
+	server := NewServer(configs, queue, history, backend.NewFileIndex(t.TempDir()))
+
+	lis := bufconn.Listen(1 << 20)
+	go server.ServeGRPC(lis)
+	t.Cleanup(func() { server.Shutdown() })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return server, conn
+}
+
+func TestGRPC_Queue(t *testing.T) {
+	_, conn := newTestGRPC(t)
+	client := pb.NewQueueServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watch, err := client.WatchQueue(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The stream is subscribed once the server has sent its headers
+	if _, err := watch.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := client.AddToQueue(ctx, &pb.AddToQueueRequest{
+		VideoUrl: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Tags:     []string{"party"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Id == "" || item.Status != string(backend.StatusPending) || len(item.Tags) != 1 || item.CreatedAt == nil {
+		t.Errorf("AddToQueue = %+v", item)
+	}
+
+	event, err := watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "added" || event.ItemId != item.Id {
+		t.Errorf("event = %+v, want added %s", event, item.Id)
+	}
+
+	list, err := client.ListQueue(ctx, &pb.ListQueueRequest{Status: string(backend.StatusPending)})
+	if err != nil || len(list.Items) != 1 || list.Items[0].Id != item.Id {
+		t.Errorf("ListQueue = %+v, %v", list, err)
+	}
+	stats, err := client.GetQueueStats(ctx, &pb.Empty{})
+	if err != nil || stats.Total != 1 || stats.Pending != 1 {
+		t.Errorf("GetQueueStats = %+v, %v", stats, err)
+	}
+
+	if _, err := client.AddToQueue(ctx, &pb.AddToQueueRequest{VideoUrl: "https://example.com/video"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad URL: %v, want InvalidArgument", err)
+	}
+	if _, err := client.GetQueueItem(ctx, &pb.QueueItemRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("missing item: %v, want NotFound", err)
+	}
+
+	if _, err := client.RemoveFromQueue(ctx, &pb.QueueItemRequest{Id: item.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetQueueItem(ctx, &pb.QueueItemRequest{Id: item.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("removed item: %v, want NotFound", err)
+	}
+}
+
+func TestGRPC_History(t *testing.T) {
+	server, conn := newTestGRPC(t)
+	client := pb.NewHistoryServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server.history.Add(backend.HistoryEntry{
+		ID:          "h1",
+		VideoURL:    "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Title:       "Never Gonna Give You Up",
+		Artist:      "Rick Astley",
+		Status:      "complete",
+		CompletedAt: time.Now(),
+	})
+
+	page, err := client.ListHistory(ctx, &pb.ListHistoryRequest{Limit: 10})
+	if err != nil || page.Total != 1 || len(page.Entries) != 1 || page.Entries[0].Artist != "Rick Astley" {
+		t.Fatalf("ListHistory = %+v, %v", page, err)
+	}
+	found, err := client.SearchHistory(ctx, &pb.SearchHistoryRequest{Query: "astley"})
+	if err != nil || len(found.Entries) != 1 {
+		t.Errorf("SearchHistory = %+v, %v", found, err)
+	}
+
+	item, err := client.RedownloadFromHistory(ctx, &pb.HistoryEntryRequest{Id: "h1"})
+	if err != nil || item.VideoUrl != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("RedownloadFromHistory = %+v, %v", item, err)
+	}
+	if server.queue.GetItem(item.GetId()) == nil {
+		t.Error("redownload not queued")
+	}
+
+	if _, err := client.DeleteHistoryEntry(ctx, &pb.HistoryEntryRequest{Id: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteHistoryEntry(ctx, &pb.HistoryEntryRequest{Id: "h1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("deleted entry: %v, want NotFound", err)
+	}
+}
+
+func TestGRPC_Config(t *testing.T) {
+	server, conn := newTestGRPC(t)
+	t.Setenv("CONFIG_DIR", t.TempDir())
+	t.Setenv(backend.SecretsPassphraseEnv, "test passphrase")
+	client := pb.NewConfigServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config := backend.GetDefaultConfig()
+	config.OutputDirectory = t.TempDir()
+	config.ConcurrentDownloads = 3
+	config.LastFMAPIKey = "lastfm-secret"
+	data, _ := json.Marshal(config)
+	if _, err := client.SaveConfig(ctx, &pb.Config{Json: data}); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.configs.Get().ConcurrentDownloads; got != 3 {
+		t.Errorf("queue config not updated: concurrentDownloads = %d", got)
+	}
+
+	saved, err := client.GetConfig(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded backend.Config
+	if err := json.Unmarshal(saved.Json, &loaded); err != nil || loaded.ConcurrentDownloads != 3 {
+		t.Errorf("GetConfig = %s, %v", saved.Json, err)
+	}
+	if loaded.LastFMAPIKey != "" {
+		t.Errorf("GetConfig leaked lastFmApiKey %q", loaded.LastFMAPIKey)
+	}
+
+	// Saving the redacted config back keeps the stored secret
+	if _, err := client.SaveConfig(ctx, &pb.Config{Json: saved.Json}); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.configs.Get().LastFMAPIKey; got != "lastfm-secret" {
+		t.Errorf("secret lost after saving a redacted config: lastFmApiKey = %q", got)
+	}
+
+	if _, err := client.SaveConfig(ctx, &pb.Config{Json: []byte("{")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad JSON: %v, want InvalidArgument", err)
+	}
+}
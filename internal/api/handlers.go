@@ -1,8 +1,12 @@
 package api
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,24 +17,22 @@ import (
 	"youflac/backend"
 )
 
-const AppVersion = "2.0.0"
-
 // Health check
 func (s *Server) handleHealth(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":  "ok",
-		"version": AppVersion,
+		"version": backend.AppVersion,
 	})
 }
 
 func (s *Server) handleGetVersion(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"version": AppVersion})
+	return c.JSON(fiber.Map{"version": backend.AppVersion})
 }
 
 func (s *Server) handleServicesStatus(c *fiber.Ctx) error {
 	proxyURL := ""
-	if s.config != nil {
-		proxyURL = s.config.ProxyURL
+	if config := s.configs.Get(); config != nil {
+		proxyURL = config.ProxyURL
 	}
 	statuses := backend.CheckServiceStatus(proxyURL)
 	return c.JSON(statuses)
@@ -53,6 +55,10 @@ func (s *Server) handleAddToQueue(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid video URL: " + err.Error()})
 	}
 
+	if err := backend.ValidateAudioSources(req.AudioSourcePriority); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	id, err := s.queue.AddToQueue(req)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -61,6 +67,73 @@ func (s *Server) handleAddToQueue(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"id": id})
 }
 
+// handleShortcutAdd queues a link with a single GET request, for iOS
+// Shortcuts and Android share targets:
+//
+//	GET /api/add?key=<shortcutKey>&url=<YouTube or Spotify link>
+//
+// url may be the whole shared text ("Title https://youtu.be/..."); its first
+// link is used. Browsers get a small HTML page, other clients JSON
+// (format=html or format=json overrides).
+func (s *Server) handleShortcutAdd(c *fiber.Ctx) error {
+	config := s.configs.Get()
+	if config == nil || config.ShortcutKey == "" {
+		return shortcutReply(c, 403, "Shortcut endpoint disabled: set a shortcut key in settings", nil)
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("key")), []byte(config.ShortcutKey)) != 1 {
+		return shortcutReply(c, 401, "Invalid key", nil)
+	}
+
+	link := ""
+	for _, f := range strings.Fields(c.Query("url")) {
+		if strings.HasPrefix(f, "http://") || strings.HasPrefix(f, "https://") {
+			link = f
+			break
+		}
+	}
+	if link == "" {
+		return shortcutReply(c, 400, "No link in url parameter", nil)
+	}
+
+	req, err := backend.DownloadRequestForLink(link, s.configs.Get())
+	if err != nil {
+		return shortcutReply(c, 400, "Not queued: "+err.Error(), nil)
+	}
+	req.Notes = "Added from a shortcut"
+	id, err := s.queue.AddToQueue(req)
+	if err != nil {
+		return shortcutReply(c, 500, "Not queued: "+err.Error(), nil)
+	}
+	return shortcutReply(c, 200, "Queued: "+link, fiber.Map{"id": id, "videoUrl": req.VideoURL})
+}
+
+// shortcutReply answers /api/add as HTML or JSON
+func shortcutReply(c *fiber.Ctx, status int, message string, data fiber.Map) error {
+	format := c.Query("format")
+	if format == "" {
+		format = "json"
+		if c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML {
+			format = "html"
+		}
+	}
+	c.Status(status)
+
+	if format == "html" {
+		c.Type("html", "utf-8")
+		return c.SendString("<!DOCTYPE html><html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1\"><title>YouFlac</title></head><body><p>" +
+			html.EscapeString(message) + "</p></body></html>")
+	}
+	if data == nil {
+		data = fiber.Map{}
+	}
+	if status >= 400 {
+		data["error"] = message
+	} else {
+		data["message"] = message
+	}
+	return c.JSON(data)
+}
+
 func (s *Server) handleGetQueueItem(c *fiber.Ctx) error {
 	id := c.Params("id")
 	item := s.queue.GetItem(id)
@@ -102,6 +175,100 @@ func (s *Server) handleResumeQueueItem(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleCommitDryRun queues a planned dry-run item for a real download
+func (s *Server) handleCommitDryRun(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := s.queue.CommitDryRun(id); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleApproveQueueItem starts an item awaiting approval. The optional
+// musicUrl picks the audio match, e.g. one of the plan's candidates.
+func (s *Server) handleApproveQueueItem(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body struct {
+		MusicURL string `json:"musicUrl"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	if err := s.queue.ApproveItem(id, body.MusicURL); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func (s *Server) handleRejectQueueItem(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := s.queue.RejectItem(id); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleGetQueuePreview serves the sync preview clip of a completed item
+func (s *Server) handleGetQueuePreview(c *fiber.Ctx) error {
+	item := s.queue.GetItem(c.Params("id"))
+	if item == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Item not found"})
+	}
+	if item.PreviewPath == "" {
+		return c.Status(404).JSON(fiber.Map{"error": "No sync preview for this item"})
+	}
+	if _, err := os.Stat(item.PreviewPath); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "No sync preview for this item"})
+	}
+	c.Set(fiber.HeaderContentType, "video/mp4")
+	return c.SendFile(item.PreviewPath)
+}
+
+// handleRejectMatch deletes a completed download with a bad match so it can be retried
+func (s *Server) handleRejectMatch(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := s.queue.RejectMatch(id); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func (s *Server) handleApproveAll(c *fiber.Ctx) error {
+	count := s.queue.ApproveAll()
+	return c.JSON(fiber.Map{"approved": count})
+}
+
+// handleRetrySync runs the rclone sync again for a completed item
+func (s *Server) handleRetrySync(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := s.queue.RetrySync(id); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handlePlanDownload dry-runs a download request without queueing it
+func (s *Server) handlePlanDownload(c *fiber.Ctx) error {
+	var req backend.DownloadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.VideoURL != "" {
+		if err := backend.ValidateYouTubeURL(req.VideoURL); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid video URL: " + err.Error()})
+		}
+	}
+
+	plan, err := backend.PlanRequest(req, s.configs.Get(), s.fileIndex)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(plan)
+}
+
 func (s *Server) handleMoveQueueItem(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var body struct {
@@ -117,6 +284,48 @@ func (s *Server) handleMoveQueueItem(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// notesRequest is the body of the queue and history notes endpoints
+type notesRequest struct {
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"`
+}
+
+func (s *Server) handleSetQueueItemNotes(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body notesRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if s.queue.GetItem(id) == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Item not found"})
+	}
+	if err := s.queue.SetItemNotes(id, body.Notes, body.Tags); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(s.queue.GetItem(id))
+}
+
+type dependenciesRequest struct {
+	DependsOn []string `json:"dependsOn"`
+}
+
+func (s *Server) handleSetQueueItemDependencies(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body dependenciesRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if s.queue.GetItem(id) == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Item not found"})
+	}
+	if err := s.queue.SetItemDependencies(id, body.DependsOn); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(s.queue.GetItem(id))
+}
+
 func (s *Server) handleGetQueueStats(c *fiber.Ctx) error {
 	stats := s.queue.GetStats()
 	return c.JSON(stats)
@@ -142,6 +351,23 @@ func (s *Server) handleResumeAll(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"resumed": count})
 }
 
+func (s *Server) handleGetSchedule(c *fiber.Ctx) error {
+	return c.JSON(s.queue.GetScheduleStatus())
+}
+
+// handleOverrideSchedule runs the queue outside its schedule for the given
+// number of minutes; 0 returns to the schedule
+func (s *Server) handleOverrideSchedule(c *fiber.Ctx) error {
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	s.queue.OverrideSchedule(time.Duration(req.Minutes) * time.Minute)
+	return c.JSON(s.queue.GetScheduleStatus())
+}
+
 func (s *Server) handleRetryQueueItemWithOverride(c *fiber.Ctx) error {
 	id := c.Params("id")
 
@@ -149,6 +375,11 @@ func (s *Server) handleRetryQueueItemWithOverride(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if req.VideoURL != "" {
+		if err := backend.ValidateYouTubeURL(req.VideoURL); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid video URL: " + err.Error()})
+		}
+	}
 
 	item, err := s.queue.RetryWithOverride(id, req)
 	if err != nil {
@@ -181,34 +412,60 @@ func (s *Server) handleExportFailed(c *fiber.Ctx) error {
 
 func (s *Server) handleAddPlaylistToQueue(c *fiber.Ctx) error {
 	var body struct {
-		URL     string `json:"url"`
-		Quality string `json:"quality"`
+		URL          string `json:"url"`
+		Quality      string `json:"quality"`
+		DryRun       bool   `json:"dryRun"`
+		Continuation string `json:"continuation"` // From a previous partial import
+		Limit        int    `json:"limit"`        // Entries per page, 0 = all
+
+		IncludeExisting bool `json:"includeExisting"` // Queue entries already downloaded or in the library
+
+		backend.PlaylistRange // from, to, reverse, after: import part of the playlist
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
+	if err := body.PlaylistRange.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	if err := backend.ValidateYouTubeURL(body.URL); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid playlist URL: " + err.Error()})
+	if body.Continuation == "" {
+		if err := backend.ValidateYouTubeURL(body.URL); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid playlist URL: " + err.Error()})
+		}
+	}
+	if body.Limit < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit must not be negative"})
 	}
 
 	quality := body.Quality
 	if quality == "" {
-		quality = s.config.VideoQuality
+		quality = s.configs.Get().VideoQuality
 	}
 
-	// Get playlist info
-	playlist, err := backend.GetPlaylistVideos(body.URL)
+	// Get playlist info, resuming after a partial fetch when a token is given
+	var playlist *backend.PlaylistInfo
+	var err error
+	if body.Continuation != "" {
+		playlist, err = backend.ContinuePlaylistRange(body.Continuation, body.PlaylistRange, body.Limit)
+	} else {
+		playlist, err = backend.GetPlaylistRange(body.URL, body.PlaylistRange, body.Limit)
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// Skip unavailable entries and, unless asked, ones already downloaded
+	check := backend.CheckPlaylist(playlist, s.configs.Get(), s.history, s.fileIndex)
+
 	// Add each video to queue
 	ids := []string{}
-	for _, video := range playlist.Videos {
+	for _, video := range check.Importable(body.IncludeExisting) {
 		req := backend.DownloadRequest{
-			VideoURL: video.URL,
-			Quality:  quality,
+			VideoURL:   video.URL,
+			Quality:    quality,
+			DryRun:     body.DryRun,
+			TrackTotal: playlist.TrackTotal(),
 		}
 		// Convert PlaylistVideo to VideoInfo
 		videoInfo := &backend.VideoInfo{
@@ -226,7 +483,201 @@ func (s *Server) handleAddPlaylistToQueue(c *fiber.Ctx) error {
 		ids = append(ids, id)
 	}
 
-	return c.JSON(fiber.Map{"ids": ids, "playlistTitle": playlist.Title})
+	return c.JSON(fiber.Map{
+		"ids":           ids,
+		"playlistTitle": playlist.Title,
+		"partial":       playlist.Partial,
+		"continuation":  playlist.Continuation,
+		"total":         playlist.Total,
+		"new":           check.New,
+		"downloaded":    check.Downloaded,
+		"inLibrary":     check.InLibrary,
+		"unavailable":   check.Unavailable,
+		"filtered":      check.Filtered,
+		"flagged":       check.Flagged,
+	})
+}
+
+// handleCheckPlaylist returns the pre-import summary of a playlist without queueing anything
+func (s *Server) handleCheckPlaylist(c *fiber.Ctx) error {
+	var body struct {
+		URL          string `json:"url"`
+		Continuation string `json:"continuation"`
+		Limit        int    `json:"limit"`
+
+		backend.PlaylistRange
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := body.PlaylistRange.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var playlist *backend.PlaylistInfo
+	var err error
+	if body.Continuation != "" {
+		playlist, err = backend.ContinuePlaylistRange(body.Continuation, body.PlaylistRange, body.Limit)
+	} else {
+		if err := backend.ValidateYouTubeURL(body.URL); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid playlist URL: " + err.Error()})
+		}
+		playlist, err = backend.GetPlaylistRange(body.URL, body.PlaylistRange, body.Limit)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(backend.CheckPlaylist(playlist, s.configs.Get(), s.history, s.fileIndex))
+}
+
+// ============== Search Handlers ==============
+
+// handleSearch runs a free-text search over YouTube and Tidal and returns
+// the merged, ranked candidates
+func (s *Server) handleSearch(c *fiber.Ctx) error {
+	var body struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(body.Query) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Query is required"})
+	}
+
+	results, err := backend.UnifiedSearch(body.Query, body.Limit, s.configs.Get().CookiesBrowser)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(results)
+}
+
+// handleResolve returns the preview card data for a URL (video info, platform
+// links, best audio candidate, output path) without queueing anything
+func (s *Server) handleResolve(c *fiber.Ctx) error {
+	rawURL := c.Query("url")
+	if rawURL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
+	}
+
+	preview, err := backend.ResolveTrack(rawURL, s.configs.Get(), s.fileIndex)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(preview)
+}
+
+// ============== Channel Archive Handlers ==============
+
+func (s *Server) handleGetChannels(c *fiber.Ctx) error {
+	return c.JSON(s.channels.List())
+}
+
+// handleAddChannel follows a channel and starts the first sync in the background
+func (s *Server) handleAddChannel(c *fiber.Ctx) error {
+	var archive backend.ChannelArchive
+	if err := c.BodyParser(&archive); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if archive.Quality == "" {
+		archive.Quality = s.configs.Get().VideoQuality
+	}
+
+	added, err := s.channels.Add(archive)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Failures are recorded in the archive's LastError
+	go s.channels.Sync(added.ID, s.queue, s.history, s.fileIndex)
+
+	return c.JSON(added)
+}
+
+// handleSyncChannel queues the videos uploaded since the last sync
+func (s *Server) handleSyncChannel(c *fiber.Ctx) error {
+	result, err := s.channels.Sync(c.Params("id"), s.queue, s.history, s.fileIndex)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+func (s *Server) handleRemoveChannel(c *fiber.Ctx) error {
+	if err := s.channels.Remove(c.Params("id")); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ============== Watchlist Handlers ==============
+
+func (s *Server) handleGetWatchlist(c *fiber.Ctx) error {
+	return c.JSON(s.watchlist.List())
+}
+
+// handleAddWatchedArtist watches an artist and runs the first check in the
+// background, which records the existing releases
+func (s *Server) handleAddWatchedArtist(c *fiber.Ctx) error {
+	var artist backend.WatchedArtist
+	if err := c.BodyParser(&artist); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	added, err := s.watchlist.Add(artist)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Failures are recorded in the artist's LastError
+	if added.LastCheckAt.IsZero() {
+		go s.watchlist.Check(added.ID, s.queue, s.history, s.fileIndex)
+	}
+
+	return c.JSON(added)
+}
+
+// handleCheckWatchedArtist looks for new releases now
+func (s *Server) handleCheckWatchedArtist(c *fiber.Ctx) error {
+	result, err := s.watchlist.Check(c.Params("id"), s.queue, s.history, s.fileIndex)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+func (s *Server) handleRemoveWatchedArtist(c *fiber.Ctx) error {
+	if err := s.watchlist.Remove(c.Params("id")); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleGetWatchReleases lists found releases, optionally by status (?status=pending)
+func (s *Server) handleGetWatchReleases(c *fiber.Ctx) error {
+	return c.JSON(s.watchlist.Releases(c.Query("status")))
+}
+
+func (s *Server) handleDismissWatchRelease(c *fiber.Ctx) error {
+	if err := s.watchlist.Dismiss(c.Params("id")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ============== Localization Handlers ==============
+
+// handleGetStageCatalog returns the queue stage messages for ?lang= (English
+// when missing or unknown), keyed by the stageCode sent with queue items
+func (s *Server) handleGetStageCatalog(c *fiber.Ctx) error {
+	lang := c.Query("lang", backend.DefaultStageLanguage)
+	return c.JSON(fiber.Map{
+		"language":  lang,
+		"languages": backend.StageLanguages(),
+		"messages":  backend.StageCatalog(lang),
+	})
 }
 
 // ============== Config Handlers ==============
@@ -236,7 +687,7 @@ func (s *Server) handleGetConfig(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(config)
+	return c.JSON(backend.RedactSecrets(config))
 }
 
 func (s *Server) handleSaveConfig(c *fiber.Ctx) error {
@@ -245,30 +696,225 @@ func (s *Server) handleSaveConfig(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	validation, err := validateConfigForSave(&config)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "validation": validation})
+	}
+	if err := s.applyConfig(&config, validation); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "warnings": validation.Warnings})
+}
+
+// validateConfigForSave normalizes config and returns an error when it
+// can't be saved. validation is nil when the error comes from the checks
+// run before Config.Validate.
+func validateConfigForSave(config *backend.Config) (*backend.ConfigValidation, error) {
 	if err := backend.ValidateOutputDirectory(config.OutputDirectory); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid output directory: " + err.Error()})
+		return nil, fmt.Errorf("Invalid output directory: %w", err)
 	}
 
 	if len(config.AudioSourcePriority) > 0 {
 		if err := backend.ValidateAudioSources(config.AudioSourcePriority); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Invalid audio source priority: " + err.Error()})
+			return nil, fmt.Errorf("Invalid audio source priority: %w", err)
 		}
 	}
 
-	if err := backend.SaveConfig(&config); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	validation := config.Validate()
+	return validation, validation.Err()
+}
+
+// applyConfig saves a validated config and publishes it to the queue and
+// the services. Items already downloading keep their snapshot. Problems
+// applying a setting are added to validation as warnings.
+func (s *Server) applyConfig(config *backend.Config, validation *backend.ConfigValidation) error {
+	if err := backend.SaveConfig(config); err != nil {
+		return err
+	}
+	if err := backend.ApplyConfig(config, s.queue); err != nil {
+		validation.Warnings = append(validation.Warnings, backend.ConfigIssue{Field: "tempDirectory", Message: err.Error()})
 	}
+	return nil
+}
 
-	// Update server config
-	s.config = &config
+// handleValidateConfig checks a config without saving it and returns the
+// normalized values so the settings UI can show fixes inline
+func (s *Server) handleValidateConfig(c *fiber.Ctx) error {
+	var config backend.Config
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
 
-	return c.JSON(fiber.Map{"success": true})
+	validation := config.Validate()
+	return c.JSON(fiber.Map{
+		"valid":    validation.Valid(),
+		"errors":   validation.Errors,
+		"warnings": validation.Warnings,
+		"config":   config,
+	})
+}
+
+// handleTestMetadataRules applies metadata rules to a sample title, artist
+// and album. Without rules in the body the configured ones are tried.
+func (s *Server) handleTestMetadataRules(c *fiber.Ctx) error {
+	var req struct {
+		Rules  []string `json:"rules"`
+		Title  string   `json:"title"`
+		Artist string   `json:"artist"`
+		Album  string   `json:"album"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rules := req.Rules
+	if len(rules) == 0 {
+		if config := s.configs.Get(); config != nil {
+			rules = config.MetadataRules
+		}
+	}
+
+	rewrite, errors := backend.TryMetadataRules(rules, req.Title, req.Artist, req.Album)
+	return c.JSON(fiber.Map{
+		"title":   rewrite.Title,
+		"artist":  rewrite.Artist,
+		"album":   rewrite.Album,
+		"applied": rewrite.Applied,
+		"errors":  errors,
+	})
 }
 
 func (s *Server) handleGetDefaultOutput(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"path": backend.GetDefaultOutputDirectory()})
 }
 
+// ============== Secrets Handlers ==============
+
+func (s *Server) handleListSecrets(c *fiber.Ctx) error {
+	store := backend.DefaultSecretStore()
+	stored := []string{}
+	if store.Exists() {
+		names, err := store.Names()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		stored = names
+	}
+	return c.JSON(fiber.Map{
+		"keySource": store.KeySource(),
+		"stored":    stored,
+		"known":     backend.KnownSecrets,
+	})
+}
+
+func (s *Server) handleSetSecret(c *fiber.Ctx) error {
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := s.setSecret(c.Params("name"), req.Value); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func (s *Server) handleDeleteSecret(c *fiber.Ctx) error {
+	if err := s.setSecret(c.Params("name"), ""); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// setSecret stores a secret and publishes a config carrying its new value
+func (s *Server) setSecret(name, value string) error {
+	var err error
+	s.configs.Update(func(config *backend.Config) {
+		err = backend.SetSecret(name, value, config)
+	})
+	return err
+}
+
+// handleTestNotification sends a test message using the posted config, or
+// the current config when the body is empty
+func (s *Server) handleTestNotification(c *fiber.Ctx) error {
+	config := s.configs.Get().Clone()
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(config); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if err := backend.SendTestNotification(config); err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleUploadCookies accepts a cookies.txt export as a multipart "file" field or raw body
+func (s *Server) handleUploadCookies(c *fiber.Ctx) error {
+	content := string(c.Body())
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Failed to read upload"})
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Failed to read upload"})
+		}
+		content = string(data)
+	}
+
+	if err := backend.SetSecret(backend.SecretYouTubeCookies, content, nil); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// ============== Setup Handlers ==============
+
+func (s *Server) handleGetSetupStatus(c *fiber.Ctx) error {
+	return c.JSON(backend.GetSetupStatus())
+}
+
+func (s *Server) handleTestOutputDirectory(c *fiber.Ctx) error {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if err := backend.TestOutputDirectory(req.Path); err != nil {
+		return c.JSON(fiber.Map{"writable": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"writable": true})
+}
+
+func (s *Server) handleProbeServices(c *fiber.Ctx) error {
+	return c.JSON(backend.ProbeAudioServices(c.Query("proxy")))
+}
+
+func (s *Server) handleCompleteSetup(c *fiber.Ctx) error {
+	config := backend.GetDefaultConfig()
+	if err := c.BodyParser(config); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	validation, err := backend.CompleteSetup(config)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "validation": validation})
+	}
+
+	if err := backend.ApplyConfig(config, s.queue); err != nil {
+		validation.Warnings = append(validation.Warnings, backend.ConfigIssue{Field: "tempDirectory", Message: err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "warnings": validation.Warnings, "config": backend.RedactSecrets(config)})
+}
+
 // ============== History Handlers ==============
 
 func (s *Server) handleGetHistory(c *fiber.Ctx) error {
@@ -276,6 +922,26 @@ func (s *Server) handleGetHistory(c *fiber.Ctx) error {
 	return c.JSON(entries)
 }
 
+func (s *Server) handleGetHistoryPage(c *fiber.Ctx) error {
+	query := backend.HistoryQuery{
+		Offset: c.QueryInt("offset", 0),
+		Limit:  c.QueryInt("limit", backend.DefaultHistoryPageSize),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Search: c.Query("q"),
+		Status: c.Query("status"),
+		Source: c.Query("source"),
+		Tag:    c.Query("tag"),
+
+		NeedsUpgrade: c.QueryBool("needsUpgrade"),
+	}
+	page, err := s.history.Query(query)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(page)
+}
+
 func (s *Server) handleGetHistoryStats(c *fiber.Ctx) error {
 	stats := s.history.GetStats()
 	return c.JSON(stats)
@@ -290,6 +956,26 @@ func (s *Server) handleSearchHistory(c *fiber.Ctx) error {
 	return c.JSON(results)
 }
 
+func (s *Server) handleGetHistoryTags(c *fiber.Ctx) error {
+	return c.JSON(s.history.Tags())
+}
+
+func (s *Server) handleSetHistoryNotes(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body notesRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if s.history.GetByID(id) == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "History entry not found"})
+	}
+	if err := s.history.SetNotes(id, body.Notes, body.Tags); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(s.history.GetByID(id))
+}
+
 func (s *Server) handleDeleteHistoryEntry(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if err := s.history.Delete(id); err != nil {
@@ -305,6 +991,37 @@ func (s *Server) handleClearHistory(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleExportYtdlpArchive downloads the history as a yt-dlp archive.txt
+func (s *Server) handleExportYtdlpArchive(c *fiber.Ctx) error {
+	var sb strings.Builder
+	if _, err := s.history.ExportYtdlpArchive(&sb); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	c.Set("Content-Disposition", `attachment; filename="archive.txt"`)
+	return c.SendString(sb.String())
+}
+
+// handleImportYtdlpArchive accepts a yt-dlp archive.txt as a multipart "file" field or raw body
+func (s *Server) handleImportYtdlpArchive(c *fiber.Ctx) error {
+	var archive io.Reader = bytes.NewReader(c.Body())
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Failed to read upload"})
+		}
+		defer f.Close()
+		archive = f
+	}
+
+	result, err := s.history.ImportYtdlpArchive(archive)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
 func (s *Server) handleRedownloadFromHistory(c *fiber.Ctx) error {
 	id := c.Params("id")
 
@@ -322,10 +1039,18 @@ func (s *Server) handleRedownloadFromHistory(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "History entry not found"})
 	}
 
-	// Add to queue
-	req := backend.DownloadRequest{
-		VideoURL: entry.VideoURL,
-		Quality:  entry.Quality,
+	// Optional body with quality/source/output/naming overrides
+	var overrides *backend.RedownloadOverrides
+	if len(c.Body()) > 0 {
+		overrides = &backend.RedownloadOverrides{}
+		if err := c.BodyParser(overrides); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	req, err := backend.RedownloadRequest(entry, overrides)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	newID, err := s.queue.AddToQueue(req)
@@ -423,7 +1148,8 @@ type FileInfo struct {
 	IsDir     bool   `json:"isDir"`
 	Size      int64  `json:"size"`
 	Extension string `json:"extension"`
-	Type      string `json:"type"` // "video", "audio", "cover", "nfo", "other"
+	Type      string `json:"type"`                // "video", "audio", "cover", "nfo", "other"
+	Thumbnail string `json:"thumbnail,omitempty"` // path servable via /api/image
 }
 
 // getFileType determines the type of file based on its extension
@@ -448,7 +1174,7 @@ func getFileType(ext string) string {
 func (s *Server) handleListFiles(c *fiber.Ctx) error {
 	dir := c.Query("dir")
 	if dir == "" {
-		dir = s.config.OutputDirectory
+		dir = s.configs.Get().OutputDirectory
 		if dir == "" {
 			dir = backend.GetDefaultOutputDirectory()
 		}
@@ -495,11 +1221,49 @@ func (s *Server) handleListFiles(c *fiber.Ctx) error {
 		})
 	}
 
+	// Thumbnails already in the cache are attached; ?thumbnails=true also
+	// generates the missing ones, otherwise /files/thumbnail does it per file
+	var mediaPaths []string
+	for _, f := range files {
+		if !f.IsDir && (f.Type == "video" || f.Type == "audio") {
+			mediaPaths = append(mediaPaths, f.Path)
+		}
+	}
+	if c.QueryBool("thumbnails") {
+		thumbs := backend.GenerateThumbnails(mediaPaths, 4)
+		for i := range files {
+			files[i].Thumbnail = thumbs[files[i].Path]
+		}
+	} else {
+		for i := range files {
+			if files[i].IsDir {
+				continue
+			}
+			if thumb, ok := backend.CachedThumbnail(files[i].Path); ok {
+				files[i].Thumbnail = thumb
+			}
+		}
+	}
+
 	return c.JSON(files)
 }
 
+// handleGetFileThumbnail generates (or returns the cached) thumbnail of one
+// library file; the path is servable via /api/image
+func (s *Server) handleGetFileThumbnail(c *fiber.Ctx) error {
+	path, ok := s.libraryFilePath(c.Query("path"))
+	if !ok {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+	thumb, err := backend.GenerateThumbnail(path)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"thumbnail": thumb})
+}
+
 func (s *Server) handleGetPlaylistFolders(c *fiber.Ctx) error {
-	outputDir := s.config.OutputDirectory
+	outputDir := s.configs.Get().OutputDirectory
 	if outputDir == "" {
 		outputDir = backend.GetDefaultOutputDirectory()
 	}
@@ -566,6 +1330,159 @@ func (s *Server) handleFlattenPlaylist(c *fiber.Ctx) error {
 	})
 }
 
+func (s *Server) handleGetDuplicates(c *fiber.Ctx) error {
+	if s.fileIndex == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "File index not available"})
+	}
+	return c.JSON(s.fileIndex.DedupeReport())
+}
+
+// handleGetAlbums reports which tracks of each library album are present
+func (s *Server) handleGetAlbums(c *fiber.Ctx) error {
+	if s.fileIndex == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "File index not available"})
+	}
+	return c.JSON(backend.CheckAlbums(s.fileIndex))
+}
+
+// handleDownloadMissingAlbumTracks queues the tracks of an album that are not in the library
+func (s *Server) handleDownloadMissingAlbumTracks(c *fiber.Ctx) error {
+	result, err := s.queue.QueueMissingAlbumTracks(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// libraryFilePath resolves a path query parameter, allowing only files in
+// one of the library directories
+func (s *Server) libraryFilePath(path string) (string, bool) {
+	absPath, err := filepath.Abs(path)
+	if path == "" || err != nil {
+		return "", false
+	}
+	return absPath, s.inLibrary(absPath)
+}
+
+// inLibrary reports whether absPath lies inside OutputDirectory, a
+// configured library or an artist override directory
+func (s *Server) inLibrary(absPath string) bool {
+	for _, dir := range backend.LibraryDirectories(s.configs.Get()) {
+		absDir, err := filepath.Abs(dir)
+		if err == nil && strings.HasPrefix(absPath, absDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetFileMetadata returns the metadata of a library file (NFO and
+// embedded tags) for editing
+func (s *Server) handleGetFileMetadata(c *fiber.Ctx) error {
+	path, ok := s.libraryFilePath(c.Query("path"))
+	if !ok {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+	metadata, err := backend.ReadMediaMetadata(path)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(metadata)
+}
+
+// handleUpdateFileMetadata applies edited metadata to the NFO, the embedded
+// tags and the file index
+func (s *Server) handleUpdateFileMetadata(c *fiber.Ctx) error {
+	path, ok := s.libraryFilePath(c.Query("path"))
+	if !ok {
+		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
+	}
+	var metadata backend.Metadata
+	if err := c.BodyParser(&metadata); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := backend.UpdateMediaMetadata(path, &metadata, s.configs.Get(), s.fileIndex); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func (s *Server) handleCheckLibrary(c *fiber.Ctx) error {
+	var body struct {
+		Repair bool `json:"repair"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	config := s.configs.Get()
+	opts := backend.LibraryCheckOptionsFromConfig(config, body.Repair)
+	opts.History = s.history
+
+	report, err := backend.CheckLibraries(backend.LibraryDirectories(config), opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// handleSyncLibraryViews brings the link-farm views up to date with the library
+func (s *Server) handleSyncLibraryViews(c *fiber.Ctx) error {
+	report, err := backend.SyncLibraryViews(s.configs.Get())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// handleBuildCollections writes the Jellyfin collections for playlists and artists
+func (s *Server) handleBuildCollections(c *fiber.Ctx) error {
+	report, err := backend.BuildCollections(s.configs.Get())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// handleBuildJellyfinPlaylists writes a Jellyfin playlist for every playlist folder
+func (s *Server) handleBuildJellyfinPlaylists(c *fiber.Ctx) error {
+	report, err := backend.BuildJellyfinPlaylists(s.configs.Get())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// handleQualityReport analyzes the whole library. ?format=csv downloads the
+// report as CSV, ?spectral=false skips the (slow) spectral cutoff measurement.
+func (s *Server) handleQualityReport(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "json"))
+	if format != "json" && format != "csv" {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be json or csv"})
+	}
+
+	report, err := backend.BuildLibraryQualityReport(backend.LibraryDirectories(s.configs.Get()), backend.QualityReportOptions{
+		Spectral: c.QueryBool("spectral", true),
+		History:  s.history,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if format == "json" {
+		return c.JSON(report)
+	}
+
+	var sb strings.Builder
+	if err := report.WriteCSV(&sb); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="library_quality_%s.csv"`, time.Now().Format("2006-01-02")))
+	return c.SendString(sb.String())
+}
+
 // ============== Analyzer Handlers ==============
 
 func (s *Server) handleAnalyzeAudio(c *fiber.Ctx) error {
@@ -593,7 +1510,7 @@ func (s *Server) handleGenerateSpectrogram(c *fiber.Ctx) error {
 	}
 
 	// Generate spectrogram to temp file
-	tempDir := os.TempDir()
+	tempDir := backend.GetTempDirectory()
 	outputPath := filepath.Join(tempDir, "spectrogram_"+filepath.Base(body.FilePath)+".png")
 
 	if err := backend.GenerateSpectrogram(body.FilePath, outputPath); err != nil {
@@ -612,7 +1529,7 @@ func (s *Server) handleGenerateWaveform(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
-	tempDir := os.TempDir()
+	tempDir := backend.GetTempDirectory()
 	outputPath := filepath.Join(tempDir, "waveform_"+filepath.Base(body.FilePath)+".png")
 
 	if err := backend.GenerateWaveform(body.FilePath, outputPath); err != nil {
@@ -709,15 +1626,12 @@ func (s *Server) handleGetImage(c *fiber.Ctx) error {
 	}
 
 	absTemp, _ := filepath.Abs(os.TempDir())
-	absOutput := s.config.OutputDirectory
-	if absOutput == "" {
-		absOutput = backend.GetDefaultOutputDirectory()
-	}
-	absOutput, _ = filepath.Abs(absOutput)
+	absWorkTemp, _ := filepath.Abs(backend.GetTempDirectory())
 
 	// Ensure the separator-terminated prefix so "/tmp" doesn't match "/tmpother"
 	if !strings.HasPrefix(absPath, absTemp+string(filepath.Separator)) &&
-		!strings.HasPrefix(absPath, absOutput+string(filepath.Separator)) {
+		!strings.HasPrefix(absPath, absWorkTemp+string(filepath.Separator)) &&
+		!s.inLibrary(absPath) {
 		return c.Status(403).JSON(fiber.Map{"error": "Access denied"})
 	}
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"log"
 	"sync"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/websocket/v2"
+	"google.golang.org/grpc"
 
 	"youflac/backend"
 )
@@ -16,15 +18,18 @@ import (
 // Server represents the HTTP API server
 type Server struct {
 	app       *fiber.App
-	config    *backend.Config
+	configs   *backend.ConfigStore // Shared with the queue
 	queue     *backend.Queue
 	history   *backend.History
 	fileIndex *backend.FileIndex
+	channels  *backend.ChannelArchives
+	watchlist *backend.Watchlist
 	wsHub     *WebSocketHub
+	grpc      *grpc.Server // See grpc.go
 }
 
 // NewServer creates a new API server instance
-func NewServer(config *backend.Config, queue *backend.Queue, history *backend.History, fileIndex *backend.FileIndex) *Server {
+func NewServer(configs *backend.ConfigStore, queue *backend.Queue, history *backend.History, fileIndex *backend.FileIndex) *Server {
 	app := fiber.New(fiber.Config{
 		AppName:      "YouFlac Server",
 		ServerHeader: "YouFlac",
@@ -37,12 +42,15 @@ func NewServer(config *backend.Config, queue *backend.Queue, history *backend.Hi
 
 	server := &Server{
 		app:       app,
-		config:    config,
+		configs:   configs,
 		queue:     queue,
 		history:   history,
 		fileIndex: fileIndex,
+		channels:  backend.NewChannelArchives(),
+		watchlist: backend.NewWatchlist(),
 		wsHub:     wsHub,
 	}
+	server.grpc = newGRPCServer(server)
 
 	// Middleware
 	app.Use(recover.New())
@@ -72,35 +80,90 @@ func (s *Server) setupRoutes() {
 	// Queue routes
 	api.Get("/queue", s.handleGetQueue)
 	api.Post("/queue", s.handleAddToQueue)
+	api.Get("/add", s.handleShortcutAdd) // Single-GET add for phone shortcuts
 	api.Get("/queue/stats", s.handleGetQueueStats)
 	api.Get("/queue/failed/export", s.handleExportFailed)
 	api.Post("/queue/clear", s.handleClearCompleted)
 	api.Post("/queue/retry", s.handleRetryFailed)
 	api.Post("/queue/retry-failed", s.handleRetryFailed)
 	api.Post("/queue/pause-all", s.handlePauseAll)
+	api.Post("/queue/approve-all", s.handleApproveAll)
 	api.Post("/queue/resume-all", s.handleResumeAll)
+	api.Get("/queue/schedule", s.handleGetSchedule)
+	api.Post("/queue/plan", s.handlePlanDownload)
+	api.Post("/queue/schedule/override", s.handleOverrideSchedule)
 	api.Get("/queue/:id", s.handleGetQueueItem)
 	api.Delete("/queue/:id", s.handleRemoveFromQueue)
 	api.Post("/queue/:id/cancel", s.handleCancelQueueItem)
 	api.Post("/queue/:id/pause", s.handlePauseQueueItem)
 	api.Post("/queue/:id/resume", s.handleResumeQueueItem)
 	api.Post("/queue/:id/retry-override", s.handleRetryQueueItemWithOverride)
+	api.Post("/queue/:id/commit", s.handleCommitDryRun)
+	api.Post("/queue/:id/approve", s.handleApproveQueueItem)
+	api.Post("/queue/:id/reject", s.handleRejectQueueItem)
+	api.Get("/queue/:id/preview", s.handleGetQueuePreview)
+	api.Post("/queue/:id/reject-match", s.handleRejectMatch)
+	api.Post("/queue/:id/sync", s.handleRetrySync)
 	api.Put("/queue/:id/move", s.handleMoveQueueItem)
+	api.Put("/queue/:id/notes", s.handleSetQueueItemNotes)
+	api.Put("/queue/:id/dependencies", s.handleSetQueueItemDependencies)
 
 	// Playlist routes
 	api.Post("/playlist", s.handleAddPlaylistToQueue)
+	api.Post("/playlist/check", s.handleCheckPlaylist)
+
+	// Search routes
+	api.Post("/search", s.handleSearch)
+	api.Get("/resolve", s.handleResolve)
+
+	// Channel archive routes
+	api.Get("/channels", s.handleGetChannels)
+	api.Post("/channels", s.handleAddChannel)
+	api.Post("/channels/:id/sync", s.handleSyncChannel)
+	api.Delete("/channels/:id", s.handleRemoveChannel)
+	api.Get("/watchlist", s.handleGetWatchlist)
+	api.Post("/watchlist", s.handleAddWatchedArtist)
+	api.Post("/watchlist/:id/check", s.handleCheckWatchedArtist)
+	api.Delete("/watchlist/:id", s.handleRemoveWatchedArtist)
+	api.Get("/watchlist/releases", s.handleGetWatchReleases)
+	api.Post("/watchlist/releases/:id/dismiss", s.handleDismissWatchRelease)
+
+	// Localization routes
+	api.Get("/i18n/stages", s.handleGetStageCatalog)
 
 	// Config routes
 	api.Get("/config", s.handleGetConfig)
 	api.Post("/config", s.handleSaveConfig)
+	api.Post("/config/validate", s.handleValidateConfig)
+	api.Post("/config/metadata-rules/test", s.handleTestMetadataRules)
 	api.Get("/config/default-output", s.handleGetDefaultOutput)
 
+	// Encrypted secrets (values are write-only over the API)
+	api.Get("/secrets", s.handleListSecrets)
+	api.Post("/secrets/cookies", s.handleUploadCookies)
+	api.Put("/secrets/:name", s.handleSetSecret)
+	api.Delete("/secrets/:name", s.handleDeleteSecret)
+
+	// Notifications
+	api.Post("/notifications/test", s.handleTestNotification)
+
+	// First-run setup wizard
+	api.Get("/setup/status", s.handleGetSetupStatus)
+	api.Post("/setup/test-output", s.handleTestOutputDirectory)
+	api.Get("/setup/services", s.handleProbeServices)
+	api.Post("/setup/complete", s.handleCompleteSetup)
+
 	// History routes
 	api.Get("/history", s.handleGetHistory)
+	api.Get("/history/page", s.handleGetHistoryPage)
 	api.Get("/history/stats", s.handleGetHistoryStats)
 	api.Get("/history/search", s.handleSearchHistory)
+	api.Get("/history/tags", s.handleGetHistoryTags)
+	api.Get("/history/archive", s.handleExportYtdlpArchive)
+	api.Put("/history/:id/notes", s.handleSetHistoryNotes)
 	api.Delete("/history/:id", s.handleDeleteHistoryEntry)
 	api.Post("/history/clear", s.handleClearHistory)
+	api.Post("/history/import-archive", s.handleImportYtdlpArchive)
 	api.Post("/history/:id/redownload", s.handleRedownloadFromHistory)
 
 	// Video/URL routes
@@ -111,8 +174,19 @@ func (s *Server) setupRoutes() {
 	// Files routes
 	api.Get("/files", s.handleListFiles)
 	api.Get("/files/playlists", s.handleGetPlaylistFolders)
+	api.Get("/files/thumbnail", s.handleGetFileThumbnail)
 	api.Post("/files/reorganize", s.handleReorganizePlaylist)
 	api.Post("/files/flatten", s.handleFlattenPlaylist)
+	api.Get("/files/duplicates", s.handleGetDuplicates)
+	api.Get("/files/metadata", s.handleGetFileMetadata)
+	api.Put("/files/metadata", s.handleUpdateFileMetadata)
+	api.Get("/files/albums", s.handleGetAlbums)
+	api.Post("/files/albums/:id/download-missing", s.handleDownloadMissingAlbumTracks)
+	api.Post("/files/check", s.handleCheckLibrary)
+	api.Get("/files/quality-report", s.handleQualityReport)
+	api.Post("/files/views/sync", s.handleSyncLibraryViews)
+	api.Post("/files/collections/build", s.handleBuildCollections)
+	api.Post("/files/jellyfin-playlists/build", s.handleBuildJellyfinPlaylists)
 
 	// Analyzer routes
 	api.Post("/analyze", s.handleAnalyzeAudio)
@@ -162,9 +236,15 @@ func (s *Server) Listen(addr string) error {
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown() error {
 	s.wsHub.Close()
+	s.grpc.Stop()
 	return s.app.Shutdown()
 }
 
+// StartWatchlist starts the periodic watchlist checks until ctx is done
+func (s *Server) StartWatchlist(ctx context.Context) {
+	s.watchlist.Start(ctx, s.queue, s.history, s.fileIndex)
+}
+
 // BroadcastQueueEvent sends a queue event to all connected WebSocket clients
 func (s *Server) BroadcastQueueEvent(event backend.QueueEvent) {
 	s.wsHub.Broadcast(event)